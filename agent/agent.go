@@ -51,16 +51,18 @@ var DefaultDataDir = dataDir
 const SystemIdentity = "system-identity"
 
 const (
-	LxcBridge              = "LXC_BRIDGE"
-	ProviderType           = "PROVIDER_TYPE"
-	ContainerType          = "CONTAINER_TYPE"
-	Namespace              = "NAMESPACE"
-	StorageDir             = "STORAGE_DIR"
-	StorageAddr            = "STORAGE_ADDR"
-	AgentServiceName       = "AGENT_SERVICE_NAME"
-	MongoOplogSize         = "MONGO_OPLOG_SIZE"
-	NumaCtlPreference      = "NUMA_CTL_PREFERENCE"
-	AllowsSecureConnection = "SECURE_STATESERVER_CONNECTION"
+	LxcBridge               = "LXC_BRIDGE"
+	ProviderType            = "PROVIDER_TYPE"
+	ContainerType           = "CONTAINER_TYPE"
+	Namespace               = "NAMESPACE"
+	StorageDir              = "STORAGE_DIR"
+	StorageAddr             = "STORAGE_ADDR"
+	AgentServiceName        = "AGENT_SERVICE_NAME"
+	MongoOplogSize          = "MONGO_OPLOG_SIZE"
+	NumaCtlPreference       = "NUMA_CTL_PREFERENCE"
+	AllowsSecureConnection  = "SECURE_STATESERVER_CONNECTION"
+	SlowAPIRequestThreshold = "SLOW_API_REQUEST_THRESHOLD"
+	UpgradeWithoutBackup    = "UPGRADE_WITHOUT_BACKUP"
 )
 
 // The Config interface is the sole way that the agent gets access to the
@@ -166,6 +168,10 @@ type ConfigSetterOnly interface {
 	// SetAPIHostPorts sets the API host/port addresses to connect to.
 	SetAPIHostPorts(servers [][]network.HostPort)
 
+	// SetCACert sets the CA certificate used to validate the
+	// API servers.
+	SetCACert(caCert string)
+
 	// Migrate takes an existing agent config and applies the given
 	// parameters to change it.
 	//
@@ -478,6 +484,10 @@ func (c *configInternal) SetAPIHostPorts(servers [][]network.HostPort) {
 	c.apiDetails.addresses = addrs
 }
 
+func (c *configInternal) SetCACert(caCert string) {
+	c.caCert = caCert
+}
+
 func (c *configInternal) SetValue(key, value string) {
 	if value == "" {
 		delete(c.values, key)
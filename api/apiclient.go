@@ -8,6 +8,7 @@ import (
 	"crypto/x509"
 	"fmt"
 	"io"
+	"math/rand"
 	"strings"
 	"time"
 
@@ -113,6 +114,12 @@ type DialOpts struct {
 	// RetryDelay is the amount of time to wait between
 	// unsucssful connection attempts.
 	RetryDelay time.Duration
+
+	// LoginRetryTimeout is the maximum amount of time to keep
+	// retrying a login that the state server rejected because it
+	// was too busy. Between attempts we wait at least as long as the
+	// server asked us to. If it is zero, such logins are not retried.
+	LoginRetryTimeout time.Duration
 }
 
 // DefaultDialOpts returns a DialOpts representing the default
@@ -122,6 +129,7 @@ func DefaultDialOpts() DialOpts {
 		DialAddressInterval: 50 * time.Millisecond,
 		Timeout:             10 * time.Minute,
 		RetryDelay:          2 * time.Second,
+		LoginRetryTimeout:   5 * time.Minute,
 	}
 }
 
@@ -188,7 +196,7 @@ func Open(info *Info, opts DialOpts) (*State, error) {
 		certPool: pool,
 	}
 	if info.Tag != nil || info.Password != "" {
-		if err := st.Login(info.Tag.String(), info.Password, info.Nonce); err != nil {
+		if err := st.loginWithRetry(info, opts); err != nil {
 			conn.Close()
 			return nil, err
 		}
@@ -199,6 +207,59 @@ func Open(info *Info, opts DialOpts) (*State, error) {
 	return st, nil
 }
 
+// loginWithRetry logs in to the API server. While the server is too
+// busy to accept the login, it keeps retrying for up to
+// opts.LoginRetryTimeout.
+func (st *State) loginWithRetry(info *Info, opts DialOpts) error {
+	deadline := time.Now().Add(opts.LoginRetryTimeout)
+	for attempt := 0; ; attempt++ {
+		err := st.Login(info.Tag.String(), info.Password, info.Nonce)
+		if err == nil || opts.LoginRetryTimeout <= 0 {
+			return err
+		}
+		delay, ok := loginRetryDelay(err, attempt)
+		if !ok || time.Now().Add(delay).After(deadline) {
+			return err
+		}
+		logger.Infof("API server is busy, retrying login in %v: %v", delay, err)
+		time.Sleep(delay)
+	}
+}
+
+const (
+	// minLoginRetryDelay holds the delay before retrying a login
+	// when the server did not say how long to wait.
+	minLoginRetryDelay = time.Second
+
+	// maxLoginRetryDelay holds the longest delay before retrying a
+	// login, not counting jitter.
+	maxLoginRetryDelay = time.Minute
+)
+
+// loginRetryDelay returns how long to wait before retrying a login
+// that failed with err, and whether it should be retried at all. The
+// delay backs off exponentially with each attempt, but is never less
+// than the server asked for, and random jitter is added so that clients
+// which were turned away together don't all come back together.
+func loginRetryDelay(err error, attempt int) (time.Duration, bool) {
+	if !params.IsCodeTryAgain(err) {
+		return 0, false
+	}
+	delay := minLoginRetryDelay
+	for i := 0; i < attempt && delay < maxLoginRetryDelay; i++ {
+		delay *= 2
+	}
+	if retryAfter, ok := params.RetryAfter(err); ok && retryAfter > delay {
+		delay = retryAfter
+	}
+	if delay > maxLoginRetryDelay {
+		delay = maxLoginRetryDelay
+	}
+	// Add up to 50% jitter.
+	delay += time.Duration(rand.Int63n(int64(delay)/2 + 1))
+	return delay, true
+}
+
 // toString returns the value of a tag's String method, or "" if the tag is nil.
 func toString(tag names.Tag) string {
 	if tag == nil {
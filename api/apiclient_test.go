@@ -8,6 +8,7 @@ import (
 	"io"
 	"net"
 	"strconv"
+	"time"
 
 	"github.com/juju/names"
 	jc "github.com/juju/testing/checkers"
@@ -177,3 +178,41 @@ func (*websocketSuite) TestSetUpWebsocketConfigHandlesEnvironUUID(c *gc.C) {
 	c.Check(conf.Location.String(), gc.Equals, "wss://0.1.2.3:1234/environment/dead-beef-1234/api")
 	c.Check(conf.Origin.String(), gc.Equals, "http://localhost/")
 }
+
+func (s *websocketSuite) TestLoginRetryDelay(c *gc.C) {
+	_, ok := api.LoginRetryDelay(&params.Error{Code: params.CodeUnauthorized}, 0)
+	c.Assert(ok, jc.IsFalse)
+
+	for i, test := range []struct {
+		err     error
+		attempt int
+		min     time.Duration
+	}{{
+		err: &params.Error{Code: params.CodeTryAgain},
+		min: time.Second,
+	}, {
+		err:     &params.Error{Code: params.CodeTryAgain},
+		attempt: 3,
+		min:     8 * time.Second,
+	}, {
+		err:     &params.Error{Code: params.CodeTryAgain},
+		attempt: 20,
+		min:     time.Minute,
+	}, {
+		err: params.NewTryAgainError("busy", 5*time.Second),
+		min: 5 * time.Second,
+	}, {
+		err:     params.NewTryAgainError("busy", 5*time.Second),
+		attempt: 4,
+		min:     16 * time.Second,
+	}, {
+		err: params.NewTryAgainError("busy", time.Hour),
+		min: time.Minute,
+	}} {
+		c.Logf("test %d: %v after %d attempts", i, test.err, test.attempt)
+		delay, ok := api.LoginRetryDelay(test.err, test.attempt)
+		c.Check(ok, jc.IsTrue)
+		c.Check(delay >= test.min, jc.IsTrue)
+		c.Check(delay <= test.min*3/2, jc.IsTrue)
+	}
+}
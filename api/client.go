@@ -81,6 +81,14 @@ type MachineStatus struct {
 	Jobs          []multiwatcher.MachineJob
 	HasVote       bool
 	WantsVote     bool
+
+	// ContainerTemplates describes the templates that the machine's
+	// containers are cloned from.
+	ContainerTemplates []params.ContainerTemplate
+
+	// InstancePolled holds when the provider was last queried for the
+	// machine's instance addresses and status, if it has been.
+	InstancePolled *time.Time
 }
 
 // ServiceStatus holds status info about a service.
@@ -94,6 +102,20 @@ type ServiceStatus struct {
 	CanUpgradeTo  string
 	SubordinateTo []string
 	Units         map[string]UnitStatus
+	Leader        string
+
+	// PlacementViolations describes how the placement of the
+	// service's units violates its placement policies.
+	PlacementViolations []string
+}
+
+// RemoteServiceStatus holds status info about a service of another
+// environment that services of the environment relate to.
+type RemoteServiceStatus struct {
+	Err         error
+	Environment string
+	Life        string
+	Relations   map[string][]string
 }
 
 // UnitStatus holds status info about a unit.
@@ -148,6 +170,7 @@ type Status struct {
 	EnvironmentName string
 	Machines        map[string]MachineStatus
 	Services        map[string]ServiceStatus
+	RemoteServices  map[string]RemoteServiceStatus
 	Networks        map[string]NetworkStatus
 	Relations       []RelationStatus
 }
@@ -162,6 +185,48 @@ func (c *Client) Status(patterns []string) (*Status, error) {
 	return &result, nil
 }
 
+// RefreshInstances asks the instance poller to query the provider for
+// the addresses and status of all provisioned machines now, and waits
+// for the results. It returns the ids of any machines whose instances
+// could not be polled, or were not polled in time.
+func (c *Client) RefreshInstances() ([]string, error) {
+	var result params.RefreshInstancesResult
+	if err := c.facade.FacadeCall("RefreshInstances", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Stale, nil
+}
+
+// APIServerLoad returns the current load on the API server the client
+// is connected to.
+func (c *Client) APIServerLoad() (params.APIServerLoad, error) {
+	var result params.APIServerLoad
+	if err := c.facade.FacadeCall("APIServerLoad", nil, &result); err != nil {
+		return params.APIServerLoad{}, err
+	}
+	return result, nil
+}
+
+// MachineNetworkStatus returns the network interfaces configured for
+// the machine, the ones last found on it by its agent, and how they
+// differ.
+func (c *Client) MachineNetworkStatus(machineId string) (params.MachineNetworkStatus, error) {
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: names.NewMachineTag(machineId).String()}},
+	}
+	var results params.MachineNetworkStatusResults
+	if err := c.facade.FacadeCall("MachineNetworkStatus", args, &results); err != nil {
+		return params.MachineNetworkStatus{}, err
+	}
+	if n := len(results.Results); n != 1 {
+		return params.MachineNetworkStatus{}, errors.Errorf("expected 1 result, got %d", n)
+	}
+	if err := results.Results[0].Error; err != nil {
+		return params.MachineNetworkStatus{}, err
+	}
+	return results.Results[0].Status, nil
+}
+
 // LegacyMachineStatus holds just the instance-id of a machine.
 type LegacyMachineStatus struct {
 	InstanceId string // Not type instance.Id just to match original api.
@@ -260,6 +325,26 @@ func (c *Client) ServiceGet(service string) (*params.ServiceGetResults, error) {
 	return &results, err
 }
 
+// ServiceConfigHistory returns the recorded changes to the
+// configuration of a service, oldest first.
+func (c *Client) ServiceConfigHistory(service string) ([]params.ServiceConfigRevision, error) {
+	var results params.ServiceConfigHistoryResults
+	args := params.ServiceGet{ServiceName: service}
+	err := c.facade.FacadeCall("ServiceConfigHistory", args, &results)
+	return results.Revisions, err
+}
+
+// ServiceConfigRevert changes the configuration of a service back to
+// what it was after the given revision of its config history. It
+// returns the names of the settings left unchanged because their
+// values were not recorded.
+func (c *Client) ServiceConfigRevert(service string, revision int) ([]string, error) {
+	var result params.ServiceConfigRevertResult
+	args := params.ServiceConfigRevert{ServiceName: service, Revision: revision}
+	err := c.facade.FacadeCall("ServiceConfigRevert", args, &result)
+	return result.Skipped, err
+}
+
 // AddRelation adds a relation between the specified endpoints and returns the relation info.
 func (c *Client) AddRelation(endpoints ...string) (*params.AddRelationResults, error) {
 	var addRelRes params.AddRelationResults
@@ -343,6 +428,21 @@ func (c *Client) ServiceUnexpose(service string) error {
 	return c.facade.FacadeCall("ServiceUnexpose", params, nil)
 }
 
+// ServiceOffer offers an endpoint of a service, of the form
+// <service>:<relation>, to the other environments hosted by the state
+// server.
+func (c *Client) ServiceOffer(endpoint string) error {
+	params := params.ServiceOffer{Endpoint: endpoint}
+	return c.facade.FacadeCall("ServiceOffer", params, nil)
+}
+
+// ServiceUnoffer stops offering an endpoint of a service to other
+// environments.
+func (c *Client) ServiceUnoffer(endpoint string) error {
+	params := params.ServiceOffer{Endpoint: endpoint}
+	return c.facade.FacadeCall("ServiceUnoffer", params, nil)
+}
+
 // ServiceDeployWithNetworks works exactly like ServiceDeploy, but
 // allows the specification of requested networks that must be present
 // on the machines where the service is deployed. Another way to specify
@@ -360,6 +460,24 @@ func (c *Client) ServiceDeployWithNetworks(charmURL string, serviceName string,
 	return c.facade.FacadeCall("ServiceDeployWithNetworks", params, nil)
 }
 
+// ServiceDeployWithBindings works like ServiceDeployWithNetworks, but
+// also binds the service's endpoints to spaces. The bindings map
+// endpoint names to space names; the empty endpoint name sets the space
+// of the endpoints not bound explicitly.
+func (c *Client) ServiceDeployWithBindings(charmURL string, serviceName string, numUnits int, configYAML string, cons constraints.Value, toMachineSpec string, networks []string, bindings map[string]string) error {
+	params := params.ServiceDeploy{
+		ServiceName:      serviceName,
+		CharmUrl:         charmURL,
+		NumUnits:         numUnits,
+		ConfigYAML:       configYAML,
+		Constraints:      cons,
+		ToMachineSpec:    toMachineSpec,
+		Networks:         networks,
+		EndpointBindings: bindings,
+	}
+	return c.facade.FacadeCall("ServiceDeployWithBindings", params, nil)
+}
+
 // ServiceDeploy obtains the charm, either locally or from the charm store,
 // and deploys it.
 func (c *Client) ServiceDeploy(charmURL string, serviceName string, numUnits int, configYAML string, cons constraints.Value, toMachineSpec string) error {
@@ -521,6 +639,11 @@ func (c *Client) ShareEnvironment(users []names.UserTag) error {
 	if err != nil {
 		return errors.Trace(err)
 	}
+	if len(result.Results) == 1 {
+		// Keep the error code, so that callers can tell whether
+		// the user already had access to the environment.
+		return result.OneError()
+	}
 	return result.Combine()
 }
 
@@ -559,6 +682,16 @@ func (c *Client) WatchAll() (*AllWatcher, error) {
 	return newAllWatcher(c.st, &info.AllWatcherId), nil
 }
 
+// WatchAllFiltered returns an AllWatcher which only reports deltas
+// for the entities matched by filter.
+func (c *Client) WatchAllFiltered(filter params.WatchAllFilter) (*AllWatcher, error) {
+	info := new(WatchAll)
+	if err := c.facade.FacadeCall("WatchAllFiltered", filter, info); err != nil {
+		return nil, err
+	}
+	return newAllWatcher(c.st, &info.AllWatcherId), nil
+}
+
 // GetAnnotations returns annotations that have been set on the given entity.
 func (c *Client) GetAnnotations(tag string) (map[string]string, error) {
 	args := params.GetAnnotations{tag}
@@ -609,6 +742,47 @@ func (c *Client) SetEnvironAgentVersion(version version.Number) error {
 	return c.facade.FacadeCall("SetEnvironAgentVersion", args, nil)
 }
 
+// StartStagedUpgrade sets the environment agent-version setting to the
+// given value, upgrading the state servers first, then the given
+// percentage of the other machines as canaries, then the rest in
+// batches of the given size once the agents upgraded before are
+// healthy. The upgrade halts if upgraded agents are not healthy within
+// the given timeout.
+func (c *Client) StartStagedUpgrade(version version.Number, canaryPercent, batchSize int, healthTimeout time.Duration) error {
+	args := params.StartStagedUpgrade{
+		Version:       version,
+		CanaryPercent: canaryPercent,
+		BatchSize:     batchSize,
+		HealthTimeout: healthTimeout,
+	}
+	return c.facade.FacadeCall("StartStagedUpgrade", args, nil)
+}
+
+// ResumeStagedUpgrade resumes a halted staged upgrade.
+func (c *Client) ResumeStagedUpgrade() error {
+	return c.facade.FacadeCall("ResumeStagedUpgrade", nil, nil)
+}
+
+// StagedUpgradeStatus returns the progress of the last staged upgrade
+// of the environment.
+func (c *Client) StagedUpgradeStatus() (params.StagedUpgradeStatus, error) {
+	var status params.StagedUpgradeStatus
+	err := c.facade.FacadeCall("StagedUpgradeStatus", nil, &status)
+	return status, err
+}
+
+// RollbackUpgrade rolls back the last upgrade of the state servers,
+// restoring the state backup taken before their upgrade steps were run
+// and setting the environment agent-version setting back to the version
+// upgraded from. Unless force is true, the rollback is refused if any
+// documents were changed since the backup was taken.
+func (c *Client) RollbackUpgrade(force bool) (params.UpgradeRollback, error) {
+	var result params.UpgradeRollback
+	args := params.UpgradeRollbackArgs{Force: force}
+	err := c.facade.FacadeCall("RollbackUpgrade", args, &result)
+	return result, err
+}
+
 // AbortCurrentUpgrade aborts and archives the current upgrade
 // synchronisation record, if any.
 func (c *Client) AbortCurrentUpgrade() error {
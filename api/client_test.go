@@ -14,6 +14,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"code.google.com/p/go.net/websocket"
 	"github.com/juju/errors"
@@ -124,7 +125,10 @@ func (s *clientSuite) TestShareEnvironmentExistingUser(c *gc.C) {
 				c.Fatalf("wrong input structure")
 			}
 			if result, ok := response.(*params.ErrorResults); ok {
-				err := &params.Error{Message: "failed to create environment user: env user already exists"}
+				err := &params.Error{
+					Message: "failed to create environment user: env user already exists",
+					Code:    params.CodeAlreadyExists,
+				}
 				*result = params.ErrorResults{Results: []params.ErrorResult{{Error: err}}}
 			} else {
 				c.Fatalf("wrong input structure")
@@ -136,6 +140,7 @@ func (s *clientSuite) TestShareEnvironmentExistingUser(c *gc.C) {
 
 	err := client.ShareEnvironment([]names.UserTag{user.UserTag()})
 	c.Assert(err, gc.ErrorMatches, "failed to create environment user: env user already exists")
+	c.Assert(err, jc.Satisfies, params.IsCodeAlreadyExists)
 }
 
 func (s *clientSuite) TestShareEnvironmentThreeUsers(c *gc.C) {
@@ -386,6 +391,98 @@ func (s *clientSuite) TestAbortCurrentUpgrade(c *gc.C) {
 	c.Assert(err, gc.Equals, someErr) // Confirms that the correct facade was called
 }
 
+func (s *clientSuite) TestRefreshInstances(c *gc.C) {
+	client := s.APIState.Client()
+	cleanup := api.PatchClientFacadeCall(client,
+		func(request string, args interface{}, response interface{}) error {
+			c.Assert(request, gc.Equals, "RefreshInstances")
+			c.Assert(args, gc.IsNil)
+			result, ok := response.(*params.RefreshInstancesResult)
+			c.Assert(ok, jc.IsTrue)
+			result.Stale = []string{"1", "2"}
+			return nil
+		},
+	)
+	defer cleanup()
+
+	stale, err := client.RefreshInstances()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(stale, jc.DeepEquals, []string{"1", "2"})
+}
+
+func (s *clientSuite) TestMachineNetworkStatus(c *gc.C) {
+	client := s.APIState.Client()
+	cleanup := api.PatchClientFacadeCall(client,
+		func(request string, args interface{}, response interface{}) error {
+			c.Assert(request, gc.Equals, "MachineNetworkStatus")
+			c.Assert(args, jc.DeepEquals, params.Entities{
+				Entities: []params.Entity{{Tag: "machine-1"}},
+			})
+			results, ok := response.(*params.MachineNetworkStatusResults)
+			c.Assert(ok, jc.IsTrue)
+			results.Results = []params.MachineNetworkStatusResult{{
+				Status: params.MachineNetworkStatus{
+					Drift: []params.NetworkInterfaceDrift{{"eth1", "missing on machine"}},
+				},
+			}}
+			return nil
+		},
+	)
+	defer cleanup()
+
+	status, err := client.MachineNetworkStatus("1")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(status.Drift, jc.DeepEquals, []params.NetworkInterfaceDrift{{"eth1", "missing on machine"}})
+}
+
+func (s *clientSuite) TestStartStagedUpgrade(c *gc.C) {
+	client := s.APIState.Client()
+	var called bool
+	cleanup := api.PatchClientFacadeCall(client,
+		func(request string, args interface{}, response interface{}) error {
+			called = true
+			c.Assert(request, gc.Equals, "StartStagedUpgrade")
+			c.Assert(args, jc.DeepEquals, params.StartStagedUpgrade{
+				Version:       version.MustParse("1.2.3"),
+				CanaryPercent: 10,
+				BatchSize:     5,
+				HealthTimeout: time.Minute,
+			})
+			return nil
+		},
+	)
+	defer cleanup()
+
+	err := client.StartStagedUpgrade(version.MustParse("1.2.3"), 10, 5, time.Minute)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(called, jc.IsTrue)
+}
+
+func (s *clientSuite) TestRollbackUpgrade(c *gc.C) {
+	client := s.APIState.Client()
+	cleanup := api.PatchClientFacadeCall(client,
+		func(request string, args interface{}, response interface{}) error {
+			c.Assert(request, gc.Equals, "RollbackUpgrade")
+			c.Assert(args, gc.Equals, params.UpgradeRollbackArgs{Force: true})
+			result, ok := response.(*params.UpgradeRollback)
+			c.Assert(ok, jc.IsTrue)
+			result.PreviousVersion = version.MustParse("1.2.3")
+			result.TargetVersion = version.MustParse("1.3.0")
+			result.BackupId = "backup-id"
+			return nil
+		},
+	)
+	defer cleanup()
+
+	result, err := client.RollbackUpgrade(true)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, gc.Equals, params.UpgradeRollback{
+		PreviousVersion: version.MustParse("1.2.3"),
+		TargetVersion:   version.MustParse("1.3.0"),
+		BackupId:        "backup-id",
+	})
+}
+
 func (s *clientSuite) TestEnvironmentGet(c *gc.C) {
 	client := s.APIState.Client()
 	env, err := client.EnvironmentGet()
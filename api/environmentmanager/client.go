@@ -0,0 +1,67 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package environmentmanager
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+	"github.com/juju/names"
+
+	"github.com/juju/juju/api/base"
+	"github.com/juju/juju/apiserver/params"
+)
+
+var logger = loggo.GetLogger("juju.api.environmentmanager")
+
+// Client provides methods that the Juju client command uses to interact
+// with the environments hosted by a state server.
+type Client struct {
+	base.ClientFacade
+	facade base.FacadeCaller
+}
+
+// NewClient creates a new `Client` based on an existing authenticated API
+// connection.
+func NewClient(st base.APICallCloser) *Client {
+	frontend, backend := base.NewClientFacade(st, "EnvironmentManager")
+	return &Client{ClientFacade: frontend, facade: backend}
+}
+
+// CreateEnvironment creates a new environment owned by the given user,
+// hosted by the state server. The account holds provider specific
+// credentials for the environment; config holds the values that differ
+// from the state server environment's configuration, and must include
+// the environment's name.
+func (c *Client) CreateEnvironment(owner string, account, config map[string]interface{}) (params.Environment, error) {
+	var result params.Environment
+	if !names.IsValidUser(owner) {
+		return result, errors.Errorf("invalid owner name %q", owner)
+	}
+	createArgs := params.EnvironmentCreateArgs{
+		OwnerTag: names.NewUserTag(owner).String(),
+		Account:  account,
+		Config:   config,
+	}
+	err := c.facade.FacadeCall("CreateEnvironment", createArgs, &result)
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	logger.Infof("created environment %s (%s)", result.Name, result.UUID)
+	return result, nil
+}
+
+// ListEnvironments returns the environments that the given user has
+// access to.
+func (c *Client) ListEnvironments(user string) ([]params.Environment, error) {
+	if !names.IsValidUser(user) {
+		return nil, errors.Errorf("invalid user name %q", user)
+	}
+	entity := params.Entity{Tag: names.NewUserTag(user).String()}
+	var result params.EnvironmentList
+	err := c.facade.FacadeCall("ListEnvironments", entity, &result)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return result.Environments, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package environmentmanager_test
+
+import (
+	"github.com/juju/names"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/api"
+	"github.com/juju/juju/api/environmentmanager"
+	"github.com/juju/juju/apiserver/params"
+	jujutesting "github.com/juju/juju/juju/testing"
+)
+
+type envmanagerSuite struct {
+	jujutesting.JujuConnSuite
+
+	envmanager *environmentmanager.Client
+}
+
+var _ = gc.Suite(&envmanagerSuite{})
+
+func (s *envmanagerSuite) SetUpTest(c *gc.C) {
+	s.JujuConnSuite.SetUpTest(c)
+	s.envmanager = environmentmanager.NewClient(s.APIState)
+	c.Assert(s.envmanager, gc.NotNil)
+}
+
+func (s *envmanagerSuite) createEnvironment(c *gc.C, name string) params.Environment {
+	owner := s.AdminUserTag(c).Name()
+	env, err := s.envmanager.CreateEnvironment(owner, nil, map[string]interface{}{"name": name})
+	c.Assert(err, jc.ErrorIsNil)
+	return env
+}
+
+func (s *envmanagerSuite) TestCreateEnvironment(c *gc.C) {
+	env := s.createEnvironment(c, "new-env")
+	c.Assert(env.Name, gc.Equals, "new-env")
+	c.Assert(env.OwnerTag, gc.Equals, s.AdminUserTag(c).String())
+
+	stateEnv, err := s.State.GetEnvironment(names.NewEnvironTag(env.UUID))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(stateEnv.Name(), gc.Equals, "new-env")
+}
+
+func (s *envmanagerSuite) TestCreateEnvironmentBadOwner(c *gc.C) {
+	_, err := s.envmanager.CreateEnvironment("not a user", nil, nil)
+	c.Assert(err, gc.ErrorMatches, `invalid owner name "not a user"`)
+}
+
+func (s *envmanagerSuite) TestListEnvironments(c *gc.C) {
+	env := s.createEnvironment(c, "new-env")
+	envs, err := s.envmanager.ListEnvironments(s.AdminUserTag(c).Name())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(envs, gc.HasLen, 2)
+	var found bool
+	for _, e := range envs {
+		if e.UUID == env.UUID {
+			found = true
+		}
+	}
+	c.Assert(found, jc.IsTrue)
+}
+
+func (s *envmanagerSuite) TestLoginToNewEnvironment(c *gc.C) {
+	env := s.createEnvironment(c, "new-env")
+
+	info := s.APIInfo(c)
+	info.EnvironTag = names.NewEnvironTag(env.UUID)
+	st, err := api.Open(info, api.DialOpts{})
+	c.Assert(err, jc.ErrorIsNil)
+	defer st.Close()
+
+	envTag, err := st.EnvironTag()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(envTag.Id(), gc.Equals, env.UUID)
+	attrs, err := st.Client().EnvironmentGet()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(attrs["name"], gc.Equals, "new-env")
+}
+
+func (s *envmanagerSuite) TestLoginToUnknownEnvironment(c *gc.C) {
+	info := s.APIInfo(c)
+	info.EnvironTag = names.NewEnvironTag("deadbeef-0bad-400d-8000-4b1d0d06f00d")
+	_, err := api.Open(info, api.DialOpts{})
+	c.Assert(err, gc.ErrorMatches, `unknown environment: "deadbeef-0bad-400d-8000-4b1d0d06f00d"`)
+}
@@ -16,6 +16,7 @@ var (
 	BestVersion         = bestVersion
 	FacadeVersions      = &facadeVersions
 	NewHTTPClient       = &newHTTPClient
+	LoginRetryDelay     = loginRetryDelay
 )
 
 // SetServerRoot allows changing the URL to the internal API server
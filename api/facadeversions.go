@@ -20,14 +20,17 @@ var facadeVersions = map[string]int{
 	"Networker":            0,
 	"StringsWatcher":       0,
 	"Environment":          0,
+	"EnvironmentManager":   0,
 	"ImageManager":         1,
 	"KeyManager":           0,
 	"Logger":               0,
 	"MetricsManager":       0,
+	"MigrationManager":     0,
 	"Pinger":               0,
 	"Provisioner":          0,
 	"Reboot":               1,
 	"RelationUnitsWatcher": 0,
+	"RemoteRelations":      0,
 	"UserManager":          0,
 	"CharmRevisionUpdater": 0,
 	"Client":               0,
@@ -38,6 +41,8 @@ var facadeVersions = map[string]int{
 	"Uniter":               1,
 	"Action":               0,
 	"Service":              1,
+	"Spaces":               0,
+	"Subnets":              0,
 }
 
 // bestVersion tries to find the newest version in the version list that we can
@@ -46,3 +46,20 @@ func (c *Client) DeleteImage(kind, series, arch string) error {
 	}
 	return results.OneError()
 }
+
+// PrimeTemplate asks for the container template of the given kind and
+// series to be built on the specified machine ahead of its first
+// container. If series is empty, the machine's own series is used.
+func (c *Client) PrimeTemplate(machineTag, kind, series string) error {
+	p := params.PrimeTemplatesParams{
+		Templates: []params.TemplateSpec{
+			{MachineTag: machineTag, Kind: kind, Series: series},
+		},
+	}
+	results := new(params.ErrorResults)
+	err := c.facade.FacadeCall("PrimeTemplates", p, results)
+	if err != nil {
+		return err
+	}
+	return results.OneError()
+}
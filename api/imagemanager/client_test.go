@@ -123,3 +123,48 @@ func (s *imagemanagerSuite) TestDeleteImageError(c *gc.C) {
 	err := im.DeleteImage("lxc", "trusty", "amd64")
 	c.Check(err, gc.ErrorMatches, "the devil made me do it")
 }
+
+func (s *imagemanagerSuite) TestPrimeTemplate(c *gc.C) {
+	var callCount int
+	apiCaller := testing.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "ImageManager")
+		c.Check(version, gc.Equals, 0)
+		c.Check(id, gc.Equals, "")
+		c.Check(request, gc.Equals, "PrimeTemplates")
+		c.Check(arg, gc.DeepEquals, params.PrimeTemplatesParams{
+			Templates: []params.TemplateSpec{{
+				MachineTag: "machine-1",
+				Kind:       "lxc",
+				Series:     "trusty",
+			}},
+		})
+		c.Assert(result, gc.FitsTypeOf, &params.ErrorResults{})
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{{
+				Error: nil,
+			}},
+		}
+		callCount++
+		return nil
+	})
+
+	im := imagemanager.NewClient(apiCaller)
+	err := im.PrimeTemplate("machine-1", "lxc", "trusty")
+	c.Check(err, jc.ErrorIsNil)
+	c.Check(callCount, gc.Equals, 1)
+}
+
+func (s *imagemanagerSuite) TestPrimeTemplateError(c *gc.C) {
+	apiCaller := testing.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{{
+				Error: &params.Error{Message: "kvm templates not supported"},
+			}},
+		}
+		return nil
+	})
+
+	im := imagemanager.NewClient(apiCaller)
+	err := im.PrimeTemplate("machine-1", "kvm", "trusty")
+	c.Check(err, gc.ErrorMatches, "kvm templates not supported")
+}
@@ -0,0 +1,144 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package migrationmanager
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/names"
+
+	"github.com/juju/juju/api/base"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/network"
+)
+
+const (
+	// CharmBlob and ToolsBlob are the kinds of blobs copied with
+	// ExportBlob and ImportBlob.
+	CharmBlob = "charm"
+	ToolsBlob = "tools"
+)
+
+const (
+	// PhaseCopying, PhaseRedirecting and PhaseRollingBack are the
+	// phases of a migration reported by MigrationStatus.
+	PhaseCopying     = "copying"
+	PhaseRedirecting = "redirecting"
+	PhaseRollingBack = "rolling-back"
+)
+
+// Client provides methods that the Juju client command uses to move
+// environments between state servers.
+type Client struct {
+	base.ClientFacade
+	facade base.FacadeCaller
+}
+
+// NewClient creates a new `Client` based on an existing authenticated API
+// connection.
+func NewClient(st base.APICallCloser) *Client {
+	frontend, backend := base.NewClientFacade(st, "MigrationManager")
+	return &Client{ClientFacade: frontend, facade: backend}
+}
+
+// ExportEnvironment exports the model of the given environment.
+func (c *Client) ExportEnvironment(env names.EnvironTag) (params.SerializedEnvironment, error) {
+	var result params.SerializedEnvironment
+	err := c.facade.FacadeCall("ExportEnvironment", params.Entity{Tag: env.String()}, &result)
+	return result, errors.Trace(err)
+}
+
+// ExportBlob returns the contents of a charm archive or tools tarball
+// of the given environment.
+func (c *Client) ExportBlob(env names.EnvironTag, kind, name string) ([]byte, error) {
+	var result params.BytesResult
+	arg := params.MigrationBlob{EnvTag: env.String(), Kind: kind, Name: name}
+	if err := c.facade.FacadeCall("ExportBlob", arg, &result); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return result.Result, nil
+}
+
+// ImportEnvironment adds an environment exported from another state
+// server. The client must be connected to the state server environment.
+func (c *Client) ImportEnvironment(export params.SerializedEnvironment) (params.Environment, error) {
+	var result params.Environment
+	err := c.facade.FacadeCall("ImportEnvironment", export, &result)
+	return result, errors.Trace(err)
+}
+
+// ImportBlob stores a charm archive or tools tarball of the given
+// imported environment.
+func (c *Client) ImportBlob(env names.EnvironTag, kind, name string, data []byte) error {
+	arg := params.MigrationBlob{EnvTag: env.String(), Kind: kind, Name: name, Data: data}
+	return errors.Trace(c.facade.FacadeCall("ImportBlob", arg, nil))
+}
+
+// StartMigration records that the given environment is being copied to
+// another state server.
+func (c *Client) StartMigration(env names.EnvironTag) error {
+	return c.entityCall("StartMigration", env)
+}
+
+// MigrationStatus returns the phase of the given environment's
+// migration, or an empty phase if it is not migrating.
+func (c *Client) MigrationStatus(env names.EnvironTag) (string, error) {
+	var result params.MigrationStatus
+	if err := c.facade.FacadeCall("MigrationStatus", params.Entity{Tag: env.String()}, &result); err != nil {
+		return "", errors.Trace(err)
+	}
+	return result.Phase, nil
+}
+
+// SetMigrationTarget redirects the agents of the given environment to
+// the API servers with the given addresses and CA certificate.
+func (c *Client) SetMigrationTarget(env names.EnvironTag, hostPorts [][]network.HostPort, caCert string) error {
+	arg := params.MigrationTarget{
+		EnvTag:       env.String(),
+		APIHostPorts: hostPorts,
+		CACert:       caCert,
+	}
+	return errors.Trace(c.facade.FacadeCall("SetMigrationTarget", arg, nil))
+}
+
+// RollBackMigration stops redirecting the agents of the given
+// environment, and records that its migration is being rolled back.
+func (c *Client) RollBackMigration(env names.EnvironTag) error {
+	return c.entityCall("RollBackMigration", env)
+}
+
+// ClearMigrationTarget removes the record of the given environment's
+// migration, so its agents are no longer redirected.
+func (c *Client) ClearMigrationTarget(env names.EnvironTag) error {
+	return c.entityCall("ClearMigrationTarget", env)
+}
+
+// TrackAgentCheckIns starts recording afresh the agents that log in to
+// the given environment.
+func (c *Client) TrackAgentCheckIns(env names.EnvironTag) error {
+	return c.entityCall("TrackAgentCheckIns", env)
+}
+
+// MigrationProgress reports which agents of the given environment are
+// expected to log in, and which have done so.
+func (c *Client) MigrationProgress(env names.EnvironTag) (params.MigrationProgress, error) {
+	var result params.MigrationProgress
+	err := c.facade.FacadeCall("MigrationProgress", params.Entity{Tag: env.String()}, &result)
+	return result, errors.Trace(err)
+}
+
+// FinishImport stops recording the agents that log in to the given
+// environment.
+func (c *Client) FinishImport(env names.EnvironTag) error {
+	return c.entityCall("FinishImport", env)
+}
+
+// DiscardEnvironment removes the given environment from the state
+// server, leaving its machines running.
+func (c *Client) DiscardEnvironment(env names.EnvironTag) error {
+	return c.entityCall("DiscardEnvironment", env)
+}
+
+func (c *Client) entityCall(method string, env names.EnvironTag) error {
+	return errors.Trace(c.facade.FacadeCall(method, params.Entity{Tag: env.String()}, nil))
+}
@@ -0,0 +1,83 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package migrationmanager_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/api/migrationmanager"
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing/factory"
+)
+
+type migrationManagerSuite struct {
+	jujutesting.JujuConnSuite
+
+	otherState *state.State
+	client     *migrationmanager.Client
+}
+
+var _ = gc.Suite(&migrationManagerSuite{})
+
+func (s *migrationManagerSuite) SetUpTest(c *gc.C) {
+	s.JujuConnSuite.SetUpTest(c)
+	s.otherState = s.Factory.MakeEnvironment(c, &factory.EnvParams{Name: "hosted"})
+	s.AddCleanup(func(*gc.C) { s.otherState.Close() })
+	s.client = migrationmanager.NewClient(s.APIState)
+	c.Assert(s.client, gc.NotNil)
+}
+
+func (s *migrationManagerSuite) TestExportEnvironment(c *gc.C) {
+	export, err := s.client.ExportEnvironment(s.otherState.EnvironTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(export.UUID, gc.Equals, s.otherState.EnvironUUID())
+	c.Assert(export.Name, gc.Equals, "hosted")
+	c.Assert(export.Model, gc.Not(gc.HasLen), 0)
+}
+
+func (s *migrationManagerSuite) TestBlobs(c *gc.C) {
+	env := s.otherState.EnvironTag()
+	err := s.client.ImportBlob(env, migrationmanager.CharmBlob, "charms/mysql", []byte("archive"))
+	c.Assert(err, jc.ErrorIsNil)
+	data, err := s.client.ExportBlob(env, migrationmanager.CharmBlob, "charms/mysql")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "archive")
+}
+
+func (s *migrationManagerSuite) TestMigrationTarget(c *gc.C) {
+	env := s.otherState.EnvironTag()
+	hostPorts := [][]network.HostPort{network.NewHostPorts(17070, "10.0.1.1")}
+	err := s.client.SetMigrationTarget(env, hostPorts, "target-cert")
+	c.Assert(err, jc.ErrorIsNil)
+	target, err := s.otherState.MigrationTarget()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(target.CACert, gc.Equals, "target-cert")
+
+	err = s.client.ClearMigrationTarget(env)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.otherState.MigrationTarget()
+	c.Assert(err, gc.ErrorMatches, "migration .* not found")
+}
+
+func (s *migrationManagerSuite) TestMigrationStatus(c *gc.C) {
+	env := s.otherState.EnvironTag()
+	phase, err := s.client.MigrationStatus(env)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(phase, gc.Equals, "")
+
+	err = s.client.StartMigration(env)
+	c.Assert(err, jc.ErrorIsNil)
+	phase, err = s.client.MigrationStatus(env)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(phase, gc.Equals, string(state.MigrationCopying))
+
+	err = s.client.RollBackMigration(env)
+	c.Assert(err, jc.ErrorIsNil)
+	phase, err = s.client.MigrationStatus(env)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(phase, gc.Equals, string(state.MigrationRollingBack))
+}
@@ -72,3 +72,19 @@ func (st *State) WatchInterfaces(tag names.MachineTag) (watcher.NotifyWatcher, e
 	w := watcher.NewNotifyWatcher(st.facade.RawAPICaller(), result)
 	return w, nil
 }
+
+// SetObservedNetworkInterfaces records the network interfaces found on
+// the machine.
+func (st *State) SetObservedNetworkInterfaces(tag names.MachineTag, interfaces []params.ObservedNetworkInterface) error {
+	args := params.SetObservedNetworkInterfaces{
+		Machines: []params.MachineObservedNetworkInterfaces{{
+			MachineTag: tag.String(),
+			Interfaces: interfaces,
+		}},
+	}
+	var results params.ErrorResults
+	if err := st.facade.FacadeCall("SetObservedNetworkInterfaces", args, &results); err != nil {
+		return err
+	}
+	return results.OneError()
+}
@@ -337,3 +337,27 @@ func (s *networkerSuite) TestWatchInterfaces(c *gc.C) {
 	statetesting.AssertStop(c, w)
 	wc.AssertClosed()
 }
+
+func (s *networkerSuite) TestSetObservedNetworkInterfaces(c *gc.C) {
+	err := s.networker.SetObservedNetworkInterfaces(names.NewMachineTag("1"), nil)
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+	c.Assert(err, jc.Satisfies, params.IsCodeUnauthorized)
+
+	err = s.networker.SetObservedNetworkInterfaces(s.machine.Tag().(names.MachineTag), []params.ObservedNetworkInterface{{
+		InterfaceName: "eth0",
+		MACAddress:    "aa:bb:cc:dd:ee:f0",
+		MTU:           1500,
+		IsUp:          true,
+		Addresses:     []string{"0.1.2.3/24"},
+	}})
+	c.Assert(err, jc.ErrorIsNil)
+	interfaces, _, err := s.machine.ObservedInterfaces()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(interfaces, jc.DeepEquals, []state.ObservedInterface{{
+		InterfaceName: "eth0",
+		MACAddress:    "aa:bb:cc:dd:ee:f0",
+		MTU:           1500,
+		IsUp:          true,
+		Addresses:     []string{"0.1.2.3/24"},
+	}})
+}
@@ -177,6 +177,80 @@ func (m *Machine) DistributionGroup() ([]instance.Id, error) {
 	return result.Result, nil
 }
 
+// ZonePlacement returns the availability zones in which the machine
+// may be started, to honour the placement policies of the units
+// assigned to it.
+func (m *Machine) ZonePlacement() (params.ZonePlacement, error) {
+	var results params.ZonePlacementResults
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: m.tag.String()}},
+	}
+	err := m.st.facade.FacadeCall("ZonePlacement", args, &results)
+	if err != nil {
+		return params.ZonePlacement{}, err
+	}
+	if len(results.Results) != 1 {
+		return params.ZonePlacement{}, fmt.Errorf("expected 1 result, got %d", len(results.Results))
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return params.ZonePlacement{}, result.Error
+	}
+	return result.Result, nil
+}
+
+// ContainerTemplates returns the container templates recorded for the
+// machine.
+func (m *Machine) ContainerTemplates() ([]params.ContainerTemplate, error) {
+	var results params.ContainerTemplatesResults
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: m.tag.String()}},
+	}
+	err := m.st.facade.FacadeCall("ContainerTemplates", args, &results)
+	if err != nil {
+		return nil, err
+	}
+	if len(results.Results) != 1 {
+		return nil, fmt.Errorf("expected 1 result, got %d", len(results.Results))
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return result.Templates, nil
+}
+
+// SetContainerTemplateBuilt records that the machine's template of the
+// given container kind and series has been built, with the given
+// packages installed.
+func (m *Machine) SetContainerTemplateBuilt(kind instance.ContainerType, series string, packages []string) error {
+	return m.setContainerTemplate(params.SetContainerTemplate{
+		TemplateSpec: params.TemplateSpec{MachineTag: m.tag.String(), Kind: string(kind), Series: series},
+		Packages:     packages,
+	})
+}
+
+// SetContainerTemplateFailed records that the machine's template of the
+// given container kind and series could not be built.
+func (m *Machine) SetContainerTemplateFailed(kind instance.ContainerType, series string, buildErr error) error {
+	return m.setContainerTemplate(params.SetContainerTemplate{
+		TemplateSpec: params.TemplateSpec{MachineTag: m.tag.String(), Kind: string(kind), Series: series},
+		Error:        buildErr.Error(),
+	})
+}
+
+func (m *Machine) setContainerTemplate(arg params.SetContainerTemplate) error {
+	var result params.ErrorResults
+	args := params.SetContainerTemplates{
+		Templates: []params.SetContainerTemplate{arg},
+	}
+	err := m.st.facade.FacadeCall("SetContainerTemplates", args, &result)
+	if err != nil {
+		return err
+	}
+	return result.OneError()
+}
+
 // SetInstanceInfo sets the provider specific instance id, nonce,
 // metadata, networks and interfaces for this machine. Once set, the
 // instance id cannot be changed.
@@ -4,12 +4,14 @@
 package provisioner
 
 import (
+	"github.com/juju/errors"
 	"github.com/juju/names"
 
 	"github.com/juju/juju/api/base"
 	"github.com/juju/juju/api/common"
 	"github.com/juju/juju/api/watcher"
 	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/network"
 	"github.com/juju/juju/tools"
 	"github.com/juju/juju/version"
 )
@@ -147,3 +149,56 @@ func (st *State) FindTools(v version.Number, series string, arch *string) (tools
 	}
 	return result.List, nil
 }
+
+// PrepareContainerInterfaceInfo allocates an address for the container
+// through the provider, and returns the configuration of the
+// container's network interfaces.
+func (st *State) PrepareContainerInterfaceInfo(containerTag names.MachineTag) ([]network.Info, error) {
+	var result params.MachineNetworkInfoResults
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: containerTag.String()}},
+	}
+	if err := st.facade.FacadeCall("PrepareContainerInterfaceInfo", args, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Results) != 1 {
+		return nil, errors.Errorf("expected 1 result, got %d", len(result.Results))
+	}
+	if err := result.Results[0].Error; err != nil {
+		return nil, err
+	}
+	return result.Results[0].Info, nil
+}
+
+// GetContainerInterfaceInfo returns the configuration of the
+// container's network interfaces with allocated addresses, without
+// allocating any.
+func (st *State) GetContainerInterfaceInfo(containerTag names.MachineTag) ([]network.Info, error) {
+	var result params.MachineNetworkInfoResults
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: containerTag.String()}},
+	}
+	if err := st.facade.FacadeCall("GetContainerInterfaceInfo", args, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Results) != 1 {
+		return nil, errors.Errorf("expected 1 result, got %d", len(result.Results))
+	}
+	if err := result.Results[0].Error; err != nil {
+		return nil, err
+	}
+	return result.Results[0].Info, nil
+}
+
+// ReleaseContainerAddresses releases the addresses allocated to the
+// container through the provider.
+func (st *State) ReleaseContainerAddresses(containerTag names.MachineTag) error {
+	var result params.ErrorResults
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: containerTag.String()}},
+	}
+	if err := st.facade.FacadeCall("ReleaseContainerAddresses", args, &result); err != nil {
+		return err
+	}
+	return result.OneError()
+}
@@ -385,6 +385,59 @@ func (s *provisionerSuite) TestDistributionGroup(c *gc.C) {
 	}
 }
 
+func (s *provisionerSuite) TestZonePlacement(c *gc.C) {
+	wordpress := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	err := wordpress.SetPlacementPolicies([]state.PlacementPolicy{{Kind: state.AntiAffinity, Service: "mysql"}})
+	c.Assert(err, jc.ErrorIsNil)
+	mysql := s.AddTestingService(c, "mysql", s.AddTestingCharm(c, "mysql"))
+	assignUnit := func(svc *state.Service) *state.Machine {
+		machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
+		c.Assert(err, jc.ErrorIsNil)
+		unit, err := svc.AddUnit()
+		c.Assert(err, jc.ErrorIsNil)
+		err = unit.AssignToMachine(machine)
+		c.Assert(err, jc.ErrorIsNil)
+		return machine
+	}
+	zone := "zone-b"
+	err = assignUnit(mysql).SetProvisioned("i-mysql", "fake", &instance.HardwareCharacteristics{
+		AvailabilityZone: &zone,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	machine := assignUnit(wordpress)
+	apiMachine, err := s.provisioner.Machine(machine.Tag().(names.MachineTag))
+	c.Assert(err, jc.ErrorIsNil)
+	placement, err := apiMachine.ZonePlacement()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(placement, jc.DeepEquals, params.ZonePlacement{Avoided: []string{"zone-b"}})
+}
+
+func (s *provisionerSuite) TestContainerTemplates(c *gc.C) {
+	err := s.machine.RequestContainerTemplate(instance.LXC, "trusty")
+	c.Assert(err, jc.ErrorIsNil)
+	apiMachine, err := s.provisioner.Machine(s.machine.Tag().(names.MachineTag))
+	c.Assert(err, jc.ErrorIsNil)
+	templates, err := apiMachine.ContainerTemplates()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(templates, gc.HasLen, 1)
+	c.Assert(templates[0].PrimeRequested, gc.NotNil)
+
+	err = apiMachine.SetContainerTemplateBuilt(instance.LXC, "trusty", []string{"cloud-init=0.7.5"})
+	c.Assert(err, jc.ErrorIsNil)
+	err = apiMachine.SetContainerTemplateFailed(instance.LXC, "precise", errors.New("boom"))
+	c.Assert(err, jc.ErrorIsNil)
+
+	stateTemplates, err := s.machine.ContainerTemplates()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(stateTemplates, gc.HasLen, 2)
+	c.Assert(stateTemplates[0].Series(), gc.Equals, "precise")
+	c.Assert(stateTemplates[0].Error(), gc.Equals, "boom")
+	c.Assert(stateTemplates[1].Series(), gc.Equals, "trusty")
+	c.Assert(stateTemplates[1].PrimeRequested().IsZero(), jc.IsTrue)
+	c.Assert(stateTemplates[1].Packages(), jc.DeepEquals, []string{"cloud-init=0.7.5"})
+}
+
 func (s *provisionerSuite) TestDistributionGroupMachineNotFound(c *gc.C) {
 	stateMachine, err := s.State.AddMachine("quantal", state.JobHostUnits)
 	c.Assert(err, jc.ErrorIsNil)
@@ -623,6 +676,45 @@ func (s *provisionerSuite) TestContainerConfig(c *gc.C) {
 	c.Assert(result.PreferIPv6, jc.IsTrue)
 }
 
+func (s *provisionerSuite) TestPrepareAndReleaseContainerAddresses(c *gc.C) {
+	template := state.MachineTemplate{
+		Series: "quantal",
+		Jobs:   []state.MachineJob{state.JobHostUnits},
+	}
+	container, err := s.State.AddMachineInsideMachine(template, s.machine.Id(), instance.LXC)
+	c.Assert(err, jc.ErrorIsNil)
+	containerTag := container.Tag().(names.MachineTag)
+
+	info, err := s.provisioner.PrepareContainerInterfaceInfo(containerTag)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info, gc.HasLen, 1)
+	c.Assert(info[0].CIDR, gc.Equals, "0.10.0.0/8")
+	c.Assert(info[0].InterfaceName, gc.Equals, "eth0")
+	got, err := s.provisioner.GetContainerInterfaceInfo(containerTag)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(got, jc.DeepEquals, info)
+	allocated, err := s.State.AllocatedIPAddresses(container.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(allocated, gc.HasLen, 1)
+	c.Assert(allocated[0].Value(), gc.Equals, info[0].Address.Value)
+
+	err = s.provisioner.ReleaseContainerAddresses(containerTag)
+	c.Assert(err, gc.ErrorMatches, `cannot release addresses of container ".*": container is alive`)
+
+	err = container.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.provisioner.ReleaseContainerAddresses(containerTag)
+	c.Assert(err, jc.ErrorIsNil)
+	allocated, err = s.State.AllocatedIPAddresses(container.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(allocated, gc.HasLen, 0)
+}
+
+func (s *provisionerSuite) TestPrepareContainerInterfaceInfoNotContainer(c *gc.C) {
+	_, err := s.provisioner.PrepareContainerInterfaceInfo(s.machine.Tag().(names.MachineTag))
+	c.Assert(err, gc.ErrorMatches, `cannot allocate address for container ".*": machine ".*" is not a container`)
+}
+
 func (s *provisionerSuite) TestSetSupportedContainers(c *gc.C) {
 	apiMachine, err := s.provisioner.Machine(s.machine.Tag().(names.MachineTag))
 	c.Assert(err, jc.ErrorIsNil)
@@ -0,0 +1,38 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/api/base"
+	"github.com/juju/juju/apiserver/params"
+)
+
+// State provides access to the remoterelations API facade, used to
+// apply the changes of relations with services in other environments
+// to their counterparts in the connected environment.
+type State struct {
+	facade base.FacadeCaller
+}
+
+// NewState returns a version of the state that provides functionality
+// required by the remote relations worker.
+func NewState(caller base.APICaller) *State {
+	return &State{base.NewFacadeCaller(caller, "RemoteRelations")}
+}
+
+// PublishRelationChange applies the given change to the connected
+// environment's copy of the relation. It returns an error satisfying
+// params.IsCodeNotFound if the relation does not exist there.
+func (st *State) PublishRelationChange(change params.RemoteRelationChange) error {
+	args := params.RemoteRelationChanges{
+		Changes: []params.RemoteRelationChange{change},
+	}
+	var results params.ErrorResults
+	if err := st.facade.FacadeCall("PublishRelationChanges", args, &results); err != nil {
+		return errors.Trace(err)
+	}
+	return results.OneError()
+}
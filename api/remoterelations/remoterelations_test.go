@@ -0,0 +1,62 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/params"
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing/factory"
+)
+
+type remoteRelationsSuite struct {
+	jujutesting.JujuConnSuite
+}
+
+var _ = gc.Suite(&remoteRelationsSuite{})
+
+func (s *remoteRelationsSuite) TestPublishRelationChange(c *gc.C) {
+	otherState := s.Factory.MakeEnvironment(c, &factory.EnvParams{Name: "db"})
+	defer otherState.Close()
+	otherFactory := factory.NewFactory(otherState)
+	otherFactory.MakeService(c, &factory.ServiceParams{
+		Name:  "mysql",
+		Charm: otherFactory.MakeCharm(c, &factory.CharmParams{Name: "mysql"}),
+	})
+	s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	offer, err := otherState.AddOffer("mysql", "server")
+	c.Assert(err, jc.ErrorIsNil)
+	rel, err := s.State.ConsumeOffer(offer, "wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+
+	st, _ := s.OpenAPIAsNewMachine(c, state.JobManageEnviron)
+	err = st.RemoteRelations().PublishRelationChange(params.RemoteRelationChange{
+		RelationKey:   rel.String(),
+		SourceEnvUUID: otherState.EnvironUUID(),
+		ChangedUnits: []params.RemoteRelationUnit{{
+			UnitName: "mysql/0",
+			Settings: map[string]interface{}{"host": "10.0.0.1"},
+		}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	settings, err := rel.UnitSettings("mysql/0")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(settings, jc.DeepEquals, map[string]interface{}{"host": "10.0.0.1"})
+
+	err = st.RemoteRelations().PublishRelationChange(params.RemoteRelationChange{
+		RelationKey:   "wordpress:db mongodb:server",
+		SourceEnvUUID: otherState.EnvironUUID(),
+		Destroy:       true,
+	})
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
+}
+
+func (s *remoteRelationsSuite) TestPublishRelationChangeRequiresStateServer(c *gc.C) {
+	st, _ := s.OpenAPIAsNewMachine(c, state.JobHostUnits)
+	err := st.RemoteRelations().PublishRelationChange(params.RemoteRelationChange{})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
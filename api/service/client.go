@@ -9,6 +9,7 @@ package service
 
 import (
 	"github.com/juju/errors"
+	"github.com/juju/names"
 
 	"github.com/juju/juju/api"
 	"github.com/juju/juju/api/base"
@@ -41,3 +42,74 @@ func (c *Client) SetMetricCredentials(service string, credentials []byte) error
 	}
 	return errors.Trace(results.OneError())
 }
+
+// RevokeLeadership revokes the leadership of the given service, so
+// that another of its units may claim it. The name of the unit which
+// was leader is returned.
+func (c *Client) RevokeLeadership(service string) (string, error) {
+	p := params.Entities{
+		Entities: []params.Entity{{Tag: names.NewServiceTag(service).String()}},
+	}
+	var results params.StringResults
+	err := c.facade.FacadeCall("RevokeLeadership", p, &results)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if len(results.Results) != 1 {
+		return "", errors.Errorf("expected 1 result, got %d", len(results.Results))
+	}
+	if err := results.Results[0].Error; err != nil {
+		return "", errors.Trace(err)
+	}
+	return results.Results[0].Result, nil
+}
+
+// ValidateConfig checks changes to the configuration of the given
+// service against the options of its charm, without applying them. The
+// changes are given either as key=value strings or as YAML.
+func (c *Client) ValidateConfig(service string, options map[string]string, yaml string) (params.ServiceValidateConfigResult, error) {
+	p := params.ServiceValidateConfig{
+		ServiceName: service,
+		Options:     options,
+		Config:      yaml,
+	}
+	var result params.ServiceValidateConfigResult
+	err := c.facade.FacadeCall("ValidateConfig", p, &result)
+	return result, errors.Trace(err)
+}
+
+// PlacementPolicies returns the placement policies of the given service.
+func (c *Client) PlacementPolicies(service string) ([]params.PlacementPolicy, error) {
+	p := params.Entities{
+		Entities: []params.Entity{{Tag: names.NewServiceTag(service).String()}},
+	}
+	var results params.PlacementPoliciesResults
+	err := c.facade.FacadeCall("PlacementPolicies", p, &results)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(results.Results) != 1 {
+		return nil, errors.Errorf("expected 1 result, got %d", len(results.Results))
+	}
+	if err := results.Results[0].Error; err != nil {
+		return nil, errors.Trace(err)
+	}
+	return results.Results[0].Policies, nil
+}
+
+// SetPlacementPolicies replaces the placement policies of the given
+// service.
+func (c *Client) SetPlacementPolicies(service string, policies []params.PlacementPolicy) error {
+	p := params.ServicesPlacementPolicies{
+		Args: []params.ServicePlacementPolicies{{
+			ServiceName: service,
+			Policies:    policies,
+		}},
+	}
+	var results params.ErrorResults
+	err := c.facade.FacadeCall("SetPlacementPolicies", p, &results)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(results.OneError())
+}
@@ -70,3 +70,71 @@ func (s *serviceSuite) TestSetServiceMetricCredentialsNoMocks(c *gc.C) {
 	c.Assert(err, jc.ErrorIsNil)
 	c.Assert(service.MetricCredentials(), gc.DeepEquals, []byte("creds"))
 }
+
+func (s *serviceSuite) TestRevokeLeadership(c *gc.C) {
+	var called bool
+	service.PatchFacadeCall(s, s.client, func(request string, a, response interface{}) error {
+		called = true
+		c.Assert(request, gc.Equals, "RevokeLeadership")
+		c.Assert(a, gc.DeepEquals, params.Entities{
+			Entities: []params.Entity{{Tag: "service-mysql"}},
+		})
+		result := response.(*params.StringResults)
+		result.Results = []params.StringResult{{Result: "mysql/0"}}
+		return nil
+	})
+	unitName, err := s.client.RevokeLeadership("mysql")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(unitName, gc.Equals, "mysql/0")
+	c.Assert(called, jc.IsTrue)
+}
+
+func (s *serviceSuite) TestRevokeLeadershipFails(c *gc.C) {
+	service.PatchFacadeCall(s, s.client, func(request string, a, response interface{}) error {
+		result := response.(*params.StringResults)
+		result.Results = []params.StringResult{{Error: common.ServerError(common.ErrPerm)}}
+		return nil
+	})
+	_, err := s.client.RevokeLeadership("mysql")
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
+func (s *serviceSuite) TestValidateConfig(c *gc.C) {
+	var called bool
+	service.PatchFacadeCall(s, s.client, func(request string, a, response interface{}) error {
+		called = true
+		c.Assert(request, gc.Equals, "ValidateConfig")
+		c.Assert(a, gc.DeepEquals, params.ServiceValidateConfig{
+			ServiceName: "mysql",
+			Options:     map[string]string{"dataset-size": "80%"},
+		})
+		result := response.(*params.ServiceValidateConfigResult)
+		result.Changes = []params.ServiceConfigDiff{{
+			Key:        "dataset-size",
+			OldValue:   "70%",
+			OldDefault: true,
+			NewValue:   "80%",
+		}}
+		return nil
+	})
+	result, err := s.client.ValidateConfig("mysql", map[string]string{"dataset-size": "80%"}, "")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Changes, gc.HasLen, 1)
+	c.Assert(called, jc.IsTrue)
+}
+
+func (s *serviceSuite) TestPlacementPolicies(c *gc.C) {
+	svc := s.Factory.MakeService(c, nil)
+	policies := []params.PlacementPolicy{
+		{Kind: "anti-affinity", Service: svc.Name()},
+		{Kind: "max-units-per-zone", MaxUnits: 2},
+	}
+	err := s.client.SetPlacementPolicies(svc.Name(), policies)
+	c.Assert(err, jc.ErrorIsNil)
+	got, err := s.client.PlacementPolicies(svc.Name())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(got, jc.DeepEquals, policies)
+
+	err = s.client.SetPlacementPolicies(svc.Name(), []params.PlacementPolicy{{Kind: "nearby"}})
+	c.Assert(err, gc.ErrorMatches, `cannot set placement policies of service ".*": placement policy kind "nearby" not valid`)
+}
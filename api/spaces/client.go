@@ -0,0 +1,46 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package spaces
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/api/base"
+	"github.com/juju/juju/apiserver/params"
+)
+
+// Client provides access to the spaces API facade, used to create and
+// list spaces.
+type Client struct {
+	base.ClientFacade
+	facade base.FacadeCaller
+}
+
+// NewClient returns a new spaces client.
+func NewClient(st base.APICallCloser) *Client {
+	frontend, backend := base.NewClientFacade(st, "Spaces")
+	return &Client{ClientFacade: frontend, facade: backend}
+}
+
+// CreateSpace creates a space with the given name, holding the existing
+// subnets with the given CIDRs.
+func (c *Client) CreateSpace(name string, subnets []string) error {
+	p := params.CreateSpacesParams{
+		Spaces: []params.CreateSpaceParams{{Name: name, SubnetCIDRs: subnets}},
+	}
+	var results params.ErrorResults
+	if err := c.facade.FacadeCall("CreateSpaces", p, &results); err != nil {
+		return errors.Trace(err)
+	}
+	return results.OneError()
+}
+
+// ListSpaces returns all the spaces in the environment.
+func (c *Client) ListSpaces() ([]params.Space, error) {
+	var result params.ListSpacesResults
+	if err := c.facade.FacadeCall("ListSpaces", nil, &result); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return result.Results, nil
+}
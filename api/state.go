@@ -23,6 +23,7 @@ import (
 	"github.com/juju/juju/api/networker"
 	"github.com/juju/juju/api/provisioner"
 	"github.com/juju/juju/api/reboot"
+	"github.com/juju/juju/api/remoterelations"
 	"github.com/juju/juju/api/rsyslog"
 	"github.com/juju/juju/api/uniter"
 	"github.com/juju/juju/api/upgrader"
@@ -267,6 +268,11 @@ func (st *State) KeyUpdater() *keyupdater.State {
 	return keyupdater.NewState(st)
 }
 
+// RemoteRelations returns access to the RemoteRelations API
+func (st *State) RemoteRelations() *remoterelations.State {
+	return remoterelations.NewState(st)
+}
+
 // CharmRevisionUpdater returns access to the CharmRevisionUpdater API
 func (st *State) CharmRevisionUpdater() *charmrevisionupdater.State {
 	return charmrevisionupdater.NewState(st)
@@ -0,0 +1,35 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package subnets
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/api/base"
+	"github.com/juju/juju/apiserver/params"
+)
+
+// Client provides access to the subnets API facade, used to add subnets
+// to spaces.
+type Client struct {
+	base.ClientFacade
+	facade base.FacadeCaller
+}
+
+// NewClient returns a new subnets client.
+func NewClient(st base.APICallCloser) *Client {
+	frontend, backend := base.NewClientFacade(st, "Subnets")
+	return &Client{ClientFacade: frontend, facade: backend}
+}
+
+// AddSubnet adds the subnet to the space named by subnet.SpaceName,
+// creating the subnet if juju does not yet know about it.
+func (c *Client) AddSubnet(subnet params.Subnet) error {
+	p := params.AddSubnetsParams{Subnets: []params.Subnet{subnet}}
+	var results params.ErrorResults
+	if err := c.facade.FacadeCall("AddSubnets", p, &results); err != nil {
+		return errors.Trace(err)
+	}
+	return results.OneError()
+}
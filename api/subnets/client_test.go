@@ -0,0 +1,50 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package subnets_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/api/base/testing"
+	"github.com/juju/juju/api/subnets"
+	"github.com/juju/juju/apiserver/params"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type subnetsSuite struct {
+	coretesting.BaseSuite
+}
+
+var _ = gc.Suite(&subnetsSuite{})
+
+func (s *subnetsSuite) TestAddSubnet(c *gc.C) {
+	subnet := params.Subnet{
+		CIDR:       "10.0.0.0/24",
+		ProviderId: "subnet-0",
+		VLANTag:    42,
+		Zone:       "zone1",
+		SpaceName:  "internal",
+	}
+	var callCount int
+	apiCaller := testing.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "Subnets")
+		c.Check(id, gc.Equals, "")
+		c.Check(request, gc.Equals, "AddSubnets")
+		c.Check(arg, jc.DeepEquals, params.AddSubnetsParams{
+			Subnets: []params.Subnet{subnet},
+		})
+		c.Assert(result, gc.FitsTypeOf, &params.ErrorResults{})
+		*(result.(*params.ErrorResults)) = params.ErrorResults{
+			Results: []params.ErrorResult{{}},
+		}
+		callCount++
+		return nil
+	})
+
+	client := subnets.NewClient(apiCaller)
+	err := client.AddSubnet(subnet)
+	c.Check(err, jc.ErrorIsNil)
+	c.Check(callCount, gc.Equals, 1)
+}
@@ -44,6 +44,14 @@ func (st *State) SetVersion(tag string, v version.Binary) error {
 }
 
 func (st *State) DesiredVersion(tag string) (version.Number, error) {
+	vers, _, err := st.DesiredVersionInfo(tag)
+	return vers, err
+}
+
+// DesiredVersionInfo returns the version the agent with the given tag
+// should run, and whether it should downgrade to it from any later
+// version because the upgrade to that version was rolled back.
+func (st *State) DesiredVersionInfo(tag string) (version.Number, bool, error) {
 	var results params.VersionResults
 	args := params.Entities{
 		Entities: []params.Entity{{Tag: tag}},
@@ -51,21 +59,21 @@ func (st *State) DesiredVersion(tag string) (version.Number, error) {
 	err := st.facade.FacadeCall("DesiredVersion", args, &results)
 	if err != nil {
 		// TODO: Not directly tested
-		return version.Number{}, err
+		return version.Number{}, false, err
 	}
 	if len(results.Results) != 1 {
 		// TODO: Not directly tested
-		return version.Number{}, fmt.Errorf("expected 1 result, got %d", len(results.Results))
+		return version.Number{}, false, fmt.Errorf("expected 1 result, got %d", len(results.Results))
 	}
 	result := results.Results[0]
 	if err := result.Error; err != nil {
-		return version.Number{}, err
+		return version.Number{}, false, err
 	}
 	if result.Version == nil {
 		// TODO: Not directly tested
-		return version.Number{}, fmt.Errorf("received no error, but got a nil Version")
+		return version.Number{}, false, fmt.Errorf("received no error, but got a nil Version")
 	}
-	return *result.Version, nil
+	return *result.Version, result.Downgrade, nil
 }
 
 // Tools returns the agent tools that should run on the given entity,
@@ -143,3 +143,21 @@ func (s *machineUpgraderSuite) TestDesiredVersion(c *gc.C) {
 	c.Assert(err, jc.ErrorIsNil)
 	c.Assert(stateVersion, gc.Equals, cur.Number)
 }
+
+func (s *machineUpgraderSuite) TestDesiredVersionInfoDowngrade(c *gc.C) {
+	cur := version.Current
+	s.rawMachine.SetAgentVersion(cur)
+	later := cur.Number
+	later.Minor++
+	err := s.BackingState.SetUpgradeBackup(cur.Number, later, "backup-id")
+	c.Assert(err, jc.ErrorIsNil)
+	backup, err := s.BackingState.UpgradeBackup()
+	c.Assert(err, jc.ErrorIsNil)
+	err = backup.SetRolledBack()
+	c.Assert(err, jc.ErrorIsNil)
+
+	stateVersion, downgrade, err := s.st.DesiredVersionInfo(s.rawMachine.Tag().String())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(stateVersion, gc.Equals, cur.Number)
+	c.Assert(downgrade, jc.IsTrue)
+}
@@ -89,6 +89,12 @@ func (c *Client) EnableUser(username string) error {
 	return c.userCall(username, "EnableUser")
 }
 
+// RemoveUser removes a user. The user can no longer log in, and their
+// SSH keys are revoked on all machines.
+func (c *Client) RemoveUser(username string) error {
+	return c.userCall(username, "RemoveUser")
+}
+
 // IncludeDisabled is a type alias to avoid bare true/false values
 // in calls to the client method.
 type IncludeDisabled bool
@@ -166,3 +172,136 @@ func (c *Client) SetPassword(username, password string) error {
 	}
 	return results.OneError()
 }
+
+// GrantEnvironAccess gives the user the given access to the
+// environment: one of "read", "write" or "admin". The user is added
+// to the environment if necessary.
+func (c *Client) GrantEnvironAccess(username, access string) error {
+	return c.modifyEnvironAccess(username, params.GrantEnvironAccess, access)
+}
+
+// RevokeEnvironAccess takes the given access to the environment away
+// from the user, leaving them with the next lower level of access.
+// Revoking read access removes the user from the environment.
+func (c *Client) RevokeEnvironAccess(username, access string) error {
+	return c.modifyEnvironAccess(username, params.RevokeEnvironAccess, access)
+}
+
+func (c *Client) modifyEnvironAccess(username string, action params.EnvironAccessAction, access string) error {
+	if !names.IsValidUserName(username) {
+		return errors.Errorf("%q is not a valid username", username)
+	}
+	args := params.ModifyEnvironAccessRequest{
+		Changes: []params.ModifyEnvironAccess{{
+			UserTag: names.NewLocalUserTag(username).String(),
+			Action:  action,
+			Access:  access,
+		}},
+	}
+	var results params.ErrorResults
+	err := c.facade.FacadeCall("ModifyEnvironAccess", args, &results)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return results.OneError()
+}
+
+// UserSessions returns the API connections of the user, who may be a
+// local user or one from an identity provider, like "bob@ldap".
+func (c *Client) UserSessions(username string) ([]params.UserSession, error) {
+	if !names.IsValidUser(username) {
+		return nil, errors.Errorf("%q is not a valid username", username)
+	}
+	args := params.Entities{
+		[]params.Entity{{names.NewUserTag(username).String()}},
+	}
+	var results params.UserSessionsResults
+	err := c.facade.FacadeCall("UserSessions", args, &results)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if count := len(results.Results); count != 1 {
+		return nil, errors.Errorf("expected 1 result, got %d", count)
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return nil, errors.Trace(result.Error)
+	}
+	return result.Sessions, nil
+}
+
+// CloseUserSessions closes all the API connections of the user, and
+// returns how many were closed.
+func (c *Client) CloseUserSessions(username string) (int, error) {
+	if !names.IsValidUser(username) {
+		return 0, errors.Errorf("%q is not a valid username", username)
+	}
+	args := params.Entities{
+		[]params.Entity{{names.NewUserTag(username).String()}},
+	}
+	var results params.CloseUserSessionsResults
+	err := c.facade.FacadeCall("CloseUserSessions", args, &results)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if count := len(results.Results); count != 1 {
+		return 0, errors.Errorf("expected 1 result, got %d", count)
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return 0, errors.Trace(result.Error)
+	}
+	return result.Closed, nil
+}
+
+// UserSSHKeys returns the SSH keys of the user.
+func (c *Client) UserSSHKeys(username string) ([]string, error) {
+	if !names.IsValidUserName(username) {
+		return nil, errors.Errorf("%q is not a valid username", username)
+	}
+	args := params.Entities{
+		[]params.Entity{{names.NewLocalUserTag(username).String()}},
+	}
+	var results params.StringsResults
+	err := c.facade.FacadeCall("UserSSHKeys", args, &results)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if count := len(results.Results); count != 1 {
+		return nil, errors.Errorf("expected 1 result, got %d", count)
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return nil, errors.Trace(result.Error)
+	}
+	return result.Result, nil
+}
+
+// AddUserSSHKeys adds SSH keys to the user, and returns the result of
+// adding each of them.
+func (c *Client) AddUserSSHKeys(username string, keys ...string) ([]params.ErrorResult, error) {
+	return c.modifyUserSSHKeys("AddUserSSHKeys", username, keys)
+}
+
+// RemoveUserSSHKeys removes SSH keys, identified by fingerprint or
+// comment, from the user, and returns the result of removing each of
+// them.
+func (c *Client) RemoveUserSSHKeys(username string, ids ...string) ([]params.ErrorResult, error) {
+	return c.modifyUserSSHKeys("RemoveUserSSHKeys", username, ids)
+}
+
+func (c *Client) modifyUserSSHKeys(methodCall, username string, keys []string) ([]params.ErrorResult, error) {
+	if !names.IsValidUserName(username) {
+		return nil, errors.Errorf("%q is not a valid username", username)
+	}
+	args := params.UserSSHKeys{
+		UserTag: names.NewLocalUserTag(username).String(),
+		Keys:    keys,
+	}
+	var results params.ErrorResults
+	err := c.facade.FacadeCall(methodCall, args, &results)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return results.Results, nil
+}
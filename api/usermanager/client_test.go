@@ -11,7 +11,10 @@ import (
 	"github.com/juju/juju/api/usermanager"
 	"github.com/juju/juju/apiserver/params"
 	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/state"
+	coretesting "github.com/juju/juju/testing"
 	"github.com/juju/juju/testing/factory"
+	sshtesting "github.com/juju/juju/utils/ssh/testing"
 )
 
 type usermanagerSuite struct {
@@ -107,6 +110,20 @@ func (s *usermanagerSuite) TestCantRemoveAdminUser(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, "failed to disable user: cannot disable state server environment owner")
 }
 
+func (s *usermanagerSuite) TestRemoveUser(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "foobar"})
+	err := user.AddSSHKeys(sshtesting.ValidKeyOne.Key)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.usermanager.RemoveUser(user.Name())
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = user.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.IsDisabled(), jc.IsTrue)
+	c.Assert(user.SSHKeys(), gc.HasLen, 0)
+}
+
 func (s *usermanagerSuite) TestUserInfo(c *gc.C) {
 	user := s.Factory.MakeUser(c, &factory.UserParams{
 		Name: "foobar", DisplayName: "Foo Bar"})
@@ -177,3 +194,127 @@ func (s *usermanagerSuite) TestSetUserPasswordBadName(c *gc.C) {
 	err := s.usermanager.SetPassword("not@home", "new-password")
 	c.Assert(err, gc.ErrorMatches, `"not@home" is not a valid username`)
 }
+
+func (s *usermanagerSuite) TestUserSessions(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", Password: "password"})
+	st := s.OpenAPIAs(c, user.Tag(), "password")
+	defer st.Close()
+
+	sessions, err := s.usermanager.UserSessions("bob")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(sessions, gc.HasLen, 1)
+	c.Assert(sessions[0].UserTag, gc.Equals, user.Tag().String())
+	c.Assert(sessions[0].EnvironTag, gc.Equals, s.State.EnvironTag().String())
+	c.Assert(sessions[0].RemoteAddress, gc.Not(gc.Equals), "")
+	c.Assert(sessions[0].LoginTime.IsZero(), jc.IsFalse)
+	c.Assert(sessions[0].APIServer, gc.Equals, "machine-0")
+}
+
+func (s *usermanagerSuite) TestUserSessionsBadName(c *gc.C) {
+	_, err := s.usermanager.UserSessions("not/valid")
+	c.Assert(err, gc.ErrorMatches, `"not/valid" is not a valid username`)
+}
+
+func (s *usermanagerSuite) TestCloseUserSessions(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", Password: "password"})
+	st := s.OpenAPIAs(c, user.Tag(), "password")
+	defer st.Close()
+
+	closed, err := s.usermanager.CloseUserSessions("bob")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(closed, gc.Equals, 1)
+	// The connection is closed asynchronously, once the API server
+	// notices the request.
+	for a := coretesting.LongAttempt.Start(); a.Next(); {
+		s.BackingState.StartSync()
+		if err = st.Ping(); err != nil {
+			break
+		}
+	}
+	c.Assert(err, gc.NotNil)
+	var sessions []params.UserSession
+	for a := coretesting.LongAttempt.Start(); a.Next(); {
+		sessions, err = s.usermanager.UserSessions("bob")
+		c.Assert(err, jc.ErrorIsNil)
+		if len(sessions) == 0 {
+			break
+		}
+	}
+	c.Assert(sessions, gc.HasLen, 0)
+}
+
+func (s *usermanagerSuite) TestGrantEnvironAccess(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "foobar", NoEnvUser: true})
+
+	err := s.usermanager.GrantEnvironAccess(user.Name(), "read")
+	c.Assert(err, jc.ErrorIsNil)
+	envUser, err := s.State.EnvironmentUser(user.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(envUser.Access(), gc.Equals, state.ReadAccess)
+
+	err = s.usermanager.GrantEnvironAccess(user.Name(), "admin")
+	c.Assert(err, jc.ErrorIsNil)
+	envUser, err = s.State.EnvironmentUser(user.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(envUser.Access(), gc.Equals, state.AdminAccess)
+
+	err = s.usermanager.GrantEnvironAccess(user.Name(), "write")
+	c.Assert(err, gc.ErrorMatches, `could not grant environment access: user already has "admin" access`)
+}
+
+func (s *usermanagerSuite) TestGrantEnvironAccessBadAccess(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "foobar", NoEnvUser: true})
+	err := s.usermanager.GrantEnvironAccess(user.Name(), "superuser")
+	c.Assert(err, gc.ErrorMatches, `access level "superuser" not valid`)
+}
+
+func (s *usermanagerSuite) TestRevokeEnvironAccess(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "foobar", Access: state.WriteAccess})
+
+	err := s.usermanager.RevokeEnvironAccess(user.Name(), "admin")
+	c.Assert(err, gc.ErrorMatches, `could not revoke environment access: user does not have "admin" access`)
+
+	err = s.usermanager.RevokeEnvironAccess(user.Name(), "write")
+	c.Assert(err, jc.ErrorIsNil)
+	envUser, err := s.State.EnvironmentUser(user.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(envUser.Access(), gc.Equals, state.ReadAccess)
+
+	err = s.usermanager.RevokeEnvironAccess(user.Name(), "read")
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.State.EnvironmentUser(user.UserTag())
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *usermanagerSuite) TestRevokeEnvironAccessOwner(c *gc.C) {
+	err := s.usermanager.RevokeEnvironAccess(s.AdminUserTag(c).Name(), "admin")
+	c.Assert(err, gc.ErrorMatches, "could not revoke environment access: cannot revoke access of the environment owner")
+}
+
+func (s *usermanagerSuite) TestUserSSHKeys(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "foobar"})
+	results, err := s.usermanager.AddUserSSHKeys("foobar", sshtesting.ValidKeyOne.Key, "invalid-key")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.HasLen, 2)
+	c.Assert(results[0].Error, gc.IsNil)
+	c.Assert(results[1].Error, gc.ErrorMatches, `cannot add ssh keys to user "foobar": ssh key "invalid-key" not valid`)
+
+	keys, err := s.usermanager.UserSSHKeys("foobar")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(keys, jc.DeepEquals, []string{sshtesting.ValidKeyOne.Key})
+
+	results, err = s.usermanager.RemoveUserSSHKeys("foobar", sshtesting.ValidKeyOne.Fingerprint)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.HasLen, 1)
+	c.Assert(results[0].Error, gc.IsNil)
+	err = user.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.SSHKeys(), gc.HasLen, 0)
+}
+
+func (s *usermanagerSuite) TestUserSSHKeysBadName(c *gc.C) {
+	_, err := s.usermanager.UserSSHKeys("not@home")
+	c.Assert(err, gc.ErrorMatches, `"not@home" is not a valid username`)
+	_, err = s.usermanager.AddUserSSHKeys("not@home", sshtesting.ValidKeyOne.Key)
+	c.Assert(err, gc.ErrorMatches, `"not@home" is not a valid username`)
+}
@@ -0,0 +1,239 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package apiserver
+
+import (
+	"github.com/juju/utils/set"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/rpc"
+	"github.com/juju/juju/rpc/rpcreflect"
+	"github.com/juju/juju/state"
+)
+
+// accessRoot restricts the API calls of a user to those allowed by
+// the user's level of access to the environment.
+type accessRoot struct {
+	rpc.MethodFinder
+	auth common.Authorizer
+}
+
+// newAccessRoot returns a new accessRoot.
+func newAccessRoot(finder rpc.MethodFinder, auth common.Authorizer) *accessRoot {
+	return &accessRoot{finder, auth}
+}
+
+// readOnlyFacades holds the facades whose every method may be called
+// by users with read access.
+var readOnlyFacades = set.NewStrings(
+	"AllWatcher",
+	"NotifyWatcher",
+	"Pinger",
+	"RelationUnitsWatcher",
+	"StringsWatcher",
+)
+
+// readOnlyMethods holds, by facade, the other methods that may be called
+// by users with read access, because they don't change the environment.
+var readOnlyMethods = map[string]set.Strings{
+	"Action": set.NewStrings(
+		"Actions",
+		"FindActionTagsByPrefix",
+		"ListAll",
+		"ListCompleted",
+		"ListPending",
+		"ServicesCharmActions",
+	),
+	"Backups": set.NewStrings(
+		"Info",
+		"List",
+	),
+	"Client": set.NewStrings(
+		"APIHostPorts",
+		"APIServerLoad",
+		"AgentVersion",
+		"CharmInfo",
+		"EnvironmentGet",
+		"EnvironmentInfo",
+		"FindTools",
+		"FullStatus",
+		"GetAnnotations",
+		"GetEnvironmentConstraints",
+		"GetServiceConstraints",
+		"MachineNetworkStatus",
+		"PrivateAddress",
+		"PublicAddress",
+		"RefreshInstances",
+		"ResolveCharms",
+		"ServiceCharmRelations",
+		"ServiceConfigHistory",
+		"ServiceGet",
+		"ServiceGetCharmURL",
+		"StagedUpgradeStatus",
+		"Status",
+		"WatchAll",
+		"WatchAllFiltered",
+	),
+	"EnvironmentManager": set.NewStrings(
+		"ListEnvironments",
+	),
+	"ImageManager": set.NewStrings(
+		"ListImages",
+	),
+	"KeyManager": set.NewStrings(
+		"ListKeys",
+	),
+	"Service": set.NewStrings(
+		"ValidateConfig",
+		"PlacementPolicies",
+	),
+	"Spaces": set.NewStrings(
+		"ListSpaces",
+	),
+	"UserManager": set.NewStrings(
+		// Users can always change their own password.
+		"SetPassword",
+		"UserInfo",
+		// Users can always see and close their own sessions.
+		"UserSessions",
+		"CloseUserSessions",
+		"UserSSHKeys",
+	),
+}
+
+// writeMethods holds, by facade, the methods that may be called by
+// users with write access, because they change the environment but
+// not who may use it.
+var writeMethods = map[string]set.Strings{
+	"Action": set.NewStrings(
+		"Cancel",
+		"Enqueue",
+	),
+	"Client": set.NewStrings(
+		"AddCharm",
+		"AddMachines",
+		"AddMachinesV2",
+		"AddRelation",
+		"AddServiceUnits",
+		"DestroyMachines",
+		"DestroyRelation",
+		"DestroyServiceUnits",
+		"EnvironmentSet",
+		"EnvironmentUnset",
+		"InjectMachines",
+		"ProvisioningScript",
+		"Resolved",
+		"RetryProvisioning",
+		"Run",
+		"RunOnAllMachines",
+		"ServiceConfigRevert",
+		"ServiceDeploy",
+		"ServiceDeployWithBindings",
+		"ServiceDeployWithNetworks",
+		"ServiceDestroy",
+		"ServiceExpose",
+		"ServiceOffer",
+		"ServiceSet",
+		"ServiceSetCharm",
+		"ServiceSetYAML",
+		"ServiceUnexpose",
+		"ServiceUnoffer",
+		"ServiceUnset",
+		"ServiceUpdate",
+		"SetAnnotations",
+		"SetEnvironmentConstraints",
+		"SetServiceConstraints",
+	),
+	"EnvironmentManager": set.NewStrings(
+		"CreateEnvironment",
+	),
+	"ImageManager": set.NewStrings(
+		"DeleteImages",
+		"PrimeTemplates",
+	),
+	"KeyManager": set.NewStrings(
+		"AddKeys",
+		"DeleteKeys",
+		"ImportKeys",
+	),
+	"Service": set.NewStrings(
+		"SetMetricCredentials",
+		"SetPlacementPolicies",
+	),
+	"Spaces": set.NewStrings(
+		"CreateSpaces",
+	),
+	"Subnets": set.NewStrings(
+		"AddSubnets",
+	),
+	"UserManager": set.NewStrings(
+		"AddUserSSHKeys",
+		"RemoveUserSSHKeys",
+	),
+}
+
+// adminMethods holds, by facade, the methods that may only be called
+// by users with admin access. Methods that are not listed anywhere
+// also need admin access, so that a newly added method is not opened
+// up to other users by mistake.
+var adminMethods = map[string]set.Strings{
+	"Backups": set.NewStrings(
+		"Create",
+		"Remove",
+	),
+	"Client": set.NewStrings(
+		"AbortCurrentUpgrade",
+		"DestroyEnvironment",
+		"EnsureAvailability",
+		"ResumeStagedUpgrade",
+		"RollbackUpgrade",
+		"SetEnvironAgentVersion",
+		"ShareEnvironment",
+		"StartStagedUpgrade",
+	),
+	"HighAvailability": set.NewStrings(
+		"EnsureAvailability",
+	),
+	"Service": set.NewStrings(
+		"RevokeLeadership",
+	),
+	"UserManager": set.NewStrings(
+		"AddUser",
+		"DisableUser",
+		"EnableUser",
+		"ModifyEnvironAccess",
+		"RemoveUser",
+	),
+}
+
+// RequiredAccess returns the level of access to the environment a
+// user needs to call the given API method.
+func RequiredAccess(rootName, methodName string) state.Access {
+	if methods, ok := adminMethods[rootName]; ok && methods.Contains(methodName) {
+		return state.AdminAccess
+	}
+	if readOnlyFacades.Contains(rootName) {
+		return state.ReadAccess
+	}
+	if methods, ok := readOnlyMethods[rootName]; ok && methods.Contains(methodName) {
+		return state.ReadAccess
+	}
+	if methods, ok := writeMethods[rootName]; ok && methods.Contains(methodName) {
+		return state.WriteAccess
+	}
+	return state.AdminAccess
+}
+
+// FindMethod returns common.ErrPerm for API calls that need more
+// access to the environment than the user has.
+func (r *accessRoot) FindMethod(rootName string, version int, methodName string) (rpcreflect.MethodCaller, error) {
+	caller, err := r.MethodFinder.FindMethod(rootName, version, methodName)
+	if err != nil {
+		return nil, err
+	}
+	if !r.auth.AuthEnvironAccess(RequiredAccess(rootName, methodName)) {
+		return nil, common.ErrPerm
+	}
+	return caller, nil
+}
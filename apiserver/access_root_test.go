@@ -0,0 +1,105 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package apiserver_test
+
+import (
+	"github.com/juju/names"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver"
+	apiservertesting "github.com/juju/juju/apiserver/testing"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing"
+)
+
+type accessRootSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&accessRootSuite{})
+
+func accessAuthorizer(access state.Access) apiservertesting.FakeAuthorizer {
+	return apiservertesting.FakeAuthorizer{
+		Tag:    names.NewLocalUserTag("bob"),
+		Access: access,
+	}
+}
+
+func (r *accessRootSuite) TestRequiredAccess(c *gc.C) {
+	for i, test := range []struct {
+		rootName   string
+		methodName string
+		access     state.Access
+	}{
+		{"Client", "FullStatus", state.ReadAccess},
+		{"AllWatcher", "Next", state.ReadAccess},
+		{"UserManager", "SetPassword", state.ReadAccess},
+		{"Client", "ServiceDeploy", state.WriteAccess},
+		{"Client", "EnvironmentSet", state.WriteAccess},
+		{"Client", "DestroyEnvironment", state.AdminAccess},
+		{"UserManager", "ModifyEnvironAccess", state.AdminAccess},
+		{"Service", "RevokeLeadership", state.AdminAccess},
+		// Methods that are not known to be safe need admin access.
+		{"Client", "NoSuchMethod", state.AdminAccess},
+		{"NoSuchFacade", "NoSuchMethod", state.AdminAccess},
+	} {
+		c.Logf("test %d: %s.%s", i, test.rootName, test.methodName)
+		c.Check(apiserver.RequiredAccess(test.rootName, test.methodName), gc.Equals, test.access)
+	}
+}
+
+func (r *accessRootSuite) TestReadAccessAllowsReadOnlyMethod(c *gc.C) {
+	root := apiserver.TestingAccessRoot(nil, accessAuthorizer(state.ReadAccess))
+
+	caller, err := root.FindMethod("Client", 0, "FullStatus")
+
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(caller, gc.NotNil)
+}
+
+func (r *accessRootSuite) TestReadAccessDeniesChange(c *gc.C) {
+	root := apiserver.TestingAccessRoot(nil, accessAuthorizer(state.ReadAccess))
+
+	caller, err := root.FindMethod("Client", 0, "ServiceDeploy")
+
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+	c.Assert(caller, gc.IsNil)
+}
+
+func (r *accessRootSuite) TestWriteAccessAllowsChange(c *gc.C) {
+	root := apiserver.TestingAccessRoot(nil, accessAuthorizer(state.WriteAccess))
+
+	caller, err := root.FindMethod("Client", 0, "ServiceDeploy")
+
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(caller, gc.NotNil)
+}
+
+func (r *accessRootSuite) TestWriteAccessDeniesAdminMethod(c *gc.C) {
+	root := apiserver.TestingAccessRoot(nil, accessAuthorizer(state.WriteAccess))
+
+	caller, err := root.FindMethod("Client", 0, "DestroyEnvironment")
+
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+	c.Assert(caller, gc.IsNil)
+}
+
+func (r *accessRootSuite) TestAdminAccessAllowsAdminMethod(c *gc.C) {
+	root := apiserver.TestingAccessRoot(nil, accessAuthorizer(state.AdminAccess))
+
+	caller, err := root.FindMethod("Client", 0, "DestroyEnvironment")
+
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(caller, gc.NotNil)
+}
+
+func (r *accessRootSuite) TestFindNonExistentMethod(c *gc.C) {
+	root := apiserver.TestingAccessRoot(nil, accessAuthorizer(state.AdminAccess))
+
+	caller, err := root.FindMethod("Foo", 0, "Bar")
+
+	c.Assert(err, gc.ErrorMatches, "unknown object type \"Foo\"")
+	c.Assert(caller, gc.IsNil)
+}
@@ -5,6 +5,7 @@ package apiserver
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/juju/errors"
@@ -105,7 +106,7 @@ func (a *admin) doLogin(req params.LoginRequest) (params.LoginResultV1, error) {
 	}
 
 	// authedApi is the API method finder we'll use after getting logged in.
-	var authedApi rpc.MethodFinder = newApiRoot(a.srv, a.root.resources, a.root)
+	var authedApi rpc.MethodFinder = newApiRoot(a.root.state, a.root.resources, a.root)
 
 	// Use the login validation function, if one was specified.
 	if a.srv.validator != nil {
@@ -125,18 +126,19 @@ func (a *admin) doLogin(req params.LoginRequest) (params.LoginResultV1, error) {
 	}
 
 	var isUser bool
-	if kind, err := names.TagKind(req.AuthTag); err != nil || kind != names.UserTagKind {
-		// Users are not rate limited, all other entities are
-		if !a.srv.limiter.Acquire() {
-			logger.Debugf("rate limiting, try again later")
-			return fail, common.ErrTryAgain
-		}
-		defer a.srv.limiter.Release()
-	} else {
+	if kind, err := names.TagKind(req.AuthTag); err == nil && kind == names.UserTagKind {
 		isUser = true
 	}
+	// Users and agents have separate budgets, so that users can still
+	// log in while a herd of agents is reconnecting.
+	budget := a.srv.admission.budget(isUser)
+	if err := budget.acquireLogin(); err != nil {
+		logger.Debugf("rate limiting login of %q: %v", req.AuthTag, err)
+		return fail, err
+	}
+	defer budget.releaseLogin()
 
-	entity, err := doCheckCreds(a.srv.state, req)
+	entity, err := a.checkCreds(req)
 	if err != nil {
 		if a.maintenanceInProgress() {
 			// An upgrade, restore or similar operation is in
@@ -150,8 +152,42 @@ func (a *admin) doLogin(req params.LoginRequest) (params.LoginResultV1, error) {
 		}
 		return fail, err
 	}
+	if isUser {
+		// Users are restricted to the API calls allowed by their
+		// access to the environment. Changes to their access take
+		// effect when they next log in.
+		envUser, err := a.root.state.EnvironmentUser(entity.Tag().(names.UserTag))
+		if err != nil {
+			return fail, errors.Trace(err)
+		}
+		a.root.access = envUser.Access()
+		authedApi = newAccessRoot(authedApi, a.root)
+	}
+	// A restore started after the login limits the calls too.
+	authedApi = newRestoreGuardedRoot(authedApi, a.srv.restore)
+
+	connSlot, err := budget.addConnection()
+	if err != nil {
+		logger.Debugf("rejecting login of %q: %v", req.AuthTag, err)
+		return fail, err
+	}
+	// The slot is freed when the connection's resources are stopped.
+	a.root.resources.Register(connSlot)
 	a.root.entity = entity
 
+	if isUser {
+		// Record the session so that it can be listed and closed by
+		// "juju user sessions" and "juju user logout".
+		id, remoteAddr := atomic.AddInt64(&globalCounter, 1), ""
+		if a.reqNotifier != nil {
+			id, remoteAddr = a.reqNotifier.id, a.reqNotifier.remoteAddr
+		}
+		session, err := a.srv.sessions.add(a.root.state, id, entity.Tag().(names.UserTag), remoteAddr, a.root.rpcConn)
+		if err != nil {
+			return fail, errors.Trace(err)
+		}
+		a.root.resources.Register(session)
+	}
 	if a.reqNotifier != nil {
 		a.reqNotifier.login(entity.Tag().String())
 	}
@@ -163,6 +199,13 @@ func (a *admin) doLogin(req params.LoginRequest) (params.LoginResultV1, error) {
 	if err := startPingerIfAgent(a.root, entity); err != nil {
 		return fail, err
 	}
+	if !isUser {
+		// Agents of an environment migrated to this state server are
+		// known to have moved once they log in.
+		if err := a.root.state.RecordAgentCheckIn(entity.Tag()); err != nil {
+			return fail, errors.Trace(err)
+		}
+	}
 
 	var maybeUserInfo *params.AuthUserInfo
 	// Send back user info if user
@@ -186,7 +229,7 @@ func (a *admin) doLogin(req params.LoginRequest) (params.LoginResultV1, error) {
 		return fail, err
 	}
 
-	a.root.rpcConn.ServeFinder(authedApi, serverError)
+	a.root.rpcConn.ServeFinder(budget.throttle(authedApi), serverError)
 
 	return params.LoginResultV1{
 		Servers:    hostPorts,
@@ -215,6 +258,30 @@ func (a *admin) maintenanceInProgress() bool {
 	return a.srv.validator(req) != nil
 }
 
+// checkCreds checks the credentials of the entity logging in to the
+// environment served by the connection. State server machines manage
+// every environment hosted by the state server, so they may also log
+// in to hosted environments with their credentials in the state
+// server environment.
+func (a *admin) checkCreds(req params.LoginRequest) (state.Entity, error) {
+	entity, err := doCheckCreds(a.root.state, req)
+	if err == nil || a.root.state == a.srv.state {
+		return entity, err
+	}
+	if kind, kindErr := names.TagKind(req.AuthTag); kindErr != nil || kind != names.MachineTagKind {
+		return nil, err
+	}
+	entity, err = doCheckCreds(a.srv.state, req)
+	if err != nil {
+		return nil, err
+	}
+	if !isMachineWithJob(entity, state.JobManageEnviron) {
+		logger.Debugf("machine %q is not a state server", req.AuthTag)
+		return nil, common.ErrBadCreds
+	}
+	return entity, nil
+}
+
 var doCheckCreds = checkCreds
 
 func checkCreds(st *state.State, req params.LoginRequest) (state.Entity, error) {
@@ -222,6 +289,9 @@ func checkCreds(st *state.State, req params.LoginRequest) (state.Entity, error)
 	if err != nil {
 		return nil, err
 	}
+	if userTag, ok := tag.(names.UserTag); ok && !userTag.IsLocal() {
+		return checkExternalUserCreds(st, userTag, req.Credentials)
+	}
 	entity, err := st.FindEntity(tag)
 	if errors.IsNotFound(err) {
 		// We return the same error when an entity does not exist as for a bad
@@ -18,6 +18,7 @@ import (
 
 	"github.com/juju/juju/api"
 	"github.com/juju/juju/apiserver"
+	"github.com/juju/juju/apiserver/authentication"
 	"github.com/juju/juju/apiserver/params"
 	jujutesting "github.com/juju/juju/juju/testing"
 	"github.com/juju/juju/network"
@@ -164,6 +165,112 @@ func (s *loginSuite) TestBadLogin(c *gc.C) {
 	}
 }
 
+func (s *loginSuite) TestFailedLoginsDelayLogin(c *gc.C) {
+	info, cleanup := s.setupServerWithValidator(c, nil)
+	defer cleanup()
+	user := s.Factory.MakeUser(c, &factory.UserParams{Password: "password"})
+
+	login := func(password string) error {
+		info.Tag = nil
+		info.Password = ""
+		st, err := api.Open(info, fastDialOpts)
+		c.Assert(err, jc.ErrorIsNil)
+		defer st.Close()
+		return st.Login(user.Tag().String(), password, "")
+	}
+	for i := 0; i <= authentication.FreeFailedLogins; i++ {
+		err := login("wrong password")
+		c.Assert(err, gc.ErrorMatches, "invalid entity name or password")
+	}
+	err := login("password")
+	c.Assert(err, gc.ErrorMatches, "too many failed logins, try again later")
+	c.Assert(err, jc.Satisfies, params.IsCodeTryAgain)
+	_, ok := params.RetryAfter(err)
+	c.Assert(ok, jc.IsTrue)
+}
+
+// setIdentityProvider configures an htpasswd identity provider, in
+// which bob has the password "password".
+func (s *loginSuite) setIdentityProvider(c *gc.C, htgroups, groupAccess string) {
+	err := s.State.UpdateEnvironConfig(map[string]interface{}{
+		"identity-provider":     "htpasswd",
+		"identity-htpasswd":     "bob:$apr1$abcdefgh$FBwExRW4dCc8aL.OvjpIE1\n",
+		"identity-htgroups":     htgroups,
+		"identity-group-access": groupAccess,
+	}, nil, nil)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *loginSuite) loginExternalUser(c *gc.C, info *api.Info, username, password string) error {
+	info.Tag = nil
+	info.Password = ""
+	st, err := api.Open(info, fastDialOpts)
+	c.Assert(err, jc.ErrorIsNil)
+	defer st.Close()
+	return st.Login(names.NewUserTag(username).String(), password, "")
+}
+
+func (s *loginSuite) TestLoginExternalUser(c *gc.C) {
+	info, cleanup := s.setupServerWithValidator(c, nil)
+	defer cleanup()
+	s.setIdentityProvider(c, "dev: bob\n", "dev=write,ops=admin")
+
+	err := s.loginExternalUser(c, info, "bob@htpasswd", "password")
+	c.Assert(err, jc.ErrorIsNil)
+	envUser, err := s.State.EnvironmentUser(names.NewUserTag("bob@htpasswd"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(envUser.Access(), gc.Equals, state.WriteAccess)
+
+	// Access follows the user's groups on each login.
+	s.setIdentityProvider(c, "dev: bob\nops: bob\n", "dev=write,ops=admin")
+	err = s.loginExternalUser(c, info, "bob@htpasswd", "password")
+	c.Assert(err, jc.ErrorIsNil)
+	envUser, err = s.State.EnvironmentUser(names.NewUserTag("bob@htpasswd"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(envUser.Access(), gc.Equals, state.AdminAccess)
+}
+
+func (s *loginSuite) TestLoginExternalUserFails(c *gc.C) {
+	info, cleanup := s.setupServerWithValidator(c, nil)
+	defer cleanup()
+	s.setIdentityProvider(c, "dev: bob\n", "ops=admin")
+
+	for i, test := range []struct {
+		about    string
+		username string
+		password string
+	}{{
+		about:    "no group with access",
+		username: "bob@htpasswd",
+		password: "password",
+	}, {
+		about:    "wrong password",
+		username: "bob@htpasswd",
+		password: "wrong",
+	}, {
+		about:    "unknown user",
+		username: "alice@htpasswd",
+		password: "password",
+	}, {
+		about:    "other domain",
+		username: "bob@ldap",
+		password: "password",
+	}} {
+		c.Logf("test %d: %s", i, test.about)
+		err := s.loginExternalUser(c, info, test.username, test.password)
+		c.Check(err, gc.ErrorMatches, "invalid entity name or password")
+	}
+	_, err := s.State.EnvironmentUser(names.NewUserTag("bob@htpasswd"))
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *loginSuite) TestLoginExternalUserWithoutProvider(c *gc.C) {
+	info, cleanup := s.setupServerWithValidator(c, nil)
+	defer cleanup()
+	err := s.loginExternalUser(c, info, "bob@htpasswd", "password")
+	c.Assert(err, gc.ErrorMatches, "invalid entity name or password")
+}
+
 func (s *loginSuite) TestLoginAsDeactivatedUser(c *gc.C) {
 	info, cleanup := s.setupServerWithValidator(c, nil)
 	defer cleanup()
@@ -496,23 +603,27 @@ func (s *loginSuite) TestUsersLoginWhileRateLimited(c *gc.C) {
 	c.Check(userCount, gc.Equals, apiserver.LoginRateLimit+1)
 }
 
-func (s *loginSuite) TestUsersAreNotRateLimited(c *gc.C) {
+func (s *loginSuite) TestUsersHaveOwnLoginBudget(c *gc.C) {
 	info, cleanup := s.setupServerWithValidator(c, nil)
 	info.Tag = s.AdminUserTag(c)
 	info.Password = "dummy-secret"
 	defer cleanup()
 	delayChan, cleanup := apiserver.DelayLogins()
 	defer cleanup()
-	// We can login more than LoginRateLimit users
-	nLogins := apiserver.LoginRateLimit * 2
-	errResults, wg := startNLogins(c, nLogins, info)
+	// We can login more than LoginRateLimit users, but no more
+	// than UserLoginRateLimit at once.
+	c.Assert(apiserver.UserLoginRateLimit, jc.GreaterThan, apiserver.LoginRateLimit)
+	errResults, wg := startNLogins(c, apiserver.UserLoginRateLimit+1, info)
 	select {
 	case err := <-errResults:
-		c.Fatalf("we should not have gotten any logins yet: %v", err)
-	case <-time.After(coretesting.ShortWait):
+		c.Check(err, jc.Satisfies, params.IsCodeTryAgain)
+		_, ok := params.RetryAfter(errors.Cause(err))
+		c.Check(ok, jc.IsTrue)
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for login to get rejected.")
 	}
-	c.Logf("letting %d logins complete", nLogins)
-	for i := 0; i < nLogins; i++ {
+	c.Logf("letting %d logins complete", apiserver.UserLoginRateLimit)
+	for i := 0; i < apiserver.UserLoginRateLimit; i++ {
 		delayChan <- struct{}{}
 	}
 	c.Logf("waiting for original requests to finish")
@@ -523,6 +634,34 @@ func (s *loginSuite) TestUsersAreNotRateLimited(c *gc.C) {
 	}
 }
 
+func (s *loginSuite) TestConnectionQuota(c *gc.C) {
+	info, cleanup := s.setupServerWithLimits(c, apiserver.LimitConfig{
+		MaxUserConnections: 1,
+	})
+	defer cleanup()
+	info.Tag = s.AdminUserTag(c)
+	info.Password = "dummy-secret"
+
+	st, err := api.Open(info, fastDialOpts)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = api.Open(info, fastDialOpts)
+	c.Assert(err, jc.Satisfies, params.IsCodeTryAgain)
+	c.Assert(err, gc.ErrorMatches, "too many connections, try again later")
+
+	// Closing the first connection frees its slot.
+	err = st.Close()
+	c.Assert(err, jc.ErrorIsNil)
+	for a := coretesting.LongAttempt.Start(); a.Next(); {
+		st, err = api.Open(info, fastDialOpts)
+		if err == nil {
+			break
+		}
+		c.Assert(err, jc.Satisfies, params.IsCodeTryAgain)
+	}
+	c.Assert(err, jc.ErrorIsNil)
+	st.Close()
+}
+
 func (s *loginSuite) TestNonEnvironUserLoginFails(c *gc.C) {
 	info, cleanup := s.setupServerWithValidator(c, nil)
 	defer cleanup()
@@ -533,6 +672,58 @@ func (s *loginSuite) TestNonEnvironUserLoginFails(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, "invalid entity name or password")
 }
 
+func (s *loginSuite) loginToHostedEnvironment(c *gc.C, job state.MachineJob) (*api.State, names.EnvironTag, error) {
+	info, cleanup := s.setupServerWithValidator(c, nil)
+	s.AddCleanup(func(*gc.C) { cleanup() })
+	machine := s.Factory.MakeMachine(c, &factory.MachineParams{
+		Jobs:     []state.MachineJob{job},
+		Password: "machine-password",
+		Nonce:    "fake_nonce",
+	})
+	hostedState := s.Factory.MakeEnvironment(c, nil)
+	defer hostedState.Close()
+
+	info.Tag = machine.Tag()
+	info.Password = "machine-password"
+	info.Nonce = "fake_nonce"
+	info.EnvironTag = hostedState.EnvironTag()
+	st, err := api.Open(info, fastDialOpts)
+	return st, hostedState.EnvironTag(), err
+}
+
+func (s *loginSuite) TestStateServerMachineLogsInToHostedEnvironment(c *gc.C) {
+	st, hostedTag, err := s.loginToHostedEnvironment(c, state.JobManageEnviron)
+	c.Assert(err, jc.ErrorIsNil)
+	defer st.Close()
+	envTag, err := st.EnvironTag()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(envTag, gc.Equals, hostedTag)
+}
+
+func (s *loginSuite) TestOtherMachineCannotLogInToHostedEnvironment(c *gc.C) {
+	_, _, err := s.loginToHostedEnvironment(c, state.JobHostUnits)
+	c.Assert(err, gc.ErrorMatches, "invalid entity name or password")
+}
+
+func (s *loginSuite) TestReadAccessUserCannotChangeEnvironment(c *gc.C) {
+	info, cleanup := s.setupServerWithValidator(c, nil)
+	defer cleanup()
+	user := s.Factory.MakeUser(c, &factory.UserParams{Password: "dummy-password", Access: state.ReadAccess})
+	info.Password = "dummy-password"
+	info.Tag = user.UserTag()
+	st, err := api.Open(info, fastDialOpts)
+	c.Assert(err, jc.ErrorIsNil)
+	defer st.Close()
+
+	client := st.Client()
+	_, err = client.Status(nil)
+	c.Assert(err, jc.ErrorIsNil)
+	err = client.DestroyMachines("0")
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+	err = client.DestroyEnvironment()
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
 func (s *loginV0Suite) TestLoginReportsEnvironTag(c *gc.C) {
 	st, cleanup := s.setupServer(c)
 	defer cleanup()
@@ -679,18 +870,24 @@ func (s *baseLoginSuite) checkLoginWithValidator(c *gc.C, validator apiserver.Lo
 }
 
 func (s *baseLoginSuite) setupServerWithValidator(c *gc.C, validator apiserver.LoginValidator) (*api.Info, func()) {
+	return s.setupServerWithConfig(c, apiserver.ServerConfig{
+		Validator: validator,
+	})
+}
+
+func (s *baseLoginSuite) setupServerWithLimits(c *gc.C, limits apiserver.LimitConfig) (*api.Info, func()) {
+	return s.setupServerWithConfig(c, apiserver.ServerConfig{
+		Limits: limits,
+	})
+}
+
+func (s *baseLoginSuite) setupServerWithConfig(c *gc.C, cfg apiserver.ServerConfig) (*api.Info, func()) {
 	listener, err := net.Listen("tcp", ":0")
 	c.Assert(err, jc.ErrorIsNil)
-	srv, err := apiserver.NewServer(
-		s.State,
-		listener,
-		apiserver.ServerConfig{
-			Cert:      []byte(coretesting.ServerCert),
-			Key:       []byte(coretesting.ServerKey),
-			Validator: validator,
-			Tag:       names.NewMachineTag("0"),
-		},
-	)
+	cfg.Cert = []byte(coretesting.ServerCert)
+	cfg.Key = []byte(coretesting.ServerKey)
+	cfg.Tag = names.NewMachineTag("0")
+	srv, err := apiserver.NewServer(s.State, listener, cfg)
 	c.Assert(err, jc.ErrorIsNil)
 	if s.setAdminApi != nil {
 		s.setAdminApi(srv)
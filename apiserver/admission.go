@@ -0,0 +1,340 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package apiserver
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/juju/ratelimit"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/rpc"
+	"github.com/juju/juju/rpc/rpcreflect"
+)
+
+// LimitConfig holds the limits the API server places on the load its
+// clients can impose on it. Agents and users have separate budgets so
+// that a flood of reconnecting agents cannot lock users out, and vice
+// versa.
+//
+// For every field, zero means the default value is used and a negative
+// value means no limit is applied.
+type LimitConfig struct {
+	// MaxAgentLogins and MaxUserLogins hold the maximum number of
+	// agent and user logins that are processed concurrently.
+	MaxAgentLogins int
+	MaxUserLogins  int
+
+	// LoginQueueTimeout holds how long a login waits for one of the
+	// concurrent login slots to become free before it is rejected.
+	LoginQueueTimeout time.Duration
+
+	// LoginRetryAfter holds the delay rejected clients are asked to
+	// wait before logging in again. It grows with the number of
+	// logins already waiting.
+	LoginRetryAfter time.Duration
+
+	// MaxAgentConnections and MaxUserConnections hold the maximum
+	// number of logged in agent and user connections.
+	MaxAgentConnections int
+	MaxUserConnections  int
+
+	// AgentRequestRate and UserRequestRate hold the number of
+	// requests per second served on each logged in agent and user
+	// connection. Requests beyond that rate are delayed.
+	AgentRequestRate float64
+	UserRequestRate  float64
+
+	// AgentRequestBurst and UserRequestBurst hold the number of
+	// requests that can be served without delay on a connection that
+	// has been idle.
+	AgentRequestBurst int
+	UserRequestBurst  int
+}
+
+const (
+	// defaultMaxAgentLogins holds how many concurrent agent Login
+	// requests we will accept.
+	defaultMaxAgentLogins = 10
+
+	// defaultMaxUserLogins holds how many concurrent user Login
+	// requests we will accept.
+	defaultMaxUserLogins = 20
+
+	defaultLoginQueueTimeout = 2 * time.Second
+	defaultLoginRetryAfter   = 5 * time.Second
+
+	defaultAgentRequestRate  = 50
+	defaultAgentRequestBurst = 100
+	defaultUserRequestRate   = 100
+	defaultUserRequestBurst  = 200
+)
+
+// withDefaults returns a copy of cfg with any zero fields set to their
+// default values.
+func (cfg LimitConfig) withDefaults() LimitConfig {
+	setInt := func(v *int, def int) {
+		if *v == 0 {
+			*v = def
+		}
+	}
+	setInt(&cfg.MaxAgentLogins, defaultMaxAgentLogins)
+	setInt(&cfg.MaxUserLogins, defaultMaxUserLogins)
+	setInt(&cfg.MaxAgentConnections, -1)
+	setInt(&cfg.MaxUserConnections, -1)
+	setInt(&cfg.AgentRequestBurst, defaultAgentRequestBurst)
+	setInt(&cfg.UserRequestBurst, defaultUserRequestBurst)
+	if cfg.LoginQueueTimeout == 0 {
+		cfg.LoginQueueTimeout = defaultLoginQueueTimeout
+	}
+	if cfg.LoginRetryAfter <= 0 {
+		cfg.LoginRetryAfter = defaultLoginRetryAfter
+	}
+	if cfg.AgentRequestRate == 0 {
+		cfg.AgentRequestRate = defaultAgentRequestRate
+	}
+	if cfg.UserRequestRate == 0 {
+		cfg.UserRequestRate = defaultUserRequestRate
+	}
+	return cfg
+}
+
+// admission decides whether logins and requests are admitted to the
+// API server.
+type admission struct {
+	agents *clientBudget
+	users  *clientBudget
+}
+
+// newAdmission returns an admission applying the limits in cfg. Logins
+// waiting for a slot give up when abort is closed.
+func newAdmission(cfg LimitConfig, abort <-chan struct{}) *admission {
+	cfg = cfg.withDefaults()
+	return &admission{
+		agents: newClientBudget(
+			cfg.MaxAgentLogins, cfg.MaxAgentConnections,
+			cfg.AgentRequestRate, cfg.AgentRequestBurst,
+			cfg.LoginQueueTimeout, cfg.LoginRetryAfter, abort,
+		),
+		users: newClientBudget(
+			cfg.MaxUserLogins, cfg.MaxUserConnections,
+			cfg.UserRequestRate, cfg.UserRequestBurst,
+			cfg.LoginQueueTimeout, cfg.LoginRetryAfter, abort,
+		),
+	}
+}
+
+// budget returns the budget for user or agent connections.
+func (a *admission) budget(isUser bool) *clientBudget {
+	if isUser {
+		return a.users
+	}
+	return a.agents
+}
+
+// load reports the current load on the API server.
+func (a *admission) load() params.APIServerLoad {
+	return params.APIServerLoad{
+		Agents: a.agents.load(),
+		Users:  a.users.load(),
+	}
+}
+
+// clientBudget limits the logins, connections and requests of one
+// kind of client.
+type clientBudget struct {
+	// requestsThrottled is accessed atomically, and is kept first
+	// so that it is 64-bit aligned on 32-bit platforms.
+	requestsThrottled int64
+
+	// loginSlots holds a value for every login in progress; it is nil
+	// if concurrent logins are not limited.
+	loginSlots   chan struct{}
+	queueTimeout time.Duration
+	retryAfter   time.Duration
+	abort        <-chan struct{}
+
+	maxConnections int
+	requestRate    float64
+	requestBurst   int
+
+	mu             sync.Mutex // protects the fields that follow
+	loginsWaiting  int
+	loginsRejected int64
+	connections    int
+}
+
+func newClientBudget(
+	maxLogins, maxConnections int,
+	requestRate float64, requestBurst int,
+	queueTimeout, retryAfter time.Duration,
+	abort <-chan struct{},
+) *clientBudget {
+	b := &clientBudget{
+		queueTimeout:   queueTimeout,
+		retryAfter:     retryAfter,
+		abort:          abort,
+		maxConnections: maxConnections,
+		requestRate:    requestRate,
+		requestBurst:   requestBurst,
+	}
+	if maxLogins > 0 {
+		b.loginSlots = make(chan struct{}, maxLogins)
+	}
+	return b
+}
+
+// acquireLogin waits for a free login slot. If none becomes free in
+// time, it returns an error asking the client to try again later.
+// The slot must be given back with releaseLogin.
+func (b *clientBudget) acquireLogin() error {
+	if b.loginSlots == nil {
+		return nil
+	}
+	select {
+	case b.loginSlots <- struct{}{}:
+		return nil
+	default:
+	}
+	if b.queueTimeout > 0 {
+		b.mu.Lock()
+		b.loginsWaiting++
+		b.mu.Unlock()
+		timer := time.NewTimer(b.queueTimeout)
+		defer timer.Stop()
+		admitted := false
+		select {
+		case b.loginSlots <- struct{}{}:
+			admitted = true
+		case <-timer.C:
+		case <-b.abort:
+		}
+		b.mu.Lock()
+		b.loginsWaiting--
+		b.mu.Unlock()
+		if admitted {
+			return nil
+		}
+	}
+	return b.reject("too many concurrent logins")
+}
+
+// releaseLogin gives back a slot acquired with acquireLogin.
+func (b *clientBudget) releaseLogin() {
+	if b.loginSlots != nil {
+		<-b.loginSlots
+	}
+}
+
+// addConnection records a newly logged in connection. It returns an
+// error asking the client to try again later if the connection quota
+// is exhausted; otherwise the returned connectionSlot must be stopped
+// when the connection closes.
+func (b *clientBudget) addConnection() (*connectionSlot, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.maxConnections > 0 && b.connections >= b.maxConnections {
+		b.loginsRejected++
+		return nil, b.tryAgainError("too many connections")
+	}
+	b.connections++
+	return &connectionSlot{budget: b}, nil
+}
+
+// reject records a rejected login and returns the error to send to
+// the client.
+func (b *clientBudget) reject(message string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.loginsRejected++
+	return b.tryAgainError(message)
+}
+
+// tryAgainError returns an error asking the client to retry later.
+// The more logins are already waiting, the longer the client is asked
+// to wait, so that a herd of reconnecting clients spreads itself out.
+// It must be called with b.mu held.
+func (b *clientBudget) tryAgainError(message string) error {
+	retryAfter := b.retryAfter
+	if n := cap(b.loginSlots); n > 0 {
+		retryAfter *= time.Duration(1 + b.loginsWaiting/n)
+	}
+	return params.NewTryAgainError(message+", try again later", retryAfter)
+}
+
+// throttle returns a MethodFinder that delays the requests served by
+// finder so that they don't exceed the budget's request rate.
+func (b *clientBudget) throttle(finder rpc.MethodFinder) rpc.MethodFinder {
+	if b.requestRate <= 0 || b.requestBurst <= 0 {
+		return finder
+	}
+	fillInterval := time.Duration(float64(time.Second) / b.requestRate)
+	return &throttledRoot{
+		MethodFinder: finder,
+		bucket:       ratelimit.NewBucket(fillInterval, int64(b.requestBurst)),
+		budget:       b,
+	}
+}
+
+// load reports the budget's current load.
+func (b *clientBudget) load() params.ClientLoad {
+	maxLogins := cap(b.loginSlots)
+	if b.loginSlots == nil {
+		maxLogins = -1
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return params.ClientLoad{
+		Connections:       b.connections,
+		MaxConnections:    b.maxConnections,
+		LoginsInProgress:  len(b.loginSlots),
+		MaxLogins:         maxLogins,
+		LoginsWaiting:     b.loginsWaiting,
+		LoginsRejected:    b.loginsRejected,
+		RequestsThrottled: atomic.LoadInt64(&b.requestsThrottled),
+	}
+}
+
+// connectionSlot represents a logged in connection counted against
+// its budget's connection quota. It is registered as a resource of the
+// connection, so that the slot is freed when the connection closes.
+type connectionSlot struct {
+	budget *clientBudget
+	once   sync.Once
+}
+
+// Stop implements common.Resource.
+func (s *connectionSlot) Stop() error {
+	s.once.Do(func() {
+		s.budget.mu.Lock()
+		s.budget.connections--
+		s.budget.mu.Unlock()
+	})
+	return nil
+}
+
+// throttledRoot delays API calls that exceed the request rate of the
+// connection. Requests on a connection are read one at a time, so
+// delaying them pushes back on the client without affecting others.
+type throttledRoot struct {
+	rpc.MethodFinder
+	bucket *ratelimit.Bucket
+	budget *clientBudget
+}
+
+// FindMethod waits until the connection's request rate allows another
+// request before looking up the method.
+func (r *throttledRoot) FindMethod(rootName string, version int, methodName string) (rpcreflect.MethodCaller, error) {
+	if delay := r.bucket.Take(1); delay > 0 {
+		atomic.AddInt64(&r.budget.requestsThrottled, 1)
+		logger.Tracef("delaying %s.%s request by %v", rootName, methodName, delay)
+		select {
+		case <-time.After(delay):
+		case <-r.budget.abort:
+		}
+	}
+	return r.MethodFinder.FindMethod(rootName, version, methodName)
+}
@@ -0,0 +1,133 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// This is an internal package test.
+
+package apiserver
+
+import (
+	"time"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/rpc/rpcreflect"
+	"github.com/juju/juju/testing"
+)
+
+type admissionSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&admissionSuite{})
+
+func (s *admissionSuite) TestDefaults(c *gc.C) {
+	cfg := LimitConfig{
+		MaxUserLogins:   -1,
+		UserRequestRate: -1,
+	}.withDefaults()
+	c.Assert(cfg, gc.DeepEquals, LimitConfig{
+		MaxAgentLogins:      defaultMaxAgentLogins,
+		MaxUserLogins:       -1,
+		LoginQueueTimeout:   defaultLoginQueueTimeout,
+		LoginRetryAfter:     defaultLoginRetryAfter,
+		MaxAgentConnections: -1,
+		MaxUserConnections:  -1,
+		AgentRequestRate:    defaultAgentRequestRate,
+		UserRequestRate:     -1,
+		AgentRequestBurst:   defaultAgentRequestBurst,
+		UserRequestBurst:    defaultUserRequestBurst,
+	})
+}
+
+func (s *admissionSuite) TestLoginQueue(c *gc.C) {
+	b := newClientBudget(1, -1, -1, -1, testing.LongWait, time.Second, nil)
+	err := b.acquireLogin()
+	c.Assert(err, jc.ErrorIsNil)
+
+	// A second login waits until the first releases its slot.
+	done := make(chan error, 1)
+	go func() {
+		done <- b.acquireLogin()
+	}()
+	select {
+	case err := <-done:
+		c.Fatalf("login admitted while slot in use: %v", err)
+	case <-time.After(testing.ShortWait):
+	}
+	c.Assert(b.load().LoginsWaiting, gc.Equals, 1)
+	b.releaseLogin()
+	select {
+	case err := <-done:
+		c.Assert(err, jc.ErrorIsNil)
+	case <-time.After(testing.LongWait):
+		c.Fatalf("timed out waiting for login to be admitted")
+	}
+	c.Assert(b.load().LoginsInProgress, gc.Equals, 1)
+}
+
+func (s *admissionSuite) TestLoginRejected(c *gc.C) {
+	b := newClientBudget(1, -1, -1, -1, time.Millisecond, 3*time.Second, nil)
+	err := b.acquireLogin()
+	c.Assert(err, jc.ErrorIsNil)
+	defer b.releaseLogin()
+
+	err = b.acquireLogin()
+	c.Assert(err, gc.ErrorMatches, "too many concurrent logins, try again later")
+	c.Assert(err, jc.Satisfies, params.IsCodeTryAgain)
+	retryAfter, ok := params.RetryAfter(err)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(retryAfter, gc.Equals, 3*time.Second)
+	c.Assert(b.load().LoginsRejected, gc.Equals, int64(1))
+}
+
+func (s *admissionSuite) TestRetryAfterGrowsWithQueue(c *gc.C) {
+	b := newClientBudget(2, -1, -1, -1, time.Millisecond, time.Second, nil)
+	b.loginsWaiting = 5
+	retryAfter, ok := params.RetryAfter(b.tryAgainError("busy"))
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(retryAfter, gc.Equals, 3*time.Second)
+}
+
+func (s *admissionSuite) TestConnectionQuota(c *gc.C) {
+	b := newClientBudget(-1, 1, -1, -1, 0, time.Second, nil)
+	slot, err := b.addConnection()
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = b.addConnection()
+	c.Assert(err, gc.ErrorMatches, "too many connections, try again later")
+
+	// Stopping the slot twice only frees it once.
+	c.Assert(slot.Stop(), jc.ErrorIsNil)
+	c.Assert(slot.Stop(), jc.ErrorIsNil)
+	c.Assert(b.load().Connections, gc.Equals, 0)
+	_, err = b.addConnection()
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+type countingFinder struct {
+	calls int
+}
+
+func (f *countingFinder) FindMethod(rootName string, version int, methodName string) (rpcreflect.MethodCaller, error) {
+	f.calls++
+	return nil, nil
+}
+
+func (s *admissionSuite) TestThrottle(c *gc.C) {
+	finder := &countingFinder{}
+	unlimited := newClientBudget(-1, -1, -1, -1, 0, time.Second, nil)
+	c.Assert(unlimited.throttle(finder), gc.Equals, finder)
+
+	// Allow a burst of 2 requests, then one every 10ms.
+	b := newClientBudget(-1, -1, 100, 2, 0, time.Second, nil)
+	throttled := b.throttle(finder)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		_, err := throttled.FindMethod("Client", 0, "FullStatus")
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	c.Assert(finder.calls, gc.Equals, 4)
+	c.Assert(time.Since(start) >= 15*time.Millisecond, jc.IsTrue)
+	c.Assert(b.load().RequestsThrottled, gc.Equals, int64(2))
+}
@@ -6,6 +6,7 @@ package apiserver
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
@@ -16,9 +17,9 @@ import (
 
 	"code.google.com/p/go.net/websocket"
 	"github.com/bmizerany/pat"
+	"github.com/juju/errors"
 	"github.com/juju/loggo"
 	"github.com/juju/names"
-	"github.com/juju/utils"
 	"launchpad.net/tomb"
 
 	"github.com/juju/juju/apiserver/common"
@@ -30,10 +31,6 @@ import (
 
 var logger = loggo.GetLogger("juju.apiserver")
 
-// loginRateLimit defines how many concurrent Login requests we will
-// accept
-const loginRateLimit = 10
-
 // Server holds the server side of the API.
 type Server struct {
 	tomb              tomb.Tomb
@@ -43,10 +40,20 @@ type Server struct {
 	tag               names.Tag
 	dataDir           string
 	logDir            string
-	limiter           utils.Limiter
+	admission         *admission
+	sessions          *sessionRegistry
+	restore           *restoreGuard
 	validator         LoginValidator
 	adminApiFactories map[int]adminApiFactory
 
+	// requestStats aggregates the time taken to serve requests on
+	// all connections.
+	requestStats *requestStats
+
+	// slowRequestThreshold is the time after which a request is
+	// logged as slow; see ServerConfig.SlowRequestThreshold.
+	slowRequestThreshold time.Duration
+
 	mu          sync.Mutex // protects the fields that follow
 	environUUID string
 }
@@ -65,8 +72,22 @@ type ServerConfig struct {
 	LogDir      string
 	Validator   LoginValidator
 	CertChanged chan params.StateServingInfo
+
+	// SlowRequestThreshold is the time after which a request is
+	// logged as slow, along with its parameters with any secrets
+	// redacted. If it is zero, DefaultSlowRequestThreshold is used;
+	// if it is negative, slow requests are not logged.
+	SlowRequestThreshold time.Duration
+
+	// Limits holds the limits on the load that agents and users can
+	// place on the server.
+	Limits LimitConfig
 }
 
+// DefaultSlowRequestThreshold is the default time after which a
+// request is logged as slow.
+const DefaultSlowRequestThreshold = 5 * time.Second
+
 // changeCertListener wraps a TLS net.Listener.
 // It allows connection handshakes to be
 // blocked while the TLS certificate is updated.
@@ -186,13 +207,20 @@ func NewServer(s *state.State, lis net.Listener, cfg ServerConfig) (*Server, err
 		tag:       cfg.Tag,
 		dataDir:   cfg.DataDir,
 		logDir:    cfg.LogDir,
-		limiter:   utils.NewLimiter(loginRateLimit),
 		validator: cfg.Validator,
 		adminApiFactories: map[int]adminApiFactory{
 			0: newAdminApiV0,
 			1: newAdminApiV1,
 		},
+		requestStats:         newRequestStats(),
+		slowRequestThreshold: cfg.SlowRequestThreshold,
 	}
+	if srv.slowRequestThreshold == 0 {
+		srv.slowRequestThreshold = DefaultSlowRequestThreshold
+	}
+	srv.admission = newAdmission(cfg.Limits, srv.tomb.Dying())
+	srv.sessions = newSessionRegistry(s, cfg.Tag.String())
+	srv.restore = newRestoreGuard(s)
 	// TODO(rog) check that *srvRoot is a valid type for using
 	// as an RPC server.
 	tlsConfig := tls.Config{
@@ -230,17 +258,33 @@ type requestNotifier struct {
 	id    int64
 	start time.Time
 
+	// remoteAddr holds the address of the client.
+	remoteAddr string
+
+	// stats, if not nil, accumulates the time taken by each request.
+	stats *requestStats
+
+	// slowThreshold, if positive, is the time after which a request
+	// is logged as slow.
+	slowThreshold time.Duration
+
 	mu   sync.Mutex
 	tag_ string
+	// pending holds the parameters of requests being served, so
+	// that they can be logged if the request is slow.
+	pending map[uint64]interface{}
 }
 
 var globalCounter int64
 
-func newRequestNotifier() *requestNotifier {
+func newRequestNotifier(stats *requestStats, slowThreshold time.Duration) *requestNotifier {
 	return &requestNotifier{
-		id:    atomic.AddInt64(&globalCounter, 1),
-		tag_:  "<unknown>",
-		start: time.Now(),
+		id:            atomic.AddInt64(&globalCounter, 1),
+		tag_:          "<unknown>",
+		start:         time.Now(),
+		stats:         stats,
+		slowThreshold: slowThreshold,
+		pending:       make(map[uint64]interface{}),
 	}
 }
 
@@ -258,21 +302,57 @@ func (n *requestNotifier) tag() (tag string) {
 }
 
 func (n *requestNotifier) ServerRequest(hdr *rpc.Header, body interface{}) {
+	if n.slowThreshold > 0 {
+		n.mu.Lock()
+		n.pending[hdr.RequestId] = body
+		n.mu.Unlock()
+	}
 	if hdr.Request.Type == "Pinger" && hdr.Request.Action == "Ping" {
 		return
 	}
-	// TODO(rog) 2013-10-11 remove secrets from some requests.
-	logger.Debugf("<- [%X] %s %s", n.id, n.tag(), jsoncodec.DumpRequest(hdr, body))
+	if logger.EffectiveLogLevel() <= loggo.DEBUG {
+		logger.Debugf("<- [%X] %s trace %s %s", n.id, n.tag(), hdr.TraceId, jsoncodec.DumpRequest(hdr, redactParams(body)))
+	}
 }
 
 func (n *requestNotifier) ServerReply(req rpc.Request, hdr *rpc.Header, body interface{}, timeSpent time.Duration) {
+	// Requests for unknown methods are not counted, so that
+	// clients cannot grow the statistics without bound.
+	if n.stats != nil && hdr.ErrorCode != rpc.CodeNotImplemented {
+		n.stats.record(req, hdr.Error != "", timeSpent)
+	}
+	if n.slowThreshold > 0 {
+		n.mu.Lock()
+		args := n.pending[hdr.RequestId]
+		delete(n.pending, hdr.RequestId)
+		n.mu.Unlock()
+		if timeSpent >= n.slowThreshold {
+			logger.Warningf("[%X] %s slow request %s took %s: %s(%d)[%q].%s %s",
+				n.id, n.tag(), hdr.TraceId, timeSpent,
+				req.Type, req.Version, req.Id, req.Action, dumpParams(args),
+			)
+		}
+	}
 	if req.Type == "Pinger" && req.Action == "Ping" {
 		return
 	}
-	logger.Debugf("-> [%X] %s %s %s %s[%q].%s", n.id, n.tag(), timeSpent, jsoncodec.DumpRequest(hdr, body), req.Type, req.Id, req.Action)
+	if logger.EffectiveLogLevel() <= loggo.DEBUG {
+		logger.Debugf("-> [%X] %s trace %s %s %s %s[%q].%s", n.id, n.tag(), hdr.TraceId, timeSpent, jsoncodec.DumpRequest(hdr, body), req.Type, req.Id, req.Action)
+	}
+}
+
+// dumpParams returns the JSON representation of the given request
+// parameters with any secrets redacted.
+func dumpParams(body interface{}) []byte {
+	data, err := json.Marshal(redactParams(body))
+	if err != nil {
+		return []byte(fmt.Sprintf("%q", "marshal error: "+err.Error()))
+	}
+	return data
 }
 
 func (n *requestNotifier) join(req *http.Request) {
+	n.remoteAddr = req.RemoteAddr
 	logger.Infof("[%X] API connection from %s", n.id, req.RemoteAddr)
 }
 
@@ -310,6 +390,18 @@ func (srv *Server) run(lis net.Listener) {
 		srv.tomb.Kill(err)
 		srv.wg.Done()
 	}()
+	srv.wg.Add(1)
+	go func() {
+		err := srv.sessions.run(srv.tomb.Dying())
+		srv.tomb.Kill(err)
+		srv.wg.Done()
+	}()
+	srv.wg.Add(1)
+	go func() {
+		err := srv.restore.run(srv.tomb.Dying())
+		srv.tomb.Kill(err)
+		srv.wg.Done()
+	}()
 	// for pat based handlers, they are matched in-order of being
 	// registered, first match wins. So more specific ones have to be
 	// registered first.
@@ -343,6 +435,12 @@ func (srv *Server) run(lis net.Listener) {
 		&backupHandler{httpHandler{state: srv.state}},
 	)
 	handleAll(mux, "/environment/:envuuid/api", http.HandlerFunc(srv.apiHandler))
+	handleAll(mux, "/environment/:envuuid/introspection/requests",
+		&introspectionHandler{httpHandler{state: srv.state}, srv.requestTimings},
+	)
+	handleAll(mux, "/environment/:envuuid/introspection/load",
+		&introspectionHandler{httpHandler{state: srv.state}, srv.load},
+	)
 	handleAll(mux, "/environment/:envuuid/images/:kind/:series/:arch/:filename",
 		&imagesDownloadHandler{httpHandler{state: srv.state}},
 	)
@@ -367,13 +465,30 @@ func (srv *Server) run(lis net.Listener) {
 			httpHandler{state: srv.state},
 		}},
 	)
+	handleAll(mux, "/introspection/requests",
+		&introspectionHandler{httpHandler{state: srv.state}, srv.requestTimings},
+	)
+	handleAll(mux, "/introspection/load",
+		&introspectionHandler{httpHandler{state: srv.state}, srv.load},
+	)
 	handleAll(mux, "/", http.HandlerFunc(srv.apiHandler))
 	// The error from http.Serve is not interesting.
 	http.Serve(lis, mux)
 }
 
+// requestTimings returns the aggregated times taken to serve requests
+// since the server started.
+func (srv *Server) requestTimings() interface{} {
+	return srv.requestStats.timings()
+}
+
+// load returns the current load on the server.
+func (srv *Server) load() interface{} {
+	return srv.admission.load()
+}
+
 func (srv *Server) apiHandler(w http.ResponseWriter, req *http.Request) {
-	reqNotifier := newRequestNotifier()
+	reqNotifier := newRequestNotifier(srv.requestStats, srv.slowRequestThreshold)
 	reqNotifier.join(req)
 	defer reqNotifier.leave()
 	wsServer := websocket.Server{
@@ -402,7 +517,11 @@ func (srv *Server) Addr() string {
 	return srv.addr
 }
 
-func (srv *Server) validateEnvironUUID(envUUID string) error {
+// stateForEnvironUUID returns the state for the environment with the
+// given UUID, which must be hosted by this server, and a function that
+// releases it when the connection using it is done.
+func (srv *Server) stateForEnvironUUID(envUUID string) (*state.State, func(), error) {
+	noRelease := func() {}
 	if envUUID == "" {
 		// We allow the environUUID to be empty for 2 cases
 		// 1) Compatibility with older clients
@@ -411,16 +530,34 @@ func (srv *Server) validateEnvironUUID(envUUID string) error {
 		//    threaded that information all the way back to the 'juju
 		//    bootstrap' process to be able to cache the value until
 		//    after we've connected one time.
-		return nil
+		// Both are served the state server environment.
+		return srv.state, noRelease, nil
 	}
 	if srv.getEnvironUUID() == "" {
 		env, err := srv.state.Environment()
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 		srv.setEnvironUUID(env.UUID())
 	}
-	return srv.checkEnvironUUID(envUUID)
+	if srv.checkEnvironUUID(envUUID) == nil {
+		return srv.state, noRelease, nil
+	}
+	// The environment may be one hosted by the state server.
+	if !names.IsValidEnvironment(envUUID) {
+		return nil, nil, common.UnknownEnvironmentError(envUUID)
+	}
+	envTag := names.NewEnvironTag(envUUID)
+	if _, err := srv.state.GetEnvironment(envTag); errors.IsNotFound(err) {
+		return nil, nil, common.UnknownEnvironmentError(envUUID)
+	} else if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	st, err := srv.state.ForEnviron(envTag)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	return st, func() { st.Close() }, nil
 }
 
 // checkEnvironUUID checks if the expected envionUUID matches the
@@ -451,18 +588,13 @@ func (srv *Server) serveConn(wsConn *websocket.Conn, reqNotifier *requestNotifie
 	if loggo.GetLogger("juju.rpc.jsoncodec").EffectiveLogLevel() <= loggo.TRACE {
 		codec.SetLogging(true)
 	}
-	var notifier rpc.RequestNotifier
-	if logger.EffectiveLogLevel() <= loggo.DEBUG {
-		// Incur request monitoring overhead only if we
-		// know we'll need it.
-		notifier = reqNotifier
-	}
-	conn := rpc.NewConn(codec, notifier)
+	conn := rpc.NewConn(codec, reqNotifier)
 
-	var err error
 	var h *apiHandler
-	if err = srv.validateEnvironUUID(envUUID); err == nil {
-		h, err = newApiHandler(srv, conn, reqNotifier)
+	st, releaseState, err := srv.stateForEnvironUUID(envUUID)
+	if err == nil {
+		defer releaseState()
+		h, err = newApiHandler(srv, st, conn, reqNotifier)
 	}
 	if err != nil {
 		conn.Serve(&errRoot{err}, serverError)
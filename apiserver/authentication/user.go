@@ -4,10 +4,55 @@
 package authentication
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+
 	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
 	"github.com/juju/juju/state"
 )
 
+var logger = loggo.GetLogger("juju.apiserver.authentication")
+
+const (
+	// FreeFailedLogins holds the number of consecutive failed logins
+	// a user may make before further logins are delayed.
+	FreeFailedLogins = 3
+
+	// MaxFailedLogins holds the number of consecutive failed logins
+	// after which a user is locked out for LockoutDuration.
+	MaxFailedLogins = 10
+
+	// LockoutDuration holds how long a user is locked out for after
+	// MaxFailedLogins consecutive failed logins.
+	LockoutDuration = 15 * time.Minute
+
+	// maxLoginDelay holds the longest delay between failed logins
+	// before the user is locked out.
+	maxLoginDelay = 2 * time.Minute
+)
+
+// LoginBlockDuration returns how long logins by a user are refused
+// after the given number of consecutive failed logins. The delay
+// doubles with each failure after the first FreeFailedLogins, until
+// the user is locked out after MaxFailedLogins.
+func LoginBlockDuration(failedLogins int) time.Duration {
+	switch {
+	case failedLogins >= MaxFailedLogins:
+		return LockoutDuration
+	case failedLogins < FreeFailedLogins:
+		return 0
+	}
+	delay := time.Second << uint(failedLogins-FreeFailedLogins)
+	if delay > maxLoginDelay {
+		delay = maxLoginDelay
+	}
+	return delay
+}
+
 // UserIdentityProvider performs authentication for users.
 type UserAuthenticator struct {
 	AgentAuthenticator
@@ -16,10 +61,67 @@ type UserAuthenticator struct {
 var _ EntityAuthenticator = (*UserAuthenticator)(nil)
 
 // Authenticate authenticates the provided entity and returns an error on authentication failure.
+//
+// Logins by users who have recently failed to log in are refused with
+// a CodeTryAgain error saying when to try again, so that passwords
+// cannot be brute-forced.
 func (u *UserAuthenticator) Authenticate(entity state.Entity, password, nonce string) error {
-	if _, ok := entity.(*state.User); ok {
-		return u.AgentAuthenticator.Authenticate(entity, password, nonce)
+	user, ok := entity.(*state.User)
+	if !ok {
+		return common.ErrBadRequest
+	}
+	if blockedUntil := user.LoginBlockedUntil(); blockedUntil != nil {
+		if wait := blockedUntil.Sub(time.Now()); wait > 0 {
+			return loginBlockedError(user, wait)
+		}
+	}
+	if err := u.AgentAuthenticator.Authenticate(entity, password, nonce); err != nil {
+		if err == common.ErrBadCreds {
+			recordErr := user.RecordFailedLogin(LoginBlockDuration)
+			switch {
+			case state.IsLoginBlocked(recordErr):
+				return loginBlockedError(user, blockedFor(recordErr))
+			case recordErr != nil:
+				logger.Errorf("%v", recordErr)
+			case user.FailedLogins() == MaxFailedLogins:
+				logger.Warningf("user %q locked out for %v after %d failed logins", user.Name(), LockoutDuration, MaxFailedLogins)
+			}
+		}
+		return err
+	}
+	// Logins by the user may have been blocked by concurrent failed
+	// logins since it was read, so check again before accepting.
+	if err := user.Refresh(); err != nil {
+		return errors.Trace(err)
+	}
+	if blockedUntil := user.LoginBlockedUntil(); blockedUntil != nil {
+		if wait := blockedUntil.Sub(time.Now()); wait > 0 {
+			return loginBlockedError(user, wait)
+		}
 	}
+	if user.FailedLogins() > 0 {
+		if err := user.ResetFailedLogins(); state.IsLoginBlocked(err) {
+			return loginBlockedError(user, blockedFor(err))
+		} else if err != nil {
+			logger.Errorf("%v", err)
+		}
+	}
+	return nil
+}
 
-	return common.ErrBadRequest
+// blockedFor returns how long logins are refused for, given a
+// *state.LoginBlockedError.
+func blockedFor(err error) time.Duration {
+	blocked := errors.Cause(err).(*state.LoginBlockedError)
+	return blocked.Until.Sub(time.Now())
+}
+
+// loginBlockedError returns the error for a login by a user whose
+// logins are refused for the given time.
+func loginBlockedError(user *state.User, wait time.Duration) error {
+	message := "too many failed logins, try again later"
+	if user.FailedLogins() >= MaxFailedLogins {
+		message = fmt.Sprintf("user locked out after %d failed logins, try again later", user.FailedLogins())
+	}
+	return params.NewTryAgainError(message, wait)
 }
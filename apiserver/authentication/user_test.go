@@ -4,11 +4,14 @@
 package authentication_test
 
 import (
+	"time"
+
 	jc "github.com/juju/testing/checkers"
 	"github.com/juju/utils"
 	gc "gopkg.in/check.v1"
 
 	"github.com/juju/juju/apiserver/authentication"
+	"github.com/juju/juju/apiserver/params"
 	jujutesting "github.com/juju/juju/juju/testing"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/testing/factory"
@@ -102,3 +105,107 @@ func (s *userAuthenticatorSuite) TestInvalidRelationLogin(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, "invalid request")
 
 }
+
+func (s *userAuthenticatorSuite) TestLoginBlockDuration(c *gc.C) {
+	for failedLogins, expect := range []time.Duration{
+		0, 0, 0, 0,
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		16 * time.Second,
+		32 * time.Second,
+		authentication.LockoutDuration,
+		authentication.LockoutDuration,
+	} {
+		c.Check(authentication.LoginBlockDuration(failedLogins), gc.Equals, expect, gc.Commentf("%d failed logins", failedLogins))
+	}
+}
+
+func (s *userAuthenticatorSuite) TestFailedLoginsDelayLogin(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Password: "password"})
+	authenticator := &authentication.UserAuthenticator{}
+	for i := 0; i < authentication.FreeFailedLogins; i++ {
+		err := authenticator.Authenticate(user, "wrongpassword", "")
+		c.Assert(err, gc.ErrorMatches, "invalid entity name or password")
+	}
+	c.Assert(user.LoginBlockedUntil(), gc.IsNil)
+
+	// After one more failure, even the right password is refused for
+	// a while.
+	err := authenticator.Authenticate(user, "wrongpassword", "")
+	c.Assert(err, gc.ErrorMatches, "invalid entity name or password")
+	c.Assert(user.LoginBlockedUntil(), gc.NotNil)
+	err = authenticator.Authenticate(user, "password", "")
+	c.Assert(err, gc.ErrorMatches, "too many failed logins, try again later")
+	c.Assert(params.IsCodeTryAgain(err), jc.IsTrue)
+	retryAfter, ok := params.RetryAfter(err)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(retryAfter > 0 && retryAfter <= 2*time.Second, jc.IsTrue)
+}
+
+func (s *userAuthenticatorSuite) TestSuccessfulLoginResetsFailedLogins(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Password: "password"})
+	authenticator := &authentication.UserAuthenticator{}
+	err := authenticator.Authenticate(user, "wrongpassword", "")
+	c.Assert(err, gc.ErrorMatches, "invalid entity name or password")
+	c.Assert(user.FailedLogins(), gc.Equals, 1)
+
+	err = authenticator.Authenticate(user, "password", "")
+	c.Assert(err, jc.ErrorIsNil)
+	err = user.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.FailedLogins(), gc.Equals, 0)
+}
+
+func (s *userAuthenticatorSuite) TestLoginRefusedWhenBlockedConcurrently(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Password: "password"})
+	right, err := s.State.User(user.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+	wrong, err := s.State.User(user.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+	err = user.RecordFailedLogin(func(int) time.Duration { return time.Hour })
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Logins with a User read before logins were blocked are
+	// refused, whether the password is right or wrong.
+	authenticator := &authentication.UserAuthenticator{}
+	err = authenticator.Authenticate(right, "password", "")
+	c.Assert(err, gc.ErrorMatches, "too many failed logins, try again later")
+	c.Assert(params.IsCodeTryAgain(err), jc.IsTrue)
+	err = authenticator.Authenticate(wrong, "wrongpassword", "")
+	c.Assert(err, gc.ErrorMatches, "too many failed logins, try again later")
+	c.Assert(params.IsCodeTryAgain(err), jc.IsTrue)
+
+	// The refused logins are not counted.
+	err = user.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.FailedLogins(), gc.Equals, 1)
+}
+
+func (s *userAuthenticatorSuite) TestLockout(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Password: "password"})
+	authenticator := &authentication.UserAuthenticator{}
+	for i := 0; i < authentication.MaxFailedLogins; i++ {
+		// Record the failures directly, without blocking logins
+		// until the last, rather than waiting for each delay to pass.
+		err := user.RecordFailedLogin(func(failedLogins int) time.Duration {
+			if failedLogins < authentication.MaxFailedLogins {
+				return 0
+			}
+			return authentication.LoginBlockDuration(failedLogins)
+		})
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	err := authenticator.Authenticate(user, "password", "")
+	c.Assert(err, gc.ErrorMatches, "user locked out after 10 failed logins, try again later")
+	retryAfter, ok := params.RetryAfter(err)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(retryAfter > authentication.LockoutDuration-time.Minute, jc.IsTrue)
+
+	// Setting a new password lifts the lockout.
+	err = user.SetPassword("new-password")
+	c.Assert(err, jc.ErrorIsNil)
+	err = authenticator.Authenticate(user, "new-password", "")
+	c.Assert(err, jc.ErrorIsNil)
+}
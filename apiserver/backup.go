@@ -37,7 +37,7 @@ func (h *backupHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if err := h.authenticate(req); err != nil {
+	if err := h.authenticate(req, state.AdminAccess); err != nil {
 		h.authError(resp, h)
 		return
 	}
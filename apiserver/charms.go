@@ -28,6 +28,7 @@ import (
 	"github.com/juju/juju/apiserver/client"
 	apihttp "github.com/juju/juju/apiserver/http"
 	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/state"
 	"github.com/juju/juju/state/storage"
 )
 
@@ -49,7 +50,7 @@ func (h *charmsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case "POST":
-		if err := h.authenticate(r); err != nil {
+		if err := h.authenticate(r, state.WriteAccess); err != nil {
 			h.authError(w, h)
 			return
 		}
@@ -42,6 +42,17 @@ func (s *authHttpSuite) SetUpTest(c *gc.C) {
 	s.userTag = user.Tag().String()
 }
 
+// makeUserWithAccess adds a user with the given access to the
+// environment, and returns its tag and password.
+func (s *authHttpSuite) makeUserWithAccess(c *gc.C, access state.Access) (string, string) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{
+		Name:     "user-" + string(access),
+		Password: s.password,
+		Access:   access,
+	})
+	return user.Tag().String(), s.password
+}
+
 func (s *authHttpSuite) sendRequest(c *gc.C, tag, password, method, uri, contentType string, body io.Reader) (*http.Response, error) {
 	req, err := http.NewRequest(method, uri, body)
 	c.Assert(err, jc.ErrorIsNil)
@@ -112,6 +123,13 @@ func (s *charmsSuite) TestPOSTRequiresAuth(c *gc.C) {
 	s.assertErrorResponse(c, resp, http.StatusUnauthorized, "unauthorized")
 }
 
+func (s *charmsSuite) TestPOSTRequiresWriteAccess(c *gc.C) {
+	tag, password := s.makeUserWithAccess(c, state.ReadAccess)
+	resp, err := s.sendRequest(c, tag, password, "POST", s.charmsURI(c, ""), "", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertErrorResponse(c, resp, http.StatusUnauthorized, "unauthorized")
+}
+
 func (s *charmsSuite) TestGETDoesNotRequireAuth(c *gc.C) {
 	resp, err := s.sendRequest(c, "", "", "GET", s.charmsURI(c, ""), "", nil)
 	c.Assert(err, jc.ErrorIsNil)
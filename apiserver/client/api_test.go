@@ -277,7 +277,8 @@ var scenarioStatus = &api.Status{
 			Scope:     "container",
 		},
 	},
-	Networks: map[string]api.NetworkStatus{},
+	RemoteServices: map[string]api.RemoteServiceStatus{},
+	Networks:       map[string]api.NetworkStatus{},
 }
 
 // setUpScenario makes an environment scenario suitable for
@@ -0,0 +1,23 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package client
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+)
+
+// APIServerLoad reports the current load on the API server serving
+// the call: the connections and logins of agents and users, and how
+// many of them have been rejected or throttled.
+func (c *Client) APIServerLoad() (params.APIServerLoad, error) {
+	if c.api.resources != nil {
+		if load, ok := c.api.resources.Get("apiServerLoad").(common.LoadResource); ok {
+			return load(), nil
+		}
+	}
+	return params.APIServerLoad{}, errors.NotSupportedf("API server load reporting")
+}
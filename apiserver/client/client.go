@@ -13,12 +13,14 @@ import (
 	"github.com/juju/loggo"
 	"github.com/juju/names"
 	"github.com/juju/utils"
+	"github.com/juju/utils/set"
 	"gopkg.in/juju/charm.v4"
 
 	"github.com/juju/juju/api"
 	"github.com/juju/juju/apiserver/common"
 	"github.com/juju/juju/apiserver/highavailability"
 	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/audit"
 	"github.com/juju/juju/environs/config"
 	"github.com/juju/juju/environs/manual"
 	"github.com/juju/juju/instance"
@@ -84,6 +86,39 @@ func (c *Client) WatchAll() (params.AllWatcherId, error) {
 	}, nil
 }
 
+// validWatchAllKinds holds the entity kinds which may be named in a
+// WatchAllFilter.
+var validWatchAllKinds = set.NewStrings("machine", "service", "unit", "relation", "annotation")
+
+// WatchAllFiltered returns an AllWatcher which only reports deltas
+// for the entities matched by the given filter, including in its
+// initial snapshot.
+func (c *Client) WatchAllFiltered(args params.WatchAllFilter) (params.AllWatcherId, error) {
+	for _, kind := range args.Kinds {
+		if !validWatchAllKinds.Contains(kind) {
+			return params.AllWatcherId{}, errors.NotValidf("entity kind %q", kind)
+		}
+	}
+	for _, name := range args.Services {
+		if !names.IsValidService(name) {
+			return params.AllWatcherId{}, errors.NotValidf("service name %q", name)
+		}
+	}
+	for _, id := range args.Machines {
+		if !names.IsValidMachine(id) {
+			return params.AllWatcherId{}, errors.NotValidf("machine id %q", id)
+		}
+	}
+	w := c.api.state.WatchFiltered(multiwatcher.Filter{
+		Kinds:    args.Kinds,
+		Services: args.Services,
+		Machines: args.Machines,
+	})
+	return params.AllWatcherId{
+		AllWatcherId: c.api.resources.Register(w),
+	}, nil
+}
+
 // ServiceSet implements the server side of Client.ServiceSet. Values set to an
 // empty string will be unset.
 //
@@ -97,7 +132,7 @@ func (c *Client) ServiceSet(p params.ServiceSet) error {
 	if err != nil {
 		return err
 	}
-	return serviceSetSettingsStrings(svc, p.Options)
+	return serviceSetSettingsStrings(svc, c.api.auth.GetAuthTag(), p.Options)
 }
 
 // NewServiceSetForClientAPI implements the server side of
@@ -113,7 +148,7 @@ func (c *Client) NewServiceSetForClientAPI(p params.ServiceSet) error {
 	if err != nil {
 		return err
 	}
-	return newServiceSetSettingsStringsForClientAPI(svc, p.Options)
+	return newServiceSetSettingsStringsForClientAPI(svc, c.api.auth.GetAuthTag(), p.Options)
 }
 
 // ServiceUnset implements the server side of Client.ServiceUnset.
@@ -130,7 +165,7 @@ func (c *Client) ServiceUnset(p params.ServiceUnset) error {
 	for _, option := range p.Options {
 		settings[option] = nil
 	}
-	return svc.UpdateConfigSettings(settings)
+	return svc.UpdateConfigSettingsBy(c.api.auth.GetAuthTag().String(), settings)
 }
 
 // ServiceSetYAML implements the server side of Client.ServerSetYAML.
@@ -143,7 +178,7 @@ func (c *Client) ServiceSetYAML(p params.ServiceSetYAML) error {
 	if err != nil {
 		return err
 	}
-	return serviceSetSettingsYAML(svc, p.Config)
+	return serviceSetSettingsYAML(svc, c.api.auth.GetAuthTag(), p.Config)
 }
 
 // ServiceCharmRelations implements the server side of Client.ServiceCharmRelations.
@@ -178,6 +213,7 @@ func (c *Client) Resolved(p params.Resolved) error {
 }
 
 // PublicAddress implements the server side of Client.PublicAddress.
+// The address is used to connect with ssh, so the access is audited.
 func (c *Client) PublicAddress(p params.PublicAddress) (results params.PublicAddressResults, err error) {
 	switch {
 	case names.IsValidMachine(p.Target):
@@ -189,6 +225,9 @@ func (c *Client) PublicAddress(p params.PublicAddress) (results params.PublicAdd
 		if addr == "" {
 			return results, fmt.Errorf("machine %q has no public address", machine)
 		}
+		if err := c.auditSSHAccess(p.Target, addr); err != nil {
+			return results, errors.Trace(err)
+		}
 		return params.PublicAddressResults{PublicAddress: addr}, nil
 
 	case names.IsValidUnit(p.Target):
@@ -200,12 +239,16 @@ func (c *Client) PublicAddress(p params.PublicAddress) (results params.PublicAdd
 		if !ok {
 			return results, fmt.Errorf("unit %q has no public address", unit)
 		}
+		if err := c.auditSSHAccess(p.Target, addr); err != nil {
+			return results, errors.Trace(err)
+		}
 		return params.PublicAddressResults{PublicAddress: addr}, nil
 	}
 	return results, fmt.Errorf("unknown unit or machine %q", p.Target)
 }
 
 // PrivateAddress implements the server side of Client.PrivateAddress.
+// The address is used to connect with ssh, so the access is audited.
 func (c *Client) PrivateAddress(p params.PrivateAddress) (results params.PrivateAddressResults, err error) {
 	switch {
 	case names.IsValidMachine(p.Target):
@@ -217,6 +260,9 @@ func (c *Client) PrivateAddress(p params.PrivateAddress) (results params.Private
 		if addr == "" {
 			return results, fmt.Errorf("machine %q has no internal address", machine)
 		}
+		if err := c.auditSSHAccess(p.Target, addr); err != nil {
+			return results, errors.Trace(err)
+		}
 		return params.PrivateAddressResults{PrivateAddress: addr}, nil
 
 	case names.IsValidUnit(p.Target):
@@ -228,11 +274,41 @@ func (c *Client) PrivateAddress(p params.PrivateAddress) (results params.Private
 		if !ok {
 			return results, fmt.Errorf("unit %q has no internal address", unit)
 		}
+		if err := c.auditSSHAccess(p.Target, addr); err != nil {
+			return results, errors.Trace(err)
+		}
 		return params.PrivateAddressResults{PrivateAddress: addr}, nil
 	}
 	return results, fmt.Errorf("unknown unit or machine %q", p.Target)
 }
 
+// auditSSHAccess records that the user was given the address of the
+// target machine or unit, to connect to with ssh. The address must not
+// be handed out if the access cannot be recorded.
+func (c *Client) auditSSHAccess(target, address string) error {
+	tag := c.api.auth.GetAuthTag()
+	user, ok := tag.(names.UserTag)
+	if !ok {
+		return common.ErrPerm
+	}
+	if err := c.api.state.AddSSHAccess(user, target, address); err != nil {
+		return errors.Trace(err)
+	}
+	audit.Audit(auditUser{tag},
+		"ssh to %q at %q in environment %q", target, address, c.api.state.EnvironUUID())
+	return nil
+}
+
+// auditUser adapts a names.Tag to audit.Tagger.
+type auditUser struct {
+	tag names.Tag
+}
+
+// Tag implements audit.Tagger.
+func (u auditUser) Tag() string {
+	return u.tag.String()
+}
+
 // ServiceExpose changes the juju-managed firewall to expose any ports that
 // were also explicitly marked by units as open.
 // TODO(mattyw, all): This api call should be move to the new service facade. The client api version will then need bumping.
@@ -337,13 +413,14 @@ func (c *Client) ServiceDeploy(args params.ServiceDeploy) error {
 		jjj.DeployServiceParams{
 			ServiceName: args.ServiceName,
 			// TODO(dfc) ServiceOwner should be a tag
-			ServiceOwner:   c.api.auth.GetAuthTag().String(),
-			Charm:          ch,
-			NumUnits:       args.NumUnits,
-			ConfigSettings: settings,
-			Constraints:    args.Constraints,
-			ToMachineSpec:  args.ToMachineSpec,
-			Networks:       requestedNetworks,
+			ServiceOwner:     c.api.auth.GetAuthTag().String(),
+			Charm:            ch,
+			NumUnits:         args.NumUnits,
+			ConfigSettings:   settings,
+			Constraints:      args.Constraints,
+			ToMachineSpec:    args.ToMachineSpec,
+			Networks:         requestedNetworks,
+			EndpointBindings: args.EndpointBindings,
 		})
 	return err
 }
@@ -356,6 +433,14 @@ func (c *Client) ServiceDeployWithNetworks(args params.ServiceDeploy) error {
 	return c.ServiceDeploy(args)
 }
 
+// ServiceDeployWithBindings works exactly like ServiceDeploy, but
+// allows binding the service's endpoints to spaces with
+// args.EndpointBindings. It exists so that clients can tell whether
+// the API server supports bindings.
+func (c *Client) ServiceDeployWithBindings(args params.ServiceDeploy) error {
+	return c.ServiceDeploy(args)
+}
+
 // ServiceUpdate updates the service attributes, including charm URL,
 // minimum number of units, settings and constraints.
 // All parameters in params.ServiceUpdate except the service name are optional.
@@ -383,11 +468,11 @@ func (c *Client) ServiceUpdate(args params.ServiceUpdate) error {
 	}
 	// Set up service's settings.
 	if args.SettingsYAML != "" {
-		if err = serviceSetSettingsYAML(service, args.SettingsYAML); err != nil {
+		if err = serviceSetSettingsYAML(service, c.api.auth.GetAuthTag(), args.SettingsYAML); err != nil {
 			return err
 		}
 	} else if len(args.SettingsStrings) > 0 {
-		if err = serviceSetSettingsStrings(service, args.SettingsStrings); err != nil {
+		if err = serviceSetSettingsStrings(service, c.api.auth.GetAuthTag(), args.SettingsStrings); err != nil {
 			return err
 		}
 	}
@@ -437,9 +522,9 @@ func (c *Client) serviceSetCharm1dot16(service *state.Service, curl *charm.URL,
 	return service.SetCharm(ch, force)
 }
 
-// serviceSetSettingsYAML updates the settings for the given service,
-// taking the configuration from a YAML string.
-func serviceSetSettingsYAML(service *state.Service, settings string) error {
+// serviceSetSettingsYAML updates the settings for the given service on
+// behalf of the given user, taking the configuration from a YAML string.
+func serviceSetSettingsYAML(service *state.Service, user names.Tag, settings string) error {
 	ch, _, err := service.Charm()
 	if err != nil {
 		return err
@@ -448,12 +533,12 @@ func serviceSetSettingsYAML(service *state.Service, settings string) error {
 	if err != nil {
 		return err
 	}
-	return service.UpdateConfigSettings(changes)
+	return service.UpdateConfigSettingsBy(user.String(), changes)
 }
 
-// serviceSetSettingsStrings updates the settings for the given service,
-// taking the configuration from a map of strings.
-func serviceSetSettingsStrings(service *state.Service, settings map[string]string) error {
+// serviceSetSettingsStrings updates the settings for the given service on
+// behalf of the given user, taking the configuration from a map of strings.
+func serviceSetSettingsStrings(service *state.Service, user names.Tag, settings map[string]string) error {
 	ch, _, err := service.Charm()
 	if err != nil {
 		return err
@@ -463,7 +548,7 @@ func serviceSetSettingsStrings(service *state.Service, settings map[string]strin
 	if err != nil {
 		return err
 	}
-	return service.UpdateConfigSettings(changes)
+	return service.UpdateConfigSettingsBy(user.String(), changes)
 }
 
 // newServiceSetSettingsStringsForClientAPI updates the settings for the given
@@ -471,7 +556,7 @@ func serviceSetSettingsStrings(service *state.Service, settings map[string]strin
 //
 // TODO(Nate): replace serviceSetSettingsStrings with this onces the GUI no
 // longer expects to be able to unset values by sending an empty string.
-func newServiceSetSettingsStringsForClientAPI(service *state.Service, settings map[string]string) error {
+func newServiceSetSettingsStringsForClientAPI(service *state.Service, user names.Tag, settings map[string]string) error {
 	ch, _, err := service.Charm()
 	if err != nil {
 		return err
@@ -483,7 +568,7 @@ func newServiceSetSettingsStringsForClientAPI(service *state.Service, settings m
 		return err
 	}
 
-	return service.UpdateConfigSettings(changes)
+	return service.UpdateConfigSettingsBy(user.String(), changes)
 }
 
 // ServiceSetCharm sets the charm for a given service.
@@ -573,6 +658,14 @@ func (c *Client) ServiceDestroy(args params.ServiceDestroy) error {
 		return errors.Trace(err)
 	}
 	svc, err := c.api.state.Service(args.ServiceName)
+	if errors.IsNotFound(err) {
+		// The service may be a remote service, which is destroyed
+		// along with its relations.
+		remote, remoteErr := c.api.state.RemoteService(args.ServiceName)
+		if remoteErr == nil {
+			return remote.Destroy()
+		}
+	}
 	if err != nil {
 		return err
 	}
@@ -621,10 +714,32 @@ func (c *Client) SetEnvironmentConstraints(args params.SetConstraints) error {
 }
 
 // AddRelation adds a relation between the specified endpoints and returns the relation info.
+// One of the endpoints may be an endpoint offered by another environment,
+// of the form <environment>.<service>[:<relation>].
 func (c *Client) AddRelation(args params.AddRelation) (params.AddRelationResults, error) {
 	if err := c.check.ChangeAllowed(); err != nil {
 		return params.AddRelationResults{}, errors.Trace(err)
 	}
+	if len(args.Endpoints) == 2 {
+		for i, name := range args.Endpoints {
+			envName, endpoint, ok := splitOfferedEndpoint(name)
+			if !ok {
+				continue
+			}
+			offer, err := c.api.state.FindOffer(envName, endpoint)
+			if err != nil {
+				return params.AddRelationResults{}, err
+			}
+			if err := c.checkCanConsumeOffer(offer); err != nil {
+				return params.AddRelationResults{}, errors.Trace(err)
+			}
+			rel, err := c.api.state.ConsumeOffer(offer, args.Endpoints[1-i])
+			if err != nil {
+				return params.AddRelationResults{}, err
+			}
+			return addRelationResults(rel), nil
+		}
+	}
 	inEps, err := c.api.state.InferEndpoints(args.Endpoints...)
 	if err != nil {
 		return params.AddRelationResults{}, err
@@ -633,15 +748,91 @@ func (c *Client) AddRelation(args params.AddRelation) (params.AddRelationResults
 	if err != nil {
 		return params.AddRelationResults{}, err
 	}
+	return addRelationResults(rel), nil
+}
+
+// checkCanConsumeOffer returns common.ErrPerm if the user may not
+// relate to the offer. The relation is added to the offering
+// environment too, so the user needs write access to it.
+func (c *Client) checkCanConsumeOffer(offer *state.Offer) error {
+	user, ok := c.api.auth.GetAuthTag().(names.UserTag)
+	if !ok {
+		return common.ErrPerm
+	}
+	st, err := c.api.state.ForEnviron(names.NewEnvironTag(offer.EnvUUID()))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer st.Close()
+	envUser, err := st.EnvironmentUser(user)
+	if errors.IsNotFound(err) {
+		return common.ErrPerm
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	if !envUser.Access().Includes(state.WriteAccess) {
+		return common.ErrPerm
+	}
+	return nil
+}
+
+func addRelationResults(rel *state.Relation) params.AddRelationResults {
 	outEps := make(map[string]charm.Relation)
-	for _, inEp := range inEps {
-		outEp, err := rel.Endpoint(inEp.ServiceName)
-		if err != nil {
-			return params.AddRelationResults{}, err
-		}
-		outEps[inEp.ServiceName] = outEp.Relation
+	for _, ep := range rel.Endpoints() {
+		outEps[ep.ServiceName] = ep.Relation
 	}
-	return params.AddRelationResults{Endpoints: outEps}, nil
+	return params.AddRelationResults{Endpoints: outEps}
+}
+
+// splitOfferedEndpoint splits an endpoint name of the form
+// <environment>.<service>[:<relation>] into the name of the environment
+// and the endpoint in it. It returns false if the name does not refer
+// to another environment.
+func splitOfferedEndpoint(name string) (envName, endpoint string, ok bool) {
+	serviceName := name
+	if i := strings.Index(name, ":"); i != -1 {
+		serviceName = name[:i]
+	}
+	i := strings.LastIndex(serviceName, ".")
+	if i <= 0 {
+		return "", "", false
+	}
+	return name[:i], name[i+1:], true
+}
+
+// ServiceOffer offers an endpoint of a service to the other environments
+// hosted by the state server.
+func (c *Client) ServiceOffer(args params.ServiceOffer) error {
+	if err := c.check.ChangeAllowed(); err != nil {
+		return errors.Trace(err)
+	}
+	serviceName, relationName, err := splitServiceEndpoint(args.Endpoint)
+	if err != nil {
+		return err
+	}
+	_, err = c.api.state.AddOffer(serviceName, relationName)
+	return err
+}
+
+// ServiceUnoffer stops offering an endpoint of a service to other
+// environments. Existing relations to the endpoint are not affected.
+func (c *Client) ServiceUnoffer(args params.ServiceOffer) error {
+	if err := c.check.RemoveAllowed(); err != nil {
+		return errors.Trace(err)
+	}
+	serviceName, relationName, err := splitServiceEndpoint(args.Endpoint)
+	if err != nil {
+		return err
+	}
+	return c.api.state.RemoveOffer(serviceName, relationName)
+}
+
+func splitServiceEndpoint(endpoint string) (serviceName, relationName string, err error) {
+	parts := strings.Split(endpoint, ":")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", errors.Errorf("invalid endpoint %q, expected <service>:<relation>", endpoint)
+	}
+	return parts[0], parts[1], nil
 }
 
 // DestroyRelation removes the relation between the specified endpoints.
@@ -914,7 +1105,11 @@ func (c *Client) ShareEnvironment(args params.ModifyEnvironUsers) (result params
 		}
 		switch arg.Action {
 		case params.AddEnvUser:
-			_, err := c.api.state.AddEnvironmentUser(user, createdBy)
+			access := state.WriteAccess
+			if arg.Access != "" {
+				access = state.Access(arg.Access)
+			}
+			_, err := c.api.state.AddEnvironmentUser(user, createdBy, access)
 			if err != nil {
 				err = errors.Annotate(err, "could not share environment")
 				result.Results[i].Error = common.ServerError(err)
@@ -1023,12 +1218,26 @@ func (c *Client) EnvironmentGet() (params.EnvironmentGetResults, error) {
 		return result, err
 	}
 	result.Config = config.AllAttrs()
+	// Only administrators may see the credentials and other
+	// secrets held in the environment configuration.
+	if !c.api.auth.AuthEnvironAccess(state.AdminAccess) {
+		if err := redactSecretAttrs(config, result.Config); err != nil {
+			return params.EnvironmentGetResults{}, errors.Trace(err)
+		}
+	}
 	return result, nil
 }
 
 // EnvironmentSet implements the server-side part of the
 // set-environment CLI command.
 func (c *Client) EnvironmentSet(args params.EnvironmentSet) error {
+	keys := make([]string, 0, len(args.Config))
+	for key := range args.Config {
+		keys = append(keys, key)
+	}
+	if err := c.checkCanChangeAttrs(keys); err != nil {
+		return errors.Trace(err)
+	}
 	if err := c.check.ChangeAllowed(); err != nil {
 		// if trying to change value for block-changes, we would want to let it go.
 		if v, present := args.Config[config.PreventAllChangesKey]; !present {
@@ -1060,6 +1269,9 @@ func (c *Client) EnvironmentSet(args params.EnvironmentSet) error {
 // EnvironmentUnset implements the server-side part of the
 // set-environment CLI command.
 func (c *Client) EnvironmentUnset(args params.EnvironmentUnset) error {
+	if err := c.checkCanChangeAttrs(args.Keys); err != nil {
+		return errors.Trace(err)
+	}
 	if err := c.check.ChangeAllowed(); err != nil {
 		return errors.Trace(err)
 	}
@@ -1069,6 +1281,21 @@ func (c *Client) EnvironmentUnset(args params.EnvironmentUnset) error {
 	return c.api.state.UpdateEnvironConfig(nil, args.Keys, nil)
 }
 
+// checkCanChangeAttrs returns common.ErrPerm if any of the given
+// configuration keys controls a block or the identity provider, and
+// the user is not an admin of the environment.
+func (c *Client) checkCanChangeAttrs(keys []string) error {
+	if c.api.auth.AuthEnvironAccess(state.AdminAccess) {
+		return nil
+	}
+	for _, key := range keys {
+		if isAdminOnlyChange(key) {
+			return common.ErrPerm
+		}
+	}
+	return nil
+}
+
 // SetEnvironAgentVersion sets the environment agent version.
 func (c *Client) SetEnvironAgentVersion(args params.SetEnvironAgentVersion) error {
 	if err := c.check.ChangeAllowed(); err != nil {
@@ -12,6 +12,7 @@ import (
 	"sync"
 
 	"github.com/juju/errors"
+	"github.com/juju/loggo"
 	"github.com/juju/names"
 	jc "github.com/juju/testing/checkers"
 	"github.com/juju/utils"
@@ -636,6 +637,39 @@ func (s *clientSuite) TestClientServiceSetYAML(c *gc.C) {
 	})
 }
 
+func (s *clientSuite) TestClientServiceConfigHistory(c *gc.C) {
+	dummy := s.AddTestingService(c, "dummy", s.AddTestingCharm(c, "dummy"))
+
+	err := s.APIState.Client().ServiceSet("dummy", map[string]string{"title": "foobar"})
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.APIState.Client().ServiceSet("dummy", map[string]string{"title": "barfoo", "username": "bob"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	revisions, err := s.APIState.Client().ServiceConfigHistory("dummy")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(revisions, gc.HasLen, 2)
+	c.Assert(revisions[0].Revision, gc.Equals, 1)
+	c.Assert(revisions[0].UserTag, gc.Equals, s.AdminUserTag(c).String())
+	c.Assert(revisions[0].CharmURL, gc.Equals, "local:quantal/dummy-1")
+	c.Assert(revisions[0].Changes, jc.DeepEquals, []params.ServiceConfigChange{
+		{Key: "title", Type: "added", NewValue: "foobar"},
+	})
+	c.Assert(revisions[1].Changes, jc.DeepEquals, []params.ServiceConfigChange{
+		{Key: "title", Type: "modified", OldValue: "foobar", NewValue: "barfoo"},
+		{Key: "username", Type: "added", NewValue: "bob"},
+	})
+
+	skipped, err := s.APIState.Client().ServiceConfigRevert("dummy", 1)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(skipped, gc.HasLen, 0)
+	settings, err := dummy.ConfigSettings()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(settings, gc.DeepEquals, charm.Settings{"title": "foobar"})
+
+	_, err = s.APIState.Client().ServiceConfigRevert("dummy", 4)
+	c.Assert(err, gc.ErrorMatches, `cannot revert service "dummy" to config revision 4: config revision 4 not found`)
+}
+
 func (s *clientSuite) assertServiceSetYAMLBlocked(c *gc.C, blocked bool, dummy *state.Service) {
 	err := s.APIState.Client().ServiceSetYAML("dummy", "dummy:\n  title: foobar\n  username: user name\n")
 	if blocked {
@@ -1380,6 +1414,34 @@ func (s *clientSuite) TestClientServiceDeployWithNetworks(c *gc.C) {
 	c.Assert(serviceCons, gc.DeepEquals, cons)
 }
 
+func (s *clientSuite) TestClientServiceDeployWithBindings(c *gc.C) {
+	s.makeMockCharmStore()
+	curl, bundle := addCharm(c, "dummy")
+	_, err := s.State.AddSpace("internal", nil)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.APIState.Client().ServiceDeployWithBindings(
+		curl.String(), "service", 1, "", constraints.Value{}, "", nil,
+		map[string]string{"db": "internal"},
+	)
+	c.Assert(err, gc.ErrorMatches, `cannot bind endpoint "db": charm "cs:precise/dummy-.*" has no such endpoint`)
+	err = s.APIState.Client().ServiceDeployWithBindings(
+		curl.String(), "service", 1, "", constraints.Value{}, "", nil,
+		map[string]string{"": "missing"},
+	)
+	c.Assert(err, gc.ErrorMatches, `cannot bind endpoint "": space "missing" not found`)
+	_, err = s.State.Service("service")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	bindings := map[string]string{"": "internal", "juju-info": "internal"}
+	err = s.APIState.Client().ServiceDeployWithBindings(
+		curl.String(), "service", 1, "", constraints.Value{}, "", nil, bindings,
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	service := s.assertPrincipalDeployed(c, "service", curl, false, bundle, constraints.Value{})
+	c.Assert(service.EndpointBindings(), jc.DeepEquals, bindings)
+}
+
 func (s *clientSuite) setupServiceDeploy(c *gc.C, args string) (*charm.URL, charm.Charm, constraints.Value) {
 	s.makeMockCharmStore()
 	curl, bundle := addCharm(c, "dummy")
@@ -2057,6 +2119,76 @@ func (s *clientSuite) TestSuccessfullyAddRelation(c *gc.C) {
 	s.assertAddRelation(c, endpoints)
 }
 
+func (s *clientSuite) TestAddRelationToOffer(c *gc.C) {
+	s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	otherState := s.Factory.MakeEnvironment(c, &factory.EnvParams{Name: "db"})
+	defer otherState.Close()
+	otherFactory := factory.NewFactory(otherState)
+	otherFactory.MakeService(c, &factory.ServiceParams{
+		Name:  "mysql",
+		Charm: otherFactory.MakeCharm(c, &factory.CharmParams{Name: "mysql"}),
+	})
+	_, err := otherState.AddOffer("mysql", "server")
+	c.Assert(err, jc.ErrorIsNil)
+
+	res, err := s.APIState.Client().AddRelation("wordpress", "db.mysql")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(res.Endpoints["wordpress"].Name, gc.Equals, "db")
+	c.Assert(res.Endpoints["mysql"].Name, gc.Equals, "server")
+
+	remote, err := s.State.RemoteService("mysql")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(remote.SourceEnvName(), gc.Equals, "db")
+	_, err = otherState.RemoteService("wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = s.APIState.Client().AddRelation("wordpress", "db.mysql:foo")
+	c.Assert(err, gc.ErrorMatches, `offer "db.mysql:foo" not found`)
+}
+
+func (s *clientSuite) TestAddRelationToOfferRequiresAccess(c *gc.C) {
+	s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	bob := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob"})
+	otherState := s.Factory.MakeEnvironment(c, &factory.EnvParams{
+		Name:  "db",
+		Owner: bob.UserTag(),
+	})
+	defer otherState.Close()
+	otherFactory := factory.NewFactory(otherState)
+	otherFactory.MakeService(c, &factory.ServiceParams{
+		Name:  "mysql",
+		Charm: otherFactory.MakeCharm(c, &factory.CharmParams{Name: "mysql"}),
+	})
+	_, err := otherState.AddOffer("mysql", "server")
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = s.APIState.Client().AddRelation("wordpress", "db.mysql")
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+	_, err = s.State.RemoteService("mysql")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	_, err = otherState.AddEnvironmentUser(s.AdminUserTag(c), bob.UserTag(), state.ReadAccess)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.APIState.Client().AddRelation("wordpress", "db.mysql")
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
+func (s *clientSuite) TestServiceOffer(c *gc.C) {
+	s.AddTestingService(c, "mysql", s.AddTestingCharm(c, "mysql"))
+	err := s.APIState.Client().ServiceOffer("mysql:server")
+	c.Assert(err, jc.ErrorIsNil)
+	offers, err := s.State.Offers()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(offers, gc.HasLen, 1)
+	c.Assert(offers[0].Endpoint().Name, gc.Equals, "server")
+
+	err = s.APIState.Client().ServiceUnoffer("mysql:server")
+	c.Assert(err, jc.ErrorIsNil)
+	offers, err = s.State.Offers()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(offers, gc.HasLen, 0)
+}
+
 func (s *clientSuite) TestBlockDestroyAddRelation(c *gc.C) {
 	s.blockDestroyEnvironment(c)
 	s.assertAddRelation(c, []string{"wordpress", "mysql"})
@@ -2229,6 +2361,48 @@ func (s *clientSuite) TestClientWatchAll(c *gc.C) {
 	}
 }
 
+func (s *clientSuite) TestClientWatchAllFiltered(c *gc.C) {
+	_, err := s.State.AddMachine("quantal", state.JobManageEnviron)
+	c.Assert(err, jc.ErrorIsNil)
+	s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	s.AddTestingService(c, "mysql", s.AddTestingCharm(c, "mysql"))
+
+	watcher, err := s.APIState.Client().WatchAllFiltered(params.WatchAllFilter{
+		Services: []string{"wordpress"},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	defer func() {
+		err := watcher.Stop()
+		c.Assert(err, jc.ErrorIsNil)
+	}()
+	deltas, err := watcher.Next()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(deltas, gc.HasLen, 1)
+	info, ok := deltas[0].Entity.(*multiwatcher.ServiceInfo)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(info.Name, gc.Equals, "wordpress")
+}
+
+func (s *clientSuite) TestClientWatchAllFilteredInvalid(c *gc.C) {
+	for i, test := range []struct {
+		filter params.WatchAllFilter
+		err    string
+	}{{
+		filter: params.WatchAllFilter{Kinds: []string{"frobnicator"}},
+		err:    `entity kind "frobnicator" not valid`,
+	}, {
+		filter: params.WatchAllFilter{Services: []string{"wordpress/0"}},
+		err:    `service name "wordpress/0" not valid`,
+	}, {
+		filter: params.WatchAllFilter{Machines: []string{"machine-0"}},
+		err:    `machine id "machine-0" not valid`,
+	}} {
+		c.Logf("test %d", i)
+		_, err := s.APIState.Client().WatchAllFiltered(test.filter)
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+}
+
 func (s *clientSuite) TestClientSetServiceConstraints(c *gc.C) {
 	service := s.AddTestingService(c, "dummy", s.AddTestingCharm(c, "dummy"))
 
@@ -2451,6 +2625,47 @@ func (s *clientSuite) TestClientPrivateAddressUnit(c *gc.C) {
 	c.Assert(addr, gc.Equals, "private")
 }
 
+func (s *clientSuite) TestClientAddressAuditsSSHAccess(c *gc.C) {
+	s.setUpScenario(c)
+	var tw loggo.TestWriter
+	c.Assert(loggo.RegisterWriter("audit-tester", &tw, loggo.INFO), gc.IsNil)
+	defer loggo.RemoveWriter("audit-tester")
+
+	m1, err := s.State.Machine("1")
+	c.Assert(err, jc.ErrorIsNil)
+	err = m1.SetAddresses(
+		network.NewAddress("public", network.ScopePublic),
+		network.NewAddress("private", network.ScopeCloudLocal),
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.APIState.Client().PublicAddress("wordpress/0")
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.APIState.Client().PrivateAddress("1")
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.APIState.Client().PublicAddress("wordpress")
+	c.Assert(err, gc.ErrorMatches, `unknown unit or machine "wordpress"`)
+
+	accesses, err := s.State.SSHAccesses()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(accesses, gc.HasLen, 2)
+	c.Assert(accesses[0].UserTag(), gc.Equals, s.AdminUserTag(c))
+	c.Assert(accesses[0].Target(), gc.Equals, "wordpress/0")
+	c.Assert(accesses[0].Address(), gc.Equals, "public")
+	c.Assert(accesses[1].Target(), gc.Equals, "1")
+	c.Assert(accesses[1].Address(), gc.Equals, "private")
+
+	var messages []string
+	for _, log := range tw.Log() {
+		if log.Module == "audit" {
+			messages = append(messages, log.Message)
+		}
+	}
+	c.Assert(messages, jc.DeepEquals, []string{
+		fmt.Sprintf("%s: ssh to %q at %q in environment %q", s.AdminUserTag(c), "wordpress/0", "public", s.State.EnvironUUID()),
+		fmt.Sprintf("%s: ssh to %q at %q in environment %q", s.AdminUserTag(c), "1", "private", s.State.EnvironUUID()),
+	})
+}
+
 func (s *serverSuite) TestClientEnvironmentGet(c *gc.C) {
 	envConfig, err := s.State.EnvironConfig()
 	c.Assert(err, jc.ErrorIsNil)
@@ -2459,6 +2674,74 @@ func (s *serverSuite) TestClientEnvironmentGet(c *gc.C) {
 	c.Assert(result.Config, gc.DeepEquals, envConfig.AllAttrs())
 }
 
+func (s *serverSuite) TestClientEnvironmentGetRedactsSecretsForNonAdmin(c *gc.C) {
+	auth := testing.FakeAuthorizer{
+		Tag:    names.NewLocalUserTag("bob"),
+		Access: state.WriteAccess,
+	}
+	writer, err := client.NewClient(s.State, common.NewResources(), auth)
+	c.Assert(err, jc.ErrorIsNil)
+	envConfig, err := s.State.EnvironConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(envConfig.AllAttrs()["secret"], gc.NotNil)
+
+	result, err := writer.EnvironmentGet()
+	c.Assert(err, jc.ErrorIsNil)
+	expected := envConfig.AllAttrs()
+	for _, name := range []string{"secret", "admin-secret", "ca-private-key"} {
+		delete(expected, name)
+	}
+	c.Assert(result.Config, jc.DeepEquals, expected)
+}
+
+var identityConfig = map[string]interface{}{
+	"identity-provider":     "htpasswd",
+	"identity-group-access": "admins=admin",
+	"identity-htpasswd":     "bob:$apr1$SZ6Yk6Jf$0bT3uz4pMkSaAkkyyq1UO.",
+}
+
+func (s *serverSuite) TestClientEnvironmentGetHidesIdentityForNonAdmin(c *gc.C) {
+	err := s.State.UpdateEnvironConfig(identityConfig, nil, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	auth := testing.FakeAuthorizer{
+		Tag:    names.NewLocalUserTag("bob"),
+		Access: state.WriteAccess,
+	}
+	writer, err := client.NewClient(s.State, common.NewResources(), auth)
+	c.Assert(err, jc.ErrorIsNil)
+
+	result, err := writer.EnvironmentGet()
+	c.Assert(err, jc.ErrorIsNil)
+	for name := range identityConfig {
+		c.Check(result.Config[name], gc.IsNil, gc.Commentf("%s", name))
+	}
+
+	result, err = s.client.EnvironmentGet()
+	c.Assert(err, jc.ErrorIsNil)
+	for name, value := range identityConfig {
+		c.Check(result.Config[name], gc.Equals, value)
+	}
+}
+
+func (s *serverSuite) TestClientEnvironmentSetIdentityRequiresAdmin(c *gc.C) {
+	auth := testing.FakeAuthorizer{
+		Tag:    names.NewLocalUserTag("bob"),
+		Access: state.WriteAccess,
+	}
+	writer, err := client.NewClient(s.State, common.NewResources(), auth)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = writer.EnvironmentSet(params.EnvironmentSet{Config: identityConfig})
+	c.Assert(errors.Cause(err), gc.Equals, common.ErrPerm)
+	err = writer.EnvironmentUnset(params.EnvironmentUnset{Keys: []string{"identity-provider"}})
+	c.Assert(errors.Cause(err), gc.Equals, common.ErrPerm)
+	s.assertEnvValueMissing(c, "identity-provider")
+
+	err = s.client.EnvironmentSet(params.EnvironmentSet{Config: identityConfig})
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertEnvValue(c, "identity-provider", "htpasswd")
+}
+
 func (s *serverSuite) assertEnvValue(c *gc.C, key string, expected interface{}) {
 	envConfig, err := s.State.EnvironConfig()
 	c.Assert(err, jc.ErrorIsNil)
@@ -0,0 +1,67 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package client
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/state"
+)
+
+var itemChangeTypes = map[int]string{
+	state.ItemAdded:    "added",
+	state.ItemModified: "modified",
+	state.ItemDeleted:  "deleted",
+}
+
+// ServiceConfigHistory returns the recorded changes to the configuration
+// of a service, oldest first.
+// TODO(mattyw, all): This api call should be move to the new service facade. The client api version will then need bumping.
+func (c *Client) ServiceConfigHistory(args params.ServiceGet) (params.ServiceConfigHistoryResults, error) {
+	var result params.ServiceConfigHistoryResults
+	service, err := c.api.state.Service(args.ServiceName)
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	history, err := service.ConfigHistory()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	for _, rev := range history {
+		revision := params.ServiceConfigRevision{
+			Revision: rev.Revision,
+			CharmURL: rev.CharmURL,
+			UserTag:  rev.User,
+			Time:     rev.Time,
+		}
+		for _, change := range rev.Changes {
+			revision.Changes = append(revision.Changes, params.ServiceConfigChange{
+				Key:      change.Key,
+				Type:     itemChangeTypes[change.Type],
+				OldValue: change.OldValue,
+				NewValue: change.NewValue,
+				Masked:   change.Masked,
+			})
+		}
+		result.Revisions = append(result.Revisions, revision)
+	}
+	return result, nil
+}
+
+// ServiceConfigRevert changes the configuration of a service back to
+// what it was after the given revision in its config history.
+// TODO(mattyw, all): This api call should be move to the new service facade. The client api version will then need bumping.
+func (c *Client) ServiceConfigRevert(args params.ServiceConfigRevert) (params.ServiceConfigRevertResult, error) {
+	var result params.ServiceConfigRevertResult
+	if err := c.check.ChangeAllowed(); err != nil {
+		return result, errors.Trace(err)
+	}
+	service, err := c.api.state.Service(args.ServiceName)
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	result.Skipped, err = service.RevertConfigSettings(c.api.auth.GetAuthTag().String(), args.Revision)
+	return result, errors.Trace(err)
+}
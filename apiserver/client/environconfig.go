@@ -0,0 +1,75 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package client
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/environs"
+	"github.com/juju/juju/environs/config"
+)
+
+// adminOnlyConfigAttrs holds the environment settings, other than the
+// provider's secrets, that only users with admin access may see.
+var adminOnlyConfigAttrs = []string{
+	"admin-secret",
+	"ca-private-key",
+}
+
+// identityConfigAttrs holds the settings of the external identity
+// provider. They determine who may log in and with what access, and
+// hold password hashes, so only users with admin access may see or
+// change them.
+var identityConfigAttrs = []string{
+	config.IdentityProviderKey,
+	config.IdentityDomainKey,
+	config.IdentityGroupAccessKey,
+	config.IdentityLDAPURLKey,
+	config.IdentityLDAPUserDNKey,
+	config.IdentityLDAPGroupBaseKey,
+	config.IdentityHtpasswdKey,
+	config.IdentityHtgroupsKey,
+	config.IdentityHtpasswdPathKey,
+	config.IdentityHtgroupsPathKey,
+}
+
+// isAdminOnlyChange returns whether only users with admin access may
+// change or remove the given setting.
+func isAdminOnlyChange(name string) bool {
+	if strings.HasPrefix(name, config.BlockKeyPrefix) {
+		return true
+	}
+	for _, attr := range identityConfigAttrs {
+		if name == attr {
+			return true
+		}
+	}
+	return false
+}
+
+// redactSecretAttrs removes from attrs the settings of cfg that only
+// users with admin access may see: the provider's credentials and
+// the other secrets held in the environment configuration.
+func redactSecretAttrs(cfg *config.Config, attrs map[string]interface{}) error {
+	provider, err := environs.Provider(cfg.Type())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	secrets, err := provider.SecretAttrs(cfg)
+	if err != nil {
+		return errors.Annotate(err, "cannot determine secret settings")
+	}
+	for name := range secrets {
+		delete(attrs, name)
+	}
+	for _, name := range adminOnlyConfigAttrs {
+		delete(attrs, name)
+	}
+	for _, name := range identityConfigAttrs {
+		delete(attrs, name)
+	}
+	return nil
+}
@@ -8,6 +8,9 @@ var (
 	RemoteParamsForMachine  = remoteParamsForMachine
 	GetAllUnitNames         = getAllUnitNames
 	NewStateStorage         = &newStateStorage
+	RefreshTimeout          = &refreshTimeout
+	RestoreUpgradeBackup    = &restoreUpgradeBackup
+	WaitForRestoreLock      = &waitForRestoreLock
 )
 
 var MachineJobFromParams = machineJobFromParams
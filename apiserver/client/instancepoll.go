@@ -0,0 +1,92 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package client
+
+import (
+	"sort"
+	"time"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/watcher"
+)
+
+// refreshTimeout holds how long RefreshInstances waits for the instance
+// poller to complete a requested poll.
+var refreshTimeout = 30 * time.Second
+
+// RefreshInstances asks the instance poller to query the provider for
+// the addresses and status of every provisioned machine's instance
+// now, and waits for it to report that the poll has completed. Machines
+// whose instances could not be queried, or all polled machines if the
+// poll does not complete in time, are reported as stale.
+func (c *Client) RefreshInstances() (params.RefreshInstancesResult, error) {
+	var result params.RefreshInstancesResult
+	env, err := c.api.state.Environment()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	generation, err := env.RequestInstancePoll()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	w := env.Watch()
+	defer w.Stop()
+	timeout := time.After(refreshTimeout)
+	for {
+		select {
+		case _, ok := <-w.Changes():
+			if !ok {
+				return result, watcher.EnsureErr(w)
+			}
+		case <-timeout:
+			pending, err := polledMachines(c.api.state)
+			if err != nil {
+				return result, errors.Trace(err)
+			}
+			for id := range pending {
+				result.Stale = append(result.Stale, id)
+			}
+			sort.Strings(result.Stale)
+			return result, nil
+		}
+		if err := env.Refresh(); err != nil {
+			return result, errors.Trace(err)
+		}
+		if env.InstancePollCompleted() >= generation {
+			result.Stale = env.InstancePollStale()
+			return result, nil
+		}
+	}
+}
+
+// polledMachines returns the machines whose instances the instance
+// poller queries: those that are provisioned and not manual.
+func polledMachines(st *state.State) (map[string]*state.Machine, error) {
+	machines, err := st.AllMachines()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	result := make(map[string]*state.Machine)
+	for _, m := range machines {
+		if m.Life() == state.Dead {
+			continue
+		}
+		if _, err := m.InstanceId(); errors.IsNotProvisioned(err) {
+			continue
+		} else if err != nil {
+			return nil, errors.Trace(err)
+		}
+		manual, err := m.IsManual()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if !manual {
+			result[m.Id()] = m
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package client
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/names"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/state"
+)
+
+// MachineNetworkStatus returns, for each given machine, the network
+// interfaces configured for it, the ones last found on it by its
+// agent, and how they differ.
+func (c *Client) MachineNetworkStatus(args params.Entities) (params.MachineNetworkStatusResults, error) {
+	result := params.MachineNetworkStatusResults{
+		Results: make([]params.MachineNetworkStatusResult, len(args.Entities)),
+	}
+	for i, entity := range args.Entities {
+		tag, err := names.ParseMachineTag(entity.Tag)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		status, err := machineNetworkStatus(c.api.state, tag.Id())
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		result.Results[i].Status = status
+	}
+	return result, nil
+}
+
+func machineNetworkStatus(st *state.State, machineId string) (params.MachineNetworkStatus, error) {
+	var status params.MachineNetworkStatus
+	machine, err := st.Machine(machineId)
+	if err != nil {
+		return status, errors.Trace(err)
+	}
+	intended, err := machine.NetworkInterfaces()
+	if err != nil {
+		return status, errors.Annotatef(err, "cannot get network interfaces of machine %s", machineId)
+	}
+	status.Intended = make([]params.IntendedNetworkInterface, len(intended))
+	for i, iface := range intended {
+		nw, err := st.Network(iface.NetworkName())
+		if err != nil {
+			return status, errors.Trace(err)
+		}
+		status.Intended[i] = params.IntendedNetworkInterface{
+			InterfaceName: iface.InterfaceName(),
+			MACAddress:    iface.MACAddress(),
+			NetworkName:   iface.NetworkName(),
+			CIDR:          nw.CIDR(),
+			VLANTag:       nw.VLANTag(),
+			Disabled:      iface.IsDisabled(),
+		}
+	}
+	observed, updated, err := machine.ObservedInterfaces()
+	if errors.IsNotFound(err) {
+		// The agent has not reported the interfaces yet.
+		return status, nil
+	} else if err != nil {
+		return status, errors.Trace(err)
+	}
+	status.ObservedAt = &updated
+	status.Observed = make([]params.ObservedNetworkInterface, len(observed))
+	for i, iface := range observed {
+		status.Observed[i] = params.ObservedNetworkInterface{
+			InterfaceName:       iface.InterfaceName,
+			MACAddress:          iface.MACAddress,
+			MTU:                 iface.MTU,
+			IsUp:                iface.IsUp,
+			Addresses:           iface.Addresses,
+			VLANTag:             iface.VLANTag,
+			ParentInterfaceName: iface.ParentInterfaceName,
+		}
+	}
+	drift, err := machine.InterfaceDrift()
+	if err != nil {
+		return status, errors.Trace(err)
+	}
+	for _, d := range drift {
+		status.Drift = append(status.Drift, params.NetworkInterfaceDrift{
+			InterfaceName: d.InterfaceName,
+			Problem:       d.Problem,
+		})
+	}
+	return status, nil
+}
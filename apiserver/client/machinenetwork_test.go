@@ -0,0 +1,89 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package client_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/state"
+)
+
+type machineNetworkSuite struct {
+	baseSuite
+	machine *state.Machine
+}
+
+var _ = gc.Suite(&machineNetworkSuite{})
+
+func (s *machineNetworkSuite) SetUpTest(c *gc.C) {
+	s.baseSuite.SetUpTest(c)
+	var err error
+	s.machine, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	networks := []state.NetworkInfo{{
+		Name:       "vlan42",
+		ProviderId: "vlan42",
+		CIDR:       "0.2.2.0/24",
+		VLANTag:    42,
+	}}
+	interfaces := []state.NetworkInterfaceInfo{{
+		MACAddress:    "aa:bb:cc:dd:ee:f1",
+		InterfaceName: "eth1.42",
+		NetworkName:   "vlan42",
+		IsVirtual:     true,
+	}}
+	err = s.machine.SetInstanceInfo("i-am", "fake_nonce", nil, networks, interfaces)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *machineNetworkSuite) TestMachineNetworkStatusNotReported(c *gc.C) {
+	status, err := s.APIState.Client().MachineNetworkStatus(s.machine.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(status, jc.DeepEquals, params.MachineNetworkStatus{
+		Intended: []params.IntendedNetworkInterface{{
+			InterfaceName: "eth1.42",
+			MACAddress:    "aa:bb:cc:dd:ee:f1",
+			NetworkName:   "vlan42",
+			CIDR:          "0.2.2.0/24",
+			VLANTag:       42,
+		}},
+	})
+}
+
+func (s *machineNetworkSuite) TestMachineNetworkStatus(c *gc.C) {
+	err := s.machine.SetObservedInterfaces([]state.ObservedInterface{{
+		InterfaceName: "eth0",
+		MACAddress:    "aa:bb:cc:dd:ee:f0",
+		MTU:           1500,
+		IsUp:          true,
+		Addresses:     []string{"0.1.2.3/24"},
+	}})
+	c.Assert(err, jc.ErrorIsNil)
+	_, updated, err := s.machine.ObservedInterfaces()
+	c.Assert(err, jc.ErrorIsNil)
+
+	status, err := s.APIState.Client().MachineNetworkStatus(s.machine.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(status.Intended, gc.HasLen, 1)
+	c.Assert(status.Observed, jc.DeepEquals, []params.ObservedNetworkInterface{{
+		InterfaceName: "eth0",
+		MACAddress:    "aa:bb:cc:dd:ee:f0",
+		MTU:           1500,
+		IsUp:          true,
+		Addresses:     []string{"0.1.2.3/24"},
+	}})
+	c.Assert(status.ObservedAt, gc.NotNil)
+	c.Assert(status.ObservedAt.Equal(updated), jc.IsTrue)
+	c.Assert(status.Drift, jc.DeepEquals, []params.NetworkInterfaceDrift{
+		{"eth1.42", "missing on machine"},
+	})
+}
+
+func (s *machineNetworkSuite) TestMachineNetworkStatusNotFound(c *gc.C) {
+	_, err := s.APIState.Client().MachineNetworkStatus("42")
+	c.Assert(err, gc.ErrorMatches, `machine 42 not found`)
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
+}
@@ -0,0 +1,80 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package client
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/state"
+)
+
+// StartStagedUpgrade sets the environment agent version, upgrading the
+// state servers first, then a percentage of the other machines as
+// canaries, then the rest in batches once the agents upgraded before
+// are healthy.
+func (c *Client) StartStagedUpgrade(args params.StartStagedUpgrade) error {
+	if err := c.check.ChangeAllowed(); err != nil {
+		return errors.Trace(err)
+	}
+	return c.api.state.StartStagedUpgrade(args.Version, state.StagedUpgradeArgs{
+		CanaryPercent: args.CanaryPercent,
+		BatchSize:     args.BatchSize,
+		HealthTimeout: args.HealthTimeout,
+	})
+}
+
+// ResumeStagedUpgrade resumes a halted staged upgrade.
+func (c *Client) ResumeStagedUpgrade() error {
+	if err := c.check.ChangeAllowed(); err != nil {
+		return errors.Trace(err)
+	}
+	return c.api.state.ResumeStagedUpgrade()
+}
+
+// StagedUpgradeStatus returns the progress of the last staged upgrade
+// of the environment.
+func (c *Client) StagedUpgradeStatus() (params.StagedUpgradeStatus, error) {
+	st := c.api.state
+	staged, err := st.StagedUpgrade()
+	if err != nil {
+		return params.StagedUpgradeStatus{}, errors.Trace(err)
+	}
+	args := staged.Args()
+	status := params.StagedUpgradeStatus{
+		PreviousVersion: staged.PreviousVersion(),
+		TargetVersion:   staged.TargetVersion(),
+		Status:          string(staged.Status()),
+		HaltReason:      staged.HaltReason(),
+		CanaryPercent:   args.CanaryPercent,
+		BatchSize:       args.BatchSize,
+		HealthTimeout:   args.HealthTimeout,
+		Started:         staged.Started(),
+		StageStarted:    staged.StageStarted(),
+	}
+	machines, err := st.AllMachines()
+	if err != nil {
+		return params.StagedUpgradeStatus{}, errors.Trace(err)
+	}
+	for _, m := range machines {
+		info := params.StagedUpgradeMachine{
+			Id:          m.Id(),
+			StateServer: m.IsManager(),
+		}
+		info.Released = info.StateServer || !staged.HoldsMachine(m.Id())
+		if tools, err := m.AgentTools(); err == nil {
+			info.Version = tools.Version.Number.String()
+		} else if !errors.IsNotFound(err) {
+			return params.StagedUpgradeStatus{}, errors.Trace(err)
+		}
+		if info.Released && staged.InProgress() {
+			info.Problems, err = staged.MachineProblems(m)
+			if err != nil {
+				return params.StagedUpgradeStatus{}, errors.Trace(err)
+			}
+		}
+		status.Machines = append(status.Machines, info)
+	}
+	return status, nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package client_test
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/version"
+)
+
+type stagedUpgradeSuite struct {
+	baseSuite
+	stateServer *state.Machine
+	machine     *state.Machine
+	oldVersion  version.Number
+	newVersion  version.Number
+}
+
+var _ = gc.Suite(&stagedUpgradeSuite{})
+
+func (s *stagedUpgradeSuite) SetUpTest(c *gc.C) {
+	s.baseSuite.SetUpTest(c)
+	cfg, err := s.State.EnvironConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	var ok bool
+	s.oldVersion, ok = cfg.AgentVersion()
+	c.Assert(ok, jc.IsTrue)
+	s.newVersion = s.oldVersion
+	s.newVersion.Patch++
+
+	s.stateServer, err = s.State.AddMachine("quantal", state.JobManageEnviron)
+	c.Assert(err, jc.ErrorIsNil)
+	s.machine, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	for _, m := range []*state.Machine{s.stateServer, s.machine} {
+		err = m.SetProvisioned(instance.Id("i-"+m.Id()), "fake_nonce", nil)
+		c.Assert(err, jc.ErrorIsNil)
+		err = m.SetAgentVersion(version.Binary{Number: s.oldVersion, Series: "quantal", Arch: "amd64"})
+		c.Assert(err, jc.ErrorIsNil)
+	}
+}
+
+func (s *stagedUpgradeSuite) TestStagedUpgradeStatusNotFound(c *gc.C) {
+	_, err := s.APIState.Client().StagedUpgradeStatus()
+	c.Assert(err, gc.ErrorMatches, "staged upgrade not found")
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
+}
+
+func (s *stagedUpgradeSuite) TestStartStagedUpgrade(c *gc.C) {
+	client := s.APIState.Client()
+	err := client.StartStagedUpgrade(s.newVersion, 10, 5, time.Minute)
+	c.Assert(err, jc.ErrorIsNil)
+
+	status, err := client.StagedUpgradeStatus()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(status.PreviousVersion, gc.Equals, s.oldVersion)
+	c.Assert(status.TargetVersion, gc.Equals, s.newVersion)
+	c.Assert(status.Status, gc.Equals, "running")
+	c.Assert(status.CanaryPercent, gc.Equals, 10)
+	c.Assert(status.BatchSize, gc.Equals, 5)
+	c.Assert(status.HealthTimeout, gc.Equals, time.Minute)
+	c.Assert(status.Started.IsZero(), jc.IsFalse)
+
+	// The state server is waited for, but the other machine is held
+	// back, so its health does not matter yet.
+	c.Assert(status.Machines, jc.DeepEquals, []params.StagedUpgradeMachine{{
+		Id:          s.stateServer.Id(),
+		StateServer: true,
+		Released:    true,
+		Version:     s.oldVersion.String(),
+		Problems: []string{
+			"agent is running " + s.oldVersion.String(),
+			"agent is not alive",
+		},
+	}, {
+		Id:      s.machine.Id(),
+		Version: s.oldVersion.String(),
+	}})
+}
+
+func (s *stagedUpgradeSuite) TestResumeStagedUpgrade(c *gc.C) {
+	client := s.APIState.Client()
+	err := client.ResumeStagedUpgrade()
+	c.Assert(err, gc.ErrorMatches, "cannot resume staged upgrade: no staged upgrade to resume")
+
+	err = client.StartStagedUpgrade(s.newVersion, 10, 5, time.Minute)
+	c.Assert(err, jc.ErrorIsNil)
+	staged, err := s.State.StagedUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+	err = staged.Halt("broken")
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = client.ResumeStagedUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+	err = staged.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(staged.Status(), gc.Equals, state.StagedUpgradeRunning)
+}
+
+func (s *stagedUpgradeSuite) TestStartStagedUpgradeBlocked(c *gc.C) {
+	s.blockAllChanges(c)
+	err := s.APIState.Client().StartStagedUpgrade(s.newVersion, 10, 5, time.Minute)
+	c.Assert(err, jc.Satisfies, params.IsCodeOperationBlocked)
+	_, err = s.State.StagedUpgrade()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
@@ -12,8 +12,10 @@ import (
 	"gopkg.in/juju/charm.v4"
 
 	"github.com/juju/juju/api"
+	"github.com/juju/juju/apiserver/common"
 	"github.com/juju/juju/apiserver/params"
 	"github.com/juju/juju/constraints"
+	"github.com/juju/juju/leadership"
 	"github.com/juju/juju/network"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/state/multiwatcher"
@@ -35,8 +37,12 @@ func (c *Client) FullStatus(args params.StatusParams) (api.Status, error) {
 		return noStatus, errors.Annotate(err, "could not fetch machines")
 	} else if context.relations, err = fetchRelations(c.api.state); err != nil {
 		return noStatus, errors.Annotate(err, "could not fetch relations")
+	} else if context.remoteServices, err = fetchRemoteServices(c.api.state); err != nil {
+		return noStatus, errors.Annotate(err, "could not fetch remote services")
 	} else if context.networks, err = fetchNetworks(c.api.state); err != nil {
 		return noStatus, errors.Annotate(err, "could not fetch networks")
+	} else if context.leaders, err = leadership.Leaders(c.api.state); err != nil {
+		return noStatus, errors.Annotate(err, "could not fetch service leaders")
 	}
 
 	logger.Debugf("Services: %v", context.services)
@@ -116,6 +122,7 @@ func (c *Client) FullStatus(args params.StatusParams) (api.Status, error) {
 		EnvironmentName: cfg.Name(),
 		Machines:        processMachines(context.machines),
 		Services:        context.processServices(),
+		RemoteServices:  context.processRemoteServices(),
 		Networks:        context.processNetworks(),
 		Relations:       context.processRelations(),
 	}, nil
@@ -143,11 +150,15 @@ type statusContext struct {
 	// this machine.
 	machines map[string][]*state.Machine
 	// services: service name -> service
-	services     map[string]*state.Service
-	relations    map[string][]*state.Relation
-	units        map[string]map[string]*state.Unit
-	networks     map[string]*state.Network
-	latestCharms map[charm.URL]string
+	services map[string]*state.Service
+	// remoteServices: remote service name -> remote service
+	remoteServices map[string]*state.RemoteService
+	relations      map[string][]*state.Relation
+	units          map[string]map[string]*state.Unit
+	networks       map[string]*state.Network
+	latestCharms   map[charm.URL]string
+	// leaders: service name -> name of the unit leading it
+	leaders map[string]string
 }
 
 // fetchMachines returns a map from top level machine id to machines, where machines[0] is the host
@@ -271,6 +282,20 @@ func fetchRelations(st *state.State) (map[string][]*state.Relation, error) {
 	return out, nil
 }
 
+// fetchRemoteServices returns a map from remote service name to remote
+// service.
+func fetchRemoteServices(st *state.State) (map[string]*state.RemoteService, error) {
+	services, err := st.AllRemoteServices()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]*state.RemoteService)
+	for _, s := range services {
+		out[s.Name()] = s
+	}
+	return out, nil
+}
+
 // fetchNetworks returns a map from network name to network.
 func fetchNetworks(st *state.State) (map[string]*state.Network, error) {
 	networks, err := st.AllNetworks()
@@ -341,6 +366,11 @@ func makeMachineStatus(machine *state.Machine) (status api.MachineStatus) {
 			status.InstanceState = "error"
 		}
 		status.DNSName = network.SelectPublicAddress(machine.Addresses())
+		if polled, err := machine.InstancePolled(); err != nil {
+			logger.Errorf("cannot get instance poll time of machine %s: %v", machine.Id(), err)
+		} else if !polled.IsZero() {
+			status.InstancePolled = &polled
+		}
 	} else {
 		if errors.IsNotProvisioned(err) {
 			status.InstanceId = "pending"
@@ -361,6 +391,12 @@ func makeMachineStatus(machine *state.Machine) (status api.MachineStatus) {
 	} else {
 		status.Hardware = hc.String()
 	}
+	templates, err := machine.ContainerTemplates()
+	if err != nil {
+		logger.Errorf("cannot get container templates of machine %s: %v", machine.Id(), err)
+	} else {
+		status.ContainerTemplates = common.ContainerTemplates(templates)
+	}
 	status.Containers = make(map[string]api.MachineStatus)
 	return
 }
@@ -461,6 +497,7 @@ func (context *statusContext) processService(service *state.Service) (status api
 	status.Charm = serviceCharmURL.String()
 	status.Exposed = service.IsExposed()
 	status.Life = processLife(service)
+	status.Leader = context.leaders[service.Name()]
 
 	latestCharm, ok := context.latestCharms[*serviceCharmURL.WithRevision(-1)]
 	if ok && latestCharm != serviceCharmURL.String() {
@@ -498,6 +535,11 @@ func (context *statusContext) processService(service *state.Service) (status api
 	}
 	if service.IsPrincipal() {
 		status.Units = context.processUnits(context.units[service.Name()], serviceCharmURL.String())
+		status.PlacementViolations, err = service.PlacementViolations()
+		if err != nil {
+			status.Err = err
+			return
+		}
 	}
 	return status
 }
@@ -578,6 +620,40 @@ func (context *statusContext) processServiceRelations(service *state.Service) (r
 	return related, subordSet.SortedValues(), nil
 }
 
+func (context *statusContext) processRemoteServices() map[string]api.RemoteServiceStatus {
+	servicesMap := make(map[string]api.RemoteServiceStatus)
+	for _, s := range context.remoteServices {
+		servicesMap[s.Name()] = context.processRemoteService(s)
+	}
+	return servicesMap
+}
+
+func (context *statusContext) processRemoteService(service *state.RemoteService) (status api.RemoteServiceStatus) {
+	status.Environment = service.SourceEnvName()
+	status.Life = processLife(service)
+	related := make(map[string][]string)
+	for _, relation := range context.relations[service.Name()] {
+		ep, err := relation.Endpoint(service.Name())
+		if err != nil {
+			status.Err = err
+			return
+		}
+		eps, err := relation.RelatedEndpoints(service.Name())
+		if err != nil {
+			status.Err = err
+			return
+		}
+		for _, other := range eps {
+			related[ep.Name] = append(related[ep.Name], other.ServiceName)
+		}
+	}
+	for relationName, serviceNames := range related {
+		related[relationName] = set.NewStrings(serviceNames...).SortedValues()
+	}
+	status.Relations = related
+	return
+}
+
 type lifer interface {
 	Life() state.Life
 }
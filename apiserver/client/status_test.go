@@ -4,11 +4,16 @@
 package client_test
 
 import (
+	"fmt"
+	"time"
+
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
 
 	"github.com/juju/juju/apiserver/client"
 	"github.com/juju/juju/instance"
+	"github.com/juju/juju/leadership"
+	"github.com/juju/juju/lease"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/testing/factory"
 )
@@ -45,6 +50,39 @@ func (s *statusSuite) TestFullStatus(c *gc.C) {
 	c.Check(resultMachine.Series, gc.Equals, machine.Series())
 }
 
+func (s *statusSuite) TestFullStatusServiceLeader(c *gc.C) {
+	service := s.Factory.MakeService(c, nil)
+	unit := s.Factory.MakeUnit(c, &factory.UnitParams{Service: service})
+	_, err := s.State.ClaimLease(lease.Token{
+		Namespace:  leadership.LeadershipNamespace(service.Name()),
+		Id:         unit.Name(),
+		Expiration: time.Now().Add(time.Minute),
+	}, time.Now())
+	c.Assert(err, jc.ErrorIsNil)
+
+	status, err := s.APIState.Client().Status(nil)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(status.Services[service.Name()].Leader, gc.Equals, unit.Name())
+}
+
+func (s *statusSuite) TestFullStatusPlacementViolations(c *gc.C) {
+	service := s.Factory.MakeService(c, nil)
+	machine := s.addMachine(c)
+	for i := 0; i < 2; i++ {
+		s.Factory.MakeUnit(c, &factory.UnitParams{Service: service, Machine: machine})
+	}
+	err := service.SetPlacementPolicies([]state.PlacementPolicy{
+		{Kind: state.AntiAffinity, Service: service.Name()},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	status, err := s.APIState.Client().Status(nil)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(status.Services[service.Name()].PlacementViolations, gc.DeepEquals, []string{
+		`anti-affinity=mysql: units "mysql/0" and "mysql/1" share machine 0`,
+	})
+}
+
 func (s *statusSuite) TestLegacyStatus(c *gc.C) {
 	machine := s.addMachine(c)
 	instanceId := "i-fakeinstance"
@@ -61,6 +99,87 @@ func (s *statusSuite) TestLegacyStatus(c *gc.C) {
 	c.Check(resultMachine.InstanceId, gc.Equals, instanceId)
 }
 
+func (s *statusSuite) TestFullStatusInstancePolled(c *gc.C) {
+	machine := s.addMachine(c)
+	err := machine.SetProvisioned("i-fakeinstance", "fakenonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	status, err := s.APIState.Client().Status(nil)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(status.Machines[machine.Id()].InstancePolled, gc.IsNil)
+
+	polled := time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)
+	err = machine.SetInstancePolled(polled)
+	c.Assert(err, jc.ErrorIsNil)
+	status, err = s.APIState.Client().Status(nil)
+	c.Assert(err, jc.ErrorIsNil)
+	resultPolled := status.Machines[machine.Id()].InstancePolled
+	c.Assert(resultPolled, gc.NotNil)
+	c.Check(resultPolled.Equal(polled), jc.IsTrue)
+}
+
+func (s *statusSuite) TestRefreshInstances(c *gc.C) {
+	machine := s.addMachine(c)
+	err := machine.SetProvisioned("i-fakeinstance", "fakenonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	// Unprovisioned machines are not waited for.
+	s.addMachine(c)
+
+	// Stand in for the instance poller, which could not query
+	// the machine's instance.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		completed := false
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+			if !completed {
+				env, err := s.State.Environment()
+				c.Check(err, jc.ErrorIsNil)
+				if generation := env.InstancePollGeneration(); generation > 0 {
+					err := env.CompleteInstancePoll(generation, []string{machine.Id()})
+					c.Check(err, jc.ErrorIsNil)
+					completed = true
+				}
+			}
+			// Let the API server's watcher see the change.
+			s.BackingState.StartSync()
+		}
+	}()
+
+	stale, err := s.APIState.Client().RefreshInstances()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(stale, jc.DeepEquals, []string{machine.Id()})
+}
+
+func (s *statusSuite) TestRefreshInstancesTimeout(c *gc.C) {
+	s.PatchValue(client.RefreshTimeout, 100*time.Millisecond)
+	for i := 0; i < 2; i++ {
+		machine := s.addMachine(c)
+		err := machine.SetProvisioned(instance.Id(fmt.Sprintf("i-%d", i)), "fakenonce", nil)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+
+	stale, err := s.APIState.Client().RefreshInstances()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(stale, jc.DeepEquals, []string{"0", "1"})
+
+	env, err := s.State.Environment()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(env.InstancePollGeneration(), gc.Equals, int64(1))
+}
+
+func (s *statusSuite) TestAPIServerLoad(c *gc.C) {
+	load, err := s.APIState.Client().APIServerLoad()
+	c.Assert(err, jc.ErrorIsNil)
+	// The connection making the call is counted.
+	c.Check(load.Users.Connections, jc.GreaterThan, 0)
+	c.Check(load.Users.LoginsRejected, gc.Equals, int64(0))
+}
+
 var _ = gc.Suite(&statusUnitTestSuite{})
 
 type statusUnitTestSuite struct {
@@ -0,0 +1,175 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package client
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/backups"
+	"github.com/juju/juju/state/watcher"
+	"github.com/juju/juju/upgrades"
+)
+
+// restoreUpgradeBackup replaces the state databases with the ones in
+// the state backup with the given id.
+var restoreUpgradeBackup = func(st *state.State, backupId string) error {
+	session := st.MongoSession().Copy()
+	defer session.Close()
+	dbInfo, err := backups.NewDBInfo(st.MongoConnectionInfo(), session)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	stor := backups.NewStorage(st)
+	defer stor.Close()
+	return backups.RestoreDB(backups.NewBackups(stor), backupId, dbInfo, session)
+}
+
+// maxReportedChanges is the number of changed documents listed when a
+// rollback is refused because of them.
+const maxReportedChanges = 20
+
+// RollbackUpgrade rolls back the last upgrade of the state servers: it
+// restores the state backup taken before their upgrade steps were run,
+// and sets the environment agent version back to the version upgraded
+// from, so that the agents downgrade their tools. The environment is
+// locked for the restore, so that no changes are made meanwhile. Unless
+// forced, the rollback is refused if any documents were changed since
+// the backup was taken.
+func (c *Client) RollbackUpgrade(args params.UpgradeRollbackArgs) (params.UpgradeRollback, error) {
+	if err := c.check.ChangeAllowed(); err != nil {
+		return params.UpgradeRollback{}, errors.Trace(err)
+	}
+	st := c.api.state
+	backup, err := st.UpgradeBackup()
+	if errors.IsNotFound(err) {
+		return params.UpgradeRollback{}, errors.New("cannot roll back upgrade: no state backup was taken before the last upgrade")
+	} else if err != nil {
+		return params.UpgradeRollback{}, errors.Trace(err)
+	}
+	result := params.UpgradeRollback{
+		PreviousVersion: backup.PreviousVersion(),
+		TargetVersion:   backup.TargetVersion(),
+		BackupId:        backup.BackupId(),
+	}
+	if err := c.rollbackUpgrade(backup, args.Force); err != nil {
+		return params.UpgradeRollback{}, errors.Annotatef(err, "cannot roll back upgrade from %s to %s",
+			result.PreviousVersion, result.TargetVersion)
+	}
+	return result, nil
+}
+
+func (c *Client) rollbackUpgrade(backup *state.UpgradeBackup, force bool) (err error) {
+	st := c.api.state
+	if err := checkRollback(st, backup, force); err != nil {
+		return errors.Trace(err)
+	}
+
+	// Lock the environment for the restore, so that the API servers
+	// and agents stop making changes, which the restore would lose.
+	info, err := st.EnsureRestoreInfo()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := info.SetStatus(state.RestorePending); err != nil {
+		return errors.Trace(err)
+	}
+	defer func() {
+		status := state.RestoreFinished
+		if err != nil {
+			status = state.UnknownRestoreStatus
+		}
+		if unlockErr := info.SetStatus(status); unlockErr != nil && err == nil {
+			err = errors.Annotate(unlockErr, "cannot unlock environment")
+		}
+	}()
+	if err := info.SetStatus(state.RestoreInProgress); err != nil {
+		return errors.Trace(err)
+	}
+	waitForRestoreLock()
+
+	// Check again, as changes may have been made until the lock took
+	// effect.
+	backup, err = st.UpgradeBackup()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := checkRollback(st, backup, force); err != nil {
+		return errors.Trace(err)
+	}
+
+	logger.Infof("restoring state backup %q", backup.BackupId())
+	if err := restoreUpgradeBackup(st, backup.BackupId()); err != nil {
+		return errors.Annotate(err, "cannot restore state backup")
+	}
+	if err := st.AbortCurrentUpgrade(); err != nil {
+		return errors.Trace(err)
+	}
+	if err := st.SetEnvironAgentVersion(backup.PreviousVersion()); err != nil {
+		return errors.Trace(err)
+	}
+	return backup.SetRolledBack()
+}
+
+// waitForRestoreLock waits for the API servers and agents, which learn
+// of the restore through watchers, to stop making changes once the
+// environment is locked for it.
+var waitForRestoreLock = func() {
+	time.Sleep(2 * watcher.Period)
+}
+
+// checkRollback returns an error if the backup cannot be restored to
+// roll back the upgrade.
+func checkRollback(st *state.State, backup *state.UpgradeBackup, force bool) error {
+	if backup.RolledBack() {
+		return errors.New("upgrade already rolled back")
+	}
+	cfg, err := st.EnvironConfig()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if agentVersion, _ := cfg.AgentVersion(); agentVersion != backup.TargetVersion() {
+		return errors.Errorf("environment agent version is %s", agentVersion)
+	}
+	irreversible := upgrades.IrreversibleSteps(backup.PreviousVersion(), backup.TargetVersion())
+	if len(irreversible) > 0 {
+		return errors.Errorf("upgrade steps cannot be reversed: %s", strings.Join(irreversible, ", "))
+	}
+	// The instances of machines added since the backup was taken
+	// would be left running, unknown to the restored state.
+	added, err := backup.MachinesAddedSince()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(added) > 0 {
+		return errors.Errorf("machines added since the upgrade: %s", strings.Join(added, ", "))
+	}
+	changed, err := backup.ChangesSince()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(changed) > 0 {
+		if !force {
+			return errors.Errorf("changes made since the upgrade would be lost (use --force to roll back anyway): %s",
+				summariseChanges(changed))
+		}
+		logger.Warningf("rolling back upgrade loses changes to %s", strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+// summariseChanges returns the changed documents as a list, truncated
+// after maxReportedChanges.
+func summariseChanges(changed []string) string {
+	if len(changed) <= maxReportedChanges {
+		return strings.Join(changed, ", ")
+	}
+	more := len(changed) - maxReportedChanges
+	return fmt.Sprintf("%s and %d more", strings.Join(changed[:maxReportedChanges], ", "), more)
+}
@@ -0,0 +1,215 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package client_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/client"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/version"
+)
+
+type upgradeRollbackSuite struct {
+	baseSuite
+	stateServer *state.Machine
+	oldVersion  version.Number
+	newVersion  version.Number
+	restored    []string
+	restoreErr  error
+
+	// restoreStatus holds the restore status while the backup is
+	// restored.
+	restoreStatus state.RestoreStatus
+}
+
+var _ = gc.Suite(&upgradeRollbackSuite{})
+
+func (s *upgradeRollbackSuite) SetUpTest(c *gc.C) {
+	s.baseSuite.SetUpTest(c)
+	cfg, err := s.State.EnvironConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	var ok bool
+	s.oldVersion, ok = cfg.AgentVersion()
+	c.Assert(ok, jc.IsTrue)
+	s.newVersion = s.oldVersion
+	s.newVersion.Patch++
+
+	s.stateServer, err = s.State.AddMachine("quantal", state.JobManageEnviron)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.stateServer.SetProvisioned(instance.Id("i-0"), "fake_nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.stateServer.SetAgentVersion(version.Binary{Number: s.oldVersion, Series: "quantal", Arch: "amd64"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	s.restored = nil
+	s.restoreErr = nil
+	s.PatchValue(client.RestoreUpgradeBackup, func(st *state.State, backupId string) error {
+		s.restored = append(s.restored, backupId)
+		info, err := st.EnsureRestoreInfo()
+		if err != nil {
+			return err
+		}
+		s.restoreStatus = info.Status()
+		return s.restoreErr
+	})
+	s.PatchValue(client.WaitForRestoreLock, func() {})
+}
+
+func (s *upgradeRollbackSuite) currentRestoreStatus(c *gc.C) state.RestoreStatus {
+	info, err := s.State.EnsureRestoreInfo()
+	c.Assert(err, jc.ErrorIsNil)
+	return info.Status()
+}
+
+// upgrade sets the environment agent version to the new version, and
+// records a backup taken before the upgrade, as the master state server
+// does.
+func (s *upgradeRollbackSuite) upgrade(c *gc.C) {
+	err := s.State.SetEnvironAgentVersion(s.newVersion)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.State.SetUpgradeBackup(s.oldVersion, s.newVersion, "backup-id")
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *upgradeRollbackSuite) assertAgentVersion(c *gc.C, expected version.Number) {
+	cfg, err := s.State.EnvironConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	agentVersion, _ := cfg.AgentVersion()
+	c.Assert(agentVersion, gc.Equals, expected)
+}
+
+func (s *upgradeRollbackSuite) TestRollbackUpgrade(c *gc.C) {
+	s.upgrade(c)
+	_, err := s.State.EnsureUpgradeInfo(s.stateServer.Id(), s.oldVersion, s.newVersion)
+	c.Assert(err, jc.ErrorIsNil)
+
+	result, err := s.APIState.Client().RollbackUpgrade(false)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, gc.Equals, params.UpgradeRollback{
+		PreviousVersion: s.oldVersion,
+		TargetVersion:   s.newVersion,
+		BackupId:        "backup-id",
+	})
+	c.Assert(s.restored, jc.DeepEquals, []string{"backup-id"})
+	s.assertAgentVersion(c, s.oldVersion)
+
+	// The environment was locked while the backup was restored.
+	c.Assert(s.restoreStatus, gc.Equals, state.RestoreInProgress)
+	c.Assert(s.currentRestoreStatus(c), gc.Equals, state.RestoreFinished)
+
+	upgrading, err := s.State.IsUpgrading()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(upgrading, jc.IsFalse)
+	backup, err := s.State.UpgradeBackup()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(backup.RolledBack(), jc.IsTrue)
+
+	_, err = s.APIState.Client().RollbackUpgrade(false)
+	c.Assert(err, gc.ErrorMatches, "cannot roll back upgrade from .* to .*: upgrade already rolled back")
+	c.Assert(s.restored, gc.HasLen, 1)
+}
+
+func (s *upgradeRollbackSuite) TestRollbackUpgradeNoBackup(c *gc.C) {
+	_, err := s.APIState.Client().RollbackUpgrade(false)
+	c.Assert(err, gc.ErrorMatches, "cannot roll back upgrade: no state backup was taken before the last upgrade")
+	c.Assert(s.restored, gc.HasLen, 0)
+}
+
+func (s *upgradeRollbackSuite) TestRollbackUpgradeAgentVersionChanged(c *gc.C) {
+	s.upgrade(c)
+	later := s.newVersion
+	later.Patch++
+	err := s.State.SetEnvironAgentVersion(later)
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = s.APIState.Client().RollbackUpgrade(false)
+	c.Assert(err, gc.ErrorMatches, "cannot roll back upgrade from .* to .*: environment agent version is "+later.String())
+	c.Assert(s.restored, gc.HasLen, 0)
+	s.assertAgentVersion(c, later)
+}
+
+func (s *upgradeRollbackSuite) TestRollbackUpgradeMachinesAdded(c *gc.C) {
+	s.upgrade(c)
+	m, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = s.APIState.Client().RollbackUpgrade(false)
+	c.Assert(err, gc.ErrorMatches, "cannot roll back upgrade from .* to .*: machines added since the upgrade: "+m.Id())
+	c.Assert(s.restored, gc.HasLen, 0)
+	s.assertAgentVersion(c, s.newVersion)
+}
+
+func (s *upgradeRollbackSuite) TestRollbackUpgradeRestoreFails(c *gc.C) {
+	s.upgrade(c)
+	s.restoreErr = errors.New("boom")
+
+	_, err := s.APIState.Client().RollbackUpgrade(false)
+	c.Assert(err, gc.ErrorMatches, "cannot roll back upgrade from .* to .*: cannot restore state backup: boom")
+	s.assertAgentVersion(c, s.newVersion)
+	backup, err := s.State.UpgradeBackup()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(backup.RolledBack(), jc.IsFalse)
+	c.Assert(s.currentRestoreStatus(c), gc.Equals, state.UnknownRestoreStatus)
+}
+
+func (s *upgradeRollbackSuite) TestRollbackUpgradeRestoreInProgress(c *gc.C) {
+	s.upgrade(c)
+	info, err := s.State.EnsureRestoreInfo()
+	c.Assert(err, jc.ErrorIsNil)
+	err = info.SetStatus(state.RestorePending)
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = s.APIState.Client().RollbackUpgrade(false)
+	c.Assert(err, gc.ErrorMatches, "cannot roll back upgrade from .* to .*: "+
+		`cannot set restore status to "PENDING": restore already pending or in progress`)
+	c.Assert(s.restored, gc.HasLen, 0)
+	c.Assert(s.currentRestoreStatus(c), gc.Equals, state.RestorePending)
+}
+
+func (s *upgradeRollbackSuite) TestRollbackUpgradeChangesBeforeLock(c *gc.C) {
+	s.upgrade(c)
+	// Changes made until the lock takes effect are found.
+	s.PatchValue(client.WaitForRestoreLock, func() {
+		err := s.stateServer.SetAgentVersion(version.Binary{Number: s.newVersion, Series: "quantal", Arch: "amd64"})
+		c.Check(err, jc.ErrorIsNil)
+	})
+
+	_, err := s.APIState.Client().RollbackUpgrade(false)
+	c.Assert(err, gc.ErrorMatches, "cannot roll back upgrade from .* to .*: "+
+		`changes made since the upgrade would be lost \(use --force to roll back anyway\): machines `+s.stateServer.Id())
+	c.Assert(s.restored, gc.HasLen, 0)
+	s.assertAgentVersion(c, s.newVersion)
+	c.Assert(s.currentRestoreStatus(c), gc.Equals, state.UnknownRestoreStatus)
+}
+
+func (s *upgradeRollbackSuite) TestRollbackUpgradeBlocked(c *gc.C) {
+	s.upgrade(c)
+	s.blockAllChanges(c)
+	_, err := s.APIState.Client().RollbackUpgrade(false)
+	c.Assert(err, jc.Satisfies, params.IsCodeOperationBlocked)
+	c.Assert(s.restored, gc.HasLen, 0)
+}
+
+func (s *upgradeRollbackSuite) TestRollbackUpgradeChangesSince(c *gc.C) {
+	s.upgrade(c)
+	err := s.stateServer.SetAgentVersion(version.Binary{Number: s.newVersion, Series: "quantal", Arch: "amd64"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = s.APIState.Client().RollbackUpgrade(false)
+	c.Assert(err, gc.ErrorMatches, "cannot roll back upgrade from .* to .*: "+
+		`changes made since the upgrade would be lost \(use --force to roll back anyway\): machines `+s.stateServer.Id())
+	c.Assert(s.restored, gc.HasLen, 0)
+	s.assertAgentVersion(c, s.newVersion)
+
+	result, err := s.APIState.Client().RollbackUpgrade(true)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.BackupId, gc.Equals, "backup-id")
+	c.Assert(s.restored, jc.DeepEquals, []string{"backup-id"})
+	s.assertAgentVersion(c, s.oldVersion)
+}
@@ -20,6 +20,16 @@ type AddressAndCertGetter interface {
 	WatchAPIHostPorts() state.NotifyWatcher
 }
 
+// agentAddressGetter is implemented by AddressAndCertGetters that know
+// where the agents of an environment should connect, which differs
+// from the state server's own API addresses while the environment is
+// migrating to another state server.
+type agentAddressGetter interface {
+	AgentAPIHostPorts() ([][]network.HostPort, error)
+	AgentCACert() (string, error)
+	WatchAgentAPIHostPorts() state.NotifyWatcher
+}
+
 // APIAddresser implements the APIAddresses method
 type APIAddresser struct {
 	resources *Resources
@@ -35,9 +45,18 @@ func NewAPIAddresser(getter AddressAndCertGetter, resources *Resources) *APIAddr
 	}
 }
 
+// apiHostPorts returns the addresses of the API servers that agents
+// should connect to.
+func (api *APIAddresser) apiHostPorts() ([][]network.HostPort, error) {
+	if getter, ok := api.getter.(agentAddressGetter); ok {
+		return getter.AgentAPIHostPorts()
+	}
+	return api.getter.APIHostPorts()
+}
+
 // APIHostPorts returns the API server addresses.
 func (api *APIAddresser) APIHostPorts() (params.APIHostPortsResult, error) {
-	servers, err := api.getter.APIHostPorts()
+	servers, err := api.apiHostPorts()
 	if err != nil {
 		return params.APIHostPortsResult{}, err
 	}
@@ -48,7 +67,12 @@ func (api *APIAddresser) APIHostPorts() (params.APIHostPortsResult, error) {
 
 // WatchAPIHostPorts watches the API server addresses.
 func (api *APIAddresser) WatchAPIHostPorts() (params.NotifyWatchResult, error) {
-	watch := api.getter.WatchAPIHostPorts()
+	var watch state.NotifyWatcher
+	if getter, ok := api.getter.(agentAddressGetter); ok {
+		watch = getter.WatchAgentAPIHostPorts()
+	} else {
+		watch = api.getter.WatchAPIHostPorts()
+	}
 	if _, ok := <-watch.Changes(); ok {
 		return params.NotifyWatchResult{
 			NotifyWatcherId: api.resources.Register(watch),
@@ -59,7 +83,7 @@ func (api *APIAddresser) WatchAPIHostPorts() (params.NotifyWatchResult, error) {
 
 // APIAddresses returns the list of addresses used to connect to the API.
 func (api *APIAddresser) APIAddresses() (params.StringsResult, error) {
-	apiHostPorts, err := api.getter.APIHostPorts()
+	apiHostPorts, err := api.apiHostPorts()
 	if err != nil {
 		return params.StringsResult{}, err
 	}
@@ -76,10 +100,17 @@ func (api *APIAddresser) APIAddresses() (params.StringsResult, error) {
 }
 
 // CACert returns the certificate used to validate the state connection.
-func (a *APIAddresser) CACert() params.BytesResult {
-	return params.BytesResult{
-		Result: []byte(a.getter.CACert()),
+func (a *APIAddresser) CACert() (params.BytesResult, error) {
+	cert := a.getter.CACert()
+	if getter, ok := a.getter.(agentAddressGetter); ok {
+		var err error
+		if cert, err = getter.AgentCACert(); err != nil {
+			return params.BytesResult{}, err
+		}
 	}
+	return params.BytesResult{
+		Result: []byte(cert),
+	}, nil
 }
 
 // StateAddresser implements a common set of methods for getting state
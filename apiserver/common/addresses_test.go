@@ -47,7 +47,8 @@ func (s *apiAddresserSuite) TestAPIAddresses(c *gc.C) {
 }
 
 func (s *apiAddresserSuite) TestCACert(c *gc.C) {
-	result := s.addresser.CACert()
+	result, err := s.addresser.CACert()
+	c.Assert(err, jc.ErrorIsNil)
 	c.Assert(string(result.Result), gc.Equals, "a cert")
 }
 
@@ -0,0 +1,33 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package common
+
+import (
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/state"
+)
+
+// ContainerTemplates converts container templates from state into
+// their API representation.
+func ContainerTemplates(templates []*state.ContainerTemplate) []params.ContainerTemplate {
+	if len(templates) == 0 {
+		return nil
+	}
+	result := make([]params.ContainerTemplate, len(templates))
+	for i, t := range templates {
+		result[i] = params.ContainerTemplate{
+			Kind:     string(t.Kind()),
+			Series:   t.Series(),
+			Packages: t.Packages(),
+			Error:    t.Error(),
+		}
+		if requested := t.PrimeRequested(); !requested.IsZero() {
+			result[i].PrimeRequested = &requested
+		}
+		if built := t.Built(); !built.IsZero() {
+			result[i].Built = &built
+		}
+	}
+	return result
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/juju/txn"
 
 	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/rpc"
 	"github.com/juju/juju/state"
 )
 
@@ -142,8 +143,13 @@ func ServerError(err error) *params.Error {
 	default:
 		code = params.ErrCode(err)
 	}
+	var info map[string]interface{}
+	if err, ok := err.(rpc.ErrorInfoProvider); ok {
+		info = err.ErrorInfo()
+	}
 	return &params.Error{
 		Message: msg,
 		Code:    code,
+		Info:    info,
 	}
 }
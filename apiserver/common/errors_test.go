@@ -5,6 +5,7 @@ package common_test
 
 import (
 	stderrors "errors"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/juju/names"
@@ -152,3 +153,12 @@ func (s *errorsSuite) TestUnknownEnvironment(c *gc.C) {
 	err := common.UnknownEnvironmentError("dead-beef")
 	c.Check(err, gc.ErrorMatches, `unknown environment: "dead-beef"`)
 }
+
+func (s *errorsSuite) TestErrorTransformKeepsInfo(c *gc.C) {
+	err := errors.Annotate(params.NewTryAgainError("busy", time.Second), "login")
+	err1 := common.ServerError(err)
+	c.Assert(err1.Code, gc.Equals, params.CodeTryAgain)
+	delay, ok := params.RetryAfter(err1)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(delay, gc.Equals, time.Second)
+}
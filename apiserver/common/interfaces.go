@@ -5,6 +5,8 @@ package common
 
 import (
 	"github.com/juju/names"
+
+	"github.com/juju/juju/state"
 )
 
 // AuthFunc returns whether the given entity is available to some operation.
@@ -38,6 +40,11 @@ type Authorizer interface {
 	// is a client user.
 	AuthClient() bool
 
+	// AuthEnvironAccess returns whether the authenticated entity is
+	// a client user with at least the given level of access to the
+	// environment.
+	AuthEnvironAccess(access state.Access) bool
+
 	// GetAuthTag returns the tag of the authenticated entity.
 	GetAuthTag() names.Tag
 }
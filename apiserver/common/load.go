@@ -0,0 +1,19 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package common
+
+import (
+	"github.com/juju/juju/apiserver/params"
+)
+
+// LoadResource reports the current load on the API server. It is
+// registered as a named resource of a connection so that facades can
+// report the load of the server they are served by.
+type LoadResource func() params.APIServerLoad
+
+// Stop implements Resource. The load is reported by the API server
+// itself, so there is nothing to do.
+func (LoadResource) Stop() error {
+	return nil
+}
@@ -329,7 +329,8 @@ func (s *deployerSuite) TestAPIAddresses(c *gc.C) {
 }
 
 func (s *deployerSuite) TestCACert(c *gc.C) {
-	result := s.deployer.CACert()
+	result, err := s.deployer.CACert()
+	c.Assert(err, jc.ErrorIsNil)
 	c.Assert(result, gc.DeepEquals, params.BytesResult{
 		Result: []byte(s.State.CACert()),
 	})
@@ -0,0 +1,191 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// The environmentmanager package defines an API end point for functions
+// dealing with the environments hosted by a state server.
+package environmentmanager
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+	"github.com/juju/names"
+	"github.com/juju/utils"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/environs"
+	"github.com/juju/juju/environs/config"
+	"github.com/juju/juju/state"
+)
+
+var logger = loggo.GetLogger("juju.apiserver.environmentmanager")
+
+func init() {
+	common.RegisterStandardFacade("EnvironmentManager", 0, NewEnvironmentManagerAPI)
+}
+
+// EnvironmentManager defines the methods on the environmentmanager API
+// end point.
+type EnvironmentManager interface {
+	CreateEnvironment(args params.EnvironmentCreateArgs) (params.Environment, error)
+	ListEnvironments(user params.Entity) (params.EnvironmentList, error)
+}
+
+// EnvironmentManagerAPI implements the environment manager interface and
+// is the concrete implementation of the api end point.
+type EnvironmentManagerAPI struct {
+	state      *state.State
+	authorizer common.Authorizer
+	check      *common.BlockChecker
+}
+
+var _ EnvironmentManager = (*EnvironmentManagerAPI)(nil)
+
+// NewEnvironmentManagerAPI creates a new api server endpoint for managing
+// environments.
+func NewEnvironmentManagerAPI(
+	st *state.State,
+	resources *common.Resources,
+	authorizer common.Authorizer,
+) (*EnvironmentManagerAPI, error) {
+	if !authorizer.AuthClient() {
+		return nil, common.ErrPerm
+	}
+
+	return &EnvironmentManagerAPI{
+		state:      st,
+		authorizer: authorizer,
+		check:      common.NewBlockChecker(st),
+	}, nil
+}
+
+// authCheck checks that the logged in user may act on behalf of the
+// given user: either it is the same user, or the logged in user has
+// admin access to the environment.
+func (em *EnvironmentManagerAPI) authCheck(user names.UserTag) error {
+	authTag := em.authorizer.GetAuthTag()
+	if authTag == user || em.authorizer.AuthEnvironAccess(state.AdminAccess) {
+		return nil
+	}
+	return common.ErrPerm
+}
+
+// CreateEnvironment creates a new environment hosted by the state
+// server, using the configuration of the state server environment for
+// any values not given in the arguments. The new environment runs its
+// own workers once the state server machines notice it.
+func (em *EnvironmentManagerAPI) CreateEnvironment(args params.EnvironmentCreateArgs) (params.Environment, error) {
+	var result params.Environment
+	if err := em.check.ChangeAllowed(); err != nil {
+		return result, errors.Trace(err)
+	}
+	ownerTag, err := names.ParseUserTag(args.OwnerTag)
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	if err := em.authCheck(ownerTag); err != nil {
+		return result, errors.Trace(err)
+	}
+
+	newConfig, err := em.newEnvironmentConfig(args)
+	if err != nil {
+		return result, errors.Annotate(err, "invalid environment config")
+	}
+	env, st, err := em.state.NewEnvironment(newConfig, ownerTag)
+	if err != nil {
+		return result, errors.Annotate(err, "failed to create new environment")
+	}
+	defer st.Close()
+	logger.Infof("created environment %q (%s) for %s", env.Name(), env.UUID(), ownerTag.Username())
+
+	result.Name = env.Name()
+	result.UUID = env.UUID()
+	result.OwnerTag = env.Owner().String()
+	return result, nil
+}
+
+// newEnvironmentConfig returns the configuration of a new environment
+// with the given arguments, based on the configuration of the state
+// server environment.
+func (em *EnvironmentManagerAPI) newEnvironmentConfig(args params.EnvironmentCreateArgs) (*config.Config, error) {
+	stateServerEnv, err := em.state.StateServerEnvironment()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	st := em.state
+	if st.EnvironUUID() != stateServerEnv.UUID() {
+		st, err = em.state.ForEnviron(stateServerEnv.EnvironTag())
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		defer st.Close()
+	}
+	baseConfig, err := st.EnvironConfig()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	attrs := baseConfig.AllAttrs()
+	// The new environment has its own identity and blocks.
+	delete(attrs, "name")
+	delete(attrs, "uuid")
+	for key := range attrs {
+		if strings.HasPrefix(key, config.BlockKeyPrefix) {
+			delete(attrs, key)
+		}
+	}
+	for key, value := range args.Account {
+		attrs[key] = value
+	}
+	for key, value := range args.Config {
+		attrs[key] = value
+	}
+	if attrs["type"] != baseConfig.Type() {
+		return nil, errors.Errorf("environment type must be %q, the same as the state server", baseConfig.Type())
+	}
+	if _, ok := attrs["name"]; !ok {
+		return nil, errors.New("name not specified")
+	}
+	uuid, err := utils.NewUUID()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	attrs["uuid"] = uuid.String()
+
+	cfg, err := config.New(config.NoDefaults, attrs)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	provider, err := environs.Provider(cfg.Type())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return provider.Validate(cfg, nil)
+}
+
+// ListEnvironments returns the environments that the given user has
+// access to.
+func (em *EnvironmentManagerAPI) ListEnvironments(user params.Entity) (params.EnvironmentList, error) {
+	var result params.EnvironmentList
+	userTag, err := names.ParseUserTag(user.Tag)
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	if err := em.authCheck(userTag); err != nil {
+		return result, errors.Trace(err)
+	}
+	environments, err := em.state.EnvironmentsForUser(userTag)
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	for _, env := range environments {
+		result.Environments = append(result.Environments, params.Environment{
+			Name:     env.Name(),
+			UUID:     env.UUID(),
+			OwnerTag: env.Owner().String(),
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package environmentmanager_test
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/environmentmanager"
+	"github.com/juju/juju/apiserver/params"
+	apiservertesting "github.com/juju/juju/apiserver/testing"
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing/factory"
+)
+
+type envManagerSuite struct {
+	jujutesting.JujuConnSuite
+
+	envmanager *environmentmanager.EnvironmentManagerAPI
+	authorizer apiservertesting.FakeAuthorizer
+}
+
+var _ = gc.Suite(&envManagerSuite{})
+
+func (s *envManagerSuite) SetUpTest(c *gc.C) {
+	s.JujuConnSuite.SetUpTest(c)
+	s.authorizer = apiservertesting.FakeAuthorizer{
+		Tag: s.AdminUserTag(c),
+	}
+	var err error
+	s.envmanager, err = environmentmanager.NewEnvironmentManagerAPI(s.State, nil, s.authorizer)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *envManagerSuite) setAPIUser(c *gc.C, user names.UserTag, access state.Access) {
+	s.authorizer = apiservertesting.FakeAuthorizer{Tag: user, Access: access}
+	var err error
+	s.envmanager, err = environmentmanager.NewEnvironmentManagerAPI(s.State, nil, s.authorizer)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *envManagerSuite) TestNewAPIRefusesNonClient(c *gc.C) {
+	anAuthoriser := s.authorizer
+	anAuthoriser.Tag = names.NewMachineTag("1")
+	endPoint, err := environmentmanager.NewEnvironmentManagerAPI(s.State, nil, anAuthoriser)
+	c.Assert(endPoint, gc.IsNil)
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
+func (s *envManagerSuite) createArgs(owner names.UserTag) params.EnvironmentCreateArgs {
+	return params.EnvironmentCreateArgs{
+		OwnerTag: owner.String(),
+		Account:  map[string]interface{}{"secret": "another-secret"},
+		Config:   map[string]interface{}{"name": "test-env"},
+	}
+}
+
+func (s *envManagerSuite) TestCreateEnvironment(c *gc.C) {
+	owner := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", Access: state.WriteAccess})
+	s.setAPIUser(c, owner.UserTag(), state.WriteAccess)
+
+	result, err := s.envmanager.CreateEnvironment(s.createArgs(owner.UserTag()))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Name, gc.Equals, "test-env")
+	c.Assert(result.OwnerTag, gc.Equals, owner.Tag().String())
+	c.Assert(result.UUID, gc.Not(gc.Equals), s.State.EnvironUUID())
+
+	envTag := names.NewEnvironTag(result.UUID)
+	env, err := s.State.GetEnvironment(envTag)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(env.ServerTag(), gc.Equals, s.State.EnvironTag())
+
+	st, err := s.State.ForEnviron(envTag)
+	c.Assert(err, jc.ErrorIsNil)
+	defer st.Close()
+	cfg, err := st.EnvironConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cfg.Name(), gc.Equals, "test-env")
+	c.Assert(cfg.AllAttrs()["secret"], gc.Equals, "another-secret")
+	uuid, ok := cfg.UUID()
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(uuid, gc.Equals, result.UUID)
+
+	// The owner has admin access to the new environment.
+	envUser, err := st.EnvironmentUser(owner.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(envUser.Access(), gc.Equals, state.AdminAccess)
+}
+
+func (s *envManagerSuite) TestCreateEnvironmentDoesNotInheritBlocks(c *gc.C) {
+	s.AssertConfigParameterUpdated(c, "block-destroy-environment", true)
+	result, err := s.envmanager.CreateEnvironment(s.createArgs(s.AdminUserTag(c)))
+	c.Assert(err, jc.ErrorIsNil)
+
+	st, err := s.State.ForEnviron(names.NewEnvironTag(result.UUID))
+	c.Assert(err, jc.ErrorIsNil)
+	defer st.Close()
+	cfg, err := st.EnvironConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cfg.PreventDestroyEnvironment(), jc.IsFalse)
+}
+
+func (s *envManagerSuite) TestCreateEnvironmentNeedsName(c *gc.C) {
+	args := s.createArgs(s.AdminUserTag(c))
+	args.Config = nil
+	_, err := s.envmanager.CreateEnvironment(args)
+	c.Assert(err, gc.ErrorMatches, "invalid environment config: name not specified")
+}
+
+func (s *envManagerSuite) TestCreateEnvironmentSameType(c *gc.C) {
+	args := s.createArgs(s.AdminUserTag(c))
+	args.Config["type"] = "ec2"
+	_, err := s.envmanager.CreateEnvironment(args)
+	c.Assert(err, gc.ErrorMatches, `invalid environment config: environment type must be "dummy", the same as the state server`)
+}
+
+func (s *envManagerSuite) TestCreateEnvironmentForOtherUserNeedsAdmin(c *gc.C) {
+	bob := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", Access: state.WriteAccess})
+	alice := s.Factory.MakeUser(c, &factory.UserParams{Name: "alice"})
+	s.setAPIUser(c, bob.UserTag(), state.WriteAccess)
+
+	_, err := s.envmanager.CreateEnvironment(s.createArgs(alice.UserTag()))
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+
+	s.setAPIUser(c, s.AdminUserTag(c), state.AdminAccess)
+	result, err := s.envmanager.CreateEnvironment(s.createArgs(alice.UserTag()))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.OwnerTag, gc.Equals, alice.Tag().String())
+}
+
+func (s *envManagerSuite) TestBlockCreateEnvironment(c *gc.C) {
+	s.AssertConfigParameterUpdated(c, "block-all-changes", true)
+	_, err := s.envmanager.CreateEnvironment(s.createArgs(s.AdminUserTag(c)))
+	c.Assert(errors.Cause(err), gc.ErrorMatches, common.ErrOperationBlocked.Error())
+}
+
+func (s *envManagerSuite) TestListEnvironments(c *gc.C) {
+	bob := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", NoEnvUser: true})
+	s.setAPIUser(c, bob.UserTag(), state.WriteAccess)
+	result, err := s.envmanager.ListEnvironments(params.Entity{Tag: bob.Tag().String()})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Environments, gc.HasLen, 0)
+
+	created, err := s.envmanager.CreateEnvironment(s.createArgs(bob.UserTag()))
+	c.Assert(err, jc.ErrorIsNil)
+	result, err = s.envmanager.ListEnvironments(params.Entity{Tag: bob.Tag().String()})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Environments, jc.DeepEquals, []params.Environment{created})
+}
+
+func (s *envManagerSuite) TestListEnvironmentsOfOtherUserNeedsAdmin(c *gc.C) {
+	bob := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", Access: state.WriteAccess})
+	s.setAPIUser(c, bob.UserTag(), state.WriteAccess)
+	_, err := s.envmanager.ListEnvironments(params.Entity{Tag: s.AdminUserTag(c).String()})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
@@ -28,7 +28,10 @@ func ApiHandlerWithEntity(entity state.Entity) *apiHandler {
 	return &apiHandler{entity: entity}
 }
 
-const LoginRateLimit = loginRateLimit
+const (
+	LoginRateLimit     = defaultMaxAgentLogins
+	UserLoginRateLimit = defaultMaxUserLogins
+)
 
 // DelayLogins changes how the Login code works so that logins won't proceed
 // until they get a message on the returned channel.
@@ -56,8 +59,7 @@ func NewErrRoot(err error) *errRoot {
 // Just enough to let you probe some of the interfaces of ApiHandler, but not
 // enough to actually do any RPC calls
 func TestingApiRoot(st *state.State) rpc.MethodFinder {
-	srv := &Server{state: st}
-	h := newApiRoot(srv, common.NewResources(), nil)
+	h := newApiRoot(st, common.NewResources(), nil)
 	return h
 }
 
@@ -68,6 +70,13 @@ func TestingUpgradingRoot(st *state.State) rpc.MethodFinder {
 	return newUpgradingRoot(r)
 }
 
+// TestingAccessRoot returns a srvRoot restricted to the API calls
+// allowed by the authorizer's access to the environment.
+func TestingAccessRoot(st *state.State, auth common.Authorizer) rpc.MethodFinder {
+	r := TestingApiRoot(st)
+	return newAccessRoot(r, auth)
+}
+
 type preFacadeAdminApi struct{}
 
 func newPreFacadeAdminApi(srv *Server, root *apiHandler, reqNotifier *requestNotifier) interface{} {
@@ -140,6 +149,14 @@ func TestingAboutToRestoreRoot(st *state.State) *aboutToRestoreRoot {
 	return newAboutToRestoreRoot(r)
 }
 
+// TestingRestoreGuardedRoot returns a restoreGuardedRoot containing a
+// srvRoot as returned by TestingSrvRoot, whose guard reports the given
+// restore status.
+func TestingRestoreGuardedRoot(st *state.State, status state.RestoreStatus) *restoreGuardedRoot {
+	r := TestingApiRoot(st)
+	return newRestoreGuardedRoot(r, &restoreGuard{st: st, status: status})
+}
+
 // LogLineAgentTag gives tests access to an internal logLine attribute
 func (logLine *logLine) LogLineAgentTag() string {
 	return logLine.agentTag
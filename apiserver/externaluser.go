@@ -0,0 +1,98 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package apiserver
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/juju/names"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/identity"
+	"github.com/juju/juju/state"
+)
+
+// externalUser is the entity of a user authenticated by an external
+// identity provider. Such users have no state.User.
+type externalUser struct {
+	tag names.UserTag
+}
+
+// Tag implements state.Entity.
+func (u *externalUser) Tag() names.Tag {
+	return u.tag
+}
+
+var newIdentityProvider = identity.NewProvider
+
+// checkExternalUserCreds authenticates a user who is not local with
+// the identity provider configured for the state server, if the user
+// belongs to its domain. The user's access to the environment is set
+// from the groups they belong to, so that changes in the identity
+// backend take effect when they next log in.
+//
+// Failed logins by external users are not delayed or locked out by
+// juju; that is left to the identity backend.
+func checkExternalUserCreds(st *state.State, tag names.UserTag, password string) (state.Entity, error) {
+	cfg, err := st.StateServerEnvironConfig()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if cfg.IdentityProvider() == "" || userDomain(tag) != cfg.IdentityDomain() {
+		logger.Debugf("no identity provider for user %q", tag.Username())
+		return nil, common.ErrBadCreds
+	}
+	provider, err := newIdentityProvider(cfg)
+	if err != nil {
+		return nil, errors.Annotate(err, "cannot create identity provider")
+	}
+	groups, err := provider.Authenticate(tag.Name(), password)
+	if errors.IsUnauthorized(err) {
+		return nil, common.ErrBadCreds
+	} else if err != nil {
+		logger.Errorf("cannot authenticate user %q: %v", tag.Username(), err)
+		return nil, errors.Annotate(err, "cannot authenticate user")
+	}
+	access, err := identity.GroupAccess(groups, cfg.IdentityGroupAccess())
+	if err != nil {
+		logger.Infof("rejecting login of %q: %v", tag.Username(), err)
+		return nil, common.ErrBadCreds
+	}
+	if err := ensureEnvironmentUser(st, tag, access); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &externalUser{tag}, nil
+}
+
+// ensureEnvironmentUser adds the user to the environment with the
+// given access, or changes the access of an existing environment user.
+func ensureEnvironmentUser(st *state.State, tag names.UserTag, access state.Access) error {
+	envUser, err := st.EnvironmentUser(tag)
+	if errors.IsNotFound(err) {
+		env, err := st.Environment()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		_, err = st.AddEnvironmentUser(tag, env.Owner(), access)
+		return errors.Annotatef(err, "cannot add user %q to environment", tag.Username())
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	if envUser.Access() == access {
+		return nil
+	}
+	logger.Infof("changing access of %q from %q to %q", tag.Username(), envUser.Access(), access)
+	return errors.Trace(envUser.SetAccess(access))
+}
+
+// userDomain returns the domain of a user: the part of the user
+// name after the "@".
+func userDomain(tag names.UserTag) string {
+	parts := strings.SplitN(tag.Username(), "@", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	return parts[1]
+}
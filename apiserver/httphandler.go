@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/juju/errors"
 	"github.com/juju/names"
 
 	"github.com/juju/juju/apiserver/common"
@@ -27,8 +28,10 @@ type httpHandler struct {
 }
 
 // authenticate parses HTTP basic authentication and authorizes the
-// request by looking up the provided tag and password against state.
-func (h *httpHandler) authenticate(r *http.Request) error {
+// request by looking up the provided tag and password against state,
+// and checking that the user has at least the given access to the
+// environment.
+func (h *httpHandler) authenticate(r *http.Request, access state.Access) error {
 	parts := strings.Fields(r.Header.Get("Authorization"))
 	if len(parts) != 2 || parts[0] != "Basic" {
 		// Invalid header format or no header provided.
@@ -45,7 +48,8 @@ func (h *httpHandler) authenticate(r *http.Request) error {
 		return fmt.Errorf("invalid request format")
 	}
 	// Only allow users, not agents.
-	if _, err := names.ParseUserTag(tagPass[0]); err != nil {
+	userTag, err := names.ParseUserTag(tagPass[0])
+	if err != nil {
 		return common.ErrBadCreds
 	}
 	// Ensure the credentials are correct.
@@ -53,7 +57,17 @@ func (h *httpHandler) authenticate(r *http.Request) error {
 		AuthTag:     tagPass[0],
 		Credentials: tagPass[1],
 	})
-	return err
+	if err != nil {
+		return err
+	}
+	envUser, err := h.state.EnvironmentUser(userTag)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !envUser.Access().Includes(access) {
+		return common.ErrPerm
+	}
+	return nil
 }
 
 func (h *httpHandler) getEnvironUUID(r *http.Request) string {
@@ -0,0 +1,210 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package identity
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/juju/errors"
+)
+
+// The classes of BER elements.
+const (
+	berClassUniversal   = 0x00
+	berClassApplication = 0x40
+	berClassContext     = 0x80
+)
+
+// The universal tags of the BER elements used by LDAP.
+const (
+	berTagBoolean     = 0x01
+	berTagInteger     = 0x02
+	berTagOctetString = 0x04
+	berTagEnumerated  = 0x0a
+	berTagSequence    = 0x10
+	berTagSet         = 0x11
+)
+
+// berMaxLength holds the largest BER element that is read, so that a
+// server cannot make us allocate unbounded memory.
+const berMaxLength = 1 << 24
+
+// berPacket is an element of a BER encoded message, of the subset of
+// BER used by LDAP: definite lengths and tags below 31.
+type berPacket struct {
+	class       byte
+	constructed bool
+	tag         byte
+
+	// data holds the contents of a primitive element.
+	data []byte
+
+	// children holds the elements of a constructed element.
+	children []*berPacket
+}
+
+// newBERPrimitive returns a primitive element with the given contents.
+func newBERPrimitive(class, tag byte, data []byte) *berPacket {
+	return &berPacket{class: class, tag: tag, data: data}
+}
+
+// newBERConstructed returns a constructed element with the given
+// children.
+func newBERConstructed(class, tag byte, children ...*berPacket) *berPacket {
+	return &berPacket{class: class, constructed: true, tag: tag, children: children}
+}
+
+// newBERSequence returns a universal sequence of the given children.
+func newBERSequence(children ...*berPacket) *berPacket {
+	return newBERConstructed(berClassUniversal, berTagSequence, children...)
+}
+
+// newBERString returns a primitive element holding s.
+func newBERString(class, tag byte, s string) *berPacket {
+	return newBERPrimitive(class, tag, []byte(s))
+}
+
+// newBERInteger returns a primitive element holding the two's
+// complement encoding of n.
+func newBERInteger(class, tag byte, n int64) *berPacket {
+	var data []byte
+	for {
+		data = append([]byte{byte(n)}, data...)
+		next := n >> 8
+		// Stop when the remaining bits are only the sign
+		// extension of the byte just encoded.
+		if (next == 0 && data[0]&0x80 == 0) || (next == -1 && data[0]&0x80 != 0) {
+			break
+		}
+		n = next
+	}
+	return newBERPrimitive(class, tag, data)
+}
+
+// newBERBoolean returns a universal boolean element holding b.
+func newBERBoolean(b bool) *berPacket {
+	data := []byte{0}
+	if b {
+		data[0] = 0xff
+	}
+	return newBERPrimitive(berClassUniversal, berTagBoolean, data)
+}
+
+// String returns the contents of a primitive element as a string.
+func (p *berPacket) String() string {
+	return string(p.data)
+}
+
+// Int returns the contents of a primitive element as an integer.
+func (p *berPacket) Int() int64 {
+	var n int64
+	for i, b := range p.data {
+		if i == 0 && b&0x80 != 0 {
+			n = -1
+		}
+		n = n<<8 | int64(b)
+	}
+	return n
+}
+
+// Bytes returns the BER encoding of p.
+func (p *berPacket) Bytes() []byte {
+	contents := p.data
+	if p.constructed {
+		contents = nil
+		for _, child := range p.children {
+			contents = append(contents, child.Bytes()...)
+		}
+	}
+	identifier := p.class | p.tag
+	if p.constructed {
+		identifier |= 0x20
+	}
+	return append(append([]byte{identifier}, berLength(len(contents))...), contents...)
+}
+
+// berLength returns the BER encoding of the given length.
+func berLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var data []byte
+	for ; n > 0; n >>= 8 {
+		data = append([]byte{byte(n)}, data...)
+	}
+	return append([]byte{0x80 | byte(len(data))}, data...)
+}
+
+// readBERPacket reads a BER element from r.
+func readBERPacket(r *bufio.Reader) (*berPacket, error) {
+	identifier, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	length, err := readBERLength(r)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	contents := make([]byte, length)
+	if _, err := io.ReadFull(r, contents); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return newBERPacket(identifier, contents)
+}
+
+// readBERLength reads the length of a BER element from r.
+func readBERLength(r *bufio.Reader) (int, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b < 0x80 {
+		return int(b), nil
+	}
+	size := int(b & 0x7f)
+	if size == 0 || size > 4 {
+		return 0, errors.Errorf("unsupported BER length encoding %#x", b)
+	}
+	length := 0
+	for i := 0; i < size; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		length = length<<8 | int(b)
+	}
+	if length < 0 || length > berMaxLength {
+		return 0, errors.Errorf("BER element too long (%d bytes)", length)
+	}
+	return length, nil
+}
+
+// newBERPacket returns the element with the given identifier octet and
+// contents.
+func newBERPacket(identifier byte, contents []byte) (*berPacket, error) {
+	if identifier&0x1f == 0x1f {
+		return nil, errors.Errorf("unsupported BER tag %#x", identifier)
+	}
+	p := &berPacket{
+		class:       identifier & 0xc0,
+		constructed: identifier&0x20 != 0,
+		tag:         identifier & 0x1f,
+	}
+	if !p.constructed {
+		p.data = contents
+		return p, nil
+	}
+	r := bufio.NewReader(bytes.NewReader(contents))
+	for {
+		child, err := readBERPacket(r)
+		if err == io.EOF {
+			return p, nil
+		} else if err != nil {
+			return nil, errors.Annotate(err, "invalid BER element")
+		}
+		p.children = append(p.children, child)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package identity
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+type berSuite struct{}
+
+var _ = gc.Suite(&berSuite{})
+
+func readBERBytes(data []byte) (*berPacket, error) {
+	return readBERPacket(bufio.NewReader(bytes.NewReader(data)))
+}
+
+func (s *berSuite) TestIntegers(c *gc.C) {
+	for _, test := range []struct {
+		n       int64
+		encoded []byte
+	}{
+		{0, []byte{0x02, 0x01, 0x00}},
+		{127, []byte{0x02, 0x01, 0x7f}},
+		{128, []byte{0x02, 0x02, 0x00, 0x80}},
+		{256, []byte{0x02, 0x02, 0x01, 0x00}},
+		{-1, []byte{0x02, 0x01, 0xff}},
+		{-128, []byte{0x02, 0x01, 0x80}},
+		{-129, []byte{0x02, 0x02, 0xff, 0x7f}},
+	} {
+		p := newBERInteger(berClassUniversal, berTagInteger, test.n)
+		c.Check(p.Bytes(), jc.DeepEquals, test.encoded, gc.Commentf("%d", test.n))
+		read, err := readBERBytes(test.encoded)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(read.Int(), gc.Equals, test.n)
+	}
+}
+
+func (s *berSuite) TestConstructed(c *gc.C) {
+	long := strings.Repeat("x", 300)
+	p := newBERConstructed(berClassApplication, ldapBindRequest,
+		newBERString(berClassUniversal, berTagOctetString, "short"),
+		newBERString(berClassContext, 0, long),
+	)
+	encoded := p.Bytes()
+	c.Assert(encoded[:4], jc.DeepEquals, []byte{0x60, 0x82, 0x01, 0x37})
+
+	read, err := readBERBytes(encoded)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(read.class, gc.Equals, byte(berClassApplication))
+	c.Assert(read.tag, gc.Equals, byte(ldapBindRequest))
+	c.Assert(read.children, gc.HasLen, 2)
+	c.Assert(read.children[0].String(), gc.Equals, "short")
+	c.Assert(read.children[1].class, gc.Equals, byte(berClassContext))
+	c.Assert(read.children[1].String(), gc.Equals, long)
+}
+
+func (s *berSuite) TestReadInvalid(c *gc.C) {
+	for _, data := range [][]byte{
+		// Indefinite length.
+		{0x30, 0x80, 0x00, 0x00},
+		// Truncated contents.
+		{0x04, 0x05, 'a'},
+		// Truncated child.
+		{0x30, 0x03, 0x04, 0x05, 'a'},
+		// Too long.
+		{0x04, 0x84, 0x7f, 0xff, 0xff, 0xff},
+	} {
+		_, err := readBERBytes(data)
+		c.Check(err, gc.NotNil, gc.Commentf("%x", data))
+	}
+}
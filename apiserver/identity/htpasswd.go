@@ -0,0 +1,195 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package identity
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+
+	"github.com/juju/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HtpasswdProvider authenticates users with the contents of an Apache
+// htpasswd file. Passwords may be hashed with bcrypt, MD5 ($apr1$) or
+// SHA1 ({SHA}); crypt(3) and plain text passwords are not supported.
+// The groups of users are taken from the contents of an Apache groups
+// file, with lines like "group: user1 user2".
+type HtpasswdProvider struct {
+	passwords map[string]string
+	groups    map[string][]string
+}
+
+var _ Provider = (*HtpasswdProvider)(nil)
+
+// NewHtpasswdProvider returns a provider that authenticates users
+// with the given htpasswd and groups file contents.
+func NewHtpasswdProvider(htpasswd, htgroups string) (*HtpasswdProvider, error) {
+	p := &HtpasswdProvider{
+		passwords: make(map[string]string),
+		groups:    make(map[string][]string),
+	}
+	err := parseLines(htpasswd, func(line string) error {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return errors.Errorf("invalid htpasswd line %q", line)
+		}
+		p.passwords[parts[0]] = parts[1]
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	err = parseLines(htgroups, func(line string) error {
+		parts := strings.SplitN(line, ":", 2)
+		group := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || group == "" {
+			return errors.Errorf("invalid htgroups line %q", line)
+		}
+		for _, user := range strings.Fields(parts[1]) {
+			p.groups[user] = append(p.groups[user], group)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return p, nil
+}
+
+// parseLines calls f for each line of s that is neither blank nor a
+// comment.
+func parseLines(s string, f func(line string) error) error {
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := f(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Authenticate implements Provider.
+func (p *HtpasswdProvider) Authenticate(username, password string) ([]string, error) {
+	hash, ok := p.passwords[username]
+	if !ok {
+		return nil, errBadCreds
+	}
+	valid, err := checkHtpasswd(hash, password)
+	if err != nil {
+		logger.Warningf("cannot check password of %q: %v", username, err)
+		return nil, errBadCreds
+	}
+	if !valid {
+		return nil, errBadCreds
+	}
+	return p.groups[username], nil
+}
+
+// checkHtpasswd returns whether the password matches the hash from an
+// htpasswd file.
+func checkHtpasswd(hash, password string) (bool, error) {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2y$"), strings.HasPrefix(hash, "$2b$"):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, nil
+		}
+		return err == nil, errors.Trace(err)
+	case strings.HasPrefix(hash, apr1Magic):
+		salt := strings.SplitN(hash[len(apr1Magic):], "$", 2)[0]
+		return constantTimeEqual(hash, apr1Hash(password, salt)), nil
+	case strings.HasPrefix(hash, "{SHA}"):
+		sum := sha1.Sum([]byte(password))
+		return constantTimeEqual(hash, "{SHA}"+base64.StdEncoding.EncodeToString(sum[:])), nil
+	}
+	return false, errors.New("unsupported password hash")
+}
+
+func constantTimeEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+const (
+	apr1Magic = "$apr1$"
+	apr1Chars = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// apr1Hash returns the Apache variant of the MD5-based crypt(3) hash
+// of the password, as written by "htpasswd -m".
+func apr1Hash(password, salt string) string {
+	if len(salt) > 8 {
+		salt = salt[:8]
+	}
+	pw := []byte(password)
+
+	alt := md5.New()
+	alt.Write(pw)
+	alt.Write([]byte(salt))
+	alt.Write(pw)
+	altSum := alt.Sum(nil)
+
+	h := md5.New()
+	h.Write(pw)
+	h.Write([]byte(apr1Magic))
+	h.Write([]byte(salt))
+	for i := len(pw); i > 0; i -= 16 {
+		if i > 16 {
+			h.Write(altSum)
+		} else {
+			h.Write(altSum[:i])
+		}
+	}
+	for i := len(pw); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			h.Write([]byte{0})
+		} else {
+			h.Write(pw[:1])
+		}
+	}
+	sum := h.Sum(nil)
+
+	// Stretch the hash to slow down brute force attacks.
+	for i := 0; i < 1000; i++ {
+		h := md5.New()
+		if i&1 != 0 {
+			h.Write(pw)
+		} else {
+			h.Write(sum)
+		}
+		if i%3 != 0 {
+			h.Write([]byte(salt))
+		}
+		if i%7 != 0 {
+			h.Write(pw)
+		}
+		if i&1 != 0 {
+			h.Write(sum)
+		} else {
+			h.Write(pw)
+		}
+		sum = h.Sum(nil)
+	}
+
+	result := []byte(apr1Magic + salt + "$")
+	encode := func(v uint, n int) {
+		for ; n > 0; n-- {
+			result = append(result, apr1Chars[v&0x3f])
+			v >>= 6
+		}
+	}
+	for _, i := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		encode(uint(sum[i[0]])<<16|uint(sum[i[1]])<<8|uint(sum[i[2]]), 4)
+	}
+	encode(uint(sum[11]), 2)
+	return string(result)
+}
@@ -0,0 +1,80 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package identity_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/identity"
+)
+
+type htpasswdSuite struct{}
+
+var _ = gc.Suite(&htpasswdSuite{})
+
+// All the users have the password "password".
+const htpasswd = `
+# Written by htpasswd -m.
+bob:$apr1$abcdefgh$FBwExRW4dCc8aL.OvjpIE1
+
+# Written by htpasswd -s.
+alice:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=
+
+# Written by htpasswd -d, which is not supported.
+carol:cO9m8KgYGGdHk
+`
+
+const htgroups = `
+ops: bob
+dev: alice bob
+`
+
+func (s *htpasswdSuite) TestAuthenticate(c *gc.C) {
+	p, err := identity.NewHtpasswdProvider(htpasswd, htgroups)
+	c.Assert(err, jc.ErrorIsNil)
+
+	groups, err := p.Authenticate("bob", "password")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(groups, jc.DeepEquals, []string{"ops", "dev"})
+
+	groups, err = p.Authenticate("alice", "password")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(groups, jc.DeepEquals, []string{"dev"})
+}
+
+func (s *htpasswdSuite) TestAuthenticateFails(c *gc.C) {
+	p, err := identity.NewHtpasswdProvider(htpasswd, htgroups)
+	c.Assert(err, jc.ErrorIsNil)
+	for i, test := range []struct {
+		username string
+		password string
+	}{
+		{"bob", "wrong"},
+		{"alice", "wrong"},
+		{"alice", ""},
+		{"carol", "password"},
+		{"dave", "password"},
+	} {
+		c.Logf("test %d: %s", i, test.username)
+		_, err := p.Authenticate(test.username, test.password)
+		c.Check(err, jc.Satisfies, errors.IsUnauthorized)
+	}
+}
+
+func (s *htpasswdSuite) TestUserWithoutGroups(c *gc.C) {
+	p, err := identity.NewHtpasswdProvider(htpasswd, "")
+	c.Assert(err, jc.ErrorIsNil)
+	groups, err := p.Authenticate("bob", "password")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(groups, gc.HasLen, 0)
+}
+
+func (s *htpasswdSuite) TestInvalidFiles(c *gc.C) {
+	_, err := identity.NewHtpasswdProvider("bob", "")
+	c.Assert(err, gc.ErrorMatches, `invalid htpasswd line "bob"`)
+	_, err = identity.NewHtpasswdProvider(htpasswd, "ops bob")
+	c.Assert(err, gc.ErrorMatches, `invalid htgroups line "ops bob"`)
+}
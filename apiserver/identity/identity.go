@@ -0,0 +1,69 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// Package identity implements the external identity providers that
+// users can log in to the API server with, instead of having a juju
+// password.
+package identity
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+
+	"github.com/juju/juju/environs/config"
+	"github.com/juju/juju/state"
+)
+
+var logger = loggo.GetLogger("juju.apiserver.identity")
+
+// Provider authenticates users against an external identity backend.
+type Provider interface {
+	// Authenticate checks the password of the named user, and returns
+	// the names of the groups the user belongs to. It returns an
+	// error satisfying errors.IsUnauthorized if the user is unknown
+	// or the password is wrong.
+	Authenticate(username, password string) ([]string, error)
+}
+
+// errBadCreds is returned by providers when a user cannot be
+// authenticated.
+var errBadCreds = errors.Unauthorizedf("invalid user name or password")
+
+// NewProvider returns the identity provider configured in the given
+// state server environment configuration, or nil if there is none.
+func NewProvider(cfg *config.Config) (Provider, error) {
+	switch cfg.IdentityProvider() {
+	case "":
+		return nil, nil
+	case config.IdentityProviderLDAP:
+		return &LDAPProvider{
+			URL:       cfg.IdentityLDAPURL(),
+			UserDN:    cfg.IdentityLDAPUserDN(),
+			GroupBase: cfg.IdentityLDAPGroupBase(),
+		}, nil
+	case config.IdentityProviderHtpasswd:
+		return NewHtpasswdProvider(cfg.IdentityHtpasswd(), cfg.IdentityHtgroups())
+	}
+	return nil, errors.NotValidf("identity provider %q", cfg.IdentityProvider())
+}
+
+// GroupAccess returns the highest access to environments granted to
+// any of the given groups by groupAccess, which maps group names to
+// access levels. It returns an error satisfying errors.IsUnauthorized
+// if none of the groups is granted access.
+func GroupAccess(groups []string, groupAccess map[string]string) (state.Access, error) {
+	var result state.Access
+	for _, group := range groups {
+		access, err := state.ParseAccess(groupAccess[group])
+		if err != nil {
+			continue
+		}
+		if !result.Includes(access) {
+			result = access
+		}
+	}
+	if result == "" {
+		return "", errors.Unauthorizedf("no access granted to groups %q", groups)
+	}
+	return result, nil
+}
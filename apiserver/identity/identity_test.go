@@ -0,0 +1,87 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package identity_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/identity"
+	"github.com/juju/juju/state"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type identitySuite struct {
+	coretesting.BaseSuite
+}
+
+var _ = gc.Suite(&identitySuite{})
+
+func (s *identitySuite) TestGroupAccess(c *gc.C) {
+	groupAccess := map[string]string{
+		"ops":   "admin",
+		"dev":   "write",
+		"staff": "read",
+	}
+	for i, test := range []struct {
+		groups []string
+		access state.Access
+	}{
+		{[]string{"staff"}, state.ReadAccess},
+		{[]string{"staff", "dev"}, state.WriteAccess},
+		{[]string{"dev", "other", "staff"}, state.WriteAccess},
+		{[]string{"staff", "ops", "dev"}, state.AdminAccess},
+	} {
+		c.Logf("test %d: %v", i, test.groups)
+		access, err := identity.GroupAccess(test.groups, groupAccess)
+		c.Check(err, jc.ErrorIsNil)
+		c.Check(access, gc.Equals, test.access)
+	}
+}
+
+func (s *identitySuite) TestGroupAccessNoGroups(c *gc.C) {
+	groupAccess := map[string]string{"ops": "admin"}
+	_, err := identity.GroupAccess(nil, groupAccess)
+	c.Assert(err, jc.Satisfies, errors.IsUnauthorized)
+	_, err = identity.GroupAccess([]string{"dev"}, groupAccess)
+	c.Assert(err, jc.Satisfies, errors.IsUnauthorized)
+}
+
+func (s *identitySuite) TestNewProviderNone(c *gc.C) {
+	p, err := identity.NewProvider(coretesting.EnvironConfig(c))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(p, gc.IsNil)
+}
+
+func (s *identitySuite) TestNewProviderLDAP(c *gc.C) {
+	cfg := coretesting.CustomEnvironConfig(c, coretesting.Attrs{
+		"identity-provider":        "ldap",
+		"identity-ldap-url":        "ldap://ldap.example.com",
+		"identity-ldap-user-dn":    "uid=%s,ou=people,dc=example,dc=com",
+		"identity-ldap-group-base": "ou=groups,dc=example,dc=com",
+		"identity-group-access":    "ops=admin",
+	})
+	p, err := identity.NewProvider(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(p, jc.DeepEquals, &identity.LDAPProvider{
+		URL:       "ldap://ldap.example.com",
+		UserDN:    "uid=%s,ou=people,dc=example,dc=com",
+		GroupBase: "ou=groups,dc=example,dc=com",
+	})
+}
+
+func (s *identitySuite) TestNewProviderHtpasswd(c *gc.C) {
+	cfg := coretesting.CustomEnvironConfig(c, coretesting.Attrs{
+		"identity-provider":     "htpasswd",
+		"identity-htpasswd":     htpasswd,
+		"identity-htgroups":     htgroups,
+		"identity-group-access": "ops=admin",
+	})
+	p, err := identity.NewProvider(cfg)
+	c.Assert(err, jc.ErrorIsNil)
+	groups, err := p.Authenticate("bob", "password")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(groups, jc.DeepEquals, []string{"ops", "dev"})
+}
@@ -0,0 +1,166 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package identity
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// ldapGroupNameAttr holds the attribute of group entries that names
+// the group.
+const ldapGroupNameAttr = "cn"
+
+// ldapTimeout holds how long a login may take to talk to the LDAP
+// server.
+var ldapTimeout = 10 * time.Second
+
+// LDAPProvider authenticates users by binding to an LDAP server as
+// them. The groups of a user are the cn of the entries under the group
+// base DN that list the user as a member, either by DN (groupOfNames)
+// or by user name (posixGroup).
+//
+// Passwords are never sent in the clear: ldaps connections use TLS
+// from the start, and ldap connections are upgraded with StartTLS
+// before binding, failing if the server does not support it.
+type LDAPProvider struct {
+	// URL holds the URL of the server, as ldap://host[:port] or
+	// ldaps://host[:port].
+	URL string
+
+	// UserDN holds the template of the DN of a user, with %s
+	// standing for the user name.
+	UserDN string
+
+	// GroupBase holds the DN under which groups are searched for.
+	GroupBase string
+
+	// TLSConfig, if not nil, is used to secure the connections to
+	// the server.
+	TLSConfig *tls.Config
+}
+
+var _ Provider = (*LDAPProvider)(nil)
+
+// Authenticate implements Provider.
+func (p *LDAPProvider) Authenticate(username, password string) ([]string, error) {
+	if password == "" {
+		// An LDAP simple bind with an empty password is an
+		// unauthenticated bind, which most servers allow.
+		return nil, errBadCreds
+	}
+	conn, err := p.dial()
+	if err != nil {
+		return nil, errors.Annotate(err, "cannot connect to LDAP server")
+	}
+	defer conn.Close()
+
+	userDN := fmt.Sprintf(p.UserDN, escapeDN(username))
+	if err := conn.Bind(userDN, password); isLDAPError(err, ldapResultInvalidCredentials) {
+		return nil, errBadCreds
+	} else if err != nil {
+		return nil, errors.Annotate(err, "LDAP bind failed")
+	}
+	groups, err := searchGroups(conn, p.GroupBase, userDN, username)
+	if err != nil {
+		return nil, errors.Annotate(err, "cannot find LDAP groups")
+	}
+	return groups, nil
+}
+
+// dial connects to the LDAP server and secures the connection.
+func (p *LDAPProvider) dial() (*ldapConn, error) {
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	tlsConfig := p.tlsConfig(u.Host)
+	dialer := &net.Dialer{Timeout: ldapTimeout}
+	var netConn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		netConn, err = dialer.Dial("tcp", hostPort(u.Host, "389"))
+	case "ldaps":
+		netConn, err = tls.DialWithDialer(dialer, "tcp", hostPort(u.Host, "636"), tlsConfig)
+	default:
+		return nil, errors.Errorf("unsupported LDAP URL scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	netConn.SetDeadline(time.Now().Add(ldapTimeout))
+	conn := newLDAPConn(netConn)
+	if u.Scheme == "ldap" {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, errors.Annotate(err, "cannot start TLS")
+		}
+	}
+	return conn, nil
+}
+
+// tlsConfig returns the TLS configuration to use for connections to
+// the given host.
+func (p *LDAPProvider) tlsConfig(host string) *tls.Config {
+	var config tls.Config
+	if p.TLSConfig != nil {
+		config = *p.TLSConfig
+	}
+	if config.ServerName == "" {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		config.ServerName = host
+	}
+	return &config
+}
+
+// hostPort adds the default port to host if it has none.
+func hostPort(host, defaultPort string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, defaultPort)
+}
+
+// searchGroups returns the names of the groups under base that have
+// the user as a member.
+func searchGroups(conn *ldapConn, base, userDN, username string) ([]string, error) {
+	matches := []ldapMatch{
+		{"member", userDN},
+		{"uniqueMember", userDN},
+		{"memberUid", username},
+	}
+	groups, err := conn.SearchAny(base, matches, ldapGroupNameAttr, int(ldapTimeout/time.Second))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return groups, nil
+}
+
+// escapeDN escapes the characters of s that are special in a DN
+// attribute value; see RFC 4514.
+func escapeDN(s string) string {
+	var buf []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case strings.IndexByte(`,+"\<>;=`, c) >= 0,
+			c == '#' && i == 0,
+			c == ' ' && (i == 0 || i == len(s)-1):
+			buf = append(buf, '\\', c)
+		case c == 0:
+			buf = append(buf, `\00`...)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return string(buf)
+}
@@ -0,0 +1,345 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package identity
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	coretesting "github.com/juju/juju/testing"
+)
+
+type ldapSuite struct {
+	server   *fakeLDAPServer
+	provider *LDAPProvider
+}
+
+var _ = gc.Suite(&ldapSuite{})
+
+func (s *ldapSuite) SetUpTest(c *gc.C) {
+	s.server = newFakeLDAPServer(c, false)
+	s.server.users["uid=bob,ou=people,dc=example,dc=com"] = "password"
+	s.server.users[`uid=eve\,ou\=admins,ou=people,dc=example,dc=com`] = "password"
+	s.server.groups["ops"] = []string{"uid=bob,ou=people,dc=example,dc=com"}
+	s.server.groups["dev"] = []string{"bob", "alice"}
+	caCerts := x509.NewCertPool()
+	c.Assert(caCerts.AppendCertsFromPEM([]byte(coretesting.CACert)), jc.IsTrue)
+	s.provider = &LDAPProvider{
+		URL:       "ldap://" + s.server.addr(),
+		UserDN:    "uid=%s,ou=people,dc=example,dc=com",
+		GroupBase: "ou=groups,dc=example,dc=com",
+		TLSConfig: &tls.Config{
+			RootCAs:    caCerts,
+			ServerName: "juju-apiserver",
+		},
+	}
+}
+
+func (s *ldapSuite) TearDownTest(c *gc.C) {
+	s.server.Close()
+}
+
+func (s *ldapSuite) TestAuthenticate(c *gc.C) {
+	groups, err := s.provider.Authenticate("bob", "password")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(groups, jc.DeepEquals, []string{"dev", "ops"})
+	c.Assert(s.server.searchBases, jc.DeepEquals, []string{"ou=groups,dc=example,dc=com"})
+	c.Assert(s.server.insecureBinds, gc.Equals, 0)
+}
+
+func (s *ldapSuite) TestAuthenticateLDAPS(c *gc.C) {
+	s.server.Close()
+	s.server = newFakeLDAPServer(c, true)
+	s.server.users["uid=bob,ou=people,dc=example,dc=com"] = "password"
+	s.provider.URL = "ldaps://" + s.server.addr()
+	_, err := s.provider.Authenticate("bob", "password")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.server.binds, gc.HasLen, 1)
+	c.Assert(s.server.insecureBinds, gc.Equals, 0)
+}
+
+func (s *ldapSuite) TestAuthenticateRequiresStartTLS(c *gc.C) {
+	s.server.noStartTLS = true
+	_, err := s.provider.Authenticate("bob", "password")
+	c.Assert(err, gc.ErrorMatches, "cannot connect to LDAP server: cannot start TLS: .*")
+	c.Assert(s.server.binds, gc.HasLen, 0)
+}
+
+func (s *ldapSuite) TestAuthenticateUntrustedServer(c *gc.C) {
+	s.provider.TLSConfig = nil
+	_, err := s.provider.Authenticate("bob", "password")
+	c.Assert(err, gc.ErrorMatches, "cannot connect to LDAP server: cannot start TLS: .*")
+	c.Assert(s.server.binds, gc.HasLen, 0)
+}
+
+func (s *ldapSuite) TestAuthenticateWrongPassword(c *gc.C) {
+	_, err := s.provider.Authenticate("bob", "wrong")
+	c.Assert(err, jc.Satisfies, errors.IsUnauthorized)
+}
+
+func (s *ldapSuite) TestAuthenticateUnknownUser(c *gc.C) {
+	_, err := s.provider.Authenticate("mallory", "password")
+	c.Assert(err, jc.Satisfies, errors.IsUnauthorized)
+}
+
+func (s *ldapSuite) TestAuthenticateEmptyPassword(c *gc.C) {
+	_, err := s.provider.Authenticate("bob", "")
+	c.Assert(err, jc.Satisfies, errors.IsUnauthorized)
+	c.Assert(s.server.binds, gc.HasLen, 0)
+}
+
+func (s *ldapSuite) TestAuthenticateEscapesUserName(c *gc.C) {
+	// Without escaping, the user name would select a different DN.
+	_, err := s.provider.Authenticate("eve,ou=admins", "password")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.server.binds, jc.DeepEquals, []string{`uid=eve\,ou\=admins,ou=people,dc=example,dc=com`})
+}
+
+func (s *ldapSuite) TestAuthenticateServerError(c *gc.C) {
+	s.server.bindResult = 52 // unavailable
+	_, err := s.provider.Authenticate("bob", "password")
+	c.Assert(err, gc.ErrorMatches, `LDAP bind failed: LDAP Result Code 52 .*: server unavailable`)
+	c.Assert(err, gc.Not(jc.Satisfies), errors.IsUnauthorized)
+}
+
+func (s *ldapSuite) TestAuthenticateNoServer(c *gc.C) {
+	s.server.Close()
+	_, err := s.provider.Authenticate("bob", "password")
+	c.Assert(err, gc.ErrorMatches, "cannot connect to LDAP server: .*")
+}
+
+func (s *ldapSuite) TestEscapeDN(c *gc.C) {
+	for _, test := range []struct {
+		in, out string
+	}{
+		{"bob", "bob"},
+		{"a,b", `a\,b`},
+		{`a+b"c\d<e>f;g=h`, `a\+b\"c\\d\<e\>f\;g\=h`},
+		{"#bob", `\#bob`},
+		{"b#ob", "b#ob"},
+		{" bob ", `\ bob\ `},
+		{"b o b", "b o b"},
+		{"b\x00b", `b\00b`},
+	} {
+		c.Check(escapeDN(test.in), gc.Equals, test.out)
+	}
+}
+
+// fakeLDAPServer is an in-process stand-in for an LDAP server. It
+// understands StartTLS, simple binds, and searches for groups as sent
+// by LDAPProvider.
+type fakeLDAPServer struct {
+	c        *gc.C
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	tls bool
+
+	// noStartTLS holds whether StartTLS requests are refused.
+	noStartTLS bool
+
+	// users maps user DNs to passwords.
+	users map[string]string
+
+	// groups maps group names to their members, either user DNs or
+	// user names.
+	groups map[string][]string
+
+	// bindResult, if not zero, is returned for all binds.
+	bindResult int64
+
+	mu            sync.Mutex
+	binds         []string
+	insecureBinds int
+	searchBases   []string
+}
+
+// newFakeLDAPServer starts a fake LDAP server. If useTLS is true,
+// connections use TLS from the start, as with ldaps.
+func newFakeLDAPServer(c *gc.C, useTLS bool) *fakeLDAPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, jc.ErrorIsNil)
+	srv := &fakeLDAPServer{
+		c:        c,
+		listener: listener,
+		tls:      useTLS,
+		users:    make(map[string]string),
+		groups:   make(map[string][]string),
+	}
+	srv.wg.Add(1)
+	go srv.run()
+	return srv
+}
+
+func (srv *fakeLDAPServer) addr() string {
+	return srv.listener.Addr().String()
+}
+
+// Close stops the server. It may be called more than once.
+func (srv *fakeLDAPServer) Close() {
+	srv.listener.Close()
+	srv.wg.Wait()
+}
+
+func (srv *fakeLDAPServer) tlsConfig() *tls.Config {
+	cert, err := tls.X509KeyPair([]byte(coretesting.ServerCert), []byte(coretesting.ServerKey))
+	srv.c.Assert(err, jc.ErrorIsNil)
+	return &tls.Config{Certificates: []tls.Certificate{cert}}
+}
+
+func (srv *fakeLDAPServer) run() {
+	defer srv.wg.Done()
+	for {
+		conn, err := srv.listener.Accept()
+		if err != nil {
+			return
+		}
+		if srv.tls {
+			conn = tls.Server(conn, srv.tlsConfig())
+		}
+		srv.wg.Add(1)
+		go func() {
+			defer srv.wg.Done()
+			defer conn.Close()
+			srv.serve(conn, srv.tls)
+		}()
+	}
+}
+
+func (srv *fakeLDAPServer) serve(conn net.Conn, secure bool) {
+	r := bufio.NewReader(conn)
+	for {
+		msg, err := readBERPacket(r)
+		if err != nil {
+			return
+		}
+		id, op := msg.children[0].Int(), msg.children[1]
+		var replies []*berPacket
+		startTLS := false
+		switch op.tag {
+		case ldapBindRequest:
+			replies = srv.bind(op, secure)
+		case ldapSearchRequest:
+			replies = srv.search(op)
+		case ldapExtendedRequest:
+			replies, startTLS = srv.extended(op, secure)
+		case ldapUnbindRequest:
+			return
+		default:
+			srv.c.Errorf("unexpected LDAP request %d", op.tag)
+			return
+		}
+		for _, reply := range replies {
+			msg := newBERSequence(
+				newBERInteger(berClassUniversal, berTagInteger, id),
+				reply,
+			)
+			if _, err := conn.Write(msg.Bytes()); err != nil {
+				return
+			}
+		}
+		if startTLS {
+			tlsConn := tls.Server(conn, srv.tlsConfig())
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, secure = tlsConn, true
+			r = bufio.NewReader(conn)
+		}
+	}
+}
+
+func ldapResultPacket(tag byte, code int64, message string) *berPacket {
+	return newBERConstructed(berClassApplication, tag,
+		newBERInteger(berClassUniversal, berTagEnumerated, code),
+		newBERString(berClassUniversal, berTagOctetString, ""),
+		newBERString(berClassUniversal, berTagOctetString, message),
+	)
+}
+
+func (srv *fakeLDAPServer) extended(op *berPacket, secure bool) ([]*berPacket, bool) {
+	if len(op.children) == 0 || op.children[0].String() != ldapStartTLSOID {
+		return []*berPacket{ldapResultPacket(ldapExtendedResponse, 2, "unsupported extended operation")}, false
+	}
+	if srv.noStartTLS || secure {
+		return []*berPacket{ldapResultPacket(ldapExtendedResponse, 53, "unwilling to perform")}, false
+	}
+	return []*berPacket{ldapResultPacket(ldapExtendedResponse, 0, "")}, true
+}
+
+func (srv *fakeLDAPServer) bind(op *berPacket, secure bool) []*berPacket {
+	dn := op.children[1].String()
+	password := op.children[2].String()
+	srv.mu.Lock()
+	srv.binds = append(srv.binds, dn)
+	if !secure {
+		srv.insecureBinds++
+	}
+	srv.mu.Unlock()
+	switch {
+	case srv.bindResult != 0:
+		return []*berPacket{ldapResultPacket(ldapBindResponse, srv.bindResult, "server unavailable")}
+	case password == "" || srv.users[dn] != password:
+		return []*berPacket{ldapResultPacket(ldapBindResponse, 49, "invalid credentials")}
+	}
+	return []*berPacket{ldapResultPacket(ldapBindResponse, 0, "")}
+}
+
+func (srv *fakeLDAPServer) search(op *berPacket) []*berPacket {
+	base := op.children[0].String()
+	srv.mu.Lock()
+	srv.searchBases = append(srv.searchBases, base)
+	srv.mu.Unlock()
+
+	// The filter is an "or" of equality matches on membership
+	// attributes.
+	filter := op.children[6]
+	if filter.class != berClassContext || filter.tag != ldapFilterOr {
+		srv.c.Errorf("unexpected filter %d", filter.tag)
+		return []*berPacket{ldapResultPacket(ldapSearchResultDone, 53, "unwilling to perform")}
+	}
+	members := make(map[string]bool)
+	for _, match := range filter.children {
+		members[match.children[1].String()] = true
+	}
+	var replies []*berPacket
+	for _, name := range sortedKeys(srv.groups) {
+		for _, member := range srv.groups[name] {
+			if !members[member] {
+				continue
+			}
+			entry := newBERConstructed(berClassApplication, ldapSearchResultEntry,
+				newBERString(berClassUniversal, berTagOctetString, "cn="+name+","+base),
+				newBERSequence(
+					newBERSequence(
+						newBERString(berClassUniversal, berTagOctetString, "cn"),
+						newBERConstructed(berClassUniversal, berTagSet,
+							newBERString(berClassUniversal, berTagOctetString, name),
+						),
+					),
+				),
+			)
+			replies = append(replies, entry)
+			break
+		}
+	}
+	return append(replies, ldapResultPacket(ldapSearchResultDone, 0, ""))
+}
+
+func sortedKeys(m map[string][]string) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,236 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package identity
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+
+	"github.com/juju/errors"
+)
+
+// The application tags of the LDAP operations used by LDAPProvider;
+// see RFC 4511.
+const (
+	ldapBindRequest       = 0
+	ldapBindResponse      = 1
+	ldapUnbindRequest     = 2
+	ldapSearchRequest     = 3
+	ldapSearchResultEntry = 4
+	ldapSearchResultDone  = 5
+	ldapSearchResultRef   = 19
+	ldapExtendedRequest   = 23
+	ldapExtendedResponse  = 24
+)
+
+// The context tags of the search filters used by LDAPProvider.
+const (
+	ldapFilterOr            = 1
+	ldapFilterEqualityMatch = 3
+)
+
+// The LDAP result codes that are told apart.
+const (
+	ldapResultSuccess            = 0
+	ldapResultInvalidCredentials = 49
+)
+
+// The values of the fields of the LDAP requests used by LDAPProvider.
+const (
+	ldapProtocolVersion   = 3
+	ldapScopeWholeSubtree = 2
+	ldapNeverDerefAliases = 0
+	ldapStartTLSOID       = "1.3.6.1.4.1.1466.20037"
+
+	// ldapSimpleAuthTag is the context tag of the password of a
+	// simple bind.
+	ldapSimpleAuthTag = 0
+
+	// ldapExtendedNameTag is the context tag of the name of an
+	// extended operation.
+	ldapExtendedNameTag = 0
+)
+
+// ldapResultNames holds the names of the LDAP result codes that are
+// commonly returned for binds and searches.
+var ldapResultNames = map[int64]string{
+	1:  "Operations Error",
+	2:  "Protocol Error",
+	3:  "Time Limit Exceeded",
+	4:  "Size Limit Exceeded",
+	32: "No Such Object",
+	34: "Invalid DN Syntax",
+	48: "Inappropriate Authentication",
+	49: "Invalid Credentials",
+	50: "Insufficient Access Rights",
+	51: "Busy",
+	52: "Unavailable",
+	53: "Unwilling To Perform",
+	80: "Other",
+}
+
+// ldapError is the error returned when an LDAP operation fails.
+type ldapError struct {
+	code    int64
+	message string
+}
+
+func (e *ldapError) Error() string {
+	return fmt.Sprintf("LDAP Result Code %d %q: %s", e.code, ldapResultNames[e.code], e.message)
+}
+
+// isLDAPError returns whether the cause of err is an LDAP error with
+// the given result code.
+func isLDAPError(err error, code int64) bool {
+	e, ok := errors.Cause(err).(*ldapError)
+	return ok && e.code == code
+}
+
+// ldapConn is a connection to an LDAP server. It supports the few
+// operations needed by LDAPProvider, one at a time.
+type ldapConn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	lastId int64
+}
+
+// newLDAPConn returns an LDAP connection using conn.
+func newLDAPConn(conn net.Conn) *ldapConn {
+	return &ldapConn{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+	}
+}
+
+// Close unbinds and closes the connection.
+func (c *ldapConn) Close() error {
+	c.send(newBERPrimitive(berClassApplication, ldapUnbindRequest, nil))
+	return c.conn.Close()
+}
+
+// StartTLS secures the connection with TLS.
+func (c *ldapConn) StartTLS(config *tls.Config) error {
+	_, err := c.request(newBERConstructed(berClassApplication, ldapExtendedRequest,
+		newBERString(berClassContext, ldapExtendedNameTag, ldapStartTLSOID),
+	))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	tlsConn := tls.Client(c.conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		return errors.Trace(err)
+	}
+	c.conn = tlsConn
+	c.r = bufio.NewReader(tlsConn)
+	return nil
+}
+
+// Bind authenticates as the entry with the given DN with a simple
+// bind.
+func (c *ldapConn) Bind(dn, password string) error {
+	_, err := c.request(newBERConstructed(berClassApplication, ldapBindRequest,
+		newBERInteger(berClassUniversal, berTagInteger, ldapProtocolVersion),
+		newBERString(berClassUniversal, berTagOctetString, dn),
+		newBERString(berClassContext, ldapSimpleAuthTag, password),
+	))
+	return errors.Trace(err)
+}
+
+// ldapMatch is an attribute value searched for.
+type ldapMatch struct {
+	attr  string
+	value string
+}
+
+// SearchAny searches the subtree under base for the entries matching
+// any of the given attribute values, and returns the values of attr of
+// the entries found. As the filter is sent as a structure rather than
+// as a string, the values need no escaping.
+func (c *ldapConn) SearchAny(base string, matches []ldapMatch, attr string, timeLimit int) ([]string, error) {
+	filter := newBERConstructed(berClassContext, ldapFilterOr)
+	for _, m := range matches {
+		filter.children = append(filter.children, newBERConstructed(berClassContext, ldapFilterEqualityMatch,
+			newBERString(berClassUniversal, berTagOctetString, m.attr),
+			newBERString(berClassUniversal, berTagOctetString, m.value),
+		))
+	}
+	entries, err := c.request(newBERConstructed(berClassApplication, ldapSearchRequest,
+		newBERString(berClassUniversal, berTagOctetString, base),
+		newBERInteger(berClassUniversal, berTagEnumerated, ldapScopeWholeSubtree),
+		newBERInteger(berClassUniversal, berTagEnumerated, ldapNeverDerefAliases),
+		newBERInteger(berClassUniversal, berTagInteger, 0),
+		newBERInteger(berClassUniversal, berTagInteger, int64(timeLimit)),
+		newBERBoolean(false),
+		filter,
+		newBERSequence(newBERString(berClassUniversal, berTagOctetString, attr)),
+	))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var values []string
+	for _, entry := range entries {
+		if entry.tag != ldapSearchResultEntry || len(entry.children) < 2 {
+			continue
+		}
+		for _, attribute := range entry.children[1].children {
+			if len(attribute.children) < 2 || attribute.children[0].String() != attr {
+				continue
+			}
+			for _, value := range attribute.children[1].children {
+				values = append(values, value.String())
+			}
+		}
+	}
+	return values, nil
+}
+
+// send writes an LDAP message holding the given operation, and
+// returns its message id.
+func (c *ldapConn) send(op *berPacket) (int64, error) {
+	c.lastId++
+	msg := newBERSequence(
+		newBERInteger(berClassUniversal, berTagInteger, c.lastId),
+		op,
+	)
+	if _, err := c.conn.Write(msg.Bytes()); err != nil {
+		return 0, errors.Trace(err)
+	}
+	return c.lastId, nil
+}
+
+// request sends an LDAP message holding the given operation, and reads
+// the responses to it up to the one holding the result. It returns the
+// responses before the result, or an error if the result is not
+// success.
+func (c *ldapConn) request(op *berPacket) ([]*berPacket, error) {
+	id, err := c.send(op)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var responses []*berPacket
+	for {
+		msg, err := readBERPacket(c.r)
+		if err != nil {
+			return nil, errors.Annotate(err, "cannot read LDAP response")
+		}
+		if len(msg.children) < 2 || msg.children[0].Int() != id {
+			return nil, errors.New("unexpected LDAP response")
+		}
+		response := msg.children[1]
+		switch response.tag {
+		case ldapSearchResultEntry, ldapSearchResultRef:
+			responses = append(responses, response)
+			continue
+		}
+		if len(response.children) < 3 {
+			return nil, errors.New("invalid LDAP result")
+		}
+		if code := response.children[0].Int(); code != ldapResultSuccess {
+			return nil, &ldapError{code: code, message: response.children[2].String()}
+		}
+		return responses, nil
+	}
+}
@@ -6,9 +6,11 @@ package imagemanager
 import (
 	"github.com/juju/errors"
 	"github.com/juju/loggo"
+	"github.com/juju/names"
 
 	"github.com/juju/juju/apiserver/common"
 	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/instance"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/state/imagestorage"
 )
@@ -23,6 +25,7 @@ func init() {
 type ImageManager interface {
 	ListImages(arg params.ImageFilterParams) (params.ListImageResult, error)
 	DeleteImages(arg params.ImageFilterParams) (params.ErrorResults, error)
+	PrimeTemplates(arg params.PrimeTemplatesParams) (params.ErrorResults, error)
 }
 
 // ImageManagerAPI implements the ImageManager interface and is the concrete
@@ -118,3 +121,38 @@ func (api *ImageManagerAPI) DeleteImages(arg params.ImageFilterParams) (params.E
 	}
 	return result, nil
 }
+
+// PrimeTemplates asks for the specified container templates to be built
+// on their host machines ahead of the first containers. Templates that
+// already exist are rebuilt. The series of a template defaults to the
+// series of its host machine.
+func (api *ImageManagerAPI) PrimeTemplates(arg params.PrimeTemplatesParams) (params.ErrorResults, error) {
+	if err := api.check.ChangeAllowed(); err != nil {
+		return params.ErrorResults{}, errors.Trace(err)
+	}
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(arg.Templates)),
+	}
+	for i, spec := range arg.Templates {
+		err := api.primeTemplate(spec)
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
+func (api *ImageManagerAPI) primeTemplate(spec params.TemplateSpec) error {
+	tag, err := names.ParseMachineTag(spec.MachineTag)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	machine, err := api.state.Machine(tag.Id())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	series := spec.Series
+	if series == "" {
+		series = machine.Series()
+	}
+	logger.Infof("requesting %s template for series %q on machine %s", spec.Kind, series, tag.Id())
+	return machine.RequestContainerTemplate(instance.ContainerType(spec.Kind), series)
+}
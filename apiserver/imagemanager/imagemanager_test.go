@@ -18,6 +18,7 @@ import (
 	"github.com/juju/juju/apiserver/params"
 	apiservertesting "github.com/juju/juju/apiserver/testing"
 	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/state"
 	"github.com/juju/juju/state/imagestorage"
 )
 
@@ -179,3 +180,37 @@ func (s *imageManagerSuite) TestBlockDeleteImages(c *gc.C) {
 	c.Assert(err, jc.ErrorIsNil)
 	rdr.Close()
 }
+
+func (s *imageManagerSuite) TestPrimeTemplates(c *gc.C) {
+	machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	args := params.PrimeTemplatesParams{
+		Templates: []params.TemplateSpec{
+			{MachineTag: machine.Tag().String(), Kind: "lxc", Series: "trusty"},
+			{MachineTag: machine.Tag().String(), Kind: "lxc"},
+			{MachineTag: machine.Tag().String(), Kind: "kvm", Series: "trusty"},
+			{MachineTag: "machine-42", Kind: "lxc", Series: "trusty"},
+		},
+	}
+	results, err := s.imagemanager.PrimeTemplates(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 4)
+	c.Assert(results.Results[0].Error, gc.IsNil)
+	c.Assert(results.Results[1].Error, gc.IsNil)
+	c.Assert(results.Results[2].Error, gc.ErrorMatches, `cannot request kvm template for series "trusty" on machine .*: kvm templates not supported`)
+	c.Assert(results.Results[3].Error, jc.DeepEquals, apiservertesting.NotFoundError("machine 42"))
+	templates, err := machine.ContainerTemplates()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(templates, gc.HasLen, 2)
+	c.Assert(templates[0].Series(), gc.Equals, "quantal")
+	c.Assert(templates[1].Series(), gc.Equals, "trusty")
+	c.Assert(templates[1].PrimeRequested().IsZero(), jc.IsFalse)
+}
+
+func (s *imageManagerSuite) TestBlockPrimeTemplates(c *gc.C) {
+	s.AssertConfigParameterUpdated(c, "block-all-changes", true)
+	_, err := s.imagemanager.PrimeTemplates(params.PrimeTemplatesParams{
+		Templates: []params.TemplateSpec{{MachineTag: "machine-0", Kind: "lxc"}},
+	})
+	c.Assert(errors.Cause(err), gc.ErrorMatches, common.ErrOperationBlocked.Error())
+}
@@ -10,6 +10,7 @@ import (
 
 type stateInterface interface {
 	ImageStorage() imagestorage.Storage
+	Machine(id string) (*state.Machine, error)
 }
 
 type stateShim struct {
@@ -0,0 +1,55 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	apihttp "github.com/juju/juju/apiserver/http"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/state"
+)
+
+// introspectionHandler reports on the internal state of the API
+// server, such as the aggregated times taken to serve requests since
+// it started, or its current load.
+type introspectionHandler struct {
+	httpHandler
+	report func() interface{}
+}
+
+func (h *introspectionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h.validateEnvironUUID(r); err != nil {
+		h.sendError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err := h.authenticate(r, state.ReadAccess); err != nil {
+		h.authError(w, h)
+		return
+	}
+	if r.Method != "GET" {
+		h.sendError(w, http.StatusMethodNotAllowed, fmt.Sprintf("unsupported method: %q", r.Method))
+		return
+	}
+	h.sendJSON(w, http.StatusOK, h.report())
+}
+
+// sendJSON sends a JSON-encoded response to the client.
+func (h *introspectionHandler) sendJSON(w http.ResponseWriter, statusCode int, response interface{}) {
+	body, err := json.Marshal(response)
+	if err != nil {
+		logger.Errorf("cannot marshal introspection report: %v", err)
+		return
+	}
+	w.Header().Set("Content-Type", apihttp.CTypeJSON)
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
+
+// sendError sends a JSON-encoded error response.
+func (h *introspectionHandler) sendError(w http.ResponseWriter, statusCode int, message string) {
+	h.sendJSON(w, statusCode, &params.Error{Message: message})
+}
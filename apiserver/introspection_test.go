@@ -0,0 +1,86 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package apiserver_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver"
+	apihttp "github.com/juju/juju/apiserver/http"
+	"github.com/juju/juju/apiserver/params"
+)
+
+type introspectionSuite struct {
+	authHttpSuite
+}
+
+var _ = gc.Suite(&introspectionSuite{})
+
+func (s *introspectionSuite) introspectionURL(c *gc.C, report string) string {
+	environ, err := s.State.Environment()
+	c.Assert(err, jc.ErrorIsNil)
+	uri := s.baseURL(c)
+	uri.Path = fmt.Sprintf("/environment/%s/introspection/%s", environ.UUID(), report)
+	return uri.String()
+}
+
+func (s *introspectionSuite) timingsURL(c *gc.C) string {
+	return s.introspectionURL(c, "requests")
+}
+
+func (s *introspectionSuite) TestRequiresAuth(c *gc.C) {
+	resp, err := s.sendRequest(c, "", "", "GET", s.timingsURL(c), "", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertErrorResponse(c, resp, http.StatusUnauthorized, "unauthorized")
+}
+
+func (s *introspectionSuite) TestRequiresGET(c *gc.C) {
+	resp, err := s.authRequest(c, "POST", s.timingsURL(c), "", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertErrorResponse(c, resp, http.StatusMethodNotAllowed, `unsupported method: "POST"`)
+}
+
+func (s *introspectionSuite) TestRequestTimings(c *gc.C) {
+	_, err := s.APIState.Client().Status(nil)
+	c.Assert(err, jc.ErrorIsNil)
+
+	resp, err := s.authRequest(c, "GET", s.timingsURL(c), "", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	body := assertResponse(c, resp, http.StatusOK, apihttp.CTypeJSON)
+	var timings params.RequestTimings
+	err = json.Unmarshal(body, &timings)
+	c.Assert(err, jc.ErrorIsNil)
+
+	c.Assert(timings.Buckets, gc.Not(gc.HasLen), 0)
+	var found bool
+	for _, t := range timings.Methods {
+		if t.Facade == "Client" && t.Method == "FullStatus" {
+			found = true
+			c.Check(t.Count, gc.Equals, int64(1))
+			c.Check(t.Errors, gc.Equals, int64(0))
+			c.Check(t.Histogram, gc.HasLen, len(timings.Buckets)+1)
+		}
+	}
+	c.Assert(found, jc.IsTrue)
+}
+
+func (s *introspectionSuite) TestLoad(c *gc.C) {
+	resp, err := s.authRequest(c, "GET", s.introspectionURL(c, "load"), "", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	body := assertResponse(c, resp, http.StatusOK, apihttp.CTypeJSON)
+	var load params.APIServerLoad
+	err = json.Unmarshal(body, &load)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// The suite's own API connection is logged in as a user.
+	c.Check(load.Users.Connections, jc.GreaterThan, 0)
+	c.Check(load.Users.MaxLogins, gc.Equals, apiserver.UserLoginRateLimit)
+	c.Check(load.Agents.MaxLogins, gc.Equals, apiserver.LoginRateLimit)
+	c.Check(load.Agents.MaxConnections, gc.Equals, -1)
+}
@@ -54,8 +54,6 @@ func NewKeyUpdaterAPI(
 
 // WatchAuthorisedKeys starts a watcher to track changes to the authorised ssh keys
 // for the specified machines.
-// The current implementation relies on global authorised keys being stored in the environment config.
-// This will change as new user management and authorisation functionality is added.
 func (api *KeyUpdaterAPI) WatchAuthorisedKeys(arg params.Entities) (params.NotifyWatchResults, error) {
 	results := make([]params.NotifyWatchResult, len(arg.Entities))
 
@@ -84,7 +82,7 @@ func (api *KeyUpdaterAPI) WatchAuthorisedKeys(arg params.Entities) (params.Notif
 			continue
 		}
 		// 3. Watch for changes
-		watch := api.state.WatchForEnvironConfigChanges()
+		watch := api.state.WatchAuthorizedKeys()
 		// Consume the initial event.
 		if _, ok := <-watch.Changes(); ok {
 			results[i].NotifyWatcherId = api.resources.Register(watch)
@@ -97,20 +95,16 @@ func (api *KeyUpdaterAPI) WatchAuthorisedKeys(arg params.Entities) (params.Notif
 }
 
 // AuthorisedKeys reports the authorised ssh keys for the specified machines.
-// The current implementation relies on global authorised keys being stored in the environment config.
-// This will change as new user management and authorisation functionality is added.
+// These are the global keys stored in the environment config, followed by
+// the keys of the users allowed to change the environment.
 func (api *KeyUpdaterAPI) AuthorisedKeys(arg params.Entities) (params.StringsResults, error) {
 	if len(arg.Entities) == 0 {
 		return params.StringsResults{}, nil
 	}
 	results := make([]params.StringsResult, len(arg.Entities))
 
-	// For now, authorised keys are global, common to all machines.
-	var keys []string
-	config, configErr := api.state.EnvironConfig()
-	if configErr == nil {
-		keys = ssh.SplitAuthorisedKeys(config.AuthorizedKeys())
-	}
+	// Authorised keys are common to all machines.
+	keys, keysErr := api.authorisedKeys()
 
 	canRead, err := api.getCanRead()
 	if err != nil {
@@ -137,12 +131,27 @@ func (api *KeyUpdaterAPI) AuthorisedKeys(arg params.Entities) (params.StringsRes
 			continue
 		}
 		// 3. Get keys
-		if configErr == nil {
+		if keysErr == nil {
 			results[i].Result = keys
 		} else {
-			err = configErr
+			err = keysErr
 		}
 		results[i].Error = common.ServerError(err)
 	}
 	return params.StringsResults{Results: results}, nil
 }
+
+// authorisedKeys returns the ssh keys authorised on all machines of the
+// environment.
+func (api *KeyUpdaterAPI) authorisedKeys() ([]string, error) {
+	config, err := api.state.EnvironConfig()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	keys := ssh.SplitAuthorisedKeys(config.AuthorizedKeys())
+	userKeys, err := api.state.AuthorizedUserKeys()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return append(keys, userKeys...), nil
+}
@@ -15,6 +15,8 @@ import (
 	jujutesting "github.com/juju/juju/juju/testing"
 	"github.com/juju/juju/state"
 	statetesting "github.com/juju/juju/state/testing"
+	"github.com/juju/juju/testing/factory"
+	sshtesting "github.com/juju/juju/utils/ssh/testing"
 )
 
 type authorisedKeysSuite struct {
@@ -140,3 +142,53 @@ func (s *authorisedKeysSuite) TestAuthorisedKeys(c *gc.C) {
 		},
 	})
 }
+
+func (s *authorisedKeysSuite) TestAuthorisedKeysIncludesUserKeys(c *gc.C) {
+	s.setAuthorizedKeys(c, "key1")
+	user := s.Factory.MakeUser(c, &factory.UserParams{Access: state.WriteAccess})
+	err := user.AddSSHKeys(sshtesting.ValidKeyOne.Key)
+	c.Assert(err, jc.ErrorIsNil)
+
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: s.rawMachine.Tag().String()}},
+	}
+	results, err := s.keyupdater.AuthorisedKeys(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.DeepEquals, params.StringsResults{
+		Results: []params.StringsResult{
+			{Result: []string{"key1", sshtesting.ValidKeyOne.Key}},
+		},
+	})
+
+	err = user.Disable()
+	c.Assert(err, jc.ErrorIsNil)
+	results, err = s.keyupdater.AuthorisedKeys(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.DeepEquals, params.StringsResults{
+		Results: []params.StringsResult{
+			{Result: []string{"key1"}},
+		},
+	})
+}
+
+func (s *authorisedKeysSuite) TestWatchAuthorisedKeysUserKeys(c *gc.C) {
+	user := s.Factory.MakeUser(c, nil)
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: s.rawMachine.Tag().String()}},
+	}
+	results, err := s.keyupdater.WatchAuthorisedKeys(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results[0].Error, gc.IsNil)
+	resource := s.resources.Get(results.Results[0].NotifyWatcherId)
+	c.Assert(resource, gc.NotNil)
+
+	w := resource.(state.NotifyWatcher)
+	wc := statetesting.NewNotifyWatcherC(c, s.State, w)
+	wc.AssertNoChange()
+
+	err = user.AddSSHKeys(sshtesting.ValidKeyOne.Key)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+	statetesting.AssertStop(c, w)
+	wc.AssertClosed()
+}
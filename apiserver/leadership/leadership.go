@@ -28,8 +28,7 @@ var (
 	// Begin injection-chain so we can instantiate leadership
 	// services. Exposed as variables so we can change the
 	// implementation for testing purposes.
-	leaseMgr  = lease.Manager()
-	leaderMgr = leadership.NewLeadershipManager(leaseMgr)
+	leaseMgr = lease.Manager()
 )
 
 func init() {
@@ -37,10 +36,24 @@ func init() {
 	common.RegisterStandardFacade(
 		FacadeName,
 		0,
-		NewLeadershipServiceFn(leaderMgr),
+		newEnvironLeadershipService,
 	)
 }
 
+// newEnvironLeadershipService constructs a LeadershipService whose
+// leadership manager only deals with the leases of the environment of
+// the given state.
+func newEnvironLeadershipService(
+	state *state.State,
+	resources *common.Resources,
+	authorizer common.Authorizer,
+) (LeadershipService, error) {
+	leaderMgr := leadership.NewLeadershipManager(
+		leadership.NewEnvironLeaseManager(leaseMgr, state.EnvironUUID()),
+	)
+	return NewLeadershipService(state, resources, authorizer, leaderMgr)
+}
+
 // NewLeadershipServiceFn returns a function which can construct a
 // LeadershipService when passed a state, resources, and authorizer.
 // This function signature conforms to Juju's required API server
@@ -18,6 +18,7 @@ import (
 
 	"github.com/juju/juju/apiserver/common"
 	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/state"
 )
 
 func Test(t *testing.T) { gc.TestingT(t) }
@@ -74,9 +75,10 @@ func (m *stubAuthorizer) AuthOwner(tag names.Tag) bool {
 	}
 	return true
 }
-func (m *stubAuthorizer) AuthEnvironManager() bool { return true }
-func (m *stubAuthorizer) AuthClient() bool         { return true }
-func (m *stubAuthorizer) GetAuthTag() names.Tag    { return names.NewServiceTag(StubServiceNm) }
+func (m *stubAuthorizer) AuthEnvironManager() bool            { return true }
+func (m *stubAuthorizer) AuthClient() bool                    { return true }
+func (m *stubAuthorizer) AuthEnvironAccess(state.Access) bool { return true }
+func (m *stubAuthorizer) GetAuthTag() names.Tag               { return names.NewServiceTag(StubServiceNm) }
 
 func (s *leadershipSuite) TestClaimLeadershipTranslation(c *gc.C) {
 	var ldrMgr stubLeadershipManager
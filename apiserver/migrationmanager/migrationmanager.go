@@ -0,0 +1,367 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// The migrationmanager package defines an API end point for moving
+// environments from one state server to another.
+package migrationmanager
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+	"github.com/juju/names"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/storage"
+	"github.com/juju/juju/state/toolstorage"
+	"github.com/juju/juju/version"
+)
+
+var logger = loggo.GetLogger("juju.apiserver.migrationmanager")
+
+func init() {
+	common.RegisterStandardFacade("MigrationManager", 0, NewMigrationManagerAPI)
+}
+
+const (
+	// CharmBlob and ToolsBlob are the kinds of migration blobs.
+	CharmBlob = "charm"
+	ToolsBlob = "tools"
+)
+
+// MigrationManager defines the methods on the migrationmanager API end
+// point.
+type MigrationManager interface {
+	ExportEnvironment(arg params.Entity) (params.SerializedEnvironment, error)
+	ExportBlob(arg params.MigrationBlob) (params.BytesResult, error)
+	ImportEnvironment(arg params.SerializedEnvironment) (params.Environment, error)
+	ImportBlob(arg params.MigrationBlob) error
+	StartMigration(arg params.Entity) error
+	MigrationStatus(arg params.Entity) (params.MigrationStatus, error)
+	SetMigrationTarget(arg params.MigrationTarget) error
+	RollBackMigration(arg params.Entity) error
+	ClearMigrationTarget(arg params.Entity) error
+	TrackAgentCheckIns(arg params.Entity) error
+	MigrationProgress(arg params.Entity) (params.MigrationProgress, error)
+	FinishImport(arg params.Entity) error
+	DiscardEnvironment(arg params.Entity) error
+}
+
+// MigrationManagerAPI implements the migration manager interface and is
+// the concrete implementation of the api end point.
+type MigrationManagerAPI struct {
+	state      *state.State
+	authorizer common.Authorizer
+}
+
+var _ MigrationManager = (*MigrationManagerAPI)(nil)
+
+// NewMigrationManagerAPI creates a new api server endpoint for
+// migrating environments. Only administrators of an environment may
+// use it; environments other than the one connected to may only be
+// named by administrators of the state server environment.
+func NewMigrationManagerAPI(
+	st *state.State,
+	resources *common.Resources,
+	authorizer common.Authorizer,
+) (*MigrationManagerAPI, error) {
+	if !authorizer.AuthClient() || !authorizer.AuthEnvironAccess(state.AdminAccess) {
+		return nil, common.ErrPerm
+	}
+	return &MigrationManagerAPI{
+		state:      st,
+		authorizer: authorizer,
+	}, nil
+}
+
+// environState returns a State for the environment with the given tag,
+// which must be closed after use.
+func (m *MigrationManagerAPI) environState(envTag string) (*state.State, error) {
+	tag, err := names.ParseEnvironTag(envTag)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if tag != m.state.EnvironTag() {
+		ssEnv, err := m.state.StateServerEnvironment()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if m.state.EnvironUUID() != ssEnv.UUID() {
+			return nil, common.ErrPerm
+		}
+	}
+	return m.state.ForEnviron(tag)
+}
+
+// ExportEnvironment exports the model of an environment, so that it can
+// be imported into another state server.
+func (m *MigrationManagerAPI) ExportEnvironment(arg params.Entity) (params.SerializedEnvironment, error) {
+	var result params.SerializedEnvironment
+	st, err := m.environState(arg.Tag)
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	defer st.Close()
+	export, err := st.ExportEnvironment()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	return params.SerializedEnvironment{
+		UUID:     export.UUID,
+		Name:     export.Name,
+		OwnerTag: names.NewUserTag(export.Owner).String(),
+		Model:    export.Model,
+		Charms:   export.Charms,
+		Tools:    export.Tools,
+	}, nil
+}
+
+// ExportBlob returns the contents of a charm archive or tools tarball of
+// an environment.
+func (m *MigrationManagerAPI) ExportBlob(arg params.MigrationBlob) (params.BytesResult, error) {
+	var result params.BytesResult
+	st, err := m.environState(arg.EnvTag)
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	defer st.Close()
+	switch arg.Kind {
+	case CharmBlob:
+		stor := storage.NewStorage(st.EnvironUUID(), st.MongoSession())
+		r, _, err := stor.Get(arg.Name)
+		if err != nil {
+			return result, errors.Annotatef(err, "cannot read charm %q", arg.Name)
+		}
+		defer r.Close()
+		result.Result, err = ioutil.ReadAll(r)
+		return result, errors.Annotatef(err, "cannot read charm %q", arg.Name)
+	case ToolsBlob:
+		vers, err := version.ParseBinary(arg.Name)
+		if err != nil {
+			return result, errors.Trace(err)
+		}
+		stor, err := st.ToolsStorage()
+		if err != nil {
+			return result, errors.Trace(err)
+		}
+		defer stor.Close()
+		_, r, err := stor.Tools(vers)
+		if err != nil {
+			return result, errors.Annotatef(err, "cannot read tools %v", vers)
+		}
+		defer r.Close()
+		result.Result, err = ioutil.ReadAll(r)
+		return result, errors.Annotatef(err, "cannot read tools %v", vers)
+	}
+	return result, errors.NotValidf("blob kind %q", arg.Kind)
+}
+
+// ImportEnvironment adds an environment exported from another state
+// server. It must be called on the state server environment.
+func (m *MigrationManagerAPI) ImportEnvironment(arg params.SerializedEnvironment) (params.Environment, error) {
+	var result params.Environment
+	ssEnv, err := m.state.StateServerEnvironment()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	if m.state.EnvironUUID() != ssEnv.UUID() {
+		return result, common.ErrPerm
+	}
+	owner, err := names.ParseUserTag(arg.OwnerTag)
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	env, st, err := m.state.ImportEnvironment(&state.EnvironmentExport{
+		UUID:   arg.UUID,
+		Name:   arg.Name,
+		Owner:  owner.Username(),
+		Model:  arg.Model,
+		Charms: arg.Charms,
+		Tools:  arg.Tools,
+	})
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	defer st.Close()
+	logger.Infof("imported environment %q (%s)", env.Name(), env.UUID())
+	return params.Environment{
+		Name:     env.Name(),
+		UUID:     env.UUID(),
+		OwnerTag: env.Owner().String(),
+	}, nil
+}
+
+// ImportBlob stores a charm archive or tools tarball of an imported
+// environment.
+func (m *MigrationManagerAPI) ImportBlob(arg params.MigrationBlob) error {
+	st, err := m.environState(arg.EnvTag)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer st.Close()
+	switch arg.Kind {
+	case CharmBlob:
+		stor := storage.NewStorage(st.EnvironUUID(), st.MongoSession())
+		err := stor.Put(arg.Name, bytes.NewReader(arg.Data), int64(len(arg.Data)))
+		return errors.Annotatef(err, "cannot store charm %q", arg.Name)
+	case ToolsBlob:
+		vers, err := version.ParseBinary(arg.Name)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		stor, err := st.ToolsStorage()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		defer stor.Close()
+		metadata := toolstorage.Metadata{
+			Version: vers,
+			Size:    int64(len(arg.Data)),
+			SHA256:  fmt.Sprintf("%x", sha256.Sum256(arg.Data)),
+		}
+		err = stor.AddTools(bytes.NewReader(arg.Data), metadata)
+		return errors.Annotatef(err, "cannot store tools %v", vers)
+	}
+	return errors.NotValidf("blob kind %q", arg.Kind)
+}
+
+// StartMigration records that an environment is being copied to
+// another state server.
+func (m *MigrationManagerAPI) StartMigration(arg params.Entity) error {
+	st, err := m.environState(arg.Tag)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer st.Close()
+	return st.StartMigration()
+}
+
+// MigrationStatus reports the phase of an environment's migration, so
+// that an interrupted migration can be resumed.
+func (m *MigrationManagerAPI) MigrationStatus(arg params.Entity) (params.MigrationStatus, error) {
+	var result params.MigrationStatus
+	st, err := m.environState(arg.Tag)
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	defer st.Close()
+	phase, err := st.MigrationPhase()
+	if errors.IsNotFound(err) {
+		return result, nil
+	} else if err != nil {
+		return result, errors.Trace(err)
+	}
+	result.Phase = string(phase)
+	return result, nil
+}
+
+// SetMigrationTarget redirects the agents of an environment to the API
+// servers of another state server.
+func (m *MigrationManagerAPI) SetMigrationTarget(arg params.MigrationTarget) error {
+	st, err := m.environState(arg.EnvTag)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer st.Close()
+	return st.SetMigrationTarget(state.MigrationTarget{
+		APIHostPorts: arg.APIHostPorts,
+		CACert:       arg.CACert,
+	})
+}
+
+// RollBackMigration stops redirecting the agents of an environment to
+// another state server, and records that its migration is being rolled
+// back.
+func (m *MigrationManagerAPI) RollBackMigration(arg params.Entity) error {
+	st, err := m.environState(arg.Tag)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer st.Close()
+	return st.RollBackMigration()
+}
+
+// ClearMigrationTarget removes the record of an environment's
+// migration, so its agents are no longer redirected to another state
+// server.
+func (m *MigrationManagerAPI) ClearMigrationTarget(arg params.Entity) error {
+	st, err := m.environState(arg.Tag)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer st.Close()
+	return st.ClearMigrationTarget()
+}
+
+// TrackAgentCheckIns starts recording afresh the agents that log in to
+// an environment.
+func (m *MigrationManagerAPI) TrackAgentCheckIns(arg params.Entity) error {
+	st, err := m.environState(arg.Tag)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer st.Close()
+	return st.TrackAgentCheckIns()
+}
+
+// MigrationProgress reports which agents of an imported environment
+// are expected to log in, and which have done so.
+func (m *MigrationManagerAPI) MigrationProgress(arg params.Entity) (params.MigrationProgress, error) {
+	var result params.MigrationProgress
+	st, err := m.environState(arg.Tag)
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	defer st.Close()
+	result.Expected, err = st.ExpectedAgents()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	result.CheckedIn, err = st.AgentsCheckedIn()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	return result, nil
+}
+
+// FinishImport stops recording the agents that log in to an imported
+// environment.
+func (m *MigrationManagerAPI) FinishImport(arg params.Entity) error {
+	st, err := m.environState(arg.Tag)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer st.Close()
+	return st.FinishImport()
+}
+
+// DiscardEnvironment removes an environment from the state server,
+// leaving its machines running. The environment must be migrating to
+// another state server, or have been imported from one.
+func (m *MigrationManagerAPI) DiscardEnvironment(arg params.Entity) error {
+	st, err := m.environState(arg.Tag)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer st.Close()
+	_, err = st.MigrationPhase()
+	if errors.IsNotFound(err) {
+		_, err = st.AgentsCheckedIn()
+	}
+	if errors.IsNotFound(err) {
+		return errors.Errorf("environment is not being migrated")
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	if err := st.DiscardEnvironment(); err != nil {
+		return errors.Trace(err)
+	}
+	logger.Infof("discarded environment %s", st.EnvironUUID())
+	return nil
+}
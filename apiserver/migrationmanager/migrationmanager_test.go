@@ -0,0 +1,175 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package migrationmanager_test
+
+import (
+	"github.com/juju/names"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/migrationmanager"
+	"github.com/juju/juju/apiserver/params"
+	apiservertesting "github.com/juju/juju/apiserver/testing"
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing/factory"
+)
+
+type migrationManagerSuite struct {
+	jujutesting.JujuConnSuite
+
+	otherState *state.State
+	machine    *state.Machine
+	manager    *migrationmanager.MigrationManagerAPI
+}
+
+var _ = gc.Suite(&migrationManagerSuite{})
+
+func (s *migrationManagerSuite) SetUpTest(c *gc.C) {
+	s.JujuConnSuite.SetUpTest(c)
+	s.otherState = s.Factory.MakeEnvironment(c, &factory.EnvParams{Name: "hosted"})
+	s.AddCleanup(func(*gc.C) { s.otherState.Close() })
+	s.machine = factory.NewFactory(s.otherState).MakeMachine(c, nil)
+	var err error
+	s.manager, err = migrationmanager.NewMigrationManagerAPI(s.State, nil, apiservertesting.FakeAuthorizer{
+		Tag: s.AdminUserTag(c),
+	})
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *migrationManagerSuite) otherEnv() params.Entity {
+	return params.Entity{Tag: s.otherState.EnvironTag().String()}
+}
+
+func (s *migrationManagerSuite) TestNewAPIRefusesNonAdmin(c *gc.C) {
+	for _, authorizer := range []apiservertesting.FakeAuthorizer{
+		{Tag: names.NewMachineTag("1")},
+		{Tag: s.AdminUserTag(c), Access: state.WriteAccess},
+	} {
+		_, err := migrationmanager.NewMigrationManagerAPI(s.State, nil, authorizer)
+		c.Assert(err, gc.ErrorMatches, "permission denied")
+	}
+}
+
+func (s *migrationManagerSuite) TestOtherEnvironmentsNeedStateServer(c *gc.C) {
+	manager, err := migrationmanager.NewMigrationManagerAPI(s.otherState, nil, apiservertesting.FakeAuthorizer{
+		Tag: s.AdminUserTag(c),
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = manager.ExportEnvironment(params.Entity{Tag: s.State.EnvironTag().String()})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+	_, err = manager.ImportEnvironment(params.SerializedEnvironment{})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+
+	// An environment's administrators may export it themselves.
+	export, err := manager.ExportEnvironment(s.otherEnv())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(export.Name, gc.Equals, "hosted")
+}
+
+func (s *migrationManagerSuite) TestBlobs(c *gc.C) {
+	data := []byte("charm archive")
+	blob := params.MigrationBlob{
+		EnvTag: s.otherState.EnvironTag().String(),
+		Kind:   migrationmanager.CharmBlob,
+		Name:   "charms/mysql",
+		Data:   data,
+	}
+	err := s.manager.ImportBlob(blob)
+	c.Assert(err, jc.ErrorIsNil)
+	blob.Data = nil
+	result, err := s.manager.ExportBlob(blob)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Result, gc.DeepEquals, data)
+
+	blob.Kind = migrationmanager.ToolsBlob
+	blob.Name = "1.24.0-trusty-amd64"
+	blob.Data = []byte("tools tarball")
+	err = s.manager.ImportBlob(blob)
+	c.Assert(err, jc.ErrorIsNil)
+	result, err = s.manager.ExportBlob(blob)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Result, gc.DeepEquals, blob.Data)
+
+	blob.Kind = "foo"
+	_, err = s.manager.ExportBlob(blob)
+	c.Assert(err, gc.ErrorMatches, `blob kind "foo" not valid`)
+}
+
+func (s *migrationManagerSuite) TestMigrate(c *gc.C) {
+	export, err := s.manager.ExportEnvironment(s.otherEnv())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(export.UUID, gc.Equals, s.otherState.EnvironUUID())
+
+	// Environments that are not migrating cannot be discarded.
+	err = s.manager.DiscardEnvironment(s.otherEnv())
+	c.Assert(err, gc.ErrorMatches, "environment is not being migrated")
+
+	err = s.manager.SetMigrationTarget(params.MigrationTarget{
+		EnvTag:       s.otherState.EnvironTag().String(),
+		APIHostPorts: [][]network.HostPort{network.NewHostPorts(17070, "10.0.1.1")},
+		CACert:       "target-cert",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	caCert, err := s.otherState.AgentCACert()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(caCert, gc.Equals, "target-cert")
+
+	// Stand in for the target state server by discarding the
+	// environment and importing it again.
+	err = s.manager.DiscardEnvironment(s.otherEnv())
+	c.Assert(err, jc.ErrorIsNil)
+	env, err := s.manager.ImportEnvironment(export)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(env.UUID, gc.Equals, export.UUID)
+
+	err = s.otherState.RecordAgentCheckIn(s.machine.Tag())
+	c.Assert(err, jc.ErrorIsNil)
+	progress, err := s.manager.MigrationProgress(s.otherEnv())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(progress, jc.DeepEquals, params.MigrationProgress{
+		Expected:  []string{s.machine.Tag().String()},
+		CheckedIn: []string{s.machine.Tag().String()},
+	})
+
+	err = s.manager.FinishImport(s.otherEnv())
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.manager.MigrationProgress(s.otherEnv())
+	c.Assert(err, gc.ErrorMatches, `import of environment ".*" not found`)
+}
+
+func (s *migrationManagerSuite) TestMigrationStatus(c *gc.C) {
+	status, err := s.manager.MigrationStatus(s.otherEnv())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(status.Phase, gc.Equals, "")
+
+	err = s.manager.StartMigration(s.otherEnv())
+	c.Assert(err, jc.ErrorIsNil)
+	status, err = s.manager.MigrationStatus(s.otherEnv())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(status.Phase, gc.Equals, string(state.MigrationCopying))
+
+	err = s.manager.SetMigrationTarget(params.MigrationTarget{
+		EnvTag:       s.otherState.EnvironTag().String(),
+		APIHostPorts: [][]network.HostPort{network.NewHostPorts(17070, "10.0.1.1")},
+		CACert:       "target-cert",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	// Rolling back stops redirecting the environment's agents.
+	err = s.manager.RollBackMigration(s.otherEnv())
+	c.Assert(err, jc.ErrorIsNil)
+	status, err = s.manager.MigrationStatus(s.otherEnv())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(status.Phase, gc.Equals, string(state.MigrationRollingBack))
+	caCert, err := s.otherState.AgentCACert()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(caCert, gc.Equals, s.otherState.CACert())
+
+	err = s.manager.ClearMigrationTarget(s.otherEnv())
+	c.Assert(err, jc.ErrorIsNil)
+	status, err = s.manager.MigrationStatus(s.otherEnv())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(status.Phase, gc.Equals, "")
+}
@@ -177,3 +177,40 @@ func (n *NetworkerAPI) WatchInterfaces(args params.Entities) (params.NotifyWatch
 	}
 	return result, nil
 }
+
+// SetObservedNetworkInterfaces records the network interfaces found on
+// each given machine by its agent.
+func (n *NetworkerAPI) SetObservedNetworkInterfaces(args params.SetObservedNetworkInterfaces) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.Machines)),
+	}
+	canAccess, err := n.getAuthFunc()
+	if err != nil {
+		return result, err
+	}
+	for i, arg := range args.Machines {
+		tag, err := names.ParseMachineTag(arg.MachineTag)
+		if err != nil || !canAccess(tag) {
+			result.Results[i].Error = common.ServerError(common.ErrPerm)
+			continue
+		}
+		machine, err := n.st.Machine(tag.Id())
+		if err == nil {
+			interfaces := make([]state.ObservedInterface, len(arg.Interfaces))
+			for j, iface := range arg.Interfaces {
+				interfaces[j] = state.ObservedInterface{
+					InterfaceName:       iface.InterfaceName,
+					MACAddress:          iface.MACAddress,
+					MTU:                 iface.MTU,
+					IsUp:                iface.IsUp,
+					Addresses:           iface.Addresses,
+					VLANTag:             iface.VLANTag,
+					ParentInterfaceName: iface.ParentInterfaceName,
+				}
+			}
+			err = machine.SetObservedInterfaces(interfaces)
+		}
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
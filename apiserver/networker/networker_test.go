@@ -350,3 +350,56 @@ func (s *networkerSuite) TestWatchInterfaces(c *gc.C) {
 		wc.AssertNoChange()
 	}
 }
+
+func (s *networkerSuite) TestSetObservedNetworkInterfaces(c *gc.C) {
+	observed := []params.ObservedNetworkInterface{{
+		InterfaceName: "eth0",
+		MACAddress:    "aa:bb:cc:dd:ee:f0",
+		MTU:           1500,
+		IsUp:          true,
+		Addresses:     []string{"0.1.2.3/24"},
+	}, {
+		InterfaceName:       "eth0.69",
+		MACAddress:          "aa:bb:cc:dd:ee:f0",
+		MTU:                 1500,
+		VLANTag:             69,
+		ParentInterfaceName: "eth0",
+	}}
+	args := params.SetObservedNetworkInterfaces{Machines: []params.MachineObservedNetworkInterfaces{
+		{MachineTag: "service-bar"},
+		{MachineTag: "machine-1"},
+		{MachineTag: "machine-0-lxc-42"},
+		{MachineTag: s.machine.Tag().String(), Interfaces: observed},
+		{MachineTag: s.container.Tag().String()},
+	}}
+	results, err := s.networker.SetObservedNetworkInterfaces(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.DeepEquals, params.ErrorResults{
+		Results: []params.ErrorResult{
+			{Error: apiservertesting.ErrUnauthorized},
+			{Error: apiservertesting.ErrUnauthorized},
+			{Error: apiservertesting.NotFoundError("machine 0/lxc/42")},
+			{Error: nil},
+			{Error: nil},
+		},
+	})
+
+	interfaces, _, err := s.machine.ObservedInterfaces()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(interfaces, jc.DeepEquals, []state.ObservedInterface{{
+		InterfaceName: "eth0",
+		MACAddress:    "aa:bb:cc:dd:ee:f0",
+		MTU:           1500,
+		IsUp:          true,
+		Addresses:     []string{"0.1.2.3/24"},
+	}, {
+		InterfaceName:       "eth0.69",
+		MACAddress:          "aa:bb:cc:dd:ee:f0",
+		MTU:                 1500,
+		VLANTag:             69,
+		ParentInterfaceName: "eth0",
+	}})
+	interfaces, _, err = s.container.ObservedInterfaces()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(interfaces, gc.HasLen, 0)
+}
@@ -5,6 +5,7 @@ package params
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/juju/juju/rpc"
 )
@@ -13,6 +14,7 @@ import (
 type Error struct {
 	Message string
 	Code    string
+	Info    map[string]interface{} `json:",omitempty"`
 }
 
 func (e *Error) Error() string {
@@ -23,12 +25,17 @@ func (e *Error) ErrorCode() string {
 	return e.Code
 }
 
+func (e *Error) ErrorInfo() map[string]interface{} {
+	return e.Info
+}
+
 var _ rpc.ErrorCoder = (*Error)(nil)
+var _ rpc.ErrorInfoProvider = (*Error)(nil)
 
 // GoString implements fmt.GoStringer.  It means that a *Error shows its
 // contents correctly when printed with %#v.
 func (e Error) GoString() string {
-	return fmt.Sprintf("&params.Error{%q, %q}", e.Code, e.Message)
+	return fmt.Sprintf("&params.Error{Message: %q, Code: %q, Info: %#v}", e.Message, e.Code, e.Info)
 }
 
 // The Code constants hold error codes for some kinds of error.
@@ -77,7 +84,44 @@ func ClientError(err error) error {
 	return &Error{
 		Message: rerr.Message,
 		Code:    rerr.Code,
+		Info:    rerr.Info,
+	}
+}
+
+// RetryAfterKey is the key in Error.Info holding the number of seconds
+// a client should wait before retrying a request that failed with
+// CodeTryAgain.
+const RetryAfterKey = "retry-after"
+
+// NewTryAgainError returns an error with CodeTryAgain that tells the
+// client to retry the request after the given delay.
+func NewTryAgainError(message string, retryAfter time.Duration) *Error {
+	return &Error{
+		Message: message,
+		Code:    CodeTryAgain,
+		Info: map[string]interface{}{
+			RetryAfterKey: retryAfter.Seconds(),
+		},
+	}
+}
+
+// RetryAfter returns the delay the server asked for before the request
+// that returned the given error is retried. It returns false if err
+// is not a CodeTryAgain error or the server did not specify a delay.
+func RetryAfter(err error) (time.Duration, bool) {
+	if !IsCodeTryAgain(err) {
+		return 0, false
+	}
+	provider, ok := err.(rpc.ErrorInfoProvider)
+	if !ok {
+		return 0, false
+	}
+	// Numbers decoded from JSON are always float64.
+	seconds, ok := provider.ErrorInfo()[RetryAfterKey].(float64)
+	if !ok || seconds < 0 {
+		return 0, false
 	}
+	return time.Duration(seconds * float64(time.Second)), true
 }
 
 func IsCodeActionNotAvailable(err error) bool {
@@ -441,6 +441,31 @@ type MachineNetworkInfoResults struct {
 	Results []MachineNetworkInfoResult
 }
 
+// ObservedNetworkInterface describes a network interface as found on a
+// machine by its agent.
+type ObservedNetworkInterface struct {
+	InterfaceName       string   `json:"interfacename"`
+	MACAddress          string   `json:"macaddress,omitempty"`
+	MTU                 int      `json:"mtu,omitempty"`
+	IsUp                bool     `json:"isup"`
+	Addresses           []string `json:"addresses,omitempty"`
+	VLANTag             int      `json:"vlantag,omitempty"`
+	ParentInterfaceName string   `json:"parentinterfacename,omitempty"`
+}
+
+// MachineObservedNetworkInterfaces holds the network interfaces found
+// on a machine.
+type MachineObservedNetworkInterfaces struct {
+	MachineTag string                     `json:"machinetag"`
+	Interfaces []ObservedNetworkInterface `json:"interfaces"`
+}
+
+// SetObservedNetworkInterfaces holds the parameters for making a
+// SetObservedNetworkInterfaces call.
+type SetObservedNetworkInterfaces struct {
+	Machines []MachineObservedNetworkInterfaces `json:"machines"`
+}
+
 // EntityStatus holds an entity tag, status and extra info.
 type EntityStatus struct {
 	Tag    string
@@ -513,10 +538,13 @@ type AgentGetEntitiesResult struct {
 }
 
 // VersionResult holds the version and possibly error for a given
-// DesiredVersion() API call.
+// DesiredVersion() API call. Downgrade is set when the version is the
+// one a rolled back upgrade was rolled back to, so that agents running
+// a later version downgrade to it.
 type VersionResult struct {
-	Version *version.Number
-	Error   *Error
+	Version   *version.Number
+	Downgrade bool
+	Error     *Error
 }
 
 // VersionResults is a list of versions for the requested entities.
@@ -191,6 +191,11 @@ type ServiceDeploy struct {
 	Constraints   constraints.Value
 	ToMachineSpec string
 	Networks      []string
+
+	// EndpointBindings maps the names of the service's endpoints to
+	// the spaces they are bound to. The space of the endpoints not
+	// bound explicitly is held under the empty name.
+	EndpointBindings map[string]string
 }
 
 // ServiceUpdate holds the parameters for making the ServiceUpdate call.
@@ -253,6 +258,78 @@ type ServiceGetResults struct {
 	Constraints constraints.Value
 }
 
+// ServiceConfigHistoryResults holds the results of the
+// ServiceConfigHistory call.
+type ServiceConfigHistoryResults struct {
+	Revisions []ServiceConfigRevision
+}
+
+// ServiceConfigRevision describes a change to the configuration of a
+// service.
+type ServiceConfigRevision struct {
+	Revision int
+	CharmURL string
+	UserTag  string
+	Time     time.Time
+	Changes  []ServiceConfigChange
+}
+
+// ServiceConfigChange describes the change of a setting in a
+// ServiceConfigRevision. The values of settings that appear to hold
+// secrets are not recorded, and Masked is set instead.
+type ServiceConfigChange struct {
+	Key string
+
+	// Type is one of "added", "modified" or "deleted".
+	Type     string
+	OldValue interface{} `json:",omitempty"`
+	NewValue interface{} `json:",omitempty"`
+	Masked   bool        `json:",omitempty"`
+}
+
+// ServiceConfigRevert holds the arguments for the ServiceConfigRevert
+// call.
+type ServiceConfigRevert struct {
+	ServiceName string
+	Revision    int
+}
+
+// ServiceConfigRevertResult holds the result of the ServiceConfigRevert
+// call: the settings left unchanged because their values are masked in
+// the config history.
+type ServiceConfigRevertResult struct {
+	Skipped []string
+}
+
+// ServiceValidateConfig holds the parameters for making the Service
+// facade's ValidateConfig call. The changes are given either as
+// key=value strings, as for ServiceSet, or as YAML, as for
+// ServiceSetYAML.
+type ServiceValidateConfig struct {
+	ServiceName string
+	Options     map[string]string
+	Config      string
+}
+
+// ServiceValidateConfigResult holds the result of the ValidateConfig
+// call: every problem found with the changes, or the changes to the
+// effective value of each setting if there are none.
+type ServiceValidateConfigResult struct {
+	Errors  []string
+	Changes []ServiceConfigDiff
+}
+
+// ServiceConfigDiff describes the change of a setting's effective value.
+// OldDefault and NewDefault are true when the value is the default of
+// the charm option.
+type ServiceConfigDiff struct {
+	Key        string
+	OldValue   interface{}
+	NewValue   interface{}
+	OldDefault bool
+	NewDefault bool
+}
+
 // ServiceCharmRelations holds parameters for making the ServiceCharmRelations call.
 type ServiceCharmRelations struct {
 	ServiceName string
@@ -268,6 +345,12 @@ type ServiceUnexpose struct {
 	ServiceName string
 }
 
+// ServiceOffer holds the parameters for the ServiceOffer and
+// ServiceUnoffer calls. Endpoint is of the form <service>:<relation>.
+type ServiceOffer struct {
+	Endpoint string
+}
+
 // ServiceMetricCredential holds parameters for the SetServiceCredentials call.
 type ServiceMetricCredential struct {
 	ServiceName       string
@@ -279,6 +362,80 @@ type ServiceMetricCredentials struct {
 	Creds []ServiceMetricCredential
 }
 
+// PlacementPolicy is a rule constraining where the units of a service
+// are placed. Service is set for the affinity and anti-affinity kinds,
+// and MaxUnits for max-units-per-zone.
+type PlacementPolicy struct {
+	Kind     string
+	Service  string
+	MaxUnits int
+}
+
+// ServicePlacementPolicies holds the placement policies of a service.
+type ServicePlacementPolicies struct {
+	ServiceName string
+	Policies    []PlacementPolicy
+}
+
+// ServicesPlacementPolicies holds the parameters for making the Service
+// facade's SetPlacementPolicies call.
+type ServicesPlacementPolicies struct {
+	Args []ServicePlacementPolicies
+}
+
+// PlacementPoliciesResult holds the placement policies of a service, or
+// an error.
+type PlacementPoliciesResult struct {
+	Error    *Error
+	Policies []PlacementPolicy
+}
+
+// PlacementPoliciesResults holds the results of the Service facade's
+// PlacementPolicies call.
+type PlacementPoliciesResults struct {
+	Results []PlacementPoliciesResult
+}
+
+// Subnet describes a subnet, and the space it is in.
+type Subnet struct {
+	CIDR       string
+	ProviderId string
+	VLANTag    int
+	Zone       string
+	SpaceName  string
+}
+
+// Space describes a space and the subnets in it.
+type Space struct {
+	Name    string
+	Subnets []Subnet
+}
+
+// CreateSpaceParams holds the name of a space to create, and the CIDRs
+// of the existing subnets to put in it.
+type CreateSpaceParams struct {
+	Name        string
+	SubnetCIDRs []string
+}
+
+// CreateSpacesParams holds the parameters for making the Spaces
+// facade's CreateSpaces call.
+type CreateSpacesParams struct {
+	Spaces []CreateSpaceParams
+}
+
+// ListSpacesResults holds the result of the Spaces facade's ListSpaces
+// call.
+type ListSpacesResults struct {
+	Results []Space
+}
+
+// AddSubnetsParams holds the parameters for making the Subnets facade's
+// AddSubnets call.
+type AddSubnetsParams struct {
+	Subnets []Subnet
+}
+
 // PublicAddress holds parameters for the PublicAddress call.
 type PublicAddress struct {
 	Target string
@@ -415,6 +572,15 @@ type AllWatcherId struct {
 	AllWatcherId string
 }
 
+// WatchAllFilter holds the criteria used to restrict the deltas sent
+// by a new AllWatcher. Empty fields place no restriction; see
+// multiwatcher.Filter for details.
+type WatchAllFilter struct {
+	Kinds    []string
+	Services []string
+	Machines []string
+}
+
 // AllWatcherNextResults holds deltas returned from calling AllWatcher.Next().
 type AllWatcherNextResults struct {
 	Deltas []multiwatcher.Delta
@@ -486,6 +652,14 @@ type ContainerConfig struct {
 	AptMirror               string
 	PreferIPv6              bool
 	*UpdateBehavior
+
+	// LXCTemplateMaxAge holds how old LXC templates may get before
+	// they are rebuilt, or zero if they are never rebuilt.
+	LXCTemplateMaxAge time.Duration
+
+	// AllocateContainerAddresses holds whether containers get static
+	// addresses allocated by the provider.
+	AllocateContainerAddresses bool
 }
 
 // ProvisioningScriptParams contains the parameters for the
@@ -546,6 +720,110 @@ const (
 type ModifyEnvironUser struct {
 	UserTag string        `json:"user-tag"`
 	Action  EnvironAction `json:"action"`
+
+	// Access holds the access to grant to an added user. If it
+	// is empty, the user is given write access.
+	Access string `json:"access,omitempty"`
+}
+
+// EnvironAccessAction is an action that changes a user's level of
+// access to an environment.
+type EnvironAccessAction string
+
+// Actions that change a user's level of access to an environment.
+const (
+	GrantEnvironAccess  EnvironAccessAction = "grant"
+	RevokeEnvironAccess EnvironAccessAction = "revoke"
+)
+
+// ModifyEnvironAccessRequest holds the parameters for making
+// UserManager ModifyEnvironAccess calls.
+type ModifyEnvironAccessRequest struct {
+	Changes []ModifyEnvironAccess `json:"changes"`
+}
+
+// ModifyEnvironAccess holds a change to a user's level of access to
+// the environment: one of "read", "write" or "admin".
+type ModifyEnvironAccess struct {
+	UserTag string              `json:"user-tag"`
+	Action  EnvironAccessAction `json:"action"`
+	Access  string              `json:"access"`
+}
+
+// EnvironmentCreateArgs holds the arguments needed to create an
+// environment hosted by the state server.
+type EnvironmentCreateArgs struct {
+	// OwnerTag represents the user that will own the new environment.
+	OwnerTag string
+
+	// Account holds the provider specific account details, such as
+	// credentials, of the new environment. If it is empty, the account
+	// of the state server environment is used.
+	Account map[string]interface{}
+
+	// Config holds the configuration of the new environment that
+	// differs from the state server environment's. It must include
+	// the name of the new environment.
+	Config map[string]interface{}
+}
+
+// Environment holds the name, UUID and owner of an environment.
+type Environment struct {
+	Name     string
+	UUID     string
+	OwnerTag string
+}
+
+// EnvironmentList holds a list of environments.
+type EnvironmentList struct {
+	Environments []Environment
+}
+
+// SerializedEnvironment holds the model of an environment exported for
+// migration to another state server.
+type SerializedEnvironment struct {
+	UUID     string
+	Name     string
+	OwnerTag string
+
+	// Model holds the environment's documents; it is opaque to clients.
+	Model []byte
+
+	// Charms holds the storage paths of the environment's charm
+	// archives, and Tools the versions of its tools, which are copied
+	// with ExportBlob and ImportBlob.
+	Charms []string
+	Tools  []version.Binary
+}
+
+// MigrationBlob identifies a charm archive or tools tarball of an
+// environment being migrated, and holds its contents when importing.
+type MigrationBlob struct {
+	EnvTag string
+	Kind   string
+	Name   string
+	Data   []byte `json:",omitempty"`
+}
+
+// MigrationTarget holds the API addresses and CA certificate of the
+// state server an environment migrates to.
+type MigrationTarget struct {
+	EnvTag       string
+	APIHostPorts [][]network.HostPort
+	CACert       string
+}
+
+// MigrationProgress reports which of the agents of a migrated
+// environment have connected to its new state server.
+type MigrationProgress struct {
+	Expected  []string
+	CheckedIn []string
+}
+
+// MigrationStatus reports the phase of an environment's migration, or
+// no phase if it is not migrating.
+type MigrationStatus struct {
+	Phase string
 }
 
 // SetEnvironAgentVersion contains the arguments for
@@ -554,6 +832,57 @@ type SetEnvironAgentVersion struct {
 	Version version.Number
 }
 
+// StartStagedUpgrade contains the arguments for the StartStagedUpgrade
+// client API call.
+type StartStagedUpgrade struct {
+	Version       version.Number
+	CanaryPercent int
+	BatchSize     int
+	HealthTimeout time.Duration
+}
+
+// StagedUpgradeMachine holds the progress of a staged upgrade on a
+// machine. Problems holds why the agents of a released machine, or of
+// its units, are not healthy on the target version.
+type StagedUpgradeMachine struct {
+	Id          string
+	StateServer bool
+	Released    bool
+	Version     string
+	Problems    []string
+}
+
+// StagedUpgradeStatus holds the progress of the staged upgrade of an
+// environment, returned by the StagedUpgradeStatus client API call.
+type StagedUpgradeStatus struct {
+	PreviousVersion version.Number
+	TargetVersion   version.Number
+	Status          string
+	HaltReason      string
+	CanaryPercent   int
+	BatchSize       int
+	HealthTimeout   time.Duration
+	Started         time.Time
+	StageStarted    time.Time
+	Machines        []StagedUpgradeMachine
+}
+
+// UpgradeRollbackArgs holds the arguments of the RollbackUpgrade client
+// API call. Force rolls back the upgrade even if documents were changed
+// since the state backup was taken.
+type UpgradeRollbackArgs struct {
+	Force bool
+}
+
+// UpgradeRollback holds the result of the RollbackUpgrade client API
+// call: the upgrade that was rolled back, and the state backup that was
+// restored.
+type UpgradeRollback struct {
+	PreviousVersion version.Number
+	TargetVersion   version.Number
+	BackupId        string
+}
+
 // DeployerConnectionValues containers the result of deployer.ConnectionInfo
 // API call.
 type DeployerConnectionValues struct {
@@ -566,6 +895,55 @@ type StatusParams struct {
 	Patterns []string
 }
 
+// RefreshInstancesResult holds the result of the RefreshInstances call.
+type RefreshInstancesResult struct {
+	// Stale holds the ids of the machines whose instances could not
+	// be polled, or were not polled before the call gave up waiting.
+	Stale []string `json:"stale,omitempty"`
+}
+
+// IntendedNetworkInterface describes a network interface as configured
+// for a machine in state.
+type IntendedNetworkInterface struct {
+	InterfaceName string `json:"interfacename"`
+	MACAddress    string `json:"macaddress"`
+	NetworkName   string `json:"networkname"`
+	CIDR          string `json:"cidr,omitempty"`
+	VLANTag       int    `json:"vlantag,omitempty"`
+	Disabled      bool   `json:"disabled"`
+}
+
+// NetworkInterfaceDrift describes how a network interface found on a
+// machine differs from its configuration in state.
+type NetworkInterfaceDrift struct {
+	InterfaceName string `json:"interfacename"`
+	Problem       string `json:"problem"`
+}
+
+// MachineNetworkStatus holds the network interfaces configured for a
+// machine, the ones last found on it by its agent, when, and how they
+// differ. Observed is nil if the agent has not reported the interfaces
+// yet.
+type MachineNetworkStatus struct {
+	Intended   []IntendedNetworkInterface `json:"intended"`
+	Observed   []ObservedNetworkInterface `json:"observed"`
+	ObservedAt *time.Time                 `json:"observedat,omitempty"`
+	Drift      []NetworkInterfaceDrift    `json:"drift,omitempty"`
+}
+
+// MachineNetworkStatusResult holds the network status of a machine, or
+// an error.
+type MachineNetworkStatusResult struct {
+	Status MachineNetworkStatus `json:"status"`
+	Error  *Error               `json:"error,omitempty"`
+}
+
+// MachineNetworkStatusResults holds the results of a
+// MachineNetworkStatus call.
+type MachineNetworkStatusResults struct {
+	Results []MachineNetworkStatusResult `json:"results"`
+}
+
 // SetRsyslogCertParams holds parameters for the SetRsyslogCert call.
 type SetRsyslogCertParams struct {
 	CACert []byte
@@ -606,6 +984,27 @@ type DistributionGroupResults struct {
 	Results []DistributionGroupResult
 }
 
+// ZonePlacement describes the availability zones in which a machine
+// may be started, to honour the placement policies of its units. If
+// Allowed is nil, every zone not excluded is allowed.
+type ZonePlacement struct {
+	Allowed  []string
+	Excluded []string
+	Avoided  []string
+}
+
+// ZonePlacementResult holds the result of the ZonePlacement
+// provisioner API call.
+type ZonePlacementResult struct {
+	Error  *Error
+	Result ZonePlacement
+}
+
+// ZonePlacementResults is the bulk form of ZonePlacementResult.
+type ZonePlacementResults struct {
+	Results []ZonePlacementResult
+}
+
 // APIHostPortsResult holds the result of an APIHostPorts
 // call. Each element in the top level slice holds
 // the addresses for one API server.
@@ -778,6 +1177,71 @@ type ImageMetadata struct {
 	Created time.Time `json:"created"`
 }
 
+// PrimeTemplatesParams holds the container templates to build on host
+// machines ahead of their first containers.
+type PrimeTemplatesParams struct {
+	Templates []TemplateSpec `json:"templates"`
+}
+
+// TemplateSpec identifies a container template on a host machine.
+type TemplateSpec struct {
+	MachineTag string `json:"machinetag"`
+	Kind       string `json:"kind"`
+	Series     string `json:"series"`
+}
+
+// ContainerTemplate describes a container template on a host machine.
+type ContainerTemplate struct {
+	Kind   string `json:"kind"`
+	Series string `json:"series"`
+
+	// PrimeRequested holds when the template was requested to be
+	// built, if the request is pending.
+	PrimeRequested *time.Time `json:"primerequested,omitempty"`
+
+	// Built holds when the template was last built, if ever.
+	Built *time.Time `json:"built,omitempty"`
+
+	// Packages holds the packages installed in the template, as
+	// "name=version".
+	Packages []string `json:"packages,omitempty"`
+
+	// Error holds why the template could not be built last time.
+	Error string `json:"error,omitempty"`
+}
+
+// ContainerTemplatesResult holds the container templates of a host
+// machine, or an error.
+type ContainerTemplatesResult struct {
+	Templates []ContainerTemplate `json:"templates"`
+	Error     *Error              `json:"error,omitempty"`
+}
+
+// ContainerTemplatesResults holds the results of a ContainerTemplates
+// call.
+type ContainerTemplatesResults struct {
+	Results []ContainerTemplatesResult `json:"results"`
+}
+
+// SetContainerTemplate records the outcome of building a container
+// template on a host machine.
+type SetContainerTemplate struct {
+	TemplateSpec
+
+	// Packages holds the packages installed in the template, as
+	// "name=version", if it was built.
+	Packages []string `json:"packages,omitempty"`
+
+	// Error holds why the template could not be built, if it failed.
+	Error string `json:"error,omitempty"`
+}
+
+// SetContainerTemplates holds the arguments of a SetContainerTemplates
+// call.
+type SetContainerTemplates struct {
+	Templates []SetContainerTemplate `json:"templates"`
+}
+
 // RebootActionResults holds a list of RebootActionResult and any error.
 type RebootActionResults struct {
 	Results []RebootActionResult `json:"results,omitempty"`
@@ -900,3 +1364,89 @@ type DatastoreResult struct {
 type DatastoreResults struct {
 	Results []DatastoreResult `json:"results,omitempty"`
 }
+
+// RequestTimings holds the aggregated times taken by an API server
+// to serve requests, as reported by its introspection endpoint.
+type RequestTimings struct {
+	// Buckets holds the upper bounds of the histogram buckets
+	// used in each RequestTiming.
+	Buckets []time.Duration
+	Methods []RequestTiming
+}
+
+// RequestTiming holds the aggregated times taken to serve requests
+// for a single facade method.
+type RequestTiming struct {
+	Facade  string
+	Version int
+	Method  string
+	Count   int64
+	Errors  int64
+	Total   time.Duration
+	Max     time.Duration
+	// Histogram holds the number of requests which fell into each
+	// of the corresponding Buckets, followed by the number which
+	// took longer than every bucket.
+	Histogram []int64
+}
+
+// APIServerLoad holds the current load on an API server, as reported
+// by its introspection endpoint and the Client.APIServerLoad call.
+// Agents and users are admitted with separate budgets, reported
+// separately.
+type APIServerLoad struct {
+	Agents ClientLoad
+	Users  ClientLoad
+}
+
+// ClientLoad holds the load placed on an API server by one kind of
+// client. A negative maximum means there is no limit.
+type ClientLoad struct {
+	Connections       int
+	MaxConnections    int
+	LoginsInProgress  int
+	MaxLogins         int
+	LoginsWaiting     int
+	LoginsRejected    int64
+	RequestsThrottled int64
+}
+
+// RemoteRelationChange describes a change to the units of the local
+// service of a relation with a service in another environment, to be
+// applied to the relation's counterpart in that environment.
+type RemoteRelationChange struct {
+	// RelationKey identifies the relation in both environments.
+	RelationKey string
+
+	// SourceEnvUUID holds the UUID of the environment the change
+	// comes from, which must run the relation's remote service.
+	SourceEnvUUID string
+
+	// ChangedUnits holds the units which entered the relation or
+	// whose settings in it changed.
+	ChangedUnits []RemoteRelationUnit
+
+	// DepartedUnits holds the names of the units which left the
+	// relation.
+	DepartedUnits []string
+
+	// RemoveAllUnits is true if all the units of the source
+	// environment have left the relation.
+	RemoveAllUnits bool
+
+	// Destroy is true if the relation is being destroyed.
+	Destroy bool
+}
+
+// RemoteRelationUnit holds the settings of a unit in a relation with a
+// service in another environment.
+type RemoteRelationUnit struct {
+	UnitName string
+	Settings map[string]interface{}
+}
+
+// RemoteRelationChanges holds the parameters for the
+// RemoteRelations.PublishRelationChanges call.
+type RemoteRelationChanges struct {
+	Changes []RemoteRelationChange
+}
@@ -5,7 +5,10 @@ package params_test
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	stdtesting "testing"
+	"time"
 
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
@@ -378,6 +381,54 @@ func (s *ErrorResultsSuite) TestCombine(c *gc.C) {
 	}
 }
 
+type RetryAfterSuite struct{}
+
+var _ = gc.Suite(&RetryAfterSuite{})
+
+func (*RetryAfterSuite) TestRetryAfter(c *gc.C) {
+	err := params.NewTryAgainError("busy", 1500*time.Millisecond)
+	c.Assert(params.IsCodeTryAgain(err), jc.IsTrue)
+	delay, ok := params.RetryAfter(err)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(delay, gc.Equals, 1500*time.Millisecond)
+
+	// The delay survives a round trip through JSON.
+	data, jsonErr := json.Marshal(err)
+	c.Assert(jsonErr, jc.ErrorIsNil)
+	var decoded params.Error
+	jsonErr = json.Unmarshal(data, &decoded)
+	c.Assert(jsonErr, jc.ErrorIsNil)
+	delay, ok = params.RetryAfter(&decoded)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(delay, gc.Equals, 1500*time.Millisecond)
+}
+
+func (*RetryAfterSuite) TestRetryAfterNotSpecified(c *gc.C) {
+	for i, err := range []error{
+		errors.New("boom"),
+		&params.Error{Message: "busy", Code: params.CodeTryAgain},
+		&params.Error{
+			Message: "not found",
+			Code:    params.CodeNotFound,
+			Info:    map[string]interface{}{params.RetryAfterKey: 1.0},
+		},
+	} {
+		c.Logf("test %d: %v", i, err)
+		_, ok := params.RetryAfter(err)
+		c.Check(ok, jc.IsFalse)
+	}
+}
+
+func (s *RetryAfterSuite) TestGoStringIncludesInfo(c *gc.C) {
+	err := &params.Error{
+		Message: "too busy",
+		Code:    params.CodeTryAgain,
+		Info:    map[string]interface{}{params.RetryAfterKey: 1.0},
+	}
+	c.Assert(fmt.Sprintf("%#v", err), gc.Equals,
+		`&params.Error{Message: "too busy", Code: "try again", Info: map[string]interface {}{"retry-after":1}}`)
+}
+
 type importSuite struct{}
 
 var _ = gc.Suite(&importSuite{})
@@ -57,3 +57,47 @@ type AddUserResult struct {
 	Tag   string `json:"tag,omitempty"`
 	Error *Error `json:"error,omitempty"`
 }
+
+// UserSession holds the details of an API connection of a logged in
+// user.
+type UserSession struct {
+	UserTag       string    `json:"user-tag"`
+	EnvironTag    string    `json:"environ-tag"`
+	RemoteAddress string    `json:"remote-address"`
+	LoginTime     time.Time `json:"login-time"`
+	APIServer     string    `json:"api-server"`
+	ConnectionId  int64     `json:"connection-id"`
+}
+
+// UserSessionsResult holds the API connections of one user, or an
+// error.
+type UserSessionsResult struct {
+	Sessions []UserSession `json:"sessions"`
+	Error    *Error        `json:"error,omitempty"`
+}
+
+// UserSessionsResults holds the results of the bulk UserSessions API
+// call.
+type UserSessionsResults struct {
+	Results []UserSessionsResult `json:"results"`
+}
+
+// CloseUserSessionsResult holds the number of API connections of one
+// user that were closed, or an error.
+type CloseUserSessionsResult struct {
+	Closed int    `json:"closed"`
+	Error  *Error `json:"error,omitempty"`
+}
+
+// CloseUserSessionsResults holds the results of the bulk
+// CloseUserSessions API call.
+type CloseUserSessionsResults struct {
+	Results []CloseUserSessionsResult `json:"results"`
+}
+
+// UserSSHKeys holds SSH keys to add to or remove from a user. Keys to
+// remove are identified by their fingerprint or comment.
+type UserSSHKeys struct {
+	UserTag string   `json:"user-tag"`
+	Keys    []string `json:"keys"`
+}
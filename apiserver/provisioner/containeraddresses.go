@@ -0,0 +1,303 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package provisioner
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+	"github.com/juju/names"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/environs"
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+)
+
+var logger = loggo.GetLogger("juju.apiserver.provisioner")
+
+// maxAddressAttempts is how many addresses are tried for a container
+// when the provider refuses to allocate them, before giving up.
+const maxAddressAttempts = 10
+
+// PrepareContainerInterfaceInfo allocates an address through the
+// provider for each given container, from a subnet of its host, and
+// returns the configuration of the container's network interface. A
+// container that already has an address allocated keeps it.
+func (p *ProvisionerAPI) PrepareContainerInterfaceInfo(args params.Entities) (params.MachineNetworkInfoResults, error) {
+	result := params.MachineNetworkInfoResults{
+		Results: make([]params.MachineNetworkInfoResult, len(args.Entities)),
+	}
+	canAccess, err := p.getAuthFunc()
+	if err != nil {
+		return result, err
+	}
+	var env environs.Environ
+	for i, entity := range args.Entities {
+		tag, err := names.ParseMachineTag(entity.Tag)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(common.ErrPerm)
+			continue
+		}
+		container, err := p.getMachine(canAccess, tag)
+		if err == nil && env == nil {
+			env, err = p.environ()
+		}
+		if err == nil {
+			var info network.Info
+			info, err = p.prepareContainerInterfaceInfo(env, container)
+			if err == nil {
+				result.Results[i].Info = []network.Info{info}
+			}
+		}
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
+// GetContainerInterfaceInfo returns the configuration of the network
+// interface of each given container that has an address allocated,
+// without allocating any. The containers need not exist any more.
+func (p *ProvisionerAPI) GetContainerInterfaceInfo(args params.Entities) (params.MachineNetworkInfoResults, error) {
+	result := params.MachineNetworkInfoResults{
+		Results: make([]params.MachineNetworkInfoResult, len(args.Entities)),
+	}
+	canAccess, err := p.getAuthFunc()
+	if err != nil {
+		return result, err
+	}
+	for i, entity := range args.Entities {
+		tag, err := names.ParseMachineTag(entity.Tag)
+		if err != nil || !canAccess(tag) {
+			result.Results[i].Error = common.ServerError(common.ErrPerm)
+			continue
+		}
+		result.Results[i].Info, err = p.containerInterfaceInfo(tag.Id())
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
+// ReleaseContainerAddresses releases the addresses allocated to each
+// given container through the provider, and removes them from state.
+// The containers must not be alive, but need not exist any more, so
+// that releases that failed can be retried after the containers have
+// been removed. Addresses that could not be released are left in state.
+func (p *ProvisionerAPI) ReleaseContainerAddresses(args params.Entities) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.Entities)),
+	}
+	canAccess, err := p.getAuthFunc()
+	if err != nil {
+		return result, err
+	}
+	var env environs.Environ
+	for i, entity := range args.Entities {
+		tag, err := names.ParseMachineTag(entity.Tag)
+		if err != nil || !canAccess(tag) {
+			result.Results[i].Error = common.ServerError(common.ErrPerm)
+			continue
+		}
+		if env == nil {
+			env, err = p.environ()
+		}
+		if err == nil {
+			err = p.releaseContainerAddresses(env, tag.Id())
+		}
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
+func (p *ProvisionerAPI) environ() (environs.Environ, error) {
+	cfg, err := p.st.EnvironConfig()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return environs.New(cfg)
+}
+
+func (p *ProvisionerAPI) prepareContainerInterfaceInfo(env environs.Environ, container *state.Machine) (info network.Info, err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot allocate address for container %q", container.Id())
+	hostId, err := hostInstanceId(p.st, container.Id())
+	if err != nil {
+		return info, errors.Trace(err)
+	}
+	allocated, err := p.st.AllocatedIPAddresses(container.Id())
+	if err != nil {
+		return info, errors.Trace(err)
+	}
+	if len(allocated) > 0 {
+		subnet, err := p.st.Subnet(allocated[0].SubnetId())
+		if err != nil {
+			return info, errors.Trace(err)
+		}
+		return interfaceInfo(subnet, allocated[0]), nil
+	}
+	subnet, err := allocatableSubnet(p.st, env, hostId)
+	if err != nil {
+		return info, errors.Trace(err)
+	}
+	for attempt := 0; attempt < maxAddressAttempts; attempt++ {
+		addr, err := subnet.PickNewAddress()
+		if err != nil {
+			return info, errors.Trace(err)
+		}
+		err = env.AllocateAddress(hostId, network.Id(subnet.ProviderId()), addr.Address())
+		if errors.IsNotSupported(err) || errors.IsNotImplemented(err) {
+			// No other address would do better.
+			if err := addr.Remove(); err != nil {
+				logger.Warningf("%v", err)
+			}
+			return info, errors.Trace(err)
+		}
+		if err != nil {
+			logger.Warningf("cannot allocate address %q for container %q: %v", addr.Value(), container.Id(), err)
+			if err := addr.SetState(state.AddressStateUnvailable); err != nil {
+				return info, errors.Trace(err)
+			}
+			continue
+		}
+		err = addr.AllocateTo(container.Id(), "")
+		if err == nil {
+			err = addr.SetState(state.AddressStateAllocated)
+		}
+		if err != nil {
+			// Don't leave the address allocated by the provider
+			// with nothing in state using it.
+			releaseUnusedAddress(env, hostId, subnet, addr)
+			return info, errors.Trace(err)
+		}
+		return interfaceInfo(subnet, addr), nil
+	}
+	return info, errors.Errorf("no address of subnet %q could be allocated after %d attempts", subnet.CIDR(), maxAddressAttempts)
+}
+
+// releaseUnusedAddress releases an address allocated by the provider
+// that could not be recorded as allocated to a container, and removes
+// it from state. If the provider cannot release it, it is marked
+// unavailable so that it is not picked again.
+func releaseUnusedAddress(env environs.Environ, hostId instance.Id, subnet *state.Subnet, addr *state.IPAddress) {
+	if err := env.ReleaseAddress(hostId, network.Id(subnet.ProviderId()), addr.Address()); err != nil {
+		logger.Errorf("cannot release unused address %q: %v", addr.Value(), err)
+		if err := addr.SetState(state.AddressStateUnvailable); err != nil {
+			logger.Warningf("%v", err)
+		}
+		return
+	}
+	if err := addr.Remove(); err != nil {
+		logger.Warningf("%v", err)
+	}
+}
+
+func (p *ProvisionerAPI) containerInterfaceInfo(containerId string) ([]network.Info, error) {
+	allocated, err := p.st.AllocatedIPAddresses(containerId)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var result []network.Info
+	for _, addr := range allocated {
+		subnet, err := p.st.Subnet(addr.SubnetId())
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		result = append(result, interfaceInfo(subnet, addr))
+	}
+	return result, nil
+}
+
+func (p *ProvisionerAPI) releaseContainerAddresses(env environs.Environ, containerId string) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot release addresses of container %q", containerId)
+	container, err := p.st.Machine(containerId)
+	if err == nil && container.Life() == state.Alive {
+		return errors.New("container is alive")
+	} else if err != nil && !errors.IsNotFound(err) {
+		return errors.Trace(err)
+	}
+	allocated, err := p.st.AllocatedIPAddresses(containerId)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(allocated) == 0 {
+		return nil
+	}
+	hostId, err := hostInstanceId(p.st, containerId)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, addr := range allocated {
+		subnet, err := p.st.Subnet(addr.SubnetId())
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if err := env.ReleaseAddress(hostId, network.Id(subnet.ProviderId()), addr.Address()); err != nil {
+			return errors.Trace(err)
+		}
+		if err := addr.Remove(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// hostInstanceId returns the instance id of the machine hosting the
+// container with the given id.
+func hostInstanceId(st *state.State, containerId string) (instance.Id, error) {
+	hostId := state.ParentId(containerId)
+	if hostId == "" {
+		return "", errors.Errorf("machine %q is not a container", containerId)
+	}
+	host, err := st.Machine(hostId)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	return host.InstanceId()
+}
+
+// allocatableSubnet returns the first subnet of the host instance with
+// allocatable addresses, adding it to state if it is not there yet.
+func allocatableSubnet(st *state.State, env environs.Environ, hostId instance.Id) (*state.Subnet, error) {
+	subnets, err := env.Subnets(hostId)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, info := range subnets {
+		if info.AllocatableIPLow == nil || info.AllocatableIPHigh == nil {
+			continue
+		}
+		subnet, err := st.Subnet(info.CIDR)
+		if errors.IsNotFound(err) {
+			subnet, err = st.AddSubnet(state.SubnetInfo{
+				ProviderId:        string(info.ProviderId),
+				CIDR:              info.CIDR,
+				VLANTag:           info.VLANTag,
+				AllocatableIPLow:  info.AllocatableIPLow.String(),
+				AllocatableIPHigh: info.AllocatableIPHigh.String(),
+			})
+			if errors.IsAlreadyExists(err) {
+				subnet, err = st.Subnet(info.CIDR)
+			}
+		}
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if subnet.AllocatableIPLow() == "" || subnet.AllocatableIPHigh() == "" {
+			// Added without the provider's details.
+			continue
+		}
+		return subnet, nil
+	}
+	return nil, errors.Errorf("host instance %q has no subnet with allocatable addresses", hostId)
+}
+
+func interfaceInfo(subnet *state.Subnet, addr *state.IPAddress) network.Info {
+	return network.Info{
+		CIDR:          subnet.CIDR(),
+		ProviderId:    network.Id(subnet.ProviderId()),
+		VLANTag:       subnet.VLANTag(),
+		InterfaceName: "eth0",
+		Address:       network.NewAddress(addr.Value(), network.ScopeCloudLocal),
+	}
+}
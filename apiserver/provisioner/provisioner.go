@@ -243,6 +243,8 @@ func (p *ProvisionerAPI) ContainerConfig() (params.ContainerConfig, error) {
 	result.Proxy = config.ProxySettings()
 	result.AptProxy = config.AptProxySettings()
 	result.PreferIPv6 = config.PreferIPv6()
+	result.LXCTemplateMaxAge = config.LXCTemplateMaxAge()
+	result.AllocateContainerAddresses = config.AllocateContainerAddresses()
 
 	return result, nil
 }
@@ -429,6 +431,95 @@ func (p *ProvisionerAPI) DistributionGroup(args params.Entities) (params.Distrib
 	return result, nil
 }
 
+// ZonePlacement returns, for each given machine entity, the
+// availability zones in which the machine may be started to honour the
+// placement policies of the units assigned to it.
+func (p *ProvisionerAPI) ZonePlacement(args params.Entities) (params.ZonePlacementResults, error) {
+	result := params.ZonePlacementResults{
+		Results: make([]params.ZonePlacementResult, len(args.Entities)),
+	}
+	canAccess, err := p.getAuthFunc()
+	if err != nil {
+		return result, err
+	}
+	for i, entity := range args.Entities {
+		tag, err := names.ParseMachineTag(entity.Tag)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(common.ErrPerm)
+			continue
+		}
+		machine, err := p.getMachine(canAccess, tag)
+		if err == nil {
+			var placement state.ZonePlacement
+			placement, err = machine.ZonePlacement()
+			result.Results[i].Result = params.ZonePlacement{
+				Allowed:  placement.Allowed,
+				Excluded: placement.Excluded,
+				Avoided:  placement.Avoided,
+			}
+		}
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
+// ContainerTemplates returns the container templates recorded for each
+// given machine.
+func (p *ProvisionerAPI) ContainerTemplates(args params.Entities) (params.ContainerTemplatesResults, error) {
+	result := params.ContainerTemplatesResults{
+		Results: make([]params.ContainerTemplatesResult, len(args.Entities)),
+	}
+	canAccess, err := p.getAuthFunc()
+	if err != nil {
+		return result, err
+	}
+	for i, entity := range args.Entities {
+		tag, err := names.ParseMachineTag(entity.Tag)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(common.ErrPerm)
+			continue
+		}
+		machine, err := p.getMachine(canAccess, tag)
+		if err == nil {
+			var templates []*state.ContainerTemplate
+			templates, err = machine.ContainerTemplates()
+			result.Results[i].Templates = common.ContainerTemplates(templates)
+		}
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
+// SetContainerTemplates records the outcome of building each given
+// container template.
+func (p *ProvisionerAPI) SetContainerTemplates(args params.SetContainerTemplates) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.Templates)),
+	}
+	canAccess, err := p.getAuthFunc()
+	if err != nil {
+		return result, err
+	}
+	for i, arg := range args.Templates {
+		tag, err := names.ParseMachineTag(arg.MachineTag)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(common.ErrPerm)
+			continue
+		}
+		machine, err := p.getMachine(canAccess, tag)
+		if err == nil {
+			kind := instance.ContainerType(arg.Kind)
+			if arg.Error != "" {
+				err = machine.SetContainerTemplateFailed(kind, arg.Series, arg.Error)
+			} else {
+				err = machine.SetContainerTemplateBuilt(kind, arg.Series, arg.Packages)
+			}
+		}
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
 // environManagerInstances returns all environ manager instances.
 func environManagerInstances(st *state.State) ([]instance.Id, error) {
 	info, err := st.StateServerInfo()
@@ -6,6 +6,7 @@ package provisioner_test
 import (
 	"fmt"
 	stdtesting "testing"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/juju/names"
@@ -668,6 +669,249 @@ func (s *withoutStateServerSuite) TestDistributionGroup(c *gc.C) {
 	})
 }
 
+func (s *withoutStateServerSuite) TestZonePlacement(c *gc.C) {
+	wordpress := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	err := wordpress.SetPlacementPolicies([]state.PlacementPolicy{
+		{Kind: state.MaxUnitsPerZone, MaxUnits: 1},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	for _, m := range s.machines[1:3] {
+		unit, err := wordpress.AddUnit()
+		c.Assert(err, jc.ErrorIsNil)
+		err = unit.AssignToMachine(m)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	zone := "zone-a"
+	err = s.machines[1].SetProvisioned("machine-1-inst", "nonce", &instance.HardwareCharacteristics{
+		AvailabilityZone: &zone,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	args := params.Entities{Entities: []params.Entity{
+		{Tag: s.machines[2].Tag().String()},
+		{Tag: s.machines[3].Tag().String()},
+		{Tag: "machine-42"},
+		{Tag: "unit-foo-0"},
+	}}
+	result, err := s.provisioner.ZonePlacement(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, params.ZonePlacementResults{
+		Results: []params.ZonePlacementResult{
+			{Result: params.ZonePlacement{Excluded: []string{"zone-a"}}},
+			{},
+			{Error: apiservertesting.NotFoundError("machine 42")},
+			{Error: apiservertesting.ErrUnauthorized},
+		},
+	})
+}
+
+func (s *withoutStateServerSuite) addContainer(c *gc.C) *state.Machine {
+	err := s.machines[0].SetProvisioned("i-host", "fake_nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	template := state.MachineTemplate{
+		Series: "quantal",
+		Jobs:   []state.MachineJob{state.JobHostUnits},
+	}
+	container, err := s.State.AddMachineInsideMachine(template, s.machines[0].Id(), instance.LXC)
+	c.Assert(err, jc.ErrorIsNil)
+	return container
+}
+
+func (s *withoutStateServerSuite) TestPrepareContainerInterfaceInfo(c *gc.C) {
+	container := s.addContainer(c)
+
+	args := params.Entities{Entities: []params.Entity{
+		{Tag: container.Tag().String()},
+		{Tag: s.machines[1].Tag().String()},
+		{Tag: "machine-42"},
+		{Tag: "unit-foo-0"},
+	}}
+	result, err := s.provisioner.PrepareContainerInterfaceInfo(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Results, gc.HasLen, 4)
+	c.Assert(result.Results[0].Error, gc.IsNil)
+	c.Assert(result.Results[0].Info, gc.HasLen, 1)
+	info := result.Results[0].Info[0]
+	c.Assert(info.Address.Value, gc.Matches, `0\.10\.0\.\d+`)
+	info.Address = network.Address{}
+	c.Assert(info, jc.DeepEquals, network.Info{
+		CIDR:          "0.10.0.0/8",
+		ProviderId:    "dummy-private",
+		InterfaceName: "eth0",
+	})
+	c.Assert(result.Results[1:], jc.DeepEquals, []params.MachineNetworkInfoResult{
+		{Error: apiservertesting.ServerError(`cannot allocate address for container "1": machine "1" is not a container`)},
+		{Error: apiservertesting.NotFoundError("machine 42")},
+		{Error: apiservertesting.ErrUnauthorized},
+	})
+
+	allocated, err := s.State.AllocatedIPAddresses(container.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(allocated, gc.HasLen, 1)
+	c.Assert(allocated[0].Value(), gc.Equals, result.Results[0].Info[0].Address.Value)
+
+	// Preparing again returns the address already allocated.
+	again, err := s.provisioner.PrepareContainerInterfaceInfo(params.Entities{
+		Entities: []params.Entity{{Tag: container.Tag().String()}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(again.Results[0].Error, gc.IsNil)
+	c.Assert(again.Results[0].Info, jc.DeepEquals, result.Results[0].Info)
+}
+
+func (s *withoutStateServerSuite) TestPrepareContainerInterfaceInfoPermissions(c *gc.C) {
+	container := s.addContainer(c)
+	template := state.MachineTemplate{
+		Series: "quantal",
+		Jobs:   []state.MachineJob{state.JobHostUnits},
+	}
+	other, err := s.State.AddMachineInsideMachine(template, s.machines[1].Id(), instance.LXC)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Login as a machine agent for machine 0.
+	anAuthorizer := s.authorizer
+	anAuthorizer.EnvironManager = false
+	anAuthorizer.Tag = s.machines[0].Tag()
+	aProvisioner, err := provisioner.NewProvisionerAPI(s.State, s.resources, anAuthorizer)
+	c.Assert(err, jc.ErrorIsNil)
+
+	result, err := aProvisioner.PrepareContainerInterfaceInfo(params.Entities{
+		Entities: []params.Entity{
+			{Tag: container.Tag().String()},
+			{Tag: other.Tag().String()},
+		},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Results[0].Error, gc.IsNil)
+	c.Assert(result.Results[1].Error, jc.DeepEquals, apiservertesting.ErrUnauthorized)
+}
+
+func (s *withoutStateServerSuite) TestReleaseContainerAddresses(c *gc.C) {
+	container := s.addContainer(c)
+	args := params.Entities{Entities: []params.Entity{{Tag: container.Tag().String()}}}
+	prepared, err := s.provisioner.PrepareContainerInterfaceInfo(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(prepared.Results[0].Error, gc.IsNil)
+	value := prepared.Results[0].Info[0].Address.Value
+
+	// The addresses of a live container are not released.
+	result, err := s.provisioner.ReleaseContainerAddresses(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, params.ErrorResults{
+		Results: []params.ErrorResult{
+			{Error: apiservertesting.ServerError(`cannot release addresses of container "0/lxc/0": container is alive`)},
+		},
+	})
+
+	err = container.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	result, err = s.provisioner.ReleaseContainerAddresses(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, params.ErrorResults{
+		Results: []params.ErrorResult{{Error: nil}},
+	})
+	allocated, err := s.State.AllocatedIPAddresses(container.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(allocated, gc.HasLen, 0)
+	_, err = s.State.IPAddress(value)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *withoutStateServerSuite) TestReleaseContainerAddressesRemovedContainer(c *gc.C) {
+	container := s.addContainer(c)
+	args := params.Entities{Entities: []params.Entity{{Tag: container.Tag().String()}}}
+	prepared, err := s.provisioner.PrepareContainerInterfaceInfo(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(prepared.Results[0].Error, gc.IsNil)
+
+	// A release that failed can be retried once the container is gone.
+	err = container.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	err = container.Remove()
+	c.Assert(err, jc.ErrorIsNil)
+	result, err := s.provisioner.ReleaseContainerAddresses(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, params.ErrorResults{
+		Results: []params.ErrorResult{{Error: nil}},
+	})
+	allocated, err := s.State.AllocatedIPAddresses(container.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(allocated, gc.HasLen, 0)
+}
+
+func (s *withoutStateServerSuite) TestGetContainerInterfaceInfo(c *gc.C) {
+	container := s.addContainer(c)
+	args := params.Entities{Entities: []params.Entity{
+		{Tag: container.Tag().String()},
+		{Tag: "unit-foo-0"},
+	}}
+	// Nothing is allocated by asking.
+	result, err := s.provisioner.GetContainerInterfaceInfo(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Results, jc.DeepEquals, []params.MachineNetworkInfoResult{
+		{},
+		{Error: apiservertesting.ErrUnauthorized},
+	})
+
+	prepared, err := s.provisioner.PrepareContainerInterfaceInfo(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(prepared.Results[0].Error, gc.IsNil)
+	result, err = s.provisioner.GetContainerInterfaceInfo(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Results[0].Error, gc.IsNil)
+	c.Assert(result.Results[0].Info, jc.DeepEquals, prepared.Results[0].Info)
+}
+
+func (s *withoutStateServerSuite) TestSetAndGetContainerTemplates(c *gc.C) {
+	err := s.machines[1].RequestContainerTemplate(instance.LXC, "trusty")
+	c.Assert(err, jc.ErrorIsNil)
+
+	result, err := s.provisioner.SetContainerTemplates(params.SetContainerTemplates{
+		Templates: []params.SetContainerTemplate{{
+			TemplateSpec: params.TemplateSpec{MachineTag: s.machines[1].Tag().String(), Kind: "lxc", Series: "trusty"},
+			Packages:     []string{"cloud-init=0.7.5"},
+		}, {
+			TemplateSpec: params.TemplateSpec{MachineTag: s.machines[2].Tag().String(), Kind: "lxc", Series: "trusty"},
+			Error:        "boom",
+		}, {
+			TemplateSpec: params.TemplateSpec{MachineTag: "machine-42", Kind: "lxc", Series: "trusty"},
+		}, {
+			TemplateSpec: params.TemplateSpec{MachineTag: "unit-foo-0", Kind: "lxc", Series: "trusty"},
+		}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, gc.DeepEquals, params.ErrorResults{
+		Results: []params.ErrorResult{
+			{nil},
+			{nil},
+			{apiservertesting.NotFoundError("machine 42")},
+			{apiservertesting.ErrUnauthorized},
+		},
+	})
+
+	templates, err := s.provisioner.ContainerTemplates(params.Entities{Entities: []params.Entity{
+		{Tag: s.machines[1].Tag().String()},
+		{Tag: s.machines[2].Tag().String()},
+		{Tag: s.machines[3].Tag().String()},
+		{Tag: "unit-foo-0"},
+	}})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(templates.Results, gc.HasLen, 4)
+	built := templates.Results[0].Templates
+	c.Assert(built, gc.HasLen, 1)
+	c.Assert(built[0].Kind, gc.Equals, "lxc")
+	c.Assert(built[0].Series, gc.Equals, "trusty")
+	c.Assert(built[0].Built, gc.NotNil)
+	c.Assert(built[0].PrimeRequested, gc.IsNil)
+	c.Assert(built[0].Packages, jc.DeepEquals, []string{"cloud-init=0.7.5"})
+	failed := templates.Results[1].Templates
+	c.Assert(failed, gc.HasLen, 1)
+	c.Assert(failed[0].Built, gc.IsNil)
+	c.Assert(failed[0].Error, gc.Equals, "boom")
+	c.Assert(templates.Results[2], jc.DeepEquals, params.ContainerTemplatesResult{})
+	c.Assert(templates.Results[3].Error, jc.DeepEquals, apiservertesting.ErrUnauthorized)
+}
+
 func (s *withoutStateServerSuite) TestDistributionGroupEnvironManagerAuth(c *gc.C) {
 	args := params.Entities{Entities: []params.Entity{
 		{Tag: "machine-0"},
@@ -1142,7 +1386,9 @@ func (s *withoutStateServerSuite) TestContainerManagerConfig(c *gc.C) {
 
 func (s *withoutStateServerSuite) TestContainerConfig(c *gc.C) {
 	attrs := map[string]interface{}{
-		"http-proxy": "http://proxy.example.com:9000",
+		"http-proxy":                   "http://proxy.example.com:9000",
+		"lxc-template-max-age":         24,
+		"allocate-container-addresses": true,
 	}
 	err := s.State.UpdateEnvironConfig(attrs, nil, nil)
 	c.Assert(err, jc.ErrorIsNil)
@@ -1159,6 +1405,8 @@ func (s *withoutStateServerSuite) TestContainerConfig(c *gc.C) {
 	c.Check(results.Proxy, gc.DeepEquals, expectedProxy)
 	c.Check(results.AptProxy, gc.DeepEquals, expectedProxy)
 	c.Check(results.PreferIPv6, jc.IsTrue)
+	c.Check(results.LXCTemplateMaxAge, gc.Equals, 24*time.Hour)
+	c.Check(results.AllocateContainerAddresses, jc.IsTrue)
 }
 
 func (s *withoutStateServerSuite) TestSetSupportedContainers(c *gc.C) {
@@ -1282,7 +1530,8 @@ func (s *withStateServerSuite) TestStateAddresses(c *gc.C) {
 }
 
 func (s *withStateServerSuite) TestCACert(c *gc.C) {
-	result := s.provisioner.CACert()
+	result, err := s.provisioner.CACert()
+	c.Assert(err, jc.ErrorIsNil)
 	c.Assert(result, gc.DeepEquals, params.BytesResult{
 		Result: []byte(s.State.CACert()),
 	})
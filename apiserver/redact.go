@@ -0,0 +1,71 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package apiserver
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// secretFieldWords holds the words which, when found in the name of
+// a request parameter, cause its value to be omitted from the logs.
+var secretFieldWords = []string{
+	"password",
+	"credential",
+	"secret",
+	"privatekey",
+	"private-key",
+	"macaroon",
+	"token",
+	"nonce",
+	"systemidentity",
+}
+
+const redacted = "<redacted>"
+
+// redactParams returns a copy of the given request parameters, as
+// they would be marshalled to JSON, with the values of any fields
+// which may hold secrets replaced.
+func redactParams(body interface{}) interface{} {
+	if body == nil {
+		return nil
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "unmarshallable params: " + err.Error()
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return "unmarshallable params: " + err.Error()
+	}
+	return redactValue(value)
+}
+
+func redactValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for k, v := range value {
+			if isSecretField(k) {
+				value[k] = redacted
+			} else {
+				value[k] = redactValue(v)
+			}
+		}
+	case []interface{}:
+		for i, v := range value {
+			value[i] = redactValue(v)
+		}
+	}
+	return value
+}
+
+func isSecretField(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range secretFieldWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// Package remoterelations defines the API end point used by the
+// remote relations worker to apply the changes of relations with
+// services in other environments to their counterparts in this one.
+package remoterelations
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/state"
+)
+
+var logger = loggo.GetLogger("juju.apiserver.remoterelations")
+
+func init() {
+	common.RegisterStandardFacade("RemoteRelations", 0, NewRemoteRelationsAPI)
+}
+
+// RemoteRelations defines the methods on the remoterelations API end
+// point.
+type RemoteRelations interface {
+	PublishRelationChanges(args params.RemoteRelationChanges) (params.ErrorResults, error)
+}
+
+// RemoteRelationsAPI implements the RemoteRelations interface and is
+// the concrete implementation of the api end point.
+type RemoteRelationsAPI struct {
+	state *state.State
+}
+
+var _ RemoteRelations = (*RemoteRelationsAPI)(nil)
+
+// NewRemoteRelationsAPI creates a new server-side remoterelations API
+// end point. Only state servers may use it.
+func NewRemoteRelationsAPI(
+	st *state.State,
+	resources *common.Resources,
+	authorizer common.Authorizer,
+) (*RemoteRelationsAPI, error) {
+	if !authorizer.AuthEnvironManager() {
+		return nil, common.ErrPerm
+	}
+	return &RemoteRelationsAPI{state: st}, nil
+}
+
+// PublishRelationChanges applies the changes made in other
+// environments to the relations with their services.
+func (api *RemoteRelationsAPI) PublishRelationChanges(args params.RemoteRelationChanges) (params.ErrorResults, error) {
+	results := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.Changes)),
+	}
+	for i, change := range args.Changes {
+		err := api.publishRelationChange(change)
+		results.Results[i].Error = common.ServerError(err)
+	}
+	return results, nil
+}
+
+func (api *RemoteRelationsAPI) publishRelationChange(change params.RemoteRelationChange) error {
+	rel, err := api.state.KeyRelation(change.RelationKey)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// A relation may only be changed from the environment running its
+	// remote service.
+	if !rel.IsRemote() || rel.RemoteEnvUUID() != change.SourceEnvUUID {
+		return common.ErrPerm
+	}
+	if change.Destroy {
+		logger.Infof("destroying relation %q", rel)
+		if err := rel.Destroy(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	for _, unit := range change.ChangedUnits {
+		logger.Debugf("setting settings of remote unit %q in relation %q", unit.UnitName, rel)
+		err := rel.SetRemoteUnitSettings(unit.UnitName, unit.Settings)
+		if errors.Cause(err) == state.ErrCannotEnterScope {
+			// The relation is going away.
+			continue
+		} else if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	for _, unitName := range change.DepartedUnits {
+		logger.Debugf("removing remote unit %q from relation %q", unitName, rel)
+		if err := rel.RemoveRemoteUnit(unitName); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if change.RemoveAllUnits {
+		if err := rel.RemoveRemoteUnits(); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
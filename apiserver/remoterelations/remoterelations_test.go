@@ -0,0 +1,166 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations_test
+
+import (
+	"sort"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/apiserver/remoterelations"
+	apiservertesting "github.com/juju/juju/apiserver/testing"
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/state"
+	statetesting "github.com/juju/juju/state/testing"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/testing/factory"
+)
+
+type remoteRelationsSuite struct {
+	jujutesting.JujuConnSuite
+
+	otherState *state.State
+	rel        *state.Relation
+	api        *remoterelations.RemoteRelationsAPI
+}
+
+var _ = gc.Suite(&remoteRelationsSuite{})
+
+func (s *remoteRelationsSuite) SetUpTest(c *gc.C) {
+	s.JujuConnSuite.SetUpTest(c)
+	s.otherState = s.Factory.MakeEnvironment(c, &factory.EnvParams{Name: "db"})
+	s.AddCleanup(func(*gc.C) { s.otherState.Close() })
+	otherFactory := factory.NewFactory(s.otherState)
+	otherFactory.MakeService(c, &factory.ServiceParams{
+		Name:  "mysql",
+		Charm: otherFactory.MakeCharm(c, &factory.CharmParams{Name: "mysql"}),
+	})
+	s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	offer, err := s.otherState.AddOffer("mysql", "server")
+	c.Assert(err, jc.ErrorIsNil)
+	s.rel, err = s.State.ConsumeOffer(offer, "wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+
+	auth := apiservertesting.FakeAuthorizer{
+		Tag:            names.NewMachineTag("0"),
+		EnvironManager: true,
+	}
+	s.api, err = remoterelations.NewRemoteRelationsAPI(s.State, common.NewResources(), auth)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *remoteRelationsSuite) TestNewRemoteRelationsAPIRequiresEnvironManager(c *gc.C) {
+	for _, auth := range []apiservertesting.FakeAuthorizer{{
+		Tag: names.NewMachineTag("1"),
+	}, {
+		Tag: names.NewLocalUserTag("admin"),
+	}} {
+		_, err := remoterelations.NewRemoteRelationsAPI(s.State, common.NewResources(), auth)
+		c.Assert(err, gc.Equals, common.ErrPerm)
+	}
+}
+
+func (s *remoteRelationsSuite) publish(c *gc.C, change params.RemoteRelationChange) error {
+	results, err := s.api.PublishRelationChanges(params.RemoteRelationChanges{
+		Changes: []params.RemoteRelationChange{change},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	return results.OneError()
+}
+
+func (s *remoteRelationsSuite) TestPublishUnitChanges(c *gc.C) {
+	err := s.publish(c, params.RemoteRelationChange{
+		RelationKey:   s.rel.String(),
+		SourceEnvUUID: s.otherState.EnvironUUID(),
+		ChangedUnits: []params.RemoteRelationUnit{{
+			UnitName: "mysql/0",
+			Settings: map[string]interface{}{"host": "10.0.0.1"},
+		}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	settings, err := s.rel.UnitSettings("mysql/0")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(settings, jc.DeepEquals, map[string]interface{}{"host": "10.0.0.1"})
+	c.Assert(s.remoteUnitsInScope(c), jc.DeepEquals, []string{"mysql/0"})
+
+	err = s.publish(c, params.RemoteRelationChange{
+		RelationKey:   s.rel.String(),
+		SourceEnvUUID: s.otherState.EnvironUUID(),
+		DepartedUnits: []string{"mysql/0"},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.remoteUnitsInScope(c), gc.HasLen, 0)
+}
+
+// remoteUnitsInScope returns the names of the units of the remote
+// service in the scope of the relation.
+func (s *remoteRelationsSuite) remoteUnitsInScope(c *gc.C) []string {
+	w, err := s.rel.WatchUnits("mysql")
+	c.Assert(err, jc.ErrorIsNil)
+	defer statetesting.AssertStop(c, w)
+	s.State.StartSync()
+	select {
+	case change := <-w.Changes():
+		var unitNames []string
+		for unitName := range change.Changed {
+			unitNames = append(unitNames, unitName)
+		}
+		sort.Strings(unitNames)
+		return unitNames
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for relation units")
+	}
+	panic("unreachable")
+}
+
+func (s *remoteRelationsSuite) TestPublishLocalUnitRejected(c *gc.C) {
+	err := s.publish(c, params.RemoteRelationChange{
+		RelationKey:   s.rel.String(),
+		SourceEnvUUID: s.otherState.EnvironUUID(),
+		ChangedUnits: []params.RemoteRelationUnit{{
+			UnitName: "wordpress/0",
+			Settings: map[string]interface{}{"host": "10.0.0.1"},
+		}},
+	})
+	c.Assert(err, gc.NotNil)
+}
+
+func (s *remoteRelationsSuite) TestPublishFromOtherEnvironmentRejected(c *gc.C) {
+	err := s.publish(c, params.RemoteRelationChange{
+		RelationKey:   s.rel.String(),
+		SourceEnvUUID: s.State.EnvironUUID(),
+		Destroy:       true,
+	})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+	c.Assert(s.rel.Refresh(), jc.ErrorIsNil)
+	c.Assert(s.rel.Life(), gc.Equals, state.Alive)
+}
+
+func (s *remoteRelationsSuite) TestPublishDestroy(c *gc.C) {
+	err := s.publish(c, params.RemoteRelationChange{
+		RelationKey:   s.rel.String(),
+		SourceEnvUUID: s.otherState.EnvironUUID(),
+		Destroy:       true,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.rel.Refresh()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *remoteRelationsSuite) TestPublishRemovedRelation(c *gc.C) {
+	err := s.rel.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.publish(c, params.RemoteRelationChange{
+		RelationKey:   s.rel.String(),
+		SourceEnvUUID: s.otherState.EnvironUUID(),
+		Destroy:       true,
+	})
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
+}
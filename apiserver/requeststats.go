@@ -0,0 +1,120 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package apiserver
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/rpc"
+)
+
+// requestTimeBuckets holds the upper bounds of the histogram buckets
+// into which the times taken to serve requests are counted.
+var requestTimeBuckets = []time.Duration{
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+}
+
+type requestKey struct {
+	facade  string
+	version int
+	method  string
+}
+
+// methodTimes holds the aggregated times for a single API method.
+type methodTimes struct {
+	count  int64
+	errors int64
+	total  time.Duration
+	max    time.Duration
+	// histogram has an entry for each of requestTimeBuckets, and a
+	// final entry for requests slower than all of them.
+	histogram []int64
+}
+
+// requestStats aggregates the time taken to serve API requests by
+// facade, version and method. It is shared between all the
+// connections to a Server and is safe for concurrent use.
+type requestStats struct {
+	mu      sync.Mutex
+	methods map[requestKey]*methodTimes
+}
+
+func newRequestStats() *requestStats {
+	return &requestStats{
+		methods: make(map[requestKey]*methodTimes),
+	}
+}
+
+// record adds a request which took timeSpent to the statistics.
+func (s *requestStats) record(req rpc.Request, failed bool, timeSpent time.Duration) {
+	key := requestKey{req.Type, req.Version, req.Action}
+	bucket := sort.Search(len(requestTimeBuckets), func(i int) bool {
+		return timeSpent <= requestTimeBuckets[i]
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	times, ok := s.methods[key]
+	if !ok {
+		times = &methodTimes{
+			histogram: make([]int64, len(requestTimeBuckets)+1),
+		}
+		s.methods[key] = times
+	}
+	times.count++
+	if failed {
+		times.errors++
+	}
+	times.total += timeSpent
+	if timeSpent > times.max {
+		times.max = timeSpent
+	}
+	times.histogram[bucket]++
+}
+
+// timings returns the statistics recorded so far, ordered by facade,
+// version and method.
+func (s *requestStats) timings() params.RequestTimings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := params.RequestTimings{
+		Buckets: append([]time.Duration(nil), requestTimeBuckets...),
+		Methods: make([]params.RequestTiming, 0, len(s.methods)),
+	}
+	for key, times := range s.methods {
+		result.Methods = append(result.Methods, params.RequestTiming{
+			Facade:    key.facade,
+			Version:   key.version,
+			Method:    key.method,
+			Count:     times.count,
+			Errors:    times.errors,
+			Total:     times.total,
+			Max:       times.max,
+			Histogram: append([]int64(nil), times.histogram...),
+		})
+	}
+	sort.Sort(requestTimingsByName(result.Methods))
+	return result
+}
+
+type requestTimingsByName []params.RequestTiming
+
+func (t requestTimingsByName) Len() int      { return len(t) }
+func (t requestTimingsByName) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t requestTimingsByName) Less(i, j int) bool {
+	if t[i].Facade != t[j].Facade {
+		return t[i].Facade < t[j].Facade
+	}
+	if t[i].Version != t[j].Version {
+		return t[i].Version < t[j].Version
+	}
+	return t[i].Method < t[j].Method
+}
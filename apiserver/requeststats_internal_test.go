@@ -0,0 +1,78 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// This is an internal package test.
+
+package apiserver
+
+import (
+	"time"
+
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/rpc"
+	"github.com/juju/juju/testing"
+)
+
+type requestStatsSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&requestStatsSuite{})
+
+func (s *requestStatsSuite) TestRecord(c *gc.C) {
+	stats := newRequestStats()
+	status := rpc.Request{Type: "Client", Version: 0, Action: "FullStatus"}
+	login := rpc.Request{Type: "Admin", Version: 1, Action: "Login"}
+	stats.record(status, false, 5*time.Millisecond)
+	stats.record(status, true, 2*time.Second)
+	stats.record(login, false, time.Minute)
+
+	c.Assert(stats.timings(), gc.DeepEquals, params.RequestTimings{
+		Buckets: requestTimeBuckets,
+		Methods: []params.RequestTiming{{
+			Facade:    "Admin",
+			Version:   1,
+			Method:    "Login",
+			Count:     1,
+			Total:     time.Minute,
+			Max:       time.Minute,
+			Histogram: []int64{0, 0, 0, 0, 0, 1},
+		}, {
+			Facade:    "Client",
+			Version:   0,
+			Method:    "FullStatus",
+			Count:     2,
+			Errors:    1,
+			Total:     2*time.Second + 5*time.Millisecond,
+			Max:       2 * time.Second,
+			Histogram: []int64{0, 1, 0, 0, 1, 0},
+		}},
+	})
+}
+
+func (s *requestStatsSuite) TestRedactParams(c *gc.C) {
+	args := params.LoginRequest{
+		AuthTag:     "user-bob",
+		Credentials: "sekrit",
+		Nonce:       "fake-nonce",
+	}
+	c.Assert(redactParams(args), gc.DeepEquals, map[string]interface{}{
+		"auth-tag":    "user-bob",
+		"credentials": redacted,
+		"nonce":       redacted,
+	})
+
+	nested := params.EnvironmentSet{Config: map[string]interface{}{
+		"admin-secret":   "sekrit",
+		"default-series": "trusty",
+	}}
+	c.Assert(redactParams(nested), gc.DeepEquals, map[string]interface{}{
+		"Config": map[string]interface{}{
+			"admin-secret":   redacted,
+			"default-series": "trusty",
+		},
+	})
+	c.Assert(redactParams(nil), gc.IsNil)
+}
@@ -4,11 +4,14 @@
 package apiserver
 
 import (
+	"sync"
+
 	"github.com/juju/errors"
 	"github.com/juju/utils/set"
 
 	"github.com/juju/juju/rpc"
 	"github.com/juju/juju/rpc/rpcreflect"
+	"github.com/juju/juju/state"
 )
 
 var aboutToRestoreError = errors.New("juju restore is in progress - Juju functionality is limited to avoid data loss")
@@ -82,3 +85,85 @@ func (r *restoreInProgressRoot) FindMethod(rootName string, version int, methodN
 	}
 	return nil, restoreInProgressError
 }
+
+// restoreGuard tracks the restore status of the environment, so that
+// the connections logged in before a restore started are limited as
+// the ones logged in during it are, and stop making changes.
+type restoreGuard struct {
+	st *state.State
+
+	mu     sync.Mutex
+	status state.RestoreStatus
+}
+
+// newRestoreGuard returns a restoreGuard for the environment of st.
+func newRestoreGuard(st *state.State) *restoreGuard {
+	return &restoreGuard{st: st}
+}
+
+// run updates the restore status as it changes until the stop channel
+// is closed.
+func (g *restoreGuard) run(stop <-chan struct{}) error {
+	w := g.st.WatchRestoreInfoChanges()
+	defer w.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case _, ok := <-w.Changes():
+			if !ok {
+				return errors.Annotate(w.Err(), "restore info watcher failed")
+			}
+			info, err := g.st.EnsureRestoreInfo()
+			if err != nil {
+				return errors.Trace(err)
+			}
+			g.mu.Lock()
+			g.status = info.Status()
+			g.mu.Unlock()
+		}
+	}
+}
+
+// restoreStatus returns the last known restore status.
+func (g *restoreGuard) restoreStatus() state.RestoreStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.status
+}
+
+// restoreGuardedRoot limits the API calls of a logged in connection
+// while a restore is pending or in progress.
+type restoreGuardedRoot struct {
+	rpc.MethodFinder
+	guard *restoreGuard
+}
+
+// newRestoreGuardedRoot returns a root that limits the calls found by
+// finder as aboutToRestoreRoot or restoreInProgressRoot do while the
+// guard reports a restore.
+func newRestoreGuardedRoot(finder rpc.MethodFinder, guard *restoreGuard) *restoreGuardedRoot {
+	return &restoreGuardedRoot{
+		MethodFinder: finder,
+		guard:        guard,
+	}
+}
+
+// FindMethod extended srvRoot.FindMethod. It returns the errors of
+// aboutToRestoreRoot and restoreInProgressRoot according to the
+// current restore status.
+func (r *restoreGuardedRoot) FindMethod(rootName string, version int, methodName string) (rpcreflect.MethodCaller, error) {
+	caller, err := r.MethodFinder.FindMethod(rootName, version, methodName)
+	if err != nil {
+		return nil, err
+	}
+	switch r.guard.restoreStatus() {
+	case state.RestorePending:
+		if !isMethodAllowedAboutToRestore(rootName, methodName) {
+			return nil, aboutToRestoreError
+		}
+	case state.RestoreInProgress:
+		return nil, restoreInProgressError
+	}
+	return caller, nil
+}
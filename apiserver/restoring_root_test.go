@@ -9,6 +9,7 @@ import (
 	gc "gopkg.in/check.v1"
 
 	"github.com/juju/juju/apiserver"
+	"github.com/juju/juju/state"
 	"github.com/juju/juju/testing"
 )
 
@@ -56,3 +57,23 @@ func (r *restoreRootSuite) TestFindDisallowedMethodWhenRestoring(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, "juju restore is in progress - Juju api is off to prevent data loss")
 	c.Assert(caller, gc.IsNil)
 }
+
+func (r *restoreRootSuite) TestRestoreGuardedRoot(c *gc.C) {
+	root := apiserver.TestingRestoreGuardedRoot(nil, state.UnknownRestoreStatus)
+	caller, err := root.FindMethod("Client", 0, "ServiceDeploy")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(caller, gc.NotNil)
+
+	root = apiserver.TestingRestoreGuardedRoot(nil, state.RestorePending)
+	caller, err = root.FindMethod("Client", 0, "FullStatus")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(caller, gc.NotNil)
+	caller, err = root.FindMethod("Client", 0, "ServiceDeploy")
+	c.Assert(err, gc.ErrorMatches, "juju restore is in progress - Juju functionality is limited to avoid data loss")
+	c.Assert(caller, gc.IsNil)
+
+	root = apiserver.TestingRestoreGuardedRoot(nil, state.RestoreInProgress)
+	caller, err = root.FindMethod("Client", 0, "FullStatus")
+	c.Assert(err, gc.ErrorMatches, "juju restore is in progress - Juju api is off to prevent data loss")
+	c.Assert(caller, gc.IsNil)
+}
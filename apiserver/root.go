@@ -46,14 +46,18 @@ type apiHandler struct {
 	rpcConn   *rpc.Conn
 	resources *common.Resources
 	entity    state.Entity
+
+	// access holds the environment access of a logged in user.
+	// It is empty for agents.
+	access state.Access
 }
 
 var _ = (*apiHandler)(nil)
 
-// newApiHandler returns a new apiHandler.
-func newApiHandler(srv *Server, rpcConn *rpc.Conn, reqNotifier *requestNotifier) (*apiHandler, error) {
+// newApiHandler returns a new apiHandler serving the environment of st.
+func newApiHandler(srv *Server, st *state.State, rpcConn *rpc.Conn, reqNotifier *requestNotifier) (*apiHandler, error) {
 	r := &apiHandler{
-		state:     srv.state,
+		state:     st,
 		resources: common.NewResources(),
 		rpcConn:   rpcConn,
 	}
@@ -66,6 +70,11 @@ func newApiHandler(srv *Server, rpcConn *rpc.Conn, reqNotifier *requestNotifier)
 	if err := r.resources.RegisterNamed("logDir", common.StringResource(srv.logDir)); err != nil {
 		return nil, err
 	}
+	if srv.admission != nil {
+		if err := r.resources.RegisterNamed("apiServerLoad", common.LoadResource(srv.admission.load)); err != nil {
+			return nil, err
+		}
+	}
 	return r, nil
 }
 
@@ -91,8 +100,13 @@ type srvCaller struct {
 	objMethod rpcreflect.ObjMethod
 	goType    reflect.Type
 	creator   func(id string) (reflect.Value, error)
+	// traceRequest records the trace id of a request with the
+	// state while the request is served.
+	traceRequest func(traceId string) (done func())
 }
 
+var _ rpc.TracedMethodCaller = (*srvCaller)(nil)
+
 // ParamsType defines the parameters that should be supplied to this function.
 // See rpcreflect.MethodCaller for more detail.
 func (s *srvCaller) ParamsType() reflect.Type {
@@ -115,6 +129,22 @@ func (s *srvCaller) Call(objId string, arg reflect.Value) (reflect.Value, error)
 	return s.objMethod.Call(objVal, arg)
 }
 
+// CallTraced is like Call, but records the given trace id with the
+// state while the call is made, so that the state operations done on
+// the request's behalf are logged with it. See rpc.TracedMethodCaller.
+func (s *srvCaller) CallTraced(traceId, objId string, arg reflect.Value) (reflect.Value, error) {
+	if traceId == "" || s.traceRequest == nil {
+		return s.Call(objId, arg)
+	}
+	objVal, err := s.creator(objId)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	done := s.traceRequest(traceId)
+	defer done()
+	return s.objMethod.Call(objVal, arg)
+}
+
 // apiRoot implements basic method dispatching to the facade registry.
 type apiRoot struct {
 	state       *state.State
@@ -124,10 +154,10 @@ type apiRoot struct {
 	objectCache map[objectKey]reflect.Value
 }
 
-// newApiRoot returns a new apiRoot.
-func newApiRoot(srv *Server, resources *common.Resources, authorizer common.Authorizer) *apiRoot {
+// newApiRoot returns a new apiRoot serving the environment of st.
+func newApiRoot(st *state.State, resources *common.Resources, authorizer common.Authorizer) *apiRoot {
 	r := &apiRoot{
-		state:       srv.state,
+		state:       st,
 		resources:   resources,
 		authorizer:  authorizer,
 		objectCache: make(map[objectKey]reflect.Value),
@@ -166,40 +196,54 @@ func (r *apiRoot) FindMethod(rootName string, version int, methodName string) (r
 		}
 		// Now that we have the write lock, check one more time in case
 		// someone got the write lock before us.
-		factory, err := common.Facades.GetFactory(rootName, version)
+		objValue, err := r.newFacade(goType, rootName, version, id)
 		if err != nil {
-			// We don't check for IsNotFound here, because it
-			// should have already been handled in the GetType
-			// check.
 			return reflect.Value{}, err
 		}
-		obj, err := factory(r.state, r.resources, r.authorizer, id)
-		if err != nil {
-			return reflect.Value{}, err
-		}
-		objValue = reflect.ValueOf(obj)
-		if !objValue.Type().AssignableTo(goType) {
-			return reflect.Value{}, errors.Errorf(
-				"internal error, %s(%d) claimed to return %s but returned %T",
-				rootName, version, goType, obj)
-		}
-		if goType.Kind() == reflect.Interface {
-			// If the original function wanted to return an
-			// interface type, the indirection in the factory via
-			// an interface{} strips the original interface
-			// information off. So here we have to create the
-			// interface again, and assign it.
-			asInterface := reflect.New(goType).Elem()
-			asInterface.Set(objValue)
-			objValue = asInterface
-		}
 		r.objectCache[objKey] = objValue
 		return objValue, nil
 	}
-	return &srvCaller{
+	caller := &srvCaller{
 		creator:   creator,
 		objMethod: objMethod,
-	}, nil
+	}
+	if r.state != nil {
+		caller.traceRequest = r.state.TraceRequest
+	}
+	return caller, nil
+}
+
+// newFacade creates the facade with the given name and version for the
+// object with the given id.
+func (r *apiRoot) newFacade(goType reflect.Type, rootName string, version int, id string) (reflect.Value, error) {
+	factory, err := common.Facades.GetFactory(rootName, version)
+	if err != nil {
+		// We don't check for IsNotFound here, because it
+		// should have already been handled in the GetType
+		// check.
+		return reflect.Value{}, err
+	}
+	obj, err := factory(r.state, r.resources, r.authorizer, id)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	objValue := reflect.ValueOf(obj)
+	if !objValue.Type().AssignableTo(goType) {
+		return reflect.Value{}, errors.Errorf(
+			"internal error, %s(%d) claimed to return %s but returned %T",
+			rootName, version, goType, obj)
+	}
+	if goType.Kind() == reflect.Interface {
+		// If the original function wanted to return an
+		// interface type, the indirection in the factory via
+		// an interface{} strips the original interface
+		// information off. So here we have to create the
+		// interface again, and assign it.
+		asInterface := reflect.New(goType).Elem()
+		asInterface.Set(objValue)
+		objValue = asInterface
+	}
+	return objValue, nil
 }
 
 func (r *apiRoot) lookupMethod(rootName string, version int, methodName string) (reflect.Type, rpcreflect.ObjMethod, error) {
@@ -293,6 +337,13 @@ func (r *apiHandler) AuthClient() bool {
 	return isUser
 }
 
+// AuthEnvironAccess returns whether the authenticated entity is a
+// client user with at least the given level of access to the
+// environment.
+func (r *apiHandler) AuthEnvironAccess(access state.Access) bool {
+	return r.AuthClient() && r.access.Includes(access)
+}
+
 // GetAuthTag returns the tag of the authenticated entity.
 func (r *apiHandler) GetAuthTag() names.Tag {
 	return r.entity.Tag()
@@ -15,6 +15,7 @@ import (
 
 	"github.com/juju/juju/apiserver"
 	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/rpc"
 	"github.com/juju/juju/rpc/rpcreflect"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/testing"
@@ -282,6 +283,32 @@ func (r *rootSuite) TestFindMethodCachesFacadesWithId(c *gc.C) {
 	assertCallResult(c, caller, "third-id", "ALT-third-id3")
 }
 
+func (r *rootSuite) TestFindMethodTracedCallsUseCachedFacade(c *gc.C) {
+	srvRoot := apiserver.TestingApiRoot(&state.State{})
+	defer common.Facades.Discard("my-counting-facade", 0)
+	var count int64
+	newCounter := func(
+		*state.State, *common.Resources, common.Authorizer, string,
+	) (interface{}, error) {
+		count += 1
+		return &countingType{count: count, id: ""}, nil
+	}
+	reflectType := reflect.TypeOf((*countingType)(nil))
+	common.RegisterFacade("my-counting-facade", 0, newCounter, reflectType)
+	caller, err := srvRoot.FindMethod("my-counting-facade", 0, "Count")
+	c.Assert(err, jc.ErrorIsNil)
+	traced, ok := caller.(rpc.TracedMethodCaller)
+	c.Assert(ok, jc.IsTrue)
+	// Traced and untraced calls share the same cached facade.
+	v, err := traced.CallTraced("trace-a", "", reflect.Value{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(v.Interface(), gc.Equals, stringVar{"1"})
+	v, err = traced.CallTraced("trace-b", "", reflect.Value{})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(v.Interface(), gc.Equals, stringVar{"1"})
+	assertCallResult(c, caller, "", "1")
+}
+
 func (r *rootSuite) TestFindMethodCacheRaceSafe(c *gc.C) {
 	srvRoot := apiserver.TestingApiRoot(nil)
 	defer common.Facades.Discard("my-counting-facade", 0)
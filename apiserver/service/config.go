@@ -0,0 +1,150 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package service
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/juju/errors"
+	"gopkg.in/juju/charm.v4"
+	goyaml "gopkg.in/yaml.v1"
+
+	"github.com/juju/juju/apiserver/params"
+)
+
+// ValidateConfig checks changes to a service's configuration against the
+// options of its charm, without applying them. Every unknown option and
+// invalid value is reported; if there are none, the changes to the
+// effective value of each setting, defaults included, are returned.
+func (api *API) ValidateConfig(args params.ServiceValidateConfig) (params.ServiceValidateConfigResult, error) {
+	var result params.ServiceValidateConfigResult
+	if args.Config != "" && len(args.Options) > 0 {
+		return result, errors.New("cannot validate both options and YAML config")
+	}
+	service, err := api.state.Service(args.ServiceName)
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	ch, _, err := service.Charm()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	config := ch.Config()
+
+	var changes charm.Settings
+	if args.Config != "" {
+		changes, result.Errors, err = validateSettingsYAML(config, service.Name(), args.Config)
+		if err != nil {
+			return result, errors.Trace(err)
+		}
+	} else {
+		changes, result.Errors = validateSettingsStrings(config, args.Options)
+	}
+	if len(result.Errors) > 0 {
+		return result, nil
+	}
+	current, err := service.ConfigSettings()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	result.Changes = configDiff(config, current, changes)
+	return result, nil
+}
+
+// validateSettingsStrings parses each of the given settings as
+// ParseSettingsStrings does, returning the valid values and a message
+// for each invalid one.
+func validateSettingsStrings(config *charm.Config, settings map[string]string) (charm.Settings, []string) {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	changes := make(charm.Settings)
+	var errs []string
+	for _, name := range names {
+		parsed, err := config.ParseSettingsStrings(map[string]string{name: settings[name]})
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		changes[name] = parsed[name]
+	}
+	return changes, errs
+}
+
+// validateSettingsYAML parses each of the settings found under the given
+// key of the YAML data as ParseSettingsYAML does, returning the valid
+// values and a message for each invalid one. An error is returned if the
+// data itself cannot be parsed.
+func validateSettingsYAML(config *charm.Config, key, yamlData string) (charm.Settings, []string, error) {
+	var allSettings map[string]map[string]interface{}
+	if err := goyaml.Unmarshal([]byte(yamlData), &allSettings); err != nil {
+		return nil, nil, errors.Errorf("cannot parse settings data: %v", err)
+	}
+	settings, ok := allSettings[key]
+	if !ok {
+		return nil, nil, errors.Errorf("no settings found for %q", key)
+	}
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	changes := make(charm.Settings)
+	var errs []string
+	for _, name := range names {
+		var parsed charm.Settings
+		var err error
+		// String values are parsed according to the option type, for
+		// compatibility with python.
+		if str, ok := settings[name].(string); ok {
+			parsed, err = config.ParseSettingsStrings(map[string]string{name: str})
+		} else {
+			parsed, err = config.ValidateSettings(charm.Settings{name: settings[name]})
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		changes[name] = parsed[name]
+	}
+	return changes, errs, nil
+}
+
+// configDiff returns the changes to the effective value of each setting
+// changed, where a setting that is not set takes the default of its
+// option.
+func configDiff(config *charm.Config, current, changes charm.Settings) []params.ServiceConfigDiff {
+	names := make([]string, 0, len(changes))
+	for name := range changes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var diffs []params.ServiceConfigDiff
+	for _, name := range names {
+		diff := params.ServiceConfigDiff{Key: name}
+		diff.OldValue, diff.OldDefault = effectiveValue(config, current, name)
+		diff.NewValue, diff.NewDefault = effectiveValue(config, changes, name)
+		if reflect.DeepEqual(diff.OldValue, diff.NewValue) {
+			continue
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+// effectiveValue returns the value of the named setting, or the default
+// of its option if it is not set, in which case the returned bool is
+// true.
+func effectiveValue(config *charm.Config, settings charm.Settings, name string) (interface{}, bool) {
+	if value := settings[name]; value != nil {
+		return value, false
+	}
+	return config.Options[name].Default, true
+}
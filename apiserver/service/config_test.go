@@ -0,0 +1,101 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package service_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/charm.v4"
+
+	"github.com/juju/juju/apiserver/params"
+)
+
+func (s *serviceSuite) addDummy(c *gc.C) {
+	dummy := s.AddTestingService(c, "dummy", s.AddTestingCharm(c, "dummy"))
+	err := dummy.UpdateConfigSettings(charm.Settings{"title": "Nearly There", "skill-level": int64(5)})
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *serviceSuite) TestValidateConfigOptions(c *gc.C) {
+	s.addDummy(c)
+	result, err := s.serviceApi.ValidateConfig(params.ServiceValidateConfig{
+		ServiceName: "dummy",
+		Options: map[string]string{
+			"title":    "There",
+			"username": "bob",
+			"outlook":  "",
+		},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Errors, gc.HasLen, 0)
+	c.Assert(result.Changes, jc.DeepEquals, []params.ServiceConfigDiff{{
+		Key:        "outlook",
+		OldValue:   nil,
+		OldDefault: true,
+		NewValue:   "",
+	}, {
+		Key:      "title",
+		OldValue: "Nearly There",
+		NewValue: "There",
+	}, {
+		Key:        "username",
+		OldValue:   "admin001",
+		OldDefault: true,
+		NewValue:   "bob",
+	}})
+
+	// Nothing was changed.
+	dummy, err := s.State.Service("dummy")
+	c.Assert(err, jc.ErrorIsNil)
+	settings, err := dummy.ConfigSettings()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(settings, jc.DeepEquals, charm.Settings{"title": "Nearly There", "skill-level": int64(5)})
+}
+
+func (s *serviceSuite) TestValidateConfigReportsAllErrors(c *gc.C) {
+	s.addDummy(c)
+	result, err := s.serviceApi.ValidateConfig(params.ServiceValidateConfig{
+		ServiceName: "dummy",
+		Options: map[string]string{
+			"title":       "There",
+			"skill-level": "lots",
+			"colour":      "blue",
+		},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Changes, gc.HasLen, 0)
+	c.Assert(result.Errors, gc.HasLen, 2)
+	c.Check(result.Errors[0], gc.Matches, `.*unknown option "colour".*`)
+	c.Check(result.Errors[1], gc.Matches, `.*option "skill-level" expected int, got "lots".*`)
+}
+
+func (s *serviceSuite) TestValidateConfigYAML(c *gc.C) {
+	s.addDummy(c)
+	result, err := s.serviceApi.ValidateConfig(params.ServiceValidateConfig{
+		ServiceName: "dummy",
+		Config:      "dummy:\n  skill-level: 7\n  title: Nearly There\n  outlook: [sunny]\n",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Errors, gc.HasLen, 1)
+	c.Check(result.Errors[0], gc.Matches, `option "outlook" expected string, got .*`)
+
+	result, err = s.serviceApi.ValidateConfig(params.ServiceValidateConfig{
+		ServiceName: "dummy",
+		Config:      "dummy:\n  skill-level: ~\n  title: Nearly There\n",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Errors, gc.HasLen, 0)
+	c.Assert(result.Changes, jc.DeepEquals, []params.ServiceConfigDiff{{
+		Key:        "skill-level",
+		OldValue:   int64(5),
+		NewValue:   nil,
+		NewDefault: true,
+	}})
+
+	_, err = s.serviceApi.ValidateConfig(params.ServiceValidateConfig{
+		ServiceName: "dummy",
+		Config:      "wordpress:\n  title: There\n",
+	})
+	c.Assert(err, gc.ErrorMatches, `no settings found for "dummy"`)
+}
@@ -5,10 +5,15 @@
 package service
 
 import (
+	"github.com/juju/errors"
 	"github.com/juju/loggo"
+	"github.com/juju/names"
 
 	"github.com/juju/juju/apiserver/common"
 	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/audit"
+	"github.com/juju/juju/leadership"
+	"github.com/juju/juju/lease"
 	"github.com/juju/juju/state"
 )
 
@@ -21,6 +26,10 @@ func init() {
 // Service defines the methods on the service API end point.
 type Service interface {
 	SetMetricCredentials(args params.ServiceMetricCredentials) (params.ErrorResults, error)
+	RevokeLeadership(args params.Entities) (params.StringResults, error)
+	ValidateConfig(args params.ServiceValidateConfig) (params.ServiceValidateConfigResult, error)
+	PlacementPolicies(args params.Entities) (params.PlacementPoliciesResults, error)
+	SetPlacementPolicies(args params.ServicesPlacementPolicies) (params.ErrorResults, error)
 }
 
 // API implements the service interface and is the concrete
@@ -67,3 +76,126 @@ func (api *API) SetMetricCredentials(args params.ServiceMetricCredentials) (para
 	}
 	return result, nil
 }
+
+// PlacementPolicies returns the placement policies of each of the
+// given services.
+func (api *API) PlacementPolicies(args params.Entities) (params.PlacementPoliciesResults, error) {
+	result := params.PlacementPoliciesResults{
+		Results: make([]params.PlacementPoliciesResult, len(args.Entities)),
+	}
+	for i, entity := range args.Entities {
+		serviceTag, err := names.ParseServiceTag(entity.Tag)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(common.ErrPerm)
+			continue
+		}
+		service, err := api.state.Service(serviceTag.Id())
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		for _, p := range service.PlacementPolicies() {
+			result.Results[i].Policies = append(result.Results[i].Policies, params.PlacementPolicy{
+				Kind:     string(p.Kind),
+				Service:  p.Service,
+				MaxUnits: p.MaxUnits,
+			})
+		}
+	}
+	return result, nil
+}
+
+// SetPlacementPolicies replaces the placement policies of each of the
+// given services.
+func (api *API) SetPlacementPolicies(args params.ServicesPlacementPolicies) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.Args)),
+	}
+	for i, arg := range args.Args {
+		service, err := api.state.Service(arg.ServiceName)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		policies := make([]state.PlacementPolicy, len(arg.Policies))
+		for j, p := range arg.Policies {
+			policies[j] = state.PlacementPolicy{
+				Kind:     state.PlacementPolicyKind(p.Kind),
+				Service:  p.Service,
+				MaxUnits: p.MaxUnits,
+			}
+		}
+		if err := service.SetPlacementPolicies(policies); err != nil {
+			result.Results[i].Error = common.ServerError(err)
+		}
+	}
+	return result, nil
+}
+
+// RevokeLeadership revokes the leadership of each of the given
+// services, so that another of their units may claim it. The result
+// for each service holds the name of the unit which was its leader.
+// Only the environment administrator may revoke leadership.
+func (api *API) RevokeLeadership(args params.Entities) (params.StringResults, error) {
+	result := params.StringResults{
+		Results: make([]params.StringResult, len(args.Entities)),
+	}
+	if len(args.Entities) == 0 {
+		return result, nil
+	}
+	if err := api.adminCheck(); err != nil {
+		return result, errors.Trace(err)
+	}
+	for i, entity := range args.Entities {
+		unitName, err := api.revokeLeadership(entity.Tag)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		result.Results[i].Result = unitName
+	}
+	return result, nil
+}
+
+func (api *API) revokeLeadership(tag string) (string, error) {
+	serviceTag, err := names.ParseServiceTag(tag)
+	if err != nil {
+		return "", common.ErrPerm
+	}
+	if _, err := api.state.Service(serviceTag.Id()); err != nil {
+		return "", errors.Trace(err)
+	}
+	clock := lease.Manager().Clock()
+	unitName, err := leadership.RevokeLeadership(api.state, clock, serviceTag.Id())
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	audit.Audit(auditUser{api.authorizer.GetAuthTag()},
+		"revoked leadership of service %q from unit %q", serviceTag.Id(), unitName)
+	return unitName, nil
+}
+
+// adminCheck returns an error unless the authenticated user is the
+// owner of the state server environment.
+func (api *API) adminCheck() error {
+	// TODO PERMISSIONS: until there are real permissions, only the
+	// owner of the initial environment is an administrator.
+	initialEnv, err := api.state.StateServerEnvironment()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if api.authorizer.GetAuthTag() != initialEnv.Owner() {
+		return common.ErrPerm
+	}
+	return nil
+}
+
+// auditUser adapts a names.Tag to audit.Tagger.
+type auditUser struct {
+	tag names.Tag
+}
+
+// Tag implements audit.Tagger.
+func (u auditUser) Tag() string {
+	return u.tag.String()
+}
@@ -4,6 +4,8 @@
 package service_test
 
 import (
+	"time"
+
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
 
@@ -11,6 +13,8 @@ import (
 	"github.com/juju/juju/apiserver/service"
 	apiservertesting "github.com/juju/juju/apiserver/testing"
 	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/leadership"
+	"github.com/juju/juju/lease"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/testing/factory"
 )
@@ -88,7 +92,7 @@ func (s *serviceSuite) TestSetMetricCredentials(c *gc.C) {
 			}},
 			params.ErrorResults{[]params.ErrorResult{
 				{Error: nil},
-				{Error: &params.Error{`service "not-a-service" not found`, "not found"}},
+				{Error: &params.Error{Message: `service "not-a-service" not found`, Code: "not found"}},
 			}},
 		},
 	}
@@ -109,3 +113,98 @@ func (s *serviceSuite) TestSetMetricCredentials(c *gc.C) {
 		}
 	}
 }
+
+func (s *serviceSuite) claimLeadership(c *gc.C, unit *state.Unit) {
+	_, err := s.State.ClaimLease(lease.Token{
+		Namespace:  leadership.LeadershipNamespace(unit.ServiceName()),
+		Id:         unit.Name(),
+		Expiration: time.Now().Add(time.Minute),
+	}, time.Now())
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *serviceSuite) TestRevokeLeadership(c *gc.C) {
+	unit := s.Factory.MakeUnit(c, &factory.UnitParams{Service: s.service})
+	s.claimLeadership(c, unit)
+
+	results, err := s.serviceApi.RevokeLeadership(params.Entities{[]params.Entity{
+		{s.service.Tag().String()},
+		{"service-not-a-service"},
+		{"unit-" + unit.Tag().Id()},
+	}})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.DeepEquals, params.StringResults{[]params.StringResult{
+		{Result: unit.Name()},
+		{Error: &params.Error{Message: `service "not-a-service" not found`, Code: "not found"}},
+		{Error: &params.Error{Message: "permission denied", Code: "unauthorized access"}},
+	}})
+
+	leaders, err := leadership.Leaders(s.State)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(leaders, gc.HasLen, 0)
+}
+
+func (s *serviceSuite) TestRevokeLeadershipNoLeader(c *gc.C) {
+	results, err := s.serviceApi.RevokeLeadership(params.Entities{[]params.Entity{
+		{s.service.Tag().String()},
+	}})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 1)
+	c.Assert(results.Results[0].Error, gc.ErrorMatches, `leader for service ".*" not found`)
+}
+
+func (s *serviceSuite) TestRevokeLeadershipRequiresAdmin(c *gc.C) {
+	unit := s.Factory.MakeUnit(c, &factory.UnitParams{Service: s.service})
+	s.claimLeadership(c, unit)
+
+	user := s.Factory.MakeUser(c, nil)
+	api, err := service.NewAPI(s.State, nil, apiservertesting.FakeAuthorizer{Tag: user.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = api.RevokeLeadership(params.Entities{[]params.Entity{
+		{s.service.Tag().String()},
+	}})
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+
+	leaders, err := leadership.Leaders(s.State)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(leaders, gc.DeepEquals, map[string]string{s.service.Name(): unit.Name()})
+}
+
+func (s *serviceSuite) TestPlacementPolicies(c *gc.C) {
+	results, err := s.serviceApi.SetPlacementPolicies(params.ServicesPlacementPolicies{
+		Args: []params.ServicePlacementPolicies{{
+			ServiceName: s.service.Name(),
+			Policies: []params.PlacementPolicy{
+				{Kind: "affinity", Service: "wordpress"},
+				{Kind: "max-units-per-zone", MaxUnits: 1},
+			},
+		}, {
+			ServiceName: s.service.Name(),
+			Policies:    []params.PlacementPolicy{{Kind: "max-units-per-zone"}},
+		}, {
+			ServiceName: "missing",
+		}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 3)
+	c.Assert(results.Results[0].Error, gc.IsNil)
+	c.Assert(results.Results[1].Error, gc.ErrorMatches, `cannot set placement policies of service ".*": max-units-per-zone policy with limit 0 not valid`)
+	c.Assert(results.Results[2].Error, gc.ErrorMatches, `service "missing" not found`)
+
+	policies, err := s.serviceApi.PlacementPolicies(params.Entities{[]params.Entity{
+		{s.service.Tag().String()},
+		{"unit-mysql-0"},
+	}})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(policies, jc.DeepEquals, params.PlacementPoliciesResults{
+		Results: []params.PlacementPoliciesResult{{
+			Policies: []params.PlacementPolicy{
+				{Kind: "affinity", Service: "wordpress"},
+				{Kind: "max-units-per-zone", MaxUnits: 1},
+			},
+		}, {
+			Error: &params.Error{Message: "permission denied", Code: params.CodeUnauthorized},
+		}},
+	})
+}
@@ -0,0 +1,112 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package apiserver
+
+import (
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/juju/names"
+
+	"github.com/juju/juju/rpc"
+	"github.com/juju/juju/state"
+)
+
+// sessionRegistry records the connections of logged in users in state,
+// so that they can be listed and closed through any of the API
+// servers, and closes the connections to this API server which are
+// asked to close.
+type sessionRegistry struct {
+	st     *state.State
+	server string
+
+	mu    sync.Mutex
+	conns map[int64]*rpc.Conn
+}
+
+func newSessionRegistry(st *state.State, server string) *sessionRegistry {
+	return &sessionRegistry{
+		st:     st,
+		server: server,
+		conns:  make(map[int64]*rpc.Conn),
+	}
+}
+
+// userSession holds a logged in user connection. It is registered as a
+// resource of the connection, so that the session is forgotten when
+// the connection is closed.
+type userSession struct {
+	registry *sessionRegistry
+	session  *state.UserSession
+}
+
+// add records a new session of the user logged in to the environment
+// of the given state, and returns it.
+func (r *sessionRegistry) add(st *state.State, id int64, user names.UserTag, remoteAddr string, conn *rpc.Conn) (*userSession, error) {
+	session, err := st.AddUserSession(r.server, id, user, remoteAddr)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.conns[id] = conn
+	return &userSession{registry: r, session: session}, nil
+}
+
+// Stop implements common.Resource by forgetting the session.
+func (s *userSession) Stop() error {
+	s.registry.mu.Lock()
+	delete(s.registry.conns, s.session.ConnectionId())
+	s.registry.mu.Unlock()
+	return s.session.Remove()
+}
+
+// run forgets the sessions left behind by a previous run of the API
+// server, then closes the connections which are asked to close until
+// the stop channel is closed.
+func (r *sessionRegistry) run(stop <-chan struct{}) error {
+	if err := r.st.RemoveServerUserSessions(r.server); err != nil {
+		return errors.Trace(err)
+	}
+	w := r.st.WatchUserSessions(r.server)
+	defer w.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case _, ok := <-w.Changes():
+			if !ok {
+				return errors.Annotate(w.Err(), "user sessions watcher failed")
+			}
+			if err := r.closeRequested(); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+}
+
+// closeRequested closes the connections to this API server which are
+// asked to close. The connections are closed asynchronously, because
+// closing a connection waits for its outstanding requests to finish.
+func (r *sessionRegistry) closeRequested() error {
+	sessions, err := r.st.ServerUserSessions(r.server)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range sessions {
+		if !s.Closing() {
+			continue
+		}
+		conn, ok := r.conns[s.ConnectionId()]
+		if !ok {
+			continue
+		}
+		logger.Infof("[%X] closing API connection of %q", s.ConnectionId(), s.UserTag().Username())
+		delete(r.conns, s.ConnectionId())
+		go conn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// Package spaces contains api calls for managing spaces: named groups
+// of subnets that service endpoints can be bound to.
+package spaces
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/state"
+)
+
+func init() {
+	common.RegisterStandardFacade("Spaces", 0, NewAPI)
+}
+
+// Spaces defines the methods on the spaces API end point.
+type Spaces interface {
+	CreateSpaces(args params.CreateSpacesParams) (params.ErrorResults, error)
+	ListSpaces() (params.ListSpacesResults, error)
+}
+
+// API implements the Spaces interface and is the concrete
+// implementation of the api end point.
+type API struct {
+	state      *state.State
+	authorizer common.Authorizer
+	check      *common.BlockChecker
+}
+
+var _ Spaces = (*API)(nil)
+
+// NewAPI returns a new spaces API facade.
+func NewAPI(st *state.State, resources *common.Resources, authorizer common.Authorizer) (*API, error) {
+	if !authorizer.AuthClient() {
+		return nil, common.ErrPerm
+	}
+	return &API{
+		state:      st,
+		authorizer: authorizer,
+		check:      common.NewBlockChecker(st),
+	}, nil
+}
+
+// CreateSpaces creates the given spaces, each holding the given
+// existing subnets.
+func (api *API) CreateSpaces(args params.CreateSpacesParams) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.Spaces)),
+	}
+	if err := api.check.ChangeAllowed(); err != nil {
+		return result, errors.Trace(err)
+	}
+	for i, arg := range args.Spaces {
+		_, err := api.state.AddSpace(arg.Name, arg.SubnetCIDRs)
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
+// ListSpaces returns all the spaces in the environment, and the subnets
+// in each.
+func (api *API) ListSpaces() (params.ListSpacesResults, error) {
+	var result params.ListSpacesResults
+	spaces, err := api.state.AllSpaces()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	result.Results = make([]params.Space, len(spaces))
+	for i, space := range spaces {
+		subnets, err := space.Subnets()
+		if err != nil {
+			return params.ListSpacesResults{}, errors.Trace(err)
+		}
+		result.Results[i].Name = space.Name()
+		for _, subnet := range subnets {
+			result.Results[i].Subnets = append(result.Results[i].Subnets, params.Subnet{
+				CIDR:       subnet.CIDR(),
+				ProviderId: subnet.ProviderId(),
+				VLANTag:    subnet.VLANTag(),
+				Zone:       subnet.AvailabilityZone(),
+				SpaceName:  subnet.SpaceName(),
+			})
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package spaces_test
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/apiserver/spaces"
+	apiservertesting "github.com/juju/juju/apiserver/testing"
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/state"
+)
+
+type spacesSuite struct {
+	jujutesting.JujuConnSuite
+
+	api        *spaces.API
+	resources  *common.Resources
+	authorizer apiservertesting.FakeAuthorizer
+}
+
+var _ = gc.Suite(&spacesSuite{})
+
+func (s *spacesSuite) SetUpTest(c *gc.C) {
+	s.JujuConnSuite.SetUpTest(c)
+	s.resources = common.NewResources()
+	s.AddCleanup(func(_ *gc.C) { s.resources.StopAll() })
+	s.authorizer = apiservertesting.FakeAuthorizer{
+		Tag: s.AdminUserTag(c),
+	}
+	var err error
+	s.api, err = spaces.NewAPI(s.State, s.resources, s.authorizer)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *spacesSuite) TestNewAPIRefusesNonClient(c *gc.C) {
+	authorizer := s.authorizer
+	authorizer.Tag = names.NewUnitTag("mysql/0")
+	api, err := spaces.NewAPI(s.State, s.resources, authorizer)
+	c.Assert(api, gc.IsNil)
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
+func (s *spacesSuite) TestCreateSpaces(c *gc.C) {
+	_, err := s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.0.0/24"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	result, err := s.api.CreateSpaces(params.CreateSpacesParams{
+		Spaces: []params.CreateSpaceParams{
+			{Name: "internal", SubnetCIDRs: []string{"10.0.0.0/24"}},
+			{Name: "public"},
+			{Name: "storage", SubnetCIDRs: []string{"192.168.1.0/24"}},
+			{Name: "Invalid"},
+		},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Results, gc.HasLen, 4)
+	c.Check(result.Results[0].Error, gc.IsNil)
+	c.Check(result.Results[1].Error, gc.IsNil)
+	c.Check(result.Results[2].Error, gc.ErrorMatches, `cannot add space "storage": subnet "192.168.1.0/24" not found`)
+	c.Check(result.Results[3].Error, gc.ErrorMatches, `cannot add space "Invalid": invalid name`)
+
+	subnet, err := s.State.Subnet("10.0.0.0/24")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(subnet.SpaceName(), gc.Equals, "internal")
+}
+
+func (s *spacesSuite) TestCreateSpacesBlocked(c *gc.C) {
+	s.AssertConfigParameterUpdated(c, "block-all-changes", true)
+	_, err := s.api.CreateSpaces(params.CreateSpacesParams{
+		Spaces: []params.CreateSpaceParams{{Name: "internal"}},
+	})
+	c.Assert(errors.Cause(err), gc.ErrorMatches, common.ErrOperationBlocked.Error())
+	_, err = s.State.Space("internal")
+	c.Assert(err, gc.ErrorMatches, `space "internal" not found`)
+}
+
+func (s *spacesSuite) TestListSpaces(c *gc.C) {
+	_, err := s.State.AddSpace("public", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.State.AddSpace("internal", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	for _, info := range []state.SubnetInfo{
+		{CIDR: "10.0.1.0/24", SpaceName: "internal", ProviderId: "subnet-1", AvailabilityZone: "zone1"},
+		{CIDR: "10.0.0.0/24", SpaceName: "internal", VLANTag: 42},
+		{CIDR: "192.168.1.0/24"},
+	} {
+		_, err := s.State.AddSubnet(info)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+
+	result, err := s.api.ListSpaces()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, params.ListSpacesResults{
+		Results: []params.Space{{
+			Name: "internal",
+			Subnets: []params.Subnet{{
+				CIDR:      "10.0.0.0/24",
+				VLANTag:   42,
+				SpaceName: "internal",
+			}, {
+				CIDR:       "10.0.1.0/24",
+				ProviderId: "subnet-1",
+				Zone:       "zone1",
+				SpaceName:  "internal",
+			}},
+		}, {
+			Name: "public",
+		}},
+	})
+}
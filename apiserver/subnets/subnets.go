@@ -0,0 +1,81 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// Package subnets contains api calls for managing the subnets known to
+// juju.
+package subnets
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/state"
+)
+
+func init() {
+	common.RegisterStandardFacade("Subnets", 0, NewAPI)
+}
+
+// Subnets defines the methods on the subnets API end point.
+type Subnets interface {
+	AddSubnets(args params.AddSubnetsParams) (params.ErrorResults, error)
+}
+
+// API implements the Subnets interface and is the concrete
+// implementation of the api end point.
+type API struct {
+	state      *state.State
+	authorizer common.Authorizer
+	check      *common.BlockChecker
+}
+
+var _ Subnets = (*API)(nil)
+
+// NewAPI returns a new subnets API facade.
+func NewAPI(st *state.State, resources *common.Resources, authorizer common.Authorizer) (*API, error) {
+	if !authorizer.AuthClient() {
+		return nil, common.ErrPerm
+	}
+	return &API{
+		state:      st,
+		authorizer: authorizer,
+		check:      common.NewBlockChecker(st),
+	}, nil
+}
+
+// AddSubnets adds the given subnets to their spaces. Subnets juju does
+// not yet know about are created; known subnets that are not yet in a
+// space are put in the given one.
+func (api *API) AddSubnets(args params.AddSubnetsParams) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.Subnets)),
+	}
+	if err := api.check.ChangeAllowed(); err != nil {
+		return result, errors.Trace(err)
+	}
+	for i, arg := range args.Subnets {
+		result.Results[i].Error = common.ServerError(api.addSubnet(arg))
+	}
+	return result, nil
+}
+
+func (api *API) addSubnet(arg params.Subnet) error {
+	if arg.SpaceName == "" {
+		return errors.Errorf("subnet %q must be added to a space", arg.CIDR)
+	}
+	subnet, err := api.state.Subnet(arg.CIDR)
+	if errors.IsNotFound(err) {
+		_, err = api.state.AddSubnet(state.SubnetInfo{
+			CIDR:             arg.CIDR,
+			ProviderId:       arg.ProviderId,
+			VLANTag:          arg.VLANTag,
+			AvailabilityZone: arg.Zone,
+			SpaceName:        arg.SpaceName,
+		})
+		return err
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	return subnet.SetSpace(arg.SpaceName)
+}
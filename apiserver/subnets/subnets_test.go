@@ -0,0 +1,93 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package subnets_test
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/apiserver/subnets"
+	apiservertesting "github.com/juju/juju/apiserver/testing"
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/state"
+)
+
+type subnetsSuite struct {
+	jujutesting.JujuConnSuite
+
+	api        *subnets.API
+	resources  *common.Resources
+	authorizer apiservertesting.FakeAuthorizer
+}
+
+var _ = gc.Suite(&subnetsSuite{})
+
+func (s *subnetsSuite) SetUpTest(c *gc.C) {
+	s.JujuConnSuite.SetUpTest(c)
+	s.resources = common.NewResources()
+	s.AddCleanup(func(_ *gc.C) { s.resources.StopAll() })
+	s.authorizer = apiservertesting.FakeAuthorizer{
+		Tag: s.AdminUserTag(c),
+	}
+	var err error
+	s.api, err = subnets.NewAPI(s.State, s.resources, s.authorizer)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *subnetsSuite) TestNewAPIRefusesNonClient(c *gc.C) {
+	authorizer := s.authorizer
+	authorizer.Tag = names.NewUnitTag("mysql/0")
+	api, err := subnets.NewAPI(s.State, s.resources, authorizer)
+	c.Assert(api, gc.IsNil)
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
+func (s *subnetsSuite) TestAddSubnets(c *gc.C) {
+	for _, name := range []string{"internal", "storage"} {
+		_, err := s.State.AddSpace(name, nil)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	// Known subnets not in a space can be added to one.
+	_, err := s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.1.0/24"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	result, err := s.api.AddSubnets(params.AddSubnetsParams{
+		Subnets: []params.Subnet{
+			{CIDR: "10.0.0.0/24", SpaceName: "internal", ProviderId: "subnet-0", VLANTag: 42, Zone: "zone1"},
+			{CIDR: "10.0.1.0/24", SpaceName: "internal"},
+			{CIDR: "10.0.1.0/24", SpaceName: "storage"},
+			{CIDR: "192.168.1.0/24", SpaceName: "missing"},
+			{CIDR: "192.168.2.0/24"},
+		},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.Results, gc.HasLen, 5)
+	c.Check(result.Results[0].Error, gc.IsNil)
+	c.Check(result.Results[1].Error, gc.IsNil)
+	c.Check(result.Results[2].Error, gc.ErrorMatches, `cannot set space of subnet "10.0.1.0/24": subnet is already in space "internal"`)
+	c.Check(result.Results[3].Error, gc.ErrorMatches, `cannot add subnet 192.168.1.0/24: space "missing" not found`)
+	c.Check(result.Results[4].Error, gc.ErrorMatches, `subnet "192.168.2.0/24" must be added to a space`)
+
+	subnet, err := s.State.Subnet("10.0.0.0/24")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(subnet.SpaceName(), gc.Equals, "internal")
+	c.Check(subnet.ProviderId(), gc.Equals, "subnet-0")
+	c.Check(subnet.VLANTag(), gc.Equals, 42)
+	c.Check(subnet.AvailabilityZone(), gc.Equals, "zone1")
+	subnet, err = s.State.Subnet("10.0.1.0/24")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(subnet.SpaceName(), gc.Equals, "internal")
+}
+
+func (s *subnetsSuite) TestAddSubnetsBlocked(c *gc.C) {
+	s.AssertConfigParameterUpdated(c, "block-all-changes", true)
+	_, err := s.api.AddSubnets(params.AddSubnetsParams{
+		Subnets: []params.Subnet{{CIDR: "10.0.0.0/24", SpaceName: "internal"}},
+	})
+	c.Assert(errors.Cause(err), gc.ErrorMatches, common.ErrOperationBlocked.Error())
+}
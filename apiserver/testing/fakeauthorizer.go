@@ -5,12 +5,18 @@ package testing
 
 import (
 	"github.com/juju/names"
+
+	"github.com/juju/juju/state"
 )
 
 // FakeAuthorizer implements the common.Authorizer interface.
 type FakeAuthorizer struct {
 	Tag            names.Tag
 	EnvironManager bool
+
+	// Access holds the environment access of a user. If it is
+	// empty, users have admin access.
+	Access state.Access
 }
 
 func (fa FakeAuthorizer) AuthOwner(tag names.Tag) bool {
@@ -40,6 +46,18 @@ func (fa FakeAuthorizer) AuthClient() bool {
 	return isUser
 }
 
+// AuthEnvironAccess returns whether the authenticated entity is a
+// client user with at least the given access to the environment.
+func (fa FakeAuthorizer) AuthEnvironAccess(access state.Access) bool {
+	if !fa.AuthClient() {
+		return false
+	}
+	if fa.Access == "" {
+		return state.AdminAccess.Includes(access)
+	}
+	return fa.Access.Includes(access)
+}
+
 func (fa FakeAuthorizer) GetAuthTag() names.Tag {
 	return fa.Tag
 }
@@ -53,6 +53,13 @@ func (s *toolsSuite) TestRequiresAuth(c *gc.C) {
 	s.assertErrorResponse(c, resp, http.StatusUnauthorized, "unauthorized")
 }
 
+func (s *toolsSuite) TestRequiresWriteAccess(c *gc.C) {
+	tag, password := s.makeUserWithAccess(c, state.ReadAccess)
+	resp, err := s.sendRequest(c, tag, password, "POST", s.toolsURI(c, ""), "", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertErrorResponse(c, resp, http.StatusUnauthorized, "unauthorized")
+}
+
 func (s *toolsSuite) TestRequiresPOST(c *gc.C) {
 	resp, err := s.authRequest(c, "PUT", s.toolsURI(c, ""), "", nil)
 	c.Assert(err, jc.ErrorIsNil)
@@ -163,7 +163,9 @@ func (u *uniterBaseAPI) PrivateAddress(args params.Entities) (params.StringResul
 			var unit *state.Unit
 			unit, err = u.getUnit(tag)
 			if err == nil {
-				address, ok := unit.PrivateAddress()
+				// Use the address in the space of the endpoints
+				// not bound explicitly, if any.
+				address, ok := unit.PrivateAddressForEndpoint("")
 				if ok {
 					result.Results[i].Result = address
 				} else {
@@ -987,7 +989,8 @@ func (u *uniterBaseAPI) EnterScope(args params.RelationUnits) (params.ErrorResul
 		relUnit, err := u.getRelationUnit(canAccess, arg.Relation, tag)
 		if err == nil {
 			// Construct the settings, passing the unit's
-			// private address (we already know it).
+			// private address in the space the relation's
+			// endpoint is bound to (we already know it).
 			privateAddress, _ := relUnit.PrivateAddress()
 			settings := map[string]interface{}{
 				"private-address": privateAddress,
@@ -453,3 +453,53 @@ func (s *uniterV1Suite) TestRequestReboot(c *gc.C) {
 	c.Assert(err, jc.ErrorIsNil)
 	c.Assert(rFlag, jc.IsFalse)
 }
+
+func (s *uniterV1Suite) bindWordpress(c *gc.C, bindings map[string]string) {
+	for space, cidr := range map[string]string{
+		"internal": "10.0.0.0/24",
+		"storage":  "192.168.1.0/24",
+	} {
+		_, err := s.State.AddSpace(space, nil)
+		c.Assert(err, jc.ErrorIsNil)
+		_, err = s.State.AddSubnet(state.SubnetInfo{CIDR: cidr, SpaceName: space})
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	err := s.wordpress.SetEndpointBindings(bindings)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine0.SetAddresses(
+		network.NewAddress("10.0.0.5", network.ScopeCloudLocal),
+		network.NewAddress("192.168.1.5", network.ScopeCloudLocal),
+	)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *uniterV1Suite) TestPrivateAddressInBoundSpace(c *gc.C) {
+	s.bindWordpress(c, map[string]string{"": "storage", "db": "internal"})
+
+	args := params.Entities{Entities: []params.Entity{{Tag: "unit-wordpress-0"}}}
+	result, err := s.uniter.PrivateAddress(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, gc.DeepEquals, params.StringResults{
+		Results: []params.StringResult{{Result: "192.168.1.5"}},
+	})
+}
+
+func (s *uniterV1Suite) TestEnterScopeUsesBoundAddress(c *gc.C) {
+	s.bindWordpress(c, map[string]string{"db": "storage"})
+	rel := s.addRelation(c, "wordpress", "mysql")
+	relUnit, err := rel.Unit(s.wordpressUnit)
+	c.Assert(err, jc.ErrorIsNil)
+
+	args := params.RelationUnits{RelationUnits: []params.RelationUnit{
+		{Relation: rel.Tag().String(), Unit: "unit-wordpress-0"},
+	}}
+	result, err := s.uniter.EnterScope(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result.OneError(), jc.ErrorIsNil)
+
+	readSettings, err := relUnit.ReadSettings(s.wordpressUnit.Name())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(readSettings, gc.DeepEquals, map[string]interface{}{
+		"private-address": "192.168.1.5",
+	})
+}
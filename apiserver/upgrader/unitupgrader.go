@@ -98,6 +98,9 @@ func (u *UnitUpgraderAPI) DesiredVersion(args params.Entities) (params.VersionRe
 		err = common.ErrPerm
 		if u.authorizer.AuthOwner(tag) {
 			result[i].Version, err = u.getMachineToolsVersion(tag)
+			if err == nil {
+				result[i].Downgrade, err = rolledBackTo(u.st, *result[i].Version)
+			}
 		}
 		result[i].Error = common.ServerError(err)
 	}
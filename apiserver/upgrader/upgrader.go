@@ -103,7 +103,7 @@ func (u *UpgraderAPI) WatchAPIVersion(args params.Entities) (params.NotifyWatchR
 		}
 		err = common.ErrPerm
 		if u.authorizer.AuthOwner(tag) {
-			watch := u.st.WatchForEnvironConfigChanges()
+			watch := u.st.WatchAgentVersion()
 			// Consume the initial event. Technically, API
 			// calls to Watch 'transmit' the initial event
 			// in the Watch response. But NotifyWatchers
@@ -149,6 +149,28 @@ func (u *UpgraderAPI) entityIsManager(tag names.Tag) bool {
 	}
 }
 
+// heldVersion returns the version a staged upgrade to agentVersion
+// holds the agent of the given entity at, if it does.
+func (u *UpgraderAPI) heldVersion(staged *state.StagedUpgrade, agentVersion version.Number, tag names.Tag) (version.Number, bool) {
+	if staged == nil || staged.TargetVersion() != agentVersion {
+		return version.Number{}, false
+	}
+	if _, ok := tag.(names.MachineTag); !ok || !staged.HoldsMachine(tag.Id()) {
+		return version.Number{}, false
+	}
+	machine, err := u.st.Machine(tag.Id())
+	if err != nil || machine.IsManager() {
+		return version.Number{}, false
+	}
+	// Agents that do not report a version yet, such as the ones of
+	// machines provisioned during the upgrade, run the new version.
+	tools, err := machine.AgentTools()
+	if err != nil || tools.Version.Number == agentVersion {
+		return version.Number{}, false
+	}
+	return tools.Version.Number, true
+}
+
 // DesiredVersion reports the Agent Version that we want that agent to be running
 func (u *UpgraderAPI) DesiredVersion(args params.Entities) (params.VersionResults, error) {
 	results := make([]params.VersionResult, len(args.Entities))
@@ -161,6 +183,16 @@ func (u *UpgraderAPI) DesiredVersion(args params.Entities) (params.VersionResult
 	}
 	// Is the desired version greater than the current API server version?
 	isNewerVersion := agentVersion.Compare(version.Current.Number) > 0
+	staged, err := u.st.StagedUpgrade()
+	if errors.IsNotFound(err) {
+		staged = nil
+	} else if err != nil {
+		return params.VersionResults{}, common.ServerError(err)
+	}
+	downgrade, err := rolledBackTo(u.st, agentVersion)
+	if err != nil {
+		return params.VersionResults{}, common.ServerError(err)
+	}
 	for i, entity := range args.Entities {
 		tag, err := names.ParseTag(entity.Tag)
 		if err != nil {
@@ -179,8 +211,16 @@ func (u *UpgraderAPI) DesiredVersion(args params.Entities) (params.VersionResult
 			// first - once they have restarted and are running the
 			// new version other agents will start to see the new
 			// agent version.
-			if !isNewerVersion || u.entityIsManager(tag) {
+			//
+			// A staged upgrade to the desired version further holds
+			// back the other machine agents on the version they
+			// run until it releases them.
+			if heldVersion, ok := u.heldVersion(staged, agentVersion, tag); ok {
+				logger.Debugf("desired version is %s, but %s is held at %s by a staged upgrade", agentVersion, tag, heldVersion)
+				results[i].Version = &heldVersion
+			} else if !isNewerVersion || u.entityIsManager(tag) {
 				results[i].Version = &agentVersion
+				results[i].Downgrade = downgrade
 			} else {
 				logger.Debugf("desired version is %s, but current version is %s and agent is not a manager node", agentVersion, version.Current.Number)
 				results[i].Version = &version.Current.Number
@@ -191,3 +231,16 @@ func (u *UpgraderAPI) DesiredVersion(args params.Entities) (params.VersionResult
 	}
 	return params.VersionResults{Results: results}, nil
 }
+
+// rolledBackTo returns whether the last upgrade was rolled back to the
+// given version, in which case agents may downgrade to it even across
+// minor versions.
+func rolledBackTo(st *state.State, vers version.Number) (bool, error) {
+	backup, err := st.UpgradeBackup()
+	if errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, errors.Trace(err)
+	}
+	return backup.RolledBack() && backup.PreviousVersion() == vers, nil
+}
@@ -5,6 +5,7 @@ package upgrader_test
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/juju/names"
@@ -324,3 +325,91 @@ func (s *upgraderSuite) TestDesiredVersionRestrictedForNonAPIAgents(c *gc.C) {
 	c.Assert(agentVersion, gc.NotNil)
 	c.Check(*agentVersion, gc.DeepEquals, version.Current.Number)
 }
+
+func (s *upgraderSuite) TestDesiredVersionDowngradeAfterRollback(c *gc.C) {
+	newer := version.Current
+	newer.Patch++
+	err := s.State.SetUpgradeBackup(version.Current.Number, newer.Number, "backup-id")
+	c.Assert(err, jc.ErrorIsNil)
+	args := params.Entities{Entities: []params.Entity{{Tag: s.rawMachine.Tag().String()}}}
+	assertDowngrade := func(expected bool) {
+		results, err := s.upgrader.DesiredVersion(args)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(results.Results, gc.HasLen, 1)
+		c.Assert(results.Results[0].Error, gc.IsNil)
+		c.Assert(*results.Results[0].Version, gc.Equals, version.Current.Number)
+		c.Assert(results.Results[0].Downgrade, gc.Equals, expected)
+	}
+	assertDowngrade(false)
+
+	// Once the upgrade is rolled back to the desired version, agents
+	// running the later version are told to downgrade.
+	backup, err := s.State.UpgradeBackup()
+	c.Assert(err, jc.ErrorIsNil)
+	err = backup.SetRolledBack()
+	c.Assert(err, jc.ErrorIsNil)
+	assertDowngrade(true)
+}
+
+func (s *upgraderSuite) TestDesiredVersionHeldByStagedUpgrade(c *gc.C) {
+	s.apiMachine.SetAgentVersion(version.Current)
+	s.rawMachine.SetAgentVersion(version.Current)
+	oldVersion := version.Current.Number
+	newer := version.Current
+	newer.Patch++
+	err := s.State.StartStagedUpgrade(newer.Number, state.StagedUpgradeArgs{
+		CanaryPercent: 10,
+		HealthTimeout: time.Minute,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	// The API server runs the new version.
+	s.PatchValue(&version.Current, newer)
+
+	desiredVersion := func() version.Number {
+		args := params.Entities{Entities: []params.Entity{{Tag: s.rawMachine.Tag().String()}}}
+		results, err := s.upgrader.DesiredVersion(args)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(results.Results, gc.HasLen, 1)
+		c.Assert(results.Results[0].Error, gc.IsNil)
+		c.Assert(results.Results[0].Version, gc.NotNil)
+		return *results.Results[0].Version
+	}
+	c.Check(desiredVersion(), gc.Equals, oldVersion)
+
+	staged, err := s.State.StagedUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+	err = staged.ReleaseMachines([]string{s.rawMachine.Id()})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(desiredVersion(), gc.Equals, newer.Number)
+}
+
+func (s *upgraderSuite) TestWatchAPIVersionNoticesStagedUpgradeRelease(c *gc.C) {
+	s.apiMachine.SetAgentVersion(version.Current)
+	s.rawMachine.SetAgentVersion(version.Current)
+	newer := version.Current
+	newer.Patch++
+	err := s.State.StartStagedUpgrade(newer.Number, state.StagedUpgradeArgs{
+		CanaryPercent: 10,
+		HealthTimeout: time.Minute,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	args := params.Entities{
+		Entities: []params.Entity{{Tag: s.rawMachine.Tag().String()}},
+	}
+	results, err := s.upgrader.WatchAPIVersion(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 1)
+	c.Assert(results.Results[0].Error, gc.IsNil)
+	w := s.resources.Get(results.Results[0].NotifyWatcherId).(state.NotifyWatcher)
+	wc := statetesting.NewNotifyWatcherC(c, s.State, w)
+	wc.AssertNoChange()
+
+	staged, err := s.State.StagedUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+	err = staged.ReleaseMachines([]string{s.rawMachine.Id()})
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+	statetesting.AssertStop(c, w)
+	wc.AssertClosed()
+}
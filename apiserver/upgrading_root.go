@@ -25,11 +25,13 @@ func newUpgradingRoot(finder rpc.MethodFinder) *upgradingRoot {
 var inUpgradeError = errors.New("upgrade in progress - Juju functionality is limited")
 
 var allowedMethodsDuringUpgrades = set.NewStrings(
-	"FullStatus",     // for "juju status"
-	"EnvironmentGet", // for "juju ssh"
-	"PrivateAddress", // for "juju ssh"
-	"PublicAddress",  // for "juju ssh"
-	"WatchDebugLog",  // for "juju debug-log"
+	"APIServerLoad",   // for "juju status --load"
+	"FullStatus",      // for "juju status"
+	"EnvironmentGet",  // for "juju ssh"
+	"PrivateAddress",  // for "juju ssh"
+	"PublicAddress",   // for "juju ssh"
+	"RollbackUpgrade", // for "juju upgrade-juju --rollback"
+	"WatchDebugLog",   // for "juju debug-log"
 )
 
 func IsMethodAllowedDuringUpgrade(rootName, methodName string) bool {
@@ -26,6 +26,13 @@ type UserManager interface {
 	EnableUser(args params.Entities) (params.ErrorResults, error)
 	SetPassword(args params.EntityPasswords) (params.ErrorResults, error)
 	UserInfo(args params.UserInfoRequest) (params.UserInfoResults, error)
+	ModifyEnvironAccess(args params.ModifyEnvironAccessRequest) (params.ErrorResults, error)
+	UserSessions(args params.Entities) (params.UserSessionsResults, error)
+	CloseUserSessions(args params.Entities) (params.CloseUserSessionsResults, error)
+	RemoveUser(args params.Entities) (params.ErrorResults, error)
+	UserSSHKeys(args params.Entities) (params.StringsResults, error)
+	AddUserSSHKeys(arg params.UserSSHKeys) (params.ErrorResults, error)
+	RemoveUserSSHKeys(arg params.UserSSHKeys) (params.ErrorResults, error)
 }
 
 // UserManagerAPI implements the user manager interface and is the concrete
@@ -132,6 +139,15 @@ func (api *UserManagerAPI) DisableUser(users params.Entities) (params.ErrorResul
 	return api.enableUserImpl(users, "disable", (*state.User).Disable)
 }
 
+// RemoveUser removes one or more users. Removed users cannot log in,
+// and their SSH keys are revoked on all machines.
+func (api *UserManagerAPI) RemoveUser(users params.Entities) (params.ErrorResults, error) {
+	if err := api.check.ChangeAllowed(); err != nil {
+		return params.ErrorResults{}, errors.Trace(err)
+	}
+	return api.enableUserImpl(users, "remove", (*state.User).Remove)
+}
+
 func (api *UserManagerAPI) enableUserImpl(args params.Entities, action string, method func(*state.User) error) (params.ErrorResults, error) {
 	result := params.ErrorResults{
 		Results: make([]params.ErrorResult, len(args.Entities)),
@@ -256,3 +272,261 @@ func (api *UserManagerAPI) getLoggedInUser() (names.UserTag, error) {
 		return names.UserTag{}, errors.New("authorizer not a user")
 	}
 }
+
+// ModifyEnvironAccess grants or revokes users' access to the
+// environment. Only environment admins may change access.
+func (api *UserManagerAPI) ModifyEnvironAccess(args params.ModifyEnvironAccessRequest) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.Changes)),
+	}
+	if err := api.check.ChangeAllowed(); err != nil {
+		return result, errors.Trace(err)
+	}
+	if !api.authorizer.AuthEnvironAccess(state.AdminAccess) {
+		return result, common.ErrPerm
+	}
+	loggedInUser, err := api.getLoggedInUser()
+	if err != nil {
+		return result, errors.Wrap(err, common.ErrPerm)
+	}
+	for i, arg := range args.Changes {
+		err := api.modifyEnvironAccess(loggedInUser, arg)
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
+func (api *UserManagerAPI) modifyEnvironAccess(loggedInUser names.UserTag, arg params.ModifyEnvironAccess) error {
+	user, err := names.ParseUserTag(arg.UserTag)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	access, err := state.ParseAccess(arg.Access)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	switch arg.Action {
+	case params.GrantEnvironAccess:
+		return errors.Annotate(grantEnvironAccess(api.state, user, loggedInUser, access), "could not grant environment access")
+	case params.RevokeEnvironAccess:
+		return errors.Annotate(revokeEnvironAccess(api.state, user, access), "could not revoke environment access")
+	}
+	return errors.Errorf("unknown action %q", arg.Action)
+}
+
+// grantEnvironAccess gives the user the given access to the
+// environment, adding them to the environment if necessary.
+func grantEnvironAccess(st *state.State, user, createdBy names.UserTag, access state.Access) error {
+	envUser, err := st.EnvironmentUser(user)
+	if errors.IsNotFound(err) {
+		_, err := st.AddEnvironmentUser(user, createdBy, access)
+		return errors.Trace(err)
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	if envUser.Access().Includes(access) {
+		return errors.Errorf("user already has %q access", envUser.Access())
+	}
+	return errors.Trace(envUser.SetAccess(access))
+}
+
+// revokeEnvironAccess takes the given access to the environment away
+// from the user, leaving them with the next lower level of access.
+// Revoking read access removes the user from the environment.
+func revokeEnvironAccess(st *state.State, user names.UserTag, access state.Access) error {
+	env, err := st.Environment()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if env.Owner() == user {
+		return errors.New("cannot revoke access of the environment owner")
+	}
+	envUser, err := st.EnvironmentUser(user)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !envUser.Access().Includes(access) {
+		return errors.Errorf("user does not have %q access", access)
+	}
+	switch access {
+	case state.AdminAccess:
+		return errors.Trace(envUser.SetAccess(state.WriteAccess))
+	case state.WriteAccess:
+		return errors.Trace(envUser.SetAccess(state.ReadAccess))
+	}
+	return errors.Trace(st.RemoveEnvironmentUser(user))
+}
+
+// UserSessions returns the API connections of the given users to all
+// the API servers. Users may list their own connections; only admins
+// may list those of other users.
+func (api *UserManagerAPI) UserSessions(args params.Entities) (params.UserSessionsResults, error) {
+	result := params.UserSessionsResults{
+		Results: make([]params.UserSessionsResult, len(args.Entities)),
+	}
+	if len(args.Entities) == 0 {
+		return result, nil
+	}
+	loggedInUser, err := api.getLoggedInUser()
+	if err != nil {
+		return result, common.ErrPerm
+	}
+	adminUser := api.permissionCheck(loggedInUser) == nil
+	for i, arg := range args.Entities {
+		user, err := api.sessionUser(loggedInUser, arg.Tag, adminUser)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		sessions, err := api.state.UserSessions(user)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		for _, s := range sessions {
+			result.Results[i].Sessions = append(result.Results[i].Sessions, params.UserSession{
+				UserTag:       s.UserTag().String(),
+				EnvironTag:    s.EnvironTag().String(),
+				RemoteAddress: s.RemoteAddress(),
+				LoginTime:     s.LoginTime(),
+				APIServer:     s.Server(),
+				ConnectionId:  s.ConnectionId(),
+			})
+		}
+	}
+	return result, nil
+}
+
+// CloseUserSessions asks the API servers to close the API connections
+// of the given users, and returns how many there were for each. Users
+// may close their own connections; only admins may close those of
+// other users.
+func (api *UserManagerAPI) CloseUserSessions(args params.Entities) (params.CloseUserSessionsResults, error) {
+	result := params.CloseUserSessionsResults{
+		Results: make([]params.CloseUserSessionsResult, len(args.Entities)),
+	}
+	if len(args.Entities) == 0 {
+		return result, nil
+	}
+	loggedInUser, err := api.getLoggedInUser()
+	if err != nil {
+		return result, common.ErrPerm
+	}
+	adminUser := api.permissionCheck(loggedInUser) == nil
+	for i, arg := range args.Entities {
+		user, err := api.sessionUser(loggedInUser, arg.Tag, adminUser)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		closed, err := api.state.CloseUserSessions(user)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		result.Results[i].Closed = closed
+		logger.Infof("%q closed %d sessions of %q", loggedInUser.Username(), result.Results[i].Closed, user.Username())
+	}
+	return result, nil
+}
+
+// sessionUser returns the user whose sessions are to be listed or
+// closed, checking that the logged in user may do so.
+func (api *UserManagerAPI) sessionUser(loggedInUser names.UserTag, tag string, adminUser bool) (names.UserTag, error) {
+	userTag, err := names.ParseUserTag(tag)
+	if err != nil {
+		return names.UserTag{}, errors.Trace(err)
+	}
+	// Users from an identity provider have no local user.
+	if userTag.IsLocal() {
+		if _, err := api.getUser(tag); err != nil {
+			return names.UserTag{}, errors.Trace(err)
+		}
+	}
+	if loggedInUser.Username() != userTag.Username() && !adminUser {
+		return names.UserTag{}, errors.Trace(common.ErrPerm)
+	}
+	return userTag, nil
+}
+
+// UserSSHKeys returns the SSH keys of the given users. Users may list
+// their own keys; only admins may list those of other users.
+func (api *UserManagerAPI) UserSSHKeys(args params.Entities) (params.StringsResults, error) {
+	result := params.StringsResults{
+		Results: make([]params.StringsResult, len(args.Entities)),
+	}
+	if len(args.Entities) == 0 {
+		return result, nil
+	}
+	loggedInUser, err := api.getLoggedInUser()
+	if err != nil {
+		return result, common.ErrPerm
+	}
+	adminUser := api.permissionCheck(loggedInUser) == nil
+	for i, arg := range args.Entities {
+		user, err := api.sshKeysUser(loggedInUser, arg.Tag, adminUser)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		result.Results[i].Result = user.SSHKeys()
+	}
+	return result, nil
+}
+
+// AddUserSSHKeys adds SSH keys to a user. The keys are authorized on
+// the machines of every environment the user can change. Users may add
+// their own keys; only admins may add keys for other users.
+func (api *UserManagerAPI) AddUserSSHKeys(arg params.UserSSHKeys) (params.ErrorResults, error) {
+	return api.modifyUserSSHKeys(arg, "added", (*state.User).AddSSHKeys)
+}
+
+// RemoveUserSSHKeys removes SSH keys, identified by fingerprint or
+// comment, from a user. The keys are revoked on all machines. Users
+// may remove their own keys; only admins may remove keys of other
+// users.
+func (api *UserManagerAPI) RemoveUserSSHKeys(arg params.UserSSHKeys) (params.ErrorResults, error) {
+	return api.modifyUserSSHKeys(arg, "removed", (*state.User).RemoveSSHKeys)
+}
+
+func (api *UserManagerAPI) modifyUserSSHKeys(arg params.UserSSHKeys, action string, method func(*state.User, ...string) error) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(arg.Keys)),
+	}
+	if err := api.check.ChangeAllowed(); err != nil {
+		return result, errors.Trace(err)
+	}
+	if len(arg.Keys) == 0 {
+		return result, nil
+	}
+	loggedInUser, err := api.getLoggedInUser()
+	if err != nil {
+		return result, common.ErrPerm
+	}
+	adminUser := api.permissionCheck(loggedInUser) == nil
+	user, err := api.sshKeysUser(loggedInUser, arg.UserTag, adminUser)
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	for i, key := range arg.Keys {
+		if err := method(user, key); err != nil {
+			result.Results[i].Error = common.ServerError(err)
+			continue
+		}
+		logger.Infof("%q %s an ssh key of %q", loggedInUser.Username(), action, user.String())
+	}
+	return result, nil
+}
+
+// sshKeysUser returns the user whose SSH keys are to be listed or
+// changed, checking that the logged in user may do so.
+func (api *UserManagerAPI) sshKeysUser(loggedInUser names.UserTag, tag string, adminUser bool) (*state.User, error) {
+	user, err := api.getUser(tag)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if loggedInUser != user.UserTag() && !adminUser {
+		return nil, errors.Trace(common.ErrPerm)
+	}
+	return user, nil
+}
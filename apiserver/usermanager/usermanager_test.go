@@ -14,7 +14,9 @@ import (
 	apiservertesting "github.com/juju/juju/apiserver/testing"
 	"github.com/juju/juju/apiserver/usermanager"
 	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/state"
 	"github.com/juju/juju/testing/factory"
+	sshtesting "github.com/juju/juju/utils/ssh/testing"
 )
 
 type userManagerSuite struct {
@@ -282,6 +284,50 @@ func (s *userManagerSuite) TestEnableUserAsNormalUser(c *gc.C) {
 	c.Assert(barb.IsDisabled(), jc.IsTrue)
 }
 
+func (s *userManagerSuite) TestRemoveUser(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	err := alex.AddSSHKeys(sshtesting.ValidKeyOne.Key)
+	c.Assert(err, jc.ErrorIsNil)
+
+	args := params.Entities{
+		Entities: []params.Entity{
+			{alex.Tag().String()},
+			{s.AdminUserTag(c).String()},
+		}}
+	result, err := s.usermanager.RemoveUser(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, gc.DeepEquals, params.ErrorResults{
+		Results: []params.ErrorResult{
+			{Error: nil},
+			{Error: &params.Error{
+				Message: "failed to remove user: cannot remove state server environment owner",
+			}},
+		}})
+	err = alex.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(alex.IsDisabled(), jc.IsTrue)
+	c.Assert(alex.SSHKeys(), gc.HasLen, 0)
+}
+
+func (s *userManagerSuite) TestRemoveUserAsNormalUser(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	usermanager, err := usermanager.NewUserManagerAPI(
+		s.State, nil, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	barb := s.Factory.MakeUser(c, &factory.UserParams{Name: "barb"})
+
+	args := params.Entities{
+		[]params.Entity{{barb.Tag().String()}},
+	}
+	_, err = usermanager.RemoveUser(args)
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+
+	err = barb.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(barb.IsDisabled(), jc.IsFalse)
+}
+
 func (s *userManagerSuite) TestUserInfo(c *gc.C) {
 	userFoo := s.Factory.MakeUser(c, &factory.UserParams{Name: "foobar", DisplayName: "Foo Bar"})
 	userBar := s.Factory.MakeUser(c, &factory.UserParams{Name: "barfoo", DisplayName: "Bar Foo", Disabled: true})
@@ -476,3 +522,312 @@ func (s *userManagerSuite) TestSetPasswordForOther(c *gc.C) {
 
 	c.Assert(barb.PasswordValid("new-password"), jc.IsFalse)
 }
+
+func (s *userManagerSuite) modifyEnvironAccess(c *gc.C, user names.UserTag, action params.EnvironAccessAction, access state.Access) error {
+	args := params.ModifyEnvironAccessRequest{
+		Changes: []params.ModifyEnvironAccess{{
+			UserTag: user.String(),
+			Action:  action,
+			Access:  string(access),
+		}}}
+	results, err := s.usermanager.ModifyEnvironAccess(args)
+	c.Assert(err, jc.ErrorIsNil)
+	return results.OneError()
+}
+
+func (s *userManagerSuite) TestGrantEnvironAccessAddsUser(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Name: "bob", NoEnvUser: true})
+	err := s.modifyEnvironAccess(c, user.UserTag(), params.GrantEnvironAccess, state.ReadAccess)
+	c.Assert(err, jc.ErrorIsNil)
+
+	envUser, err := s.State.EnvironmentUser(user.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(envUser.Access(), gc.Equals, state.ReadAccess)
+	c.Assert(envUser.CreatedBy(), gc.Equals, s.AdminUserTag(c).Username())
+}
+
+func (s *userManagerSuite) TestGrantEnvironAccessRaisesAccess(c *gc.C) {
+	envUser := s.Factory.MakeEnvUser(c, &factory.EnvUserParams{Access: state.ReadAccess})
+	err := s.modifyEnvironAccess(c, envUser.UserTag(), params.GrantEnvironAccess, state.WriteAccess)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.modifyEnvironAccess(c, envUser.UserTag(), params.GrantEnvironAccess, state.ReadAccess)
+	c.Assert(err, gc.ErrorMatches, `could not grant environment access: user already has "write" access`)
+
+	envUser, err = s.State.EnvironmentUser(envUser.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(envUser.Access(), gc.Equals, state.WriteAccess)
+}
+
+func (s *userManagerSuite) TestGrantEnvironAccessInvalidAccess(c *gc.C) {
+	envUser := s.Factory.MakeEnvUser(c, nil)
+	err := s.modifyEnvironAccess(c, envUser.UserTag(), params.GrantEnvironAccess, state.Access("root"))
+	c.Assert(err, gc.ErrorMatches, `access level "root" not valid`)
+}
+
+func (s *userManagerSuite) TestRevokeEnvironAccess(c *gc.C) {
+	envUser := s.Factory.MakeEnvUser(c, &factory.EnvUserParams{Access: state.AdminAccess})
+	user := envUser.UserTag()
+
+	err := s.modifyEnvironAccess(c, user, params.RevokeEnvironAccess, state.AdminAccess)
+	c.Assert(err, jc.ErrorIsNil)
+	envUser, err = s.State.EnvironmentUser(user)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(envUser.Access(), gc.Equals, state.WriteAccess)
+
+	err = s.modifyEnvironAccess(c, user, params.RevokeEnvironAccess, state.AdminAccess)
+	c.Assert(err, gc.ErrorMatches, `could not revoke environment access: user does not have "admin" access`)
+
+	err = s.modifyEnvironAccess(c, user, params.RevokeEnvironAccess, state.ReadAccess)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.State.EnvironmentUser(user)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *userManagerSuite) TestRevokeEnvironAccessOwner(c *gc.C) {
+	err := s.modifyEnvironAccess(c, s.AdminUserTag(c), params.RevokeEnvironAccess, state.ReadAccess)
+	c.Assert(err, gc.ErrorMatches, "could not revoke environment access: cannot revoke access of the environment owner")
+}
+
+func (s *userManagerSuite) TestModifyEnvironAccessNeedsAdminAccess(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex", Access: state.WriteAccess})
+	barb := s.Factory.MakeUser(c, &factory.UserParams{Name: "barb", NoEnvUser: true})
+	usermanager, err := usermanager.NewUserManagerAPI(
+		s.State, nil, apiservertesting.FakeAuthorizer{Tag: alex.Tag(), Access: state.WriteAccess})
+	c.Assert(err, jc.ErrorIsNil)
+
+	args := params.ModifyEnvironAccessRequest{
+		Changes: []params.ModifyEnvironAccess{{
+			UserTag: barb.Tag().String(),
+			Action:  params.GrantEnvironAccess,
+			Access:  string(state.ReadAccess),
+		}}}
+	_, err = usermanager.ModifyEnvironAccess(args)
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+
+	_, err = s.State.EnvironmentUser(barb.UserTag())
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *userManagerSuite) TestBlockModifyEnvironAccess(c *gc.C) {
+	envUser := s.Factory.MakeEnvUser(c, &factory.EnvUserParams{Access: state.ReadAccess})
+	s.AssertConfigParameterUpdated(c, "block-all-changes", true)
+
+	args := params.ModifyEnvironAccessRequest{
+		Changes: []params.ModifyEnvironAccess{{
+			UserTag: envUser.UserTag().String(),
+			Action:  params.GrantEnvironAccess,
+			Access:  string(state.WriteAccess),
+		}}}
+	_, err := s.usermanager.ModifyEnvironAccess(args)
+	c.Assert(errors.Cause(err), gc.ErrorMatches, common.ErrOperationBlocked.Error())
+
+	envUser, err = s.State.EnvironmentUser(envUser.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(envUser.Access(), gc.Equals, state.ReadAccess)
+}
+
+func (s *userManagerSuite) addSession(c *gc.C, user names.UserTag) *state.UserSession {
+	session, err := s.State.AddUserSession("machine-0", 1, user, "10.0.0.1:1234")
+	c.Assert(err, jc.ErrorIsNil)
+	return session
+}
+
+func (s *userManagerSuite) newAPI(c *gc.C, tag names.Tag) *usermanager.UserManagerAPI {
+	api, err := usermanager.NewUserManagerAPI(s.State, nil, apiservertesting.FakeAuthorizer{Tag: tag})
+	c.Assert(err, jc.ErrorIsNil)
+	return api
+}
+
+func (s *userManagerSuite) TestUserSessions(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	barb := s.Factory.MakeUser(c, &factory.UserParams{Name: "barb"})
+	session := s.addSession(c, alex.UserTag())
+	api := s.newAPI(c, s.AdminUserTag(c))
+
+	results, err := api.UserSessions(params.Entities{
+		Entities: []params.Entity{{alex.Tag().String()}, {barb.Tag().String()}, {"user-unknown"}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, jc.DeepEquals, params.UserSessionsResults{
+		Results: []params.UserSessionsResult{{
+			Sessions: []params.UserSession{{
+				UserTag:       "user-alex@local",
+				EnvironTag:    s.State.EnvironTag().String(),
+				RemoteAddress: "10.0.0.1:1234",
+				LoginTime:     session.LoginTime(),
+				APIServer:     "machine-0",
+				ConnectionId:  1,
+			}},
+		}, {}, {
+			Error: &params.Error{
+				Message: "permission denied",
+				Code:    params.CodeUnauthorized,
+			},
+		}},
+	})
+}
+
+func (s *userManagerSuite) TestUserSessionsForOther(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	barb := s.Factory.MakeUser(c, &factory.UserParams{Name: "barb"})
+	s.addSession(c, alex.UserTag())
+	api := s.newAPI(c, barb.Tag())
+
+	results, err := api.UserSessions(params.Entities{
+		Entities: []params.Entity{{alex.Tag().String()}, {barb.Tag().String()}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 2)
+	c.Assert(results.Results[0].Error, gc.DeepEquals, &params.Error{
+		Message: "permission denied",
+		Code:    params.CodeUnauthorized,
+	})
+	c.Assert(results.Results[1], jc.DeepEquals, params.UserSessionsResult{})
+}
+
+func (s *userManagerSuite) TestCloseUserSessions(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	s.addSession(c, alex.UserTag())
+	api := s.newAPI(c, s.AdminUserTag(c))
+
+	results, err := api.CloseUserSessions(params.Entities{
+		Entities: []params.Entity{{alex.Tag().String()}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, jc.DeepEquals, params.CloseUserSessionsResults{
+		Results: []params.CloseUserSessionsResult{{Closed: 1}},
+	})
+	s.assertClosing(c, alex.UserTag(), true)
+}
+
+func (s *userManagerSuite) TestCloseUserSessionsForSelf(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	s.addSession(c, alex.UserTag())
+	api := s.newAPI(c, alex.Tag())
+
+	results, err := api.CloseUserSessions(params.Entities{
+		Entities: []params.Entity{{alex.Tag().String()}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results[0].Closed, gc.Equals, 1)
+	s.assertClosing(c, alex.UserTag(), true)
+}
+
+func (s *userManagerSuite) TestCloseUserSessionsForOther(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	barb := s.Factory.MakeUser(c, &factory.UserParams{Name: "barb"})
+	s.addSession(c, alex.UserTag())
+	api := s.newAPI(c, barb.Tag())
+
+	results, err := api.CloseUserSessions(params.Entities{
+		Entities: []params.Entity{{alex.Tag().String()}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results[0].Error, gc.DeepEquals, &params.Error{
+		Message: "permission denied",
+		Code:    params.CodeUnauthorized,
+	})
+	s.assertClosing(c, alex.UserTag(), false)
+}
+
+func (s *userManagerSuite) assertClosing(c *gc.C, user names.UserTag, closing bool) {
+	sessions, err := s.State.UserSessions(user)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(sessions, gc.HasLen, 1)
+	c.Assert(sessions[0].Closing(), gc.Equals, closing)
+}
+
+func (s *userManagerSuite) TestAddUserSSHKeys(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	args := params.UserSSHKeys{
+		UserTag: alex.Tag().String(),
+		Keys:    []string{sshtesting.ValidKeyOne.Key, "invalid-key"},
+	}
+	results, err := s.usermanager.AddUserSSHKeys(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.DeepEquals, params.ErrorResults{
+		Results: []params.ErrorResult{
+			{Error: nil},
+			{Error: &params.Error{
+				Message: `cannot add ssh keys to user "alex": ssh key "invalid-key" not valid`,
+			}},
+		}})
+
+	userKeys, err := s.usermanager.UserSSHKeys(params.Entities{
+		[]params.Entity{{alex.Tag().String()}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(userKeys, gc.DeepEquals, params.StringsResults{
+		Results: []params.StringsResult{{Result: []string{sshtesting.ValidKeyOne.Key}}},
+	})
+}
+
+func (s *userManagerSuite) TestRemoveUserSSHKeys(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	err := alex.AddSSHKeys(sshtesting.ValidKeyOne.Key, sshtesting.ValidKeyTwo.Key)
+	c.Assert(err, jc.ErrorIsNil)
+
+	args := params.UserSSHKeys{
+		UserTag: alex.Tag().String(),
+		Keys:    []string{sshtesting.ValidKeyOne.Fingerprint, "missing"},
+	}
+	results, err := s.usermanager.RemoveUserSSHKeys(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.DeepEquals, params.ErrorResults{
+		Results: []params.ErrorResult{
+			{Error: nil},
+			{Error: &params.Error{
+				Message: `cannot remove ssh keys from user "alex": ssh key "missing" not found`,
+				Code:    params.CodeNotFound,
+			}},
+		}})
+	err = alex.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(alex.SSHKeys(), jc.DeepEquals, []string{sshtesting.ValidKeyTwo.Key})
+}
+
+func (s *userManagerSuite) TestUserSSHKeysForSelf(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	usermanager, err := usermanager.NewUserManagerAPI(
+		s.State, nil, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	args := params.UserSSHKeys{
+		UserTag: alex.Tag().String(),
+		Keys:    []string{sshtesting.ValidKeyOne.Key},
+	}
+	results, err := usermanager.AddUserSSHKeys(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.Results, gc.HasLen, 1)
+	c.Assert(results.Results[0].Error, gc.IsNil)
+
+	err = alex.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(alex.SSHKeys(), jc.DeepEquals, []string{sshtesting.ValidKeyOne.Key})
+}
+
+func (s *userManagerSuite) TestUserSSHKeysForOther(c *gc.C) {
+	alex := s.Factory.MakeUser(c, &factory.UserParams{Name: "alex"})
+	barb := s.Factory.MakeUser(c, &factory.UserParams{Name: "barb"})
+	usermanager, err := usermanager.NewUserManagerAPI(
+		s.State, nil, apiservertesting.FakeAuthorizer{Tag: alex.Tag()})
+	c.Assert(err, jc.ErrorIsNil)
+
+	args := params.UserSSHKeys{
+		UserTag: barb.Tag().String(),
+		Keys:    []string{sshtesting.ValidKeyOne.Key},
+	}
+	_, err = usermanager.AddUserSSHKeys(args)
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+
+	userKeys, err := usermanager.UserSSHKeys(params.Entities{
+		[]params.Entity{{barb.Tag().String()}},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(userKeys.Results[0].Error, gc.ErrorMatches, "permission denied")
+
+	err = barb.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(barb.SSHKeys(), gc.HasLen, 0)
+}
@@ -0,0 +1,77 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package envcmd
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	goyaml "gopkg.in/yaml.v1"
+
+	"github.com/juju/juju/environs/configstore"
+)
+
+// ConnectionFilePath returns the absolute path of a connection file,
+// adding the .jenv suffix if it is missing.
+func ConnectionFilePath(ctx *cmd.Context, path string) string {
+	if !strings.HasSuffix(path, ".jenv") {
+		path = path + ".jenv"
+	}
+	return ctx.AbsPath(path)
+}
+
+// WriteConnectionFile writes a connection file to outPath that allows
+// the given user to connect to the named environment with the given
+// password. The file holds everything needed to connect, so that it
+// can be handed to the user and imported with "juju environment import".
+func WriteConnectionFile(envName, user, password, outPath string) error {
+	info, err := ConnectionInfoForName(envName)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	endpoint := info.APIEndpoint()
+	outputInfo := configstore.EnvironInfoData{
+		User:            user,
+		Password:        password,
+		EnvironUUID:     endpoint.EnvironUUID,
+		StateServers:    endpoint.Addresses,
+		ServerHostnames: endpoint.Hostnames,
+		CACert:          endpoint.CACert,
+	}
+	data, err := cmd.FormatYaml(outputInfo)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// The file holds a password, so only the owner may read it.
+	if err := ioutil.WriteFile(outPath, data, 0600); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// ReadConnectionFile reads a connection file written by
+// WriteConnectionFile.
+func ReadConnectionFile(path string) (*configstore.EnvironInfoData, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var info configstore.EnvironInfoData
+	if err := goyaml.Unmarshal(data, &info); err != nil {
+		return nil, errors.Annotatef(err, "cannot parse connection file %q", path)
+	}
+	switch {
+	case info.User == "":
+		return nil, errors.Errorf("connection file %q has no user", path)
+	case info.Password == "":
+		return nil, errors.Errorf("connection file %q has no password", path)
+	case len(info.StateServers) == 0:
+		return nil, errors.Errorf("connection file %q has no state servers", path)
+	case info.CACert == "":
+		return nil, errors.Errorf("connection file %q has no CA certificate", path)
+	}
+	return &info, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package envcmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/environs/configstore"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type ConnectionFileSuite struct {
+	coretesting.FakeJujuHomeSuite
+}
+
+var _ = gc.Suite(&ConnectionFileSuite{})
+
+func (s *ConnectionFileSuite) SetUpTest(c *gc.C) {
+	s.FakeJujuHomeSuite.SetUpTest(c)
+	store := configstore.NewMem()
+	s.PatchValue(envcmd.GetConfigStore, func() (configstore.Storage, error) {
+		return store, nil
+	})
+	info := store.CreateInfo("env-name")
+	info.SetAPICredentials(configstore.APICredentials{
+		User:     "admin",
+		Password: "adminpass",
+	})
+	info.SetAPIEndpoint(configstore.APIEndpoint{
+		Addresses:   []string{"0.1.2.3:17070"},
+		Hostnames:   []string{"foo.invalid:17070"},
+		CACert:      "certificated",
+		EnvironUUID: "fake-uuid",
+	})
+	err := info.Write()
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *ConnectionFileSuite) TestWriteAndRead(c *gc.C) {
+	path := filepath.Join(c.MkDir(), "bob.jenv")
+	err := envcmd.WriteConnectionFile("env-name", "bob", "bobpass", path)
+	c.Assert(err, jc.ErrorIsNil)
+
+	fileInfo, err := os.Stat(path)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(fileInfo.Mode().Perm(), gc.Equals, os.FileMode(0600))
+
+	info, err := envcmd.ReadConnectionFile(path)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info, jc.DeepEquals, &configstore.EnvironInfoData{
+		User:            "bob",
+		Password:        "bobpass",
+		EnvironUUID:     "fake-uuid",
+		StateServers:    []string{"0.1.2.3:17070"},
+		ServerHostnames: []string{"foo.invalid:17070"},
+		CACert:          "certificated",
+	})
+}
+
+func (s *ConnectionFileSuite) TestWriteUnknownEnvironment(c *gc.C) {
+	path := filepath.Join(c.MkDir(), "bob.jenv")
+	err := envcmd.WriteConnectionFile("no-such-env", "bob", "bobpass", path)
+	c.Assert(err, gc.ErrorMatches, `environment "no-such-env" not found`)
+}
+
+func (s *ConnectionFileSuite) TestReadIncompleteFile(c *gc.C) {
+	path := filepath.Join(c.MkDir(), "bob.jenv")
+	err := ioutil.WriteFile(path, []byte("user: bob\nstate-servers: [0.1.2.3:17070]\n"), 0600)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = envcmd.ReadConnectionFile(path)
+	c.Assert(err, gc.ErrorMatches, `connection file ".*bob.jenv" has no password`)
+}
@@ -12,6 +12,15 @@ import (
 	"github.com/juju/juju/cmd/juju/block"
 )
 
+const addRelationDoc = `
+Adds a relation between two service endpoints. One of the services may
+run in another environment hosted by the same state server, if it offers
+the endpoint (see "juju help offer"); it is named by prefixing the
+service with the name of its environment:
+
+    juju add-relation wordpress:db db-env.mysql
+`
+
 // AddRelationCommand adds a relation between two service endpoints.
 type AddRelationCommand struct {
 	envcmd.EnvCommandBase
@@ -23,6 +32,7 @@ func (c *AddRelationCommand) Info() *cmd.Info {
 		Name:    "add-relation",
 		Args:    "<service1>[:<relation name1>] <service2>[:<relation name2>]",
 		Purpose: "add a relation between two services",
+		Doc:     addRelationDoc,
 	}
 }
 
@@ -28,6 +28,7 @@ func NewSuperCommand() cmd.Command {
 	})
 	usercmd.Register(envcmd.Wrap(&DeleteCommand{}))
 	usercmd.Register(envcmd.Wrap(&ListCommand{}))
+	usercmd.Register(envcmd.Wrap(&PrimeCommand{}))
 	return usercmd
 }
 
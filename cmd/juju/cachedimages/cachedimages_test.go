@@ -21,6 +21,7 @@ var expectedCachedImagesCommmandNames = []string{
 	"delete",
 	"help",
 	"list",
+	"prime",
 }
 
 func (s *cachedImagesSuite) TestHelp(c *gc.C) {
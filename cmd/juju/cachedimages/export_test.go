@@ -4,6 +4,7 @@
 package cachedimages
 
 var (
-	GetListImagesAPI  = &getListImagesAPI
-	GetDeleteImageAPI = &getDeleteImageAPI
+	GetListImagesAPI    = &getListImagesAPI
+	GetDeleteImageAPI   = &getDeleteImageAPI
+	GetPrimeTemplateAPI = &getPrimeTemplateAPI
 )
@@ -0,0 +1,91 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cachedimages
+
+import (
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	"launchpad.net/gnuflag"
+)
+
+const PrimeCommandDoc = `
+Build a container template on a machine ahead of its first container.
+
+LXC containers are cloned from a template that is otherwise built when
+the first container of a series is deployed to a machine, which makes
+that first deployment slow. Priming builds the template in advance, or
+rebuilds it if it already exists. The machine agent builds the template
+in the background; "juju status" shows its age and any build error.
+
+Templates are identified by:
+  Kind         eg "lxc" (the only kind currently supported)
+  Series       eg "trusty" (defaults to the series of the machine)
+
+Templates are also rebuilt once they are older than the number of hours
+in the "lxc-template-max-age" environment setting, if it is set.
+
+Examples:
+
+  # Build the trusty lxc template on machine 1.
+  juju cached-images prime --machine 1 --series trusty
+`
+
+// PrimeCommand asks for a container template to be built on a machine.
+type PrimeCommand struct {
+	CachedImagesCommandBase
+	Kind, Series, Machine string
+}
+
+// Info implements Command.Info.
+func (c *PrimeCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "prime",
+		Purpose: "build a container template on a machine",
+		Doc:     PrimeCommandDoc,
+	}
+}
+
+// SetFlags implements Command.SetFlags.
+func (c *PrimeCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.CachedImagesCommandBase.SetFlags(f)
+	f.StringVar(&c.Kind, "kind", "lxc", "the template kind to build eg lxc")
+	f.StringVar(&c.Series, "series", "", "the series of the template to build eg trusty")
+	f.StringVar(&c.Machine, "machine", "", "the machine to build the template on")
+}
+
+// Init implements Command.Init.
+func (c *PrimeCommand) Init(args []string) (err error) {
+	if c.Machine == "" {
+		return errors.New("machine must be specified")
+	}
+	if !names.IsValidMachine(c.Machine) {
+		return errors.Errorf("invalid machine %q", c.Machine)
+	}
+	if c.Kind == "" {
+		return errors.New("template kind must be specified")
+	}
+	return cmd.CheckEmpty(args)
+}
+
+// PrimeTemplateAPI defines the imagemanager API methods that the prime command uses.
+type PrimeTemplateAPI interface {
+	PrimeTemplate(machineTag, kind, series string) error
+	Close() error
+}
+
+var getPrimeTemplateAPI = func(p *PrimeCommand) (PrimeTemplateAPI, error) {
+	return p.NewImagesManagerClient()
+}
+
+// Run implements Command.Run.
+func (c *PrimeCommand) Run(ctx *cmd.Context) (err error) {
+	client, err := getPrimeTemplateAPI(c)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return client.PrimeTemplate(names.NewMachineTag(c.Machine).String(), c.Kind, c.Series)
+}
@@ -0,0 +1,86 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package cachedimages_test
+
+import (
+	"github.com/juju/cmd"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/cmd/juju/cachedimages"
+	"github.com/juju/juju/testing"
+)
+
+type primeTemplateCommandSuite struct {
+	testing.FakeJujuHomeSuite
+	mockAPI *fakePrimeTemplateAPI
+}
+
+var _ = gc.Suite(&primeTemplateCommandSuite{})
+
+type fakePrimeTemplateAPI struct {
+	machineTag string
+	kind       string
+	series     string
+}
+
+func (*fakePrimeTemplateAPI) Close() error {
+	return nil
+}
+
+func (f *fakePrimeTemplateAPI) PrimeTemplate(machineTag, kind, series string) error {
+	f.machineTag = machineTag
+	f.kind = kind
+	f.series = series
+	return nil
+}
+
+func (s *primeTemplateCommandSuite) SetUpTest(c *gc.C) {
+	s.FakeJujuHomeSuite.SetUpTest(c)
+	s.mockAPI = &fakePrimeTemplateAPI{}
+	s.PatchValue(cachedimages.GetPrimeTemplateAPI, func(c *cachedimages.PrimeCommand) (cachedimages.PrimeTemplateAPI, error) {
+		return s.mockAPI, nil
+	})
+}
+
+func runPrimeCommand(c *gc.C, args ...string) (*cmd.Context, error) {
+	return testing.RunCommand(c, envcmd.Wrap(&cachedimages.PrimeCommand{}), args...)
+}
+
+func (s *primeTemplateCommandSuite) TestPrimeTemplate(c *gc.C) {
+	_, err := runPrimeCommand(c, "--machine", "1/lxc/0", "--series", "trusty")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.mockAPI.machineTag, gc.Equals, "machine-1-lxc-0")
+	c.Assert(s.mockAPI.kind, gc.Equals, "lxc")
+	c.Assert(s.mockAPI.series, gc.Equals, "trusty")
+}
+
+func (s *primeTemplateCommandSuite) TestPrimeTemplateDefaultSeries(c *gc.C) {
+	_, err := runPrimeCommand(c, "--machine", "2")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.mockAPI.machineTag, gc.Equals, "machine-2")
+	c.Assert(s.mockAPI.kind, gc.Equals, "lxc")
+	c.Assert(s.mockAPI.series, gc.Equals, "")
+}
+
+func (*primeTemplateCommandSuite) TestTooManyArgs(c *gc.C) {
+	_, err := runPrimeCommand(c, "--machine", "1", "bad")
+	c.Assert(err, gc.ErrorMatches, `unrecognized args: \["bad"\]`)
+}
+
+func (*primeTemplateCommandSuite) TestMachineRequired(c *gc.C) {
+	_, err := runPrimeCommand(c, "--series", "trusty")
+	c.Assert(err, gc.ErrorMatches, `machine must be specified`)
+}
+
+func (*primeTemplateCommandSuite) TestInvalidMachine(c *gc.C) {
+	_, err := runPrimeCommand(c, "--machine", "foo")
+	c.Assert(err, gc.ErrorMatches, `invalid machine "foo"`)
+}
+
+func (*primeTemplateCommandSuite) TestKindRequired(c *gc.C) {
+	_, err := runPrimeCommand(c, "--machine", "1", "--kind", "")
+	c.Assert(err, gc.ErrorMatches, `template kind must be specified`)
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/juju/juju/constraints"
 	"github.com/juju/juju/environs/config"
 	"github.com/juju/juju/juju/osenv"
+	"github.com/juju/juju/network"
 )
 
 type DeployCommand struct {
@@ -31,6 +32,8 @@ type DeployCommand struct {
 	Config       cmd.FileVar
 	Constraints  constraints.Value
 	Networks     string
+	Bindings     map[string]string
+	BindToSpaces string
 	BumpRevision bool   // Remove this once the 1.16 support is dropped.
 	RepoPath     string // defaults to JUJU_REPOSITORY
 }
@@ -103,6 +106,16 @@ to configure on it. The --networks argument instructs juju to add all the
 networks specified with it to all new machines deployed to host units of
 the service. Not supported on all providers.
 
+The endpoints of the service can be bound to network spaces with the
+--bind argument, which takes a space-separated list of bindings. Each
+binding is either <endpoint>=<space>, binding the named endpoint to the
+space, or just <space>, binding all the endpoints not bound explicitly.
+Units use their address in the bound space when talking over an endpoint.
+
+   juju deploy mysql --bind "db=storage public"
+   (bind the "db" endpoint of mysql to the "storage" space, and all its
+    other endpoints to the "public" space)
+
 See Also:
    juju help constraints
    juju help set-constraints
@@ -126,6 +139,7 @@ func (c *DeployCommand) SetFlags(f *gnuflag.FlagSet) {
 	f.Var(&c.Config, "config", "path to yaml-formatted service config")
 	f.Var(constraints.ConstraintsValue{Target: &c.Constraints}, "constraints", "set service constraints")
 	f.StringVar(&c.Networks, "networks", "", "bind the service to specific networks")
+	f.StringVar(&c.BindToSpaces, "bind", "", "bind the service endpoints to network spaces")
 	f.StringVar(&c.RepoPath, "repository", os.Getenv(osenv.JujuRepositoryEnvKey), "local charm repository")
 }
 
@@ -147,6 +161,11 @@ func (c *DeployCommand) Init(args []string) error {
 	default:
 		return cmd.CheckEmpty(args[2:])
 	}
+	bindings, err := parseBindings(c.BindToSpaces)
+	if err != nil {
+		return err
+	}
+	c.Bindings = bindings
 	return c.UnitCommandBase.Init(args)
 }
 
@@ -230,6 +249,22 @@ func (c *DeployCommand) Run(ctx *cmd.Context) error {
 			return err
 		}
 	}
+	if len(c.Bindings) > 0 {
+		err = client.ServiceDeployWithBindings(
+			curl.String(),
+			serviceName,
+			numUnits,
+			string(configYAML),
+			c.Constraints,
+			c.ToMachineSpec,
+			requestedNetworks,
+			c.Bindings,
+		)
+		if params.IsCodeNotImplemented(err) {
+			return errors.New("cannot use --bind: not supported by the API server")
+		}
+		return block.ProcessBlockedError(err, block.BlockChange)
+	}
 	err = client.ServiceDeployWithNetworks(
 		curl.String(),
 		serviceName,
@@ -302,6 +337,37 @@ func parseNetworks(networksValue string) []string {
 	return networks
 }
 
+// parseBindings returns the endpoint bindings given by the
+// space-separated value of the --bind argument. Each binding is either
+// <endpoint>=<space>, or just <space> for the endpoints not bound
+// explicitly, which is returned under the empty endpoint name.
+func parseBindings(bindValue string) (map[string]string, error) {
+	var bindings map[string]string
+	for _, part := range strings.Fields(bindValue) {
+		endpoint, space := "", part
+		if i := strings.Index(part, "="); i >= 0 {
+			endpoint, space = part[:i], part[i+1:]
+			if endpoint == "" {
+				return nil, fmt.Errorf("invalid --bind parameter %q: endpoint name must be specified", part)
+			}
+		}
+		if !network.IsValidSpace(space) {
+			return nil, fmt.Errorf("invalid --bind parameter %q: %q is not a valid space name", part, space)
+		}
+		if bindings == nil {
+			bindings = make(map[string]string)
+		}
+		if _, ok := bindings[endpoint]; ok {
+			if endpoint == "" {
+				return nil, fmt.Errorf("invalid --bind parameter %q: default space already specified", part)
+			}
+			return nil, fmt.Errorf("invalid --bind parameter %q: endpoint %q already bound", part, endpoint)
+		}
+		bindings[endpoint] = space
+	}
+	return bindings, nil
+}
+
 // networkNamesToTags returns the given network names converted to
 // tags, or an error.
 func networkNamesToTags(networks []string) ([]string, error) {
@@ -63,6 +63,18 @@ var initErrorTests = []struct {
 	}, {
 		args: []string{"craziness", "burble1", "--constraints", "gibber=plop"},
 		err:  `invalid value "gibber=plop" for flag --constraints: unknown constraint "gibber"`,
+	}, {
+		args: []string{"craziness", "burble1", "--bind", "db=Storage"},
+		err:  `invalid --bind parameter "db=Storage": "Storage" is not a valid space name`,
+	}, {
+		args: []string{"craziness", "burble1", "--bind", "=storage"},
+		err:  `invalid --bind parameter "=storage": endpoint name must be specified`,
+	}, {
+		args: []string{"craziness", "burble1", "--bind", "db=storage db=public"},
+		err:  `invalid --bind parameter "db=public": endpoint "db" already bound`,
+	}, {
+		args: []string{"craziness", "burble1", "--bind", "storage public"},
+		err:  `invalid --bind parameter "public": default space already specified`,
 	},
 }
 
@@ -200,6 +212,28 @@ func (s *DeploySuite) TestNetworks(c *gc.C) {
 	c.Assert(cons, jc.DeepEquals, constraints.MustParse("mem=2G cpu-cores=2 networks=net1,net0,^net3,^net4"))
 }
 
+func (s *DeploySuite) TestBindings(c *gc.C) {
+	_, err := s.State.AddSpace("storage", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.State.AddSpace("public", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	testcharms.Repo.CharmArchivePath(s.SeriesPath, "dummy")
+	err = runDeploy(c, "local:dummy", "--bind", "juju-info=storage public")
+	c.Assert(err, jc.ErrorIsNil)
+	curl := charm.MustParseURL("local:trusty/dummy-1")
+	service, _ := s.AssertService(c, "dummy", curl, 1, 0)
+	c.Assert(service.EndpointBindings(), jc.DeepEquals, map[string]string{
+		"juju-info": "storage",
+		"":          "public",
+	})
+}
+
+func (s *DeploySuite) TestBindingsUnknownSpace(c *gc.C) {
+	testcharms.Repo.CharmArchivePath(s.SeriesPath, "dummy")
+	err := runDeploy(c, "local:dummy", "--bind", "storage")
+	c.Assert(err, gc.ErrorMatches, `.*cannot bind endpoint "": space "storage" not found`)
+}
+
 func (s *DeploySuite) TestSubordinateConstraints(c *gc.C) {
 	testcharms.Repo.CharmArchivePath(s.SeriesPath, "logging")
 	err := runDeploy(c, "local:logging", "--constraints", "mem=1G")
@@ -0,0 +1,196 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package environment
+
+import (
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	"github.com/juju/utils/keyvalues"
+	yaml "gopkg.in/yaml.v1"
+	"launchpad.net/gnuflag"
+
+	"github.com/juju/juju/api/environmentmanager"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/cmd/juju/block"
+	"github.com/juju/juju/environs/configstore"
+)
+
+const createEnvHelpDoc = `
+Create another environment hosted by the state server of the current
+environment. The new environment shares the state server's machines for
+its own state, but has its own configuration, machines, services and
+agents.
+
+Configuration values not given are taken from the state server
+environment, apart from its name, its UUID and any blocks. Values may be
+given in a yaml file with --config, and overridden by key=value pairs on
+the command line.
+
+If the new environment is owned by the current user, its connection
+information is saved so that it can be used straight away with
+"juju switch <name>".
+
+Examples:
+  juju create-environment sandbox
+  juju create-environment sandbox --config sandbox.yaml
+  juju create-environment sandbox --owner bob default-series=trusty
+
+See Also:
+  juju switch
+`
+
+// CreateCommand creates an environment hosted by the state server of
+// the current environment.
+type CreateCommand struct {
+	envcmd.EnvCommandBase
+	api        CreateEnvironmentAPI
+	name       string
+	owner      string
+	configFile cmd.FileVar
+	values     map[string]string
+}
+
+// Info implements Command.Info.
+func (c *CreateCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "create",
+		Args:    "<name> [key=[value] ...]",
+		Purpose: "create an environment hosted by the current state server",
+		Doc:     createEnvHelpDoc,
+	}
+}
+
+// SetFlags implements Command.SetFlags.
+func (c *CreateCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.StringVar(&c.owner, "owner", "", "the user that owns the new environment; defaults to the current user")
+	f.Var(&c.configFile, "config", "path to yaml-formatted configuration file")
+}
+
+// Init implements Command.Init.
+func (c *CreateCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return errors.New("environment name is required")
+	}
+	c.name, args = args[0], args[1:]
+	if c.owner != "" && !names.IsValidUser(c.owner) {
+		return errors.Errorf("%q is not a valid user", c.owner)
+	}
+	values, err := keyvalues.Parse(args, true)
+	if err != nil {
+		return err
+	}
+	for key := range values {
+		switch key {
+		case "name", "uuid", "type":
+			return errors.Errorf("%s cannot be set for a new environment", key)
+		}
+	}
+	c.values = values
+	return nil
+}
+
+// CreateEnvironmentAPI defines the methods on the environment manager
+// API that the create command uses.
+type CreateEnvironmentAPI interface {
+	Close() error
+	CreateEnvironment(owner string, account, config map[string]interface{}) (params.Environment, error)
+}
+
+func (c *CreateCommand) getAPI() (CreateEnvironmentAPI, error) {
+	if c.api != nil {
+		return c.api, nil
+	}
+	root, err := c.NewAPIRoot()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return environmentmanager.NewClient(root), nil
+}
+
+// Run implements Command.Run.
+func (c *CreateCommand) Run(ctx *cmd.Context) error {
+	store, err := configstore.Default()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// Check the name is free before creating the environment, so we
+	// can save its connection information afterwards.
+	if _, err := store.ReadInfo(c.name); err == nil {
+		return errors.Errorf("environment %q already exists locally", c.name)
+	} else if !errors.IsNotFound(err) {
+		return errors.Trace(err)
+	}
+
+	creds, err := c.ConnectionCredentials()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	owner := c.owner
+	if owner == "" {
+		owner = creds.User
+	}
+
+	attrs, err := c.getConfigValues(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	client, err := c.getAPI()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	env, err := client.CreateEnvironment(owner, nil, attrs)
+	if err != nil {
+		return block.ProcessBlockedError(err, block.BlockChange)
+	}
+	ctx.Infof("created environment %q", env.Name)
+
+	if names.NewUserTag(owner) != names.NewUserTag(creds.User) {
+		// Only the owner can log in to the new environment.
+		ctx.Infof("share it with the owner using their own connection details")
+		return nil
+	}
+	endpoint, err := c.ConnectionEndpoint(false)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	endpoint.EnvironUUID = env.UUID
+	info := store.CreateInfo(c.name)
+	info.SetAPIEndpoint(endpoint)
+	info.SetAPICredentials(creds)
+	if err := info.Write(); err != nil {
+		return errors.Annotate(err, "cannot save environment information")
+	}
+	ctx.Infof("use \"juju switch %s\" to use it", c.name)
+	return nil
+}
+
+// getConfigValues returns the configuration of the new environment from
+// the config file and the command line.
+func (c *CreateCommand) getConfigValues(ctx *cmd.Context) (map[string]interface{}, error) {
+	attrs := make(map[string]interface{})
+	if c.configFile.Path != "" {
+		data, err := c.configFile.Read(ctx)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err := yaml.Unmarshal(data, &attrs); err != nil {
+			return nil, errors.Annotate(err, "cannot parse config file")
+		}
+	}
+	for key, value := range c.values {
+		attrs[key] = value
+	}
+	attrs["name"] = c.name
+	for _, key := range []string{"uuid", "type"} {
+		if _, ok := attrs[key]; ok {
+			return nil, errors.Errorf("%s cannot be set for a new environment", key)
+		}
+	}
+	return attrs, nil
+}
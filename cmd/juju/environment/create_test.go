@@ -0,0 +1,164 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package environment_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/cmd/juju/environment"
+	"github.com/juju/juju/environs/configstore"
+	"github.com/juju/juju/juju/osenv"
+	"github.com/juju/juju/testing"
+)
+
+type CreateSuite struct {
+	testing.FakeJujuHomeSuite
+	fake  *fakeCreateAPI
+	store configstore.Storage
+}
+
+var _ = gc.Suite(&CreateSuite{})
+
+func (s *CreateSuite) SetUpTest(c *gc.C) {
+	s.FakeJujuHomeSuite.SetUpTest(c)
+	s.fake = &fakeCreateAPI{
+		env: params.Environment{
+			Name:     "test-env",
+			UUID:     "fake-uuid",
+			OwnerTag: "user-ignored-for-now",
+		},
+	}
+	store := configstore.NewMem()
+	s.store = store
+	s.PatchValue(&configstore.Default, func() (configstore.Storage, error) {
+		return store, nil
+	})
+	os.Setenv(osenv.JujuEnvEnvKey, "testing")
+	info := store.CreateInfo("testing")
+	info.SetAPIEndpoint(configstore.APIEndpoint{
+		Addresses:   []string{"127.0.0.1:12345"},
+		Hostnames:   []string{"localhost:12345"},
+		CACert:      testing.CACert,
+		EnvironUUID: "env-uuid",
+	})
+	info.SetAPICredentials(configstore.APICredentials{
+		User:     "bob",
+		Password: "sekrit",
+	})
+	err := info.Write()
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *CreateSuite) run(c *gc.C, args ...string) (*cmd.Context, error) {
+	command := environment.NewCreateCommand(s.fake)
+	return testing.RunCommand(c, envcmd.Wrap(command), args...)
+}
+
+func (s *CreateSuite) TestInit(c *gc.C) {
+	for i, test := range []struct {
+		args       []string
+		errorMatch string
+	}{
+		{
+			errorMatch: "environment name is required",
+		}, {
+			args:       []string{"new-env", "special"},
+			errorMatch: `expected "key=value", got "special"`,
+		}, {
+			args:       []string{"new-env", "uuid=foo"},
+			errorMatch: "uuid cannot be set for a new environment",
+		}, {
+			args:       []string{"new-env", "--owner", "not a user"},
+			errorMatch: `"not a user" is not a valid user`,
+		},
+	} {
+		c.Logf("test %d", i)
+		err := testing.InitCommand(&environment.CreateCommand{}, test.args)
+		c.Check(err, gc.ErrorMatches, test.errorMatch)
+	}
+}
+
+func (s *CreateSuite) TestCreate(c *gc.C) {
+	_, err := s.run(c, "test-env", "default-series=trusty")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.fake.owner, gc.Equals, "bob")
+	c.Assert(s.fake.config, jc.DeepEquals, map[string]interface{}{
+		"name":           "test-env",
+		"default-series": "trusty",
+	})
+
+	// The new environment can be used straight away.
+	info, err := s.store.ReadInfo("test-env")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.APIEndpoint().EnvironUUID, gc.Equals, "fake-uuid")
+	c.Assert(info.APIEndpoint().Addresses, jc.DeepEquals, []string{"127.0.0.1:12345"})
+	c.Assert(info.APICredentials(), jc.DeepEquals, configstore.APICredentials{
+		User:     "bob",
+		Password: "sekrit",
+	})
+}
+
+func (s *CreateSuite) TestCreateWithConfigFile(c *gc.C) {
+	path := filepath.Join(c.MkDir(), "config.yaml")
+	err := ioutil.WriteFile(path, []byte("default-series: precise\nlogging-config: <root>=DEBUG\n"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.run(c, "test-env", "--config", path, "default-series=trusty")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.fake.config, jc.DeepEquals, map[string]interface{}{
+		"name":           "test-env",
+		"default-series": "trusty",
+		"logging-config": "<root>=DEBUG",
+	})
+}
+
+func (s *CreateSuite) TestCreateForOtherOwner(c *gc.C) {
+	_, err := s.run(c, "test-env", "--owner", "alice")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.fake.owner, gc.Equals, "alice")
+
+	// Only the owner can log in to the new environment.
+	_, err = s.store.ReadInfo("test-env")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *CreateSuite) TestCreateExistingName(c *gc.C) {
+	_, err := s.run(c, "testing")
+	c.Assert(err, gc.ErrorMatches, `environment "testing" already exists locally`)
+	c.Assert(s.fake.owner, gc.Equals, "")
+}
+
+func (s *CreateSuite) TestCreateError(c *gc.C) {
+	s.fake.err = errors.New("bah humbug")
+	_, err := s.run(c, "test-env")
+	c.Assert(err, gc.ErrorMatches, "bah humbug")
+
+	_, err = s.store.ReadInfo("test-env")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+type fakeCreateAPI struct {
+	env    params.Environment
+	err    error
+	owner  string
+	config map[string]interface{}
+}
+
+func (f *fakeCreateAPI) Close() error {
+	return nil
+}
+
+func (f *fakeCreateAPI) CreateEnvironment(owner string, account, config map[string]interface{}) (params.Environment, error) {
+	f.owner = owner
+	f.config = config
+	return f.env, f.err
+}
@@ -25,8 +25,12 @@ func NewSuperCommand() cmd.Command {
 		UsagePrefix: "juju",
 		Purpose:     "manage environments",
 	})
+	environmentCmd.Register(envcmd.Wrap(&CreateCommand{}))
 	environmentCmd.Register(envcmd.Wrap(&GetCommand{}))
+	environmentCmd.Register(&ImportCommand{})
+	environmentCmd.Register(envcmd.Wrap(&MigrateCommand{}))
 	environmentCmd.Register(envcmd.Wrap(&SetCommand{}))
+	environmentCmd.Register(envcmd.Wrap(&ShareCommand{}))
 	environmentCmd.Register(envcmd.Wrap(&UnsetCommand{}))
 	return environmentCmd
 }
@@ -21,9 +21,13 @@ type EnvironmentCommandSuite struct {
 var _ = gc.Suite(&EnvironmentCommandSuite{})
 
 var expectedCommmandNames = []string{
+	"create",
 	"get",
 	"help",
+	"import",
+	"migrate",
 	"set",
+	"share",
 	"unset",
 }
 
@@ -23,3 +23,35 @@ func NewUnsetCommand(api UnsetEnvironmentAPI) *UnsetCommand {
 		api: api,
 	}
 }
+
+// NewCreateCommand returns a CreateCommand with the api provided as specified.
+func NewCreateCommand(api CreateEnvironmentAPI) *CreateCommand {
+	return &CreateCommand{
+		api: api,
+	}
+}
+
+// NewShareCommand returns a ShareCommand with the api provided as specified.
+func NewShareCommand(api ShareEnvironmentAPI) *ShareCommand {
+	return &ShareCommand{
+		api: api,
+	}
+}
+
+// NewImportCommand returns an ImportCommand with the api provided as specified.
+func NewImportCommand(api ImportEnvironmentAPI) *ImportCommand {
+	return &ImportCommand{
+		api: api,
+	}
+}
+
+// NewMigrateCommand returns a MigrateCommand with the apis provided as
+// specified.
+func NewMigrateCommand(source, target MigrateEnvironmentAPI) *MigrateCommand {
+	return &MigrateCommand{
+		api:       source,
+		targetAPI: target,
+	}
+}
+
+var MigrationPollInterval = &migrationPollInterval
@@ -0,0 +1,164 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package environment
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/utils"
+
+	"github.com/juju/juju/api/usermanager"
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/environs/configstore"
+	"github.com/juju/juju/juju"
+)
+
+const importEnvHelpDoc = `
+Import a connection file (.jenv) written by "juju user add" or
+"juju environment share", so that the environment it describes can be
+used with "juju switch".
+
+The password in a connection file is only meant to be used once. On
+import, it is replaced with a new random password that is saved locally,
+so the file can be thrown away afterwards.
+
+The environment is saved under the name given, or the name of the file
+without its .jenv suffix.
+
+Examples:
+  juju environment import bob.jenv
+  juju environment import ~/Downloads/bob.jenv staging
+
+See Also:
+  juju environment share
+  juju switch
+`
+
+// ImportCommand imports a connection file and changes the password in
+// it to one that only the importing user knows.
+type ImportCommand struct {
+	cmd.CommandBase
+	api  ImportEnvironmentAPI
+	path string
+	name string
+}
+
+// Info implements Command.Info.
+func (c *ImportCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "import",
+		Args:    "<connection file> [<name>]",
+		Purpose: "import a connection file for an environment",
+		Doc:     importEnvHelpDoc,
+	}
+}
+
+// Init implements Command.Init.
+func (c *ImportCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no connection file specified")
+	}
+	c.path, args = args[0], args[1:]
+	if len(args) > 0 {
+		c.name, args = args[0], args[1:]
+	} else {
+		c.name = strings.TrimSuffix(filepath.Base(c.path), ".jenv")
+	}
+	return cmd.CheckEmpty(args)
+}
+
+// ImportEnvironmentAPI defines the methods on the user manager API that
+// the import command uses.
+type ImportEnvironmentAPI interface {
+	Close() error
+	SetPassword(username, password string) error
+}
+
+func (c *ImportCommand) getAPI() (ImportEnvironmentAPI, error) {
+	if c.api != nil {
+		return c.api, nil
+	}
+	root, err := juju.NewAPIFromName(c.name)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return usermanager.NewClient(root), nil
+}
+
+// Run implements Command.Run.
+func (c *ImportCommand) Run(ctx *cmd.Context) error {
+	data, err := envcmd.ReadConnectionFile(ctx.AbsPath(c.path))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	store, err := configstore.Default()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if _, err := store.ReadInfo(c.name); err == nil {
+		return errors.Errorf("environment %q already exists locally", c.name)
+	} else if !errors.IsNotFound(err) {
+		return errors.Trace(err)
+	}
+	password, err := utils.RandomPassword()
+	if err != nil {
+		return errors.Annotate(err, "failed to generate random password")
+	}
+
+	// Save the connection file as it is, so that we can connect with
+	// the one-time password.
+	info := store.CreateInfo(c.name)
+	info.SetAPIEndpoint(configstore.APIEndpoint{
+		Addresses:   data.StateServers,
+		Hostnames:   data.ServerHostnames,
+		CACert:      data.CACert,
+		EnvironUUID: data.EnvironUUID,
+	})
+	info.SetAPICredentials(configstore.APICredentials{
+		User:     data.User,
+		Password: data.Password,
+	})
+	if err := info.Write(); err != nil {
+		return errors.Annotate(err, "cannot save environment information")
+	}
+	if err := c.changePassword(data.User, password); err != nil {
+		if err := info.Destroy(); err != nil {
+			logger.Errorf("cannot remove environment information: %v", err)
+		}
+		return errors.Trace(err)
+	}
+
+	info, err = store.ReadInfo(c.name)
+	if err == nil {
+		info.SetAPICredentials(configstore.APICredentials{
+			User:     data.User,
+			Password: password,
+		})
+		err = info.Write()
+	}
+	if err != nil {
+		logger.Errorf("cannot save the new password, you will need to edit your environment file by hand to specify the password: %q", password)
+		return errors.Annotate(err, "cannot save new password")
+	}
+	fmt.Fprintf(ctx.Stdout, "environment %q imported\n", c.name)
+	ctx.Infof("use \"juju switch %s\" to use it", c.name)
+	return nil
+}
+
+// changePassword replaces the one-time password of the user.
+func (c *ImportCommand) changePassword(user, password string) error {
+	client, err := c.getAPI()
+	if err != nil {
+		return errors.Annotate(err, "cannot connect to environment")
+	}
+	defer client.Close()
+	if err := client.SetPassword(user, password); err != nil {
+		return errors.Annotate(err, "cannot change password")
+	}
+	return nil
+}
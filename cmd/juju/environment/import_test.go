@@ -0,0 +1,143 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package environment_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/cmd/juju/environment"
+	"github.com/juju/juju/environs/configstore"
+	"github.com/juju/juju/testing"
+)
+
+type ImportSuite struct {
+	testing.FakeJujuHomeSuite
+	fake  *fakeImportAPI
+	store configstore.Storage
+	path  string
+}
+
+var _ = gc.Suite(&ImportSuite{})
+
+const connectionFile = `
+user: bob
+password: one-time
+environ-uuid: env-uuid
+state-servers: [127.0.0.1:12345]
+server-hostnames: [localhost:12345]
+ca-cert: certificated
+`
+
+func (s *ImportSuite) SetUpTest(c *gc.C) {
+	s.FakeJujuHomeSuite.SetUpTest(c)
+	s.fake = &fakeImportAPI{}
+	store := configstore.NewMem()
+	s.store = store
+	s.PatchValue(&configstore.Default, func() (configstore.Storage, error) {
+		return store, nil
+	})
+	s.path = filepath.Join(c.MkDir(), "bob.jenv")
+	err := ioutil.WriteFile(s.path, []byte(connectionFile), 0600)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *ImportSuite) run(c *gc.C, args ...string) (*cmd.Context, error) {
+	command := environment.NewImportCommand(s.fake)
+	return testing.RunCommand(c, command, args...)
+}
+
+func (s *ImportSuite) TestInit(c *gc.C) {
+	for i, test := range []struct {
+		args       []string
+		errorMatch string
+	}{
+		{
+			errorMatch: "no connection file specified",
+		}, {
+			args:       []string{"bob.jenv", "staging", "extra"},
+			errorMatch: `unrecognized args: \["extra"\]`,
+		},
+	} {
+		c.Logf("test %d", i)
+		err := testing.InitCommand(&environment.ImportCommand{}, test.args)
+		c.Check(err, gc.ErrorMatches, test.errorMatch)
+	}
+}
+
+func (s *ImportSuite) TestImport(c *gc.C) {
+	ctx, err := s.run(c, s.path)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(testing.Stdout(ctx), gc.Equals, "environment \"bob\" imported\n")
+
+	// The one-time password has been replaced.
+	c.Assert(s.fake.user, gc.Equals, "bob")
+	c.Assert(s.fake.password, gc.HasLen, 24)
+	info, err := s.store.ReadInfo("bob")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.APICredentials(), jc.DeepEquals, configstore.APICredentials{
+		User:     "bob",
+		Password: s.fake.password,
+	})
+	c.Assert(info.APIEndpoint(), jc.DeepEquals, configstore.APIEndpoint{
+		Addresses:   []string{"127.0.0.1:12345"},
+		Hostnames:   []string{"localhost:12345"},
+		CACert:      "certificated",
+		EnvironUUID: "env-uuid",
+	})
+}
+
+func (s *ImportSuite) TestImportWithName(c *gc.C) {
+	_, err := s.run(c, s.path, "staging")
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.store.ReadInfo("staging")
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *ImportSuite) TestImportExistingName(c *gc.C) {
+	info := s.store.CreateInfo("bob")
+	err := info.Write()
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.run(c, s.path)
+	c.Assert(err, gc.ErrorMatches, `environment "bob" already exists locally`)
+	c.Assert(s.fake.user, gc.Equals, "")
+}
+
+func (s *ImportSuite) TestImportPasswordChangeFails(c *gc.C) {
+	s.fake.err = errors.New("invalid entity name or password")
+	_, err := s.run(c, s.path)
+	c.Assert(err, gc.ErrorMatches, "cannot change password: invalid entity name or password")
+
+	// Nothing is left behind, so the import can be tried again.
+	_, err = s.store.ReadInfo("bob")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *ImportSuite) TestImportBadFile(c *gc.C) {
+	err := ioutil.WriteFile(s.path, []byte("user: bob\n"), 0600)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.run(c, s.path)
+	c.Assert(err, gc.ErrorMatches, `connection file ".*bob.jenv" has no password`)
+}
+
+type fakeImportAPI struct {
+	user     string
+	password string
+	err      error
+}
+
+func (f *fakeImportAPI) Close() error {
+	return nil
+}
+
+func (f *fakeImportAPI) SetPassword(username, password string) error {
+	f.user = username
+	f.password = password
+	return f.err
+}
@@ -0,0 +1,454 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package environment
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	"launchpad.net/gnuflag"
+
+	"github.com/juju/juju/api"
+	"github.com/juju/juju/api/migrationmanager"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/cmd/juju/block"
+	"github.com/juju/juju/environs/config"
+	"github.com/juju/juju/environs/configstore"
+	"github.com/juju/juju/juju"
+	"github.com/juju/juju/network"
+)
+
+const migrateEnvHelpDoc = `
+Move the current environment to another state server, leaving its machines
+and units running.
+
+The target is the name of an environment of the other state server, such
+as the one it was bootstrapped as; you must be an administrator of it. The
+migration:
+
+  - blocks all changes to the environment;
+  - copies its services, units, machines, relations, settings, charms and
+    tools to the target state server;
+  - redirects the environment's agents to the target state server;
+  - waits for all the agents to connect to the target state server, and
+    then removes the environment from its current state server.
+
+If the agents do not all connect within the time given by --timeout, the
+migration is rolled back: the agents that moved are sent back, the copy
+on the target state server is removed, and changes are unblocked again.
+
+The progress of the migration is recorded by the current state server. If
+the command is interrupted, running it again resumes the migration, or
+its rollback, from where it stopped.
+
+Environments of the state server itself, and environments related to
+other environments, cannot be migrated.
+
+Examples:
+  juju environment migrate other-server
+  juju environment migrate other-server --timeout 30m
+
+See Also:
+  juju environment create
+`
+
+// migrationPollInterval is how often the migrate command checks which
+// agents have connected to their new state server.
+var migrationPollInterval = 5 * time.Second
+
+// MigrateCommand moves the current environment to another state server.
+type MigrateCommand struct {
+	envcmd.EnvCommandBase
+	api       MigrateEnvironmentAPI
+	targetAPI MigrateEnvironmentAPI
+	target    string
+	timeout   time.Duration
+}
+
+// Info implements Command.Info.
+func (c *MigrateCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "migrate",
+		Args:    "<target state server environment>",
+		Purpose: "move the current environment to another state server",
+		Doc:     migrateEnvHelpDoc,
+	}
+}
+
+// SetFlags implements Command.SetFlags.
+func (c *MigrateCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.DurationVar(&c.timeout, "timeout", 10*time.Minute, "how long to wait for agents to connect to the target state server")
+}
+
+// Init implements Command.Init.
+func (c *MigrateCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no target environment specified")
+	}
+	c.target, args = args[0], args[1:]
+	if c.timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	return cmd.CheckEmpty(args)
+}
+
+// MigrateEnvironmentAPI defines the methods on the client and migration
+// manager APIs that the migrate command uses, on both the current and
+// the target state servers.
+type MigrateEnvironmentAPI interface {
+	Close() error
+	EnvironTag() (names.EnvironTag, error)
+	APIHostPorts() [][]network.HostPort
+	EnvironmentSet(config map[string]interface{}) error
+	ExportEnvironment(env names.EnvironTag) (params.SerializedEnvironment, error)
+	ExportBlob(env names.EnvironTag, kind, name string) ([]byte, error)
+	ImportEnvironment(export params.SerializedEnvironment) (params.Environment, error)
+	ImportBlob(env names.EnvironTag, kind, name string, data []byte) error
+	StartMigration(env names.EnvironTag) error
+	MigrationStatus(env names.EnvironTag) (string, error)
+	SetMigrationTarget(env names.EnvironTag, hostPorts [][]network.HostPort, caCert string) error
+	RollBackMigration(env names.EnvironTag) error
+	ClearMigrationTarget(env names.EnvironTag) error
+	TrackAgentCheckIns(env names.EnvironTag) error
+	MigrationProgress(env names.EnvironTag) (params.MigrationProgress, error)
+	FinishImport(env names.EnvironTag) error
+	DiscardEnvironment(env names.EnvironTag) error
+}
+
+// migrateAPI combines the client and migration manager APIs, which are
+// both served over the same connection.
+type migrateAPI struct {
+	*migrationmanager.Client
+	root *api.State
+}
+
+// Close implements MigrateEnvironmentAPI.
+func (a *migrateAPI) Close() error {
+	return a.root.Close()
+}
+
+// EnvironTag implements MigrateEnvironmentAPI.
+func (a *migrateAPI) EnvironTag() (names.EnvironTag, error) {
+	return a.root.EnvironTag()
+}
+
+// APIHostPorts implements MigrateEnvironmentAPI.
+func (a *migrateAPI) APIHostPorts() [][]network.HostPort {
+	return a.root.APIHostPorts()
+}
+
+// EnvironmentSet implements MigrateEnvironmentAPI.
+func (a *migrateAPI) EnvironmentSet(config map[string]interface{}) error {
+	return a.root.Client().EnvironmentSet(config)
+}
+
+func (c *MigrateCommand) getAPI() (MigrateEnvironmentAPI, error) {
+	if c.api != nil {
+		return c.api, nil
+	}
+	root, err := c.NewAPIRoot()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &migrateAPI{migrationmanager.NewClient(root), root}, nil
+}
+
+func (c *MigrateCommand) getTargetAPI() (MigrateEnvironmentAPI, error) {
+	if c.targetAPI != nil {
+		return c.targetAPI, nil
+	}
+	root, err := juju.NewAPIFromName(c.target)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &migrateAPI{migrationmanager.NewClient(root), root}, nil
+}
+
+// migration holds the connections to the current and target state
+// servers of an environment being migrated.
+type migration struct {
+	ctx     *cmd.Context
+	env     names.EnvironTag
+	source  MigrateEnvironmentAPI
+	target  MigrateEnvironmentAPI
+	timeout time.Duration
+}
+
+// Run implements Command.Run.
+func (c *MigrateCommand) Run(ctx *cmd.Context) error {
+	targetInfo, err := envcmd.ConnectionInfoForName(c.target)
+	if err != nil {
+		return errors.Annotatef(err, "cannot read target environment %q", c.target)
+	}
+	targetCACert := targetInfo.APIEndpoint().CACert
+	sourceEndpoint, err := c.ConnectionEndpoint(false)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	source, err := c.getAPI()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer source.Close()
+	target, err := c.getTargetAPI()
+	if err != nil {
+		return errors.Annotatef(err, "cannot connect to target environment %q", c.target)
+	}
+	defer target.Close()
+	env, err := source.EnvironTag()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	m := &migration{
+		ctx:     ctx,
+		env:     env,
+		source:  source,
+		target:  target,
+		timeout: c.timeout,
+	}
+	targetHostPorts := target.APIHostPorts()
+
+	// A migration interrupted earlier is resumed from the phase
+	// recorded by the current state server.
+	phase, err := source.MigrationStatus(env)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	switch phase {
+	case "":
+		if err := m.block(); err != nil {
+			return block.ProcessBlockedError(err, block.BlockChange)
+		}
+		if err := source.StartMigration(env); err != nil {
+			m.unblock()
+			return errors.Trace(err)
+		}
+	case migrationmanager.PhaseCopying:
+		ctx.Infof("restarting interrupted migration")
+		m.discardTarget()
+	case migrationmanager.PhaseRedirecting:
+		ctx.Infof("resuming interrupted migration")
+		return c.completeMigration(m, targetHostPorts, targetCACert, sourceEndpoint.CACert)
+	case migrationmanager.PhaseRollingBack:
+		ctx.Infof("resuming interrupted rollback")
+		progress, err := target.MigrationProgress(env)
+		if err != nil {
+			// The copy on the target state server has already been
+			// removed, so no agents remain there.
+			progress.CheckedIn = nil
+		}
+		return m.resumeRollBack(sourceEndpoint.CACert, progress.CheckedIn)
+	default:
+		return errors.Errorf("unknown migration phase %q", phase)
+	}
+
+	if err := m.copyEnvironment(); err != nil {
+		m.abandon()
+		return errors.Trace(err)
+	}
+	ctx.Infof("redirecting agents to the target state server")
+	if err := source.SetMigrationTarget(env, targetHostPorts, targetCACert); err != nil {
+		m.discardTarget()
+		m.abandon()
+		return errors.Trace(err)
+	}
+	return c.completeMigration(m, targetHostPorts, targetCACert, sourceEndpoint.CACert)
+}
+
+// completeMigration waits for the agents of the environment to connect
+// to the target state server, and then removes the environment from
+// the current one; if they do not all connect, the migration is rolled
+// back.
+func (c *MigrateCommand) completeMigration(m *migration, targetHostPorts [][]network.HostPort, targetCACert, sourceCACert string) error {
+	progress, err := m.waitForAgents(m.target, nil)
+	if err != nil {
+		m.ctx.Infof("migration failed: %v", err)
+		return m.rollBack(sourceCACert, progress.CheckedIn)
+	}
+
+	if err := m.source.DiscardEnvironment(m.env); err != nil {
+		return errors.Annotate(err, "agents have moved, but the environment could not be removed from its old state server")
+	}
+	if err := m.target.FinishImport(m.env); err != nil {
+		return errors.Trace(err)
+	}
+	if err := c.updateConnectionInfo(targetHostPorts, targetCACert); err != nil {
+		return errors.Annotate(err, "cannot update local environment information")
+	}
+	fmt.Fprintf(m.ctx.Stdout, "environment migrated to the state server of %q\n", c.target)
+	return nil
+}
+
+// block blocks changes to the environment while it is migrated.
+func (m *migration) block() error {
+	return m.source.EnvironmentSet(map[string]interface{}{
+		config.PreventAllChangesKey: true,
+	})
+}
+
+// unblock allows changes to the environment again after a failed
+// migration.
+func (m *migration) unblock() {
+	err := m.source.EnvironmentSet(map[string]interface{}{
+		config.PreventAllChangesKey: false,
+	})
+	if err != nil {
+		m.ctx.Infof("cannot unblock changes to the environment: %v", err)
+	}
+}
+
+// abandon removes the record of a migration that failed before any
+// agents were redirected, and unblocks changes to the environment.
+func (m *migration) abandon() {
+	if err := m.source.ClearMigrationTarget(m.env); err != nil {
+		m.ctx.Infof("cannot clear the migration of the environment: %v", err)
+	}
+	m.unblock()
+}
+
+// discardTarget removes the copy of the environment on the target
+// state server.
+func (m *migration) discardTarget() {
+	if err := m.target.DiscardEnvironment(m.env); err != nil {
+		m.ctx.Infof("cannot remove the environment from the target state server: %v", err)
+	}
+}
+
+// copyEnvironment copies the environment's model, charms and tools to
+// the target state server.
+func (m *migration) copyEnvironment() error {
+	m.ctx.Infof("exporting environment")
+	export, err := m.source.ExportEnvironment(m.env)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	m.ctx.Infof("importing environment into the target state server")
+	if _, err := m.target.ImportEnvironment(export); err != nil {
+		return errors.Trace(err)
+	}
+	copyBlob := func(kind, name string) error {
+		data, err := m.source.ExportBlob(m.env, kind, name)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		return errors.Trace(m.target.ImportBlob(m.env, kind, name, data))
+	}
+	for _, path := range export.Charms {
+		if err := copyBlob(migrationmanager.CharmBlob, path); err != nil {
+			m.discardTarget()
+			return errors.Trace(err)
+		}
+	}
+	for _, vers := range export.Tools {
+		if err := copyBlob(migrationmanager.ToolsBlob, vers.String()); err != nil {
+			m.discardTarget()
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// waitForAgents waits until the agents of the environment have checked
+// in with the state server of the given API. If expected is nil, all
+// the environment's agents are waited for. It returns the last progress
+// seen.
+func (m *migration) waitForAgents(st MigrateEnvironmentAPI, expected []string) (params.MigrationProgress, error) {
+	timeout := time.After(m.timeout)
+	for {
+		progress, err := st.MigrationProgress(m.env)
+		if err != nil {
+			return progress, errors.Trace(err)
+		}
+		if expected == nil {
+			expected = progress.Expected
+		}
+		missing := missingAgents(expected, progress.CheckedIn)
+		if len(missing) == 0 {
+			return progress, nil
+		}
+		m.ctx.Infof("waiting for %d of %d agents", len(missing), len(expected))
+		select {
+		case <-time.After(migrationPollInterval):
+		case <-timeout:
+			return progress, errors.Errorf("timed out waiting for agents: %v", missing)
+		}
+	}
+}
+
+// missingAgents returns the agents in expected that are not in
+// checkedIn.
+func missingAgents(expected, checkedIn []string) []string {
+	seen := make(map[string]bool)
+	for _, tag := range checkedIn {
+		seen[tag] = true
+	}
+	var missing []string
+	for _, tag := range expected {
+		if !seen[tag] {
+			missing = append(missing, tag)
+		}
+	}
+	return missing
+}
+
+// rollBack returns the agents that moved to the target state server to
+// the current one, and removes the environment from the target. The
+// rollback is recorded by the current state server before any agents
+// are sent back, so that it is resumed if the command is interrupted.
+func (m *migration) rollBack(sourceCACert string, moved []string) error {
+	m.ctx.Infof("rolling back migration")
+	if err := m.source.TrackAgentCheckIns(m.env); err != nil {
+		return errors.Annotate(err, "cannot roll back migration")
+	}
+	if err := m.source.RollBackMigration(m.env); err != nil {
+		return errors.Annotate(err, "cannot roll back migration")
+	}
+	return m.resumeRollBack(sourceCACert, moved)
+}
+
+// resumeRollBack completes a rollback recorded by rollBack, sending
+// the given agents back from the target state server.
+func (m *migration) resumeRollBack(sourceCACert string, moved []string) error {
+	if len(moved) > 0 {
+		err := m.target.SetMigrationTarget(m.env, m.source.APIHostPorts(), sourceCACert)
+		if err != nil {
+			return errors.Annotate(err, "cannot roll back migration")
+		}
+		if _, err := m.waitForAgents(m.source, moved); err != nil {
+			return errors.Annotate(err, "cannot roll back migration; the environment has been left on both state servers")
+		}
+	}
+	m.discardTarget()
+	if err := m.source.FinishImport(m.env); err != nil {
+		return errors.Trace(err)
+	}
+	if err := m.source.ClearMigrationTarget(m.env); err != nil {
+		return errors.Annotate(err, "cannot roll back migration")
+	}
+	m.unblock()
+	return errors.New("migration rolled back")
+}
+
+// updateConnectionInfo points the local information for the environment
+// at its new state server.
+func (c *MigrateCommand) updateConnectionInfo(hostPorts [][]network.HostPort, caCert string) error {
+	info, err := envcmd.ConnectionInfoForName(c.ConnectionName())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	endpoint := info.APIEndpoint()
+	addrs := network.HostPortsToStrings(network.CollapseHostPorts(hostPorts))
+	info.SetAPIEndpoint(configstore.APIEndpoint{
+		Addresses:   addrs,
+		Hostnames:   addrs,
+		CACert:      caCert,
+		EnvironUUID: endpoint.EnvironUUID,
+	})
+	return info.Write()
+}
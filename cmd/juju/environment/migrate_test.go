@@ -0,0 +1,358 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package environment_test
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/cmd/juju/environment"
+	"github.com/juju/juju/environs/configstore"
+	"github.com/juju/juju/juju/osenv"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/testing"
+	"github.com/juju/juju/version"
+)
+
+type MigrateSuite struct {
+	testing.FakeJujuHomeSuite
+	store  configstore.Storage
+	source *fakeMigrateAPI
+	target *fakeMigrateAPI
+}
+
+var _ = gc.Suite(&MigrateSuite{})
+
+var migrateEnvTag = names.NewEnvironTag("deadbeef-0bad-400d-8000-4b1d0d06f00d")
+
+func (s *MigrateSuite) SetUpTest(c *gc.C) {
+	s.FakeJujuHomeSuite.SetUpTest(c)
+	s.store = configstore.NewMem()
+	s.PatchValue(&configstore.Default, func() (configstore.Storage, error) {
+		return s.store, nil
+	})
+	s.PatchValue(environment.MigrationPollInterval, time.Millisecond)
+	os.Setenv(osenv.JujuEnvEnvKey, "testing")
+	for name, endpoint := range map[string]configstore.APIEndpoint{
+		"testing": {
+			Addresses:   []string{"10.0.0.1:17070"},
+			CACert:      "source-cert",
+			EnvironUUID: migrateEnvTag.Id(),
+		},
+		"other": {
+			Addresses:   []string{"10.0.1.1:17070"},
+			CACert:      "target-cert",
+			EnvironUUID: "other-uuid",
+		},
+	} {
+		info := s.store.CreateInfo(name)
+		info.SetAPIEndpoint(endpoint)
+		info.SetAPICredentials(configstore.APICredentials{User: "admin", Password: "sekrit"})
+		err := info.Write()
+		c.Assert(err, jc.ErrorIsNil)
+	}
+
+	s.source = &fakeMigrateAPI{
+		hostPorts: [][]network.HostPort{network.NewHostPorts(17070, "10.0.0.1")},
+		export: params.SerializedEnvironment{
+			UUID:     migrateEnvTag.Id(),
+			Name:     "testing",
+			OwnerTag: "user-admin",
+			Charms:   []string{"charms/wordpress"},
+			Tools:    []version.Binary{version.MustParseBinary("1.24.0-trusty-amd64")},
+		},
+		checkedIn: []string{"machine-0"},
+	}
+	s.target = &fakeMigrateAPI{
+		hostPorts: [][]network.HostPort{network.NewHostPorts(17070, "10.0.1.1")},
+		expected:  []string{"machine-0", "unit-mysql-0"},
+		checkedIn: []string{"machine-0", "unit-mysql-0"},
+	}
+}
+
+func (s *MigrateSuite) run(c *gc.C, args ...string) (*cmd.Context, error) {
+	command := environment.NewMigrateCommand(s.source, s.target)
+	return testing.RunCommand(c, envcmd.Wrap(command), args...)
+}
+
+func (s *MigrateSuite) TestInit(c *gc.C) {
+	for i, test := range []struct {
+		args       []string
+		errorMatch string
+	}{
+		{
+			errorMatch: "no target environment specified",
+		}, {
+			args:       []string{"other", "--timeout", "0"},
+			errorMatch: "timeout must be positive",
+		}, {
+			args:       []string{"other", "extra"},
+			errorMatch: `unrecognized args: \["extra"\]`,
+		},
+	} {
+		c.Logf("test %d", i)
+		err := testing.InitCommand(&environment.MigrateCommand{}, test.args)
+		c.Check(err, gc.ErrorMatches, test.errorMatch)
+	}
+}
+
+func (s *MigrateSuite) TestMigrate(c *gc.C) {
+	ctx, err := s.run(c, "other")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(testing.Stdout(ctx), gc.Equals, "environment migrated to the state server of \"other\"\n")
+
+	c.Assert(s.source.calls, gc.DeepEquals, []string{
+		"MigrationStatus",
+		"EnvironmentSet map[block-all-changes:true]",
+		"StartMigration",
+		"ExportEnvironment",
+		"ExportBlob charm charms/wordpress",
+		"ExportBlob tools 1.24.0-trusty-amd64",
+		"SetMigrationTarget target-cert",
+		"DiscardEnvironment",
+	})
+	c.Assert(s.target.calls, gc.DeepEquals, []string{
+		"ImportEnvironment",
+		"ImportBlob charm charms/wordpress",
+		"ImportBlob tools 1.24.0-trusty-amd64",
+		"MigrationProgress",
+		"FinishImport",
+	})
+
+	// The environment is now found on the target state server.
+	info, err := s.store.ReadInfo("testing")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.APIEndpoint().Addresses, gc.DeepEquals, []string{"10.0.1.1:17070"})
+	c.Assert(info.APIEndpoint().CACert, gc.Equals, "target-cert")
+	c.Assert(info.APIEndpoint().EnvironUUID, gc.Equals, migrateEnvTag.Id())
+}
+
+func (s *MigrateSuite) TestMigrateExportFails(c *gc.C) {
+	s.source.err = map[string]error{
+		"ExportEnvironment": errors.New("environment has relations with other environments"),
+	}
+	_, err := s.run(c, "other")
+	c.Assert(err, gc.ErrorMatches, "environment has relations with other environments")
+	c.Assert(s.source.calls, gc.DeepEquals, []string{
+		"MigrationStatus",
+		"EnvironmentSet map[block-all-changes:true]",
+		"StartMigration",
+		"ExportEnvironment",
+		"ClearMigrationTarget",
+		"EnvironmentSet map[block-all-changes:false]",
+	})
+	c.Assert(s.target.calls, gc.HasLen, 0)
+}
+
+func (s *MigrateSuite) TestMigrateBlobCopyFails(c *gc.C) {
+	s.target.err = map[string]error{"ImportBlob": errors.New("boom")}
+	_, err := s.run(c, "other")
+	c.Assert(err, gc.ErrorMatches, "boom")
+	c.Assert(s.target.calls, gc.DeepEquals, []string{
+		"ImportEnvironment",
+		"ImportBlob charm charms/wordpress",
+		"DiscardEnvironment",
+	})
+	c.Assert(s.source.calls[len(s.source.calls)-1], gc.Equals, "EnvironmentSet map[block-all-changes:false]")
+}
+
+func (s *MigrateSuite) TestMigrateRollBack(c *gc.C) {
+	// Only one of the agents makes it to the target state server; once
+	// redirected back, it checks in to the current one.
+	s.target.checkedIn = []string{"machine-0"}
+	_, err := s.run(c, "other", "--timeout", "50ms")
+	c.Assert(err, gc.ErrorMatches, "migration rolled back")
+
+	c.Assert(s.source.calls, gc.DeepEquals, []string{
+		"MigrationStatus",
+		"EnvironmentSet map[block-all-changes:true]",
+		"StartMigration",
+		"ExportEnvironment",
+		"ExportBlob charm charms/wordpress",
+		"ExportBlob tools 1.24.0-trusty-amd64",
+		"SetMigrationTarget target-cert",
+		"TrackAgentCheckIns",
+		"RollBackMigration",
+		"MigrationProgress",
+		"FinishImport",
+		"ClearMigrationTarget",
+		"EnvironmentSet map[block-all-changes:false]",
+	})
+	c.Assert(s.target.calls, gc.DeepEquals, []string{
+		"ImportEnvironment",
+		"ImportBlob charm charms/wordpress",
+		"ImportBlob tools 1.24.0-trusty-amd64",
+		"MigrationProgress",
+		"SetMigrationTarget source-cert",
+		"DiscardEnvironment",
+	})
+
+	// The environment is still found on its original state server.
+	info, err := s.store.ReadInfo("testing")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.APIEndpoint().CACert, gc.Equals, "source-cert")
+}
+
+func (s *MigrateSuite) TestMigrateRestartsInterruptedCopy(c *gc.C) {
+	s.source.phase = "copying"
+	_, err := s.run(c, "other")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.source.calls, gc.DeepEquals, []string{
+		"MigrationStatus",
+		"ExportEnvironment",
+		"ExportBlob charm charms/wordpress",
+		"ExportBlob tools 1.24.0-trusty-amd64",
+		"SetMigrationTarget target-cert",
+		"DiscardEnvironment",
+	})
+	c.Assert(s.target.calls, gc.DeepEquals, []string{
+		"DiscardEnvironment",
+		"ImportEnvironment",
+		"ImportBlob charm charms/wordpress",
+		"ImportBlob tools 1.24.0-trusty-amd64",
+		"MigrationProgress",
+		"FinishImport",
+	})
+}
+
+func (s *MigrateSuite) TestMigrateResumesRedirect(c *gc.C) {
+	s.source.phase = "redirecting"
+	ctx, err := s.run(c, "other")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(testing.Stdout(ctx), gc.Equals, "environment migrated to the state server of \"other\"\n")
+	c.Assert(s.source.calls, gc.DeepEquals, []string{
+		"MigrationStatus",
+		"DiscardEnvironment",
+	})
+	c.Assert(s.target.calls, gc.DeepEquals, []string{
+		"MigrationProgress",
+		"FinishImport",
+	})
+}
+
+func (s *MigrateSuite) TestMigrateResumesRollBack(c *gc.C) {
+	s.source.phase = "rolling-back"
+	s.target.checkedIn = []string{"machine-0"}
+	_, err := s.run(c, "other")
+	c.Assert(err, gc.ErrorMatches, "migration rolled back")
+	c.Assert(s.source.calls, gc.DeepEquals, []string{
+		"MigrationStatus",
+		"MigrationProgress",
+		"FinishImport",
+		"ClearMigrationTarget",
+		"EnvironmentSet map[block-all-changes:false]",
+	})
+	c.Assert(s.target.calls, gc.DeepEquals, []string{
+		"MigrationProgress",
+		"SetMigrationTarget source-cert",
+		"DiscardEnvironment",
+	})
+	info, err := s.store.ReadInfo("testing")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.APIEndpoint().CACert, gc.Equals, "source-cert")
+}
+
+type fakeMigrateAPI struct {
+	calls     []string
+	phase     string
+	err       map[string]error
+	hostPorts [][]network.HostPort
+	export    params.SerializedEnvironment
+	expected  []string
+	checkedIn []string
+}
+
+func (f *fakeMigrateAPI) call(name string, args ...interface{}) error {
+	call := name
+	for _, arg := range args {
+		call += " " + fmt.Sprint(arg)
+	}
+	f.calls = append(f.calls, call)
+	return f.err[name]
+}
+
+func (f *fakeMigrateAPI) Close() error {
+	return nil
+}
+
+func (f *fakeMigrateAPI) EnvironTag() (names.EnvironTag, error) {
+	return migrateEnvTag, nil
+}
+
+func (f *fakeMigrateAPI) APIHostPorts() [][]network.HostPort {
+	return f.hostPorts
+}
+
+func (f *fakeMigrateAPI) EnvironmentSet(config map[string]interface{}) error {
+	return f.call("EnvironmentSet", config)
+}
+
+func (f *fakeMigrateAPI) ExportEnvironment(env names.EnvironTag) (params.SerializedEnvironment, error) {
+	return f.export, f.call("ExportEnvironment")
+}
+
+func (f *fakeMigrateAPI) ExportBlob(env names.EnvironTag, kind, name string) ([]byte, error) {
+	return []byte(name), f.call("ExportBlob", kind, name)
+}
+
+func (f *fakeMigrateAPI) ImportEnvironment(export params.SerializedEnvironment) (params.Environment, error) {
+	return params.Environment{Name: export.Name, UUID: export.UUID}, f.call("ImportEnvironment")
+}
+
+func (f *fakeMigrateAPI) ImportBlob(env names.EnvironTag, kind, name string, data []byte) error {
+	return f.call("ImportBlob", kind, name)
+}
+
+func (f *fakeMigrateAPI) StartMigration(env names.EnvironTag) error {
+	return f.call("StartMigration")
+}
+
+func (f *fakeMigrateAPI) MigrationStatus(env names.EnvironTag) (string, error) {
+	return f.phase, f.call("MigrationStatus")
+}
+
+func (f *fakeMigrateAPI) RollBackMigration(env names.EnvironTag) error {
+	return f.call("RollBackMigration")
+}
+
+func (f *fakeMigrateAPI) SetMigrationTarget(env names.EnvironTag, hostPorts [][]network.HostPort, caCert string) error {
+	return f.call("SetMigrationTarget", caCert)
+}
+
+func (f *fakeMigrateAPI) ClearMigrationTarget(env names.EnvironTag) error {
+	return f.call("ClearMigrationTarget")
+}
+
+func (f *fakeMigrateAPI) TrackAgentCheckIns(env names.EnvironTag) error {
+	return f.call("TrackAgentCheckIns")
+}
+
+func (f *fakeMigrateAPI) MigrationProgress(env names.EnvironTag) (params.MigrationProgress, error) {
+	progress := params.MigrationProgress{
+		Expected:  f.expected,
+		CheckedIn: f.checkedIn,
+	}
+	// Repeated polls are only recorded once.
+	if n := len(f.calls); n > 0 && f.calls[n-1] == "MigrationProgress" {
+		return progress, f.err["MigrationProgress"]
+	}
+	return progress, f.call("MigrationProgress")
+}
+
+func (f *fakeMigrateAPI) FinishImport(env names.EnvironTag) error {
+	return f.call("FinishImport")
+}
+
+func (f *fakeMigrateAPI) DiscardEnvironment(env names.EnvironTag) error {
+	return f.call("DiscardEnvironment")
+}
@@ -0,0 +1,161 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package environment
+
+import (
+	"fmt"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	"github.com/juju/utils"
+	"launchpad.net/gnuflag"
+
+	"github.com/juju/juju/api"
+	"github.com/juju/juju/api/usermanager"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/cmd/juju/block"
+)
+
+const shareEnvHelpDoc = `
+Share the current environment with an existing user, and write out a
+connection file (.jenv) for them.
+
+The user is given a new one-time password, which is written to the
+connection file along with the API endpoints, CA certificate and UUID of
+the environment. Hand the file to the user, who imports it with
+"juju environment import"; the import replaces the one-time password with
+one that only they know. Connection files previously given to the user
+stop working.
+
+If the user already has access to the environment, their access is left
+as it is and they are only issued new credentials.
+
+The connection file is written to <user>.jenv in the current directory
+unless the --output option is given.
+
+Examples:
+  juju environment share bob
+  juju environment share bob --output /tmp/bob-staging.jenv
+
+See Also:
+  juju user add
+  juju environment import
+`
+
+// ShareCommand shares the current environment with a user and writes
+// a connection file for them.
+type ShareCommand struct {
+	envcmd.EnvCommandBase
+	api     ShareEnvironmentAPI
+	user    string
+	outPath string
+}
+
+// Info implements Command.Info.
+func (c *ShareCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "share",
+		Args:    "<user>",
+		Purpose: "share the current environment with a user",
+		Doc:     shareEnvHelpDoc,
+	}
+}
+
+// SetFlags implements Command.SetFlags.
+func (c *ShareCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.StringVar(&c.outPath, "o", "", "specify the connection file for the user")
+	f.StringVar(&c.outPath, "output", "", "")
+}
+
+// Init implements Command.Init.
+func (c *ShareCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no username supplied")
+	}
+	c.user, args = args[0], args[1:]
+	// Only local users have passwords.
+	if !names.IsValidUserName(c.user) {
+		return errors.Errorf("%q is not a valid local user", c.user)
+	}
+	return cmd.CheckEmpty(args)
+}
+
+// ShareEnvironmentAPI defines the methods on the client and user manager
+// APIs that the share command uses.
+type ShareEnvironmentAPI interface {
+	Close() error
+	ShareEnvironment(users []names.UserTag) error
+	SetPassword(username, password string) error
+}
+
+// shareAPI combines the client and user manager APIs, which are both
+// served over the same connection.
+type shareAPI struct {
+	*api.Client
+	userManager *usermanager.Client
+}
+
+// SetPassword implements ShareEnvironmentAPI.
+func (a *shareAPI) SetPassword(username, password string) error {
+	return a.userManager.SetPassword(username, password)
+}
+
+func (c *ShareCommand) getAPI() (ShareEnvironmentAPI, error) {
+	if c.api != nil {
+		return c.api, nil
+	}
+	root, err := c.NewAPIRoot()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &shareAPI{
+		Client:      root.Client(),
+		userManager: usermanager.NewClient(root),
+	}, nil
+}
+
+// Run implements Command.Run.
+func (c *ShareCommand) Run(ctx *cmd.Context) error {
+	client, err := c.getAPI()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	password, err := utils.RandomPassword()
+	if err != nil {
+		return errors.Annotate(err, "failed to generate random password")
+	}
+
+	// Share the environment first, so that the user's existing password
+	// is left alone if they cannot be given access. A user who already
+	// has access is just issued new credentials.
+	shared := true
+	if err := client.ShareEnvironment([]names.UserTag{names.NewUserTag(c.user)}); params.IsCodeAlreadyExists(err) {
+		shared = false
+	} else if err != nil {
+		return block.ProcessBlockedError(err, block.BlockChange)
+	}
+	if err := client.SetPassword(c.user, password); err != nil {
+		return block.ProcessBlockedError(err, block.BlockChange)
+	}
+	if shared {
+		fmt.Fprintf(ctx.Stdout, "environment shared with user %q\n", c.user)
+	} else {
+		fmt.Fprintf(ctx.Stdout, "credentials re-issued for user %q\n", c.user)
+	}
+
+	outPath := c.outPath
+	if outPath == "" {
+		outPath = c.user + ".jenv"
+	}
+	outPath = envcmd.ConnectionFilePath(ctx, outPath)
+	if err := envcmd.WriteConnectionFile(c.ConnectionName(), c.user, password, outPath); err != nil {
+		return errors.Annotate(err, "cannot write connection file")
+	}
+	fmt.Fprintf(ctx.Stdout, "connection file written to %s\n", outPath)
+	return nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package environment_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/cmd/juju/environment"
+	"github.com/juju/juju/environs/configstore"
+	"github.com/juju/juju/juju/osenv"
+	"github.com/juju/juju/testing"
+)
+
+type ShareSuite struct {
+	testing.FakeJujuHomeSuite
+	fake *fakeShareAPI
+}
+
+var _ = gc.Suite(&ShareSuite{})
+
+func (s *ShareSuite) SetUpTest(c *gc.C) {
+	s.FakeJujuHomeSuite.SetUpTest(c)
+	s.fake = &fakeShareAPI{}
+	store := configstore.NewMem()
+	s.PatchValue(&configstore.Default, func() (configstore.Storage, error) {
+		return store, nil
+	})
+	os.Setenv(osenv.JujuEnvEnvKey, "testing")
+	info := store.CreateInfo("testing")
+	info.SetAPIEndpoint(configstore.APIEndpoint{
+		Addresses:   []string{"127.0.0.1:12345"},
+		Hostnames:   []string{"localhost:12345"},
+		CACert:      testing.CACert,
+		EnvironUUID: "env-uuid",
+	})
+	info.SetAPICredentials(configstore.APICredentials{
+		User:     "admin",
+		Password: "sekrit",
+	})
+	err := info.Write()
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *ShareSuite) run(c *gc.C, args ...string) (*cmd.Context, error) {
+	command := environment.NewShareCommand(s.fake)
+	return testing.RunCommand(c, envcmd.Wrap(command), args...)
+}
+
+func (s *ShareSuite) TestInit(c *gc.C) {
+	for i, test := range []struct {
+		args       []string
+		errorMatch string
+	}{
+		{
+			errorMatch: "no username supplied",
+		}, {
+			args:       []string{"bob@external"},
+			errorMatch: `"bob@external" is not a valid local user`,
+		}, {
+			args:       []string{"bob", "extra"},
+			errorMatch: `unrecognized args: \["extra"\]`,
+		},
+	} {
+		c.Logf("test %d", i)
+		err := testing.InitCommand(&environment.ShareCommand{}, test.args)
+		c.Check(err, gc.ErrorMatches, test.errorMatch)
+	}
+}
+
+func (s *ShareSuite) TestShare(c *gc.C) {
+	ctx, err := s.run(c, "bob")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.fake.shared, jc.DeepEquals, []names.UserTag{names.NewLocalUserTag("bob")})
+	c.Assert(s.fake.user, gc.Equals, "bob")
+	c.Assert(s.fake.password, gc.HasLen, 24)
+	c.Assert(testing.Stdout(ctx), gc.Matches, `
+environment shared with user "bob"
+connection file written to .*bob.jenv
+`[1:])
+
+	info, err := envcmd.ReadConnectionFile(ctx.AbsPath("bob.jenv"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.User, gc.Equals, "bob")
+	c.Assert(info.Password, gc.Equals, s.fake.password)
+	c.Assert(info.EnvironUUID, gc.Equals, "env-uuid")
+	c.Assert(info.StateServers, jc.DeepEquals, []string{"127.0.0.1:12345"})
+	c.Assert(info.ServerHostnames, jc.DeepEquals, []string{"localhost:12345"})
+}
+
+func (s *ShareSuite) TestShareOutput(c *gc.C) {
+	outPath := filepath.Join(c.MkDir(), "staging")
+	_, err := s.run(c, "bob", "--output", outPath)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = envcmd.ReadConnectionFile(outPath + ".jenv")
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *ShareSuite) TestShareErrorKeepsPassword(c *gc.C) {
+	s.fake.err = errors.New(`could not share environment: user "bob" does not exist locally`)
+	ctx, err := s.run(c, "bob")
+	c.Assert(err, gc.ErrorMatches, `could not share environment: user "bob" does not exist locally`)
+	c.Assert(s.fake.password, gc.Equals, "")
+	_, err = os.Stat(ctx.AbsPath("bob.jenv"))
+	c.Assert(err, jc.Satisfies, os.IsNotExist)
+}
+
+func (s *ShareSuite) TestShareExistingUserReissuesCredentials(c *gc.C) {
+	s.fake.err = &params.Error{
+		Message: "could not share environment: env user already exists",
+		Code:    params.CodeAlreadyExists,
+	}
+	ctx, err := s.run(c, "bob")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.fake.user, gc.Equals, "bob")
+	c.Assert(s.fake.password, gc.HasLen, 24)
+	c.Assert(testing.Stdout(ctx), gc.Matches, `
+credentials re-issued for user "bob"
+connection file written to .*bob.jenv
+`[1:])
+
+	info, err := envcmd.ReadConnectionFile(ctx.AbsPath("bob.jenv"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.Password, gc.Equals, s.fake.password)
+}
+
+type fakeShareAPI struct {
+	shared   []names.UserTag
+	user     string
+	password string
+	err      error
+}
+
+func (f *fakeShareAPI) Close() error {
+	return nil
+}
+
+func (f *fakeShareAPI) ShareEnvironment(users []names.UserTag) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.shared = users
+	return nil
+}
+
+func (f *fakeShareAPI) SetPassword(username, password string) error {
+	f.user = username
+	f.password = password
+	return nil
+}
@@ -7,8 +7,10 @@ import (
 	"errors"
 
 	"github.com/juju/cmd"
+	"github.com/juju/names"
 	"launchpad.net/gnuflag"
 
+	"github.com/juju/juju/apiserver/params"
 	"github.com/juju/juju/cmd/envcmd"
 )
 
@@ -16,6 +18,7 @@ import (
 type GetCommand struct {
 	envcmd.EnvCommandBase
 	ServiceName string
+	history     bool
 	out         cmd.Output
 }
 
@@ -42,6 +45,23 @@ settings:
 NOTE: In the example above the descriptions and most other settings were omitted for
 brevity. The "engine" setting was left at its default value ("nginx"), while the
 "tuning" setting was set to "optimized" (the default value is "single").
+
+With --history, the recorded changes to the service's configuration are
+shown instead, oldest first: the revision, time, user, charm, and the old
+and new values of each setting changed. The values of settings that appear
+to hold secrets, such as passwords and keys, are not recorded. Use
+"juju set --revert" to go back to a revision.
+
+$ juju get --history wordpress
+
+- revision: 1
+  time: 2015-05-11 09:21:07 +0000 UTC
+  user: admin@local
+  charm: cs:trusty/wordpress-2
+  changes:
+    tuning:
+      old: single
+      new: optimized
 `
 
 func (c *GetCommand) Info() *cmd.Info {
@@ -58,6 +78,7 @@ func (c *GetCommand) SetFlags(f *gnuflag.FlagSet) {
 	c.out.AddFlags(f, "yaml", map[string]cmd.Formatter{
 		"yaml": cmd.FormatYaml,
 	})
+	f.BoolVar(&c.history, "history", false, "show the history of changes to the configuration")
 }
 
 func (c *GetCommand) Init(args []string) error {
@@ -78,6 +99,14 @@ func (c *GetCommand) Run(ctx *cmd.Context) error {
 	}
 	defer client.Close()
 
+	if c.history {
+		revisions, err := client.ServiceConfigHistory(c.ServiceName)
+		if err != nil {
+			return err
+		}
+		return c.out.Write(ctx, formatConfigHistory(revisions))
+	}
+
 	results, err := client.ServiceGet(c.ServiceName)
 	if err != nil {
 		return err
@@ -90,3 +119,39 @@ func (c *GetCommand) Run(ctx *cmd.Context) error {
 	}
 	return c.out.Write(ctx, resultsMap)
 }
+
+// formatConfigHistory returns the config history of a service in a form
+// suitable for output.
+func formatConfigHistory(revisions []params.ServiceConfigRevision) []map[string]interface{} {
+	result := []map[string]interface{}{}
+	for _, rev := range revisions {
+		changes := make(map[string]interface{})
+		for _, change := range rev.Changes {
+			if change.Masked {
+				changes[change.Key] = map[string]interface{}{
+					change.Type: "(masked)",
+				}
+				continue
+			}
+			values := make(map[string]interface{})
+			if change.OldValue != nil {
+				values["old"] = change.OldValue
+			}
+			if change.NewValue != nil {
+				values["new"] = change.NewValue
+			}
+			changes[change.Key] = values
+		}
+		entry := map[string]interface{}{
+			"revision": rev.Revision,
+			"time":     rev.Time.String(),
+			"charm":    rev.CharmURL,
+			"changes":  changes,
+		}
+		if tag, err := names.ParseUserTag(rev.UserTag); err == nil {
+			entry["user"] = tag.Username()
+		}
+		result = append(result, entry)
+	}
+	return result
+}
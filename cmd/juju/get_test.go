@@ -87,3 +87,38 @@ func (s *GetSuite) TestGetConfig(c *gc.C) {
 		c.Assert(actual, gc.DeepEquals, expected)
 	}
 }
+
+func (s *GetSuite) TestGetHistory(c *gc.C) {
+	sch := s.AddTestingCharm(c, "dummy")
+	svc := s.AddTestingService(c, "dummy-service", sch)
+	err := svc.UpdateConfigSettingsBy(s.AdminUserTag(c).String(), charm.Settings{"title": "Nearly There"})
+	c.Assert(err, jc.ErrorIsNil)
+	err = svc.UpdateConfigSettings(charm.Settings{"title": "There", "outlook": "sunny"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	ctx, err := coretesting.RunCommand(c, envcmd.Wrap(&GetCommand{}), "--history", "dummy-service")
+	c.Assert(err, jc.ErrorIsNil)
+	var actual []map[string]interface{}
+	err = goyaml.Unmarshal(ctx.Stdout.(*bytes.Buffer).Bytes(), &actual)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(actual, gc.HasLen, 2)
+	for _, rev := range actual {
+		c.Assert(rev["time"], gc.Not(gc.Equals), "")
+		delete(rev, "time")
+	}
+	c.Assert(actual, jc.DeepEquals, []map[string]interface{}{{
+		"revision": 1,
+		"user":     "admin@local",
+		"charm":    "local:quantal/dummy-1",
+		"changes": map[interface{}]interface{}{
+			"title": map[interface{}]interface{}{"new": "Nearly There"},
+		},
+	}, {
+		"revision": 2,
+		"charm":    "local:quantal/dummy-1",
+		"changes": map[interface{}]interface{}{
+			"outlook": map[interface{}]interface{}{"new": "sunny"},
+			"title":   map[interface{}]interface{}{"old": "Nearly There", "new": "There"},
+		},
+	}})
+}
@@ -0,0 +1,8 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package leadership
+
+var (
+	GetReleaseLeadershipAPI = &getReleaseLeadershipAPI
+)
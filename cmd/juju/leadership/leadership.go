@@ -0,0 +1,49 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package leadership
+
+import (
+	"github.com/juju/cmd"
+
+	"github.com/juju/juju/api/service"
+	"github.com/juju/juju/cmd/envcmd"
+)
+
+const leadershipCommandDoc = `
+"juju leadership" is used to manage the leadership of services in the
+Juju environment.
+
+The unit currently leading each service is shown by "juju status".
+`
+
+const leadershipCommandPurpose = "manage service leadership"
+
+// NewSuperCommand creates the leadership supercommand and registers the
+// subcommands that it supports.
+func NewSuperCommand() cmd.Command {
+	leadershipcmd := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:        "leadership",
+		Doc:         leadershipCommandDoc,
+		UsagePrefix: "juju",
+		Purpose:     leadershipCommandPurpose,
+	})
+	leadershipcmd.Register(envcmd.Wrap(&ReleaseCommand{}))
+	return leadershipcmd
+}
+
+// LeadershipCommandBase is a helper base structure that has a method to
+// get the service client.
+type LeadershipCommandBase struct {
+	envcmd.EnvCommandBase
+}
+
+// NewServiceClient returns a service client for the root api endpoint
+// that the environment command returns.
+func (c *LeadershipCommandBase) NewServiceClient() (*service.Client, error) {
+	root, err := c.NewAPIRoot()
+	if err != nil {
+		return nil, err
+	}
+	return service.NewClient(root), nil
+}
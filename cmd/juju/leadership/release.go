@@ -0,0 +1,81 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package leadership
+
+import (
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/names"
+)
+
+const releaseCommandDoc = `
+Revoke the leadership of a service from the unit which currently holds
+it, so that another unit of the service can claim it. The unit whose
+leadership was revoked is blocked from claiming it again for a while.
+
+This is intended for use when the leader unit is wedged but its agent
+is still running and renewing its leadership. The revocation is
+recorded in the audit log. Only an environment administrator may
+release leadership.
+
+Examples:
+
+  # Let another unit of mysql take over leadership.
+  juju leadership release mysql
+`
+
+// ReleaseCommand revokes the leadership of a service.
+type ReleaseCommand struct {
+	LeadershipCommandBase
+	ServiceName string
+}
+
+// Info implements Command.Info.
+func (c *ReleaseCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "release",
+		Args:    "<service>",
+		Purpose: "release the leadership of a service",
+		Doc:     releaseCommandDoc,
+	}
+}
+
+// Init implements Command.Init.
+func (c *ReleaseCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no service name specified")
+	}
+	c.ServiceName = args[0]
+	if !names.IsValidService(c.ServiceName) {
+		return errors.Errorf("invalid service name %q", c.ServiceName)
+	}
+	return cmd.CheckEmpty(args[1:])
+}
+
+// ReleaseLeadershipAPI defines the service API methods that the release
+// command uses.
+type ReleaseLeadershipAPI interface {
+	RevokeLeadership(service string) (string, error)
+	Close() error
+}
+
+var getReleaseLeadershipAPI = func(c *ReleaseCommand) (ReleaseLeadershipAPI, error) {
+	return c.NewServiceClient()
+}
+
+// Run implements Command.Run.
+func (c *ReleaseCommand) Run(ctx *cmd.Context) error {
+	client, err := getReleaseLeadershipAPI(c)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	unitName, err := client.RevokeLeadership(c.ServiceName)
+	if err != nil {
+		return errors.Annotatef(err, "cannot release leadership of %q", c.ServiceName)
+	}
+	ctx.Infof("leadership of %q released by %q", c.ServiceName, unitName)
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package leadership_test
+
+import (
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/cmd/juju/leadership"
+	"github.com/juju/juju/testing"
+)
+
+type releaseCommandSuite struct {
+	testing.FakeJujuHomeSuite
+	mockAPI *fakeReleaseLeadershipAPI
+}
+
+var _ = gc.Suite(&releaseCommandSuite{})
+
+type fakeReleaseLeadershipAPI struct {
+	service string
+	err     error
+}
+
+func (*fakeReleaseLeadershipAPI) Close() error {
+	return nil
+}
+
+func (f *fakeReleaseLeadershipAPI) RevokeLeadership(service string) (string, error) {
+	f.service = service
+	if f.err != nil {
+		return "", f.err
+	}
+	return service + "/0", nil
+}
+
+func (s *releaseCommandSuite) SetUpTest(c *gc.C) {
+	s.FakeJujuHomeSuite.SetUpTest(c)
+	s.mockAPI = &fakeReleaseLeadershipAPI{}
+	s.PatchValue(leadership.GetReleaseLeadershipAPI, func(c *leadership.ReleaseCommand) (leadership.ReleaseLeadershipAPI, error) {
+		return s.mockAPI, nil
+	})
+}
+
+func runReleaseCommand(c *gc.C, args ...string) (*cmd.Context, error) {
+	return testing.RunCommand(c, envcmd.Wrap(&leadership.ReleaseCommand{}), args...)
+}
+
+func (s *releaseCommandSuite) TestRelease(c *gc.C) {
+	ctx, err := runReleaseCommand(c, "mysql")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.mockAPI.service, gc.Equals, "mysql")
+	c.Assert(testing.Stderr(ctx), gc.Equals, "leadership of \"mysql\" released by \"mysql/0\"\n")
+}
+
+func (s *releaseCommandSuite) TestReleaseError(c *gc.C) {
+	s.mockAPI.err = errors.New("permission denied")
+	_, err := runReleaseCommand(c, "mysql")
+	c.Assert(err, gc.ErrorMatches, `cannot release leadership of "mysql": permission denied`)
+}
+
+func (*releaseCommandSuite) TestServiceRequired(c *gc.C) {
+	_, err := runReleaseCommand(c)
+	c.Assert(err, gc.ErrorMatches, "no service name specified")
+}
+
+func (*releaseCommandSuite) TestInvalidService(c *gc.C) {
+	_, err := runReleaseCommand(c, "mysql/0")
+	c.Assert(err, gc.ErrorMatches, `invalid service name "mysql/0"`)
+}
+
+func (*releaseCommandSuite) TestTooManyArgs(c *gc.C) {
+	_, err := runReleaseCommand(c, "mysql", "bad")
+	c.Assert(err, gc.ErrorMatches, `unrecognized args: \["bad"\]`)
+}
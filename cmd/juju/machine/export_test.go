@@ -20,3 +20,10 @@ func NewRemoveCommand(api RemoveMachineAPI) *RemoveCommand {
 		api: api,
 	}
 }
+
+// NewNetworkCommand returns a NetworkCommand with the api provided as specified.
+func NewNetworkCommand(api MachineNetworkAPI) *NetworkCommand {
+	return &NetworkCommand{
+		api: api,
+	}
+}
@@ -13,7 +13,7 @@ import (
 var logger = loggo.GetLogger("juju.cmd.juju.machine")
 
 const machineCommandDoc = `
-"juju machine" provides commands to add and remove machines in the Juju environment.
+"juju machine" provides commands to add, remove and inspect machines in the Juju environment.
 `
 
 const machineCommandPurpose = "manage machines"
@@ -29,5 +29,6 @@ func NewSuperCommand() cmd.Command {
 	})
 	machineCmd.Register(envcmd.Wrap(&AddCommand{}))
 	machineCmd.Register(envcmd.Wrap(&RemoveCommand{}))
+	machineCmd.Register(envcmd.Wrap(&NetworkCommand{}))
 	return machineCmd
 }
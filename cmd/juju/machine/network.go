@@ -0,0 +1,177 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package machine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juju/cmd"
+	"github.com/juju/names"
+	"launchpad.net/gnuflag"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/cmd/envcmd"
+)
+
+// NetworkCommand shows the network interfaces of a machine.
+type NetworkCommand struct {
+	envcmd.EnvCommandBase
+	api       MachineNetworkAPI
+	out       cmd.Output
+	MachineId string
+}
+
+const networkMachineDoc = `
+Show the network interfaces configured for a machine in Juju alongside the
+ones its agent last found on it, and any differences between the two, such
+as interfaces that are missing, down, or have no address in their network.
+
+Interfaces found on the machine are reported by its agent when it starts,
+when the configuration changes, and every few minutes.
+
+Examples:
+	# Show the network interfaces of machine 2
+	$ juju machine network 2
+`
+
+// Info implements Command.Info.
+func (c *NetworkCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "network",
+		Args:    "<machine>",
+		Purpose: "show the configured and actual network interfaces of a machine",
+		Doc:     networkMachineDoc,
+	}
+}
+
+// SetFlags implements Command.SetFlags.
+func (c *NetworkCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.out.AddFlags(f, "yaml", map[string]cmd.Formatter{
+		"yaml": cmd.FormatYaml,
+		"json": cmd.FormatJson,
+	})
+}
+
+// Init implements Command.Init.
+func (c *NetworkCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no machine specified")
+	}
+	id, args := args[0], args[1:]
+	if !names.IsValidMachine(id) {
+		return fmt.Errorf("invalid machine id %q", id)
+	}
+	c.MachineId = id
+	return cmd.CheckEmpty(args)
+}
+
+// MachineNetworkAPI defines the API methods that the network command
+// uses.
+type MachineNetworkAPI interface {
+	MachineNetworkStatus(machineId string) (params.MachineNetworkStatus, error)
+	Close() error
+}
+
+func (c *NetworkCommand) getMachineNetworkAPI() (MachineNetworkAPI, error) {
+	if c.api != nil {
+		return c.api, nil
+	}
+	return c.NewAPIClient()
+}
+
+// MachineNetwork defines the serialization behaviour of the network
+// interfaces of a machine.
+type MachineNetwork struct {
+	ObservedAt string                      `yaml:"observed-at,omitempty" json:"observed-at,omitempty"`
+	Interfaces map[string]InterfaceNetwork `yaml:"interfaces" json:"interfaces"`
+	Drift      []InterfaceDrift            `yaml:"drift,omitempty" json:"drift,omitempty"`
+}
+
+// InterfaceNetwork defines the serialization behaviour of a network
+// interface, as configured and as found on the machine.
+type InterfaceNetwork struct {
+	Intended *IntendedInterface `yaml:"intended,omitempty" json:"intended,omitempty"`
+	Observed *ObservedInterface `yaml:"observed,omitempty" json:"observed,omitempty"`
+}
+
+// IntendedInterface defines the serialization behaviour of a network
+// interface as configured in Juju.
+type IntendedInterface struct {
+	MACAddress string `yaml:"mac-address" json:"mac-address"`
+	Network    string `yaml:"network" json:"network"`
+	CIDR       string `yaml:"cidr,omitempty" json:"cidr,omitempty"`
+	VLANTag    int    `yaml:"vlan-tag,omitempty" json:"vlan-tag,omitempty"`
+	Disabled   bool   `yaml:"disabled,omitempty" json:"disabled,omitempty"`
+}
+
+// ObservedInterface defines the serialization behaviour of a network
+// interface as found on the machine.
+type ObservedInterface struct {
+	MACAddress string   `yaml:"mac-address,omitempty" json:"mac-address,omitempty"`
+	MTU        int      `yaml:"mtu,omitempty" json:"mtu,omitempty"`
+	Up         bool     `yaml:"up" json:"up"`
+	Addresses  []string `yaml:"addresses,omitempty" json:"addresses,omitempty"`
+	VLANTag    int      `yaml:"vlan-tag,omitempty" json:"vlan-tag,omitempty"`
+	RawDevice  string   `yaml:"raw-device,omitempty" json:"raw-device,omitempty"`
+}
+
+// InterfaceDrift defines the serialization behaviour of a difference
+// between a network interface and its configuration.
+type InterfaceDrift struct {
+	Interface string `yaml:"interface" json:"interface"`
+	Problem   string `yaml:"problem" json:"problem"`
+}
+
+// Run implements Command.Run.
+func (c *NetworkCommand) Run(ctx *cmd.Context) error {
+	client, err := c.getMachineNetworkAPI()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	status, err := client.MachineNetworkStatus(c.MachineId)
+	if err != nil {
+		return err
+	}
+	output := MachineNetwork{
+		Interfaces: make(map[string]InterfaceNetwork),
+	}
+	if status.ObservedAt != nil {
+		output.ObservedAt = status.ObservedAt.UTC().Format(time.RFC3339)
+	} else {
+		ctx.Infof("the agent of machine %s has not reported its network interfaces yet", c.MachineId)
+	}
+	for _, iface := range status.Intended {
+		output.Interfaces[iface.InterfaceName] = InterfaceNetwork{
+			Intended: &IntendedInterface{
+				MACAddress: iface.MACAddress,
+				Network:    iface.NetworkName,
+				CIDR:       iface.CIDR,
+				VLANTag:    iface.VLANTag,
+				Disabled:   iface.Disabled,
+			},
+		}
+	}
+	for _, iface := range status.Observed {
+		info := output.Interfaces[iface.InterfaceName]
+		info.Observed = &ObservedInterface{
+			MACAddress: iface.MACAddress,
+			MTU:        iface.MTU,
+			Up:         iface.IsUp,
+			Addresses:  iface.Addresses,
+			VLANTag:    iface.VLANTag,
+			RawDevice:  iface.ParentInterfaceName,
+		}
+		output.Interfaces[iface.InterfaceName] = info
+	}
+	for _, drift := range status.Drift {
+		output.Drift = append(output.Drift, InterfaceDrift{
+			Interface: drift.InterfaceName,
+			Problem:   drift.Problem,
+		})
+	}
+	return c.out.Write(ctx, output)
+}
@@ -0,0 +1,178 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package machine_test
+
+import (
+	"time"
+
+	"github.com/juju/cmd"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/cmd/juju/machine"
+	"github.com/juju/juju/testing"
+)
+
+type NetworkMachineSuite struct {
+	testing.FakeJujuHomeSuite
+	fake *fakeMachineNetworkAPI
+}
+
+var _ = gc.Suite(&NetworkMachineSuite{})
+
+func (s *NetworkMachineSuite) SetUpTest(c *gc.C) {
+	s.FakeJujuHomeSuite.SetUpTest(c)
+	s.fake = &fakeMachineNetworkAPI{}
+}
+
+func (s *NetworkMachineSuite) run(c *gc.C, args ...string) (*cmd.Context, error) {
+	network := machine.NewNetworkCommand(s.fake)
+	return testing.RunCommand(c, envcmd.Wrap(network), args...)
+}
+
+func (s *NetworkMachineSuite) TestInit(c *gc.C) {
+	for i, test := range []struct {
+		args        []string
+		machine     string
+		errorString string
+	}{
+		{
+			errorString: "no machine specified",
+		}, {
+			args:    []string{"1"},
+			machine: "1",
+		}, {
+			args:    []string{"1/lxc/2"},
+			machine: "1/lxc/2",
+		}, {
+			args:        []string{"lxc"},
+			errorString: `invalid machine id "lxc"`,
+		}, {
+			args:        []string{"1", "2"},
+			errorString: `unrecognized args: \["2"\]`,
+		},
+	} {
+		c.Logf("test %d", i)
+		networkCmd := &machine.NetworkCommand{}
+		err := testing.InitCommand(networkCmd, test.args)
+		if test.errorString == "" {
+			c.Check(err, jc.ErrorIsNil)
+			c.Check(networkCmd.MachineId, gc.Equals, test.machine)
+		} else {
+			c.Check(err, gc.ErrorMatches, test.errorString)
+		}
+	}
+}
+
+func (s *NetworkMachineSuite) TestNetwork(c *gc.C) {
+	observedAt := time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)
+	s.fake.status = params.MachineNetworkStatus{
+		Intended: []params.IntendedNetworkInterface{{
+			InterfaceName: "eth1",
+			MACAddress:    "aa:bb:cc:dd:ee:f1",
+			NetworkName:   "net1",
+			CIDR:          "0.1.2.0/24",
+		}, {
+			InterfaceName: "eth1.42",
+			MACAddress:    "aa:bb:cc:dd:ee:f1",
+			NetworkName:   "vlan42",
+			CIDR:          "0.2.2.0/24",
+			VLANTag:       42,
+		}},
+		Observed: []params.ObservedNetworkInterface{{
+			InterfaceName: "eth0",
+			MACAddress:    "aa:bb:cc:dd:ee:f0",
+			MTU:           1500,
+			IsUp:          true,
+			Addresses:     []string{"10.0.0.5/24"},
+		}, {
+			InterfaceName: "eth1",
+			MACAddress:    "aa:bb:cc:dd:ee:f1",
+			MTU:           1500,
+			IsUp:          true,
+			Addresses:     []string{"0.1.2.3/24"},
+		}},
+		ObservedAt: &observedAt,
+		Drift: []params.NetworkInterfaceDrift{
+			{"eth1.42", "missing on machine"},
+		},
+	}
+	ctx, err := s.run(c, "2")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.fake.machineId, gc.Equals, "2")
+	c.Assert(testing.Stdout(ctx), gc.Equals, `
+observed-at: 2015-06-01T12:00:00Z
+interfaces:
+  eth0:
+    observed:
+      mac-address: aa:bb:cc:dd:ee:f0
+      mtu: 1500
+      up: true
+      addresses:
+      - 10.0.0.5/24
+  eth1:
+    intended:
+      mac-address: aa:bb:cc:dd:ee:f1
+      network: net1
+      cidr: 0.1.2.0/24
+    observed:
+      mac-address: aa:bb:cc:dd:ee:f1
+      mtu: 1500
+      up: true
+      addresses:
+      - 0.1.2.3/24
+  eth1.42:
+    intended:
+      mac-address: aa:bb:cc:dd:ee:f1
+      network: vlan42
+      cidr: 0.2.2.0/24
+      vlan-tag: 42
+drift:
+- interface: eth1.42
+  problem: missing on machine
+`[1:])
+}
+
+func (s *NetworkMachineSuite) TestNetworkNotReported(c *gc.C) {
+	s.fake.status = params.MachineNetworkStatus{
+		Intended: []params.IntendedNetworkInterface{{
+			InterfaceName: "eth1",
+			MACAddress:    "aa:bb:cc:dd:ee:f1",
+			NetworkName:   "net1",
+		}},
+	}
+	ctx, err := s.run(c, "2")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(testing.Stderr(ctx), gc.Equals, "the agent of machine 2 has not reported its network interfaces yet\n")
+	c.Assert(testing.Stdout(ctx), gc.Equals, `
+interfaces:
+  eth1:
+    intended:
+      mac-address: aa:bb:cc:dd:ee:f1
+      network: net1
+`[1:])
+}
+
+func (s *NetworkMachineSuite) TestNetworkError(c *gc.C) {
+	s.fake.err = &params.Error{Message: "machine 2 not found", Code: params.CodeNotFound}
+	_, err := s.run(c, "2")
+	c.Assert(err, gc.ErrorMatches, "machine 2 not found")
+}
+
+type fakeMachineNetworkAPI struct {
+	machineId string
+	status    params.MachineNetworkStatus
+	err       error
+}
+
+func (f *fakeMachineNetworkAPI) Close() error {
+	return nil
+}
+
+func (f *fakeMachineNetworkAPI) MachineNetworkStatus(machineId string) (params.MachineNetworkStatus, error) {
+	f.machineId = machineId
+	return f.status, f.err
+}
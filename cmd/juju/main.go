@@ -18,7 +18,10 @@ import (
 	"github.com/juju/juju/cmd/juju/block"
 	"github.com/juju/juju/cmd/juju/cachedimages"
 	"github.com/juju/juju/cmd/juju/environment"
+	"github.com/juju/juju/cmd/juju/leadership"
 	"github.com/juju/juju/cmd/juju/machine"
+	"github.com/juju/juju/cmd/juju/space"
+	"github.com/juju/juju/cmd/juju/subnet"
 	"github.com/juju/juju/cmd/juju/user"
 	"github.com/juju/juju/environs"
 	"github.com/juju/juju/juju"
@@ -144,9 +147,13 @@ func registerCommands(r commandRegistry, ctx *cmd.Context) {
 	r.Register(wrapEnvCommand(&UnsetCommand{}))
 	r.Register(wrapEnvCommand(&GetConstraintsCommand{}))
 	r.Register(wrapEnvCommand(&SetConstraintsCommand{}))
+	r.Register(wrapEnvCommand(&GetPlacementPolicyCommand{}))
+	r.Register(wrapEnvCommand(&SetPlacementPolicyCommand{}))
 	r.Register(wrapEnvCommand(&ExposeCommand{}))
+	r.Register(wrapEnvCommand(&OfferCommand{}))
 	r.Register(wrapEnvCommand(&SyncToolsCommand{}))
 	r.Register(wrapEnvCommand(&UnexposeCommand{}))
+	r.Register(wrapEnvCommand(&UnofferCommand{}))
 	r.Register(wrapEnvCommand(&UpgradeJujuCommand{}))
 	r.Register(wrapEnvCommand(&UpgradeCharmCommand{}))
 
@@ -168,6 +175,13 @@ func registerCommands(r commandRegistry, ctx *cmd.Context) {
 	// Manage cached images
 	r.Register(cachedimages.NewSuperCommand())
 
+	// Manage network spaces and subnets
+	r.Register(space.NewSuperCommand())
+	r.Register(subnet.NewSuperCommand())
+
+	// Manage service leadership
+	r.Register(leadership.NewSuperCommand())
+
 	// Manage machines
 	r.Register(machine.NewSuperCommand())
 	r.RegisterSuperAlias("add-machine", "machine", "add", twoDotOhDeprecation("machine add"))
@@ -183,6 +197,8 @@ func registerCommands(r commandRegistry, ctx *cmd.Context) {
 	r.RegisterSuperAlias("set-env", "environment", "set", twoDotOhDeprecation("environment set"))
 	r.RegisterSuperAlias("unset-environment", "environment", "unset", twoDotOhDeprecation("environment unset"))
 	r.RegisterSuperAlias("unset-env", "environment", "unset", twoDotOhDeprecation("environment unset"))
+	r.RegisterSuperAlias("create-environment", "environment", "create", nil)
+	r.RegisterSuperAlias("migrate-environment", "environment", "migrate", nil)
 
 	// Manage and control actions.
 	if featureflag.Enabled(action.FeatureFlag) {
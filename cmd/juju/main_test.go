@@ -188,6 +188,7 @@ var commandNames = []string{
 	"block",
 	"bootstrap",
 	"cached-images",
+	"create-environment",
 	"debug-hooks",
 	"debug-log",
 	"deploy",
@@ -205,10 +206,14 @@ var commandNames = []string{
 	"get-constraints",
 	"get-env", // alias for get-environment
 	"get-environment",
+	"get-placement-policy",
 	"help",
 	"help-tool",
 	"init",
+	"leadership",
 	"machine",
+	"migrate-environment",
+	"offer",
 	"publish",
 	"remove-machine",  // alias for destroy-machine
 	"remove-relation", // alias for destroy-relation
@@ -222,14 +227,18 @@ var commandNames = []string{
 	"set-constraints",
 	"set-env", // alias for set-environment
 	"set-environment",
+	"set-placement-policy",
+	"space",
 	"ssh",
 	"stat", // alias for status
 	"status",
+	"subnet",
 	"switch",
 	"sync-tools",
 	"terminate-machine", // alias for destroy-machine
 	"unblock",
 	"unexpose",
+	"unoffer",
 	"unset",
 	"unset-env", // alias for unset-environment
 	"unset-environment",
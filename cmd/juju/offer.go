@@ -0,0 +1,94 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"errors"
+
+	"github.com/juju/cmd"
+
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/cmd/juju/block"
+)
+
+// OfferCommand offers a service endpoint to other environments.
+type OfferCommand struct {
+	envcmd.EnvCommandBase
+	Endpoint string
+}
+
+var jujuOfferHelp = `
+Offers an endpoint of a service to the other environments hosted by the
+same state server. Services of those environments can then relate to it
+by prefixing the service with the name of this environment:
+
+    juju offer mysql:db
+    juju add-relation -e app-env app:db db-env.mysql
+
+Each environment sees the other side of the relation as a remote service,
+shown under "remote-services" in juju status.
+`
+
+func (c *OfferCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "offer",
+		Args:    "<service>:<relation name>",
+		Purpose: "offer a service endpoint to other environments",
+		Doc:     jujuOfferHelp,
+	}
+}
+
+func (c *OfferCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no endpoint specified")
+	}
+	c.Endpoint = args[0]
+	return cmd.CheckEmpty(args[1:])
+}
+
+func (c *OfferCommand) Run(_ *cmd.Context) error {
+	client, err := c.NewAPIClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return block.ProcessBlockedError(client.ServiceOffer(c.Endpoint), block.BlockChange)
+}
+
+// UnofferCommand stops offering a service endpoint to other environments.
+type UnofferCommand struct {
+	envcmd.EnvCommandBase
+	Endpoint string
+}
+
+var jujuUnofferHelp = `
+Stops offering an endpoint of a service to other environments. Existing
+relations to the endpoint are not affected.
+`
+
+func (c *UnofferCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "unoffer",
+		Args:    "<service>:<relation name>",
+		Purpose: "stop offering a service endpoint to other environments",
+		Doc:     jujuUnofferHelp,
+	}
+}
+
+func (c *UnofferCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no endpoint specified")
+	}
+	c.Endpoint = args[0]
+	return cmd.CheckEmpty(args[1:])
+}
+
+func (c *UnofferCommand) Run(_ *cmd.Context) error {
+	client, err := c.NewAPIClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return block.ProcessBlockedError(client.ServiceUnoffer(c.Endpoint), block.BlockRemove)
+}
@@ -0,0 +1,215 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	"launchpad.net/gnuflag"
+
+	"github.com/juju/juju/api/service"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/cmd/juju/block"
+)
+
+const getPlacementPolicyDoc = `
+get-placement-policy shows the placement policies that have been set on a
+service using juju set-placement-policy.
+
+See Also:
+   juju help set-placement-policy
+`
+
+const setPlacementPolicyDoc = `
+set-placement-policy replaces the placement policies of a service, which
+constrain where its units are placed relative to the units of other
+services, or to each other. Calling it without policies removes them.
+
+The policies are:
+
+   anti-affinity=<service>   never place a unit on a machine hosting a unit
+                             of the other service, counting the units in
+                             the machine's containers; the service may be
+                             the service itself, to keep its units apart.
+                             New machines are started in availability zones
+                             without units of the other service, if any.
+   affinity=<service>        place each unit in the availability zone of a
+                             unit of the other service that has no unit of
+                             this service in its zone yet. This is zone
+                             affinity only: units are not placed on the
+                             same machines as the other service's units.
+   max-units-per-zone=<n>    place at most n units in each availability zone.
+
+The policies are honoured when units are placed on machines and when
+machines are started; units already placed are not moved. Placements
+that violate the policies are shown by juju status.
+
+Examples:
+
+   set-placement-policy mysql anti-affinity=mysql max-units-per-zone=1
+   set-placement-policy wordpress affinity=memcached
+   set-placement-policy mysql        (remove the policies of mysql)
+
+See Also:
+   juju help get-placement-policy
+   juju help status
+`
+
+// PlacementPolicyAPI defines the service API methods that the placement
+// policy commands use.
+type PlacementPolicyAPI interface {
+	PlacementPolicies(service string) ([]params.PlacementPolicy, error)
+	SetPlacementPolicies(service string, policies []params.PlacementPolicy) error
+	Close() error
+}
+
+var getPlacementPolicyAPI = func(c *envcmd.EnvCommandBase) (PlacementPolicyAPI, error) {
+	root, err := c.NewAPIRoot()
+	if err != nil {
+		return nil, err
+	}
+	return service.NewClient(root), nil
+}
+
+// GetPlacementPolicyCommand shows the placement policies of a service.
+type GetPlacementPolicyCommand struct {
+	envcmd.EnvCommandBase
+	ServiceName string
+	out         cmd.Output
+}
+
+func (c *GetPlacementPolicyCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "get-placement-policy",
+		Args:    "<service>",
+		Purpose: "view the placement policies of a service",
+		Doc:     getPlacementPolicyDoc,
+	}
+}
+
+func formatPlacementPolicies(value interface{}) ([]byte, error) {
+	return []byte(strings.Join(value.([]string), "\n")), nil
+}
+
+func (c *GetPlacementPolicyCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.out.AddFlags(f, "policies", map[string]cmd.Formatter{
+		"policies": formatPlacementPolicies,
+		"yaml":     cmd.FormatYaml,
+		"json":     cmd.FormatJson,
+	})
+}
+
+func (c *GetPlacementPolicyCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no service name specified")
+	}
+	if !names.IsValidService(args[0]) {
+		return fmt.Errorf("invalid service name %q", args[0])
+	}
+	c.ServiceName = args[0]
+	return cmd.CheckEmpty(args[1:])
+}
+
+func (c *GetPlacementPolicyCommand) Run(ctx *cmd.Context) error {
+	client, err := getPlacementPolicyAPI(&c.EnvCommandBase)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	policies, err := client.PlacementPolicies(c.ServiceName)
+	if err != nil {
+		return err
+	}
+	formatted := make([]string, len(policies))
+	for i, p := range policies {
+		formatted[i] = formatPlacementPolicy(p)
+	}
+	return c.out.Write(ctx, formatted)
+}
+
+// SetPlacementPolicyCommand replaces the placement policies of a service.
+type SetPlacementPolicyCommand struct {
+	envcmd.EnvCommandBase
+	ServiceName string
+	Policies    []params.PlacementPolicy
+}
+
+func (c *SetPlacementPolicyCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "set-placement-policy",
+		Args:    "<service> [<kind>=<value> ...]",
+		Purpose: "set the placement policies of a service",
+		Doc:     setPlacementPolicyDoc,
+	}
+}
+
+func (c *SetPlacementPolicyCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no service name specified")
+	}
+	if !names.IsValidService(args[0]) {
+		return fmt.Errorf("invalid service name %q", args[0])
+	}
+	c.ServiceName = args[0]
+	c.Policies = nil
+	for _, arg := range args[1:] {
+		p, err := parsePlacementPolicy(arg)
+		if err != nil {
+			return err
+		}
+		c.Policies = append(c.Policies, p)
+	}
+	return nil
+}
+
+func (c *SetPlacementPolicyCommand) Run(_ *cmd.Context) error {
+	client, err := getPlacementPolicyAPI(&c.EnvCommandBase)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	err = client.SetPlacementPolicies(c.ServiceName, c.Policies)
+	return block.ProcessBlockedError(err, block.BlockChange)
+}
+
+// parsePlacementPolicy parses a placement policy given as kind=value.
+func parsePlacementPolicy(arg string) (params.PlacementPolicy, error) {
+	parts := strings.SplitN(arg, "=", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return params.PlacementPolicy{}, errors.Errorf("invalid placement policy %q: expected kind=value", arg)
+	}
+	p := params.PlacementPolicy{Kind: parts[0]}
+	switch p.Kind {
+	case "anti-affinity", "affinity":
+		if !names.IsValidService(parts[1]) {
+			return params.PlacementPolicy{}, errors.Errorf("invalid placement policy %q: invalid service name %q", arg, parts[1])
+		}
+		p.Service = parts[1]
+	case "max-units-per-zone":
+		n, err := strconv.Atoi(parts[1])
+		if err != nil || n < 1 {
+			return params.PlacementPolicy{}, errors.Errorf("invalid placement policy %q: expected a positive number of units", arg)
+		}
+		p.MaxUnits = n
+	default:
+		return params.PlacementPolicy{}, errors.Errorf("invalid placement policy %q: unknown kind %q", arg, p.Kind)
+	}
+	return p, nil
+}
+
+// formatPlacementPolicy formats a placement policy as kind=value.
+func formatPlacementPolicy(p params.PlacementPolicy) string {
+	if p.Kind == "max-units-per-zone" {
+		return fmt.Sprintf("%s=%d", p.Kind, p.MaxUnits)
+	}
+	return fmt.Sprintf("%s=%s", p.Kind, p.Service)
+}
@@ -0,0 +1,83 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package main
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/cmd/envcmd"
+	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing"
+)
+
+type PlacementPolicySuite struct {
+	jujutesting.JujuConnSuite
+}
+
+var _ = gc.Suite(&PlacementPolicySuite{})
+
+func (s *PlacementPolicySuite) TestInit(c *gc.C) {
+	for i, test := range []struct {
+		args []string
+		err  string
+	}{{
+		err: "no service name specified",
+	}, {
+		args: []string{"mysql/0"},
+		err:  `invalid service name "mysql/0"`,
+	}, {
+		args: []string{"mysql", "anti-affinity"},
+		err:  `invalid placement policy "anti-affinity": expected kind=value`,
+	}, {
+		args: []string{"mysql", "affinity=wordpress/0"},
+		err:  `invalid placement policy "affinity=wordpress/0": invalid service name "wordpress/0"`,
+	}, {
+		args: []string{"mysql", "max-units-per-zone=0"},
+		err:  `invalid placement policy "max-units-per-zone=0": expected a positive number of units`,
+	}, {
+		args: []string{"mysql", "nearby=wordpress"},
+		err:  `invalid placement policy "nearby=wordpress": unknown kind "nearby"`,
+	}} {
+		c.Logf("test %d", i)
+		err := testing.InitCommand(&SetPlacementPolicyCommand{}, test.args)
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+}
+
+func (s *PlacementPolicySuite) TestSetAndGet(c *gc.C) {
+	mysql := s.AddTestingService(c, "mysql", s.AddTestingCharm(c, "mysql"))
+
+	_, err := testing.RunCommand(c, envcmd.Wrap(&SetPlacementPolicyCommand{}),
+		"mysql", "anti-affinity=mysql", "max-units-per-zone=1")
+	c.Assert(err, jc.ErrorIsNil)
+	err = mysql.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(mysql.PlacementPolicies(), gc.DeepEquals, []state.PlacementPolicy{
+		{Kind: state.AntiAffinity, Service: "mysql"},
+		{Kind: state.MaxUnitsPerZone, MaxUnits: 1},
+	})
+
+	ctx, err := testing.RunCommand(c, envcmd.Wrap(&GetPlacementPolicyCommand{}), "mysql")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(testing.Stdout(ctx), gc.Equals, "anti-affinity=mysql\nmax-units-per-zone=1\n")
+
+	ctx, err = testing.RunCommand(c, envcmd.Wrap(&GetPlacementPolicyCommand{}), "mysql", "--format", "yaml")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(testing.Stdout(ctx), gc.Equals, "- anti-affinity=mysql\n- max-units-per-zone=1\n")
+
+	// Without policies, they are removed.
+	_, err = testing.RunCommand(c, envcmd.Wrap(&SetPlacementPolicyCommand{}), "mysql")
+	c.Assert(err, jc.ErrorIsNil)
+	err = mysql.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(mysql.PlacementPolicies(), gc.HasLen, 0)
+}
+
+func (s *PlacementPolicySuite) TestSetAffinityWithItself(c *gc.C) {
+	s.AddTestingService(c, "mysql", s.AddTestingCharm(c, "mysql"))
+	_, err := testing.RunCommand(c, envcmd.Wrap(&SetPlacementPolicyCommand{}), "mysql", "affinity=mysql")
+	c.Assert(err, gc.ErrorMatches, `cannot set placement policies of service "mysql": affinity policy of service "mysql" with itself not valid`)
+}
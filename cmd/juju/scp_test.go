@@ -154,6 +154,23 @@ func (s *SCPSuite) TestSCPCommand(c *gc.C) {
 	}
 }
 
+func (s *SCPSuite) TestSCPCommandAuditsConnection(c *gc.C) {
+	s.makeMachines(1, c, true)
+	ctx := coretesting.Context(c)
+	scpcmd := &SCPCommand{}
+	err := envcmd.Wrap(scpcmd).Init([]string{"0:foo", "."})
+	c.Assert(err, jc.ErrorIsNil)
+	err = scpcmd.Run(ctx)
+	c.Assert(err, jc.ErrorIsNil)
+
+	accesses, err := s.State.SSHAccesses()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(accesses, gc.HasLen, 1)
+	c.Assert(accesses[0].UserTag(), gc.Equals, s.AdminUserTag(c))
+	c.Assert(accesses[0].Target(), gc.Equals, "0")
+	c.Assert(accesses[0].Address(), gc.Equals, "dummyenv-0.dns")
+}
+
 type userHost struct {
 	user string
 	host string
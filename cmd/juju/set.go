@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
 	"unicode/utf8"
 
@@ -15,6 +16,8 @@ import (
 	"github.com/juju/utils/keyvalues"
 	"launchpad.net/gnuflag"
 
+	"github.com/juju/juju/api/service"
+	"github.com/juju/juju/apiserver/params"
 	"github.com/juju/juju/cmd/envcmd"
 	"github.com/juju/juju/cmd/juju/block"
 )
@@ -25,6 +28,9 @@ type SetCommand struct {
 	ServiceName     string
 	SettingsStrings map[string]string
 	SettingsYAML    cmd.FileVar
+	Revert          bool
+	RevertRevision  int
+	DryRun          bool
 }
 
 const setDoc = `
@@ -38,6 +44,27 @@ size of this value is 5M.
 
 Option values may be any UTF-8 encoded string. UTF-8 is accepted on the command
 line and in configuration files.
+
+With --revert, the service's configuration is changed back to what it was
+after the given revision of its history, as shown by "juju get --history";
+revision 0 is the configuration before any recorded change. Settings that
+appear to hold secrets are not recorded in the history, so they are left
+unchanged. Changes made before the service's charm was last upgraded cannot
+be reverted.
+
+Example:
+  juju set --revert wordpress 3
+
+With --dry-run, the changes are checked against the options of the
+service's charm without being applied. Every unknown option and invalid
+value is reported; if there are none, the changes to the effective value
+of each setting are shown, with values left at the charm's default marked
+as such.
+
+Example:
+  juju set --dry-run wordpress tuning=optimized
+  -tuning: "single" (default)
+  +tuning: "optimized"
 `
 
 const maxValueSize = 5242880
@@ -53,6 +80,8 @@ func (c *SetCommand) Info() *cmd.Info {
 
 func (c *SetCommand) SetFlags(f *gnuflag.FlagSet) {
 	f.Var(&c.SettingsYAML, "config", "path to yaml-formatted service config")
+	f.BoolVar(&c.Revert, "revert", false, "revert the service config to the given revision of its history")
+	f.BoolVar(&c.DryRun, "dry-run", false, "check and show the changes without applying them")
 }
 
 func (c *SetCommand) Init(args []string) error {
@@ -63,6 +92,23 @@ func (c *SetCommand) Init(args []string) error {
 		return errors.New("cannot specify --config when using key=value arguments")
 	}
 	c.ServiceName = args[0]
+	if c.Revert {
+		if c.DryRun {
+			return errors.New("cannot specify --dry-run with --revert")
+		}
+		if c.SettingsYAML.Path != "" {
+			return errors.New("cannot specify --config with --revert")
+		}
+		if len(args) < 2 {
+			return errors.New("no revision specified")
+		}
+		revision, err := strconv.Atoi(args[1])
+		if err != nil || revision < 0 {
+			return fmt.Errorf("invalid revision %q", args[1])
+		}
+		c.RevertRevision = revision
+		return cmd.CheckEmpty(args[2:])
+	}
 	settings, err := keyvalues.Parse(args[1:], true)
 	if err != nil {
 		return err
@@ -73,17 +119,31 @@ func (c *SetCommand) Init(args []string) error {
 
 // Run updates the configuration of a service.
 func (c *SetCommand) Run(ctx *cmd.Context) error {
-	api, err := c.NewAPIClient()
+	root, err := c.NewAPIRoot()
 	if err != nil {
 		return err
 	}
-	defer api.Close()
+	defer root.Close()
+	api := root.Client()
 
+	if c.Revert {
+		skipped, err := api.ServiceConfigRevert(c.ServiceName, c.RevertRevision)
+		if err != nil {
+			return block.ProcessBlockedError(err, block.BlockChange)
+		}
+		for _, key := range skipped {
+			ctx.Infof("setting %q was left unchanged: its values are not recorded", key)
+		}
+		return nil
+	}
 	if c.SettingsYAML.Path != "" {
 		b, err := c.SettingsYAML.Read(ctx)
 		if err != nil {
 			return err
 		}
+		if c.DryRun {
+			return c.validate(ctx, service.NewClient(root), nil, string(b))
+		}
 		return block.ProcessBlockedError(api.ServiceSetYAML(c.ServiceName, string(b)), block.BlockChange)
 	} else if len(c.SettingsStrings) == 0 {
 		return nil
@@ -112,6 +172,9 @@ func (c *SetCommand) Run(ctx *cmd.Context) error {
 		}
 		settings[k] = nv
 	}
+	if c.DryRun {
+		return c.validate(ctx, service.NewClient(root), settings, "")
+	}
 
 	result, err := api.ServiceGet(c.ServiceName)
 	if err != nil {
@@ -133,6 +196,45 @@ func (c *SetCommand) Run(ctx *cmd.Context) error {
 	return block.ProcessBlockedError(api.ServiceSet(c.ServiceName, settings), block.BlockChange)
 }
 
+// validate checks the given changes to the service's configuration
+// without applying them, and shows the changes to the effective values.
+func (c *SetCommand) validate(ctx *cmd.Context, client *service.Client, settings map[string]string, yaml string) error {
+	result, err := client.ValidateConfig(c.ServiceName, settings, yaml)
+	if err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("invalid settings:\n  %s", strings.Join(result.Errors, "\n  "))
+	}
+	if len(result.Changes) == 0 {
+		ctx.Infof("no changes")
+		return nil
+	}
+	for _, change := range result.Changes {
+		fmt.Fprintf(ctx.Stdout, "-%s: %s\n", change.Key, formatSettingValue(change.OldValue, change.OldDefault))
+		fmt.Fprintf(ctx.Stdout, "+%s: %s\n", change.Key, formatSettingValue(change.NewValue, change.NewDefault))
+	}
+	return nil
+}
+
+// formatSettingValue returns the value of a setting in a form suitable
+// for output.
+func formatSettingValue(value interface{}, isDefault bool) string {
+	var text string
+	switch value := value.(type) {
+	case nil:
+		return "(unset)"
+	case string:
+		text = strconv.Quote(value)
+	default:
+		text = fmt.Sprint(value)
+	}
+	if isDefault {
+		text += " (default)"
+	}
+	return text
+}
+
 // readValue reads the value of an option out of the named file.
 // An empty content is valid, like in parsing the options. The upper
 // size is 5M.
@@ -122,6 +122,76 @@ func (s *SetSuite) TestSetConfig(c *gc.C) {
 	})
 }
 
+func (s *SetSuite) TestRevert(c *gc.C) {
+	assertSetSuccess(c, s.dir, s.svc, []string{
+		"username=hello",
+	}, charm.Settings{
+		"username": "hello",
+	})
+	assertSetSuccess(c, s.dir, s.svc, []string{
+		"username=goodbye",
+		"outlook=hello@world.tld",
+	}, charm.Settings{
+		"username": "goodbye",
+		"outlook":  "hello@world.tld",
+	})
+	assertSetSuccess(c, s.dir, s.svc, []string{
+		"--revert", "1",
+	}, charm.Settings{
+		"username": "hello",
+	})
+	history, err := s.svc.ConfigHistory()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(history, gc.HasLen, 3)
+	c.Assert(history[2].User, gc.Equals, s.AdminUserTag(c).String())
+}
+
+func (s *SetSuite) TestRevertFail(c *gc.C) {
+	assertSetFail(c, s.dir, []string{"--revert"}, "error: no revision specified\n")
+	assertSetFail(c, s.dir, []string{"--revert", "latest"}, "error: invalid revision \"latest\"\n")
+	assertSetFail(c, s.dir, []string{"--revert", "1", "username=hello"}, "error: unrecognized args: \\[\"username=hello\"\\]\n")
+	assertSetFail(c, s.dir, []string{"--revert", "1"}, "error: cannot revert service \"dummy-service\" to config revision 1: config revision 1 not found\n")
+}
+
+func (s *SetSuite) TestDryRun(c *gc.C) {
+	assertSetSuccess(c, s.dir, s.svc, []string{
+		"username=hello",
+	}, charm.Settings{
+		"username": "hello",
+	})
+	ctx, err := coretesting.RunCommandInDir(c, envcmd.Wrap(&SetCommand{}), []string{
+		"dummy-service", "--dry-run", "username=", "title=Nearly There", "outlook=hello",
+	}, s.dir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, ""+
+		"-outlook: (unset)\n"+
+		"+outlook: \"hello\"\n"+
+		"-title: \"My Title\" (default)\n"+
+		"+title: \"Nearly There\"\n"+
+		"-username: \"hello\"\n"+
+		"+username: \"\"\n",
+	)
+
+	// Nothing was changed.
+	settings, err := s.svc.ConfigSettings()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(settings, gc.DeepEquals, charm.Settings{"username": "hello"})
+
+	_, err = coretesting.RunCommandInDir(c, envcmd.Wrap(&SetCommand{}), []string{
+		"dummy-service", "--dry-run", "--config", "testconfig.yaml",
+	}, s.dir)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *SetSuite) TestDryRunInvalid(c *gc.C) {
+	_, err := coretesting.RunCommandInDir(c, envcmd.Wrap(&SetCommand{}), []string{
+		"dummy-service", "--dry-run", "skill-level=lots", "colour=blue", "title=There",
+	}, s.dir)
+	c.Assert(err, gc.ErrorMatches, `invalid settings:
+  .*unknown option "colour".*
+  .*option "skill-level" expected int, got "lots".*`)
+}
+
 func (s *SetSuite) TestBlockSetConfig(c *gc.C) {
 	// Block operation
 	s.AssertConfigParameterUpdated(c, "block-all-changes", true)
@@ -0,0 +1,85 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package space
+
+import (
+	"net"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/cmd/juju/block"
+	"github.com/juju/juju/network"
+)
+
+const CreateCommandDoc = `
+Create a space, optionally holding some subnets.
+
+Space names consist of lower case letters and digits, with single
+hyphens between them. The subnets are given by CIDR, and must be known
+to juju but not yet be in a space; use "juju subnet add" to add other
+subnets to the space later.
+
+Examples:
+
+  # Create the "storage" space holding two subnets.
+  juju space create storage 10.10.0.0/24 10.10.1.0/24
+`
+
+// CreateCommand creates a space.
+type CreateCommand struct {
+	SpaceCommandBase
+	Name    string
+	Subnets []string
+}
+
+// Info implements Command.Info.
+func (c *CreateCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "create",
+		Args:    "<name> [<CIDR> ...]",
+		Purpose: "create a space",
+		Doc:     CreateCommandDoc,
+	}
+}
+
+// Init implements Command.Init.
+func (c *CreateCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return errors.New("space name must be specified")
+	}
+	c.Name, c.Subnets = args[0], args[1:]
+	if !network.IsValidSpace(c.Name) {
+		return errors.Errorf("invalid space name %q", c.Name)
+	}
+	for _, cidr := range c.Subnets {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return errors.Errorf("invalid subnet CIDR %q", cidr)
+		}
+	}
+	return nil
+}
+
+// CreateSpaceAPI defines the spaces API methods that the create command
+// uses.
+type CreateSpaceAPI interface {
+	CreateSpace(name string, subnets []string) error
+	Close() error
+}
+
+var getCreateSpaceAPI = func(c *CreateCommand) (CreateSpaceAPI, error) {
+	return c.NewSpacesClient()
+}
+
+// Run implements Command.Run.
+func (c *CreateCommand) Run(ctx *cmd.Context) error {
+	client, err := getCreateSpaceAPI(c)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	err = client.CreateSpace(c.Name, c.Subnets)
+	return block.ProcessBlockedError(err, block.BlockChange)
+}
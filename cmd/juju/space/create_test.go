@@ -0,0 +1,77 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package space_test
+
+import (
+	"github.com/juju/cmd"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/cmd/juju/space"
+	"github.com/juju/juju/testing"
+)
+
+type createSpaceCommandSuite struct {
+	testing.FakeJujuHomeSuite
+	mockAPI *fakeCreateSpaceAPI
+}
+
+var _ = gc.Suite(&createSpaceCommandSuite{})
+
+type fakeCreateSpaceAPI struct {
+	name    string
+	subnets []string
+}
+
+func (*fakeCreateSpaceAPI) Close() error {
+	return nil
+}
+
+func (f *fakeCreateSpaceAPI) CreateSpace(name string, subnets []string) error {
+	f.name = name
+	f.subnets = subnets
+	return nil
+}
+
+func (s *createSpaceCommandSuite) SetUpTest(c *gc.C) {
+	s.FakeJujuHomeSuite.SetUpTest(c)
+	s.mockAPI = &fakeCreateSpaceAPI{}
+	s.PatchValue(space.GetCreateSpaceAPI, func(c *space.CreateCommand) (space.CreateSpaceAPI, error) {
+		return s.mockAPI, nil
+	})
+}
+
+func runCreateCommand(c *gc.C, args ...string) (*cmd.Context, error) {
+	return testing.RunCommand(c, envcmd.Wrap(&space.CreateCommand{}), args...)
+}
+
+func (s *createSpaceCommandSuite) TestCreate(c *gc.C) {
+	_, err := runCreateCommand(c, "storage", "10.10.0.0/24", "10.10.1.0/24")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.mockAPI.name, gc.Equals, "storage")
+	c.Assert(s.mockAPI.subnets, jc.DeepEquals, []string{"10.10.0.0/24", "10.10.1.0/24"})
+}
+
+func (s *createSpaceCommandSuite) TestCreateWithoutSubnets(c *gc.C) {
+	_, err := runCreateCommand(c, "storage")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.mockAPI.name, gc.Equals, "storage")
+	c.Assert(s.mockAPI.subnets, gc.HasLen, 0)
+}
+
+func (*createSpaceCommandSuite) TestInitErrors(c *gc.C) {
+	for i, test := range []struct {
+		args []string
+		err  string
+	}{
+		{nil, "space name must be specified"},
+		{[]string{"Storage"}, `invalid space name "Storage"`},
+		{[]string{"storage", "10.10.0.0"}, `invalid subnet CIDR "10.10.0.0"`},
+	} {
+		c.Logf("test %d: %v", i, test.args)
+		_, err := runCreateCommand(c, test.args...)
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+}
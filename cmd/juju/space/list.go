@@ -0,0 +1,101 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package space
+
+import (
+	"fmt"
+
+	"github.com/juju/cmd"
+	"launchpad.net/gnuflag"
+
+	"github.com/juju/juju/apiserver/params"
+)
+
+const ListCommandDoc = `
+List the spaces in the Juju environment, and the subnets in each.
+`
+
+// ListCommand shows the spaces in the environment.
+type ListCommand struct {
+	SpaceCommandBase
+	out cmd.Output
+}
+
+// Info implements Command.Info.
+func (c *ListCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "list",
+		Purpose: "shows the spaces and their subnets",
+		Doc:     ListCommandDoc,
+	}
+}
+
+// SetFlags implements Command.SetFlags.
+func (c *ListCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.SpaceCommandBase.SetFlags(f)
+	c.out.AddFlags(f, "yaml", map[string]cmd.Formatter{
+		"yaml": cmd.FormatYaml,
+		"json": cmd.FormatJson,
+	})
+}
+
+// Init implements Command.Init.
+func (c *ListCommand) Init(args []string) error {
+	return cmd.CheckEmpty(args)
+}
+
+// ListSpacesAPI defines the spaces API methods that the list command
+// uses.
+type ListSpacesAPI interface {
+	ListSpaces() ([]params.Space, error)
+	Close() error
+}
+
+var getListSpacesAPI = func(c *ListCommand) (ListSpacesAPI, error) {
+	return c.NewSpacesClient()
+}
+
+// SpaceInfo defines the serialization behaviour of a space.
+type SpaceInfo struct {
+	Subnets map[string]SubnetInfo `yaml:"subnets" json:"subnets"`
+}
+
+// SubnetInfo defines the serialization behaviour of a subnet in a
+// space.
+type SubnetInfo struct {
+	ProviderId string `yaml:"provider-id,omitempty" json:"provider-id,omitempty"`
+	VLANTag    int    `yaml:"vlan-tag,omitempty" json:"vlan-tag,omitempty"`
+	Zone       string `yaml:"zone,omitempty" json:"zone,omitempty"`
+}
+
+// Run implements Command.Run.
+func (c *ListCommand) Run(ctx *cmd.Context) error {
+	client, err := getListSpacesAPI(c)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	spaces, err := client.ListSpaces()
+	if err != nil {
+		return err
+	}
+	if len(spaces) == 0 {
+		fmt.Fprintf(ctx.Stdout, "no spaces found\n")
+		return nil
+	}
+	output := make(map[string]SpaceInfo)
+	for _, space := range spaces {
+		info := SpaceInfo{Subnets: make(map[string]SubnetInfo)}
+		for _, subnet := range space.Subnets {
+			info.Subnets[subnet.CIDR] = SubnetInfo{
+				ProviderId: subnet.ProviderId,
+				VLANTag:    subnet.VLANTag,
+				Zone:       subnet.Zone,
+			}
+		}
+		output[space.Name] = info
+	}
+	return c.out.Write(ctx, output)
+}
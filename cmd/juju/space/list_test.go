@@ -0,0 +1,87 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package space_test
+
+import (
+	"github.com/juju/cmd"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/cmd/juju/space"
+	"github.com/juju/juju/testing"
+)
+
+type listSpacesCommandSuite struct {
+	testing.FakeJujuHomeSuite
+	mockAPI *fakeListSpacesAPI
+}
+
+var _ = gc.Suite(&listSpacesCommandSuite{})
+
+type fakeListSpacesAPI struct {
+	spaces []params.Space
+}
+
+func (*fakeListSpacesAPI) Close() error {
+	return nil
+}
+
+func (f *fakeListSpacesAPI) ListSpaces() ([]params.Space, error) {
+	return f.spaces, nil
+}
+
+func (s *listSpacesCommandSuite) SetUpTest(c *gc.C) {
+	s.FakeJujuHomeSuite.SetUpTest(c)
+	s.mockAPI = &fakeListSpacesAPI{}
+	s.PatchValue(space.GetListSpacesAPI, func(c *space.ListCommand) (space.ListSpacesAPI, error) {
+		return s.mockAPI, nil
+	})
+}
+
+func runListCommand(c *gc.C, args ...string) (*cmd.Context, error) {
+	return testing.RunCommand(c, envcmd.Wrap(&space.ListCommand{}), args...)
+}
+
+func (s *listSpacesCommandSuite) TestListNone(c *gc.C) {
+	context, err := runListCommand(c)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(testing.Stdout(context), gc.Equals, "no spaces found\n")
+}
+
+func (s *listSpacesCommandSuite) TestListFormatYaml(c *gc.C) {
+	s.mockAPI.spaces = []params.Space{{
+		Name: "internal",
+		Subnets: []params.Subnet{
+			{CIDR: "10.0.0.0/24", ProviderId: "subnet-0", Zone: "zone1"},
+			{CIDR: "10.0.1.0/24", VLANTag: 42},
+		},
+	}, {
+		Name: "public",
+	}}
+	context, err := runListCommand(c, "--format", "yaml")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(testing.Stdout(context), gc.Equals, ""+
+		"internal:\n"+
+		"  subnets:\n"+
+		"    10.0.0.0/24:\n"+
+		"      provider-id: subnet-0\n"+
+		"      zone: zone1\n"+
+		"    10.0.1.0/24:\n"+
+		"      vlan-tag: 42\n"+
+		"public:\n"+
+		"  subnets: {}\n")
+}
+
+func (s *listSpacesCommandSuite) TestListFormatJson(c *gc.C) {
+	s.mockAPI.spaces = []params.Space{{
+		Name:    "internal",
+		Subnets: []params.Subnet{{CIDR: "10.0.0.0/24"}},
+	}}
+	context, err := runListCommand(c, "--format", "json")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(testing.Stdout(context), gc.Equals,
+		`{"internal":{"subnets":{"10.0.0.0/24":{}}}}`+"\n")
+}
@@ -0,0 +1,52 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package space
+
+import (
+	"github.com/juju/cmd"
+
+	"github.com/juju/juju/api/spaces"
+	"github.com/juju/juju/cmd/envcmd"
+)
+
+const spaceCommandDoc = `
+"juju space" is used to manage the spaces in the Juju environment.
+
+A space is a named group of subnets. The endpoints of a service can be
+bound to spaces when it is deployed, with "juju deploy --bind", so that
+its units use their addresses in the bound spaces on those endpoints.
+Subnets are added to spaces with "juju space create" or "juju subnet add".
+`
+
+const spaceCommandPurpose = "manage network spaces"
+
+// NewSuperCommand creates the space supercommand and registers the
+// subcommands that it supports.
+func NewSuperCommand() cmd.Command {
+	spacecmd := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:        "space",
+		Doc:         spaceCommandDoc,
+		UsagePrefix: "juju",
+		Purpose:     spaceCommandPurpose,
+	})
+	spacecmd.Register(envcmd.Wrap(&CreateCommand{}))
+	spacecmd.Register(envcmd.Wrap(&ListCommand{}))
+	return spacecmd
+}
+
+// SpaceCommandBase is a helper base structure that has a method to get
+// the spaces client.
+type SpaceCommandBase struct {
+	envcmd.EnvCommandBase
+}
+
+// NewSpacesClient returns a spaces client for the root api endpoint
+// that the environment command returns.
+func (c *SpaceCommandBase) NewSpacesClient() (*spaces.Client, error) {
+	root, err := c.NewAPIRoot()
+	if err != nil {
+		return nil, err
+	}
+	return spaces.NewClient(root), nil
+}
@@ -0,0 +1,25 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package space_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/cmd/juju/space"
+	"github.com/juju/juju/testing"
+)
+
+type spaceSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&spaceSuite{})
+
+func (s *spaceSuite) TestHelp(c *gc.C) {
+	ctx, err := testing.RunCommand(c, space.NewSuperCommand(), "--help")
+	c.Assert(err, jc.ErrorIsNil)
+	namesFound := testing.ExtractCommandsFromHelpOutput(ctx)
+	c.Assert(namesFound, gc.DeepEquals, []string{"create", "help", "list"})
+}
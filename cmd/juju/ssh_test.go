@@ -145,6 +145,22 @@ func (s *SSHSuite) TestSSHCommandEnvironProxySSH(c *gc.C) {
 	c.Check(ctx.Stdout.(*bytes.Buffer).String(), gc.Equals, sshArgsNoProxy+"ubuntu@dummyenv-0.dns\n")
 }
 
+func (s *SSHSuite) TestSSHCommandAuditsConnection(c *gc.C) {
+	s.makeMachines(1, c, true)
+	ctx := coretesting.Context(c)
+	jujucmd := cmd.NewSuperCommand(cmd.SuperCommandParams{})
+	jujucmd.Register(envcmd.Wrap(&SSHCommand{}))
+	code := cmd.Main(jujucmd, ctx, []string{"ssh", "ubuntu@0"})
+	c.Assert(code, gc.Equals, 0)
+
+	accesses, err := s.State.SSHAccesses()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(accesses, gc.HasLen, 1)
+	c.Assert(accesses[0].UserTag(), gc.Equals, s.AdminUserTag(c))
+	c.Assert(accesses[0].Target(), gc.Equals, "0")
+	c.Assert(accesses[0].Address(), gc.Equals, "dummyenv-0.internal")
+}
+
 func (s *SSHSuite) TestSSHWillWorkInUpgrade(c *gc.C) {
 	// Check the API client interface used by "juju ssh" against what
 	// the API server will allow during upgrades. Ensure that the API
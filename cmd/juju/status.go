@@ -6,6 +6,8 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/juju/cmd"
 	"launchpad.net/gnuflag"
@@ -24,6 +26,8 @@ type StatusCommand struct {
 	envcmd.EnvCommandBase
 	out      cmd.Output
 	patterns []string
+	refresh  bool
+	load     bool
 }
 
 var statusDoc = `
@@ -55,6 +59,17 @@ will be displayed.
 Wildcards ('*') may be specified in service/unit names to match any sequence
 of characters. For example, 'nova-*' will match any service whose name begins
 with 'nova-': 'nova-compute', 'nova-volume', etc.
+
+Machine addresses and instance states are normally polled from the
+provider periodically, so they may lag behind changes made there. The
+--refresh option asks for every provisioned machine's instance to be
+polled immediately, and waits for the results before reporting status.
+The time each machine's instance was last polled is shown as
+last-polled in the yaml and json formats.
+
+The --load option adds the current load on the API server answering
+the request to the yaml and json formats: the connections and logins
+of agents and users, and how many of them were rejected or throttled.
 `
 
 func (c *StatusCommand) Info() *cmd.Info {
@@ -77,6 +92,8 @@ func (c *StatusCommand) SetFlags(f *gnuflag.FlagSet) {
 		"tabular": FormatTabular,
 		"summary": FormatSummary,
 	})
+	f.BoolVar(&c.refresh, "refresh", false, "poll the provider for instance addresses and states before reporting")
+	f.BoolVar(&c.load, "load", false, "report the current load on the API server")
 }
 
 func (c *StatusCommand) Init(args []string) error {
@@ -93,6 +110,8 @@ Error details:
 
 type statusAPI interface {
 	Status(patterns []string) (*api.Status, error)
+	RefreshInstances() ([]string, error)
+	APIServerLoad() (params.APIServerLoad, error)
 	Close() error
 }
 
@@ -108,6 +127,16 @@ func (c *StatusCommand) Run(ctx *cmd.Context) error {
 	}
 	defer apiclient.Close()
 
+	if c.refresh {
+		stale, err := apiclient.RefreshInstances()
+		if err != nil {
+			return errors.Annotate(err, "cannot refresh instances")
+		}
+		if len(stale) > 0 {
+			fmt.Fprintf(ctx.Stderr, "WARNING: instances of machines %s could not be polled; their addresses and states may be out of date\n", strings.Join(stale, ", "))
+		}
+	}
+
 	status, err := apiclient.Status(c.patterns)
 	if err != nil {
 		if status == nil {
@@ -121,14 +150,64 @@ func (c *StatusCommand) Run(ctx *cmd.Context) error {
 	}
 
 	result := newStatusFormatter(status).format()
+	if c.load {
+		load, err := apiclient.APIServerLoad()
+		if err != nil {
+			return errors.Annotate(err, "cannot get API server load")
+		}
+		result.APIServerLoad = formatAPIServerLoad(load)
+	}
 	return c.out.Write(ctx, result)
 }
 
 type formattedStatus struct {
-	Environment string                   `json:"environment"`
-	Machines    map[string]machineStatus `json:"machines"`
-	Services    map[string]serviceStatus `json:"services"`
-	Networks    map[string]networkStatus `json:"networks,omitempty" yaml:",omitempty"`
+	Environment    string                         `json:"environment"`
+	Machines       map[string]machineStatus       `json:"machines"`
+	Services       map[string]serviceStatus       `json:"services"`
+	RemoteServices map[string]remoteServiceStatus `json:"remote-services,omitempty" yaml:"remote-services,omitempty"`
+	Networks       map[string]networkStatus       `json:"networks,omitempty" yaml:",omitempty"`
+	APIServerLoad  *apiServerLoad                 `json:"api-server-load,omitempty" yaml:"api-server-load,omitempty"`
+}
+
+type apiServerLoad struct {
+	Agents clientLoad `json:"agents" yaml:"agents"`
+	Users  clientLoad `json:"users" yaml:"users"`
+}
+
+// clientLoad reports the load placed on the API server by one kind of
+// client. Unlimited maximums are left out.
+type clientLoad struct {
+	Connections       int   `json:"connections" yaml:"connections"`
+	MaxConnections    int   `json:"max-connections,omitempty" yaml:"max-connections,omitempty"`
+	LoginsInProgress  int   `json:"logins-in-progress" yaml:"logins-in-progress"`
+	MaxLogins         int   `json:"max-logins,omitempty" yaml:"max-logins,omitempty"`
+	LoginsWaiting     int   `json:"logins-waiting" yaml:"logins-waiting"`
+	LoginsRejected    int64 `json:"logins-rejected" yaml:"logins-rejected"`
+	RequestsThrottled int64 `json:"requests-throttled" yaml:"requests-throttled"`
+}
+
+func formatAPIServerLoad(load params.APIServerLoad) *apiServerLoad {
+	return &apiServerLoad{
+		Agents: formatClientLoad(load.Agents),
+		Users:  formatClientLoad(load.Users),
+	}
+}
+
+func formatClientLoad(load params.ClientLoad) clientLoad {
+	out := clientLoad{
+		Connections:       load.Connections,
+		LoginsInProgress:  load.LoginsInProgress,
+		LoginsWaiting:     load.LoginsWaiting,
+		LoginsRejected:    load.LoginsRejected,
+		RequestsThrottled: load.RequestsThrottled,
+	}
+	if load.MaxConnections > 0 {
+		out.MaxConnections = load.MaxConnections
+	}
+	if load.MaxLogins > 0 {
+		out.MaxLogins = load.MaxLogins
+	}
+	return out
 }
 
 type errorStatus struct {
@@ -143,12 +222,27 @@ type machineStatus struct {
 	DNSName        string                   `json:"dns-name,omitempty" yaml:"dns-name,omitempty"`
 	InstanceId     instance.Id              `json:"instance-id,omitempty" yaml:"instance-id,omitempty"`
 	InstanceState  string                   `json:"instance-state,omitempty" yaml:"instance-state,omitempty"`
+	LastPolled     string                   `json:"last-polled,omitempty" yaml:"last-polled,omitempty"`
 	Life           string                   `json:"life,omitempty" yaml:"life,omitempty"`
 	Series         string                   `json:"series,omitempty" yaml:"series,omitempty"`
 	Id             string                   `json:"-" yaml:"-"`
 	Containers     map[string]machineStatus `json:"containers,omitempty" yaml:"containers,omitempty"`
 	Hardware       string                   `json:"hardware,omitempty" yaml:"hardware,omitempty"`
 	HAStatus       string                   `json:"state-server-member-status,omitempty" yaml:"state-server-member-status,omitempty"`
+
+	// ContainerTemplates maps container kind to series to the
+	// status of the template the machine clones containers from.
+	ContainerTemplates map[string]map[string]containerTemplateStatus `json:"container-templates,omitempty" yaml:"container-templates,omitempty"`
+}
+
+// containerTemplateStatus describes a container template on a machine.
+// Its status is "warm" once the template has been built, "building"
+// while a request to build it is pending, and "failed" if the last
+// build failed.
+type containerTemplateStatus struct {
+	Status string `json:"status" yaml:"status"`
+	Built  string `json:"built,omitempty" yaml:"built,omitempty"`
+	Error  string `json:"error,omitempty" yaml:"error,omitempty"`
 }
 
 // A goyaml bug means we can't declare these types
@@ -179,10 +273,13 @@ type serviceStatus struct {
 	CanUpgradeTo  string                `json:"can-upgrade-to,omitempty" yaml:"can-upgrade-to,omitempty"`
 	Exposed       bool                  `json:"exposed" yaml:"exposed"`
 	Life          string                `json:"life,omitempty" yaml:"life,omitempty"`
+	Leader        string                `json:"leader,omitempty" yaml:"leader,omitempty"`
 	Relations     map[string][]string   `json:"relations,omitempty" yaml:"relations,omitempty"`
 	Networks      map[string][]string   `json:"networks,omitempty" yaml:"networks,omitempty"`
 	SubordinateTo []string              `json:"subordinate-to,omitempty" yaml:"subordinate-to,omitempty"`
 	Units         map[string]unitStatus `json:"units,omitempty" yaml:"units,omitempty"`
+
+	PlacementViolations []string `json:"placement-violations,omitempty" yaml:"placement-violations,omitempty"`
 }
 
 type serviceStatusNoMarshal serviceStatus
@@ -233,6 +330,29 @@ func (s unitStatus) GetYAML() (tag string, value interface{}) {
 	return "", unitStatusNoMarshal(s)
 }
 
+type remoteServiceStatus struct {
+	Err         error               `json:"-" yaml:",omitempty"`
+	Environment string              `json:"environment" yaml:"environment"`
+	Life        string              `json:"life,omitempty" yaml:"life,omitempty"`
+	Relations   map[string][]string `json:"relations,omitempty" yaml:"relations,omitempty"`
+}
+
+func (s remoteServiceStatus) MarshalJSON() ([]byte, error) {
+	if s.Err != nil {
+		return json.Marshal(errorStatus{s.Err.Error()})
+	}
+	type sNoMethods remoteServiceStatus
+	return json.Marshal(sNoMethods(s))
+}
+
+func (s remoteServiceStatus) GetYAML() (tag string, value interface{}) {
+	if s.Err != nil {
+		return "", errorStatus{s.Err.Error()}
+	}
+	type sNoMethods remoteServiceStatus
+	return "", sNoMethods(s)
+}
+
 type networkStatus struct {
 	Err        error      `json:"-" yaml:",omitempty"`
 	ProviderId network.Id `json:"provider-id" yaml:"provider-id"`
@@ -289,6 +409,17 @@ func (sf *statusFormatter) format() formattedStatus {
 	for sn, s := range sf.status.Services {
 		out.Services[sn] = sf.formatService(sn, s)
 	}
+	for sn, s := range sf.status.RemoteServices {
+		if out.RemoteServices == nil {
+			out.RemoteServices = make(map[string]remoteServiceStatus)
+		}
+		out.RemoteServices[sn] = remoteServiceStatus{
+			Err:         s.Err,
+			Environment: s.Environment,
+			Life:        s.Life,
+			Relations:   s.Relations,
+		}
+	}
 	for k, n := range sf.status.Networks {
 		if out.Networks == nil {
 			out.Networks = make(map[string]networkStatus)
@@ -337,10 +468,24 @@ func (sf *statusFormatter) formatMachine(machine api.MachineStatus) machineStatu
 		}
 	}
 
+	if machine.InstancePolled != nil {
+		out.LastPolled = machine.InstancePolled.UTC().Format(time.RFC3339)
+	}
+
 	for k, m := range machine.Containers {
 		out.Containers[k] = sf.formatMachine(m)
 	}
 
+	for _, template := range machine.ContainerTemplates {
+		if out.ContainerTemplates == nil {
+			out.ContainerTemplates = make(map[string]map[string]containerTemplateStatus)
+		}
+		if out.ContainerTemplates[template.Kind] == nil {
+			out.ContainerTemplates[template.Kind] = make(map[string]containerTemplateStatus)
+		}
+		out.ContainerTemplates[template.Kind][template.Series] = formatContainerTemplate(template)
+	}
+
 	for _, job := range machine.Jobs {
 		if job == multiwatcher.JobManageEnviron {
 			out.HAStatus = makeHAStatus(machine.HasVote, machine.WantsVote)
@@ -350,17 +495,37 @@ func (sf *statusFormatter) formatMachine(machine api.MachineStatus) machineStatu
 	return out
 }
 
+func formatContainerTemplate(template params.ContainerTemplate) containerTemplateStatus {
+	var out containerTemplateStatus
+	switch {
+	case template.PrimeRequested != nil:
+		out.Status = "building"
+	case template.Error != "":
+		out.Status = "failed"
+		out.Error = template.Error
+	default:
+		out.Status = "warm"
+	}
+	if template.Built != nil {
+		out.Built = template.Built.UTC().Format(time.RFC3339)
+	}
+	return out
+}
+
 func (sf *statusFormatter) formatService(name string, service api.ServiceStatus) serviceStatus {
 	out := serviceStatus{
 		Err:           service.Err,
 		Charm:         service.Charm,
 		Exposed:       service.Exposed,
 		Life:          service.Life,
+		Leader:        service.Leader,
 		Relations:     service.Relations,
 		Networks:      make(map[string][]string),
 		CanUpgradeTo:  service.CanUpgradeTo,
 		SubordinateTo: service.SubordinateTo,
 		Units:         make(map[string]unitStatus),
+
+		PlacementViolations: service.PlacementViolations,
 	}
 	if len(service.Networks.Enabled) > 0 {
 		out.Networks["enabled"] = service.Networks.Enabled
@@ -27,6 +27,7 @@ func FormatOneline(value interface{}) ([]byte, error) {
 		return nil, errors.Errorf("expected value of type %T, got %T", fs, value)
 	}
 	var out bytes.Buffer
+	leaders := serviceLeaders(fs.Services)
 
 	pprint := func(uName string, u unitStatus, level int) {
 		var fmtPorts string
@@ -34,7 +35,7 @@ func FormatOneline(value interface{}) ([]byte, error) {
 			fmtPorts = fmt.Sprintf(" %s", strings.Join(u.OpenedPorts, ", "))
 		}
 		fmt.Fprintf(&out, indent("\n", level*2, "- %s: %s (%v)%v"),
-			uName,
+			markLeader(uName, leaders),
 			u.PublicAddress,
 			u.AgentState,
 			fmtPorts,
@@ -80,6 +81,7 @@ func FormatTabular(value interface{}) ([]byte, error) {
 	tw.Flush()
 
 	units := make(map[string]unitStatus)
+	leaders := serviceLeaders(fs.Services)
 
 	p("\n[Services]")
 	p("NAME\tEXPOSED\tCHARM")
@@ -92,9 +94,18 @@ func FormatTabular(value interface{}) ([]byte, error) {
 	}
 	tw.Flush()
 
+	if len(fs.RemoteServices) > 0 {
+		p("\n[Remote services]")
+		p("NAME\tENVIRONMENT")
+		for _, svcName := range sortStrings(stringKeysFromMap(fs.RemoteServices)) {
+			p(svcName, fs.RemoteServices[svcName].Environment)
+		}
+		tw.Flush()
+	}
+
 	pUnit := func(name string, u unitStatus, level int) {
 		p(
-			indent("", level*2, name),
+			indent("", level*2, markLeader(name, leaders)),
 			u.AgentState,
 			u.AgentVersion,
 			u.Machine,
@@ -287,6 +298,27 @@ func (f *summaryFormatter) aggregateServiceAndUnitStates(services map[string]ser
 	return svcExposure
 }
 
+// serviceLeaders returns the names of the units which currently lead
+// their services.
+func serviceLeaders(services map[string]serviceStatus) set.Strings {
+	leaders := set.NewStrings()
+	for _, svc := range services {
+		if svc.Leader != "" {
+			leaders.Add(svc.Leader)
+		}
+	}
+	return leaders
+}
+
+// markLeader appends an asterisk to the name of a unit which leads its
+// service.
+func markLeader(unitName string, leaders set.Strings) string {
+	if leaders.Contains(unitName) {
+		return unitName + "*"
+	}
+	return unitName
+}
+
 // sortStrings is syntactic sugar so we can do sorts in one line.
 func sortStrings(s []string) []string {
 	sort.Strings(s)
@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/juju/cmd"
 	jc "github.com/juju/testing/checkers"
@@ -16,11 +17,14 @@ import (
 	goyaml "gopkg.in/yaml.v1"
 
 	"github.com/juju/juju/api"
+	"github.com/juju/juju/apiserver/params"
 	"github.com/juju/juju/cmd/envcmd"
 	"github.com/juju/juju/constraints"
 	"github.com/juju/juju/environs"
 	"github.com/juju/juju/instance"
 	"github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/leadership"
+	"github.com/juju/juju/lease"
 	"github.com/juju/juju/network"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/state/multiwatcher"
@@ -1640,6 +1644,48 @@ var statusTests = []testCase{
 				},
 			},
 		},
+	), test(
+		"container templates",
+		addMachine{machineId: "0", job: state.JobManageEnviron},
+		setAddresses{"0", []network.Address{network.NewAddress("dummyenv-0.dns", network.ScopeUnknown)}},
+		startAliveMachine{"0"},
+		setMachineStatus{"0", state.StatusStarted, ""},
+		addMachine{machineId: "1", job: state.JobHostUnits},
+		setAddresses{"1", []network.Address{network.NewAddress("dummyenv-1.dns", network.ScopeUnknown)}},
+		startAliveMachine{"1"},
+		setMachineStatus{"1", state.StatusStarted, ""},
+		requestContainerTemplate{"1", "trusty"},
+		requestContainerTemplate{"1", "precise"},
+		failContainerTemplate{"1", "precise", "no space left on device"},
+
+		expect{
+			"templates requested and failed",
+			M{
+				"environment": "dummyenv",
+				"machines": M{
+					"0": machine0,
+					"1": M{
+						"agent-state": "started",
+						"dns-name":    "dummyenv-1.dns",
+						"instance-id": "dummyenv-1",
+						"series":      "quantal",
+						"hardware":    "arch=amd64 cpu-cores=1 mem=1024M root-disk=8192M",
+						"container-templates": M{
+							"lxc": M{
+								"precise": M{
+									"status": "failed",
+									"error":  "no space left on device",
+								},
+								"trusty": M{
+									"status": "building",
+								},
+							},
+						},
+					},
+				},
+				"services": M{},
+			},
+		},
 	),
 }
 
@@ -1811,6 +1857,21 @@ func (as addService) step(c *gc.C, ctx *context) {
 	}
 }
 
+type setServiceLeader struct {
+	serviceName string
+	unitName    string
+}
+
+func (ssl setServiceLeader) step(c *gc.C, ctx *context) {
+	token := lease.Token{
+		Namespace:  leadership.LeadershipNamespace(ssl.serviceName),
+		Id:         ssl.unitName,
+		Expiration: time.Now().Add(time.Hour),
+	}
+	_, err := ctx.st.ClaimLease(token, time.Now())
+	c.Assert(err, jc.ErrorIsNil)
+}
+
 type setServiceExposed struct {
 	name    string
 	exposed bool
@@ -1995,6 +2056,31 @@ func (sms setMachineStatus) step(c *gc.C, ctx *context) {
 	c.Assert(err, jc.ErrorIsNil)
 }
 
+type requestContainerTemplate struct {
+	machineId string
+	series    string
+}
+
+func (rct requestContainerTemplate) step(c *gc.C, ctx *context) {
+	m, err := ctx.st.Machine(rct.machineId)
+	c.Assert(err, jc.ErrorIsNil)
+	err = m.RequestContainerTemplate(instance.LXC, rct.series)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+type failContainerTemplate struct {
+	machineId string
+	series    string
+	message   string
+}
+
+func (fct failContainerTemplate) step(c *gc.C, ctx *context) {
+	m, err := ctx.st.Machine(fct.machineId)
+	c.Assert(err, jc.ErrorIsNil)
+	err = m.SetContainerTemplateFailed(instance.LXC, fct.series, fct.message)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
 type relateServices struct {
 	ep1, ep2 string
 }
@@ -2082,9 +2168,14 @@ func (s *StatusSuite) TestStatusAllFormats(c *gc.C) {
 }
 
 type fakeApiClient struct {
-	statusReturn *api.Status
-	patternsUsed []string
-	closeCalled  bool
+	statusReturn  *api.Status
+	patternsUsed  []string
+	closeCalled   bool
+	refreshCalled bool
+	staleReturn   []string
+	refreshError  error
+	loadReturn    params.APIServerLoad
+	loadCalled    bool
 }
 
 func newFakeApiClient(statusReturn *api.Status) fakeApiClient {
@@ -2098,6 +2189,16 @@ func (a *fakeApiClient) Status(patterns []string) (*api.Status, error) {
 	return a.statusReturn, nil
 }
 
+func (a *fakeApiClient) RefreshInstances() ([]string, error) {
+	a.refreshCalled = true
+	return a.staleReturn, a.refreshError
+}
+
+func (a *fakeApiClient) APIServerLoad() (params.APIServerLoad, error) {
+	a.loadCalled = true
+	return a.loadReturn, nil
+}
+
 func (a *fakeApiClient) Close() error {
 	a.closeCalled = true
 	return nil
@@ -2264,6 +2365,7 @@ func (s *StatusSuite) TestStatusWithFormatSummary(c *gc.C) {
 		setUnitsAlive{"logging"},
 		setUnitStatus{"logging/0", state.StatusActive, "", nil},
 		setUnitStatus{"logging/1", state.StatusError, "somehow lost in all those logs", nil},
+		setServiceLeader{"mysql", "mysql/0"},
 	}
 	for _, s := range steps {
 		s.step(c, ctx)
@@ -2333,12 +2435,13 @@ func (s *StatusSuite) TestStatusWithFormatOneline(c *gc.C) {
 		setUnitsAlive{"logging"},
 		setUnitStatus{"logging/0", state.StatusActive, "", nil},
 		setUnitStatus{"logging/1", state.StatusError, "somehow lost in all those logs", nil},
+		setServiceLeader{"mysql", "mysql/0"},
 	}
 
 	ctx.run(c, steps)
 
 	const expected = `
-- mysql/0: dummyenv-2.dns (started)
+- mysql/0*: dummyenv-2.dns (started)
   - logging/1: dummyenv-2.dns (error)
 - wordpress/0: dummyenv-1.dns (started)
   - logging/0: dummyenv-1.dns (started)
@@ -2398,6 +2501,7 @@ func (s *StatusSuite) TestStatusWithFormatTabular(c *gc.C) {
 		setUnitsAlive{"logging"},
 		setUnitStatus{"logging/0", state.StatusActive, "", nil},
 		setUnitStatus{"logging/1", state.StatusError, "somehow lost in all those logs", nil},
+		setServiceLeader{"mysql", "mysql/0"},
 	}
 	for _, s := range steps {
 		s.step(c, ctx)
@@ -2422,7 +2526,7 @@ func (s *StatusSuite) TestStatusWithFormatTabular(c *gc.C) {
 			"\n"+
 			"[Units]     \n"+
 			"ID          STATE   VERSION MACHINE PORTS PUBLIC-ADDRESS \n"+
-			"mysql/0     started         2             dummyenv-2.dns \n"+
+			"mysql/0*    started         2             dummyenv-2.dns \n"+
 			"  logging/1 error                         dummyenv-2.dns \n"+
 			"wordpress/0 started         1             dummyenv-1.dns \n"+
 			"  logging/0 started                       dummyenv-1.dns \n"+
@@ -2458,6 +2562,130 @@ func (s *StatusSuite) TestStatusWithNilStatusApi(c *gc.C) {
 	c.Check(string(stderr), gc.Equals, "error: unable to obtain the current status\n")
 }
 
+func (s *StatusSuite) TestStatusRefresh(c *gc.C) {
+	polled := time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)
+	client := newFakeApiClient(&api.Status{
+		EnvironmentName: "dummyenv",
+		Machines: map[string]api.MachineStatus{
+			"0": {
+				Id:             "0",
+				InstanceId:     instance.Id("dummyenv-0"),
+				Series:         "quantal",
+				Containers:     map[string]api.MachineStatus{},
+				InstancePolled: &polled,
+			},
+			"1": {
+				Id:         "1",
+				InstanceId: instance.Id("dummyenv-1"),
+				Series:     "quantal",
+				Containers: map[string]api.MachineStatus{},
+			},
+		},
+	})
+	client.staleReturn = []string{"1"}
+	s.PatchValue(&newApiClientForStatus, func(_ *StatusCommand) (statusAPI, error) {
+		return &client, nil
+	})
+
+	code, stdout, stderr := runStatus(c, "--refresh", "--format", "json")
+	c.Assert(code, gc.Equals, 0)
+	c.Check(client.refreshCalled, jc.IsTrue)
+	c.Check(string(stderr), gc.Equals, "WARNING: instances of machines 1 could not be polled; their addresses and states may be out of date\n")
+	var result M
+	err := json.Unmarshal(stdout, &result)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(result["machines"], jc.DeepEquals, map[string]interface{}{
+		"0": map[string]interface{}{
+			"instance-id": "dummyenv-0",
+			"last-polled": "2015-06-01T12:00:00Z",
+			"series":      "quantal",
+		},
+		"1": map[string]interface{}{
+			"instance-id": "dummyenv-1",
+			"series":      "quantal",
+		},
+	})
+}
+
+func (s *StatusSuite) TestStatusRefreshError(c *gc.C) {
+	client := newFakeApiClient(&api.Status{EnvironmentName: "dummyenv"})
+	client.refreshError = fmt.Errorf("boom")
+	s.PatchValue(&newApiClientForStatus, func(_ *StatusCommand) (statusAPI, error) {
+		return &client, nil
+	})
+
+	code, _, stderr := runStatus(c, "--refresh")
+	c.Check(code, gc.Equals, 1)
+	c.Check(string(stderr), gc.Equals, "error: cannot refresh instances: boom\n")
+}
+
+func (s *StatusSuite) TestStatusWithoutRefresh(c *gc.C) {
+	client := newFakeApiClient(&api.Status{EnvironmentName: "dummyenv"})
+	s.PatchValue(&newApiClientForStatus, func(_ *StatusCommand) (statusAPI, error) {
+		return &client, nil
+	})
+
+	code, _, _ := runStatus(c)
+	c.Check(code, gc.Equals, 0)
+	c.Check(client.refreshCalled, jc.IsFalse)
+}
+
+func (s *StatusSuite) TestStatusLoad(c *gc.C) {
+	client := newFakeApiClient(&api.Status{EnvironmentName: "dummyenv"})
+	client.loadReturn = params.APIServerLoad{
+		Agents: params.ClientLoad{
+			Connections:      3,
+			MaxConnections:   -1,
+			LoginsInProgress: 1,
+			MaxLogins:        10,
+			LoginsWaiting:    2,
+		},
+		Users: params.ClientLoad{
+			Connections:       1,
+			MaxConnections:    -1,
+			MaxLogins:         -1,
+			RequestsThrottled: 4,
+		},
+	}
+	s.PatchValue(&newApiClientForStatus, func(_ *StatusCommand) (statusAPI, error) {
+		return &client, nil
+	})
+
+	code, stdout, stderr := runStatus(c, "--load", "--format", "json")
+	c.Assert(code, gc.Equals, 0, gc.Commentf("stderr: %s", stderr))
+	var result map[string]interface{}
+	err := json.Unmarshal(stdout, &result)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(result["api-server-load"], jc.DeepEquals, map[string]interface{}{
+		"agents": map[string]interface{}{
+			"connections":        3.0,
+			"logins-in-progress": 1.0,
+			"max-logins":         10.0,
+			"logins-waiting":     2.0,
+			"logins-rejected":    0.0,
+			"requests-throttled": 0.0,
+		},
+		"users": map[string]interface{}{
+			"connections":        1.0,
+			"logins-in-progress": 0.0,
+			"logins-waiting":     0.0,
+			"logins-rejected":    0.0,
+			"requests-throttled": 4.0,
+		},
+	})
+}
+
+func (s *StatusSuite) TestStatusWithoutLoad(c *gc.C) {
+	client := newFakeApiClient(&api.Status{EnvironmentName: "dummyenv"})
+	s.PatchValue(&newApiClientForStatus, func(_ *StatusCommand) (statusAPI, error) {
+		return &client, nil
+	})
+
+	code, _, _ := runStatus(c)
+	c.Check(code, gc.Equals, 0)
+	c.Check(client.loadCalled, jc.IsFalse)
+}
+
 //
 // Filtering Feature
 //
@@ -0,0 +1,107 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package subnet
+
+import (
+	"net"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"launchpad.net/gnuflag"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/cmd/juju/block"
+	"github.com/juju/juju/network"
+)
+
+const AddCommandDoc = `
+Add a subnet to a space.
+
+If juju does not yet know about the subnet, it is added with the given
+provider id, VLAN tag and availability zone. A subnet can only be in
+one space, and cannot be moved once it is in one.
+
+Examples:
+
+  # Add a subnet to the "storage" space.
+  juju subnet add 10.10.2.0/24 storage --zone us-east-1a
+`
+
+// AddCommand adds a subnet to a space.
+type AddCommand struct {
+	SubnetCommandBase
+	CIDR       string
+	Space      string
+	ProviderId string
+	VLANTag    int
+	Zone       string
+}
+
+// Info implements Command.Info.
+func (c *AddCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "add",
+		Args:    "<CIDR> <space>",
+		Purpose: "add a subnet to a space",
+		Doc:     AddCommandDoc,
+	}
+}
+
+// SetFlags implements Command.SetFlags.
+func (c *AddCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.SubnetCommandBase.SetFlags(f)
+	f.StringVar(&c.ProviderId, "provider-id", "", "the provider's id for the subnet")
+	f.IntVar(&c.VLANTag, "vlan-tag", 0, "the VLAN tag of the subnet, or 0 if it is untagged")
+	f.StringVar(&c.Zone, "zone", "", "the availability zone of the subnet")
+}
+
+// Init implements Command.Init.
+func (c *AddCommand) Init(args []string) error {
+	switch len(args) {
+	case 0:
+		return errors.New("subnet CIDR must be specified")
+	case 1:
+		return errors.New("space name must be specified")
+	}
+	c.CIDR, c.Space = args[0], args[1]
+	if _, _, err := net.ParseCIDR(c.CIDR); err != nil {
+		return errors.Errorf("invalid subnet CIDR %q", c.CIDR)
+	}
+	if !network.IsValidSpace(c.Space) {
+		return errors.Errorf("invalid space name %q", c.Space)
+	}
+	if c.VLANTag < 0 || c.VLANTag > 4094 {
+		return errors.Errorf("invalid VLAN tag %d: must be between 0 and 4094", c.VLANTag)
+	}
+	return cmd.CheckEmpty(args[2:])
+}
+
+// AddSubnetAPI defines the subnets API methods that the add command
+// uses.
+type AddSubnetAPI interface {
+	AddSubnet(subnet params.Subnet) error
+	Close() error
+}
+
+var getAddSubnetAPI = func(c *AddCommand) (AddSubnetAPI, error) {
+	return c.NewSubnetsClient()
+}
+
+// Run implements Command.Run.
+func (c *AddCommand) Run(ctx *cmd.Context) error {
+	client, err := getAddSubnetAPI(c)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	err = client.AddSubnet(params.Subnet{
+		CIDR:       c.CIDR,
+		ProviderId: c.ProviderId,
+		VLANTag:    c.VLANTag,
+		Zone:       c.Zone,
+		SpaceName:  c.Space,
+	})
+	return block.ProcessBlockedError(err, block.BlockChange)
+}
@@ -0,0 +1,77 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package subnet_test
+
+import (
+	"github.com/juju/cmd"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/cmd/juju/subnet"
+	"github.com/juju/juju/testing"
+)
+
+type addSubnetCommandSuite struct {
+	testing.FakeJujuHomeSuite
+	mockAPI *fakeAddSubnetAPI
+}
+
+var _ = gc.Suite(&addSubnetCommandSuite{})
+
+type fakeAddSubnetAPI struct {
+	subnet params.Subnet
+}
+
+func (*fakeAddSubnetAPI) Close() error {
+	return nil
+}
+
+func (f *fakeAddSubnetAPI) AddSubnet(subnet params.Subnet) error {
+	f.subnet = subnet
+	return nil
+}
+
+func (s *addSubnetCommandSuite) SetUpTest(c *gc.C) {
+	s.FakeJujuHomeSuite.SetUpTest(c)
+	s.mockAPI = &fakeAddSubnetAPI{}
+	s.PatchValue(subnet.GetAddSubnetAPI, func(c *subnet.AddCommand) (subnet.AddSubnetAPI, error) {
+		return s.mockAPI, nil
+	})
+}
+
+func runAddCommand(c *gc.C, args ...string) (*cmd.Context, error) {
+	return testing.RunCommand(c, envcmd.Wrap(&subnet.AddCommand{}), args...)
+}
+
+func (s *addSubnetCommandSuite) TestAdd(c *gc.C) {
+	_, err := runAddCommand(c, "10.10.2.0/24", "storage", "--provider-id", "subnet-2", "--vlan-tag", "42", "--zone", "zone1")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.mockAPI.subnet, jc.DeepEquals, params.Subnet{
+		CIDR:       "10.10.2.0/24",
+		ProviderId: "subnet-2",
+		VLANTag:    42,
+		Zone:       "zone1",
+		SpaceName:  "storage",
+	})
+}
+
+func (*addSubnetCommandSuite) TestInitErrors(c *gc.C) {
+	for i, test := range []struct {
+		args []string
+		err  string
+	}{
+		{nil, "subnet CIDR must be specified"},
+		{[]string{"10.10.2.0/24"}, "space name must be specified"},
+		{[]string{"10.10.2.0", "storage"}, `invalid subnet CIDR "10.10.2.0"`},
+		{[]string{"10.10.2.0/24", "Storage"}, `invalid space name "Storage"`},
+		{[]string{"10.10.2.0/24", "storage", "--vlan-tag", "5000"}, `invalid VLAN tag 5000: must be between 0 and 4094`},
+		{[]string{"10.10.2.0/24", "storage", "extra"}, `unrecognized args: \["extra"\]`},
+	} {
+		c.Logf("test %d: %v", i, test.args)
+		_, err := runAddCommand(c, test.args...)
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+}
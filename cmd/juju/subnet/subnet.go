@@ -0,0 +1,46 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package subnet
+
+import (
+	"github.com/juju/cmd"
+
+	"github.com/juju/juju/api/subnets"
+	"github.com/juju/juju/cmd/envcmd"
+)
+
+const subnetCommandDoc = `
+"juju subnet" is used to manage the subnets known to the Juju environment.
+`
+
+const subnetCommandPurpose = "manage subnets"
+
+// NewSuperCommand creates the subnet supercommand and registers the
+// subcommands that it supports.
+func NewSuperCommand() cmd.Command {
+	subnetcmd := cmd.NewSuperCommand(cmd.SuperCommandParams{
+		Name:        "subnet",
+		Doc:         subnetCommandDoc,
+		UsagePrefix: "juju",
+		Purpose:     subnetCommandPurpose,
+	})
+	subnetcmd.Register(envcmd.Wrap(&AddCommand{}))
+	return subnetcmd
+}
+
+// SubnetCommandBase is a helper base structure that has a method to get
+// the subnets client.
+type SubnetCommandBase struct {
+	envcmd.EnvCommandBase
+}
+
+// NewSubnetsClient returns a subnets client for the root api endpoint
+// that the environment command returns.
+func (c *SubnetCommandBase) NewSubnetsClient() (*subnets.Client, error) {
+	root, err := c.NewAPIRoot()
+	if err != nil {
+		return nil, err
+	}
+	return subnets.NewClient(root), nil
+}
@@ -0,0 +1,25 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package subnet_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/cmd/juju/subnet"
+	"github.com/juju/juju/testing"
+)
+
+type subnetSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&subnetSuite{})
+
+func (s *subnetSuite) TestHelp(c *gc.C) {
+	ctx, err := testing.RunCommand(c, subnet.NewSuperCommand(), "--help")
+	c.Assert(err, jc.ErrorIsNil)
+	namesFound := testing.ExtractCommandsFromHelpOutput(ctx)
+	c.Assert(namesFound, gc.DeepEquals, []string{"add", "help"})
+}
@@ -11,6 +11,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/juju/cmd"
 	"github.com/juju/errors"
@@ -35,6 +36,25 @@ type UpgradeJujuCommand struct {
 	ResetPrevious bool
 	AssumeYes     bool
 	Series        []string
+
+	// Staged upgrades the agents in stages, as configured by
+	// CanaryPercent, BatchSize and HealthTimeout.
+	Staged        bool
+	CanaryPercent int
+	BatchSize     int
+	HealthTimeout time.Duration
+
+	// Status shows the progress of the last staged upgrade, and
+	// Resume resumes it if it was halted.
+	Status bool
+	Resume bool
+
+	// Rollback rolls back the last upgrade to the state backup taken
+	// before it, and ForceRollback does so even if documents were
+	// changed since the backup was taken.
+	Rollback      bool
+	ForceRollback bool
+	out           cmd.Output
 }
 
 var upgradeJujuDoc = `
@@ -74,7 +94,42 @@ progress. It will also abort if a previous upgrade was partially
 completed - this can happen if one of the state servers in a high
 availability environment failed to upgrade. If a failed upgrade has
 been resolved, the --reset-previous-upgrade flag can be used to reset
-the environment's upgrade tracking state, allowing further upgrades.`
+the environment's upgrade tracking state, allowing further upgrades.
+
+With the --staged flag, the agents are upgraded in stages, so that a bad
+agent build cannot take out the whole environment at once. The state
+servers are upgraded first. Once their agents are back on the new version,
+a percentage of the other machines (--canary-percent) are upgraded as
+canaries, then the rest in batches (--batch-size, 0 for all at once). Each
+stage starts once the agents upgraded before it, including the agents of
+the units on their machines, are alive on the new version and not in an
+error state. If they are not healthy within --health-timeout, the upgrade
+halts and the remaining agents stay on their version.
+
+The --status flag shows the progress of the last staged upgrade, and the
+--resume flag resumes a halted one once its cause has been resolved. A
+halted upgrade may also be replaced by starting a new staged upgrade, for
+instance to a fixed version. Upgrading without --staged while a staged
+upgrade is in progress cancels it and upgrades all the remaining agents.
+
+Before running its upgrade steps, the master state server backs up the
+environment's state; the upgrade fails if it cannot, unless the state
+server's agent configuration sets UPGRADE_WITHOUT_BACKUP to true. The
+--rollback flag rolls back the last upgrade,
+failed or not, by restoring that backup and setting the agent version back
+to the version upgraded from; the agents then downgrade to it. All changes
+made to the environment since the upgrade started are lost, and the
+rollback is refused if machines were added since, or if any of the
+upgrade's steps cannot be reversed. It is also refused if any of the
+environment's documents were changed since the backup was taken, listing
+them, unless --force is given.
+
+Examples:
+    juju upgrade-juju --staged --canary-percent 5 --batch-size 10
+    juju upgrade-juju --status
+    juju upgrade-juju --resume
+    juju upgrade-juju --rollback
+    juju upgrade-juju --rollback --force`
 
 func (c *UpgradeJujuCommand) Info() *cmd.Info {
 	return &cmd.Info{
@@ -92,6 +147,18 @@ func (c *UpgradeJujuCommand) SetFlags(f *gnuflag.FlagSet) {
 	f.BoolVar(&c.AssumeYes, "y", false, "answer 'yes' to confirmation prompts")
 	f.BoolVar(&c.AssumeYes, "yes", false, "")
 	f.Var(newSeriesValue(nil, &c.Series), "series", "upload tools for supplied comma-separated series list (OBSOLETE)")
+	f.BoolVar(&c.Staged, "staged", false, "upgrade the state servers, then canaries, then the other machines in batches")
+	f.IntVar(&c.CanaryPercent, "canary-percent", 10, "percentage of the machines upgraded as canaries in a staged upgrade")
+	f.IntVar(&c.BatchSize, "batch-size", 5, "number of machines upgraded at a time after the canaries in a staged upgrade")
+	f.DurationVar(&c.HealthTimeout, "health-timeout", 10*time.Minute, "how long upgraded agents have to be healthy before a staged upgrade halts")
+	f.BoolVar(&c.Status, "status", false, "show the progress of the last staged upgrade")
+	f.BoolVar(&c.Resume, "resume", false, "resume a halted staged upgrade")
+	f.BoolVar(&c.Rollback, "rollback", false, "roll back the last upgrade, restoring the state backup taken before it")
+	f.BoolVar(&c.ForceRollback, "force", false, "roll back even if documents were changed since the backup was taken")
+	c.out.AddFlags(f, "yaml", map[string]cmd.Formatter{
+		"yaml": cmd.FormatYaml,
+		"json": cmd.FormatJson,
+	})
 }
 
 func (c *UpgradeJujuCommand) Init(args []string) error {
@@ -117,6 +184,36 @@ func (c *UpgradeJujuCommand) Init(args []string) error {
 	if len(c.Series) > 0 && !c.UploadTools {
 		return fmt.Errorf("--series requires --upload-tools")
 	}
+	if c.Status || c.Resume {
+		if c.Status && c.Resume {
+			return fmt.Errorf("--status and --resume cannot be used together")
+		}
+		if c.vers != "" || c.UploadTools || c.DryRun || c.ResetPrevious || c.Staged {
+			return fmt.Errorf("--status and --resume cannot be used with other upgrade flags")
+		}
+	}
+	if c.ForceRollback && !c.Rollback {
+		return fmt.Errorf("--force requires --rollback")
+	}
+	if c.Rollback {
+		if c.Status || c.Resume {
+			return fmt.Errorf("--rollback cannot be used with --status or --resume")
+		}
+		if c.vers != "" || c.UploadTools || c.DryRun || c.ResetPrevious || c.Staged {
+			return fmt.Errorf("--rollback cannot be used with other upgrade flags")
+		}
+	}
+	if c.Staged {
+		if c.CanaryPercent < 1 || c.CanaryPercent > 100 {
+			return fmt.Errorf("--canary-percent must be between 1 and 100")
+		}
+		if c.BatchSize < 0 {
+			return fmt.Errorf("--batch-size must not be negative")
+		}
+		if c.HealthTimeout <= 0 {
+			return fmt.Errorf("--health-timeout must be positive")
+		}
+	}
 	return cmd.CheckEmpty(args)
 }
 
@@ -136,6 +233,10 @@ type upgradeJujuAPI interface {
 	UploadTools(r io.Reader, vers version.Binary, additionalSeries ...string) (*coretools.Tools, error)
 	AbortCurrentUpgrade() error
 	SetEnvironAgentVersion(version version.Number) error
+	StartStagedUpgrade(version version.Number, canaryPercent, batchSize int, healthTimeout time.Duration) error
+	ResumeStagedUpgrade() error
+	StagedUpgradeStatus() (params.StagedUpgradeStatus, error)
+	RollbackUpgrade(force bool) (params.UpgradeRollback, error)
 	Close() error
 }
 
@@ -154,6 +255,19 @@ func (c *UpgradeJujuCommand) Run(ctx *cmd.Context) (err error) {
 		return err
 	}
 	defer client.Close()
+	if c.Status {
+		return c.showStagedUpgradeStatus(ctx, client)
+	}
+	if c.Resume {
+		if err := client.ResumeStagedUpgrade(); err != nil {
+			return block.ProcessBlockedError(err, block.BlockChange)
+		}
+		ctx.Infof("resumed staged upgrade")
+		return nil
+	}
+	if c.Rollback {
+		return c.rollbackUpgrade(ctx, client)
+	}
 	defer func() {
 		if err == errUpToDate {
 			ctx.Infof(err.Error())
@@ -200,7 +314,7 @@ func (c *UpgradeJujuCommand) Run(ctx *cmd.Context) (err error) {
 				return block.ProcessBlockedError(err, block.BlockChange)
 			}
 		}
-		if err := client.SetEnvironAgentVersion(context.chosen); err != nil {
+		if err := c.setAgentVersion(client, context.chosen); err != nil {
 			if params.IsCodeUpgradeInProgress(err) {
 				return errors.Errorf("%s\n\n"+
 					"Please wait for the upgrade to complete or if there was a problem with\n"+
@@ -211,11 +325,116 @@ func (c *UpgradeJujuCommand) Run(ctx *cmd.Context) (err error) {
 				return block.ProcessBlockedError(err, block.BlockChange)
 			}
 		}
-		logger.Infof("started upgrade to %s", context.chosen)
+		if c.Staged {
+			logger.Infof("started staged upgrade to %s", context.chosen)
+			ctx.Infof("started staged upgrade to %s; see its progress with juju upgrade-juju --status", context.chosen)
+		} else {
+			logger.Infof("started upgrade to %s", context.chosen)
+		}
 	}
 	return nil
 }
 
+// setAgentVersion starts the upgrade of the agents to the given version,
+// in stages if requested.
+func (c *UpgradeJujuCommand) setAgentVersion(client upgradeJujuAPI, vers version.Number) error {
+	if c.Staged {
+		return client.StartStagedUpgrade(vers, c.CanaryPercent, c.BatchSize, c.HealthTimeout)
+	}
+	return client.SetEnvironAgentVersion(vers)
+}
+
+// StagedUpgradeStatus defines the serialization behaviour of the
+// progress of a staged upgrade.
+type StagedUpgradeStatus struct {
+	PreviousVersion string                          `yaml:"previous-version" json:"previous-version"`
+	TargetVersion   string                          `yaml:"target-version" json:"target-version"`
+	Status          string                          `yaml:"status" json:"status"`
+	HaltReason      string                          `yaml:"halt-reason,omitempty" json:"halt-reason,omitempty"`
+	CanaryPercent   int                             `yaml:"canary-percent" json:"canary-percent"`
+	BatchSize       int                             `yaml:"batch-size" json:"batch-size"`
+	HealthTimeout   string                          `yaml:"health-timeout" json:"health-timeout"`
+	Started         string                          `yaml:"started" json:"started"`
+	StageStarted    string                          `yaml:"stage-started" json:"stage-started"`
+	Released        int                             `yaml:"released-machines" json:"released-machines"`
+	Held            int                             `yaml:"held-machines" json:"held-machines"`
+	Machines        map[string]StagedUpgradeMachine `yaml:"machines" json:"machines"`
+}
+
+// StagedUpgradeMachine defines the serialization behaviour of the
+// progress of a staged upgrade on a machine.
+type StagedUpgradeMachine struct {
+	Version     string   `yaml:"version,omitempty" json:"version,omitempty"`
+	StateServer bool     `yaml:"state-server,omitempty" json:"state-server,omitempty"`
+	Released    bool     `yaml:"released" json:"released"`
+	Problems    []string `yaml:"problems,omitempty" json:"problems,omitempty"`
+}
+
+func (c *UpgradeJujuCommand) showStagedUpgradeStatus(ctx *cmd.Context, client upgradeJujuAPI) error {
+	status, err := client.StagedUpgradeStatus()
+	if params.IsCodeNotFound(err) {
+		ctx.Infof("no staged upgrade has been started")
+		return nil
+	} else if err != nil {
+		return err
+	}
+	output := StagedUpgradeStatus{
+		PreviousVersion: status.PreviousVersion.String(),
+		TargetVersion:   status.TargetVersion.String(),
+		Status:          status.Status,
+		HaltReason:      status.HaltReason,
+		CanaryPercent:   status.CanaryPercent,
+		BatchSize:       status.BatchSize,
+		HealthTimeout:   status.HealthTimeout.String(),
+		Started:         status.Started.UTC().Format(time.RFC3339),
+		StageStarted:    status.StageStarted.UTC().Format(time.RFC3339),
+		Machines:        make(map[string]StagedUpgradeMachine),
+	}
+	for _, m := range status.Machines {
+		output.Machines[m.Id] = StagedUpgradeMachine{
+			Version:     m.Version,
+			StateServer: m.StateServer,
+			Released:    m.Released,
+			Problems:    m.Problems,
+		}
+		if m.StateServer {
+			continue
+		}
+		if m.Released {
+			output.Released++
+		} else {
+			output.Held++
+		}
+	}
+	return c.out.Write(ctx, output)
+}
+
+const rollbackUpgradeMessage = `
+WARNING! rolling back the last upgrade restores the state backup taken
+before it: all changes made to the environment since the upgrade started
+will be lost.
+
+Continue [y/N]? `
+
+// rollbackUpgrade rolls back the last upgrade once confirmed.
+func (c *UpgradeJujuCommand) rollbackUpgrade(ctx *cmd.Context, client upgradeJujuAPI) error {
+	if ok, err := c.confirm(ctx, rollbackUpgradeMessage); !ok || err != nil {
+		const message = "upgrade not rolled back"
+		if err != nil {
+			return errors.Annotate(err, message)
+		}
+		return errors.New(message)
+	}
+	result, err := client.RollbackUpgrade(c.ForceRollback)
+	if err != nil {
+		return block.ProcessBlockedError(err, block.BlockChange)
+	}
+	logger.Infof("rolled back upgrade from %s to %s", result.PreviousVersion, result.TargetVersion)
+	ctx.Infof("rolled back upgrade from %s to %s using backup %s; agents are downgrading to %s",
+		result.PreviousVersion, result.TargetVersion, result.BackupId, result.PreviousVersion)
+	return nil
+}
+
 const resetPreviousUpgradeMessage = `
 WARNING! using --reset-previous-upgrade when an upgrade is in progress
 will cause the upgrade to fail. Only use this option to clear an
@@ -224,10 +443,17 @@ incomplete upgrade where the root cause has been resolved.
 Continue [y/N]? `
 
 func (c *UpgradeJujuCommand) confirmResetPreviousUpgrade(ctx *cmd.Context) (bool, error) {
+	return c.confirm(ctx, resetPreviousUpgradeMessage)
+}
+
+// confirm prompts with the given message, unless the user already
+// answered yes to confirmation prompts, and returns whether the user
+// answered yes.
+func (c *UpgradeJujuCommand) confirm(ctx *cmd.Context, message string) (bool, error) {
 	if c.AssumeYes {
 		return true, nil
 	}
-	fmt.Fprintf(ctx.Stdout, resetPreviousUpgradeMessage)
+	fmt.Fprintf(ctx.Stdout, message)
 	scanner := bufio.NewScanner(ctx.Stdin)
 	scanner.Scan()
 	err := scanner.Err()
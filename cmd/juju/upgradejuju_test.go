@@ -10,8 +10,10 @@ import (
 	"io"
 	"io/ioutil"
 	"strings"
+	"time"
 
 	jujucmd "github.com/juju/cmd"
+	"github.com/juju/errors"
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
 
@@ -624,6 +626,203 @@ func (s *UpgradeJujuSuite) TestResetPreviousUpgrade(c *gc.C) {
 	}
 }
 
+func (s *UpgradeJujuSuite) TestStagedUpgradeInitErrors(c *gc.C) {
+	for i, test := range []struct {
+		args []string
+		err  string
+	}{{
+		args: []string{"--status", "--resume"},
+		err:  "--status and --resume cannot be used together",
+	}, {
+		args: []string{"--status", "--staged"},
+		err:  "--status and --resume cannot be used with other upgrade flags",
+	}, {
+		args: []string{"--resume", "--version", "1.2.3"},
+		err:  "--status and --resume cannot be used with other upgrade flags",
+	}, {
+		args: []string{"--rollback", "--status"},
+		err:  "--rollback cannot be used with --status or --resume",
+	}, {
+		args: []string{"--rollback", "--version", "1.2.3"},
+		err:  "--rollback cannot be used with other upgrade flags",
+	}, {
+		args: []string{"--force"},
+		err:  "--force requires --rollback",
+	}, {
+		args: []string{"--staged", "--canary-percent", "0"},
+		err:  "--canary-percent must be between 1 and 100",
+	}, {
+		args: []string{"--staged", "--batch-size", "-1"},
+		err:  "--batch-size must not be negative",
+	}, {
+		args: []string{"--staged", "--health-timeout", "0"},
+		err:  "--health-timeout must be positive",
+	}} {
+		c.Logf("test %d: %v", i, test.args)
+		err := coretesting.InitCommand(envcmd.Wrap(&UpgradeJujuCommand{}), test.args)
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+}
+
+func (s *UpgradeJujuSuite) TestStagedUpgrade(c *gc.C) {
+	fakeAPI := NewFakeUpgradeJujuAPI(c, s.State)
+	fakeAPI.patch(s)
+	cmd := &UpgradeJujuCommand{}
+	err := coretesting.InitCommand(envcmd.Wrap(cmd), []string{
+		"--staged", "--canary-percent", "20", "--batch-size", "3", "--health-timeout", "5m",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	ctx := coretesting.Context(c)
+	err = cmd.Run(ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(fakeAPI.setVersionCalledWith, gc.Equals, version.Number{})
+	c.Assert(fakeAPI.stagedUpgradeCalledWith, jc.DeepEquals, []interface{}{
+		fakeAPI.nextVersion.Number, 20, 3, 5 * time.Minute,
+	})
+	c.Assert(coretesting.Stderr(ctx), jc.Contains, "started staged upgrade to "+fakeAPI.nextVersion.Number.String())
+}
+
+func (s *UpgradeJujuSuite) TestStagedUpgradeDefaults(c *gc.C) {
+	fakeAPI := NewFakeUpgradeJujuAPI(c, s.State)
+	fakeAPI.patch(s)
+	cmd := &UpgradeJujuCommand{}
+	err := coretesting.InitCommand(envcmd.Wrap(cmd), []string{"--staged"})
+	c.Assert(err, jc.ErrorIsNil)
+	err = cmd.Run(coretesting.Context(c))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(fakeAPI.stagedUpgradeCalledWith, jc.DeepEquals, []interface{}{
+		fakeAPI.nextVersion.Number, 10, 5, 10 * time.Minute,
+	})
+}
+
+func (s *UpgradeJujuSuite) TestResumeStagedUpgrade(c *gc.C) {
+	fakeAPI := NewFakeUpgradeJujuAPI(c, s.State)
+	fakeAPI.patch(s)
+	ctx, err := coretesting.RunCommand(c, envcmd.Wrap(&UpgradeJujuCommand{}), "--resume")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(fakeAPI.resumeCalled, jc.IsTrue)
+	c.Assert(fakeAPI.setVersionCalledWith, gc.Equals, version.Number{})
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "resumed staged upgrade\n")
+}
+
+func (s *UpgradeJujuSuite) TestStagedUpgradeStatus(c *gc.C) {
+	fakeAPI := NewFakeUpgradeJujuAPI(c, s.State)
+	fakeAPI.stagedStatus = params.StagedUpgradeStatus{
+		PreviousVersion: version.MustParse("1.24.0"),
+		TargetVersion:   version.MustParse("1.24.1"),
+		Status:          "halted",
+		HaltReason:      "agents not healthy on 1.24.1 after 10m0s: machine 1: agent is not alive",
+		CanaryPercent:   10,
+		BatchSize:       5,
+		HealthTimeout:   10 * time.Minute,
+		Started:         time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC),
+		StageStarted:    time.Date(2015, 6, 1, 12, 5, 0, 0, time.UTC),
+		Machines: []params.StagedUpgradeMachine{{
+			Id:          "0",
+			StateServer: true,
+			Released:    true,
+			Version:     "1.24.1",
+		}, {
+			Id:       "1",
+			Released: true,
+			Version:  "1.24.1",
+			Problems: []string{"agent is not alive"},
+		}, {
+			Id:      "2",
+			Version: "1.24.0",
+		}},
+	}
+	fakeAPI.patch(s)
+	ctx, err := coretesting.RunCommand(c, envcmd.Wrap(&UpgradeJujuCommand{}), "--status")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(fakeAPI.setVersionCalledWith, gc.Equals, version.Number{})
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, `
+previous-version: 1.24.0
+target-version: 1.24.1
+status: halted
+halt-reason: 'agents not healthy on 1.24.1 after 10m0s: machine 1: agent is not alive'
+canary-percent: 10
+batch-size: 5
+health-timeout: 10m0s
+started: 2015-06-01T12:00:00Z
+stage-started: 2015-06-01T12:05:00Z
+released-machines: 1
+held-machines: 1
+machines:
+  "0":
+    version: 1.24.1
+    state-server: true
+    released: true
+  "1":
+    version: 1.24.1
+    released: true
+    problems:
+    - agent is not alive
+  "2":
+    version: 1.24.0
+    released: false
+`[1:])
+}
+
+func (s *UpgradeJujuSuite) TestStagedUpgradeStatusNotFound(c *gc.C) {
+	fakeAPI := NewFakeUpgradeJujuAPI(c, s.State)
+	fakeAPI.stagedStatusErr = &params.Error{Message: "staged upgrade not found", Code: params.CodeNotFound}
+	fakeAPI.patch(s)
+	ctx, err := coretesting.RunCommand(c, envcmd.Wrap(&UpgradeJujuCommand{}), "--status")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(coretesting.Stdout(ctx), gc.Equals, "")
+	c.Assert(coretesting.Stderr(ctx), gc.Equals, "no staged upgrade has been started\n")
+}
+
+func (s *UpgradeJujuSuite) TestRollbackUpgrade(c *gc.C) {
+	fakeAPI := NewFakeUpgradeJujuAPI(c, s.State)
+	fakeAPI.rollback = params.UpgradeRollback{
+		PreviousVersion: version.MustParse("1.24.0"),
+		TargetVersion:   version.MustParse("1.25.0"),
+		BackupId:        "backup-id",
+	}
+	fakeAPI.patch(s)
+	ctx, err := coretesting.RunCommand(c, envcmd.Wrap(&UpgradeJujuCommand{}), "--rollback", "-y")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(fakeAPI.rollbackCalled, jc.IsTrue)
+	c.Assert(fakeAPI.rollbackForced, jc.IsFalse)
+	c.Assert(fakeAPI.setVersionCalledWith, gc.Equals, version.Number{})
+	c.Assert(coretesting.Stderr(ctx), gc.Equals,
+		"rolled back upgrade from 1.24.0 to 1.25.0 using backup backup-id; agents are downgrading to 1.24.0\n")
+}
+
+func (s *UpgradeJujuSuite) TestRollbackUpgradeForced(c *gc.C) {
+	fakeAPI := NewFakeUpgradeJujuAPI(c, s.State)
+	fakeAPI.patch(s)
+	_, err := coretesting.RunCommand(c, envcmd.Wrap(&UpgradeJujuCommand{}), "--rollback", "--force", "-y")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(fakeAPI.rollbackCalled, jc.IsTrue)
+	c.Assert(fakeAPI.rollbackForced, jc.IsTrue)
+}
+
+func (s *UpgradeJujuSuite) TestRollbackUpgradeNotConfirmed(c *gc.C) {
+	fakeAPI := NewFakeUpgradeJujuAPI(c, s.State)
+	fakeAPI.patch(s)
+	ctx := coretesting.Context(c)
+	ctx.Stdin = strings.NewReader("n")
+	cmd := &UpgradeJujuCommand{}
+	err := coretesting.InitCommand(envcmd.Wrap(cmd), []string{"--rollback"})
+	c.Assert(err, jc.ErrorIsNil)
+	err = cmd.Run(ctx)
+	c.Assert(err, gc.ErrorMatches, "upgrade not rolled back")
+	c.Assert(fakeAPI.rollbackCalled, jc.IsFalse)
+	c.Assert(coretesting.Stdout(ctx), jc.Contains, "WARNING! rolling back the last upgrade")
+}
+
+func (s *UpgradeJujuSuite) TestRollbackUpgradeFailure(c *gc.C) {
+	fakeAPI := NewFakeUpgradeJujuAPI(c, s.State)
+	fakeAPI.setVersionErr = errors.New("cannot roll back upgrade from 1.24.0 to 1.25.0: upgrade already rolled back")
+	fakeAPI.patch(s)
+	_, err := coretesting.RunCommand(c, envcmd.Wrap(&UpgradeJujuCommand{}), "--rollback", "-y")
+	c.Assert(err, gc.ErrorMatches, "cannot roll back upgrade from 1.24.0 to 1.25.0: upgrade already rolled back")
+}
+
 func NewFakeUpgradeJujuAPI(c *gc.C, st *state.State) *fakeUpgradeJujuAPI {
 	nextVersion := version.Current
 	nextVersion.Minor++
@@ -641,12 +840,22 @@ type fakeUpgradeJujuAPI struct {
 	setVersionErr             error
 	abortCurrentUpgradeCalled bool
 	setVersionCalledWith      version.Number
+	stagedUpgradeCalledWith   []interface{}
+	resumeCalled              bool
+	stagedStatus              params.StagedUpgradeStatus
+	stagedStatusErr           error
+	rollbackCalled            bool
+	rollback                  params.UpgradeRollback
+	rollbackForced            bool
 }
 
 func (a *fakeUpgradeJujuAPI) reset() {
 	a.setVersionErr = nil
 	a.abortCurrentUpgradeCalled = false
 	a.setVersionCalledWith = version.Number{}
+	a.stagedUpgradeCalledWith = nil
+	a.resumeCalled = false
+	a.rollbackCalled = false
 }
 
 func (a *fakeUpgradeJujuAPI) patch(s *UpgradeJujuSuite) {
@@ -689,6 +898,26 @@ func (a *fakeUpgradeJujuAPI) SetEnvironAgentVersion(v version.Number) error {
 	return a.setVersionErr
 }
 
+func (a *fakeUpgradeJujuAPI) StartStagedUpgrade(v version.Number, canaryPercent, batchSize int, healthTimeout time.Duration) error {
+	a.stagedUpgradeCalledWith = []interface{}{v, canaryPercent, batchSize, healthTimeout}
+	return a.setVersionErr
+}
+
+func (a *fakeUpgradeJujuAPI) ResumeStagedUpgrade() error {
+	a.resumeCalled = true
+	return a.setVersionErr
+}
+
+func (a *fakeUpgradeJujuAPI) StagedUpgradeStatus() (params.StagedUpgradeStatus, error) {
+	return a.stagedStatus, a.stagedStatusErr
+}
+
+func (a *fakeUpgradeJujuAPI) RollbackUpgrade(force bool) (params.UpgradeRollback, error) {
+	a.rollbackCalled = true
+	a.rollbackForced = force
+	return a.rollback, a.setVersionErr
+}
+
 func (a *fakeUpgradeJujuAPI) Close() error {
 	return nil
 }
@@ -0,0 +1,147 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package user
+
+import (
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/utils/set"
+
+	"github.com/juju/juju/cmd/juju/block"
+)
+
+const grantAccessDoc = `
+Grant a user access to the current environment. The access level is one of:
+
+  read   the user can view the environment and its status
+  write  the user can also deploy and change services, machines and units
+  admin  the user can also manage other users' access, destroy the
+         environment and change its blocks
+
+The user is added to the environment if necessary. The new access level
+takes effect the next time the user logs in.
+
+Examples:
+  juju user grant foobar read
+  juju user grant foobar admin
+
+See Also:
+  juju user revoke
+`
+
+const revokeAccessDoc = `
+Revoke a level of access to the current environment from a user. The user
+is left with the next lower level of access: revoking admin access leaves
+write access, revoking write access leaves read access, and revoking read
+access removes the user from the environment. The access of the environment
+owner cannot be revoked.
+
+Examples:
+  juju user revoke foobar write
+  juju user revoke foobar read
+
+See Also:
+  juju user grant
+`
+
+// accessLevels holds the valid levels of access to an environment.
+var accessLevels = set.NewStrings("read", "write", "admin")
+
+// AccessCommandBase holds the common code for the grant and revoke
+// commands.
+type AccessCommandBase struct {
+	UserCommandBase
+	user   string
+	access string
+}
+
+// GrantCommand grants users access to the environment.
+type GrantCommand struct {
+	AccessCommandBase
+}
+
+// RevokeCommand revokes users' access to the environment.
+type RevokeCommand struct {
+	AccessCommandBase
+}
+
+// Info implements Command.Info.
+func (c *GrantCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "grant",
+		Args:    "<username> <read|write|admin>",
+		Purpose: "grant a user access to the environment",
+		Doc:     grantAccessDoc,
+	}
+}
+
+// Info implements Command.Info.
+func (c *RevokeCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "revoke",
+		Args:    "<username> <read|write|admin>",
+		Purpose: "revoke a user's access to the environment",
+		Doc:     revokeAccessDoc,
+	}
+}
+
+// Init implements Command.Init.
+func (c *AccessCommandBase) Init(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no username supplied")
+	}
+	if len(args) == 1 {
+		return errors.New("no access level supplied")
+	}
+	if !accessLevels.Contains(args[1]) {
+		return errors.Errorf("invalid access level %q, expected one of read, write or admin", args[1])
+	}
+	c.user = args[0]
+	c.access = args[1]
+	return cmd.CheckEmpty(args[2:])
+}
+
+// AccessAPI defines the API methods that the grant and revoke commands
+// use.
+type AccessAPI interface {
+	GrantEnvironAccess(username, access string) error
+	RevokeEnvironAccess(username, access string) error
+	Close() error
+}
+
+func (c *AccessCommandBase) getAccessAPI() (AccessAPI, error) {
+	return c.NewUserManagerClient()
+}
+
+var getAccessAPI = (*AccessCommandBase).getAccessAPI
+
+// Run implements Command.Run.
+func (c *GrantCommand) Run(ctx *cmd.Context) error {
+	client, err := getAccessAPI(&c.AccessCommandBase)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	err = client.GrantEnvironAccess(c.user, c.access)
+	if err != nil {
+		return block.ProcessBlockedError(err, block.BlockChange)
+	}
+	ctx.Infof("Granted %s access to user %q", c.access, c.user)
+	return nil
+}
+
+// Run implements Command.Run.
+func (c *RevokeCommand) Run(ctx *cmd.Context) error {
+	client, err := getAccessAPI(&c.AccessCommandBase)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	err = client.RevokeEnvironAccess(c.user, c.access)
+	if err != nil {
+		return block.ProcessBlockedError(err, block.BlockChange)
+	}
+	ctx.Infof("Revoked %s access from user %q", c.access, c.user)
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package user_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/cmd/juju/user"
+	"github.com/juju/juju/testing"
+)
+
+type AccessSuite struct {
+	BaseSuite
+	mock mockAccessAPI
+}
+
+var _ = gc.Suite(&AccessSuite{})
+
+func (s *AccessSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	s.mock = mockAccessAPI{}
+	s.PatchValue(user.GetAccessAPI, func(*user.AccessCommandBase) (user.AccessAPI, error) {
+		return &s.mock, nil
+	})
+}
+
+func (s *AccessSuite) testInit(c *gc.C, command user.AccessCommand) {
+	for i, test := range []struct {
+		args     []string
+		errMatch string
+		user     string
+		access   string
+	}{
+		{
+			errMatch: "no username supplied",
+		}, {
+			args:     []string{"username"},
+			errMatch: "no access level supplied",
+		}, {
+			args:     []string{"username", "root"},
+			errMatch: `invalid access level "root", expected one of read, write or admin`,
+		}, {
+			args:     []string{"username", "read", "extra"},
+			errMatch: `unrecognized args: \["extra"\]`,
+		}, {
+			args:   []string{"username", "write"},
+			user:   "username",
+			access: "write",
+		},
+	} {
+		c.Logf("test %d, args %v", i, test.args)
+		err := testing.InitCommand(command, test.args)
+		if test.errMatch == "" {
+			c.Assert(err, jc.ErrorIsNil)
+			c.Assert(command.Username(), gc.Equals, test.user)
+			c.Assert(command.Access(), gc.Equals, test.access)
+		} else {
+			c.Assert(err, gc.ErrorMatches, test.errMatch)
+		}
+	}
+}
+
+func (s *AccessSuite) TestInit(c *gc.C) {
+	s.testInit(c, &user.GrantCommand{})
+	s.testInit(c, &user.RevokeCommand{})
+}
+
+func (s *AccessSuite) TestGrant(c *gc.C) {
+	ctx, err := testing.RunCommand(c, envcmd.Wrap(&user.GrantCommand{}), "bob", "admin")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.mock.granted, gc.DeepEquals, []string{"bob", "admin"})
+	c.Assert(testing.Stderr(ctx), gc.Equals, "Granted admin access to user \"bob\"\n")
+}
+
+func (s *AccessSuite) TestRevoke(c *gc.C) {
+	ctx, err := testing.RunCommand(c, envcmd.Wrap(&user.RevokeCommand{}), "bob", "read")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.mock.revoked, gc.DeepEquals, []string{"bob", "read"})
+	c.Assert(testing.Stderr(ctx), gc.Equals, "Revoked read access from user \"bob\"\n")
+}
+
+func (s *AccessSuite) TestGrantError(c *gc.C) {
+	s.mock.err = errors.New("boom")
+	_, err := testing.RunCommand(c, envcmd.Wrap(&user.GrantCommand{}), "bob", "read")
+	c.Assert(err, gc.ErrorMatches, "boom")
+}
+
+type mockAccessAPI struct {
+	granted []string
+	revoked []string
+	err     error
+}
+
+var _ user.AccessAPI = (*mockAccessAPI)(nil)
+
+func (m *mockAccessAPI) Close() error {
+	return nil
+}
+
+func (m *mockAccessAPI) GrantEnvironAccess(username, access string) error {
+	m.granted = []string{username, access}
+	return m.err
+}
+
+func (m *mockAccessAPI) RevokeEnvironAccess(username, access string) error {
+	m.revoked = []string{username, access}
+	return m.err
+}
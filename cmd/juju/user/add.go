@@ -5,16 +5,14 @@ package user
 
 import (
 	"fmt"
-	"os"
-	"strings"
 
 	"github.com/juju/cmd"
 	"github.com/juju/errors"
 	"github.com/juju/names"
 	"launchpad.net/gnuflag"
 
+	"github.com/juju/juju/cmd/envcmd"
 	"github.com/juju/juju/cmd/juju/block"
-	"github.com/juju/juju/environs/configstore"
 )
 
 const userAddCommandDoc = `
@@ -25,6 +23,10 @@ lost when the environent is destroyed.  An environment file (.jenv) will be
 written out in the current directory.  You can control the name and location
 of this file using the --output option.
 
+The environment file holds everything the new user needs to connect.  They
+import it with "juju environment import", which replaces the password in
+the file with a new one that only they know.
+
 Examples:
   # Add user "foobar". You will be prompted to enter a password.
   juju user add foobar
@@ -35,6 +37,8 @@ Examples:
 
 See Also:
   juju user change-password
+  juju environment import
+  juju environment share
 `
 
 // AddCommand adds new users into a Juju Server.
@@ -147,52 +151,11 @@ func (c *AddCommand) Run(ctx *cmd.Context) error {
 		c.OutPath = c.User + ".jenv"
 	}
 
-	outPath := normaliseJenvPath(ctx, c.OutPath)
-	err = generateUserJenv(c.ConnectionName(), c.User, c.Password, outPath)
+	outPath := envcmd.ConnectionFilePath(ctx, c.OutPath)
+	err = envcmd.WriteConnectionFile(c.ConnectionName(), c.User, c.Password, outPath)
 	if err == nil {
 		fmt.Fprintf(ctx.Stdout, "environment file written to %s\n", outPath)
 	}
 
 	return err
 }
-
-func normaliseJenvPath(ctx *cmd.Context, outPath string) string {
-	if !strings.HasSuffix(outPath, ".jenv") {
-		outPath = outPath + ".jenv"
-	}
-	return ctx.AbsPath(outPath)
-}
-
-func generateUserJenv(envName, user, password, outPath string) error {
-	store, err := configstore.Default()
-	if err != nil {
-		return errors.Trace(err)
-	}
-	storeInfo, err := store.ReadInfo(envName)
-	if err != nil {
-		return errors.Trace(err)
-	}
-	endpoint := storeInfo.APIEndpoint()
-	outputInfo := configstore.EnvironInfoData{
-		User:         user,
-		Password:     password,
-		EnvironUUID:  endpoint.EnvironUUID,
-		StateServers: endpoint.Addresses,
-		CACert:       endpoint.CACert,
-	}
-	yaml, err := cmd.FormatYaml(outputInfo)
-	if err != nil {
-		return errors.Trace(err)
-	}
-
-	outFile, err := os.Create(outPath)
-	if err != nil {
-		return errors.Trace(err)
-	}
-	defer outFile.Close()
-	outFile.Write(yaml)
-	if err != nil {
-		return errors.Trace(err)
-	}
-	return nil
-}
@@ -10,6 +10,7 @@ import (
 	"github.com/juju/errors"
 	"launchpad.net/gnuflag"
 
+	"github.com/juju/juju/cmd/envcmd"
 	"github.com/juju/juju/cmd/juju/block"
 	"github.com/juju/juju/environs/configstore"
 )
@@ -164,8 +165,8 @@ func (c *ChangePasswordCommand) writeEnvironmentFile(ctx *cmd.Context) error {
 	if outPath == "" {
 		outPath = c.User + ".jenv"
 	}
-	outPath = normaliseJenvPath(ctx, outPath)
-	if err := generateUserJenv(c.ConnectionName(), c.User, c.Password, outPath); err != nil {
+	outPath = envcmd.ConnectionFilePath(ctx, outPath)
+	if err := envcmd.WriteConnectionFile(c.ConnectionName(), c.User, c.Password, outPath); err != nil {
 		return err
 	}
 	fmt.Fprintf(ctx.Stdout, "environment file written to %s\n", outPath)
@@ -10,9 +10,10 @@ import (
 )
 
 const disableUserDoc = `
-Disabling a user stops that user from being able to log in. The user still
-exists and can be reenabled using the "juju enable" command.  If the user is
-already disabled, this command succeeds silently.
+Disabling a user stops that user from being able to log in, and revokes
+the user's ssh keys on all machines. The user still exists and can be
+reenabled using the "juju enable" command.  If the user is already
+disabled, this command succeeds silently.
 
 Examples:
   juju user disable foobar
@@ -33,6 +34,19 @@ See Also:
   juju disable
 `
 
+const removeUserDoc = `
+Removing a user stops that user from being able to log in, and deletes
+the user's ssh keys, revoking them on all machines. Unlike a disabled
+user, a removed user cannot be reenabled with the keys restored.
+
+Examples:
+  juju user remove foobar
+
+See Also:
+  juju user disable
+  juju user remove-key
+`
+
 // DisenableUserBase common code for enable/disable user commands
 type DisenableUserBase struct {
 	UserCommandBase
@@ -49,6 +63,11 @@ type EnableCommand struct {
 	DisenableUserBase
 }
 
+// RemoveCommand removes users.
+type RemoveCommand struct {
+	DisenableUserBase
+}
+
 // Info implements Command.Info.
 func (c *DisableCommand) Info() *cmd.Info {
 	return &cmd.Info{
@@ -69,6 +88,16 @@ func (c *EnableCommand) Info() *cmd.Info {
 	}
 }
 
+// Info implements Command.Info.
+func (c *RemoveCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "remove",
+		Args:    "<username>",
+		Purpose: "remove a user and revoke the user's ssh keys",
+		Doc:     removeUserDoc,
+	}
+}
+
 // Init implements Command.Init.
 func (c *DisenableUserBase) Init(args []string) error {
 	if len(args) == 0 {
@@ -83,6 +112,7 @@ func (c *DisenableUserBase) Init(args []string) error {
 type DisenableUserAPI interface {
 	EnableUser(username string) error
 	DisableUser(username string) error
+	RemoveUser(username string) error
 	Close() error
 }
 
@@ -121,3 +151,18 @@ func (c *EnableCommand) Run(ctx *cmd.Context) error {
 	ctx.Infof("User %q enabled", c.user)
 	return nil
 }
+
+// Run implements Command.Run.
+func (c *RemoveCommand) Run(ctx *cmd.Context) error {
+	client, err := getDisableUserAPI(&c.DisenableUserBase)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	err = client.RemoveUser(c.user)
+	if err != nil {
+		return block.ProcessBlockedError(err, block.BlockChange)
+	}
+	ctx.Infof("User %q removed", c.user)
+	return nil
+}
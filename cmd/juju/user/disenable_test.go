@@ -33,6 +33,10 @@ func (s *DisableUserSuite) enableUserCommand() cmd.Command {
 	return envcmd.Wrap(&user.EnableCommand{})
 }
 
+func (s *DisableUserSuite) removeUserCommand() cmd.Command {
+	return envcmd.Wrap(&user.RemoveCommand{})
+}
+
 func (s *DisableUserSuite) SetUpTest(c *gc.C) {
 	s.BaseSuite.SetUpTest(c)
 	s.PatchValue(user.GetDisableUserAPI, func(*user.DisenableUserBase) (user.DisenableUserAPI, error) {
@@ -71,6 +75,7 @@ func (s *DisableUserSuite) testInit(c *gc.C, command user.DisenableCommand) {
 func (s *DisableUserSuite) TestInit(c *gc.C) {
 	s.testInit(c, &user.EnableCommand{})
 	s.testInit(c, &user.DisableCommand{})
+	s.testInit(c, &user.RemoveCommand{})
 }
 
 func (s *DisableUserSuite) TestDisable(c *gc.C) {
@@ -87,9 +92,18 @@ func (s *DisableUserSuite) TestEnable(c *gc.C) {
 	c.Assert(s.mock.enable, gc.Equals, username)
 }
 
+func (s *DisableUserSuite) TestRemove(c *gc.C) {
+	username := "testing"
+	ctx, err := testing.RunCommand(c, s.removeUserCommand(), username)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.mock.remove, gc.Equals, username)
+	c.Assert(testing.Stderr(ctx), gc.Equals, "User \"testing\" removed\n")
+}
+
 type mockDisableUserAPI struct {
 	enable  string
 	disable string
+	remove  string
 }
 
 var _ user.DisenableUserAPI = (*mockDisableUserAPI)(nil)
@@ -107,3 +121,8 @@ func (m *mockDisableUserAPI) DisableUser(username string) error {
 	m.disable = username
 	return nil
 }
+
+func (m *mockDisableUserAPI) RemoveUser(username string) error {
+	m.remove = username
+	return nil
+}
@@ -16,8 +16,10 @@ var (
 	GetChangePasswordAPI     = &getChangePasswordAPI
 	GetEnvironInfoWriter     = &getEnvironInfoWriter
 	GetConnectionCredentials = &getConnectionCredentials
-	// disable and enable
+	// disable, enable and remove
 	GetDisableUserAPI = &getDisableUserAPI
+	// grant and revoke
+	GetAccessAPI = &getAccessAPI
 
 	UserFriendlyDuration = userFriendlyDuration
 )
@@ -36,9 +38,34 @@ func (c *EnableCommand) Username() string {
 	return c.user
 }
 
+func (c *RemoveCommand) Username() string {
+	return c.user
+}
+
 var (
 	_ DisenableCommand = (*DisableCommand)(nil)
 	_ DisenableCommand = (*EnableCommand)(nil)
+	_ DisenableCommand = (*RemoveCommand)(nil)
+)
+
+// AccessCommand is used for testing both Grant and Revoke commands.
+type AccessCommand interface {
+	cmd.Command
+	Username() string
+	Access() string
+}
+
+func (c *AccessCommandBase) Username() string {
+	return c.user
+}
+
+func (c *AccessCommandBase) Access() string {
+	return c.access
+}
+
+var (
+	_ AccessCommand = (*GrantCommand)(nil)
+	_ AccessCommand = (*RevokeCommand)(nil)
 )
 
 // NewInfoCommand returns an InfoCommand with the api provided as specified.
@@ -58,3 +85,49 @@ func NewListCommand(api UserInfoAPI) *ListCommand {
 		},
 	}
 }
+
+// NewSessionsCommand returns a SessionsCommand with the api provided as
+// specified.
+func NewSessionsCommand(api UserSessionsAPI) *SessionsCommand {
+	return &SessionsCommand{
+		api: api,
+	}
+}
+
+// NewLogoutCommand returns a LogoutCommand with the api provided as
+// specified.
+func NewLogoutCommand(api LogoutAPI) *LogoutCommand {
+	return &LogoutCommand{
+		api: api,
+	}
+}
+
+// NewAddKeyCommand returns an AddKeyCommand with the api provided as
+// specified.
+func NewAddKeyCommand(api UserKeysAPI) *AddKeyCommand {
+	return &AddKeyCommand{
+		UserKeysBase: UserKeysBase{
+			api: api,
+		},
+	}
+}
+
+// NewRemoveKeyCommand returns a RemoveKeyCommand with the api provided
+// as specified.
+func NewRemoveKeyCommand(api UserKeysAPI) *RemoveKeyCommand {
+	return &RemoveKeyCommand{
+		UserKeysBase: UserKeysBase{
+			api: api,
+		},
+	}
+}
+
+// NewKeysCommand returns a KeysCommand with the api provided as
+// specified.
+func NewKeysCommand(api UserKeysAPI) *KeysCommand {
+	return &KeysCommand{
+		UserKeysBase: UserKeysBase{
+			api: api,
+		},
+	}
+}
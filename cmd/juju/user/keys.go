@@ -0,0 +1,254 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package user
+
+import (
+	"fmt"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"launchpad.net/gnuflag"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/cmd/juju/block"
+	"github.com/juju/juju/utils/ssh"
+)
+
+const addKeyCommandDoc = `
+Add ssh public keys to a user. The keys are authorized on all the
+machines of the environments the user can change, for as long as the
+user is enabled and has write access to the environment.
+
+Users can add their own keys; only environment admins can add keys
+for other users.
+
+Examples:
+  juju user add-key bob "$(cat ~/.ssh/id_rsa.pub)"
+
+See Also:
+  juju user keys
+  juju user remove-key
+`
+
+const removeKeyCommandDoc = `
+Remove ssh public keys from a user, revoking them on all machines. Keys
+are identified by their fingerprint or comment, as listed by
+"juju user keys".
+
+Users can remove their own keys; only environment admins can remove
+keys of other users.
+
+Examples:
+  juju user remove-key bob 86:ed:1b:cd:26:a0:a3:4c:27:35:49:60:95:b7:0f:68
+  juju user remove-key bob bob@laptop
+
+See Also:
+  juju user keys
+  juju user add-key
+`
+
+const keysCommandDoc = `
+List the ssh public keys of a user by fingerprint and comment, or in
+full with --full. If no user is given, the keys of the current user are
+listed.
+
+Examples:
+  juju user keys
+  juju user keys --full bob
+
+See Also:
+  juju user add-key
+  juju user remove-key
+`
+
+// UserKeysAPI defines the API methods that the user key commands use.
+type UserKeysAPI interface {
+	UserSSHKeys(username string) ([]string, error)
+	AddUserSSHKeys(username string, keys ...string) ([]params.ErrorResult, error)
+	RemoveUserSSHKeys(username string, ids ...string) ([]params.ErrorResult, error)
+	Close() error
+}
+
+// UserKeysBase is the base type of the commands that manage the ssh
+// keys of a user.
+type UserKeysBase struct {
+	UserCommandBase
+	api  UserKeysAPI
+	user string
+}
+
+func (c *UserKeysBase) getUserKeysAPI() (UserKeysAPI, error) {
+	if c.api != nil {
+		return c.api, nil
+	}
+	return c.NewUserManagerClient()
+}
+
+// AddKeyCommand adds ssh keys to a user.
+type AddKeyCommand struct {
+	UserKeysBase
+	keys []string
+}
+
+// Info implements Command.Info.
+func (c *AddKeyCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "add-key",
+		Args:    "<username> <ssh key> [...]",
+		Purpose: "adds ssh keys to a user",
+		Doc:     addKeyCommandDoc,
+	}
+}
+
+// Init implements Command.Init.
+func (c *AddKeyCommand) Init(args []string) error {
+	switch len(args) {
+	case 0:
+		return errors.New("no username supplied")
+	case 1:
+		return errors.New("no ssh key specified")
+	}
+	c.user, c.keys = args[0], args[1:]
+	return nil
+}
+
+// Run implements Command.Run.
+func (c *AddKeyCommand) Run(ctx *cmd.Context) error {
+	client, err := c.getUserKeysAPI()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	results, err := client.AddUserSSHKeys(c.user, c.keys...)
+	if err != nil {
+		return block.ProcessBlockedError(err, block.BlockChange)
+	}
+	return reportKeyErrors(ctx, "add", c.keys, results)
+}
+
+// RemoveKeyCommand removes ssh keys from a user.
+type RemoveKeyCommand struct {
+	UserKeysBase
+	ids []string
+}
+
+// Info implements Command.Info.
+func (c *RemoveKeyCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "remove-key",
+		Args:    "<username> <fingerprint|comment> [...]",
+		Purpose: "removes ssh keys from a user",
+		Doc:     removeKeyCommandDoc,
+	}
+}
+
+// Init implements Command.Init.
+func (c *RemoveKeyCommand) Init(args []string) error {
+	switch len(args) {
+	case 0:
+		return errors.New("no username supplied")
+	case 1:
+		return errors.New("no ssh key id specified")
+	}
+	c.user, c.ids = args[0], args[1:]
+	return nil
+}
+
+// Run implements Command.Run.
+func (c *RemoveKeyCommand) Run(ctx *cmd.Context) error {
+	client, err := c.getUserKeysAPI()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	results, err := client.RemoveUserSSHKeys(c.user, c.ids...)
+	if err != nil {
+		return block.ProcessBlockedError(err, block.BlockChange)
+	}
+	return reportKeyErrors(ctx, "remove", c.ids, results)
+}
+
+// reportKeyErrors writes the errors of changing each key to stderr,
+// and returns an error if any key could not be changed.
+func reportKeyErrors(ctx *cmd.Context, action string, keys []string, results []params.ErrorResult) error {
+	var failed bool
+	for i, result := range results {
+		if result.Error != nil && i < len(keys) {
+			fmt.Fprintf(ctx.Stderr, "cannot %s key %q: %v\n", action, keys[i], result.Error)
+			failed = true
+		}
+	}
+	if failed {
+		return cmd.ErrSilent
+	}
+	return nil
+}
+
+// KeysCommand lists the ssh keys of a user.
+type KeysCommand struct {
+	UserKeysBase
+	full bool
+}
+
+// Info implements Command.Info.
+func (c *KeysCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "keys",
+		Args:    "[<username>]",
+		Purpose: "lists the ssh keys of a user",
+		Doc:     keysCommandDoc,
+	}
+}
+
+// SetFlags implements Command.SetFlags.
+func (c *KeysCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.BoolVar(&c.full, "full", false, "show full key instead of just the fingerprint")
+}
+
+// Init implements Command.Init.
+func (c *KeysCommand) Init(args []string) (err error) {
+	c.user, err = cmd.ZeroOrOneArgs(args)
+	return err
+}
+
+// Run implements Command.Run.
+func (c *KeysCommand) Run(ctx *cmd.Context) error {
+	client, err := c.getUserKeysAPI()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	username := c.user
+	if username == "" {
+		info, err := c.ConnectionCredentials()
+		if err != nil {
+			return err
+		}
+		username = info.User
+	}
+	keys, err := client.UserSSHKeys(username)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		ctx.Infof("No keys for user %q", username)
+		return nil
+	}
+	for _, key := range keys {
+		if c.full {
+			fmt.Fprintln(ctx.Stdout, key)
+			continue
+		}
+		fingerprint, comment, err := ssh.KeyFingerprint(key)
+		if err != nil {
+			fmt.Fprintf(ctx.Stdout, "Invalid key: %v\n", key)
+			continue
+		}
+		if comment != "" {
+			fingerprint += fmt.Sprintf(" (%s)", comment)
+		}
+		fmt.Fprintln(ctx.Stdout, fingerprint)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package user_test
+
+import (
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/cmd/juju/user"
+	"github.com/juju/juju/testing"
+	sshtesting "github.com/juju/juju/utils/ssh/testing"
+)
+
+type UserKeysCommandSuite struct {
+	BaseSuite
+	fake *fakeUserKeysAPI
+}
+
+var _ = gc.Suite(&UserKeysCommandSuite{})
+
+func (s *UserKeysCommandSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	s.fake = &fakeUserKeysAPI{}
+}
+
+type fakeUserKeysAPI struct {
+	username string
+	keys     []string
+	results  []params.ErrorResult
+	err      error
+}
+
+func (*fakeUserKeysAPI) Close() error {
+	return nil
+}
+
+func (f *fakeUserKeysAPI) UserSSHKeys(username string) ([]string, error) {
+	f.username = username
+	return f.keys, f.err
+}
+
+func (f *fakeUserKeysAPI) AddUserSSHKeys(username string, keys ...string) ([]params.ErrorResult, error) {
+	f.username = username
+	f.keys = keys
+	return f.results, f.err
+}
+
+func (f *fakeUserKeysAPI) RemoveUserSSHKeys(username string, ids ...string) ([]params.ErrorResult, error) {
+	f.username = username
+	f.keys = ids
+	return f.results, f.err
+}
+
+func (s *UserKeysCommandSuite) TestAddKeyInit(c *gc.C) {
+	for i, test := range []struct {
+		args       []string
+		errorMatch string
+	}{
+		{
+			errorMatch: "no username supplied",
+		}, {
+			args:       []string{"bob"},
+			errorMatch: "no ssh key specified",
+		},
+	} {
+		c.Logf("test %d", i)
+		err := testing.InitCommand(user.NewAddKeyCommand(s.fake), test.args)
+		c.Check(err, gc.ErrorMatches, test.errorMatch)
+	}
+}
+
+func (s *UserKeysCommandSuite) TestAddKey(c *gc.C) {
+	command := envcmd.Wrap(user.NewAddKeyCommand(s.fake))
+	_, err := testing.RunCommand(c, command, "bob", sshtesting.ValidKeyOne.Key)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.fake.username, gc.Equals, "bob")
+	c.Assert(s.fake.keys, jc.DeepEquals, []string{sshtesting.ValidKeyOne.Key})
+}
+
+func (s *UserKeysCommandSuite) TestAddKeyErrors(c *gc.C) {
+	s.fake.results = []params.ErrorResult{
+		{},
+		{Error: &params.Error{Message: "invalid"}},
+	}
+	command := envcmd.Wrap(user.NewAddKeyCommand(s.fake))
+	ctx, err := testing.RunCommand(c, command, "bob", sshtesting.ValidKeyOne.Key, "bad-key")
+	c.Assert(err, gc.Equals, cmd.ErrSilent)
+	c.Assert(testing.Stderr(ctx), gc.Equals, "cannot add key \"bad-key\": invalid\n")
+}
+
+func (s *UserKeysCommandSuite) TestRemoveKey(c *gc.C) {
+	command := envcmd.Wrap(user.NewRemoveKeyCommand(s.fake))
+	_, err := testing.RunCommand(c, command, "bob", sshtesting.ValidKeyOne.Fingerprint, "bob@laptop")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.fake.username, gc.Equals, "bob")
+	c.Assert(s.fake.keys, jc.DeepEquals, []string{sshtesting.ValidKeyOne.Fingerprint, "bob@laptop"})
+}
+
+func (s *UserKeysCommandSuite) TestRemoveKeyInit(c *gc.C) {
+	err := testing.InitCommand(user.NewRemoveKeyCommand(s.fake), []string{"bob"})
+	c.Assert(err, gc.ErrorMatches, "no ssh key id specified")
+}
+
+func (s *UserKeysCommandSuite) TestKeys(c *gc.C) {
+	s.fake.keys = []string{
+		sshtesting.ValidKeyOne.Key + " bob@laptop",
+		sshtesting.ValidKeyTwo.Key,
+	}
+	ctx, err := testing.RunCommand(c, envcmd.Wrap(user.NewKeysCommand(s.fake)), "bob")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.fake.username, gc.Equals, "bob")
+	c.Assert(testing.Stdout(ctx), gc.Equals, ""+
+		sshtesting.ValidKeyOne.Fingerprint+" (bob@laptop)\n"+
+		sshtesting.ValidKeyTwo.Fingerprint+"\n")
+}
+
+func (s *UserKeysCommandSuite) TestKeysFull(c *gc.C) {
+	s.fake.keys = []string{sshtesting.ValidKeyOne.Key}
+	ctx, err := testing.RunCommand(c, envcmd.Wrap(user.NewKeysCommand(s.fake)), "--full")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.fake.username, gc.Equals, "user-test")
+	c.Assert(testing.Stdout(ctx), gc.Equals, sshtesting.ValidKeyOne.Key+"\n")
+}
+
+func (s *UserKeysCommandSuite) TestKeysError(c *gc.C) {
+	s.fake.err = errors.New("permission denied")
+	_, err := testing.RunCommand(c, envcmd.Wrap(user.NewKeysCommand(s.fake)), "bob")
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
@@ -0,0 +1,93 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package user
+
+import (
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"launchpad.net/gnuflag"
+)
+
+const logoutCommandDoc = `
+Close all the API connections of a user to the Juju server, as listed
+by "juju user sessions". Clients that reconnect must log in again, so
+combined with "juju user change-password" or "juju user disable" this
+cuts off someone using a user's stolen password.
+
+The connections to all the API servers are closed, each by the API
+server serving it shortly after the request.
+
+Users can close their own connections; only environment admins can
+close the connections of other users.
+
+Examples:
+  juju user logout --all bob
+
+See Also:
+  juju user sessions
+  juju user disable
+`
+
+// LogoutCommand closes the API connections of a user.
+type LogoutCommand struct {
+	UserCommandBase
+	api  LogoutAPI
+	user string
+	all  bool
+}
+
+// Info implements Command.Info.
+func (c *LogoutCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "logout",
+		Args:    "--all <username>",
+		Purpose: "closes the API connections of a user",
+		Doc:     logoutCommandDoc,
+	}
+}
+
+// SetFlags implements Command.SetFlags.
+func (c *LogoutCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.BoolVar(&c.all, "all", false, "close all the connections of the user")
+}
+
+// Init implements Command.Init.
+func (c *LogoutCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no username supplied")
+	}
+	if !c.all {
+		return errors.New("--all must be specified")
+	}
+	c.user = args[0]
+	return cmd.CheckEmpty(args[1:])
+}
+
+// LogoutAPI defines the API methods that the logout command uses.
+type LogoutAPI interface {
+	CloseUserSessions(username string) (int, error)
+	Close() error
+}
+
+func (c *LogoutCommand) getLogoutAPI() (LogoutAPI, error) {
+	if c.api != nil {
+		return c.api, nil
+	}
+	return c.NewUserManagerClient()
+}
+
+// Run implements Command.Run.
+func (c *LogoutCommand) Run(ctx *cmd.Context) error {
+	client, err := c.getLogoutAPI()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	closed, err := client.CloseUserSessions(c.user)
+	if err != nil {
+		return err
+	}
+	ctx.Infof("closed %d connections of user %q", closed, c.user)
+	return nil
+}
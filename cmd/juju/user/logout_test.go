@@ -0,0 +1,80 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package user_test
+
+import (
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/cmd/juju/user"
+	"github.com/juju/juju/testing"
+)
+
+type LogoutCommandSuite struct {
+	BaseSuite
+	fake *fakeLogoutAPI
+}
+
+var _ = gc.Suite(&LogoutCommandSuite{})
+
+func (s *LogoutCommandSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	s.fake = &fakeLogoutAPI{closed: 2}
+}
+
+func (s *LogoutCommandSuite) run(c *gc.C, args ...string) (*cmd.Context, error) {
+	return testing.RunCommand(c, envcmd.Wrap(user.NewLogoutCommand(s.fake)), args...)
+}
+
+type fakeLogoutAPI struct {
+	username string
+	closed   int
+	err      error
+}
+
+func (*fakeLogoutAPI) Close() error {
+	return nil
+}
+
+func (f *fakeLogoutAPI) CloseUserSessions(username string) (int, error) {
+	f.username = username
+	return f.closed, f.err
+}
+
+func (s *LogoutCommandSuite) TestInit(c *gc.C) {
+	for i, test := range []struct {
+		args       []string
+		errorMatch string
+	}{
+		{
+			errorMatch: "no username supplied",
+		}, {
+			args:       []string{"bob"},
+			errorMatch: "--all must be specified",
+		}, {
+			args:       []string{"--all", "bob", "extra"},
+			errorMatch: `unrecognized args: \["extra"\]`,
+		},
+	} {
+		c.Logf("test %d", i)
+		_, err := s.run(c, test.args...)
+		c.Check(err, gc.ErrorMatches, test.errorMatch)
+	}
+}
+
+func (s *LogoutCommandSuite) TestLogout(c *gc.C) {
+	ctx, err := s.run(c, "--all", "bob")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.fake.username, gc.Equals, "bob")
+	c.Assert(testing.Stderr(ctx), gc.Equals, "closed 2 connections of user \"bob\"\n")
+}
+
+func (s *LogoutCommandSuite) TestLogoutError(c *gc.C) {
+	s.fake.err = errors.New("permission denied")
+	_, err := s.run(c, "--all", "bob")
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
@@ -0,0 +1,162 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package user
+
+import (
+	"bytes"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	"launchpad.net/gnuflag"
+
+	"github.com/juju/juju/apiserver/params"
+)
+
+const sessionsCommandDoc = `
+List the API connections of a user to the Juju server, with the address
+each connection comes from and when it logged in. If no user is given,
+the connections of the current user are listed. The connections to all
+the API servers are listed; connection ids match those in the log of the
+API server serving the connection.
+
+Users can list their own connections; only environment admins can list
+the connections of other users.
+
+Examples:
+  juju user sessions
+  juju user sessions bob
+
+See Also:
+  juju user logout
+`
+
+// SessionsCommand lists the API connections of a user.
+type SessionsCommand struct {
+	UserCommandBase
+	api       UserSessionsAPI
+	user      string
+	exactTime bool
+	out       cmd.Output
+}
+
+// UserSession defines the serialization behaviour of a user's API
+// connection.
+type UserSession struct {
+	Id            string `yaml:"id" json:"id"`
+	APIServer     string `yaml:"api-server" json:"api-server"`
+	Environment   string `yaml:"environment" json:"environment"`
+	RemoteAddress string `yaml:"remote-address" json:"remote-address"`
+	LoginTime     string `yaml:"login-time" json:"login-time"`
+}
+
+// Info implements Command.Info.
+func (c *SessionsCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "sessions",
+		Args:    "[<username>]",
+		Purpose: "lists the API connections of a user",
+		Doc:     sessionsCommandDoc,
+	}
+}
+
+// SetFlags implements Command.SetFlags.
+func (c *SessionsCommand) SetFlags(f *gnuflag.FlagSet) {
+	f.BoolVar(&c.exactTime, "exact-time", false, "use full timestamp precision")
+	c.out.AddFlags(f, "tabular", map[string]cmd.Formatter{
+		"yaml":    cmd.FormatYaml,
+		"json":    cmd.FormatJson,
+		"tabular": c.formatTabular,
+	})
+}
+
+// Init implements Command.Init.
+func (c *SessionsCommand) Init(args []string) (err error) {
+	c.user, err = cmd.ZeroOrOneArgs(args)
+	return err
+}
+
+// UserSessionsAPI defines the API methods that the sessions command
+// uses.
+type UserSessionsAPI interface {
+	UserSessions(username string) ([]params.UserSession, error)
+	Close() error
+}
+
+func (c *SessionsCommand) getUserSessionsAPI() (UserSessionsAPI, error) {
+	if c.api != nil {
+		return c.api, nil
+	}
+	return c.NewUserManagerClient()
+}
+
+// Run implements Command.Run.
+func (c *SessionsCommand) Run(ctx *cmd.Context) error {
+	client, err := c.getUserSessionsAPI()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	username := c.user
+	if username == "" {
+		info, err := c.ConnectionCredentials()
+		if err != nil {
+			return err
+		}
+		username = info.User
+	}
+	sessions, err := client.UserSessions(username)
+	if err != nil {
+		return err
+	}
+	output := []UserSession{}
+	now := time.Now()
+	for _, session := range sessions {
+		out := UserSession{
+			Id:            fmt.Sprintf("%X", session.ConnectionId),
+			APIServer:     session.APIServer,
+			Environment:   session.EnvironTag,
+			RemoteAddress: session.RemoteAddress,
+		}
+		if tag, err := names.ParseEnvironTag(session.EnvironTag); err == nil {
+			out.Environment = tag.Id()
+		}
+		if tag, err := names.ParseTag(session.APIServer); err == nil {
+			out.APIServer = tag.Id()
+		}
+		if c.exactTime {
+			out.LoginTime = session.LoginTime.String()
+		} else {
+			out.LoginTime = userFriendlyDuration(session.LoginTime, now)
+		}
+		output = append(output, out)
+	}
+	return c.out.Write(ctx, output)
+}
+
+func (c *SessionsCommand) formatTabular(value interface{}) ([]byte, error) {
+	sessions, valueConverted := value.([]UserSession)
+	if !valueConverted {
+		return nil, errors.Errorf("expected value of type %T, got %T", sessions, value)
+	}
+	var out bytes.Buffer
+	const (
+		// To format things into columns.
+		minwidth = 0
+		tabwidth = 1
+		padding  = 2
+		padchar  = ' '
+		flags    = 0
+	)
+	tw := tabwriter.NewWriter(&out, minwidth, tabwidth, padding, padchar, flags)
+	fmt.Fprintf(tw, "ID\tAPI SERVER\tENVIRONMENT\tREMOTE ADDRESS\tLOGIN TIME\n")
+	for _, session := range sessions {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", session.Id, session.APIServer, session.Environment, session.RemoteAddress, session.LoginTime)
+	}
+	tw.Flush()
+	return out.Bytes(), nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package user_test
+
+import (
+	"time"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/cmd/envcmd"
+	"github.com/juju/juju/cmd/juju/user"
+	"github.com/juju/juju/testing"
+)
+
+type UserSessionsCommandSuite struct {
+	BaseSuite
+	fake *fakeUserSessionsAPI
+}
+
+var _ = gc.Suite(&UserSessionsCommandSuite{})
+
+func (s *UserSessionsCommandSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	s.fake = &fakeUserSessionsAPI{}
+}
+
+func (s *UserSessionsCommandSuite) run(c *gc.C, args ...string) (*cmd.Context, error) {
+	return testing.RunCommand(c, envcmd.Wrap(user.NewSessionsCommand(s.fake)), args...)
+}
+
+type fakeUserSessionsAPI struct {
+	username string
+	err      error
+}
+
+func (*fakeUserSessionsAPI) Close() error {
+	return nil
+}
+
+func (f *fakeUserSessionsAPI) UserSessions(username string) ([]params.UserSession, error) {
+	f.username = username
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []params.UserSession{{
+		UserTag:       "user-" + username + "@local",
+		EnvironTag:    "environment-deadbeef-0bad-400d-8000-4b1d0d06f00d",
+		RemoteAddress: "10.0.0.1:51234",
+		LoginTime:     time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC),
+		APIServer:     "machine-1",
+		ConnectionId:  26,
+	}}, nil
+}
+
+func (s *UserSessionsCommandSuite) TestInit(c *gc.C) {
+	err := testing.InitCommand(&user.SessionsCommand{}, []string{"bob", "extra"})
+	c.Assert(err, gc.ErrorMatches, `unrecognized args: \["extra"\]`)
+}
+
+func (s *UserSessionsCommandSuite) TestSessions(c *gc.C) {
+	ctx, err := s.run(c, "bob")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.fake.username, gc.Equals, "bob")
+	c.Assert(testing.Stdout(ctx), gc.Equals, ""+
+		"ID  API SERVER  ENVIRONMENT                           REMOTE ADDRESS  LOGIN TIME\n"+
+		"1A  1           deadbeef-0bad-400d-8000-4b1d0d06f00d  10.0.0.1:51234  2014-01-01\n"+
+		"\n")
+}
+
+func (s *UserSessionsCommandSuite) TestSessionsCurrentUser(c *gc.C) {
+	_, err := s.run(c)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.fake.username, gc.Equals, "user-test")
+}
+
+func (s *UserSessionsCommandSuite) TestSessionsYAML(c *gc.C) {
+	ctx, err := s.run(c, "bob", "--format", "yaml", "--exact-time")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(testing.Stdout(ctx), gc.Equals, `
+- id: 1A
+  api-server: "1"
+  environment: deadbeef-0bad-400d-8000-4b1d0d06f00d
+  remote-address: 10.0.0.1:51234
+  login-time: 2014-01-01 00:00:00 +0000 UTC
+`[1:])
+}
+
+func (s *UserSessionsCommandSuite) TestSessionsError(c *gc.C) {
+	s.fake.err = errors.New("permission denied")
+	_, err := s.run(c, "bob")
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
@@ -21,6 +21,16 @@ var logger = loggo.GetLogger("juju.cmd.juju.user")
 const userCommandDoc = `
 "juju user" is used to manage the user accounts and access control in
 the Juju environment.
+
+Instead of having a juju password, users can be authenticated by an
+LDAP server or an htpasswd file, configured with the identity-* settings
+when the environment is bootstrapped. Such users log in as
+<name>@<identity-domain>, and their access to environments is set by
+identity-group-access from the groups they belong to.
+
+Each user can have ssh keys, which are authorized on the machines of
+the environments the user can change. Disabling or removing a user
+revokes their keys on all machines.
 `
 
 const userCommandPurpose = "manage user accounts and access control"
@@ -39,7 +49,15 @@ func NewSuperCommand() cmd.Command {
 	usercmd.Register(envcmd.Wrap(&InfoCommand{}))
 	usercmd.Register(envcmd.Wrap(&DisableCommand{}))
 	usercmd.Register(envcmd.Wrap(&EnableCommand{}))
+	usercmd.Register(envcmd.Wrap(&RemoveCommand{}))
+	usercmd.Register(envcmd.Wrap(&GrantCommand{}))
+	usercmd.Register(envcmd.Wrap(&RevokeCommand{}))
 	usercmd.Register(envcmd.Wrap(&ListCommand{}))
+	usercmd.Register(envcmd.Wrap(&SessionsCommand{}))
+	usercmd.Register(envcmd.Wrap(&LogoutCommand{}))
+	usercmd.Register(envcmd.Wrap(&AddKeyCommand{}))
+	usercmd.Register(envcmd.Wrap(&RemoveKeyCommand{}))
+	usercmd.Register(envcmd.Wrap(&KeysCommand{}))
 	return usercmd
 }
 
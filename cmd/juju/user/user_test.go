@@ -23,12 +23,20 @@ var _ = gc.Suite(&UserCommandSuite{})
 
 var expectedUserCommmandNames = []string{
 	"add",
+	"add-key",
 	"change-password",
 	"disable",
 	"enable",
+	"grant",
 	"help",
 	"info",
+	"keys",
 	"list",
+	"logout",
+	"remove",
+	"remove-key",
+	"revoke",
+	"sessions",
 }
 
 func (s *UserCommandSuite) TestHelp(c *gc.C) {
@@ -34,6 +34,14 @@ var (
 		Total: 1 * time.Minute,
 		Delay: 5 * time.Second,
 	}
+
+	// agentDialOpts holds the options used by agents to connect to
+	// the API. Logins rejected by a busy API server are retried for
+	// a short while, waiting as long as the server asks, so that a
+	// herd of reconnecting agents spreads itself out.
+	agentDialOpts = api.DialOpts{
+		LoginRetryTimeout: 1 * time.Minute,
+	}
 )
 
 // AgentConf handles command-line flags shared by all agents.
@@ -102,6 +110,20 @@ func (a *AgentConf) SetAPIHostPorts(servers [][]network.HostPort) error {
 	})
 }
 
+// CurrentCACert satisfies worker/apiaddressupdater/APIRedirectSetter.
+func (a *AgentConf) CurrentCACert() string {
+	return a.CurrentConfig().CACert()
+}
+
+// SetAPIRedirect satisfies worker/apiaddressupdater/APIRedirectSetter.
+func (a *AgentConf) SetAPIRedirect(servers [][]network.HostPort, caCert string) error {
+	return a.ChangeConfig(func(c agent.ConfigSetter) error {
+		c.SetAPIHostPorts(servers)
+		c.SetCACert(caCert)
+		return nil
+	})
+}
+
 // SetStateServingInfo satisfies worker/certupdater/SetStateServingInfo.
 func (a *AgentConf) SetStateServingInfo(info params.StateServingInfo) error {
 	return a.ChangeConfig(func(c agent.ConfigSetter) error {
@@ -152,9 +174,10 @@ func OpenAPIState(agentConfig agent.Config, a Agent) (_ *api.State, _ *apiagent.
 	// runner's loop outside the caller of openAPIState will
 	// keep on retrying. If we block for ages here,
 	// then the worker that's calling this cannot
-	// be interrupted.
+	// be interrupted. Only logins rejected by a busy
+	// server are retried, for a bounded time.
 	info := agentConfig.APIInfo()
-	st, err := apiOpen(info, api.DialOpts{})
+	st, err := apiOpen(info, agentDialOpts)
 	usedOldPassword := false
 	if params.IsCodeUnauthorized(err) {
 		// We've perhaps used the wrong password, so
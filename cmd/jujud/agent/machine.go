@@ -31,6 +31,7 @@ import (
 	apiagent "github.com/juju/juju/api/agent"
 	apideployer "github.com/juju/juju/api/deployer"
 	"github.com/juju/juju/api/metricsmanager"
+	apiremoterelations "github.com/juju/juju/api/remoterelations"
 	"github.com/juju/juju/apiserver"
 	"github.com/juju/juju/apiserver/params"
 	"github.com/juju/juju/cmd/jujud/reboot"
@@ -63,6 +64,7 @@ import (
 	"github.com/juju/juju/worker/cleaner"
 	"github.com/juju/juju/worker/deployer"
 	"github.com/juju/juju/worker/diskmanager"
+	"github.com/juju/juju/worker/envworkermanager"
 	"github.com/juju/juju/worker/firewaller"
 	"github.com/juju/juju/worker/instancepoller"
 	"github.com/juju/juju/worker/localstorage"
@@ -75,9 +77,11 @@ import (
 	"github.com/juju/juju/worker/provisioner"
 	"github.com/juju/juju/worker/proxyupdater"
 	rebootworker "github.com/juju/juju/worker/reboot"
+	"github.com/juju/juju/worker/remoterelations"
 	"github.com/juju/juju/worker/resumer"
 	"github.com/juju/juju/worker/rsyslog"
 	"github.com/juju/juju/worker/singular"
+	"github.com/juju/juju/worker/stagedupgrader"
 	"github.com/juju/juju/worker/terminationworker"
 	"github.com/juju/juju/worker/upgrader"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -429,6 +433,14 @@ func (a *MachineAgent) BeginRestore() error {
 	return nil
 }
 
+// EndRestore will flag the agent to allow all commands again, once
+// a restore that did not restart it, such as the one done to roll
+// back an upgrade, is over.
+func (a *MachineAgent) EndRestore() {
+	a.restoreMode = false
+	a.restoring = false
+}
+
 // newrestorestatewatcherworker will return a worker or err if there is a failure,
 // the worker takes care of watching the state of restoreInfo doc and put the
 // agent in the different restore modes.
@@ -451,6 +463,8 @@ func (a *MachineAgent) restoreChanged(st *state.State) error {
 		a.PrepareRestore()
 	case state.RestoreInProgress:
 		a.BeginRestore()
+	default:
+		a.EndRestore()
 	}
 	return nil
 }
@@ -564,6 +578,9 @@ func (a *MachineAgent) APIWorker() (worker.Worker, error) {
 			agentConfig,
 			a.previousAgentVersion,
 			a.upgradeWorkerContext.IsUpgradeRunning,
+			func(to version.Number) error {
+				return a.upgradeWorkerContext.ReverseUpgrade(a, st, entity.Jobs(), to)
+			},
 		), nil
 	})
 	runner.StartWorker("upgrade-steps", a.upgradeStepsWorkerStarter(st, entity.Jobs()))
@@ -660,8 +677,9 @@ func (a *MachineAgent) postUpgradeAPIWorker(
 		}
 	}
 	intrusiveMode = intrusiveMode && !disableNetworkManagement
+	safeApply := envConfig.NetworkSafeApply()
 	runner.StartWorker("networker", func() (worker.Worker, error) {
-		return newNetworker(st.Networker(), agentConfig, intrusiveMode, networker.DefaultConfigBaseDir)
+		return newNetworker(st.Networker(), agentConfig, intrusiveMode, safeApply, networker.DefaultConfigBaseDir)
 	})
 
 	// If not a local provider bootstrap machine, start the worker to
@@ -835,6 +853,22 @@ func (a *MachineAgent) updateSupportedContainers(
 	a.startWorkerAfterUpgrade(runner, watcherName, func() (worker.Worker, error) {
 		return worker.NewStringsWorker(handler), nil
 	})
+	for _, containerType := range containers {
+		if containerType != instance.LXC {
+			continue
+		}
+		// Build and refresh the LXC templates requested for the machine.
+		templatesParams := provisioner.ContainerTemplatesParams{
+			ImageURLGetter: imageURLGetter,
+			Machine:        machine,
+			Provisioner:    pr,
+			Config:         agentConfig,
+			InitLock:       initLock,
+		}
+		a.startWorkerAfterUpgrade(runner, "container-templates", func() (worker.Worker, error) {
+			return provisioner.NewContainerTemplatesWorker(templatesParams), nil
+		})
+	}
 	return nil
 }
 
@@ -890,7 +924,7 @@ func (a *MachineAgent) StateWorker() (worker.Worker, error) {
 				return a.newRestoreStateWatcherWorker(st)
 			})
 			a.startWorkerAfterUpgrade(runner, "lease manager", func() (worker.Worker, error) {
-				workerLoop := lease.WorkerLoop(st)
+				workerLoop := lease.WorkerLoop(st.AllEnvironmentsLeaseStore())
 				return worker.NewSimpleWorker(workerLoop), nil
 			})
 			certChangedChan := make(chan params.StateServingInfo, 1)
@@ -918,6 +952,15 @@ func (a *MachineAgent) StateWorker() (worker.Worker, error) {
 			a.startWorkerAfterUpgrade(singularRunner, "minunitsworker", func() (worker.Worker, error) {
 				return minunitsworker.NewMinUnitsWorker(st), nil
 			})
+			a.startWorkerAfterUpgrade(singularRunner, "remoterelations", func() (worker.Worker, error) {
+				return remoterelations.NewWorker(st, a.openRemoteEnviron), nil
+			})
+			a.startWorkerAfterUpgrade(singularRunner, "stagedupgrader", func() (worker.Worker, error) {
+				return stagedupgrader.NewWorker(st), nil
+			})
+			a.startWorkerAfterUpgrade(singularRunner, "envworkermanager", func() (worker.Worker, error) {
+				return envworkermanager.NewEnvWorkerManager(st, a.envWorkersStarter(st)), nil
+			})
 		case state.JobManageStateDeprecated:
 			// Legacy environments may set this, but we ignore it.
 		default:
@@ -927,6 +970,83 @@ func (a *MachineAgent) StateWorker() (worker.Worker, error) {
 	return cmdutil.NewCloseWorker(logger, runner, st), nil
 }
 
+// envWorkersStarter returns a function that starts the workers of an
+// environment hosted by the state server. They connect to the hosted
+// environment's state, and to its API as this machine.
+func (a *MachineAgent) envWorkersStarter(st *state.State) envworkermanager.EnvWorkersStarter {
+	return func(uuid string) (worker.Worker, error) {
+		envTag := names.NewEnvironTag(uuid)
+		envState, err := st.ForEnviron(envTag)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		agentConfig := a.CurrentConfig()
+		info := agentConfig.APIInfo()
+		info.EnvironTag = envTag
+		apiSt, err := apiOpen(info, agentDialOpts)
+		if err != nil {
+			envState.Close()
+			return nil, errors.Annotatef(err, "cannot connect to API of environment %s", uuid)
+		}
+		envConfig, err := envState.EnvironConfig()
+		if err != nil {
+			apiSt.Close()
+			envState.Close()
+			return nil, errors.Trace(err)
+		}
+
+		runner := worker.NewRunner(cmdutil.ConnectionIsFatal(logger, apiSt), cmdutil.MoreImportant)
+		runner.StartWorker("cleaner", func() (worker.Worker, error) {
+			return cleaner.NewCleaner(envState), nil
+		})
+		runner.StartWorker("minunitsworker", func() (worker.Worker, error) {
+			return minunitsworker.NewMinUnitsWorker(envState), nil
+		})
+		runner.StartWorker("instancepoller", func() (worker.Worker, error) {
+			return instancepoller.NewWorker(envState), nil
+		})
+		runner.StartWorker("remoterelations", func() (worker.Worker, error) {
+			return remoterelations.NewWorker(envState, a.openRemoteEnviron), nil
+		})
+		runner.StartWorker("stagedupgrader", func() (worker.Worker, error) {
+			return stagedupgrader.NewWorker(envState), nil
+		})
+		runner.StartWorker("environ-provisioner", func() (worker.Worker, error) {
+			return provisioner.NewEnvironProvisioner(apiSt.Provisioner(), agentConfig), nil
+		})
+		if envConfig.FirewallMode() != config.FwNone {
+			runner.StartWorker("firewaller", func() (worker.Worker, error) {
+				return newFirewaller(apiSt.Firewaller())
+			})
+		}
+		return cmdutil.NewCloseWorker(logger, cmdutil.NewCloseWorker(logger, runner, apiSt), envState), nil
+	}
+}
+
+// openRemoteEnviron connects to the API of another environment hosted
+// by the state server as this machine, for the remote relations worker
+// to change the relations with the environment's services.
+func (a *MachineAgent) openRemoteEnviron(uuid string) (remoterelations.RemoteEnviron, error) {
+	info := a.CurrentConfig().APIInfo()
+	info.EnvironTag = names.NewEnvironTag(uuid)
+	apiSt, err := apiOpen(info, agentDialOpts)
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot connect to API of environment %s", uuid)
+	}
+	return &remoteEnviron{State: apiSt.RemoteRelations(), conn: apiSt}, nil
+}
+
+// remoteEnviron implements remoterelations.RemoteEnviron.
+type remoteEnviron struct {
+	*apiremoterelations.State
+	conn *api.State
+}
+
+// Close is part of the remoterelations.RemoteEnviron interface.
+func (r *remoteEnviron) Close() error {
+	return r.conn.Close()
+}
+
 // stateWorkerDialOpts is a mongo.DialOpts suitable
 // for use by StateWorker to dial mongo.
 //
@@ -958,19 +1078,30 @@ func (a *MachineAgent) newApiserverWorker(st *state.State, certChanged chan para
 	dataDir := agentConfig.DataDir()
 	logDir := agentConfig.LogDir()
 
+	var slowRequestThreshold time.Duration
+	if value := agentConfig.Value(agent.SlowAPIRequestThreshold); value != "" {
+		threshold, err := time.ParseDuration(value)
+		if err != nil {
+			logger.Warningf("ignoring invalid %s %q: %v", agent.SlowAPIRequestThreshold, value, err)
+		} else {
+			slowRequestThreshold = threshold
+		}
+	}
+
 	endpoint := net.JoinHostPort("", strconv.Itoa(info.APIPort))
 	listener, err := net.Listen("tcp", endpoint)
 	if err != nil {
 		return nil, err
 	}
 	return apiserver.NewServer(st, listener, apiserver.ServerConfig{
-		Cert:        cert,
-		Key:         key,
-		Tag:         tag,
-		DataDir:     dataDir,
-		LogDir:      logDir,
-		Validator:   a.limitLogins,
-		CertChanged: certChanged,
+		Cert:                 cert,
+		Key:                  key,
+		Tag:                  tag,
+		DataDir:              dataDir,
+		LogDir:               logDir,
+		Validator:            a.limitLogins,
+		CertChanged:          certChanged,
+		SlowRequestThreshold: slowRequestThreshold,
 	})
 }
 
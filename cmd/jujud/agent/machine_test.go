@@ -493,9 +493,12 @@ func (s *MachineSuite) TestManageEnviron(c *gc.C) {
 		"charm-revision-updater",
 		"cleaner",
 		"environ-provisioner",
+		"envworkermanager",
 		"firewaller",
 		"minunitsworker",
+		"remoterelations",
 		"resumer",
+		"stagedupgrader",
 	})
 }
 
@@ -1235,6 +1238,7 @@ func (s *MachineSuite) TestMachineAgentNetworkerMode(c *gc.C) {
 		about          string
 		managedNetwork bool
 		jobs           []state.MachineJob
+		safeApply      bool
 		intrusiveMode  bool
 	}{{
 		about:          "network management enabled, network management job set",
@@ -1256,26 +1260,38 @@ func (s *MachineSuite) TestMachineAgentNetworkerMode(c *gc.C) {
 		managedNetwork: false,
 		jobs:           []state.MachineJob{state.JobHostUnits},
 		intrusiveMode:  false,
+	}, {
+		about:          "network management enabled, network management job set, safe apply",
+		managedNetwork: true,
+		jobs:           []state.MachineJob{state.JobHostUnits, state.JobManageNetworking},
+		safeApply:      true,
+		intrusiveMode:  true,
 	}}
 	// Perform tests.
 	for i, test := range tests {
 		c.Logf("test #%d: %s", i, test.about)
 
 		modeCh := make(chan bool, 1)
+		safeApplyCh := make(chan bool, 1)
 		s.AgentSuite.PatchValue(&newNetworker, func(
 			st *apinetworker.State,
 			conf agent.Config,
 			intrusiveMode bool,
+			safeApply bool,
 			configBaseDir string,
 		) (*networker.Networker, error) {
 			select {
 			case modeCh <- intrusiveMode:
+				safeApplyCh <- safeApply
 			default:
 			}
-			return networker.NewNetworker(st, conf, intrusiveMode, configBaseDir)
+			return networker.NewNetworker(st, conf, intrusiveMode, safeApply, configBaseDir)
 		})
 
-		attrs := coretesting.Attrs{"disable-network-management": !test.managedNetwork}
+		attrs := coretesting.Attrs{
+			"disable-network-management": !test.managedNetwork,
+			"network-safe-apply":         test.safeApply,
+		}
 		err := s.BackingState.UpdateEnvironConfig(attrs, nil, nil)
 		c.Assert(err, jc.ErrorIsNil)
 
@@ -1292,6 +1308,9 @@ func (s *MachineSuite) TestMachineAgentNetworkerMode(c *gc.C) {
 			if intrusiveMode != test.intrusiveMode {
 				c.Fatalf("expected networker intrusive mode = %v, got mode = %v", test.intrusiveMode, intrusiveMode)
 			}
+			if safeApply := <-safeApplyCh; safeApply != test.safeApply {
+				c.Fatalf("expected networker safe apply = %v, got %v", test.safeApply, safeApply)
+			}
 		case <-time.After(coretesting.LongWait):
 			c.Fatalf("timed out waiting for the networker to start")
 		}
@@ -1371,6 +1390,23 @@ func (s *MachineSuite) TestMachineAgentSetsRestoreInProgress(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, "already restoring")
 }
 
+func (s *MachineSuite) TestMachineAgentEndsRestore(c *gc.C) {
+	// Start the machine agent.
+	m, _, _ := s.primeAgent(c, version.Current, state.JobHostUnits)
+	a := s.newAgent(c, m)
+	go func() { c.Check(a.Run(nil), jc.ErrorIsNil) }()
+	defer func() { c.Check(a.Stop(), jc.ErrorIsNil) }()
+	err := a.PrepareRestore()
+	c.Assert(err, jc.ErrorIsNil)
+	err = a.BeginRestore()
+	c.Assert(err, jc.ErrorIsNil)
+	a.EndRestore()
+	c.Assert(a.IsRestorePreparing(), jc.IsFalse)
+	c.Assert(a.IsRestoreRunning(), jc.IsFalse)
+	err = a.PrepareRestore()
+	c.Assert(err, jc.ErrorIsNil)
+}
+
 func (s *MachineSuite) TestMachineAgentRestoreRequiresPrepare(c *gc.C) {
 	// Start the machine agent.
 	m, _, _ := s.primeAgent(c, version.Current, state.JobHostUnits)
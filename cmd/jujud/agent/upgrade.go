@@ -15,6 +15,7 @@ import (
 	"github.com/juju/juju/environs"
 	"github.com/juju/juju/mongo"
 	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/backups"
 	"github.com/juju/juju/state/multiwatcher"
 	"github.com/juju/juju/state/storage"
 	"github.com/juju/juju/upgrades"
@@ -32,7 +33,8 @@ type upgradingMachineAgent interface {
 }
 
 var (
-	upgradesPerformUpgrade = upgrades.PerformUpgrade // Allow patching
+	upgradesPerformUpgrade  = upgrades.PerformUpgrade  // Allow patching
+	upgradesPerformRollback = upgrades.PerformRollback // Allow patching
 
 	// The maximum time a master state server will wait for other
 	// state servers to come up and indicate they are ready to begin
@@ -226,6 +228,10 @@ func (c *upgradeWorkerContext) runUpgrades() error {
 		return errors.New("wrench")
 	}
 
+	if err := c.backUpBeforeUpgrade(); err != nil {
+		return err
+	}
+
 	if err := c.agent.ChangeConfig(c.runUpgradeSteps); err != nil {
 		return err
 	}
@@ -276,6 +282,78 @@ func (c *upgradeWorkerContext) prepareForUpgrade() (*state.UpgradeInfo, error) {
 	return info, nil
 }
 
+// backUpBeforeUpgrade takes a backup of state on the master state
+// server before it runs the upgrade steps, so that the upgrade can be
+// rolled back. The backup taken before an earlier attempt at the same
+// upgrade is kept. Failing to take or record the backup fails the
+// upgrade, unless the agent's UpgradeWithoutBackup config value is
+// "true": the upgrade then goes ahead, but it cannot be rolled back.
+func (c *upgradeWorkerContext) backUpBeforeUpgrade() error {
+	if !c.isMaster {
+		return nil
+	}
+	err := c.takeUpgradeBackup()
+	if err == nil {
+		return nil
+	}
+	if c.agentConfig.Value(agent.UpgradeWithoutBackup) == "true" {
+		logger.Errorf("cannot back up state, the upgrade will not be reversible: %v", err)
+		return nil
+	}
+	return errors.Annotate(err, "cannot back up state")
+}
+
+// takeUpgradeBackup takes and records the backup of state for the
+// upgrade, unless one was taken already.
+func (c *upgradeWorkerContext) takeUpgradeBackup() error {
+	backup, err := c.st.UpgradeBackup()
+	if err == nil && !backup.RolledBack() &&
+		backup.PreviousVersion() == c.fromVersion && backup.TargetVersion() == c.toVersion {
+		logger.Infof("state was backed up before upgrading to %v already", c.toVersion)
+		return nil
+	} else if err != nil && !errors.IsNotFound(err) {
+		return errors.Trace(err)
+	}
+	logger.Infof("backing up state before upgrading from %v to %v", c.fromVersion, c.toVersion)
+	notes := fmt.Sprintf("taken before upgrading from %v to %v", c.fromVersion, c.toVersion)
+	backupId, err := backUpState(c.st, c.agentConfig, c.machineId, notes)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := c.st.SetUpgradeBackup(c.fromVersion, c.toVersion, backupId); err != nil {
+		return errors.Annotatef(err, "cannot record state backup %q", backupId)
+	}
+	logger.Infof("state backed up as %q", backupId)
+	return nil
+}
+
+// backUpState creates a backup of state with the given notes, stored in
+// the state server's backup storage, and returns its id.
+var backUpState = func(st *state.State, agentConfig agent.Config, machineId, notes string) (string, error) {
+	stor := backups.NewStorage(st)
+	defer stor.Close()
+
+	session := st.MongoSession().Copy()
+	defer session.Close()
+	dbInfo, err := backups.NewDBInfo(st.MongoConnectionInfo(), session)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	meta, err := backups.NewMetadataState(st, machineId)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	meta.Notes = notes
+	paths := backups.Paths{
+		DataDir: agentConfig.DataDir(),
+		LogsDir: agentConfig.LogDir(),
+	}
+	if err := backups.NewBackups(stor).Create(meta, &paths, dbInfo); err != nil {
+		return "", errors.Trace(err)
+	}
+	return meta.ID(), nil
+}
+
 func (c *upgradeWorkerContext) waitForOtherStateServers(info *state.UpgradeInfo) error {
 	watcher := info.Watch()
 
@@ -351,6 +429,27 @@ func (c *upgradeWorkerContext) runUpgradeSteps(agentConfig agent.ConfigSetter) e
 	return nil
 }
 
+// ReverseUpgrade reverses the upgrade steps run for the machine agent
+// when its upgrade was rolled back, before it downgrades to the given
+// version. The agent's UpgradedToVersion is set to that version so the
+// downgraded agent does not run any upgrade steps.
+func (c *upgradeWorkerContext) ReverseUpgrade(
+	a upgradingMachineAgent,
+	apiState *api.State,
+	jobs []multiwatcher.MachineJob,
+	to version.Number,
+) error {
+	return a.ChangeConfig(func(agentConfig agent.ConfigSetter) error {
+		logger.Infof("reversing upgrade from %v to %v for %q", to, version.Current.Number, agentConfig.Tag())
+		context := upgrades.NewContext(agentConfig, apiState, nil)
+		if err := upgradesPerformRollback(to, jobsToTargets(jobs, false), context); err != nil {
+			return errors.Trace(err)
+		}
+		agentConfig.SetUpgradedToVersion(to)
+		return nil
+	})
+}
+
 func (c *upgradeWorkerContext) reportUpgradeFailure(err error, willRetry bool) {
 	retryText := "will retry"
 	if !willRetry {
@@ -47,6 +47,10 @@ type UpgradeSuite struct {
 	connectionDead  bool
 	machineIsMaster bool
 	aptMutex        sync.Mutex
+	backupErr       error
+	backupNotes     []string
+
+	upgradeWithoutBackup bool
 }
 
 var _ = gc.Suite(&UpgradeSuite{})
@@ -117,6 +121,18 @@ func (s *UpgradeSuite) SetUpTest(c *gc.C) {
 		return s.machineIsMaster, nil
 	}
 	s.PatchValue(&isMachineMaster, fakeIsMachineMaster)
+
+	// Don't take real backups.
+	s.backupErr = nil
+	s.upgradeWithoutBackup = false
+	s.backupNotes = nil
+	s.PatchValue(&backUpState, func(_ *state.State, _ agent.Config, machineId, notes string) (string, error) {
+		s.backupNotes = append(s.backupNotes, notes)
+		if s.backupErr != nil {
+			return "", s.backupErr
+		}
+		return "backup-" + machineId, nil
+	})
 }
 
 func (s *UpgradeSuite) captureLogs(c *gc.C) {
@@ -177,6 +193,40 @@ func (s *UpgradeSuite) TestContextInitializeWhenUpgradeRequired(c *gc.C) {
 	c.Assert(config.Version, gc.Equals, initialVersion)
 }
 
+func (s *UpgradeSuite) TestReverseUpgrade(c *gc.C) {
+	var reversedTo version.Number
+	var reversedTargets []upgrades.Target
+	s.PatchValue(&upgradesPerformRollback, func(to version.Number, targets []upgrades.Target, _ upgrades.Context) error {
+		reversedTo = to
+		reversedTargets = targets
+		return nil
+	})
+	config := NewFakeConfigSetter(names.NewMachineTag("0"), version.Current.Number)
+	agent := NewFakeUpgradingMachineAgent(config)
+	previous := version.MustParse("1.21.3")
+
+	context := NewUpgradeWorkerContext()
+	err := context.ReverseUpgrade(agent, nil, []multiwatcher.MachineJob{multiwatcher.JobManageEnviron}, previous)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(reversedTo, gc.Equals, previous)
+	c.Assert(reversedTargets, jc.DeepEquals, []upgrades.Target{upgrades.StateServer})
+	// The downgraded agent will not run any upgrade steps.
+	c.Assert(config.Version, gc.Equals, previous)
+}
+
+func (s *UpgradeSuite) TestReverseUpgradeFailure(c *gc.C) {
+	s.PatchValue(&upgradesPerformRollback, func(version.Number, []upgrades.Target, upgrades.Context) error {
+		return errors.New("boom")
+	})
+	config := NewFakeConfigSetter(names.NewMachineTag("0"), version.Current.Number)
+	agent := NewFakeUpgradingMachineAgent(config)
+
+	context := NewUpgradeWorkerContext()
+	err := context.ReverseUpgrade(agent, nil, []multiwatcher.MachineJob{multiwatcher.JobHostUnits}, version.MustParse("1.21.3"))
+	c.Assert(err, gc.ErrorMatches, "boom")
+	c.Assert(config.Version, gc.Equals, version.Current.Number)
+}
+
 func (s *UpgradeSuite) TestRetryStrategy(c *gc.C) {
 	retries := getUpgradeRetryStrategy()
 	c.Assert(retries.Delay, gc.Equals, 2*time.Minute)
@@ -371,6 +421,68 @@ func (s *UpgradeSuite) TestSuccessMaster(c *gc.C) {
 	c.Assert(info.Status(), gc.Equals, state.UpgradeFinishing)
 }
 
+func (s *UpgradeSuite) TestSuccessMasterBacksUpState(c *gc.C) {
+	s.machineIsMaster = true
+	s.checkSuccess(c, "databaseMaster", func(*state.UpgradeInfo) {})
+
+	expectedNotes := fmt.Sprintf("taken before upgrading from %v to %v", s.oldVersion.Number, version.Current.Number)
+	c.Assert(s.backupNotes, jc.DeepEquals, []string{expectedNotes})
+	backup, err := s.State.UpgradeBackup()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(backup.PreviousVersion(), gc.Equals, s.oldVersion.Number)
+	c.Assert(backup.TargetVersion(), gc.Equals, version.Current.Number)
+	c.Assert(backup.BackupId(), gc.Equals, "backup-0")
+	c.Assert(backup.RolledBack(), jc.IsFalse)
+}
+
+func (s *UpgradeSuite) TestSuccessMasterKeepsEarlierBackup(c *gc.C) {
+	s.machineIsMaster = true
+	err := s.State.SetUpgradeBackup(s.oldVersion.Number, version.Current.Number, "earlier")
+	c.Assert(err, jc.ErrorIsNil)
+	s.checkSuccess(c, "databaseMaster", func(*state.UpgradeInfo) {})
+
+	c.Assert(s.backupNotes, gc.HasLen, 0)
+	backup, err := s.State.UpgradeBackup()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(backup.BackupId(), gc.Equals, "earlier")
+}
+
+func (s *UpgradeSuite) TestMasterBackupFailureFailsUpgrade(c *gc.C) {
+	s.machineIsMaster = true
+	s.backupErr = errors.New("no space left")
+	_, machineIdB, machineIdC := s.createUpgradingStateServers(c)
+	_, err := s.State.EnsureUpgradeInfo(machineIdB, s.oldVersion.Number, version.Current.Number)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.State.EnsureUpgradeInfo(machineIdC, s.oldVersion.Number, version.Current.Number)
+	c.Assert(err, jc.ErrorIsNil)
+	attemptsP := s.countUpgradeAttempts(nil)
+
+	workerErr, config, agent, context := s.runUpgradeWorker(c, multiwatcher.JobManageEnviron)
+
+	c.Check(workerErr, gc.IsNil)
+	c.Check(*attemptsP, gc.Equals, 0)
+	c.Check(config.Version, gc.Equals, s.oldVersion.Number)
+	c.Assert(agent.MachineStatusCalls, jc.DeepEquals,
+		s.makeExpectedStatusCalls(maxUpgradeRetries-1, fails, "cannot back up state: no space left"))
+	assertUpgradeNotComplete(c, context)
+	_, err = s.State.UpgradeBackup()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *UpgradeSuite) TestSuccessMasterWhenBackupFailsWithoutBackupAllowed(c *gc.C) {
+	s.machineIsMaster = true
+	s.backupErr = errors.New("no space left")
+	s.upgradeWithoutBackup = true
+	s.checkSuccess(c, "databaseMaster", func(*state.UpgradeInfo) {})
+
+	c.Assert(s.backupNotes, gc.HasLen, 1)
+	_, err := s.State.UpgradeBackup()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	c.Assert(s.logWriter.Log(), jc.LogMatches, []jc.SimpleMessage{
+		{loggo.ERROR, "cannot back up state, the upgrade will not be reversible: no space left"},
+	})
+}
+
 func (s *UpgradeSuite) TestSuccessSecondary(c *gc.C) {
 	// This test checks what happens when an upgrade works on the
 	// first attempt on a secondary state server.
@@ -383,6 +495,7 @@ func (s *UpgradeSuite) TestSuccessSecondary(c *gc.C) {
 		c.Assert(err, jc.ErrorIsNil)
 	}
 	s.checkSuccess(c, "stateServer", mungeInfo)
+	c.Assert(s.backupNotes, gc.HasLen, 0)
 }
 
 func (s *UpgradeSuite) checkSuccess(c *gc.C, target string, mungeInfo func(*state.UpgradeInfo)) *state.UpgradeInfo {
@@ -635,7 +748,11 @@ func (s *UpgradeSuite) runUpgradeWorkerUsingAgent(
 }
 
 func (s *UpgradeSuite) makeFakeConfig() *fakeConfigSetter {
-	return NewFakeConfigSetter(names.NewMachineTag("0"), s.oldVersion.Number)
+	config := NewFakeConfigSetter(names.NewMachineTag("0"), s.oldVersion.Number)
+	if s.upgradeWithoutBackup {
+		config.Values = map[string]string{agent.UpgradeWithoutBackup: "true"}
+	}
+	return config
 }
 
 // Create 3 configured state servers that appear to be running tools
@@ -914,6 +1031,11 @@ type fakeConfigSetter struct {
 	agent.ConfigSetter
 	AgentTag names.Tag
 	Version  version.Number
+	Values   map[string]string
+}
+
+func (s *fakeConfigSetter) Value(key string) string {
+	return s.Values[key]
 }
 
 func (s *fakeConfigSetter) Tag() names.Tag {
@@ -139,6 +139,7 @@ func (a *UnitAgent) APIWorkers() (worker.Worker, error) {
 			agentConfig,
 			agentConfig.UpgradedToVersion(),
 			func() bool { return false },
+			nil,
 		), nil
 	})
 	runner.StartWorker("logger", func() (worker.Worker, error) {
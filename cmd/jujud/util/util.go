@@ -22,6 +22,7 @@ import (
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/version"
 	"github.com/juju/juju/worker"
+	"github.com/juju/juju/worker/apiaddressupdater"
 	"github.com/juju/juju/worker/rsyslog"
 	"github.com/juju/juju/worker/upgrader"
 )
@@ -41,6 +42,9 @@ func IsFatal(err error) bool {
 	switch err {
 	case worker.ErrTerminateAgent, worker.ErrRebootMachine, worker.ErrShutdownMachine:
 		return true
+	case apiaddressupdater.ErrRedirected:
+		// The agent restarts to connect to another state server.
+		return true
 	}
 	if isUpgraded(err) {
 		return true
@@ -14,6 +14,7 @@ import (
 	"github.com/juju/juju/apiserver/params"
 	coretesting "github.com/juju/juju/testing"
 	"github.com/juju/juju/worker"
+	"github.com/juju/juju/worker/apiaddressupdater"
 	"github.com/juju/juju/worker/upgrader"
 )
 
@@ -61,6 +62,9 @@ var isFatalTests = []struct {
 		Code:    params.CodeNotProvisioned,
 	},
 	isFatal: false,
+}, {
+	err:     apiaddressupdater.ErrRedirected,
+	isFatal: true,
 }, {
 	err:     &FatalError{"some fatal error"},
 	isFatal: true,
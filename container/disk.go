@@ -0,0 +1,22 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// +build !windows
+
+package container
+
+import (
+	"syscall"
+
+	"github.com/juju/errors"
+)
+
+// diskSize returns the size in megabytes of the filesystem holding the
+// given path.
+func diskSize(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, errors.Trace(err)
+	}
+	return st.Blocks * uint64(st.Bsize) / (1024 * 1024), nil
+}
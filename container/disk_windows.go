@@ -0,0 +1,14 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// +build windows
+
+package container
+
+import (
+	"github.com/juju/errors"
+)
+
+func diskSize(path string) (uint64, error) {
+	return 0, errors.NotSupportedf("reading disk size")
+}
@@ -8,3 +8,8 @@ package container
 func IsLocked(lock *Lock) bool {
 	return lock.lockFile != nil
 }
+
+var (
+	MeminfoFile       = &meminfoFile
+	ContainerDiskPath = &containerDiskPath
+)
@@ -0,0 +1,93 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package container
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/constraints"
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/juju/arch"
+)
+
+// meminfoFile holds the kernel's memory statistics; it is a variable so
+// that tests can replace it.
+var meminfoFile = "/proc/meminfo"
+
+// containerDiskPath is a path on the filesystem that holds the root
+// disks of containers; it is a variable so that tests can replace it.
+var containerDiskPath = "/var/lib"
+
+// HostHardware returns the hardware characteristics of the machine that
+// hosts the containers: its architecture, memory, number of cores and
+// the size of the disk that holds the containers' root disks. It is a
+// variable so that tests can override it.
+var HostHardware = func() (instance.HardwareCharacteristics, error) {
+	hostArch := arch.HostArch()
+	cores := uint64(runtime.NumCPU())
+	hc := instance.HardwareCharacteristics{
+		Arch:     &hostArch,
+		CpuCores: &cores,
+	}
+	mem, err := hostMemory()
+	if err != nil {
+		return instance.HardwareCharacteristics{}, errors.Annotate(err, "cannot read host memory")
+	}
+	hc.Mem = &mem
+	disk, err := diskSize(containerDiskPath)
+	if err != nil {
+		return instance.HardwareCharacteristics{}, errors.Annotate(err, "cannot read host disk size")
+	}
+	hc.RootDisk = &disk
+	return hc, nil
+}
+
+// hostMemory returns the total memory of the host in megabytes.
+func hostMemory() (uint64, error) {
+	f, err := os.Open(meminfoFile)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// The line is "MemTotal: NNN kB".
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kB, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, errors.Annotatef(err, "invalid MemTotal %q", fields[1])
+		}
+		return kB / 1024, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, errors.Trace(err)
+	}
+	return 0, errors.NotFoundf("MemTotal in %s", meminfoFile)
+}
+
+// ValidateResourceConstraints returns an error if the mem, cpu-cores or
+// root-disk constraints of a container ask for more than the given host
+// hardware has. Characteristics of the host that are unknown are not
+// checked.
+func ValidateResourceConstraints(cons constraints.Value, host instance.HardwareCharacteristics) error {
+	if cons.Mem != nil && host.Mem != nil && *cons.Mem > *host.Mem {
+		return errors.Errorf("mem constraint of %dM exceeds the host's %dM", *cons.Mem, *host.Mem)
+	}
+	if cons.CpuCores != nil && host.CpuCores != nil && *cons.CpuCores > *host.CpuCores {
+		return errors.Errorf("cpu-cores constraint of %d exceeds the host's %d", *cons.CpuCores, *host.CpuCores)
+	}
+	if cons.RootDisk != nil && host.RootDisk != nil && *cons.RootDisk > *host.RootDisk {
+		return errors.Errorf("root-disk constraint of %dM exceeds the host's %dM", *cons.RootDisk, *host.RootDisk)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package container_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/constraints"
+	"github.com/juju/juju/container"
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/testing"
+)
+
+type HardwareSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&HardwareSuite{})
+
+func (s *HardwareSuite) TestHostHardware(c *gc.C) {
+	meminfo := filepath.Join(c.MkDir(), "meminfo")
+	err := ioutil.WriteFile(meminfo, []byte("MemTotal:        4194304 kB\nMemFree:         1024 kB\n"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	s.PatchValue(container.MeminfoFile, meminfo)
+	s.PatchValue(container.ContainerDiskPath, c.MkDir())
+
+	hc, err := container.HostHardware()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(*hc.Mem, gc.Equals, uint64(4096))
+	c.Assert(*hc.CpuCores > 0, jc.IsTrue)
+	c.Assert(hc.Arch, gc.NotNil)
+	c.Assert(hc.RootDisk, gc.NotNil)
+	c.Assert(*hc.RootDisk > 0, jc.IsTrue)
+}
+
+func (s *HardwareSuite) TestHostHardwareNoMemTotal(c *gc.C) {
+	meminfo := filepath.Join(c.MkDir(), "meminfo")
+	err := ioutil.WriteFile(meminfo, []byte("MemFree:         1024 kB\n"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	s.PatchValue(container.MeminfoFile, meminfo)
+
+	_, err = container.HostHardware()
+	c.Assert(err, gc.ErrorMatches, "cannot read host memory: MemTotal in .* not found")
+}
+
+func (s *HardwareSuite) TestValidateResourceConstraints(c *gc.C) {
+	host, err := instance.ParseHardware("mem=2G cpu-cores=4 root-disk=10G")
+	c.Assert(err, jc.ErrorIsNil)
+	for i, test := range []struct {
+		cons string
+		err  string
+	}{{
+		cons: "mem=2G cpu-cores=4 root-disk=10G cpu-power=1000",
+	}, {
+		cons: "mem=3G",
+		err:  "mem constraint of 3072M exceeds the host's 2048M",
+	}, {
+		cons: "cpu-cores=8",
+		err:  "cpu-cores constraint of 8 exceeds the host's 4",
+	}, {
+		cons: "root-disk=20G",
+		err:  "root-disk constraint of 20480M exceeds the host's 10240M",
+	}} {
+		c.Logf("test %d: %s", i, test.cons)
+		err := container.ValidateResourceConstraints(constraints.MustParse(test.cons), host)
+		if test.err == "" {
+			c.Check(err, jc.ErrorIsNil)
+		} else {
+			c.Check(err, gc.ErrorMatches, test.err)
+		}
+	}
+
+	// Unknown host characteristics are not checked.
+	err = container.ValidateResourceConstraints(constraints.MustParse("mem=64G"), instance.HardwareCharacteristics{})
+	c.Assert(err, jc.ErrorIsNil)
+}
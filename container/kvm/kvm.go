@@ -110,6 +110,15 @@ func (manager *containerManager) CreateContainer(
 	if manager.name != "" {
 		name = fmt.Sprintf("%s-%s", manager.name, name)
 	}
+	// Check the constraints fit on the host before creating anything.
+	host, err := container.HostHardware()
+	if err != nil {
+		logger.Warningf("cannot validate constraints of container %q: %v", name, err)
+	}
+	if err := container.ValidateResourceConstraints(machineConfig.Constraints, host); err != nil {
+		return nil, nil, errors.Annotate(err, "invalid container constraints")
+	}
+
 	// Note here that the kvmObjectFacotry only returns a valid container
 	// object, and doesn't actually construct the underlying kvm container on
 	// disk.
@@ -121,7 +130,7 @@ func (manager *containerManager) CreateContainer(
 		return nil, nil, fmt.Errorf("failed to create container directory: %v", err)
 	}
 	logger.Tracef("write cloud-init")
-	userDataFilename, err := container.WriteUserData(machineConfig, directory)
+	userDataFilename, err := container.WriteUserData(machineConfig, network, directory)
 	if err != nil {
 		err = errors.Annotate(err, "failed to write user data")
 		logger.Infof(err.Error())
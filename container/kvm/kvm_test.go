@@ -114,6 +114,28 @@ func (s *KVMSuite) TestDestroyContainer(c *gc.C) {
 	c.Assert(filepath.Join(s.RemovedDir, name), jc.IsDirectory)
 }
 
+func (s *KVMSuite) TestCreateContainerWithConstraints(c *gc.C) {
+	machineConfig, err := containertesting.MockMachineConfig("1/kvm/0")
+	c.Assert(err, jc.ErrorIsNil)
+	machineConfig.Constraints = constraints.MustParse("mem=2G cpu-cores=2 root-disk=4G")
+	network := container.BridgeNetworkConfig("nic42")
+	_, hardware, err := s.manager.CreateContainer(machineConfig, "quantal", network)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(kvm.TestStartParams.Memory, gc.Equals, uint64(2048))
+	c.Assert(kvm.TestStartParams.CpuCores, gc.Equals, uint64(2))
+	c.Assert(kvm.TestStartParams.RootDisk, gc.Equals, uint64(4))
+	c.Assert(hardware.String(), gc.Matches, `arch=\S+ cpu-cores=2 mem=2048M root-disk=4096M`)
+}
+
+func (s *KVMSuite) TestCreateContainerConstraintsExceedHost(c *gc.C) {
+	machineConfig, err := containertesting.MockMachineConfig("1/kvm/0")
+	c.Assert(err, jc.ErrorIsNil)
+	machineConfig.Constraints = constraints.MustParse("mem=8G")
+	network := container.BridgeNetworkConfig("nic42")
+	_, _, err = s.manager.CreateContainer(machineConfig, "quantal", network)
+	c.Assert(err, gc.ErrorMatches, "invalid container constraints: mem constraint of 8192M exceeds the host's 4096M")
+}
+
 // Test that CreateContainer creates proper startParams.
 func (s *KVMSuite) TestCreateContainerUtilizesReleaseSimpleStream(c *gc.C) {
 
@@ -13,6 +13,7 @@ import (
 	"github.com/juju/juju/container"
 	"github.com/juju/juju/container/kvm"
 	"github.com/juju/juju/container/kvm/mock"
+	"github.com/juju/juju/instance"
 	"github.com/juju/juju/testing"
 )
 
@@ -33,4 +34,11 @@ func (s *TestSuite) SetUpTest(c *gc.C) {
 	s.PatchValue(&container.RemovedContainerDir, s.RemovedDir)
 	s.ContainerFactory = mock.MockFactory()
 	s.PatchValue(&kvm.KvmObjectFactory, s.ContainerFactory)
+	s.PatchValue(&container.HostHardware, FakeHostHardware)
+}
+
+// FakeHostHardware is used in place of container.HostHardware by the
+// TestSuite.
+func FakeHostHardware() (instance.HardwareCharacteristics, error) {
+	return instance.MustParseHardware("arch=amd64 cpu-cores=4 mem=4G"), nil
 }
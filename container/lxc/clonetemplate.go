@@ -4,10 +4,13 @@
 package lxc
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -113,7 +116,7 @@ func EnsureCloneTemplate(
 	enableOSUpgrades bool,
 	imageURLGetter container.ImageURLGetter,
 ) (golxc.Container, error) {
-	name := fmt.Sprintf("juju-%s-lxc-template", series)
+	name := templateName(series)
 	containerDirectory, err := container.NewDirectory(name)
 	if err != nil {
 		return nil, err
@@ -225,6 +228,102 @@ func EnsureCloneTemplate(
 	return lxcContainer, nil
 }
 
+// templateName returns the name of the container that containers of
+// the given series are cloned from.
+func templateName(series string) string {
+	return fmt.Sprintf("juju-%s-lxc-template", series)
+}
+
+// TemplateParams holds the settings used to build a clone template.
+type TemplateParams struct {
+	Series               string
+	Network              *container.NetworkConfig
+	AuthorizedKeys       string
+	AptProxy             proxy.Settings
+	AptMirror            string
+	EnablePackageUpdates bool
+	EnableOSUpgrades     bool
+}
+
+// TemplateBuilder is implemented by container managers that clone their
+// containers from a template, and can build that template ahead of the
+// first container.
+type TemplateBuilder interface {
+	// BuildTemplate builds the template for the series in the given
+	// params, replacing any existing template, and returns the packages
+	// installed in it as "name=version".
+	BuildTemplate(params TemplateParams) ([]string, error)
+}
+
+// destroyCloneTemplate removes the template for the given series, if
+// it exists, so that the next call to EnsureCloneTemplate builds it
+// afresh.
+func destroyCloneTemplate(series string) error {
+	name := templateName(series)
+	lock, err := AcquireTemplateLock(name, "destroy template")
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	lxcContainer := LxcObjectFactory.New(name)
+	if !lxcContainer.IsConstructed() {
+		return nil
+	}
+	logger.Infof("destroying template %q", name)
+	if err := lxcContainer.Destroy(); err != nil {
+		return errors.Annotatef(err, "cannot destroy template %q", name)
+	}
+	return container.RemoveDirectory(name)
+}
+
+// TemplatePackages returns the packages installed in the template for
+// the given series as "name=version", sorted by name, as recorded by
+// dpkg inside the template's root filesystem. It returns an error
+// satisfying errors.IsNotFound if the template has not been built.
+func TemplatePackages(series string) ([]string, error) {
+	statusFile := filepath.Join(LxcContainerDir, templateName(series), "rootfs", "var", "lib", "dpkg", "status")
+	f, err := os.Open(statusFile)
+	if os.IsNotExist(err) {
+		return nil, errors.NotFoundf("template for series %q", series)
+	} else if err != nil {
+		return nil, errors.Annotate(err, "cannot read template packages")
+	}
+	defer f.Close()
+
+	// The status file holds a stanza per package, separated by blank
+	// lines; only packages that are fully installed are reported.
+	var packages []string
+	var name, version string
+	var installed bool
+	addPackage := func() {
+		if name != "" && version != "" && installed {
+			packages = append(packages, name+"="+version)
+		}
+		name, version, installed = "", "", false
+	}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			addPackage()
+		case strings.HasPrefix(line, "Package: "):
+			name = strings.TrimPrefix(line, "Package: ")
+		case strings.HasPrefix(line, "Version: "):
+			version = strings.TrimPrefix(line, "Version: ")
+		case strings.HasPrefix(line, "Status: "):
+			installed = strings.HasSuffix(line, " installed")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Annotate(err, "cannot read template packages")
+	}
+	addPackage()
+	sort.Strings(packages)
+	return packages, nil
+}
+
 type logTail struct {
 	tick  time.Time
 	mutex sync.Mutex
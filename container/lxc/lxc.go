@@ -25,6 +25,7 @@ import (
 
 	"github.com/juju/errors"
 	"github.com/juju/juju/agent"
+	"github.com/juju/juju/constraints"
 	"github.com/juju/juju/container"
 	"github.com/juju/juju/environs/cloudinit"
 	"github.com/juju/juju/instance"
@@ -49,6 +50,11 @@ const (
 	DefaultLxcBridge = "lxcbr0"
 	// Btrfs is special as we treat it differently for create and clone.
 	Btrfs = "btrfs"
+	// minCPUShares is the smallest cpu.shares weight the kernel accepts.
+	minCPUShares = 2
+	// cfsPeriod is the scheduling period, in microseconds, over which
+	// the CPU time of a container limited by cpu-cores is allotted.
+	cfsPeriod = 100000
 )
 
 // DefaultNetworkConfig returns a valid NetworkConfig to use the
@@ -213,13 +219,25 @@ func (manager *containerManager) CreateContainer(
 		name = fmt.Sprintf("%s-%s", manager.name, name)
 	}
 
+	// Check the constraints fit on the host before creating anything.
+	if machineConfig.Constraints.RootDisk != nil {
+		return nil, nil, errors.Errorf("invalid container constraints: root-disk is not supported by lxc containers")
+	}
+	host, err := container.HostHardware()
+	if err != nil {
+		logger.Warningf("cannot validate constraints of container %q: %v", name, err)
+	}
+	if err := container.ValidateResourceConstraints(machineConfig.Constraints, host); err != nil {
+		return nil, nil, errors.Annotate(err, "invalid container constraints")
+	}
+
 	// Create the cloud-init.
 	directory, err := container.NewDirectory(name)
 	if err != nil {
 		return nil, nil, errors.Annotate(err, "failed to create a directory for the container")
 	}
 	logger.Tracef("write cloud-init")
-	userDataFilename, err := container.WriteUserData(machineConfig, directory)
+	userDataFilename, err := container.WriteUserData(machineConfig, network, directory)
 	if err != nil {
 		return nil, nil, errors.Annotate(err, "failed to write user data")
 	}
@@ -294,6 +312,12 @@ func (manager *containerManager) CreateContainer(
 	if err := mountHostLogDir(name, manager.logdir); err != nil {
 		return nil, nil, errors.Annotate(err, "failed to mount the directory to log to")
 	}
+	limits, hardware := resourceLimits(machineConfig.Constraints, host)
+	if limits != "" {
+		if err := appendToContainerConfig(name, limits); err != nil {
+			return nil, nil, errors.Annotate(err, "failed to set the resource limits of the container")
+		}
+	}
 	// Start the lxc container with the appropriate settings for grabbing the
 	// console output and a log file.
 	consoleFile := filepath.Join(directory, "console.log")
@@ -323,11 +347,43 @@ func (manager *containerManager) CreateContainer(
 		return nil, nil, errors.Annotate(err, "container failed to start")
 	}
 
+	return &lxcInstance{lxcContainer, name}, hardware, nil
+}
+
+// resourceLimits returns the cgroup settings of the LXC config that limit
+// a container to the mem, cpu-cores and cpu-power constraints, and the
+// hardware characteristics the container ends up with on the given host.
+// Unconstrained resources are shared with the host. The cpu-cores
+// constraint limits the CPU time of the container to that of the given
+// number of cores, rather than pinning it to particular cores, so that
+// containers are not all crowded onto the first cores of the host.
+func resourceLimits(cons constraints.Value, host instance.HardwareCharacteristics) (string, *instance.HardwareCharacteristics) {
 	hardware := &instance.HardwareCharacteristics{
-		Arch: &version.Current.Arch,
+		Arch:     &version.Current.Arch,
+		Mem:      host.Mem,
+		CpuCores: host.CpuCores,
+	}
+	var config string
+	if cons.Mem != nil && *cons.Mem > 0 {
+		config += fmt.Sprintf("lxc.cgroup.memory.limit_in_bytes = %dM\n", *cons.Mem)
+		hardware.Mem = cons.Mem
+	}
+	if cons.CpuCores != nil && *cons.CpuCores > 0 {
+		config += fmt.Sprintf("lxc.cgroup.cpu.cfs_period_us = %d\n", cfsPeriod)
+		config += fmt.Sprintf("lxc.cgroup.cpu.cfs_quota_us = %d\n", *cons.CpuCores*cfsPeriod)
+		hardware.CpuCores = cons.CpuCores
+	}
+	if cons.CpuPower != nil && *cons.CpuPower > 0 {
+		// A cpu-power of 100 is one reference core, which gets the
+		// default weight of 1024 shares.
+		shares := *cons.CpuPower * 1024 / 100
+		if shares < minCPUShares {
+			shares = minCPUShares
+		}
+		config += fmt.Sprintf("lxc.cgroup.cpu.shares = %d\n", shares)
+		hardware.CpuPower = cons.CpuPower
 	}
-
-	return &lxcInstance{lxcContainer, name}, hardware, nil
+	return config, hardware
 }
 
 func createContainer(lxcContainer golxc.Container, network *container.NetworkConfig, containerDirectory string,
@@ -471,6 +527,41 @@ func (manager *containerManager) DestroyContainer(id instance.Id) error {
 	return err
 }
 
+// containerManager implements TemplateBuilder.
+var _ TemplateBuilder = (*containerManager)(nil)
+
+// BuildTemplate implements TemplateBuilder. It returns an error
+// satisfying errors.IsNotSupported if the manager does not clone its
+// containers.
+func (manager *containerManager) BuildTemplate(params TemplateParams) ([]string, error) {
+	if !manager.createWithClone {
+		return nil, errors.NotSupportedf("lxc templates without lxc-clone")
+	}
+	if manager.useAUFS && LxcObjectFactory.New(templateName(params.Series)).IsConstructed() {
+		// AUFS clones share the template's root filesystem, so it
+		// cannot be replaced underneath them.
+		return nil, errors.Errorf("cannot rebuild template for series %q used by lxc-clone-aufs containers", params.Series)
+	}
+	if err := destroyCloneTemplate(params.Series); err != nil {
+		return nil, errors.Trace(err)
+	}
+	_, err := EnsureCloneTemplate(
+		manager.backingFilesystem,
+		params.Series,
+		params.Network,
+		params.AuthorizedKeys,
+		params.AptProxy,
+		params.AptMirror,
+		params.EnablePackageUpdates,
+		params.EnableOSUpgrades,
+		manager.imageURLGetter,
+	)
+	if err != nil {
+		return nil, errors.Annotate(err, "cannot build template")
+	}
+	return TemplatePackages(params.Series)
+}
+
 func (manager *containerManager) ListContainers() (result []instance.Instance, err error) {
 	containers, err := LxcObjectFactory.List()
 	if err != nil {
@@ -608,10 +699,32 @@ func generateNetworkConfig(network *container.NetworkConfig) string {
 	case container.BridgeNetwork:
 		lxcConfig = networkConfigTemplate("veth", network.Device)
 	}
-
+	if len(network.Interfaces) > 0 {
+		lxcConfig += staticAddressConfig(network)
+	}
 	return lxcConfig
 }
 
+// staticAddressConfig returns the LXC config setting the static address
+// of the container's first network interface, so that it is configured
+// before the container boots.
+func staticAddressConfig(network *container.NetworkConfig) string {
+	iface := network.Interfaces[0]
+	_, ipNet, err := net.ParseCIDR(iface.CIDR)
+	if err != nil || iface.Address.Value == "" {
+		logger.Warningf("cannot configure static address of container: invalid interface %+v", iface)
+		return ""
+	}
+	prefix, _ := ipNet.Mask.Size()
+	config := fmt.Sprintf("lxc.network.ipv4 = %s/%d\n", iface.Address.Value, prefix)
+	// LXC routes to a gateway outside the subnet, such as the host's
+	// address on the bridge, through the interface itself.
+	if iface.GatewayAddress.Value != "" {
+		config += fmt.Sprintf("lxc.network.ipv4.gateway = %s\n", iface.GatewayAddress.Value)
+	}
+	return config
+}
+
 func writeLxcConfig(network *container.NetworkConfig, directory string) (string, error) {
 	networkConfig := generateNetworkConfig(network)
 	configFilename := filepath.Join(directory, "lxc.conf")
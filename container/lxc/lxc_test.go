@@ -27,6 +27,7 @@ import (
 	"launchpad.net/golxc"
 
 	"github.com/juju/juju/agent"
+	"github.com/juju/juju/constraints"
 	"github.com/juju/juju/container"
 	"github.com/juju/juju/container/lxc"
 	"github.com/juju/juju/container/lxc/mock"
@@ -34,6 +35,7 @@ import (
 	containertesting "github.com/juju/juju/container/testing"
 	"github.com/juju/juju/instance"
 	instancetest "github.com/juju/juju/instance/testing"
+	"github.com/juju/juju/network"
 	coretesting "github.com/juju/juju/testing"
 )
 
@@ -422,6 +424,82 @@ func (s *LxcSuite) TestCreateContainerEventsWithCloneExistingTemplateAUFS(c *gc.
 	s.AssertEvent(c, <-s.events, mock.Started, name)
 }
 
+const dpkgStatus = `Package: cloud-init
+Status: install ok installed
+Version: 0.7.5-0ubuntu1
+
+Package: python2.7
+Status: install ok installed
+Version: 2.7.6-8
+
+Package: removed-package
+Status: deinstall ok config-files
+Version: 1.0
+`
+
+func (s *LxcSuite) writeTemplatePackages(c *gc.C, name string) {
+	dir := filepath.Join(s.LxcDir, name, "rootfs", "var", "lib", "dpkg")
+	err := os.MkdirAll(dir, 0755)
+	c.Assert(err, jc.ErrorIsNil)
+	err = ioutil.WriteFile(filepath.Join(dir, "status"), []byte(dpkgStatus), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *LxcSuite) buildTemplate(c *gc.C) ([]string, error) {
+	manager := s.makeManager(c, "test")
+	return manager.(lxc.TemplateBuilder).BuildTemplate(lxc.TemplateParams{
+		Series:  "quantal",
+		Network: container.BridgeNetworkConfig("nic42"),
+	})
+}
+
+func (s *LxcSuite) TestBuildTemplate(c *gc.C) {
+	s.PatchValue(&s.useClone, true)
+	template := "juju-quantal-lxc-template"
+	s.writeTemplatePackages(c, template)
+	ch := s.ensureTemplateStopped(template)
+	defer func() { <-ch }()
+	packages, err := s.buildTemplate(c)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(packages, jc.DeepEquals, []string{"cloud-init=0.7.5-0ubuntu1", "python2.7=2.7.6-8"})
+	s.AssertEvent(c, <-s.events, mock.Created, template)
+	s.AssertEvent(c, <-s.events, mock.Started, template)
+	s.AssertEvent(c, <-s.events, mock.Stopped, template)
+}
+
+func (s *LxcSuite) TestBuildTemplateReplacesExisting(c *gc.C) {
+	s.createTemplate(c)
+	s.PatchValue(&s.useClone, true)
+	template := "juju-quantal-lxc-template"
+	s.writeTemplatePackages(c, template)
+	ch := s.ensureTemplateStopped(template)
+	defer func() { <-ch }()
+	_, err := s.buildTemplate(c)
+	c.Assert(err, jc.ErrorIsNil)
+	s.AssertEvent(c, <-s.events, mock.Destroyed, template)
+	s.AssertEvent(c, <-s.events, mock.Created, template)
+	s.AssertEvent(c, <-s.events, mock.Started, template)
+	s.AssertEvent(c, <-s.events, mock.Stopped, template)
+}
+
+func (s *LxcSuite) TestTemplatePackagesNotBuilt(c *gc.C) {
+	_, err := lxc.TemplatePackages("quantal")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *LxcSuite) TestBuildTemplateWithoutClone(c *gc.C) {
+	_, err := s.buildTemplate(c)
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+}
+
+func (s *LxcSuite) TestBuildTemplateExistingAUFS(c *gc.C) {
+	s.createTemplate(c)
+	s.PatchValue(&s.useClone, true)
+	s.PatchValue(&s.useAUFS, true)
+	_, err := s.buildTemplate(c)
+	c.Assert(err, gc.ErrorMatches, `cannot rebuild template for series "quantal" used by lxc-clone-aufs containers`)
+}
+
 func (s *LxcSuite) TestCreateContainerWithCloneMountsAndAutostarts(c *gc.C) {
 	s.createTemplate(c)
 	s.PatchValue(&s.useClone, true)
@@ -539,6 +617,61 @@ lxc.mount.entry=/var/log/juju var/log/juju none defaults,bind 0 0
 	c.Assert(autostartLink, jc.DoesNotExist)
 }
 
+func (s *LxcSuite) TestCreateContainerWithConstraints(c *gc.C) {
+	manager := s.makeManager(c, "test")
+	machineConfig, err := containertesting.MockMachineConfig("1/lxc/0")
+	c.Assert(err, jc.ErrorIsNil)
+	machineConfig.Constraints = constraints.MustParse("mem=1G cpu-cores=2 cpu-power=50")
+	network := container.BridgeNetworkConfig("nic42")
+	inst, hardware, err := manager.CreateContainer(machineConfig, "quantal", network)
+	c.Assert(err, jc.ErrorIsNil)
+
+	config, err := ioutil.ReadFile(lxc.ContainerConfigFilename(string(inst.Id())))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(config), jc.Contains, `
+lxc.cgroup.memory.limit_in_bytes = 1024M
+lxc.cgroup.cpu.cfs_period_us = 100000
+lxc.cgroup.cpu.cfs_quota_us = 200000
+lxc.cgroup.cpu.shares = 512
+`)
+	c.Assert(string(config), gc.Not(jc.Contains), "cpuset")
+	c.Assert(hardware.String(), gc.Matches, `arch=\S+ cpu-cores=2 cpu-power=50 mem=1024M`)
+}
+
+func (s *LxcSuite) TestCreateContainerRootDiskNotSupported(c *gc.C) {
+	manager := s.makeManager(c, "test")
+	machineConfig, err := containertesting.MockMachineConfig("1/lxc/0")
+	c.Assert(err, jc.ErrorIsNil)
+	machineConfig.Constraints = constraints.MustParse("root-disk=4G")
+	network := container.BridgeNetworkConfig("nic42")
+	_, _, err = manager.CreateContainer(machineConfig, "quantal", network)
+	c.Assert(err, gc.ErrorMatches, "invalid container constraints: root-disk is not supported by lxc containers")
+}
+
+func (s *LxcSuite) TestCreateContainerWithoutConstraintsSharesHost(c *gc.C) {
+	manager := s.makeManager(c, "test")
+	machineConfig, err := containertesting.MockMachineConfig("1/lxc/0")
+	c.Assert(err, jc.ErrorIsNil)
+	network := container.BridgeNetworkConfig("nic42")
+	inst, hardware, err := manager.CreateContainer(machineConfig, "quantal", network)
+	c.Assert(err, jc.ErrorIsNil)
+
+	config, err := ioutil.ReadFile(lxc.ContainerConfigFilename(string(inst.Id())))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(config), gc.Not(jc.Contains), "lxc.cgroup")
+	c.Assert(hardware.String(), gc.Matches, `arch=\S+ cpu-cores=4 mem=4096M`)
+}
+
+func (s *LxcSuite) TestCreateContainerConstraintsExceedHost(c *gc.C) {
+	manager := s.makeManager(c, "test")
+	machineConfig, err := containertesting.MockMachineConfig("1/lxc/0")
+	c.Assert(err, jc.ErrorIsNil)
+	machineConfig.Constraints = constraints.MustParse("cpu-cores=8")
+	network := container.BridgeNetworkConfig("nic42")
+	_, _, err = manager.CreateContainer(machineConfig, "quantal", network)
+	c.Assert(err, gc.ErrorMatches, "invalid container constraints: cpu-cores constraint of 8 exceeds the host's 4")
+}
+
 func (s *LxcSuite) TestDestroyContainerRemovesAutostartLink(c *gc.C) {
 	manager := s.makeManager(c, "test")
 	instance := containertesting.CreateContainer(c, manager, "1/lxc/0")
@@ -609,6 +742,24 @@ func (*NetworkSuite) TestGenerateNetworkConfig(c *gc.C) {
 	}
 }
 
+func (*NetworkSuite) TestGenerateNetworkConfigStaticAddress(c *gc.C) {
+	restorer := gitjujutesting.PatchValue(lxc.DiscoverHostNIC, func() (net.Interface, error) {
+		return net.Interface{}, fmt.Errorf("no NIC")
+	})
+	defer restorer.Restore()
+
+	config := container.BridgeNetworkConfig("br0")
+	config.Interfaces = []network.Info{{
+		CIDR:           "10.0.0.0/24",
+		InterfaceName:  "eth0",
+		Address:        network.NewAddress("10.0.0.5", network.ScopeCloudLocal),
+		GatewayAddress: network.NewAddress("10.0.0.1", network.ScopeCloudLocal),
+	}}
+	generated := lxc.GenerateNetworkConfig(config)
+	c.Check(generated, jc.Contains, "lxc.network.link = br0\n")
+	c.Check(generated, jc.Contains, "lxc.network.ipv4 = 10.0.0.5/24\nlxc.network.ipv4.gateway = 10.0.0.1\n")
+}
+
 func (*NetworkSuite) TestNetworkConfigTemplate(c *gc.C) {
 	restorer := gitjujutesting.PatchValue(lxc.DiscoverHostNIC, func() (net.Interface, error) {
 		return net.Interface{
@@ -9,6 +9,7 @@ import (
 	"github.com/juju/juju/container"
 	"github.com/juju/juju/container/lxc"
 	"github.com/juju/juju/container/lxc/mock"
+	"github.com/juju/juju/instance"
 	"github.com/juju/juju/testing"
 )
 
@@ -35,4 +36,11 @@ func (s *TestSuite) SetUpTest(c *gc.C) {
 	s.PatchValue(&lxc.LxcRestartDir, s.RestartDir)
 	s.ContainerFactory = mock.MockFactory(s.LxcDir)
 	s.PatchValue(&lxc.LxcObjectFactory, s.ContainerFactory)
+	s.PatchValue(&container.HostHardware, FakeHostHardware)
+}
+
+// FakeHostHardware reports a host with 4 cores and 4G of memory, so
+// that tests do not depend on the machine running them.
+func FakeHostHardware() (instance.HardwareCharacteristics, error) {
+	return instance.MustParseHardware("arch=amd64 cpu-cores=4 mem=4G"), nil
 }
@@ -3,6 +3,17 @@
 
 package container
 
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/network"
+)
+
 const (
 	// BridgeNetwork will have the container use the network bridge.
 	BridgeNetwork = "bridge"
@@ -14,16 +25,65 @@ const (
 type NetworkConfig struct {
 	NetworkType string
 	Device      string
+
+	// Interfaces holds the configuration of the container's network
+	// interfaces when they have static addresses. If it is empty, the
+	// interfaces are configured dynamically.
+	Interfaces []network.Info
 }
 
 // BridgeNetworkConfig returns a valid NetworkConfig to use the specified
 // device as a network bridge for the container.
 func BridgeNetworkConfig(device string) *NetworkConfig {
-	return &NetworkConfig{BridgeNetwork, device}
+	return &NetworkConfig{NetworkType: BridgeNetwork, Device: device}
 }
 
 // PhysicalNetworkConfig returns a valid NetworkConfig to use the specified
 // device as the network device for the container.
 func PhysicalNetworkConfig(device string) *NetworkConfig {
-	return &NetworkConfig{PhysicalNetwork, device}
+	return &NetworkConfig{NetworkType: PhysicalNetwork, Device: device}
+}
+
+// networkInterfacesFile is the file of the container that holds the
+// configuration of its network interfaces.
+const networkInterfacesFile = "/etc/network/interfaces"
+
+// NetworkInterfacesConfig returns the contents of the container's
+// /etc/network/interfaces file, configuring each of the given
+// interfaces with its static address.
+func NetworkInterfacesConfig(interfaces []network.Info) (string, error) {
+	var buf bytes.Buffer
+	buf.WriteString("auto lo\niface lo inet loopback\n")
+	for _, iface := range interfaces {
+		if iface.Address.Value == "" {
+			return "", errors.Errorf("interface %q has no address", iface.InterfaceName)
+		}
+		_, ipNet, err := net.ParseCIDR(iface.CIDR)
+		if err != nil {
+			return "", errors.Annotatef(err, "invalid CIDR of interface %q", iface.InterfaceName)
+		}
+		name := iface.ActualInterfaceName()
+		fmt.Fprintf(&buf, "\nauto %s\niface %s inet static\n", name, name)
+		fmt.Fprintf(&buf, "    address %s\n", iface.Address.Value)
+		fmt.Fprintf(&buf, "    netmask %s\n", net.IP(ipNet.Mask))
+		if gateway := iface.GatewayAddress.Value; gateway != "" {
+			if ip := net.ParseIP(gateway); ip != nil && !ipNet.Contains(ip) {
+				// The gateway is outside the subnet, as when the
+				// host routes the container's address, so it needs
+				// a route of its own.
+				fmt.Fprintf(&buf, "    post-up ip route add %s dev %s\n", gateway, name)
+				fmt.Fprintf(&buf, "    post-up ip route add default via %s\n", gateway)
+			} else {
+				fmt.Fprintf(&buf, "    gateway %s\n", gateway)
+			}
+		}
+		if len(iface.DNSServers) > 0 {
+			servers := make([]string, len(iface.DNSServers))
+			for i, server := range iface.DNSServers {
+				servers[i] = server.Value
+			}
+			fmt.Fprintf(&buf, "    dns-nameservers %s\n", strings.Join(servers, " "))
+		}
+	}
+	return buf.String(), nil
 }
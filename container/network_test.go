@@ -0,0 +1,85 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package container_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/container"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/testing"
+)
+
+type NetworkSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&NetworkSuite{})
+
+func (s *NetworkSuite) TestNetworkInterfacesConfig(c *gc.C) {
+	config, err := container.NetworkInterfacesConfig([]network.Info{{
+		CIDR:           "10.0.0.0/24",
+		InterfaceName:  "eth0",
+		Address:        network.NewAddress("10.0.0.5", network.ScopeCloudLocal),
+		GatewayAddress: network.NewAddress("10.0.0.1", network.ScopeCloudLocal),
+		DNSServers:     network.NewAddresses("10.0.0.2", "8.8.8.8"),
+	}, {
+		CIDR:          "192.168.0.0/16",
+		InterfaceName: "eth1",
+		VLANTag:       42,
+		Address:       network.NewAddress("192.168.1.5", network.ScopeCloudLocal),
+	}})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(config, gc.Equals, `auto lo
+iface lo inet loopback
+
+auto eth0
+iface eth0 inet static
+    address 10.0.0.5
+    netmask 255.255.255.0
+    gateway 10.0.0.1
+    dns-nameservers 10.0.0.2 8.8.8.8
+
+auto eth1.42
+iface eth1.42 inet static
+    address 192.168.1.5
+    netmask 255.255.0.0
+`)
+}
+
+func (s *NetworkSuite) TestNetworkInterfacesConfigGatewayOutsideSubnet(c *gc.C) {
+	config, err := container.NetworkInterfacesConfig([]network.Info{{
+		CIDR:           "10.0.0.0/24",
+		InterfaceName:  "eth0",
+		Address:        network.NewAddress("10.0.0.5", network.ScopeCloudLocal),
+		GatewayAddress: network.NewAddress("10.0.3.1", network.ScopeCloudLocal),
+	}})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(config, gc.Equals, `auto lo
+iface lo inet loopback
+
+auto eth0
+iface eth0 inet static
+    address 10.0.0.5
+    netmask 255.255.255.0
+    post-up ip route add 10.0.3.1 dev eth0
+    post-up ip route add default via 10.0.3.1
+`)
+}
+
+func (s *NetworkSuite) TestNetworkInterfacesConfigErrors(c *gc.C) {
+	_, err := container.NetworkInterfacesConfig([]network.Info{{
+		CIDR:          "10.0.0.0/24",
+		InterfaceName: "eth0",
+	}})
+	c.Assert(err, gc.ErrorMatches, `interface "eth0" has no address`)
+
+	_, err = container.NetworkInterfacesConfig([]network.Info{{
+		CIDR:          "10.0.0.0",
+		InterfaceName: "eth0",
+		Address:       network.NewAddress("10.0.0.5", network.ScopeCloudLocal),
+	}})
+	c.Assert(err, gc.ErrorMatches, `invalid CIDR of interface "eth0": .*`)
+}
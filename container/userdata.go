@@ -4,6 +4,7 @@
 package container
 
 import (
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 
@@ -19,9 +20,10 @@ var (
 
 // WriteUserData generates the cloud init for the specified machine config,
 // and writes the serialized form out to a cloud-init file in the directory
-// specified.
-func WriteUserData(machineConfig *cloudinit.MachineConfig, directory string) (string, error) {
-	userData, err := cloudInitUserData(machineConfig)
+// specified. If the network config has interfaces with static addresses,
+// the cloud init configures them.
+func WriteUserData(machineConfig *cloudinit.MachineConfig, networkConfig *NetworkConfig, directory string) (string, error) {
+	userData, err := cloudInitUserData(machineConfig, networkConfig)
 	if err != nil {
 		logger.Errorf("failed to create user data: %v", err)
 		return "", err
@@ -40,8 +42,21 @@ func WriteCloudInitFile(directory string, userData []byte) (string, error) {
 	return userDataFilename, nil
 }
 
-func cloudInitUserData(machineConfig *cloudinit.MachineConfig) ([]byte, error) {
+func cloudInitUserData(machineConfig *cloudinit.MachineConfig, networkConfig *NetworkConfig) ([]byte, error) {
 	cloudConfig := coreCloudinit.New()
+	if networkConfig != nil && len(networkConfig.Interfaces) > 0 {
+		// Configure the static addresses before anything needs the
+		// network.
+		interfaces, err := NetworkInterfacesConfig(networkConfig.Interfaces)
+		if err != nil {
+			return nil, err
+		}
+		cloudConfig.AddBootTextFile(networkInterfacesFile, interfaces, 0644)
+		for _, iface := range networkConfig.Interfaces {
+			name := iface.ActualInterfaceName()
+			cloudConfig.AddBootCmd(fmt.Sprintf("ifdown --force %s; ifup %s", name, name))
+		}
+	}
 	udata, err := cloudinit.NewUserdataConfig(machineConfig, cloudConfig)
 	if err != nil {
 		return nil, err
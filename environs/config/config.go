@@ -65,6 +65,19 @@ const (
 	// refresh addresses from the provider each time.
 	DefaultBootstrapSSHAddressesDelay int = 10
 
+	// DefaultProvisionerRetryAttempts is the number of attempts the
+	// provisioner makes to start an instance when it fails transiently.
+	DefaultProvisionerRetryAttempts int = 5
+
+	// DefaultProvisionerRetryDelay is the amount of time the provisioner
+	// waits before retrying to start an instance, in seconds. The delay
+	// doubles after each further attempt.
+	DefaultProvisionerRetryDelay int = 10
+
+	// DefaultProvisionerRetryMaxDelay is the longest time the provisioner
+	// waits before retrying to start an instance, in seconds.
+	DefaultProvisionerRetryMaxDelay int = 600
+
 	// fallbackLtsSeries is the latest LTS series we'll use, if we fail to
 	// obtain this information from the system.
 	fallbackLtsSeries string = "trusty"
@@ -101,6 +114,24 @@ const (
 	// ProvisionerHarvestModeKey stores the key for this setting.
 	ProvisionerHarvestModeKey = "provisioner-harvest-mode"
 
+	// ProvisionerRetryAttemptsKey stores the key for this setting.
+	ProvisionerRetryAttemptsKey = "provisioner-retry-attempts"
+
+	// ProvisionerRetryDelayKey stores the key for this setting.
+	ProvisionerRetryDelayKey = "provisioner-retry-delay"
+
+	// ProvisionerRetryMaxDelayKey stores the key for this setting.
+	ProvisionerRetryMaxDelayKey = "provisioner-retry-max-delay"
+
+	// LXCTemplateMaxAgeKey stores the key for this setting.
+	LXCTemplateMaxAgeKey = "lxc-template-max-age"
+
+	// AllocateContainerAddressesKey stores the key for this setting.
+	AllocateContainerAddressesKey = "allocate-container-addresses"
+
+	// NetworkSafeApplyKey stores the key for this setting.
+	NetworkSafeApplyKey = "network-safe-apply"
+
 	// AgentStreamKey stores the key for this setting.
 	AgentStreamKey = "agent-stream"
 
@@ -146,6 +177,42 @@ const (
 	// PreventAllChangesKey stores the value for this setting
 	PreventAllChangesKey = BlockKeyPrefix + "all-changes"
 
+	// PasswordMinLengthKey stores the key for this setting.
+	PasswordMinLengthKey = "password-min-length"
+
+	// PasswordMinCharClassesKey stores the key for this setting.
+	PasswordMinCharClassesKey = "password-min-char-classes"
+
+	// IdentityProviderKey stores the key for this setting.
+	IdentityProviderKey = "identity-provider"
+
+	// IdentityDomainKey stores the key for this setting.
+	IdentityDomainKey = "identity-domain"
+
+	// IdentityGroupAccessKey stores the key for this setting.
+	IdentityGroupAccessKey = "identity-group-access"
+
+	// IdentityLDAPURLKey stores the key for this setting.
+	IdentityLDAPURLKey = "identity-ldap-url"
+
+	// IdentityLDAPUserDNKey stores the key for this setting.
+	IdentityLDAPUserDNKey = "identity-ldap-user-dn"
+
+	// IdentityLDAPGroupBaseKey stores the key for this setting.
+	IdentityLDAPGroupBaseKey = "identity-ldap-group-base"
+
+	// IdentityHtpasswdKey stores the key for this setting.
+	IdentityHtpasswdKey = "identity-htpasswd"
+
+	// IdentityHtgroupsKey stores the key for this setting.
+	IdentityHtgroupsKey = "identity-htgroups"
+
+	// IdentityHtpasswdPathKey and IdentityHtgroupsPathKey store the
+	// keys of the paths of files to read IdentityHtpasswdKey and
+	// IdentityHtgroupsKey from.
+	IdentityHtpasswdPathKey = IdentityHtpasswdKey + "-path"
+	IdentityHtgroupsPathKey = IdentityHtgroupsKey + "-path"
+
 	//
 	// Deprecated Settings Attributes
 	//
@@ -413,6 +480,15 @@ func (c *Config) fillInDefaults() error {
 	if err != nil {
 		return err
 	}
+	// The htpasswd and htgroups files of the identity provider have
+	// no default location, so they are only read if a path is given.
+	for _, attr := range []string{IdentityHtpasswdKey, IdentityHtgroupsKey} {
+		if path, _ := c.defined[attr+"-path"].(string); path != "" {
+			if err := maybeReadAttrFromFile(c.defined, attr, ""); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -570,6 +646,30 @@ func Validate(cfg, old *Config) error {
 		}
 	}
 
+	// Check the provisioner retry policy.
+	for _, key := range []string{ProvisionerRetryAttemptsKey, ProvisionerRetryDelayKey, ProvisionerRetryMaxDelayKey} {
+		if v, ok := cfg.defined[key].(int); ok && v < 1 {
+			return fmt.Errorf("%s must be positive, got %d", key, v)
+		}
+	}
+
+	// Check the LXC template refresh schedule.
+	if v, ok := cfg.defined[LXCTemplateMaxAgeKey].(int); ok && v < 0 {
+		return fmt.Errorf("%s must not be negative, got %d", LXCTemplateMaxAgeKey, v)
+	}
+
+	// Check the password rules.
+	if v, ok := cfg.defined[PasswordMinLengthKey].(int); ok && v < 0 {
+		return fmt.Errorf("%s must not be negative, got %d", PasswordMinLengthKey, v)
+	}
+	if v, ok := cfg.defined[PasswordMinCharClassesKey].(int); ok && (v < 0 || v > MaxPasswordCharClasses) {
+		return fmt.Errorf("%s must be between 0 and %d, got %d", PasswordMinCharClassesKey, MaxPasswordCharClasses, v)
+	}
+
+	if err := validateIdentityProvider(cfg); err != nil {
+		return err
+	}
+
 	// Check the immutable config values.  These can't change
 	if old != nil {
 		for _, attr := range immutableAttributes {
@@ -778,6 +878,155 @@ func (c *Config) PreventAllChanges() bool {
 	return DefaultPreventAllChanges
 }
 
+// MaxPasswordCharClasses holds the number of classes of character
+// that a password can contain: lower case letters, upper case letters,
+// digits and everything else.
+const MaxPasswordCharClasses = 4
+
+// PasswordMinLength returns the minimum length of user passwords. Zero
+// means there is no minimum.
+func (c *Config) PasswordMinLength() int {
+	v, _ := c.defined[PasswordMinLengthKey].(int)
+	return v
+}
+
+// PasswordMinCharClasses returns the minimum number of classes of
+// character that user passwords must contain; see
+// MaxPasswordCharClasses.
+func (c *Config) PasswordMinCharClasses() int {
+	v, _ := c.defined[PasswordMinCharClassesKey].(int)
+	return v
+}
+
+// Identity providers that users can log in with instead of having a
+// local juju password.
+const (
+	IdentityProviderLDAP     = "ldap"
+	IdentityProviderHtpasswd = "htpasswd"
+)
+
+// IdentityProvider returns the type of the external identity provider
+// that users can log in with, or "" if there is none.
+func (c *Config) IdentityProvider() string {
+	return c.asString(IdentityProviderKey)
+}
+
+// IdentityDomain returns the domain of the users of the identity
+// provider: a user "bob" of the provider logs in as "bob@<domain>".
+// It defaults to the type of the provider.
+func (c *Config) IdentityDomain() string {
+	if domain := c.asString(IdentityDomainKey); domain != "" {
+		return domain
+	}
+	return c.IdentityProvider()
+}
+
+// IdentityGroupAccess returns the access to environments, by group,
+// of the users of the identity provider. Users belonging to several
+// groups get the highest access; users belonging to none cannot log
+// in.
+func (c *Config) IdentityGroupAccess() map[string]string {
+	groupAccess, _ := parseIdentityGroupAccess(c.asString(IdentityGroupAccessKey))
+	return groupAccess
+}
+
+// IdentityLDAPURL returns the URL of the LDAP server users are
+// authenticated with, as ldap://host[:port] or ldaps://host[:port].
+// Connections to ldap:// URLs are always upgraded with StartTLS.
+func (c *Config) IdentityLDAPURL() string {
+	return c.asString(IdentityLDAPURLKey)
+}
+
+// IdentityLDAPUserDN returns the template of the DN users bind to the
+// LDAP server as, with "%s" standing for the user name.
+func (c *Config) IdentityLDAPUserDN() string {
+	return c.asString(IdentityLDAPUserDNKey)
+}
+
+// IdentityLDAPGroupBase returns the DN under which the LDAP groups of
+// users are searched for.
+func (c *Config) IdentityLDAPGroupBase() string {
+	return c.asString(IdentityLDAPGroupBaseKey)
+}
+
+// IdentityHtpasswd returns the contents of the htpasswd file users
+// are authenticated with.
+func (c *Config) IdentityHtpasswd() string {
+	return c.asString(IdentityHtpasswdKey)
+}
+
+// IdentityHtgroups returns the contents of the htgroups file holding
+// the groups of the users in the htpasswd file.
+func (c *Config) IdentityHtgroups() string {
+	return c.asString(IdentityHtgroupsKey)
+}
+
+// parseIdentityGroupAccess parses a comma-separated list of
+// group=access pairs.
+func parseIdentityGroupAccess(s string) (map[string]string, error) {
+	groupAccess := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("%s must be a list of group=access pairs, got %q", IdentityGroupAccessKey, s)
+		}
+		group, access := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		switch access {
+		case "read", "write", "admin":
+		default:
+			return nil, fmt.Errorf("%s: unknown access %q for group %q", IdentityGroupAccessKey, access, group)
+		}
+		groupAccess[group] = access
+	}
+	return groupAccess, nil
+}
+
+// validateIdentityProvider checks the settings of the identity provider.
+func validateIdentityProvider(cfg *Config) error {
+	provider := cfg.IdentityProvider()
+	switch provider {
+	case "":
+		return nil
+	case IdentityProviderLDAP:
+		ldapURL := cfg.IdentityLDAPURL()
+		if ldapURL == "" {
+			return fmt.Errorf("%s must be set for the %s identity provider", IdentityLDAPURLKey, provider)
+		}
+		if !strings.HasPrefix(ldapURL, "ldap://") && !strings.HasPrefix(ldapURL, "ldaps://") {
+			return fmt.Errorf("%s must start with ldap:// or ldaps://, got %q", IdentityLDAPURLKey, ldapURL)
+		}
+		if strings.Count(cfg.IdentityLDAPUserDN(), "%s") != 1 {
+			return fmt.Errorf("%s must contain %%s once, got %q", IdentityLDAPUserDNKey, cfg.IdentityLDAPUserDN())
+		}
+		if cfg.IdentityLDAPGroupBase() == "" {
+			return fmt.Errorf("%s must be set for the %s identity provider", IdentityLDAPGroupBaseKey, provider)
+		}
+	case IdentityProviderHtpasswd:
+		if cfg.IdentityHtpasswd() == "" {
+			return fmt.Errorf("%s must be set for the %s identity provider", IdentityHtpasswdKey, provider)
+		}
+	default:
+		return fmt.Errorf("unknown %s %q", IdentityProviderKey, provider)
+	}
+	if domain := cfg.IdentityDomain(); domain == "local" || !validIdentityDomain.MatchString(domain) {
+		return fmt.Errorf("invalid %s %q", IdentityDomainKey, domain)
+	}
+	groupAccess, err := parseIdentityGroupAccess(cfg.asString(IdentityGroupAccessKey))
+	if err != nil {
+		return err
+	}
+	if len(groupAccess) == 0 {
+		return fmt.Errorf("%s must be set for the %s identity provider", IdentityGroupAccessKey, provider)
+	}
+	return nil
+}
+
+var validIdentityDomain = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9.-]*$`)
+
 // RsyslogCACert returns the certificate of the CA that signed the
 // rsyslog certificate, in PEM format, or nil if one hasn't been
 // generated yet.
@@ -891,6 +1140,26 @@ func (c *Config) BootstrapSSHOpts() SSHTimeoutOpts {
 	return opts
 }
 
+// ProvisionerRetryOpts returns how the provisioner retries starting
+// instances that fail to start for transient reasons.
+func (c *Config) ProvisionerRetryOpts() ProvisionerRetryOpts {
+	opts := ProvisionerRetryOpts{
+		Attempts: DefaultProvisionerRetryAttempts,
+		Delay:    time.Duration(DefaultProvisionerRetryDelay) * time.Second,
+		MaxDelay: time.Duration(DefaultProvisionerRetryMaxDelay) * time.Second,
+	}
+	if v, ok := c.defined[ProvisionerRetryAttemptsKey].(int); ok && v != 0 {
+		opts.Attempts = v
+	}
+	if v, ok := c.defined[ProvisionerRetryDelayKey].(int); ok && v != 0 {
+		opts.Delay = time.Duration(v) * time.Second
+	}
+	if v, ok := c.defined[ProvisionerRetryMaxDelayKey].(int); ok && v != 0 {
+		opts.MaxDelay = time.Duration(v) * time.Second
+	}
+	return opts
+}
+
 // CACert returns the certificate of the CA that signed the state server
 // certificate, in PEM format, and whether the setting is available.
 func (c *Config) CACert() (string, bool) {
@@ -1058,6 +1327,29 @@ func (c *Config) LXCUseClone() (bool, bool) {
 	return v, ok
 }
 
+// LXCTemplateMaxAge returns how old the LXC templates on host machines
+// may get before they are rebuilt, or zero if they are never rebuilt.
+func (c *Config) LXCTemplateMaxAge() time.Duration {
+	hours, _ := c.defined[LXCTemplateMaxAgeKey].(int)
+	return time.Duration(hours) * time.Hour
+}
+
+// AllocateContainerAddresses reports whether containers should get
+// static addresses allocated by the provider from the subnet of their
+// host, instead of addresses on the host's private bridge.
+func (c *Config) AllocateContainerAddresses() bool {
+	v, _ := c.defined[AllocateContainerAddressesKey].(bool)
+	return v
+}
+
+// NetworkSafeApply reports whether machine agents should undo changes
+// they make to the network configuration when they cannot reach the
+// API server afterwards.
+func (c *Config) NetworkSafeApply() bool {
+	v, _ := c.defined[NetworkSafeApplyKey].(bool)
+	return v
+}
+
 // LXCUseCloneAUFS reports whether the LXC provisioner should create a
 // lxc clone using aufs if available.
 func (c *Config) LXCUseCloneAUFS() (bool, bool) {
@@ -1161,6 +1453,24 @@ var fields = schema.Fields{
 	PreventDestroyEnvironmentKey: schema.Bool(),
 	PreventRemoveObjectKey:       schema.Bool(),
 	PreventAllChangesKey:         schema.Bool(),
+	PasswordMinLengthKey:         schema.ForceInt(),
+	PasswordMinCharClassesKey:    schema.ForceInt(),
+	ProvisionerRetryAttemptsKey:  schema.ForceInt(),
+	ProvisionerRetryDelayKey:     schema.ForceInt(),
+	ProvisionerRetryMaxDelayKey:  schema.ForceInt(),
+	LXCTemplateMaxAgeKey:         schema.ForceInt(),
+	AllocateContainerAddressesKey: schema.Bool(),
+	NetworkSafeApplyKey:           schema.Bool(),
+	IdentityProviderKey:          schema.String(),
+	IdentityDomainKey:            schema.String(),
+	IdentityGroupAccessKey:       schema.String(),
+	IdentityLDAPURLKey:           schema.String(),
+	IdentityLDAPUserDNKey:        schema.String(),
+	IdentityLDAPGroupBaseKey:     schema.String(),
+	IdentityHtpasswdKey:          schema.String(),
+	IdentityHtpasswdPathKey:      schema.String(),
+	IdentityHtgroupsKey:          schema.String(),
+	IdentityHtgroupsPathKey:      schema.String(),
 
 	// Deprecated fields, retain for backwards compatibility.
 	ToolsMetadataURLKey:    schema.String(),
@@ -1205,6 +1515,24 @@ var alwaysOptional = schema.Defaults{
 	PreventDestroyEnvironmentKey: DefaultPreventDestroyEnvironment,
 	PreventRemoveObjectKey:       DefaultPreventRemoveObject,
 	PreventAllChangesKey:         DefaultPreventAllChanges,
+	PasswordMinLengthKey:         schema.Omit,
+	PasswordMinCharClassesKey:    schema.Omit,
+	ProvisionerRetryAttemptsKey:  schema.Omit,
+	ProvisionerRetryDelayKey:     schema.Omit,
+	ProvisionerRetryMaxDelayKey:  schema.Omit,
+	LXCTemplateMaxAgeKey:         schema.Omit,
+	AllocateContainerAddressesKey: schema.Omit,
+	NetworkSafeApplyKey:           schema.Omit,
+	IdentityProviderKey:          schema.Omit,
+	IdentityDomainKey:            schema.Omit,
+	IdentityGroupAccessKey:       schema.Omit,
+	IdentityLDAPURLKey:           schema.Omit,
+	IdentityLDAPUserDNKey:        schema.Omit,
+	IdentityLDAPGroupBaseKey:     schema.Omit,
+	IdentityHtpasswdKey:          schema.Omit,
+	IdentityHtpasswdPathKey:      schema.Omit,
+	IdentityHtgroupsKey:          schema.Omit,
+	IdentityHtgroupsPathKey:      schema.Omit,
 
 	// Deprecated fields, retain for backwards compatibility.
 	ToolsMetadataURLKey:    "",
@@ -1287,6 +1615,8 @@ var allowedWithDefaultsOnly = []string{
 	"ca-cert-path",
 	"ca-private-key-path",
 	"authorized-keys-path",
+	IdentityHtpasswdPathKey,
+	IdentityHtgroupsPathKey,
 }
 
 // mandatoryWithoutDefaults holds those attributes
@@ -1398,6 +1728,21 @@ type SSHTimeoutOpts struct {
 	AddressesDelay time.Duration
 }
 
+// ProvisionerRetryOpts describes how the provisioner retries starting an
+// instance that failed to start for a transient reason.
+type ProvisionerRetryOpts struct {
+	// Attempts is the maximum number of attempts to start an instance,
+	// the first included.
+	Attempts int
+
+	// Delay is the amount of time to wait before the first retry. It
+	// doubles after each further attempt.
+	Delay time.Duration
+
+	// MaxDelay is the longest amount of time to wait between attempts.
+	MaxDelay time.Duration
+}
+
 func addIfNotEmpty(settings map[string]interface{}, key, value string) {
 	if value != "" {
 		settings[key] = value
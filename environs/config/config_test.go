@@ -5,6 +5,8 @@ package config_test
 
 import (
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
 	"regexp"
 	"strings"
 	stdtesting "testing"
@@ -407,6 +409,156 @@ var configTests = []configTest{
 			"name":               "my-name",
 			"block-all-changest": false,
 		},
+	}, {
+		about:       "Password rules",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                      "my-type",
+			"name":                      "my-name",
+			"password-min-length":       12,
+			"password-min-char-classes": 3,
+		},
+	}, {
+		about:       "Negative password minimum length",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                "my-type",
+			"name":                "my-name",
+			"password-min-length": -1,
+		},
+		err: `password-min-length must not be negative, got -1`,
+	}, {
+		about:       "Too many password character classes",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                      "my-type",
+			"name":                      "my-name",
+			"password-min-char-classes": 5,
+		},
+		err: `password-min-char-classes must be between 0 and 4, got 5`,
+	}, {
+		about:       "Provisioner retry policy",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                        "my-type",
+			"name":                        "my-name",
+			"provisioner-retry-attempts":  3,
+			"provisioner-retry-delay":     30,
+			"provisioner-retry-max-delay": 120,
+		},
+	}, {
+		about:       "Invalid provisioner retry attempts",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                       "my-type",
+			"name":                       "my-name",
+			"provisioner-retry-attempts": -1,
+		},
+		err: `provisioner-retry-attempts must be positive, got -1`,
+	}, {
+		about:       "LXC template max age",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                 "my-type",
+			"name":                 "my-name",
+			"lxc-template-max-age": 168,
+		},
+	}, {
+		about:       "Negative LXC template max age",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                 "my-type",
+			"name":                 "my-name",
+			"lxc-template-max-age": -1,
+		},
+		err: `lxc-template-max-age must not be negative, got -1`,
+	}, {
+		about:       "Allocate container addresses",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                         "my-type",
+			"name":                         "my-name",
+			"allocate-container-addresses": true,
+		},
+	}, {
+		about:       "Network safe apply",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":               "my-type",
+			"name":               "my-name",
+			"network-safe-apply": true,
+		},
+	}, {
+		about:       "Unknown identity provider",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":              "my-type",
+			"name":              "my-name",
+			"identity-provider": "kerberos",
+		},
+		err: `unknown identity-provider "kerberos"`,
+	}, {
+		about:       "LDAP identity provider without URL",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":              "my-type",
+			"name":              "my-name",
+			"identity-provider": "ldap",
+		},
+		err: `identity-ldap-url must be set for the ldap identity provider`,
+	}, {
+		about:       "LDAP identity provider with bad user DN",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                  "my-type",
+			"name":                  "my-name",
+			"identity-provider":     "ldap",
+			"identity-ldap-url":     "ldap://ldap.example.com",
+			"identity-ldap-user-dn": "ou=people,dc=example,dc=com",
+		},
+		err: `identity-ldap-user-dn must contain %s once, got "ou=people,dc=example,dc=com"`,
+	}, {
+		about:       "htpasswd identity provider without htpasswd",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":              "my-type",
+			"name":              "my-name",
+			"identity-provider": "htpasswd",
+		},
+		err: `identity-htpasswd must be set for the htpasswd identity provider`,
+	}, {
+		about:       "Identity provider without group access",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":              "my-type",
+			"name":              "my-name",
+			"identity-provider": "htpasswd",
+			"identity-htpasswd": "bob:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=",
+		},
+		err: `identity-group-access must be set for the htpasswd identity provider`,
+	}, {
+		about:       "Identity provider with bad group access",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                  "my-type",
+			"name":                  "my-name",
+			"identity-provider":     "htpasswd",
+			"identity-htpasswd":     "bob:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=",
+			"identity-group-access": "ops=root",
+		},
+		err: `identity-group-access: unknown access "root" for group "ops"`,
+	}, {
+		about:       "Identity provider with local domain",
+		useDefaults: config.UseDefaults,
+		attrs: testing.Attrs{
+			"type":                  "my-type",
+			"name":                  "my-name",
+			"identity-provider":     "htpasswd",
+			"identity-domain":       "local",
+			"identity-htpasswd":     "bob:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=",
+			"identity-group-access": "ops=admin",
+		},
+		err: `invalid identity-domain "local"`,
 	}, {
 		about:       "Invalid prefer-ipv6 flag",
 		useDefaults: config.UseDefaults,
@@ -1103,6 +1255,45 @@ func (test configTest) check(c *gc.C, home *gitjujutesting.FakeHome) {
 	if statePort, ok := test.attrs["state-port"]; ok {
 		c.Assert(cfg.StatePort(), gc.Equals, statePort)
 	}
+	if minLength, ok := test.attrs["password-min-length"]; ok {
+		c.Assert(cfg.PasswordMinLength(), gc.Equals, minLength)
+	} else {
+		c.Assert(cfg.PasswordMinLength(), gc.Equals, 0)
+	}
+	if minClasses, ok := test.attrs["password-min-char-classes"]; ok {
+		c.Assert(cfg.PasswordMinCharClasses(), gc.Equals, minClasses)
+	} else {
+		c.Assert(cfg.PasswordMinCharClasses(), gc.Equals, 0)
+	}
+	retryOpts := cfg.ProvisionerRetryOpts()
+	if attempts, ok := test.attrs["provisioner-retry-attempts"]; ok {
+		c.Assert(retryOpts.Attempts, gc.Equals, attempts)
+	} else {
+		c.Assert(retryOpts.Attempts, gc.Equals, config.DefaultProvisionerRetryAttempts)
+	}
+	if delay, ok := test.attrs["provisioner-retry-delay"].(int); ok {
+		c.Assert(retryOpts.Delay, gc.Equals, time.Duration(delay)*time.Second)
+	} else {
+		c.Assert(retryOpts.Delay, gc.Equals, time.Duration(config.DefaultProvisionerRetryDelay)*time.Second)
+	}
+	if maxDelay, ok := test.attrs["provisioner-retry-max-delay"].(int); ok {
+		c.Assert(retryOpts.MaxDelay, gc.Equals, time.Duration(maxDelay)*time.Second)
+	}
+	if maxAge, ok := test.attrs["lxc-template-max-age"].(int); ok {
+		c.Assert(cfg.LXCTemplateMaxAge(), gc.Equals, time.Duration(maxAge)*time.Hour)
+	} else {
+		c.Assert(cfg.LXCTemplateMaxAge(), gc.Equals, time.Duration(0))
+	}
+	if allocate, ok := test.attrs["allocate-container-addresses"].(bool); ok {
+		c.Assert(cfg.AllocateContainerAddresses(), gc.Equals, allocate)
+	} else {
+		c.Assert(cfg.AllocateContainerAddresses(), jc.IsFalse)
+	}
+	if safeApply, ok := test.attrs["network-safe-apply"].(bool); ok {
+		c.Assert(cfg.NetworkSafeApply(), gc.Equals, safeApply)
+	} else {
+		c.Assert(cfg.NetworkSafeApply(), jc.IsFalse)
+	}
 	if apiPort, ok := test.attrs["api-port"]; ok {
 		c.Assert(cfg.APIPort(), gc.Equals, apiPort)
 	}
@@ -1579,6 +1770,61 @@ func newTestConfig(c *gc.C, explicit testing.Attrs) *config.Config {
 	return result
 }
 
+func (s *ConfigSuite) TestIdentityProviderLDAP(c *gc.C) {
+	s.addJujuFiles(c)
+	cfg := newTestConfig(c, testing.Attrs{
+		"identity-provider":        "ldap",
+		"identity-ldap-url":        "ldaps://ldap.example.com",
+		"identity-ldap-user-dn":    "uid=%s,ou=people,dc=example,dc=com",
+		"identity-ldap-group-base": "ou=groups,dc=example,dc=com",
+		"identity-group-access":    "ops=admin, dev=write,staff=read",
+	})
+	c.Assert(cfg.IdentityProvider(), gc.Equals, config.IdentityProviderLDAP)
+	c.Assert(cfg.IdentityDomain(), gc.Equals, "ldap")
+	c.Assert(cfg.IdentityLDAPURL(), gc.Equals, "ldaps://ldap.example.com")
+	c.Assert(cfg.IdentityLDAPUserDN(), gc.Equals, "uid=%s,ou=people,dc=example,dc=com")
+	c.Assert(cfg.IdentityLDAPGroupBase(), gc.Equals, "ou=groups,dc=example,dc=com")
+	c.Assert(cfg.IdentityGroupAccess(), jc.DeepEquals, map[string]string{
+		"ops":   "admin",
+		"dev":   "write",
+		"staff": "read",
+	})
+}
+
+func (s *ConfigSuite) TestIdentityProviderHtpasswdPath(c *gc.C) {
+	s.addJujuFiles(c)
+	dir := c.MkDir()
+	htpasswd := "bob:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"
+	htgroups := "ops: bob\n"
+	err := ioutil.WriteFile(filepath.Join(dir, "htpasswd"), []byte(htpasswd), 0600)
+	c.Assert(err, jc.ErrorIsNil)
+	err = ioutil.WriteFile(filepath.Join(dir, "htgroups"), []byte(htgroups), 0600)
+	c.Assert(err, jc.ErrorIsNil)
+
+	cfg := newTestConfig(c, testing.Attrs{
+		"identity-provider":      "htpasswd",
+		"identity-domain":        "example.com",
+		"identity-htpasswd-path": filepath.Join(dir, "htpasswd"),
+		"identity-htgroups-path": filepath.Join(dir, "htgroups"),
+		"identity-group-access":  "ops=admin",
+	})
+	c.Assert(cfg.IdentityProvider(), gc.Equals, config.IdentityProviderHtpasswd)
+	c.Assert(cfg.IdentityDomain(), gc.Equals, "example.com")
+	c.Assert(cfg.IdentityHtpasswd(), gc.Equals, htpasswd)
+	c.Assert(cfg.IdentityHtgroups(), gc.Equals, htgroups)
+	attrs := cfg.AllAttrs()
+	c.Assert(attrs["identity-htpasswd-path"], gc.IsNil)
+	c.Assert(attrs["identity-htgroups-path"], gc.IsNil)
+}
+
+func (s *ConfigSuite) TestNoIdentityProvider(c *gc.C) {
+	s.addJujuFiles(c)
+	cfg := newTestConfig(c, testing.Attrs{})
+	c.Assert(cfg.IdentityProvider(), gc.Equals, "")
+	c.Assert(cfg.IdentityDomain(), gc.Equals, "")
+	c.Assert(cfg.IdentityGroupAccess(), gc.HasLen, 0)
+}
+
 func (s *ConfigSuite) TestLoggingConfig(c *gc.C) {
 	s.addJujuFiles(c)
 	config := newTestConfig(c, testing.Attrs{
@@ -87,6 +87,33 @@ func IsRetryableCreationError(err error) bool {
 	return ok
 }
 
+// CapacityError is returned by an InstanceBroker that cannot start an
+// instance because the provider lacks capacity for it, or because an
+// availability zone is otherwise unusable for the request. Starting the
+// instance may succeed later, or in another availability zone.
+type CapacityError struct {
+	// Zones holds the names of the availability zones that lack
+	// capacity, if known.
+	Zones []string
+
+	message string
+}
+
+// Error implements error.
+func (e *CapacityError) Error() string { return e.message }
+
+// NewCapacityError returns a CapacityError for the given availability
+// zones, which may be empty if they are not known.
+func NewCapacityError(zones []string, errorMessage string) *CapacityError {
+	return &CapacityError{Zones: zones, message: errorMessage}
+}
+
+// IsCapacityError returns true if the given error is a CapacityError.
+func IsCapacityError(err error) bool {
+	_, ok := err.(*CapacityError)
+	return ok
+}
+
 func (hc HardwareCharacteristics) String() string {
 	var strs []string
 	if hc.Arch != nil {
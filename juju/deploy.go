@@ -33,6 +33,9 @@ type DeployServiceParams struct {
 	ToMachineSpec string
 	// Networks holds a list of networks to required to start on boot.
 	Networks []string
+	// EndpointBindings maps endpoint names to the spaces they are
+	// bound to; the empty name holds the space of the other endpoints.
+	EndpointBindings map[string]string
 }
 
 // DeployService takes a charm and various parameters and deploys it.
@@ -74,6 +77,9 @@ func DeployService(st *state.State, args DeployServiceParams) (*state.Service, e
 			return nil, fmt.Errorf("cannot deploy with networks: not suppored by the environment")
 		}
 	}
+	if err := checkEndpointBindings(st, args.Charm, args.EndpointBindings); err != nil {
+		return nil, err
+	}
 	service, err := st.AddService(
 		args.ServiceName,
 		args.ServiceOwner,
@@ -83,8 +89,13 @@ func DeployService(st *state.State, args DeployServiceParams) (*state.Service, e
 	if err != nil {
 		return nil, err
 	}
+	if len(args.EndpointBindings) > 0 {
+		if err := service.SetEndpointBindings(args.EndpointBindings); err != nil {
+			return nil, err
+		}
+	}
 	if len(settings) > 0 {
-		if err := service.UpdateConfigSettings(settings); err != nil {
+		if err := service.UpdateConfigSettingsBy(args.ServiceOwner, settings); err != nil {
 			return nil, err
 		}
 	}
@@ -104,6 +115,28 @@ func DeployService(st *state.State, args DeployServiceParams) (*state.Service, e
 	return service, nil
 }
 
+// checkEndpointBindings returns an error if the bindings name an
+// endpoint the charm does not have, or a space that does not exist, so
+// that a service is not deployed with only some of its bindings.
+func checkEndpointBindings(st *state.State, ch *state.Charm, bindings map[string]string) error {
+	meta := ch.Meta()
+	for endpoint, space := range bindings {
+		if _, err := st.Space(space); err != nil {
+			return errors.Annotatef(err, "cannot bind endpoint %q", endpoint)
+		}
+		if endpoint == "" || endpoint == "juju-info" {
+			continue
+		}
+		_, provides := meta.Provides[endpoint]
+		_, requires := meta.Requires[endpoint]
+		_, peers := meta.Peers[endpoint]
+		if !provides && !requires && !peers {
+			return errors.Errorf("cannot bind endpoint %q: charm %q has no such endpoint", endpoint, ch.URL())
+		}
+	}
+	return nil
+}
+
 // AddUnits starts n units of the given service and allocates machines
 // to them as necessary.
 func AddUnits(st *state.State, svc *state.Service, n int, machineIdSpec string) ([]*state.Unit, error) {
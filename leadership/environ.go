@@ -0,0 +1,64 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package leadership
+
+import (
+	"strings"
+	"time"
+
+	"github.com/juju/juju/lease"
+)
+
+// NewEnvironLeaseManager returns a LeadershipLeaseManager which only
+// deals with the leases of the environment with the given UUID. The
+// lease manager it wraps holds the leases of every environment, with
+// their namespaces prefixed by the UUID of the environment they belong
+// to, so that services with the same name in different environments
+// do not share a lease.
+func NewEnvironLeaseManager(leaseMgr LeadershipLeaseManager, envUUID string) LeadershipLeaseManager {
+	return &environLeaseManager{
+		leaseMgr: leaseMgr,
+		prefix:   envUUID + ":",
+	}
+}
+
+type environLeaseManager struct {
+	leaseMgr LeadershipLeaseManager
+	prefix   string
+}
+
+// ClaimLease implements LeadershipLeaseManager.
+func (m *environLeaseManager) ClaimLease(namespace, id string, forDur time.Duration) (string, error) {
+	return m.leaseMgr.ClaimLease(m.prefix+namespace, id, forDur)
+}
+
+// ClaimLeaseUnlessHeld implements LeadershipLeaseManager.
+func (m *environLeaseManager) ClaimLeaseUnlessHeld(namespace, id string, forDur time.Duration, blocker string) (string, error) {
+	return m.leaseMgr.ClaimLeaseUnlessHeld(m.prefix+namespace, id, forDur, m.prefix+blocker)
+}
+
+// ReleaseLease implements LeadershipLeaseManager.
+func (m *environLeaseManager) ReleaseLease(namespace, id string) error {
+	return m.leaseMgr.ReleaseLease(m.prefix+namespace, id)
+}
+
+// LeaseReleasedNotifier implements LeadershipLeaseManager.
+func (m *environLeaseManager) LeaseReleasedNotifier(namespace string) <-chan struct{} {
+	return m.leaseMgr.LeaseReleasedNotifier(m.prefix + namespace)
+}
+
+// CopyOfLeaseTokens implements LeadershipLeaseManager. Only the
+// tokens of the environment's leases are returned, without the
+// environment prefix.
+func (m *environLeaseManager) CopyOfLeaseTokens() []lease.Token {
+	var tokens []lease.Token
+	for _, tok := range m.leaseMgr.CopyOfLeaseTokens() {
+		if !strings.HasPrefix(tok.Namespace, m.prefix) {
+			continue
+		}
+		tok.Namespace = strings.TrimPrefix(tok.Namespace, m.prefix)
+		tokens = append(tokens, tok)
+	}
+	return tokens
+}
@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/juju/errors"
+
+	"github.com/juju/juju/lease"
 )
 
 // LeadershipClaimDeniedErr is the error which will be returned when a
@@ -32,6 +34,10 @@ type LeadershipLeaseManager interface {
 	// LeaseClaimDeniedErr will be returned. Either way the current lease
 	// owner's ID will be returned.
 	ClaimLease(namespace, id string, forDur time.Duration) (leaseOwnerId string, err error)
+	// ClaimLeaseUnlessHeld claims a lease like ClaimLease, except that
+	// the claim is denied while a lease for the blocker namespace is
+	// held.
+	ClaimLeaseUnlessHeld(namespace, id string, forDur time.Duration, blocker string) (leaseOwnerId string, err error)
 	// ReleaseLease releases the lease held for namespace by id.
 	ReleaseLease(namespace, id string) (err error)
 	// LeaseReleasedNotifier returns a channel a caller can block on to be
@@ -39,4 +45,14 @@ type LeadershipLeaseManager interface {
 	// reusable, but will be closed if it does not respond within
 	// "notificationTimeout".
 	LeaseReleasedNotifier(namespace string) (notifier <-chan struct{})
+	// CopyOfLeaseTokens returns a copy of the lease tokens currently
+	// held.
+	CopyOfLeaseTokens() []lease.Token
+}
+
+// LeadershipLeaseReader provides read access to the authoritative
+// record of held leases.
+type LeadershipLeaseReader interface {
+	// Leases returns the tokens for all leases currently held.
+	Leases() ([]lease.Token, error)
 }
@@ -4,6 +4,7 @@
 package leadership
 
 import (
+	"strings"
 	"time"
 
 	"github.com/juju/errors"
@@ -14,6 +15,11 @@ import (
 const (
 	leadershipDuration        = 30 * time.Second
 	leadershipNamespaceSuffix = "-leadership"
+
+	// revocationDuration is how long a unit whose leadership was
+	// revoked is prevented from claiming it again, so that another
+	// unit may take it over.
+	revocationDuration = 2 * leadershipDuration
 )
 
 // NewLeadershipManager returns a new Manager.
@@ -29,9 +35,13 @@ type Manager struct {
 }
 
 // ClaimLeadership implements the LeadershipManager interface.
+// A unit whose leadership was revoked is denied by the lease store
+// while its revocation lease is held.
 func (m *Manager) ClaimLeadership(sid, uid string) (time.Duration, error) {
 
-	_, err := m.leaseMgr.ClaimLease(leadershipNamespace(sid), uid, leadershipDuration)
+	_, err := m.leaseMgr.ClaimLeaseUnlessHeld(
+		LeadershipNamespace(sid), uid, leadershipDuration, revokedNamespace(sid, uid),
+	)
 	if err != nil {
 		if errors.Cause(err) == lease.LeaseClaimDeniedErr {
 			err = errors.Wrap(err, LeadershipClaimDeniedErr)
@@ -45,16 +55,75 @@ func (m *Manager) ClaimLeadership(sid, uid string) (time.Duration, error) {
 
 // ReleaseLeadership implements the LeadershipManager interface.
 func (m *Manager) ReleaseLeadership(sid, uid string) error {
-	return m.leaseMgr.ReleaseLease(leadershipNamespace(sid), uid)
+	return m.leaseMgr.ReleaseLease(LeadershipNamespace(sid), uid)
 }
 
 // BlockUntilLeadershipReleased implements the LeadershipManager interface.
 func (m *Manager) BlockUntilLeadershipReleased(serviceId string) error {
-	notifier := m.leaseMgr.LeaseReleasedNotifier(leadershipNamespace(serviceId))
+	notifier := m.leaseMgr.LeaseReleasedNotifier(LeadershipNamespace(serviceId))
 	<-notifier
 	return nil
 }
 
-func leadershipNamespace(serviceId string) string {
+// LeadershipNamespace returns the lease namespace which records the
+// leadership of the given service.
+func LeadershipNamespace(serviceId string) string {
 	return serviceId + leadershipNamespaceSuffix
 }
+
+// revokedNamespace returns the lease namespace which, while held,
+// prevents the given unit from claiming the leadership of the given
+// service because its leadership was revoked.
+func revokedNamespace(serviceId, unitId string) string {
+	return LeadershipNamespace(serviceId) + "-revoked:" + unitId
+}
+
+// Leaders returns the id of the unit leading each service which
+// currently has a leader, keyed by service id.
+func Leaders(store LeadershipLeaseReader) (map[string]string, error) {
+	tokens, err := store.Leases()
+	if err != nil {
+		return nil, errors.Annotate(err, "cannot read leadership leases")
+	}
+	leaders := make(map[string]string)
+	for _, tok := range tokens {
+		if !strings.HasSuffix(tok.Namespace, leadershipNamespaceSuffix) {
+			continue
+		}
+		leaders[strings.TrimSuffix(tok.Namespace, leadershipNamespaceSuffix)] = tok.Id
+	}
+	return leaders, nil
+}
+
+// RevokeLeadership releases the leadership of the given service,
+// whichever unit holds it, so that another unit may claim it. The unit
+// which was leader may not claim the leadership again until
+// revocationDuration has passed, leaving time for another unit to take
+// it over, as measured by the given clock. The id of the unit which was
+// leader is returned. If the service has no leader, an error satisfying
+// errors.IsNotFound is returned.
+func RevokeLeadership(store lease.LeaseStore, clock lease.Clock, serviceId string) (string, error) {
+	leaders, err := Leaders(store)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	unitId, ok := leaders[serviceId]
+	if !ok {
+		return "", errors.NotFoundf("leader for service %q", serviceId)
+	}
+	// The unit is blocked before its leadership is released, so that
+	// it cannot claim the leadership again in between.
+	now := clock.Now()
+	block := lease.Token{
+		Namespace:  revokedNamespace(serviceId, unitId),
+		Id:         unitId,
+		Expiration: now.Add(revocationDuration),
+	}
+	if _, err := store.ClaimLease(block, now); err != nil {
+		return "", errors.Annotatef(err, "cannot block %q from claiming leadership of %q", unitId, serviceId)
+	}
+	if err := store.ReleaseLease(LeadershipNamespace(serviceId), unitId); err != nil {
+		return "", errors.Annotatef(err, "cannot revoke leadership of %q from %q", serviceId, unitId)
+	}
+	return unitId, nil
+}
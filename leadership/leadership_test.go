@@ -7,7 +7,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/juju/errors"
 	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/lease"
+	leasetesting "github.com/juju/juju/lease/testing"
 )
 
 func Test(t *testing.T) { gc.TestingT(t) }
@@ -26,8 +30,10 @@ type leadershipSuite struct{}
 
 type leaseStub struct {
 	ClaimLeaseFn            func(string, string, time.Duration) (string, error)
+	ClaimLeaseUnlessHeldFn  func(string, string, time.Duration, string) (string, error)
 	ReleaseLeaseFn          func(string, string) error
 	LeaseReleasedNotifierFn func(string) <-chan struct{}
+	tokens                  []lease.Token
 }
 
 func (s *leaseStub) ClaimLease(namespace, id string, forDur time.Duration) (string, error) {
@@ -37,6 +43,13 @@ func (s *leaseStub) ClaimLease(namespace, id string, forDur time.Duration) (stri
 	return id, nil
 }
 
+func (s *leaseStub) ClaimLeaseUnlessHeld(namespace, id string, forDur time.Duration, blocker string) (string, error) {
+	if s.ClaimLeaseUnlessHeldFn != nil {
+		return s.ClaimLeaseUnlessHeldFn(namespace, id, forDur, blocker)
+	}
+	return s.ClaimLease(namespace, id, forDur)
+}
+
 func (s *leaseStub) ReleaseLease(namespace, id string) error {
 	if s.ReleaseLeaseFn != nil {
 		return s.ReleaseLeaseFn(namespace, id)
@@ -51,10 +64,14 @@ func (s *leaseStub) LeaseReleasedNotifier(namespace string) <-chan struct{} {
 	return nil
 }
 
+func (s *leaseStub) CopyOfLeaseTokens() []lease.Token {
+	return s.tokens
+}
+
 func (s *leadershipSuite) TestClaimLeadershipTranslation(c *gc.C) {
 	stub := &leaseStub{
 		ClaimLeaseFn: func(namespace, id string, forDur time.Duration) (string, error) {
-			c.Check(namespace, gc.Equals, leadershipNamespace(StubServiceNm))
+			c.Check(namespace, gc.Equals, LeadershipNamespace(StubServiceNm))
 			c.Check(id, gc.Equals, StubUnitNm)
 			c.Check(forDur, gc.Equals, leadershipDuration)
 			return id, nil
@@ -68,13 +85,27 @@ func (s *leadershipSuite) TestClaimLeadershipTranslation(c *gc.C) {
 	c.Check(err, gc.IsNil)
 }
 
+func (s *leadershipSuite) TestClaimLeadershipDeniedWhenRevoked(c *gc.C) {
+	stub := &leaseStub{
+		ClaimLeaseUnlessHeldFn: func(namespace, id string, forDur time.Duration, blocker string) (string, error) {
+			c.Check(namespace, gc.Equals, LeadershipNamespace(StubServiceNm))
+			c.Check(blocker, gc.Equals, revokedNamespace(StubServiceNm, StubUnitNm))
+			return "", lease.LeaseClaimDeniedErr
+		},
+	}
+
+	leaderMgr := NewLeadershipManager(stub)
+	_, err := leaderMgr.ClaimLeadership(StubServiceNm, StubUnitNm)
+	c.Check(errors.Cause(err), gc.Equals, LeadershipClaimDeniedErr)
+}
+
 func (s *leadershipSuite) TestReleaseLeadershipTranslation(c *gc.C) {
 
 	numStubCalls := 0
 	stub := &leaseStub{
 		ReleaseLeaseFn: func(namespace, id string) error {
 			numStubCalls++
-			c.Check(namespace, gc.Equals, leadershipNamespace(StubServiceNm))
+			c.Check(namespace, gc.Equals, LeadershipNamespace(StubServiceNm))
 			c.Check(id, gc.Equals, StubUnitNm)
 			return nil
 		},
@@ -93,7 +124,7 @@ func (s *leadershipSuite) TestBlockUntilLeadershipReleasedTranslation(c *gc.C) {
 	stub := &leaseStub{
 		LeaseReleasedNotifierFn: func(namespace string) <-chan struct{} {
 			numStubCalls++
-			c.Check(namespace, gc.Equals, leadershipNamespace(StubServiceNm))
+			c.Check(namespace, gc.Equals, LeadershipNamespace(StubServiceNm))
 			// Send something pre-emptively so test doesn't block.
 			released := make(chan struct{}, 1)
 			released <- struct{}{}
@@ -107,3 +138,105 @@ func (s *leadershipSuite) TestBlockUntilLeadershipReleasedTranslation(c *gc.C) {
 	c.Check(numStubCalls, gc.Equals, 1)
 	c.Check(err, gc.IsNil)
 }
+
+type leaseStoreStub struct {
+	lease.LeaseStore
+	tokens   []lease.Token
+	claimed  []lease.Token
+	released []lease.Token
+}
+
+func (s *leaseStoreStub) ClaimLease(tok lease.Token, expiredBy time.Time) (lease.Token, error) {
+	s.claimed = append(s.claimed, tok)
+	return tok, nil
+}
+
+func (s *leaseStoreStub) Leases() ([]lease.Token, error) {
+	return s.tokens, nil
+}
+
+func (s *leaseStoreStub) ReleaseLease(namespace, id string) error {
+	s.released = append(s.released, lease.Token{Namespace: namespace, Id: id})
+	return nil
+}
+
+func (s *leadershipSuite) TestLeaders(c *gc.C) {
+	store := &leaseStoreStub{tokens: []lease.Token{
+		{Namespace: LeadershipNamespace(StubServiceNm), Id: StubUnitNm},
+		{Namespace: "some-other-lease", Id: "someone"},
+	}}
+
+	leaders, err := Leaders(store)
+	c.Assert(err, gc.IsNil)
+	c.Check(leaders, gc.DeepEquals, map[string]string{StubServiceNm: StubUnitNm})
+}
+
+func (s *leadershipSuite) TestRevokeLeadership(c *gc.C) {
+	store := &leaseStoreStub{tokens: []lease.Token{
+		{Namespace: LeadershipNamespace(StubServiceNm), Id: StubUnitNm},
+	}}
+
+	now := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	unitId, err := RevokeLeadership(store, leasetesting.NewClock(now), StubServiceNm)
+	c.Assert(err, gc.IsNil)
+	c.Check(unitId, gc.Equals, StubUnitNm)
+	// The revoked unit is blocked from claiming the leadership again.
+	c.Assert(store.claimed, gc.HasLen, 1)
+	c.Check(store.claimed[0].Namespace, gc.Equals, revokedNamespace(StubServiceNm, StubUnitNm))
+	c.Check(store.claimed[0].Id, gc.Equals, StubUnitNm)
+	c.Check(store.claimed[0].Expiration, gc.Equals, now.Add(revocationDuration))
+	c.Check(store.released, gc.DeepEquals, []lease.Token{
+		{Namespace: LeadershipNamespace(StubServiceNm), Id: StubUnitNm},
+	})
+}
+
+func (s *leadershipSuite) TestRevokeLeadershipNoLeader(c *gc.C) {
+	store := &leaseStoreStub{}
+
+	_, err := RevokeLeadership(store, lease.SystemClock, StubServiceNm)
+	c.Check(err, gc.ErrorMatches, `leader for service "stub-service" not found`)
+	c.Check(store.released, gc.HasLen, 0)
+}
+
+func (s *leadershipSuite) TestEnvironLeaseManagerScopesNamespaces(c *gc.C) {
+	var claimed, released, notified string
+	stub := &leaseStub{
+		ClaimLeaseFn: func(namespace, id string, forDur time.Duration) (string, error) {
+			claimed = namespace
+			return id, nil
+		},
+		ReleaseLeaseFn: func(namespace, id string) error {
+			released = namespace
+			return nil
+		},
+		LeaseReleasedNotifierFn: func(namespace string) <-chan struct{} {
+			notified = namespace
+			return nil
+		},
+		tokens: []lease.Token{
+			{Namespace: "env-a:svc-leadership", Id: "svc/0"},
+			{Namespace: "env-b:svc-leadership", Id: "svc/1"},
+		},
+	}
+	leaseMgr := NewEnvironLeaseManager(stub, "env-a")
+
+	_, err := leaseMgr.ClaimLease("svc-leadership", "svc/0", time.Minute)
+	c.Assert(err, gc.IsNil)
+	c.Check(claimed, gc.Equals, "env-a:svc-leadership")
+	stub.ClaimLeaseUnlessHeldFn = func(namespace, id string, forDur time.Duration, blocker string) (string, error) {
+		claimed = namespace + " unless " + blocker
+		return id, nil
+	}
+	_, err = leaseMgr.ClaimLeaseUnlessHeld("svc-leadership", "svc/0", time.Minute, "svc-blocked")
+	c.Assert(err, gc.IsNil)
+	c.Check(claimed, gc.Equals, "env-a:svc-leadership unless env-a:svc-blocked")
+	err = leaseMgr.ReleaseLease("svc-leadership", "svc/0")
+	c.Assert(err, gc.IsNil)
+	c.Check(released, gc.Equals, "env-a:svc-leadership")
+	leaseMgr.LeaseReleasedNotifier("svc-leadership")
+	c.Check(notified, gc.Equals, "env-a:svc-leadership")
+
+	c.Check(leaseMgr.CopyOfLeaseTokens(), gc.DeepEquals, []lease.Token{
+		{Namespace: "svc-leadership", Id: "svc/0"},
+	})
+}
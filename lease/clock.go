@@ -0,0 +1,34 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package lease
+
+import (
+	"time"
+)
+
+// Clock provides the lease manager with the current time and with
+// timers. It exists so that lease expiry can be tested without
+// depending on elapsed wall-clock time.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After returns a channel which will receive the current time
+	// once the given duration has elapsed.
+	After(time.Duration) <-chan time.Time
+}
+
+// SystemClock is a Clock backed by the time package.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+// Now implements Clock.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// After implements Clock.
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
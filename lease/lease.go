@@ -4,7 +4,6 @@
 package lease
 
 import (
-	"fmt"
 	"time"
 
 	"github.com/juju/errors"
@@ -17,8 +16,12 @@ const (
 	// will time out after this value.
 	notificationTimeout = 1 * time.Minute
 
-	// This is a useful thing to know in several contexts.
-	maxDuration = time.Duration(1<<63 - 1)
+	// ClockSkewTolerance is the amount of time a lease is held past
+	// its expiration before it may be expired or claimed by another
+	// party. Every state server manages leases with its own clock,
+	// so this protects a holder from a server whose clock runs ahead
+	// of the one which granted the lease.
+	ClockSkewTolerance = 5 * time.Second
 )
 
 var (
@@ -29,25 +32,50 @@ var (
 )
 
 func init() {
-	singleton = &leaseManager{
-		claimLease:       make(chan Token),
-		releaseLease:     make(chan releaseLeaseMsg),
-		leaseReleasedSub: make(chan leaseReleasedMsg),
-		copyOfTokens:     make(chan []Token),
-	}
+	singleton = NewManager(nil, SystemClock)
+}
+
+// LeaseStore is the authority on which leases are held. Every state
+// server runs its own manager, so implementations must apply claims,
+// releases and expiries atomically with respect to each other.
+type LeaseStore interface {
+	// ClaimLease attempts to claim or extend the lease for
+	// tok.Namespace on behalf of tok.Id, until tok.Expiration. A
+	// lease held by another id is only taken over if it expired at
+	// or before expiredBy. The token of the resulting holder is
+	// returned, whether or not the claim succeeded.
+	ClaimLease(tok Token, expiredBy time.Time) (Token, error)
+	// ClaimLeaseUnlessHeld is like ClaimLease, except that the claim
+	// is denied while a lease for the blocker namespace is held.
+	// The blocker lease is checked by the same atomic operation that
+	// records the claim.
+	ClaimLeaseUnlessHeld(tok Token, blocker string, expiredBy time.Time) (Token, error)
+	// ReleaseLease releases the lease for namespace if it is held by
+	// id. If it is not, NotLeaseOwnerErr is returned.
+	ReleaseLease(namespace, id string) error
+	// ExpireLeases removes every lease which expired at or before
+	// expiredBy.
+	ExpireLeases(expiredBy time.Time) error
+	// Leases returns the tokens for all leases currently held.
+	Leases() ([]Token, error)
+	// WatchLeases returns a watcher which notifies whenever any
+	// lease is claimed, released or expired.
+	WatchLeases() Watcher
 }
 
-type leasePersistor interface {
-	WriteToken(string, Token) error
-	RemoveToken(id string) error
-	PersistedTokens() ([]Token, error)
+// Watcher is the subset of a state NotifyWatcher used to observe
+// changes in a LeaseStore.
+type Watcher interface {
+	Changes() <-chan struct{}
+	Stop() error
 }
 
-// WorkerLoop returns a function which can be utilized within a
+// WorkerLoop configures the process-wide manager to use store as its
+// authority, and returns a function which can be utilized within a
 // worker.
-func WorkerLoop(persistor leasePersistor) func(<-chan struct{}) error {
-	singleton.leasePersistor = persistor
-	return singleton.workerLoop
+func WorkerLoop(store LeaseStore) func(<-chan struct{}) error {
+	singleton.store = store
+	return singleton.WorkerLoop
 }
 
 // Token represents a lease claim.
@@ -63,32 +91,65 @@ func Manager() *leaseManager {
 	return singleton
 }
 
+// NewManager returns a manager which uses store as its authority and
+// clock to determine when leases expire. The manager does not serve
+// requests until its WorkerLoop is running.
+func NewManager(store LeaseStore, clock Clock) *leaseManager {
+	return &leaseManager{
+		store:            store,
+		clock:            clock,
+		claimLease:       make(chan claimLeaseMsg),
+		releaseLease:     make(chan releaseLeaseMsg),
+		leaseReleasedSub: make(chan leaseReleasedMsg),
+		copyOfTokens:     make(chan copyOfTokensMsg),
+	}
+}
+
 //
 // Messages for channels.
 //
 
-type releaseLeaseMsg struct {
-	Token *Token
+type claimLeaseMsg struct {
+	Token    Token
+	Blocker  string
+	Response chan<- claimLeaseResult
+}
+type claimLeaseResult struct {
+	Token Token
 	Err   error
 }
+type releaseLeaseMsg struct {
+	Token    Token
+	Response chan<- error
+}
 type leaseReleasedMsg struct {
 	Watcher      chan<- struct{}
 	ForNamespace string
 }
+type copyOfTokensMsg struct {
+	Response chan<- []Token
+}
 
 type leaseManager struct {
-	leasePersistor   leasePersistor
-	claimLease       chan Token
+	store            LeaseStore
+	clock            Clock
+	claimLease       chan claimLeaseMsg
 	releaseLease     chan releaseLeaseMsg
 	leaseReleasedSub chan leaseReleasedMsg
-	copyOfTokens     chan []Token
+	copyOfTokens     chan copyOfTokensMsg
+}
+
+// Clock returns the clock the manager measures lease expiry with.
+func (m *leaseManager) Clock() Clock {
+	return m.clock
 }
 
 // CopyOfLeaseTokens returns a copy of the lease tokens current held
 // by the manager.
 func (m *leaseManager) CopyOfLeaseTokens() []Token {
-	m.copyOfTokens <- nil
-	return <-m.copyOfTokens
+	response := make(chan []Token)
+	m.copyOfTokens <- copyOfTokensMsg{response}
+	return <-response
 }
 
 // Claimlease claims a lease for the given duration for the given
@@ -96,12 +157,27 @@ func (m *leaseManager) CopyOfLeaseTokens() []Token {
 // LeaseClaimDeniedErr will be returned. Either way the current lease
 // owner's ID will be returned.
 func (m *leaseManager) ClaimLease(namespace, id string, forDur time.Duration) (leaseOwnerId string, err error) {
+	return m.claim(namespace, id, forDur, "")
+}
 
-	token := Token{namespace, id, time.Now().Add(forDur)}
-	m.claimLease <- token
-	activeClaim := <-m.claimLease
+// ClaimLeaseUnlessHeld claims a lease like ClaimLease, except that the
+// claim is denied with LeaseClaimDeniedErr while a lease for the
+// blocker namespace is held.
+func (m *leaseManager) ClaimLeaseUnlessHeld(namespace, id string, forDur time.Duration, blocker string) (leaseOwnerId string, err error) {
+	return m.claim(namespace, id, forDur, blocker)
+}
+
+func (m *leaseManager) claim(namespace, id string, forDur time.Duration, blocker string) (leaseOwnerId string, err error) {
+
+	response := make(chan claimLeaseResult)
+	token := Token{namespace, id, m.clock.Now().Add(forDur)}
+	m.claimLease <- claimLeaseMsg{token, blocker, response}
+	result := <-response
+	if result.Err != nil {
+		return "", errors.Annotatef(result.Err, `could not claim lease for namespace "%s", id "%s"`, namespace, id)
+	}
 
-	leaseOwnerId = activeClaim.Id
+	leaseOwnerId = result.Token.Id
 	if id != leaseOwnerId {
 		err = LeaseClaimDeniedErr
 	}
@@ -112,12 +188,11 @@ func (m *leaseManager) ClaimLease(namespace, id string, forDur time.Duration) (l
 // ReleaseLease releases the lease held for namespace by id.
 func (m *leaseManager) ReleaseLease(namespace, id string) (err error) {
 
-	token := Token{Namespace: namespace, Id: id}
-	m.releaseLease <- releaseLeaseMsg{Token: &token}
-	response := <-m.releaseLease
+	response := make(chan error)
+	m.releaseLease <- releaseLeaseMsg{Token{Namespace: namespace, Id: id}, response}
 
-	if err := response.Err; err != nil {
-		err = errors.Annotatef(response.Err, `could not release lease for namespace "%s", id "%s"`, namespace, id)
+	if err := <-response; err != nil {
+		err = errors.Annotatef(err, `could not release lease for namespace "%s", id "%s"`, namespace, id)
 
 		// Log errors so that we're aware they're happening, but don't
 		// burden the caller with dealing with an error if it's
@@ -143,89 +218,129 @@ func (m *leaseManager) LeaseReleasedNotifier(namespace string) (notifier <-chan
 	return watcher
 }
 
-// workerLoop serializes all requests into a single thread.
-func (m *leaseManager) workerLoop(stop <-chan struct{}) error {
+// WorkerLoop serializes all requests into a single thread. Leases
+// may also be claimed, released and expired by managers on other
+// state servers; the store's watcher keeps this manager's view of
+// the leases, and thus its release notifications, up to date.
+func (m *leaseManager) WorkerLoop(stop <-chan struct{}) error {
+
+	if m.store == nil {
+		return errors.New("lease manager has no lease store")
+	}
 
 	// These data-structures are local to ensure they're only utilized
 	// within this thread-safe context.
 
 	releaseSubs := make(map[string][]chan<- struct{}, 0)
+	leaseCache := make(map[string]Token)
+
+	watcher := m.store.WatchLeases()
+	defer watcher.Stop()
 
 	// Pull everything off our data-store & check for expirations.
-	leaseCache, err := populateTokenCache(m.leasePersistor)
+	expiry, err := m.refresh(leaseCache, releaseSubs)
 	if err != nil {
 		return err
 	}
-	nextExpiration := m.expireLeases(leaseCache, releaseSubs)
 
 	for {
 		select {
 		case <-stop:
 			return nil
 		case claim := <-m.claimLease:
-			lease := claimLease(leaseCache, claim)
-			if lease.Id != claim.Id {
-				m.claimLease <- lease
+			var result claimLeaseResult
+			expiredBy := m.clock.Now().Add(-ClockSkewTolerance)
+			if claim.Blocker == "" {
+				result.Token, result.Err = m.store.ClaimLease(claim.Token, expiredBy)
+			} else {
+				result.Token, result.Err = m.store.ClaimLeaseUnlessHeld(claim.Token, claim.Blocker, expiredBy)
 			}
-
-			m.leasePersistor.WriteToken(lease.Namespace, lease)
-			if lease.Expiration.Before(nextExpiration) {
-				nextExpiration = lease.Expiration
+			if result.Err == nil {
+				if result.Token.Id == claim.Token.Id {
+					logger.Infof(`"%s" obtained lease for "%s"`, claim.Token.Id, claim.Token.Namespace)
+				}
+				if expiry, err = m.refresh(leaseCache, releaseSubs); err != nil {
+					claim.Response <- claimLeaseResult{Err: err}
+					return err
+				}
 			}
-			m.claimLease <- lease
-		case claim := <-m.releaseLease:
-			var response releaseLeaseMsg
-			response.Err = releaseLease(leaseCache, claim.Token)
-			if response.Err != nil {
-				m.releaseLease <- response
+			claim.Response <- result
+		case release := <-m.releaseLease:
+			err := m.store.ReleaseLease(release.Token.Namespace, release.Token.Id)
+			if err == nil {
+				logger.Infof(`"%s" released lease for namespace "%s"`, release.Token.Id, release.Token.Namespace)
+				if expiry, err = m.refresh(leaseCache, releaseSubs); err != nil {
+					release.Response <- err
+					return err
+				}
 			}
-
-			// Unwind our layers from most volatile to least.
-			response.Err = m.leasePersistor.RemoveToken(claim.Token.Namespace)
-			m.releaseLease <- response
-			notifyOfRelease(releaseSubs[claim.Token.Namespace], claim.Token.Namespace)
-
+			release.Response <- err
 		case subscription := <-m.leaseReleasedSub:
 			subscribe(releaseSubs, subscription)
-		case <-m.copyOfTokens:
+		case request := <-m.copyOfTokens:
 			// create a copy of the lease cache for use by code
 			// external to our thread-safe context.
-			m.copyOfTokens <- copyTokens(leaseCache)
-		case <-time.After(nextExpiration.Sub(time.Now())):
-			nextExpiration = m.expireLeases(leaseCache, releaseSubs)
-			break
+			request.Response <- copyTokens(leaseCache)
+		case _, ok := <-watcher.Changes():
+			if !ok {
+				return errors.New("lease watcher closed unexpectedly")
+			}
+			if expiry, err = m.refresh(leaseCache, releaseSubs); err != nil {
+				return err
+			}
+		case <-expiry:
+			if err := m.store.ExpireLeases(m.clock.Now().Add(-ClockSkewTolerance)); err != nil {
+				return errors.Annotate(err, "could not expire leases")
+			}
+			if expiry, err = m.refresh(leaseCache, releaseSubs); err != nil {
+				return err
+			}
 		}
 	}
 }
 
-func (m *leaseManager) expireLeases(
+// refresh replaces the contents of cache with the leases currently
+// held in the store, notifying subscribers of any namespace whose
+// lease has been released, expired or taken over by another holder.
+// It returns a channel which will fire when the next lease is due to
+// be expired.
+func (m *leaseManager) refresh(
 	cache map[string]Token,
 	subscribers map[string][]chan<- struct{},
-) time.Time {
+) (<-chan time.Time, error) {
 
-	// Having just looped through all the leases we're holding, we can
-	// inform the caller of when the next expiration will occur.
-	nextExpiration := time.Now().Add(maxDuration)
-
-	for _, token := range cache {
+	tokens, err := m.store.Leases()
+	if err != nil {
+		return nil, errors.Annotate(err, "could not retrieve leases")
+	}
 
-		if token.Expiration.After(time.Now()) {
-			// For the tokens that aren't expiring yet, find the
-			// minimum time we should wait before cleaning up again.
-			if nextExpiration.After(token.Expiration) {
-				nextExpiration = token.Expiration
-				fmt.Printf("Setting next expiration to %s\n", nextExpiration)
-			}
-			continue
+	current := make(map[string]Token)
+	for _, token := range tokens {
+		current[token.Namespace] = token
+	}
+	for namespace, token := range cache {
+		if active, ok := current[namespace]; !ok || active.Id != token.Id {
+			logger.Infof(`Lease for namespace "%s" held by "%s" has ended.`, namespace, token.Id)
+			notifyOfRelease(subscribers[namespace], namespace)
 		}
+		delete(cache, namespace)
+	}
 
-		logger.Infof(`Lease for namespace "%s" has expired.`, token.Namespace)
-		if err := releaseLease(cache, &token); err == nil {
-			notifyOfRelease(subscribers[token.Namespace], token.Namespace)
+	// Having just looped through all the leases we're holding, we can
+	// work out when the next expiration will occur.
+	var nextExpiration time.Time
+	for namespace, token := range current {
+		cache[namespace] = token
+		expiration := token.Expiration.Add(ClockSkewTolerance)
+		if nextExpiration.IsZero() || expiration.Before(nextExpiration) {
+			nextExpiration = expiration
 		}
 	}
-
-	return nextExpiration
+	if nextExpiration.IsZero() {
+		// Nothing to expire; a nil channel blocks forever.
+		return nil, nil
+	}
+	return m.clock.After(nextExpiration.Sub(m.clock.Now())), nil
 }
 
 func copyTokens(cache map[string]Token) (copy []Token) {
@@ -235,24 +350,6 @@ func copyTokens(cache map[string]Token) (copy []Token) {
 	return copy
 }
 
-func claimLease(cache map[string]Token, claim Token) Token {
-	if active, ok := cache[claim.Namespace]; ok && active.Id != claim.Id {
-		return active
-	}
-	cache[claim.Namespace] = claim
-	logger.Infof(`"%s" obtained lease for "%s"`, claim.Id, claim.Namespace)
-	return claim
-}
-
-func releaseLease(cache map[string]Token, claim *Token) error {
-	if active, ok := cache[claim.Namespace]; !ok || active.Id != claim.Id {
-		return NotLeaseOwnerErr
-	}
-	delete(cache, claim.Namespace)
-	logger.Infof(`"%s" released lease for namespace "%s"`, claim.Id, claim.Namespace)
-	return nil
-}
-
 func subscribe(subMap map[string][]chan<- struct{}, subscription leaseReleasedMsg) {
 	subList := subMap[subscription.ForNamespace]
 	subList = append(subList, subscription.Watcher)
@@ -275,18 +372,3 @@ func notifyOfRelease(subscribers []chan<- struct{}, namespace string) {
 		}(subscriber)
 	}
 }
-
-func populateTokenCache(persistor leasePersistor) (map[string]Token, error) {
-
-	tokens, err := persistor.PersistedTokens()
-	if err != nil {
-		return nil, err
-	}
-
-	cache := make(map[string]Token)
-	for _, tok := range tokens {
-		cache[tok.Namespace] = tok
-	}
-
-	return cache, nil
-}
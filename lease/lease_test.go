@@ -1,13 +1,20 @@
 // Copyright 2014 Canonical Ltd.
 // Licensed under the AGPLv3, see LICENCE file for details.
 
-package lease
+package lease_test
 
 import (
-	coretesting "github.com/juju/juju/testing"
-	gc "gopkg.in/check.v1"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/lease"
+	leasetesting "github.com/juju/juju/lease/testing"
+	coretesting "github.com/juju/juju/testing"
 )
 
 func Test(t *testing.T) { gc.TestingT(t) }
@@ -15,64 +22,184 @@ func Test(t *testing.T) { gc.TestingT(t) }
 const (
 	testNamespace = "leadership-stub-service"
 	testId        = "stub-unit/0"
-	testDuration  = 30 * time.Hour
+	otherId       = "stub-unit/1"
+	testDuration  = 30 * time.Second
 )
 
 var (
 	_ = gc.Suite(&leaseSuite{})
 )
 
-type stubLeasePersistor struct {
-	WriteTokenFn      func(string, Token) error
-	RemoveTokenFn     func(string) error
-	PersistedTokensFn func() ([]Token, error)
+// stubLeaseStore is an in-memory lease.LeaseStore. Several managers
+// may share one to simulate multiple state servers.
+type stubLeaseStore struct {
+	mu       sync.Mutex
+	tokens   map[string]lease.Token
+	watchers []chan struct{}
+
+	ClaimLeaseFn func(lease.Token, time.Time) (lease.Token, error)
+}
+
+func newStubLeaseStore(tokens ...lease.Token) *stubLeaseStore {
+	store := &stubLeaseStore{tokens: make(map[string]lease.Token)}
+	for _, tok := range tokens {
+		store.tokens[tok.Namespace] = tok
+	}
+	return store
+}
+
+func (s *stubLeaseStore) ClaimLease(tok lease.Token, expiredBy time.Time) (lease.Token, error) {
+	if s.ClaimLeaseFn != nil {
+		return s.ClaimLeaseFn(tok, expiredBy)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if active, ok := s.tokens[tok.Namespace]; ok && active.Id != tok.Id && active.Expiration.After(expiredBy) {
+		return active, nil
+	}
+	s.tokens[tok.Namespace] = tok
+	s.changed()
+	return tok, nil
+}
+
+func (s *stubLeaseStore) ClaimLeaseUnlessHeld(tok lease.Token, blocker string, expiredBy time.Time) (lease.Token, error) {
+	s.mu.Lock()
+	if _, ok := s.tokens[blocker]; ok {
+		defer s.mu.Unlock()
+		return s.tokens[tok.Namespace], nil
+	}
+	s.mu.Unlock()
+	return s.ClaimLease(tok, expiredBy)
 }
 
-func (p *stubLeasePersistor) WriteToken(id string, tok Token) error {
-	if p.WriteTokenFn != nil {
-		return p.WriteTokenFn(id, tok)
+func (s *stubLeaseStore) ReleaseLease(namespace, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if active, ok := s.tokens[namespace]; !ok || active.Id != id {
+		return lease.NotLeaseOwnerErr
 	}
+	delete(s.tokens, namespace)
+	s.changed()
 	return nil
 }
 
-func (p *stubLeasePersistor) RemoveToken(id string) error {
-	if p.RemoveTokenFn != nil {
-		return p.RemoveTokenFn(id)
+func (s *stubLeaseStore) ExpireLeases(expiredBy time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for namespace, tok := range s.tokens {
+		if !tok.Expiration.After(expiredBy) {
+			delete(s.tokens, namespace)
+			s.changed()
+		}
 	}
 	return nil
 }
 
-func (p *stubLeasePersistor) PersistedTokens() ([]Token, error) {
-	if p.PersistedTokensFn != nil {
-		return p.PersistedTokensFn()
+func (s *stubLeaseStore) Leases() ([]lease.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var tokens []lease.Token
+	for _, tok := range s.tokens {
+		tokens = append(tokens, tok)
 	}
-	return nil, nil
+	return tokens, nil
 }
 
-type leaseSuite struct{}
+func (s *stubLeaseStore) WatchLeases() lease.Watcher {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes := make(chan struct{}, 1)
+	s.watchers = append(s.watchers, changes)
+	return &stubWatcher{changes}
+}
 
-func (s *leaseSuite) TestSingleton(c *gc.C) {
+// changed must be called with s.mu held.
+func (s *stubLeaseStore) changed() {
+	for _, w := range s.watchers {
+		select {
+		case w <- struct{}{}:
+		default:
+		}
+	}
+}
+
+type stubWatcher struct {
+	changes chan struct{}
+}
+
+func (w *stubWatcher) Changes() <-chan struct{} { return w.changes }
+func (w *stubWatcher) Stop() error              { return nil }
+
+// leaseManager is the set of methods exposed by a lease manager.
+type leaseManager interface {
+	ClaimLease(namespace, id string, forDur time.Duration) (string, error)
+	ClaimLeaseUnlessHeld(namespace, id string, forDur time.Duration, blocker string) (string, error)
+	ReleaseLease(namespace, id string) error
+	LeaseReleasedNotifier(namespace string) <-chan struct{}
+	CopyOfLeaseTokens() []lease.Token
+}
+
+type leaseSuite struct {
+	coretesting.BaseSuite
+	clock *leasetesting.Clock
+}
+
+func (s *leaseSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	s.clock = leasetesting.NewClock(time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+// startManager starts a manager backed by store, and arranges for it
+// to be stopped at the end of the test.
+func (s *leaseSuite) startManager(c *gc.C, store lease.LeaseStore) leaseManager {
+	mgr := lease.NewManager(store, s.clock)
 	stop := make(chan struct{})
-	go WorkerLoop(&stubLeasePersistor{})(stop)
-	defer func() { stop <- struct{}{} }()
+	done := make(chan error, 1)
+	go func() { done <- mgr.WorkerLoop(stop) }()
+	s.AddCleanup(func(c *gc.C) {
+		close(stop)
+		c.Check(<-done, jc.ErrorIsNil)
+	})
+	return mgr
+}
 
-	copyA := Manager()
-	copyB := Manager()
+func (s *leaseSuite) assertReleased(c *gc.C, notifier <-chan struct{}) {
+	select {
+	case <-notifier:
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("Failed to unblock after release. Waited for %s", coretesting.LongWait)
+	}
+}
+
+func (s *leaseSuite) assertNotReleased(c *gc.C, notifier <-chan struct{}) {
+	select {
+	case <-notifier:
+		c.Fatalf("Lease was unexpectedly released.")
+	case <-time.After(coretesting.ShortWait):
+	}
+}
+
+func (s *leaseSuite) TestSingleton(c *gc.C) {
+	copyA := lease.Manager()
+	copyB := lease.Manager()
 
 	c.Assert(copyA, gc.NotNil)
 	c.Assert(copyA, gc.Equals, copyB)
 }
 
+func (s *leaseSuite) TestWorkerLoopRequiresStore(c *gc.C) {
+	mgr := lease.NewManager(nil, s.clock)
+	err := mgr.WorkerLoop(make(chan struct{}))
+	c.Assert(err, gc.ErrorMatches, "lease manager has no lease store")
+}
+
 // TestTokenListIsolation ensures that the copy of the lease tokens we
 // get is truly a copy and thus isolated from all other code.
 func (s *leaseSuite) TestTokenListIsolation(c *gc.C) {
-	stop := make(chan struct{})
-	go WorkerLoop(&stubLeasePersistor{})(stop)
-	defer func() { stop <- struct{}{} }()
-
-	mgr := Manager()
+	mgr := s.startManager(c, newStubLeaseStore())
 
-	mgr.ClaimLease(testNamespace, testId, testDuration)
+	_, err := mgr.ClaimLease(testNamespace, testId, testDuration)
+	c.Assert(err, jc.ErrorIsNil)
 	toksA := mgr.CopyOfLeaseTokens()
 	toksB := mgr.CopyOfLeaseTokens()
 
@@ -83,229 +210,195 @@ func (s *leaseSuite) TestTokenListIsolation(c *gc.C) {
 	//...but isolated.
 	toksA[0].Id = "I'm a bad, bad programmer. Why would I do this?"
 	c.Check(toksA[0], gc.Not(gc.Equals), toksB[0])
-
-	//...and the cache remains in tact.
-	err := mgr.ReleaseLease(testNamespace, testId)
-	c.Check(err, gc.IsNil)
 }
 
 func (s *leaseSuite) TestClaimLease(c *gc.C) {
-	stop := make(chan struct{})
-	go WorkerLoop(&stubLeasePersistor{})(stop)
-	defer func() { stop <- struct{}{} }()
+	store := newStubLeaseStore()
+	mgr := s.startManager(c, store)
 
-	mgr := Manager()
 	ownerId, err := mgr.ClaimLease(testNamespace, testId, testDuration)
-
-	c.Assert(err, gc.IsNil)
+	c.Assert(err, jc.ErrorIsNil)
 	c.Assert(ownerId, gc.Equals, testId)
 
-	toks := mgr.CopyOfLeaseTokens()
-	c.Assert(toks, gc.HasLen, 1)
-	c.Assert(toks[0].Namespace, gc.Equals, testNamespace)
-	c.Assert(toks[0].Id, gc.Equals, testId)
+	toks, err := store.Leases()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(toks, gc.DeepEquals, []lease.Token{{
+		testNamespace, testId, s.clock.Now().Add(testDuration),
+	}})
 }
 
-func (s *leaseSuite) TestReleaseLease(c *gc.C) {
-	stop := make(chan struct{})
-	go WorkerLoop(&stubLeasePersistor{})(stop)
-	defer func() { stop <- struct{}{} }()
+func (s *leaseSuite) TestClaimLeaseDenied(c *gc.C) {
+	mgr := s.startManager(c, newStubLeaseStore(
+		lease.Token{testNamespace, otherId, s.clock.Now().Add(testDuration)},
+	))
+
+	ownerId, err := mgr.ClaimLease(testNamespace, testId, testDuration)
+	c.Assert(err, gc.Equals, lease.LeaseClaimDeniedErr)
+	c.Assert(ownerId, gc.Equals, otherId)
+}
+
+func (s *leaseSuite) TestClaimLeaseUnlessHeld(c *gc.C) {
+	const blocker = "blocker"
+	store := newStubLeaseStore(
+		lease.Token{blocker, testId, s.clock.Now().Add(testDuration)},
+	)
+	mgr := s.startManager(c, store)
 
-	mgr := Manager()
+	ownerId, err := mgr.ClaimLeaseUnlessHeld(testNamespace, testId, testDuration, blocker)
+	c.Assert(err, gc.Equals, lease.LeaseClaimDeniedErr)
+	c.Assert(ownerId, gc.Equals, "")
 
-	ownerId, err := mgr.ClaimLease(testNamespace, testId, 30*time.Hour)
-	c.Assert(err, gc.IsNil)
+	err = mgr.ReleaseLease(blocker, testId)
+	c.Assert(err, jc.ErrorIsNil)
+	ownerId, err = mgr.ClaimLeaseUnlessHeld(testNamespace, testId, testDuration, blocker)
+	c.Assert(err, jc.ErrorIsNil)
 	c.Assert(ownerId, gc.Equals, testId)
+}
 
-	err = mgr.ReleaseLease(testNamespace, testId)
-	c.Assert(err, gc.IsNil)
+func (s *leaseSuite) TestClaimLeaseAllowsForClockSkew(c *gc.C) {
+	var expiredBy time.Time
+	store := newStubLeaseStore()
+	store.ClaimLeaseFn = func(tok lease.Token, cutoff time.Time) (lease.Token, error) {
+		expiredBy = cutoff
+		return tok, nil
+	}
+	mgr := s.startManager(c, store)
 
-	toks := mgr.CopyOfLeaseTokens()
-	c.Assert(toks, gc.HasLen, 0)
+	_, err := mgr.ClaimLease(testNamespace, testId, testDuration)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(expiredBy, gc.Equals, s.clock.Now().Add(-lease.ClockSkewTolerance))
 }
 
-func (s *leaseSuite) TestReleaseLeaseNotification(c *gc.C) {
-	stop := make(chan struct{})
-	go WorkerLoop(&stubLeasePersistor{})(stop)
-	defer func() { stop <- struct{}{} }()
+func (s *leaseSuite) TestClaimLeaseError(c *gc.C) {
+	store := newStubLeaseStore()
+	store.ClaimLeaseFn = func(lease.Token, time.Time) (lease.Token, error) {
+		return lease.Token{}, errors.New("boom")
+	}
+	mgr := s.startManager(c, store)
 
-	mgr := Manager()
+	_, err := mgr.ClaimLease(testNamespace, testId, testDuration)
+	c.Assert(err, gc.ErrorMatches, `could not claim lease for namespace "leadership-stub-service", id "stub-unit/0": boom`)
+}
 
-	// Grab a lease.
-	_, err := mgr.ClaimLease(testNamespace, testId, 30*time.Hour)
-	c.Assert(err, gc.IsNil)
+func (s *leaseSuite) TestReleaseLease(c *gc.C) {
+	store := newStubLeaseStore()
+	mgr := s.startManager(c, store)
 
-	// Listen for it to be released.
-	subscription := mgr.LeaseReleasedNotifier(testNamespace)
-	receivedSignal := make(chan struct{})
-	go func() {
-		<-subscription
-		receivedSignal <- struct{}{}
-	}()
+	_, err := mgr.ClaimLease(testNamespace, testId, testDuration)
+	c.Assert(err, jc.ErrorIsNil)
 
-	// Release it
 	err = mgr.ReleaseLease(testNamespace, testId)
-	c.Assert(err, gc.IsNil)
+	c.Assert(err, jc.ErrorIsNil)
 
-	select {
-	case <-receivedSignal:
-	case <-time.After(coretesting.LongWait):
-		c.Errorf("Failed to unblock after release. Waited for %s", coretesting.LongWait)
-	}
+	toks, err := store.Leases()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(toks, gc.HasLen, 0)
 }
 
-func (s *leaseSuite) TestLeaseExpiration(c *gc.C) {
-
-	// WARNING: This code may be load-sensitive. Unfortunately it must
-	// deal with ellapsed time since this is the nature of the code
-	// it is testing. For that reason, we try a few times to see if we
-	// can get a successful run.
-
-	stop := make(chan struct{})
-	go WorkerLoop(&stubLeasePersistor{})(stop)
-	defer func() { stop <- struct{}{} }()
-
-	const (
-		leaseDuration      = 500 * time.Millisecond
-		acceptableOverhead = 50 * time.Millisecond
+func (s *leaseSuite) TestReleaseLeaseNotOwner(c *gc.C) {
+	store := newStubLeaseStore(
+		lease.Token{testNamespace, otherId, s.clock.Now().Add(testDuration)},
 	)
+	mgr := s.startManager(c, store)
 
-	if leaseDuration+acceptableOverhead > coretesting.LongWait {
-		panic("This test will always fail.")
-	}
-
-	// Listen for releases before sending the claim to avoid the
-	// overhead which may affect our timing measurements.
-	mgr := Manager()
-	subscription := mgr.LeaseReleasedNotifier(testNamespace)
-	receivedSignal := make(chan struct{})
-
-	var leaseClaimedTime time.Time
-	go func() {
+	// Releasing someone else's lease is logged, but not an error.
+	err := mgr.ReleaseLease(testNamespace, testId)
+	c.Assert(err, jc.ErrorIsNil)
 
-		<-subscription
-		leaseReleasedTime := time.Now()
+	toks, err := store.Leases()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(toks, gc.HasLen, 1)
+}
 
-		// Ensure we didn't release too early or too late.
-		switch elapsed := leaseReleasedTime.Sub(leaseClaimedTime); {
-		default:
-			receivedSignal <- struct{}{}
-		case elapsed > leaseDuration+acceptableOverhead:
-			fallthrough
-		case elapsed < leaseDuration-acceptableOverhead:
-			c.Errorf(
-				"Expected the lease to be released in %s, but it was released in %s",
-				leaseDuration,
-				elapsed,
-			)
-		}
-	}()
+func (s *leaseSuite) TestReleaseLeaseNotification(c *gc.C) {
+	mgr := s.startManager(c, newStubLeaseStore())
 
 	// Grab a lease.
-	_, err := mgr.ClaimLease(testNamespace, testId, leaseDuration)
-	leaseClaimedTime = time.Now()
-	c.Assert(err, gc.IsNil)
-
-	// Wait for the all-clear, or a time-out.
-	select {
-	case <-receivedSignal:
-	case <-time.After(coretesting.LongWait):
-		c.Errorf("Failed to unblock after release. Waited for %s", coretesting.LongWait)
-	}
-}
-
-func (s *leaseSuite) TestManagerPeresistsOnClaims(c *gc.C) {
+	_, err := mgr.ClaimLease(testNamespace, testId, testDuration)
+	c.Assert(err, jc.ErrorIsNil)
 
-	persistor := &stubLeasePersistor{}
+	// Listen for it to be released.
+	subscription := mgr.LeaseReleasedNotifier(testNamespace)
 
-	stop := make(chan struct{})
-	go WorkerLoop(persistor)(stop)
-	defer func() { stop <- struct{}{} }()
+	// Release it
+	err = mgr.ReleaseLease(testNamespace, testId)
+	c.Assert(err, jc.ErrorIsNil)
 
-	mgr := Manager()
+	s.assertReleased(c, subscription)
+}
 
-	numWriteCalls := 0
-	persistor.WriteTokenFn = func(id string, tok Token) error {
-		numWriteCalls++
+func (s *leaseSuite) TestLeaseExpiration(c *gc.C) {
+	store := newStubLeaseStore()
+	mgr := s.startManager(c, store)
 
-		c.Assert(tok, gc.NotNil)
-		c.Check(tok.Namespace, gc.Equals, testNamespace)
-		c.Check(tok.Id, gc.Equals, testId)
-		c.Check(id, gc.Equals, testNamespace)
+	subscription := mgr.LeaseReleasedNotifier(testNamespace)
+	_, err := mgr.ClaimLease(testNamespace, testId, testDuration)
+	c.Assert(err, jc.ErrorIsNil)
 
-		return nil
-	}
+	// The lease is not expired when its duration elapses...
+	s.clock.Advance(testDuration)
+	s.assertNotReleased(c, subscription)
 
-	mgr.ClaimLease(testNamespace, testId, testDuration)
+	// ...but only once the clock skew tolerance has also passed.
+	s.clock.Advance(lease.ClockSkewTolerance + time.Nanosecond)
+	s.assertReleased(c, subscription)
 
-	c.Check(numWriteCalls, gc.Equals, 1)
+	toks, err := store.Leases()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(toks, gc.HasLen, 0)
 }
 
-func (s *leaseSuite) TestManagerRemovesOnRelease(c *gc.C) {
-
-	persistor := &stubLeasePersistor{}
-
-	stop := make(chan struct{})
-	go WorkerLoop(persistor)(stop)
-	defer func() { stop <- struct{}{} }()
+func (s *leaseSuite) TestExtendedLeaseDoesNotExpire(c *gc.C) {
+	mgr := s.startManager(c, newStubLeaseStore())
 
-	mgr := Manager()
-
-	// Grab a lease.
+	subscription := mgr.LeaseReleasedNotifier(testNamespace)
 	_, err := mgr.ClaimLease(testNamespace, testId, testDuration)
-	c.Assert(err, gc.IsNil)
+	c.Assert(err, jc.ErrorIsNil)
 
-	numRemoveCalls := 0
-	persistor.RemoveTokenFn = func(id string) error {
-		numRemoveCalls++
-		c.Check(id, gc.Equals, testNamespace)
-		return nil
-	}
+	s.clock.Advance(testDuration / 2)
+	_, err = mgr.ClaimLease(testNamespace, testId, testDuration)
+	c.Assert(err, jc.ErrorIsNil)
 
-	// Release the lease, and the peresitor should be called.
-	mgr.ReleaseLease(testNamespace, testId)
+	s.clock.Advance(testDuration/2 + lease.ClockSkewTolerance + time.Nanosecond)
+	s.assertNotReleased(c, subscription)
 
-	c.Check(numRemoveCalls, gc.Equals, 1)
+	s.clock.Advance(testDuration / 2)
+	s.assertReleased(c, subscription)
 }
 
-func (s *leaseSuite) TestManagerDepersistsAllTokensOnStart(c *gc.C) {
+// TestSharedStore checks that managers on different state servers
+// cannot both grant the same lease, and that releases made through
+// one are observed by the other.
+func (s *leaseSuite) TestSharedStore(c *gc.C) {
+	store := newStubLeaseStore()
+	mgrA := s.startManager(c, store)
+	mgrB := s.startManager(c, store)
 
-	persistor := &stubLeasePersistor{}
+	ownerId, err := mgrA.ClaimLease(testNamespace, testId, testDuration)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ownerId, gc.Equals, testId)
 
-	numCalls := 0
-	testToks := []Token{
-		Token{testNamespace, testId, time.Now().Add(testDuration)},
-		Token{testNamespace + "2", "a" + testId, time.Now().Add(testDuration)},
-	}
-	persistor.PersistedTokensFn = func() ([]Token, error) {
+	ownerId, err = mgrB.ClaimLease(testNamespace, otherId, testDuration)
+	c.Assert(err, gc.Equals, lease.LeaseClaimDeniedErr)
+	c.Assert(ownerId, gc.Equals, testId)
 
-		numCalls++
-		return testToks, nil
-	}
+	subscription := mgrB.LeaseReleasedNotifier(testNamespace)
+	err = mgrA.ReleaseLease(testNamespace, testId)
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertReleased(c, subscription)
 
-	stop := make(chan struct{})
-	go WorkerLoop(persistor)(stop)
-	defer func() { stop <- struct{}{} }()
+	ownerId, err = mgrB.ClaimLease(testNamespace, otherId, testDuration)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ownerId, gc.Equals, otherId)
+}
 
-	mgr := Manager()
+func (s *leaseSuite) TestManagerLoadsLeasesOnStart(c *gc.C) {
+	testToks := []lease.Token{
+		{testNamespace, testId, s.clock.Now().Add(testDuration)},
+	}
+	mgr := s.startManager(c, newStubLeaseStore(testToks...))
 
 	// NOTE: This call will naturally block until the worker loop is
 	// sucessfully pumping. Place all checks below here.
-	heldToks := mgr.CopyOfLeaseTokens()
-
-	c.Assert(numCalls, gc.Equals, 1)
-
-	for _, heldTok := range heldToks {
-		found := false
-		for _, testTok := range testToks {
-			found, _ = gc.DeepEquals.Check([]interface{}{testTok, heldTok}, []string{})
-			if found {
-				break
-			}
-		}
-		if !found {
-			c.Log("The manager is not managing the expected token list.\nNOTE: Test is coded so that order does not matter.")
-			c.Assert(heldToks, gc.DeepEquals, testToks)
-		}
-	}
+	c.Assert(mgr.CopyOfLeaseTokens(), gc.DeepEquals, testToks)
 }
@@ -0,0 +1,77 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package testing
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/juju/lease"
+)
+
+var _ lease.Clock = (*Clock)(nil)
+
+// Clock is a deterministic lease.Clock. Time only moves forward when
+// Advance is called, at which point any timers that have come due
+// are fired.
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiting []timer
+}
+
+type timer struct {
+	deadline time.Time
+	fire     chan time.Time
+}
+
+// NewClock returns a Clock whose current time is now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now implements lease.Clock.
+func (clock *Clock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.now
+}
+
+// After implements lease.Clock.
+func (clock *Clock) After(d time.Duration) <-chan time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	fire := make(chan time.Time, 1)
+	deadline := clock.now.Add(d)
+	if !deadline.After(clock.now) {
+		fire <- clock.now
+		return fire
+	}
+	clock.waiting = append(clock.waiting, timer{deadline, fire})
+	return fire
+}
+
+// Advance moves the clock forward by d, firing any timers whose
+// deadlines have been reached.
+func (clock *Clock) Advance(d time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.now = clock.now.Add(d)
+	var stillWaiting []timer
+	for _, t := range clock.waiting {
+		if t.deadline.After(clock.now) {
+			stillWaiting = append(stillWaiting, t)
+			continue
+		}
+		t.fire <- clock.now
+	}
+	clock.waiting = stillWaiting
+}
+
+// Waiting returns the number of timers which have not yet fired.
+func (clock *Clock) Waiting() int {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return len(clock.waiting)
+}
@@ -78,6 +78,20 @@ type Info struct {
 	// Disabled is true when the interface needs to be disabled on the
 	// machine, e.g. not to configure it.
 	Disabled bool
+
+	// Address is the static address the interface is configured
+	// with. It is empty if the interface is configured dynamically.
+	Address Address
+
+	// GatewayAddress is the address of the default gateway of the
+	// interface. It is empty if the interface is configured
+	// dynamically.
+	GatewayAddress Address
+
+	// DNSServers holds the addresses of the name servers used over
+	// the interface. It is empty if the interface is configured
+	// dynamically.
+	DNSServers []Address
 }
 
 // ActualInterfaceName returns raw interface name for raw interface (e.g. "eth0") and
@@ -0,0 +1,16 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package network
+
+import (
+	"regexp"
+)
+
+var validSpace = regexp.MustCompile("^[a-z0-9]+(-[a-z0-9]+)*$")
+
+// IsValidSpace reports whether name is a valid space name: lower case
+// letters and digits, with single hyphens between them.
+func IsValidSpace(name string) bool {
+	return validSpace.MatchString(name)
+}
@@ -239,6 +239,7 @@ type environState struct {
 	mu           sync.Mutex
 	maxId        int // maximum instance id allocated so far.
 	maxAddr      int // maximum allocated address last byte
+	addresses    map[string]instance.Id
 	insts        map[instance.Id]*dummyInstance
 	globalPorts  map[network.PortRange]bool
 	bootstrapped bool
@@ -354,6 +355,7 @@ func newState(name string, ops chan<- Operation, policy state.Policy) *environSt
 		name:        name,
 		ops:         ops,
 		statePolicy: policy,
+		addresses:   make(map[string]instance.Id),
 		insts:       make(map[instance.Id]*dummyInstance),
 		globalPorts: make(map[network.PortRange]bool),
 	}
@@ -1014,6 +1016,10 @@ func (env *environ) AllocateAddress(instId instance.Id, netId network.Id, addr n
 	}
 	estate.mu.Lock()
 	defer estate.mu.Unlock()
+	if _, ok := estate.addresses[addr.Value]; ok {
+		return errors.Errorf("address %q already allocated", addr.Value)
+	}
+	estate.addresses[addr.Value] = instId
 	estate.maxAddr++
 	estate.ops <- OpAllocateAddress{
 		Env:        env.name,
@@ -1037,6 +1043,7 @@ func (env *environ) ReleaseAddress(instId instance.Id, netId network.Id, addr ne
 	}
 	estate.mu.Lock()
 	defer estate.mu.Unlock()
+	delete(estate.addresses, addr.Value)
 	estate.maxAddr++
 	estate.ops <- OpReleaseAddress{
 		Env:        env.name,
@@ -1060,10 +1067,15 @@ func (env *environ) Subnets(_ instance.Id) ([]network.SubnetInfo, error) {
 	estate.mu.Lock()
 	defer estate.mu.Unlock()
 
-	netInfo := []network.SubnetInfo{
-		{CIDR: "0.10.0.0/8", ProviderId: "dummy-private"},
-		{CIDR: "0.20.0.0/24", ProviderId: "dummy-public"},
-	}
+	netInfo := []network.SubnetInfo{{
+		CIDR:              "0.10.0.0/8",
+		ProviderId:        "dummy-private",
+		AllocatableIPLow:  net.ParseIP("0.10.0.2"),
+		AllocatableIPHigh: net.ParseIP("0.10.0.254"),
+	}, {
+		CIDR:       "0.20.0.0/24",
+		ProviderId: "dummy-public",
+	}}
 	estate.ops <- OpListNetworks{
 		Env:  env.name,
 		Info: netInfo,
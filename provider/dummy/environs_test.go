@@ -4,6 +4,7 @@
 package dummy_test
 
 import (
+	"net"
 	stdtesting "testing"
 	"time"
 
@@ -155,6 +156,30 @@ func (s *suite) TestAllocateAddress(c *gc.C) {
 	assertAllocateAddress(c, e, opc, inst.Id(), netId, newAddress)
 }
 
+func (s *suite) TestAllocateAddressAlreadyAllocated(c *gc.C) {
+	e := s.bootstrapTestEnviron(c, false)
+	defer func() {
+		err := e.Destroy()
+		c.Assert(err, jc.ErrorIsNil)
+	}()
+
+	inst, _ := jujutesting.AssertStartInstance(c, e, "0")
+	c.Assert(inst, gc.NotNil)
+	netId := network.Id("net1")
+
+	address := network.NewAddress("0.1.2.1", network.ScopeCloudLocal)
+	err := e.AllocateAddress(inst.Id(), netId, address)
+	c.Assert(err, jc.ErrorIsNil)
+	err = e.AllocateAddress(inst.Id(), netId, address)
+	c.Assert(err, gc.ErrorMatches, `address "0.1.2.1" already allocated`)
+
+	// Once released, the address can be allocated again.
+	err = e.ReleaseAddress(inst.Id(), netId, address)
+	c.Assert(err, jc.ErrorIsNil)
+	err = e.AllocateAddress(inst.Id(), netId, address)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
 func (s *suite) TestReleaseAddress(c *gc.C) {
 	e := s.bootstrapTestEnviron(c, false)
 	defer func() {
@@ -191,10 +216,15 @@ func (s *suite) TestSubnets(c *gc.C) {
 	opc := make(chan dummy.Operation, 200)
 	dummy.Listen(opc)
 
-	expectInfo := []network.SubnetInfo{
-		{CIDR: "0.10.0.0/8", ProviderId: "dummy-private"},
-		{CIDR: "0.20.0.0/24", ProviderId: "dummy-public"},
-	}
+	expectInfo := []network.SubnetInfo{{
+		CIDR:              "0.10.0.0/8",
+		ProviderId:        "dummy-private",
+		AllocatableIPLow:  net.ParseIP("0.10.0.2"),
+		AllocatableIPHigh: net.ParseIP("0.10.0.254"),
+	}, {
+		CIDR:       "0.20.0.0/24",
+		ProviderId: "dummy-public",
+	}}
 	netInfo, err := e.Subnets("")
 	c.Assert(err, jc.ErrorIsNil)
 	c.Assert(netInfo, jc.DeepEquals, expectInfo)
@@ -494,6 +494,7 @@ func (e *environ) StartInstance(args environs.StartInstanceParams) (*environs.St
 	}
 	rootDiskSize := uint64(blockDeviceMappings[0].VolumeSize) * 1024
 
+	var constrainedZones []string
 	for _, availZone := range availabilityZones {
 		instResp, err = runInstances(e.ec2(), &ec2.RunInstances{
 			AvailZone:           availZone,
@@ -507,10 +508,19 @@ func (e *environ) StartInstance(args environs.StartInstanceParams) (*environs.St
 		})
 		if isZoneConstrainedError(err) {
 			logger.Infof("%q is constrained, trying another availability zone", availZone)
+			constrainedZones = append(constrainedZones, availZone)
 		} else {
 			break
 		}
 	}
+	switch {
+	case isZoneConstrainedError(err):
+		// Every zone tried is constrained; report them all, so the
+		// provisioner tries another zone when it retries.
+		err = instance.NewCapacityError(constrainedZones, err.Error())
+	case isTransientRunError(err):
+		err = instance.NewRetryableCreationError(err.Error())
+	}
 	if err != nil {
 		return nil, errors.Annotate(err, "cannot run instances")
 	}
@@ -1222,6 +1232,17 @@ func isZoneConstrainedError(err error) bool {
 	return false
 }
 
+// isTransientRunError reports whether or not the error indicates
+// RunInstances failed for a reason that is likely to go away by itself,
+// such as throttling of requests or an outage of the EC2 service.
+func isTransientRunError(err error) bool {
+	switch ec2ErrCode(err) {
+	case "RequestLimitExceeded", "InternalError", "Unavailable", "ServiceUnavailable":
+		return true
+	}
+	return false
+}
+
 // If the err is of type *ec2.Error, ec2ErrCode returns
 // its code, otherwise it returns the empty string.
 func ec2ErrCode(err error) string {
@@ -520,6 +520,24 @@ func (t *localServerSuite) testStartInstanceAvailZoneAllConstrained(c *gc.C, run
 		runInstancesError.Code,
 	))
 	c.Assert(azArgs, gc.DeepEquals, []string{"az1", "az2"})
+
+	// The provisioner is told every zone that lacked capacity.
+	capacityErr, ok := errors.Cause(err).(*instance.CapacityError)
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(capacityErr.Zones, jc.DeepEquals, []string{"az1", "az2"})
+}
+
+func (t *localServerSuite) TestStartInstanceTransientError(c *gc.C) {
+	env := t.Prepare(c)
+	err := bootstrap.Bootstrap(envtesting.BootstrapContext(c), env, bootstrap.BootstrapParams{})
+	c.Assert(err, jc.ErrorIsNil)
+
+	t.PatchValue(ec2.RunInstances, func(e *amzec2.EC2, ri *amzec2.RunInstances) (*amzec2.RunInstancesResp, error) {
+		return nil, &amzec2.Error{Code: "RequestLimitExceeded", Message: "Request limit exceeded."}
+	})
+	_, _, _, err = testing.StartInstance(env, "1")
+	c.Assert(err, gc.ErrorMatches, `cannot run instances: Request limit exceeded. \(RequestLimitExceeded\)`)
+	c.Assert(errors.Cause(err), jc.Satisfies, instance.IsRetryableCreationError)
 }
 
 func (t *localServerSuite) TestStartInstanceAvailZoneOneConstrained(c *gc.C) {
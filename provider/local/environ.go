@@ -347,8 +347,6 @@ func (env *localEnviron) setLocalStorage() error {
 }
 
 var unsupportedConstraints = []string{
-	constraints.CpuCores,
-	constraints.CpuPower,
 	constraints.InstanceType,
 	constraints.Tags,
 }
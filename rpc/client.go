@@ -13,6 +13,9 @@ var ErrShutdown = errors.New("connection is shut down")
 // Call represents an active RPC.
 type Call struct {
 	Request
+	// TraceId is sent with the request so that it may be identified
+	// in the server's logs.
+	TraceId  string
 	Params   interface{}
 	Response interface{}
 	Error    error
@@ -23,6 +26,7 @@ type Call struct {
 type RequestError struct {
 	Message string
 	Code    string
+	Info    map[string]interface{}
 }
 
 func (e *RequestError) Error() string {
@@ -37,6 +41,10 @@ func (e *RequestError) ErrorCode() string {
 	return e.Code
 }
 
+func (e *RequestError) ErrorInfo() map[string]interface{} {
+	return e.Info
+}
+
 func (conn *Conn) send(call *Call) {
 	conn.sending.Lock()
 	defer conn.sending.Unlock()
@@ -61,6 +69,7 @@ func (conn *Conn) send(call *Call) {
 	hdr := &Header{
 		RequestId: reqId,
 		Request:   call.Request,
+		TraceId:   call.TraceId,
 	}
 	params := call.Params
 	if params == nil {
@@ -111,6 +120,7 @@ func (conn *Conn) handleResponse(hdr *Header) error {
 		call.Error = &RequestError{
 			Message: hdr.Error,
 			Code:    hdr.ErrorCode,
+			Info:    hdr.ErrorInfo,
 		}
 		err = conn.readBody(nil, false)
 		if conn.notifier != nil {
@@ -167,6 +177,7 @@ func (conn *Conn) Go(req Request, args, response interface{}, done chan *Call) *
 	}
 	call := &Call{
 		Request:  req,
+		TraceId:  NewTraceId(),
 		Params:   args,
 		Response: response,
 		Done:     done,
@@ -54,6 +54,15 @@ var readTests = []struct {
 		ErrorCode: "a code",
 	},
 	expectBody: new(map[string]interface{}),
+}, {
+	msg: `{"RequestId": 2, "Error": "an error", "ErrorCode": "a code", "ErrorInfo": {"retry-after": 1.5}}`,
+	expectHdr: rpc.Header{
+		RequestId: 2,
+		Error:     "an error",
+		ErrorCode: "a code",
+		ErrorInfo: map[string]interface{}{"retry-after": 1.5},
+	},
+	expectBody: new(map[string]interface{}),
 }, {
 	msg: `{"RequestId": 3, "Response": {"X": "result"}}`,
 	expectHdr: rpc.Header{
@@ -72,6 +81,17 @@ var readTests = []struct {
 		},
 	},
 	expectBody: &value{X: "param"},
+}, {
+	msg: `{"RequestId": 5, "Type": "foo", "Request": "frob", "TraceId": "0123456789abcdef", "Params": {"X": "param"}}`,
+	expectHdr: rpc.Header{
+		RequestId: 5,
+		Request: rpc.Request{
+			Type:   "foo",
+			Action: "frob",
+		},
+		TraceId: "0123456789abcdef",
+	},
+	expectBody: &value{X: "param"},
 }}
 
 func (*suite) TestRead(c *gc.C) {
@@ -237,6 +257,14 @@ var writeTests = []struct {
 		ErrorCode: "a code",
 	},
 	expect: `{"RequestId": 2, "Error": "an error", "ErrorCode": "a code"}`,
+}, {
+	hdr: &rpc.Header{
+		RequestId: 2,
+		Error:     "an error",
+		ErrorCode: "a code",
+		ErrorInfo: map[string]interface{}{"retry-after": 1.5},
+	},
+	expect: `{"RequestId": 2, "Error": "an error", "ErrorCode": "a code", "ErrorInfo": {"retry-after": 1.5}}`,
 }, {
 	hdr: &rpc.Header{
 		RequestId: 3,
@@ -255,6 +283,13 @@ var writeTests = []struct {
 	},
 	body:   &value{X: "param"},
 	expect: `{"RequestId": 4, "Type": "foo", "Version": 2, "Request": "frob", "Params": {"X": "param"}}`,
+}, {
+	hdr: &rpc.Header{
+		RequestId: 5,
+		TraceId:   "0123456789abcdef",
+	},
+	body:   &value{X: "result"},
+	expect: `{"RequestId": 5, "TraceId": "0123456789abcdef", "Response": {"X": "result"}}`,
 }}
 
 func (*suite) TestWrite(c *gc.C) {
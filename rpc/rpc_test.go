@@ -488,9 +488,9 @@ func (root *Root) testCall(c *gc.C, p testCallParams) {
 
 	root.assertCallMade(c, p)
 
-	requestId := root.assertClientNotified(c, p, &r)
+	requestId, traceId := root.assertClientNotified(c, p, &r)
 
-	root.assertServerNotified(c, p, requestId)
+	root.assertServerNotified(c, p, requestId, traceId)
 }
 
 func (root *Root) assertCallMade(c *gc.C, p testCallParams) {
@@ -508,8 +508,8 @@ func (root *Root) assertCallMade(c *gc.C, p testCallParams) {
 // assertClientNotified asserts that the right client notifications
 // were made for the given test call parameters. The value of r
 // holds the result parameter passed to the call.
-// It returns the request id.
-func (root *Root) assertClientNotified(c *gc.C, p testCallParams, r interface{}) uint64 {
+// It returns the request id and trace id.
+func (root *Root) assertClientNotified(c *gc.C, p testCallParams, r interface{}) (uint64, string) {
 	c.Assert(p.clientNotifier.serverRequests, gc.HasLen, 0)
 	c.Assert(p.clientNotifier.serverReplies, gc.HasLen, 0)
 
@@ -518,8 +518,11 @@ func (root *Root) assertClientNotified(c *gc.C, p testCallParams, r interface{})
 	clientReq := p.clientNotifier.clientRequests[0]
 	requestId := clientReq.hdr.RequestId
 	clientReq.hdr.RequestId = 0 // Ignore the exact value of the request id to start with.
+	traceId := clientReq.hdr.TraceId
+	c.Assert(traceId, gc.Not(gc.Equals), "")
 	c.Assert(clientReq.hdr, gc.DeepEquals, rpc.Header{
 		Request: p.request(),
+		TraceId: traceId,
 	})
 	c.Assert(clientReq.body, gc.Equals, stringVal{"arg"})
 
@@ -536,19 +539,21 @@ func (root *Root) assertClientNotified(c *gc.C, p testCallParams, r interface{})
 		c.Assert(clientReply.hdr, gc.DeepEquals, rpc.Header{
 			RequestId: requestId,
 			Error:     p.errorMessage(),
+			TraceId:   traceId,
 		})
 	} else {
 		c.Assert(clientReply.hdr, gc.DeepEquals, rpc.Header{
 			RequestId: requestId,
+			TraceId:   traceId,
 		})
 	}
-	return requestId
+	return requestId, traceId
 }
 
 // assertServerNotified asserts that the right server notifications
 // were made for the given test call parameters. The id of the request
-// is held in requestId.
-func (root *Root) assertServerNotified(c *gc.C, p testCallParams, requestId uint64) {
+// is held in requestId and its trace id in traceId.
+func (root *Root) assertServerNotified(c *gc.C, p testCallParams, requestId uint64, traceId string) {
 	// Check that the right server notifications were made.
 	c.Assert(p.serverNotifier.clientRequests, gc.HasLen, 0)
 	c.Assert(p.serverNotifier.clientReplies, gc.HasLen, 0)
@@ -559,6 +564,7 @@ func (root *Root) assertServerNotified(c *gc.C, p testCallParams, requestId uint
 	c.Assert(serverReq.hdr, gc.DeepEquals, rpc.Header{
 		RequestId: requestId,
 		Request:   p.request(),
+		TraceId:   traceId,
 	})
 	if p.narg > 0 {
 		c.Assert(serverReq.body, gc.Equals, stringVal{"arg"})
@@ -576,13 +582,15 @@ func (root *Root) assertServerNotified(c *gc.C, p testCallParams, requestId uint
 		c.Assert(serverReply.body, gc.Equals, stringVal{p.request().Action + " ret"})
 	}
 	if p.retErr && p.testErr {
-		c.Assert(serverReply.hdr, gc.Equals, rpc.Header{
+		c.Assert(serverReply.hdr, gc.DeepEquals, rpc.Header{
 			RequestId: requestId,
 			Error:     p.errorMessage(),
+			TraceId:   traceId,
 		})
 	} else {
-		c.Assert(serverReply.hdr, gc.Equals, rpc.Header{
+		c.Assert(serverReply.hdr, gc.DeepEquals, rpc.Header{
 			RequestId: requestId,
+			TraceId:   traceId,
 		})
 	}
 }
@@ -773,6 +781,32 @@ func (*rpcSuite) TestErrorCode(c *gc.C) {
 	c.Assert(err.(rpc.ErrorCoder).ErrorCode(), gc.Equals, "code")
 }
 
+type infoError struct {
+	codedError
+	info map[string]interface{}
+}
+
+func (e *infoError) ErrorInfo() map[string]interface{} {
+	return e.info
+}
+
+func (*rpcSuite) TestErrorInfo(c *gc.C) {
+	root := &Root{
+		errorInst: &ErrorMethods{&infoError{
+			codedError: codedError{"message", "code"},
+			info:       map[string]interface{}{"retry-after": 2.5},
+		}},
+	}
+	client, srvDone, _, _ := newRPCClientServer(c, root, nil, false)
+	defer closeClient(c, client, srvDone)
+	err := client.Call(rpc.Request{"ErrorMethods", 0, "", "Call"}, nil, nil)
+	c.Assert(err, gc.DeepEquals, &rpc.RequestError{
+		Message: "message",
+		Code:    "code",
+		Info:    map[string]interface{}{"retry-after": 2.5},
+	})
+}
+
 func (*rpcSuite) TestTransformErrors(c *gc.C) {
 	root := &Root{
 		errorInst: &ErrorMethods{&codedError{"message", "code"}},
@@ -957,10 +991,12 @@ func testBadCall(
 	c.Assert(clientNotifier.clientRequests, gc.HasLen, 1)
 	clientReq := clientNotifier.clientRequests[0]
 	requestId := clientReq.hdr.RequestId
+	traceId := clientReq.hdr.TraceId
 	c.Assert(clientReq, gc.DeepEquals, requestEvent{
 		hdr: rpc.Header{
 			RequestId: requestId,
 			Request:   req,
+			TraceId:   traceId,
 		},
 		body: struct{}{},
 	})
@@ -973,6 +1009,7 @@ func testBadCall(
 			RequestId: requestId,
 			Error:     expectedErr,
 			ErrorCode: expectedErrCode,
+			TraceId:   traceId,
 		},
 	})
 
@@ -991,6 +1028,7 @@ func testBadCall(
 		hdr: rpc.Header{
 			RequestId: requestId,
 			Request:   req,
+			TraceId:   traceId,
 		},
 		body: expectBody,
 	})
@@ -1003,6 +1041,7 @@ func testBadCall(
 			RequestId: requestId,
 			Error:     expectedErr,
 			ErrorCode: expectedErrCode,
+			TraceId:   traceId,
 		},
 		req:  req,
 		body: struct{}{},
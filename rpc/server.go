@@ -62,6 +62,16 @@ type Header struct {
 
 	// ErrorCode holds the code of the error, if any.
 	ErrorCode string
+
+	// ErrorInfo holds additional information provided by the error,
+	// if any.
+	ErrorInfo map[string]interface{}
+
+	// TraceId identifies the request across both ends of the
+	// connection so that it can be followed through the logs of
+	// the client and the server. Replies carry the trace id of the
+	// request being replied to.
+	TraceId string
 }
 
 // Request represents an RPC to be performed, absent its parameters.
@@ -344,12 +354,31 @@ type ErrorCoder interface {
 	ErrorCode() string
 }
 
+// ErrorInfoProvider represents an error that carries additional
+// information which should be sent to the client along with the error
+// message and code, for example how long the client should wait
+// before retrying the request.
+type ErrorInfoProvider interface {
+	ErrorInfo() map[string]interface{}
+}
+
 // MethodFinder represents a type that can be used to lookup a Method and place
 // calls on that method.
 type MethodFinder interface {
 	FindMethod(rootName string, version int, methodName string) (rpcreflect.MethodCaller, error)
 }
 
+// TracedMethodCaller may be implemented by a MethodCaller that wants
+// to know the trace id of the requests it handles, so that the work
+// done on their behalf can be correlated with them.
+type TracedMethodCaller interface {
+	rpcreflect.MethodCaller
+
+	// CallTraced is like Call, but is also given the trace id of
+	// the request.
+	CallTraced(traceId, objId string, arg reflect.Value) (reflect.Value, error)
+}
+
 // Killer represents a type that can be asked to abort any outstanding
 // requests.  The Kill method should return immediately.
 type Killer interface {
@@ -410,6 +439,12 @@ func (conn *Conn) readBody(resp interface{}, isRequest bool) error {
 
 func (conn *Conn) handleRequest(hdr *Header) error {
 	startTime := time.Now()
+	if hdr.TraceId == "" {
+		// The request came from a client which does not send trace
+		// ids; allocate one so that the request may still be traced
+		// through the server.
+		hdr.TraceId = NewTraceId()
+	}
 	req, err := conn.bindRequest(hdr)
 	if err != nil {
 		if conn.notifier != nil {
@@ -474,12 +509,16 @@ func (conn *Conn) writeErrorResponse(reqHdr *Header, err error, startTime time.T
 	defer conn.sending.Unlock()
 	hdr := &Header{
 		RequestId: reqHdr.RequestId,
+		TraceId:   reqHdr.TraceId,
 	}
 	if err, ok := err.(ErrorCoder); ok {
 		hdr.ErrorCode = err.ErrorCode()
 	} else {
 		hdr.ErrorCode = ""
 	}
+	if err, ok := err.(ErrorInfoProvider); ok {
+		hdr.ErrorInfo = err.ErrorInfo()
+	}
 	hdr.Error = err.Error()
 	if conn.notifier != nil {
 		conn.notifier.ServerReply(reqHdr.Request, hdr, struct{}{}, time.Since(startTime))
@@ -530,12 +569,19 @@ func (conn *Conn) bindRequest(hdr *Header) (boundRequest, error) {
 // runRequest runs the given request and sends the reply.
 func (conn *Conn) runRequest(req boundRequest, arg reflect.Value, startTime time.Time) {
 	defer conn.srvPending.Done()
-	rv, err := req.Call(req.hdr.Request.Id, arg)
+	var rv reflect.Value
+	var err error
+	if caller, ok := req.MethodCaller.(TracedMethodCaller); ok {
+		rv, err = caller.CallTraced(req.hdr.TraceId, req.hdr.Request.Id, arg)
+	} else {
+		rv, err = req.Call(req.hdr.Request.Id, arg)
+	}
 	if err != nil {
 		err = conn.writeErrorResponse(&req.hdr, req.transformErrors(err), startTime)
 	} else {
 		hdr := &Header{
 			RequestId: req.hdr.RequestId,
+			TraceId:   req.hdr.TraceId,
 		}
 		var rvi interface{}
 		if rv.IsValid() {
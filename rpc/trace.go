@@ -0,0 +1,26 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package rpc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync/atomic"
+)
+
+var traceCounter uint64
+
+// NewTraceId returns a new identifier suitable for use as the TraceId
+// of a request. Trace ids are random, so that the ids allocated by
+// different clients and servers are unlikely to collide.
+func NewTraceId() string {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		// Trace ids are only used for diagnostics, so fall back to
+		// a process-wide counter rather than failing the request.
+		return fmt.Sprintf("c%015x", atomic.AddUint64(&traceCounter, 1))
+	}
+	return hex.EncodeToString(id[:])
+}
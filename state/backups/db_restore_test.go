@@ -0,0 +1,123 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package backups_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/utils/set"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state/backups"
+	"github.com/juju/juju/testing"
+)
+
+type restoreSuite struct {
+	testing.BaseSuite
+
+	dbInfo   *backups.DBInfo
+	dumpDir  string
+	commands [][]string
+}
+
+var _ = gc.Suite(&restoreSuite{}) // Register the suite.
+
+func (s *restoreSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+
+	targets := set.NewStrings("juju", "admin", "blobstore")
+	s.dbInfo = &backups.DBInfo{"a", "b", "c", targets}
+	s.dumpDir = c.MkDir()
+	s.commands = nil
+
+	s.PatchValue(backups.GetMongorestorePath, func() (string, error) {
+		return "bogusmongorestore", nil
+	})
+	s.PatchValue(backups.RunCommand, func(cmd string, args ...string) error {
+		s.commands = append(s.commands, append([]string{cmd}, args...))
+		return nil
+	})
+}
+
+func (s *restoreSuite) prepFiles(c *gc.C, dbName string, fileNames ...string) {
+	dirName := filepath.Join(s.dumpDir, dbName)
+	err := os.MkdirAll(dirName, 0777)
+	c.Assert(err, jc.ErrorIsNil)
+	for _, fileName := range fileNames {
+		err := ioutil.WriteFile(filepath.Join(dirName, fileName), nil, 0644)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+}
+
+func (s *restoreSuite) command(dbName string) []string {
+	return []string{
+		"bogusmongorestore",
+		"--ssl",
+		"--authenticationDatabase", "admin",
+		"--host", "a",
+		"--username", "b",
+		"--password", "c",
+		"--drop",
+		"--db", dbName,
+		filepath.Join(s.dumpDir, dbName),
+	}
+}
+
+func (s *restoreSuite) TestRestoreDatabases(c *gc.C) {
+	s.prepFiles(c, "juju",
+		"machines.bson", "machines.metadata.json",
+		"settings.bson", "settings.metadata.json",
+		"system.users.bson", "system.indexes.bson",
+		"txns.bson", "txns.log.bson", "txns.log.metadata.json",
+	)
+	s.prepFiles(c, "admin", "system.users.bson", "other.bson")
+	s.prepFiles(c, "presence", "presence.beings.bson")
+	s.prepFiles(c, "blobstore", "blobstore.files.bson")
+	restorer, err := backups.NewDBRestorer(s.dbInfo)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = restorer.Restore(s.dumpDir)
+	c.Assert(err, jc.ErrorIsNil)
+
+	c.Assert(s.commands, jc.DeepEquals, [][]string{
+		s.command("blobstore"),
+		s.command("juju"),
+	})
+	// The ignored collections are not restored with the database.
+	list, err := ioutil.ReadDir(filepath.Join(s.dumpDir, "juju"))
+	c.Assert(err, jc.ErrorIsNil)
+	var remaining []string
+	for _, info := range list {
+		remaining = append(remaining, info.Name())
+	}
+	c.Assert(remaining, jc.SameContents, []string{
+		"machines.bson", "machines.metadata.json",
+		"settings.bson", "settings.metadata.json",
+		"txns.bson",
+	})
+}
+
+func (s *restoreSuite) TestRestoreFailure(c *gc.C) {
+	s.prepFiles(c, "juju", "machines.bson")
+	s.PatchValue(backups.RunCommand, func(cmd string, args ...string) error {
+		return errors.New("boom")
+	})
+	restorer, err := backups.NewDBRestorer(s.dbInfo)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = restorer.Restore(s.dumpDir)
+	c.Assert(err, gc.ErrorMatches, "error restoring juju: boom")
+}
+
+func (s *restoreSuite) TestNewDBRestorerNoMongorestore(c *gc.C) {
+	s.PatchValue(backups.GetMongorestorePath, func() (string, error) {
+		return "", errors.NotFoundf("mongorestore")
+	})
+	_, err := backups.NewDBRestorer(s.dbInfo)
+	c.Assert(err, gc.ErrorMatches, "mongorestore not available: mongorestore not found")
+}
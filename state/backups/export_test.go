@@ -12,6 +12,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/juju/testing"
 	"github.com/juju/utils/filestorage"
+	"gopkg.in/mgo.v2"
 
 	"github.com/juju/juju/state"
 )
@@ -26,7 +27,10 @@ var (
 	FinishMeta           = &finishMeta
 	StoreArchiveRef      = &storeArchive
 	GetMongodumpPath     = &getMongodumpPath
+	GetMongorestorePath  = &getMongorestorePath
+	GetDBRestorer        = &getDBRestorer
 	RunCommand           = &runCommand
+	CheckCollections     = checkCollections
 )
 
 var _ filestorage.DocStorage = (*backupsDocStorage)(nil)
@@ -161,3 +165,15 @@ func NewTestArchiveStorer(failure string) func(filestorage.FileStorage, *Metadat
 		return errors.New(failure)
 	}
 }
+
+// RestoreJujuCollections runs restore, which stands in for the restore
+// of the given collections of the juju database, then cleans up the
+// restored transactions as RestoreDB does.
+func RestoreJujuCollections(db *mgo.Database, collections []string, restore func()) error {
+	before, err := readRevnos(db, collections)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	restore()
+	return cleanUpRestoredTxns(db, before)
+}
@@ -0,0 +1,267 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package backups
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/juju/utils/set"
+	"gopkg.in/mgo.v2"
+
+	"github.com/juju/juju/mongo"
+)
+
+const restoreName = "mongorestore"
+
+// ignoredRestoreDatabases is the list of dumped databases that are not
+// restored. The admin database holds the mongo users, whose passwords
+// may have changed since the backup was taken.
+var ignoredRestoreDatabases = set.NewStrings(
+	"admin",
+	"local",
+)
+
+// ignoredRestoreCollection returns whether the collection with the
+// given name is not restored. The mongo users of a database are kept
+// for the same reason as the admin database, the transaction log is
+// kept so that watchers notice the changes made by the restore, and
+// the restore info is kept so that the environment stays locked for
+// the restore.
+func ignoredRestoreCollection(name string) bool {
+	return strings.HasPrefix(name, "system.") || name == txnsLogC || name == restoreInfoC
+}
+
+// DBRestorer is any type that restores something from a dump dir.
+type DBRestorer interface {
+	// Restore something from dumpDir.
+	Restore(dumpDir string) error
+}
+
+var getMongorestorePath = func() (string, error) {
+	mongod, err := mongo.Path()
+	if err != nil {
+		return "", errors.Annotate(err, "failed to get mongod path")
+	}
+	mongoRestorePath := filepath.Join(filepath.Dir(mongod), restoreName)
+
+	if _, err := os.Stat(mongoRestorePath); err == nil {
+		// It already exists so no need to continue.
+		return mongoRestorePath, nil
+	}
+
+	path, err := exec.LookPath(restoreName)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	return path, nil
+}
+
+type mongoRestorer struct {
+	*DBInfo
+	// binPath is the path to the restore executable.
+	binPath string
+}
+
+// NewDBRestorer returns a new value with a Restore method for replacing
+// the juju state databases with the ones in a dump dir.
+func NewDBRestorer(info *DBInfo) (DBRestorer, error) {
+	mongorestorePath, err := getMongorestorePath()
+	if err != nil {
+		return nil, errors.Annotate(err, "mongorestore not available")
+	}
+
+	restorer := mongoRestorer{
+		DBInfo:  info,
+		binPath: mongorestorePath,
+	}
+	return &restorer, nil
+}
+
+func (mr *mongoRestorer) options(dbName, dbDir string) []string {
+	options := []string{
+		"--ssl",
+		"--authenticationDatabase", "admin",
+		"--host", mr.Address,
+		"--username", mr.Username,
+		"--password", mr.Password,
+		"--drop",
+		"--db", dbName,
+		dbDir,
+	}
+	return options
+}
+
+// Restore replaces each of the databases dumped in the dump dir that
+// are backup targets with its dumped contents, as a whole. Collections
+// that were not dumped are left alone, so the caller must check that
+// the collection sets match first. The ignored collections are removed
+// from the dump dir.
+func (mr *mongoRestorer) Restore(baseDumpDir string) error {
+	dbNames, err := restoredDatabases(baseDumpDir, mr.Targets)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, dbName := range dbNames {
+		dbDir := filepath.Join(baseDumpDir, dbName)
+		if err := removeIgnoredCollections(dbDir); err != nil {
+			return errors.Trace(err)
+		}
+		if err := runCommand(mr.binPath, mr.options(dbName, dbDir)...); err != nil {
+			return errors.Annotatef(err, "error restoring %s", dbName)
+		}
+	}
+	return nil
+}
+
+// restoredDatabases returns the names of the databases dumped in the
+// dump dir that are restored, sorted.
+func restoredDatabases(baseDumpDir string, targets set.Strings) ([]string, error) {
+	found, err := listDatabases(baseDumpDir)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	restored := found.Intersection(targets).Difference(ignoredRestoreDatabases)
+	return restored.SortedValues(), nil
+}
+
+// dumpedCollections returns the names of the collections dumped in the
+// dump dir of a database that are restored.
+func dumpedCollections(dbDir string) (set.Strings, error) {
+	list, err := ioutil.ReadDir(dbDir)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	collections := make(set.Strings)
+	for _, info := range list {
+		name := strings.TrimSuffix(info.Name(), ".bson")
+		if info.IsDir() || name == info.Name() || ignoredRestoreCollection(name) {
+			continue
+		}
+		collections.Add(name)
+	}
+	return collections, nil
+}
+
+// removeIgnoredCollections removes the dumps of the collections that
+// are not restored from the dump dir of a database, so that restoring
+// the whole dir leaves them alone.
+func removeIgnoredCollections(dbDir string) error {
+	list, err := ioutil.ReadDir(dbDir)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, info := range list {
+		name := strings.TrimSuffix(info.Name(), ".bson")
+		if name == info.Name() {
+			name = strings.TrimSuffix(info.Name(), ".metadata.json")
+		}
+		if info.IsDir() || name == info.Name() || !ignoredRestoreCollection(name) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dbDir, info.Name())); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// checkCollections returns an error if the collections of any of the
+// given databases differ from the ones dumped in the dump dir, as the
+// collections that were not dumped would be left alone by the restore
+// and the ones that were would be created.
+func checkCollections(session *mgo.Session, baseDumpDir string, dbNames []string) error {
+	for _, dbName := range dbNames {
+		dumped, err := dumpedCollections(filepath.Join(baseDumpDir, dbName))
+		if err != nil {
+			return errors.Trace(err)
+		}
+		names, err := session.DB(dbName).CollectionNames()
+		if err != nil {
+			return errors.Annotatef(err, "cannot list collections of %s", dbName)
+		}
+		live := make(set.Strings)
+		for _, name := range names {
+			if !ignoredRestoreCollection(name) {
+				live.Add(name)
+			}
+		}
+		var problems []string
+		if missing := live.Difference(dumped); !missing.IsEmpty() {
+			problems = append(problems, "not in backup: "+strings.Join(missing.SortedValues(), ", "))
+		}
+		if extra := dumped.Difference(live); !extra.IsEmpty() {
+			problems = append(problems, "only in backup: "+strings.Join(extra.SortedValues(), ", "))
+		}
+		if len(problems) > 0 {
+			return errors.Errorf("collections of %s differ from the backup (%s)", dbName, strings.Join(problems, "; "))
+		}
+	}
+	return nil
+}
+
+var getDBRestorer = NewDBRestorer
+
+// RestoreDB replaces the juju state databases with the ones dumped in
+// the archive of the backup with the given ID. The files in the archive
+// are not restored, and neither are the mongo users nor the transaction
+// log. The restore is refused if the collections of the databases
+// differ from the dumped ones. Changes made after the backup was taken
+// are lost: afterwards, the transactions missing from the restored
+// database are purged from the documents' queues, the pending ones are
+// resumed, and the changes to the juju documents are written to the
+// transaction log so that watchers notice them.
+func RestoreDB(backups Backups, id string, dbInfo *DBInfo, session *mgo.Session) error {
+	_, archiveFile, err := backups.Get(id)
+	if err != nil {
+		return errors.Annotatef(err, "cannot get backup %q", id)
+	}
+	defer archiveFile.Close()
+
+	workspace, err := NewArchiveWorkspaceReader(archiveFile)
+	if workspace != nil {
+		defer workspace.Close()
+	}
+	if err != nil {
+		return errors.Annotate(err, "while unpacking backup archive")
+	}
+
+	dbNames, err := restoredDatabases(workspace.DBDumpDir, dbInfo.Targets)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := checkCollections(session, workspace.DBDumpDir, dbNames); err != nil {
+		return errors.Trace(err)
+	}
+	restoreJuju := set.NewStrings(dbNames...).Contains(jujuDB)
+	var before map[string]docRevnos
+	if restoreJuju {
+		collections, err := dumpedCollections(filepath.Join(workspace.DBDumpDir, jujuDB))
+		if err != nil {
+			return errors.Trace(err)
+		}
+		before, err = readRevnos(session.DB(jujuDB), watchedCollections(collections))
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+
+	restorer, err := getDBRestorer(dbInfo)
+	if err != nil {
+		return errors.Annotate(err, "while preparing for DB restore")
+	}
+	if err := restorer.Restore(workspace.DBDumpDir); err != nil {
+		return errors.Annotate(err, "while restoring databases")
+	}
+	if restoreJuju {
+		if err := cleanUpRestoredTxns(session.DB(jujuDB), before); err != nil {
+			return errors.Annotate(err, "while cleaning up restored transactions")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package backups
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/juju/utils/set"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+const (
+	// jujuDB is the name of the juju state database.
+	jujuDB = "juju"
+
+	txnsC      = "txns"
+	txnsLogC   = "txns.log"
+	txnsStashC = "txns.stash"
+
+	// restoreInfoC holds the restore info, which locks the environment
+	// while a backup is restored.
+	restoreInfoC = "restoreInfo"
+
+	// maxLogIds is the number of document ids written to each entry
+	// of the transaction log for a collection.
+	maxLogIds = 1000
+)
+
+// docRevnos maps the ids of the documents of a collection to their
+// txn-revno.
+type docRevnos map[interface{}]int64
+
+// watchedCollections returns the given collections of the juju database
+// whose changes are written to the transaction log, sorted.
+func watchedCollections(collections set.Strings) []string {
+	watched := collections.Difference(set.NewStrings(txnsC, txnsStashC))
+	return watched.SortedValues()
+}
+
+// readRevnos returns the txn-revno of every document of the given
+// collections that is written by transactions.
+func readRevnos(db *mgo.Database, collections []string) (map[string]docRevnos, error) {
+	result := make(map[string]docRevnos)
+	for _, name := range collections {
+		revnos := make(docRevnos)
+		query := bson.D{{"txn-revno", bson.D{{"$exists", true}}}}
+		iter := db.C(name).Find(query).Select(bson.D{{"_id", 1}, {"txn-revno", 1}}).Iter()
+		for {
+			var doc struct {
+				Id    interface{} `bson:"_id"`
+				Revno int64       `bson:"txn-revno"`
+			}
+			if !iter.Next(&doc) {
+				break
+			}
+			revnos[doc.Id] = doc.Revno
+		}
+		if err := iter.Close(); err != nil {
+			return nil, errors.Annotatef(err, "cannot read %s", name)
+		}
+		result[name] = revnos
+	}
+	return result, nil
+}
+
+// cleanUpRestoredTxns makes the restored juju database consistent for
+// the transaction runner and the watchers, given the txn-revnos of the
+// documents before the restore: it purges the tokens of the transactions
+// missing from the restored transaction collection from the documents'
+// queues, resumes the pending transactions, and writes the changes made
+// by the restore to the transaction log.
+func cleanUpRestoredTxns(db *mgo.Database, before map[string]docRevnos) error {
+	names, err := db.CollectionNames()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, name := range names {
+		// The documents kept by the restore, such as the restore
+		// info, may be queued on the transactions it removed too.
+		if strings.HasPrefix(name, "system.") || name == txnsC || name == txnsLogC {
+			continue
+		}
+		if err := purgeMissingTxnTokens(db, name); err != nil {
+			return errors.Annotatef(err, "cannot purge transactions from %s", name)
+		}
+	}
+	runner := txn.NewRunner(db.C(txnsC))
+	runner.ChangeLog(db.C(txnsLogC))
+	if err := runner.ResumeAll(); err != nil {
+		return errors.Annotate(err, "cannot resume transactions")
+	}
+	for _, name := range sortedKeys(before) {
+		if err := logRestoredChanges(db, name, before[name]); err != nil {
+			return errors.Annotatef(err, "cannot log changes to %s", name)
+		}
+	}
+	return nil
+}
+
+// purgeMissingTxnTokens removes from the queues of the documents of a
+// collection the tokens of the transactions that are not in the
+// transaction collection, which would otherwise block any further
+// transaction on them.
+func purgeMissingTxnTokens(db *mgo.Database, name string) error {
+	txns := db.C(txnsC)
+	coll := db.C(name)
+	exists := make(map[bson.ObjectId]bool)
+	missing := make(map[interface{}][]string)
+	query := bson.D{{"txn-queue.0", bson.D{{"$exists", true}}}}
+	iter := coll.Find(query).Select(bson.D{{"_id", 1}, {"txn-queue", 1}}).Iter()
+	for {
+		var doc struct {
+			Id    interface{} `bson:"_id"`
+			Queue []string    `bson:"txn-queue"`
+		}
+		if !iter.Next(&doc) {
+			break
+		}
+		for _, token := range doc.Queue {
+			if len(token) < 24 || !bson.IsObjectIdHex(token[:24]) {
+				continue
+			}
+			txnId := bson.ObjectIdHex(token[:24])
+			found, ok := exists[txnId]
+			if !ok {
+				count, err := txns.FindId(txnId).Count()
+				if err != nil {
+					iter.Close()
+					return errors.Trace(err)
+				}
+				found = count > 0
+				exists[txnId] = found
+			}
+			if !found {
+				missing[doc.Id] = append(missing[doc.Id], token)
+			}
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return errors.Trace(err)
+	}
+	for id, tokens := range missing {
+		update := bson.D{{"$pullAll", bson.D{{"txn-queue", tokens}}}}
+		if err := coll.UpdateId(id, update); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// logRestoredChanges writes to the transaction log the documents of a
+// collection that the restore changed, given their txn-revnos before it.
+// Watchers only report a document whose txn-revno grows, so the restored
+// documents are given a txn-revno above their one before the restore.
+// Removed documents are logged with a txn-revno of -1.
+func logRestoredChanges(db *mgo.Database, name string, before docRevnos) error {
+	coll := db.C(name)
+	after, err := readRevnos(db, []string{name})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	var ids []interface{}
+	var revnos []int64
+	for id, revno := range after[name] {
+		old, existed := before[id]
+		if existed && old == revno {
+			continue
+		}
+		if existed && old > revno {
+			revno = old + 1
+			update := bson.D{{"$set", bson.D{{"txn-revno", revno}}}}
+			if err := coll.UpdateId(id, update); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		ids = append(ids, id)
+		revnos = append(revnos, revno)
+	}
+	for id := range before {
+		if _, exists := after[name][id]; !exists {
+			ids = append(ids, id)
+			revnos = append(revnos, -1)
+		}
+	}
+
+	log := db.C(txnsLogC)
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > maxLogIds {
+			n = maxLogIds
+		}
+		entry := bson.D{
+			{"_id", bson.NewObjectId()},
+			{name, bson.D{{"d", ids[:n]}, {"r", revnos[:n]}}},
+		}
+		if err := log.Insert(entry); err != nil {
+			return errors.Trace(err)
+		}
+		ids, revnos = ids[n:], revnos[n:]
+	}
+	return nil
+}
+
+// sortedKeys returns the collection names of the given revnos, sorted.
+func sortedKeys(revnos map[string]docRevnos) []string {
+	names := make(set.Strings)
+	for name := range revnos {
+		names.Add(name)
+	}
+	return names.SortedValues()
+}
@@ -0,0 +1,137 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package backups_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	gitjujutesting "github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/juju/juju/state/backups"
+	"github.com/juju/juju/testing"
+)
+
+type restoreTxnsSuite struct {
+	gitjujutesting.MgoSuite
+	testing.BaseSuite
+	db *mgo.Database
+}
+
+var _ = gc.Suite(&restoreTxnsSuite{})
+
+func (s *restoreTxnsSuite) SetUpSuite(c *gc.C) {
+	s.BaseSuite.SetUpSuite(c)
+	s.MgoSuite.SetUpSuite(c)
+}
+
+func (s *restoreTxnsSuite) TearDownSuite(c *gc.C) {
+	s.MgoSuite.TearDownSuite(c)
+	s.BaseSuite.TearDownSuite(c)
+}
+
+func (s *restoreTxnsSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	s.MgoSuite.SetUpTest(c)
+	s.db = s.Session.DB("juju")
+}
+
+func (s *restoreTxnsSuite) TearDownTest(c *gc.C) {
+	s.MgoSuite.TearDownTest(c)
+	s.BaseSuite.TearDownTest(c)
+}
+
+func (s *restoreTxnsSuite) insert(c *gc.C, collection string, docs ...interface{}) {
+	err := s.db.C(collection).Insert(docs...)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *restoreTxnsSuite) dumpDir(c *gc.C, fileNames ...string) string {
+	dumpDir := c.MkDir()
+	dbDir := filepath.Join(dumpDir, "juju")
+	err := os.MkdirAll(dbDir, 0777)
+	c.Assert(err, jc.ErrorIsNil)
+	for _, fileName := range fileNames {
+		err := ioutil.WriteFile(filepath.Join(dbDir, fileName), nil, 0644)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	return dumpDir
+}
+
+func (s *restoreTxnsSuite) TestCheckCollections(c *gc.C) {
+	s.insert(c, "machines", bson.M{"_id": "0"})
+	s.insert(c, "settings", bson.M{"_id": "e"})
+	s.insert(c, "txns.log", bson.M{"_id": bson.NewObjectId()})
+	s.insert(c, "restoreInfo", bson.M{"_id": "current"})
+
+	dumpDir := s.dumpDir(c, "machines.bson", "settings.bson", "system.indexes.bson")
+	err := backups.CheckCollections(s.Session, dumpDir, []string{"juju"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	dumpDir = s.dumpDir(c, "machines.bson", "units.bson")
+	err = backups.CheckCollections(s.Session, dumpDir, []string{"juju"})
+	c.Assert(err, gc.ErrorMatches, `collections of juju differ from the backup \(not in backup: settings; only in backup: units\)`)
+}
+
+func (s *restoreTxnsSuite) TestCleanUpRestoredTxns(c *gc.C) {
+	s.insert(c, "machines",
+		bson.M{"_id": "0", "txn-revno": int64(5)},
+		bson.M{"_id": "1", "txn-revno": int64(2)},
+		bson.M{"_id": "2", "txn-revno": int64(1)},
+	)
+	missingToken := bson.NewObjectId().Hex() + "_12345678"
+	s.insert(c, "restoreInfo", bson.M{"_id": "current", "txn-queue": []string{missingToken}})
+	err := backups.RestoreJujuCollections(s.db, []string{"machines"}, func() {
+		machines := s.db.C("machines")
+		_, err := machines.RemoveAll(nil)
+		c.Assert(err, jc.ErrorIsNil)
+		s.insert(c, "machines",
+			bson.M{"_id": "0", "txn-revno": int64(3), "txn-queue": []string{missingToken}},
+			bson.M{"_id": "1", "txn-revno": int64(2)},
+			bson.M{"_id": "3", "txn-revno": int64(1)},
+		)
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	// The document restored to an older revision gets a txn-revno
+	// above its one before the restore, and the token of the missing
+	// transaction is purged from its queue.
+	var doc struct {
+		Revno int64    `bson:"txn-revno"`
+		Queue []string `bson:"txn-queue"`
+	}
+	err = s.db.C("machines").FindId("0").One(&doc)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(doc.Revno, gc.Equals, int64(6))
+	c.Assert(doc.Queue, gc.HasLen, 0)
+
+	// The restore info, which is not restored, is purged too.
+	err = s.db.C("restoreInfo").FindId("current").One(&doc)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(doc.Queue, gc.HasLen, 0)
+
+	var entries []struct {
+		Machines struct {
+			Ids    []interface{} `bson:"d"`
+			Revnos []int64       `bson:"r"`
+		} `bson:"machines"`
+	}
+	err = s.db.C("txns.log").Find(nil).All(&entries)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(entries, gc.HasLen, 1)
+	changes := make(map[interface{}]int64)
+	for i, id := range entries[0].Machines.Ids {
+		changes[id] = entries[0].Machines.Revnos[i]
+	}
+	c.Assert(changes, jc.DeepEquals, map[interface{}]int64{
+		"0": 6,
+		"2": -1,
+		"3": 1,
+	})
+}
@@ -66,23 +66,31 @@ var multiEnvCollections = set.NewStrings(
 	blockDevicesC,
 	charmsC,
 	cleanupsC,
+	configHistoryC,
 	constraintsC,
 	containerRefsC,
+	containerTemplatesC,
 	instanceDataC,
+	leaseC,
 	machinesC,
 	meterStatusC,
 	minUnitsC,
 	networkInterfacesC,
 	networksC,
+	observedInterfacesC,
 	openedPortsC,
 	rebootC,
 	relationScopesC,
 	relationsC,
+	remoteServicesC,
 	requestedNetworksC,
 	sequenceC,
 	servicesC,
 	settingsC,
 	settingsrefsC,
+	sshAccessC,
+	spacesC,
+	stagedUpgradesC,
 	statusesC,
 	subnetsC,
 	unitsC,
@@ -0,0 +1,286 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/juju/errors"
+	jujutxn "github.com/juju/txn"
+	"gopkg.in/juju/charm.v4"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+// secretConfigKey matches the names of charm settings that appear to
+// hold secrets, whose values are not recorded in the config history.
+var secretConfigKey = regexp.MustCompile(`(?i)(^|[-_.])(pass|passwd|password|passphrase|secret|token|key|credentials?)($|[-_.])`)
+
+// ConfigRevision describes a change to the configuration of a service.
+type ConfigRevision struct {
+	// Revision numbers the changes to the service's configuration,
+	// starting from 1.
+	Revision int
+
+	// CharmURL holds the URL of the service's charm when the
+	// change was made.
+	CharmURL string
+
+	// User holds the tag of the user who made the change, if known.
+	User string
+
+	Time    time.Time
+	Changes []ConfigChange
+}
+
+// ConfigChange describes the change of a setting in a ConfigRevision.
+type ConfigChange struct {
+	ItemChange
+
+	// Masked is true if the setting appears to hold a secret, so its
+	// values were not recorded.
+	Masked bool
+}
+
+// configHistoryDoc records a change to the configuration of a service.
+type configHistoryDoc struct {
+	DocID    string            `bson:"_id"`
+	EnvUUID  string            `bson:"env-uuid"`
+	Service  string            `bson:"service"`
+	Revision int               `bson:"revision"`
+	CharmURL string            `bson:"charmurl"`
+	User     string            `bson:"user"`
+	Time     time.Time         `bson:"time"`
+	Changes  []configChangeDoc `bson:"changes"`
+}
+
+type configChangeDoc struct {
+	Key      string      `bson:"key"`
+	Type     int         `bson:"type"`
+	OldValue interface{} `bson:"oldvalue,omitempty"`
+	NewValue interface{} `bson:"newvalue,omitempty"`
+	Masked   bool        `bson:"masked,omitempty"`
+}
+
+// configHistoryOp returns the operation that records the given changes
+// to the service's configuration as the given revision.
+func (s *Service) configHistoryOp(revision int, user string, changes []ItemChange) txn.Op {
+	doc := &configHistoryDoc{
+		DocID:    s.st.docID(fmt.Sprintf("%s#%d", s.doc.Name, revision)),
+		EnvUUID:  s.st.EnvironUUID(),
+		Service:  s.doc.Name,
+		Revision: revision,
+		CharmURL: s.doc.CharmURL.String(),
+		User:     user,
+		Time:     nowToTheSecond(),
+	}
+	for _, change := range changes {
+		changeDoc := configChangeDoc{
+			Key:  change.Key,
+			Type: change.Type,
+		}
+		if secretConfigKey.MatchString(change.Key) {
+			changeDoc.Masked = true
+		} else {
+			changeDoc.OldValue = change.OldValue
+			changeDoc.NewValue = change.NewValue
+		}
+		doc.Changes = append(doc.Changes, changeDoc)
+	}
+	return txn.Op{
+		C:      configHistoryC,
+		Id:     doc.DocID,
+		Assert: txn.DocMissing,
+		Insert: doc,
+	}
+}
+
+// latestConfigRevision returns the revision of the last recorded change
+// to the service's configuration, or 0 if there is none.
+func (s *Service) latestConfigRevision() (int, error) {
+	history, closer := s.st.getCollection(configHistoryC)
+	defer closer()
+
+	var docs []configHistoryDoc
+	err := history.Find(bson.D{{"service", s.doc.Name}}).Sort("-revision").Limit(1).All(&docs)
+	if err != nil {
+		return 0, errors.Annotatef(err, "cannot read config history of service %q", s.doc.Name)
+	}
+	if len(docs) == 0 {
+		return 0, nil
+	}
+	return docs[0].Revision, nil
+}
+
+// ConfigHistory returns the recorded changes to the service's
+// configuration, oldest first.
+func (s *Service) ConfigHistory() ([]ConfigRevision, error) {
+	history, closer := s.st.getCollection(configHistoryC)
+	defer closer()
+
+	var docs []configHistoryDoc
+	if err := history.Find(bson.D{{"service", s.doc.Name}}).Sort("revision").All(&docs); err != nil {
+		return nil, errors.Annotatef(err, "cannot read config history of service %q", s.doc.Name)
+	}
+	revisions := make([]ConfigRevision, len(docs))
+	for i, doc := range docs {
+		revisions[i] = ConfigRevision{
+			Revision: doc.Revision,
+			CharmURL: doc.CharmURL,
+			User:     doc.User,
+			Time:     doc.Time,
+		}
+		for _, change := range doc.Changes {
+			revisions[i].Changes = append(revisions[i].Changes, ConfigChange{
+				ItemChange: ItemChange{
+					Type:     change.Type,
+					Key:      change.Key,
+					OldValue: change.OldValue,
+					NewValue: change.NewValue,
+				},
+				Masked: change.Masked,
+			})
+		}
+	}
+	return revisions, nil
+}
+
+// RevertConfigSettings changes the service's configuration back to what
+// it was after the given revision, or before any recorded change for
+// revision 0. The revert is itself recorded as a new revision made by
+// the given user. Settings whose values were masked in the history are
+// left unchanged, and their names returned. Changes made with a charm
+// other than the service's current one cannot be reverted.
+func (s *Service) RevertConfigSettings(user string, revision int) (skipped []string, err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot revert service %q to config revision %d", s.doc.Name, revision)
+	history, err := s.ConfigHistory()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	latest := 0
+	if len(history) > 0 {
+		latest = history[len(history)-1].Revision
+	}
+	if revision < 0 || revision > latest {
+		return nil, errors.NotFoundf("config revision %d", revision)
+	}
+	current, err := s.ConfigSettings()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	// Undo the changes made since the revision, newest first, so that
+	// each setting ends up with its value at the revision.
+	target := make(charm.Settings)
+	masked := make(map[string]bool)
+	for i := len(history) - 1; i >= 0 && history[i].Revision > revision; i-- {
+		rev := history[i]
+		if rev.CharmURL != s.doc.CharmURL.String() {
+			return nil, errors.Errorf("revision %d was made with charm %q", rev.Revision, rev.CharmURL)
+		}
+		for _, change := range rev.Changes {
+			if change.Masked {
+				masked[change.Key] = true
+				continue
+			}
+			switch change.Type {
+			case ItemAdded:
+				target[change.Key] = nil
+			default:
+				target[change.Key] = change.OldValue
+			}
+		}
+	}
+	for key := range masked {
+		skipped = append(skipped, key)
+	}
+	sort.Strings(skipped)
+
+	changes := make(charm.Settings)
+	for key, value := range target {
+		old, found := current[key]
+		switch {
+		case value == nil && found:
+			changes[key] = nil
+		case value != nil && (!found || old != value):
+			changes[key] = value
+		}
+	}
+	if len(changes) == 0 {
+		return skipped, nil
+	}
+	if err := s.UpdateConfigSettingsBy(user, changes); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return skipped, nil
+}
+
+// UpdateConfigSettingsBy changes a service's charm config settings on
+// behalf of the given user, and records the change in the service's
+// config history. Values set to nil will be deleted; unknown and invalid
+// values will return an error.
+func (s *Service) UpdateConfigSettingsBy(user string, changes charm.Settings) error {
+	charm, _, err := s.Charm()
+	if err != nil {
+		return err
+	}
+	changes, err = charm.Config().ValidateSettings(changes)
+	if err != nil {
+		return err
+	}
+	// TODO(fwereade) state.Settings is itself really problematic in just
+	// about every use case. This needs to be resolved some time; but at
+	// least the settings docs are keyed by charm url as well as service
+	// name, so the actual impact of a race is non-threatening.
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		node, err := readSettings(s.st, s.settingsKey())
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range changes {
+			if value == nil {
+				node.Delete(name)
+			} else {
+				node.Set(name, value)
+			}
+		}
+		itemChanges, ops := node.writeOps()
+		if len(itemChanges) == 0 {
+			return nil, jujutxn.ErrNoOperations
+		}
+		latest, err := s.latestConfigRevision()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return append(ops, s.configHistoryOp(latest+1, user, itemChanges)), nil
+	}
+	return s.st.run(buildTxn)
+}
+
+// removeConfigHistoryOps returns the operations that remove the
+// service's config history.
+func (s *Service) removeConfigHistoryOps() ([]txn.Op, error) {
+	history, closer := s.st.getCollection(configHistoryC)
+	defer closer()
+
+	var docs []struct {
+		DocID string `bson:"_id"`
+	}
+	err := history.Find(bson.D{{"service", s.doc.Name}}).Select(bson.D{{"_id", 1}}).All(&docs)
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot read config history of service %q", s.doc.Name)
+	}
+	ops := make([]txn.Op, len(docs))
+	for i, doc := range docs {
+		ops[i] = txn.Op{
+			C:      configHistoryC,
+			Id:     doc.DocID,
+			Remove: true,
+		}
+	}
+	return ops, nil
+}
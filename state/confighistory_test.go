@@ -0,0 +1,125 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/charm.v4"
+
+	"github.com/juju/juju/state"
+)
+
+type ConfigHistorySuite struct {
+	ConnSuite
+	charm   *state.Charm
+	service *state.Service
+}
+
+var _ = gc.Suite(&ConfigHistorySuite{})
+
+const configHistoryYaml = `
+options:
+  title: {default: My Title, description: A title., type: string}
+  outlook: {description: An outlook., type: string}
+  admin-password: {description: A secret., type: string}
+`
+
+func (s *ConfigHistorySuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	s.charm = s.AddConfigCharm(c, "wordpress", configHistoryYaml, 1)
+	s.service = s.AddTestingService(c, "wordpress", s.charm)
+}
+
+func (s *ConfigHistorySuite) TestHistoryRecorded(c *gc.C) {
+	err := s.service.UpdateConfigSettingsBy("user-bob", charm.Settings{"outlook": "sunny", "admin-password": "sekrit"})
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.service.UpdateConfigSettings(charm.Settings{"outlook": "cloudy", "title": "Blog"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Changes that leave the settings unchanged are not recorded.
+	err = s.service.UpdateConfigSettingsBy("user-bob", charm.Settings{"outlook": "cloudy"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	history, err := s.service.ConfigHistory()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(history, gc.HasLen, 2)
+	c.Assert(history[0].Revision, gc.Equals, 1)
+	c.Assert(history[0].User, gc.Equals, "user-bob")
+	c.Assert(history[0].CharmURL, gc.Equals, "local:quantal/quantal-wordpress-1")
+	c.Assert(history[0].Time.IsZero(), jc.IsFalse)
+	c.Assert(history[0].Changes, jc.DeepEquals, []state.ConfigChange{{
+		ItemChange: state.ItemChange{Type: state.ItemAdded, Key: "admin-password"},
+		Masked:     true,
+	}, {
+		ItemChange: state.ItemChange{Type: state.ItemAdded, Key: "outlook", NewValue: "sunny"},
+	}})
+	c.Assert(history[1].Revision, gc.Equals, 2)
+	c.Assert(history[1].User, gc.Equals, "")
+	c.Assert(history[1].Changes, jc.DeepEquals, []state.ConfigChange{{
+		ItemChange: state.ItemChange{Type: state.ItemModified, Key: "outlook", OldValue: "sunny", NewValue: "cloudy"},
+	}, {
+		ItemChange: state.ItemChange{Type: state.ItemAdded, Key: "title", NewValue: "Blog"},
+	}})
+}
+
+func (s *ConfigHistorySuite) TestRevert(c *gc.C) {
+	err := s.service.UpdateConfigSettingsBy("user-bob", charm.Settings{"outlook": "sunny"})
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.service.UpdateConfigSettingsBy("user-bob", charm.Settings{"outlook": "cloudy", "title": "Blog", "admin-password": "sekrit"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	skipped, err := s.service.RevertConfigSettings("user-alice", 1)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(skipped, jc.DeepEquals, []string{"admin-password"})
+	settings, err := s.service.ConfigSettings()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(settings, jc.DeepEquals, charm.Settings{
+		"outlook":        "sunny",
+		"admin-password": "sekrit",
+	})
+
+	history, err := s.service.ConfigHistory()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(history, gc.HasLen, 3)
+	c.Assert(history[2].User, gc.Equals, "user-alice")
+
+	// Reverting to revision 0 undoes every recorded change.
+	_, err = s.service.RevertConfigSettings("user-alice", 0)
+	c.Assert(err, jc.ErrorIsNil)
+	settings, err = s.service.ConfigSettings()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(settings, jc.DeepEquals, charm.Settings{"admin-password": "sekrit"})
+}
+
+func (s *ConfigHistorySuite) TestRevertUnknownRevision(c *gc.C) {
+	_, err := s.service.RevertConfigSettings("user-bob", 1)
+	c.Assert(err, gc.ErrorMatches, `cannot revert service "wordpress" to config revision 1: config revision 1 not found`)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *ConfigHistorySuite) TestRevertAcrossCharmUpgrade(c *gc.C) {
+	err := s.service.UpdateConfigSettingsBy("user-bob", charm.Settings{"outlook": "sunny"})
+	c.Assert(err, jc.ErrorIsNil)
+	ch := s.AddConfigCharm(c, "wordpress", configHistoryYaml, 2)
+	err = s.service.SetCharm(ch, false)
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = s.service.RevertConfigSettings("user-bob", 0)
+	c.Assert(err, gc.ErrorMatches, `cannot revert service "wordpress" to config revision 0: revision 1 was made with charm "local:quantal/quantal-wordpress-1"`)
+}
+
+func (s *ConfigHistorySuite) TestHistoryRemovedWithService(c *gc.C) {
+	err := s.service.UpdateConfigSettingsBy("user-bob", charm.Settings{"outlook": "sunny"})
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.service.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+
+	// A new service with the same name starts with an empty history.
+	svc := s.AddTestingService(c, "wordpress", s.charm)
+	history, err := svc.ConfigHistory()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(history, gc.HasLen, 0)
+}
@@ -0,0 +1,248 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/instance"
+)
+
+// ContainerTemplate describes the template that containers of a kind
+// and series are cloned from on a host machine, and any request to
+// build it ahead of the first container.
+type ContainerTemplate struct {
+	doc containerTemplateDoc
+}
+
+// containerTemplateDoc records the state of a container template on a
+// host machine.
+type containerTemplateDoc struct {
+	DocID          string    `bson:"_id"`
+	EnvUUID        string    `bson:"env-uuid"`
+	MachineId      string    `bson:"machineid"`
+	Kind           string    `bson:"kind"`
+	Series         string    `bson:"series"`
+	PrimeRequested time.Time `bson:"primerequested,omitempty"`
+	Built          time.Time `bson:"built,omitempty"`
+	Packages       []string  `bson:"packages,omitempty"`
+	Error          string    `bson:"error,omitempty"`
+}
+
+// MachineId returns the id of the machine hosting the template.
+func (t *ContainerTemplate) MachineId() string {
+	return t.doc.MachineId
+}
+
+// Kind returns the kind of containers cloned from the template.
+func (t *ContainerTemplate) Kind() instance.ContainerType {
+	return instance.ContainerType(t.doc.Kind)
+}
+
+// Series returns the series of the template.
+func (t *ContainerTemplate) Series() string {
+	return t.doc.Series
+}
+
+// PrimeRequested returns when the template was last requested to be
+// built, or the zero time if no request is pending.
+func (t *ContainerTemplate) PrimeRequested() time.Time {
+	return t.doc.PrimeRequested
+}
+
+// Built returns when the template was last built, or the zero time if
+// it has never been built.
+func (t *ContainerTemplate) Built() time.Time {
+	return t.doc.Built
+}
+
+// Packages returns the packages installed in the template when it was
+// last built, as "name=version".
+func (t *ContainerTemplate) Packages() []string {
+	return t.doc.Packages
+}
+
+// Error returns why the last attempt to build the template failed, or
+// the empty string if it succeeded.
+func (t *ContainerTemplate) Error() string {
+	return t.doc.Error
+}
+
+func containerTemplateKey(machineId string, kind instance.ContainerType, series string) string {
+	return fmt.Sprintf("%s#%s#%s", machineId, kind, series)
+}
+
+// ContainerTemplates returns the container templates recorded for the
+// machine, ordered by kind and series.
+func (m *Machine) ContainerTemplates() ([]*ContainerTemplate, error) {
+	templates, err := m.st.containerTemplates(bson.D{{"machineid", m.doc.Id}})
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot get container templates of machine %s", m.doc.Id)
+	}
+	return templates, nil
+}
+
+// AllContainerTemplates returns the container templates recorded for
+// all machines, ordered by machine, kind and series.
+func (st *State) AllContainerTemplates() ([]*ContainerTemplate, error) {
+	templates, err := st.containerTemplates(nil)
+	if err != nil {
+		return nil, errors.Annotate(err, "cannot get container templates")
+	}
+	return templates, nil
+}
+
+func (st *State) containerTemplates(sel bson.D) ([]*ContainerTemplate, error) {
+	coll, closer := st.getCollection(containerTemplatesC)
+	defer closer()
+
+	var docs []containerTemplateDoc
+	if err := coll.Find(sel).Sort("machineid", "kind", "series").All(&docs); err != nil {
+		return nil, errors.Trace(err)
+	}
+	templates := make([]*ContainerTemplate, len(docs))
+	for i, doc := range docs {
+		templates[i] = &ContainerTemplate{doc}
+	}
+	return templates, nil
+}
+
+// RequestContainerTemplate asks for the template of the given container
+// kind and series to be built on the machine ahead of its first
+// container, or rebuilt if it already exists. Only LXC templates are
+// supported.
+func (m *Machine) RequestContainerTemplate(kind instance.ContainerType, series string) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot request %s template for series %q on machine %s", kind, series, m.doc.Id)
+	if kind != instance.LXC {
+		return errors.NotSupportedf("%s templates", kind)
+	}
+	if series == "" {
+		return errors.New("series not specified")
+	}
+	if supported, known := m.SupportedContainers(); known {
+		found := false
+		for _, containerType := range supported {
+			found = found || containerType == kind
+		}
+		if !found {
+			return errors.Errorf("machine does not support %s containers", kind)
+		}
+	}
+	return m.setContainerTemplate(kind, series, func(doc *containerTemplateDoc) {
+		doc.PrimeRequested = nowToTheSecond()
+	})
+}
+
+// SetContainerTemplateBuilt records that the template of the given
+// container kind and series has been built on the machine, with the
+// given packages installed, and that no request to build it is pending.
+func (m *Machine) SetContainerTemplateBuilt(kind instance.ContainerType, series string, packages []string) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot record %s template for series %q on machine %s", kind, series, m.doc.Id)
+	return m.setContainerTemplate(kind, series, func(doc *containerTemplateDoc) {
+		doc.PrimeRequested = time.Time{}
+		doc.Built = nowToTheSecond()
+		doc.Packages = packages
+		doc.Error = ""
+	})
+}
+
+// SetContainerTemplateFailed records that the template of the given
+// container kind and series could not be built on the machine, and
+// that no request to build it is pending. The last successfully built
+// template, if any, remains recorded.
+func (m *Machine) SetContainerTemplateFailed(kind instance.ContainerType, series, message string) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot record %s template for series %q on machine %s", kind, series, m.doc.Id)
+	return m.setContainerTemplate(kind, series, func(doc *containerTemplateDoc) {
+		doc.PrimeRequested = time.Time{}
+		doc.Error = message
+	})
+}
+
+// setContainerTemplate applies the given change to the machine's
+// container template of the given kind and series, creating the
+// template's document if necessary.
+func (m *Machine) setContainerTemplate(kind instance.ContainerType, series string, change func(*containerTemplateDoc)) error {
+	coll, closer := m.st.getCollection(containerTemplatesC)
+	defer closer()
+
+	docID := m.st.docID(containerTemplateKey(m.doc.Id, kind, series))
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := m.Refresh(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		if m.doc.Life != Alive {
+			return nil, errors.New("machine is not alive")
+		}
+		ops := []txn.Op{{
+			C:      machinesC,
+			Id:     m.doc.DocID,
+			Assert: isAliveDoc,
+		}}
+		var doc containerTemplateDoc
+		err := coll.FindId(docID).One(&doc)
+		if err == mgo.ErrNotFound {
+			doc = containerTemplateDoc{
+				DocID:     docID,
+				EnvUUID:   m.st.EnvironUUID(),
+				MachineId: m.doc.Id,
+				Kind:      string(kind),
+				Series:    series,
+			}
+			change(&doc)
+			return append(ops, txn.Op{
+				C:      containerTemplatesC,
+				Id:     docID,
+				Assert: txn.DocMissing,
+				Insert: &doc,
+			}), nil
+		} else if err != nil {
+			return nil, errors.Trace(err)
+		}
+		change(&doc)
+		return append(ops, txn.Op{
+			C:      containerTemplatesC,
+			Id:     docID,
+			Assert: txn.DocExists,
+			Update: bson.D{{"$set", bson.D{
+				{"primerequested", doc.PrimeRequested},
+				{"built", doc.Built},
+				{"packages", doc.Packages},
+				{"error", doc.Error},
+			}}},
+		}), nil
+	}
+	return m.st.run(buildTxn)
+}
+
+// removeContainerTemplatesOps returns the operations that remove the
+// container templates recorded for the machine.
+func removeContainerTemplatesOps(st *State, machineId string) ([]txn.Op, error) {
+	coll, closer := st.getCollection(containerTemplatesC)
+	defer closer()
+
+	var docs []struct {
+		DocID string `bson:"_id"`
+	}
+	err := coll.Find(bson.D{{"machineid", machineId}}).Select(bson.D{{"_id", 1}}).All(&docs)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	ops := make([]txn.Op, len(docs))
+	for i, doc := range docs {
+		ops[i] = txn.Op{
+			C:      containerTemplatesC,
+			Id:     doc.DocID,
+			Remove: true,
+		}
+	}
+	return ops, nil
+}
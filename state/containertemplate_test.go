@@ -0,0 +1,110 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/state"
+)
+
+type ContainerTemplateSuite struct {
+	ConnSuite
+	machine *state.Machine
+}
+
+var _ = gc.Suite(&ContainerTemplateSuite{})
+
+func (s *ContainerTemplateSuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	var err error
+	s.machine, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *ContainerTemplateSuite) TestRequestContainerTemplate(c *gc.C) {
+	templates, err := s.machine.ContainerTemplates()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(templates, gc.HasLen, 0)
+
+	before := state.NowToTheSecond()
+	err = s.machine.RequestContainerTemplate(instance.LXC, "trusty")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.RequestContainerTemplate(instance.LXC, "precise")
+	c.Assert(err, jc.ErrorIsNil)
+
+	templates, err = s.machine.ContainerTemplates()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(templates, gc.HasLen, 2)
+	t := templates[1]
+	c.Assert(t.MachineId(), gc.Equals, s.machine.Id())
+	c.Assert(t.Kind(), gc.Equals, instance.LXC)
+	c.Assert(t.Series(), gc.Equals, "trusty")
+	c.Assert(t.PrimeRequested().Before(before), jc.IsFalse)
+	c.Assert(t.Built().IsZero(), jc.IsTrue)
+	c.Assert(templates[0].Series(), gc.Equals, "precise")
+}
+
+func (s *ContainerTemplateSuite) TestRequestContainerTemplateInvalid(c *gc.C) {
+	err := s.machine.RequestContainerTemplate(instance.KVM, "trusty")
+	c.Assert(err, gc.ErrorMatches, `cannot request kvm template for series "trusty" on machine 0: kvm templates not supported`)
+	err = s.machine.RequestContainerTemplate(instance.LXC, "")
+	c.Assert(err, gc.ErrorMatches, `cannot request lxc template for series "" on machine 0: series not specified`)
+
+	err = s.machine.SetSupportedContainers([]instance.ContainerType{instance.KVM})
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.RequestContainerTemplate(instance.LXC, "trusty")
+	c.Assert(err, gc.ErrorMatches, `cannot request lxc template for series "trusty" on machine 0: machine does not support lxc containers`)
+
+	err = s.machine.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.SetContainerTemplateBuilt(instance.LXC, "trusty", nil)
+	c.Assert(err, gc.ErrorMatches, `cannot record lxc template for series "trusty" on machine 0: machine is not alive`)
+}
+
+func (s *ContainerTemplateSuite) TestSetContainerTemplateBuiltAndFailed(c *gc.C) {
+	err := s.machine.RequestContainerTemplate(instance.LXC, "trusty")
+	c.Assert(err, jc.ErrorIsNil)
+	packages := []string{"cloud-init=0.7.5-0ubuntu1", "python2.7=2.7.6-8"}
+	err = s.machine.SetContainerTemplateBuilt(instance.LXC, "trusty", packages)
+	c.Assert(err, jc.ErrorIsNil)
+
+	templates, err := s.State.AllContainerTemplates()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(templates, gc.HasLen, 1)
+	t := templates[0]
+	c.Assert(t.PrimeRequested().IsZero(), jc.IsTrue)
+	c.Assert(t.Built().IsZero(), jc.IsFalse)
+	c.Assert(t.Packages(), jc.DeepEquals, packages)
+	c.Assert(t.Error(), gc.Equals, "")
+	built := t.Built()
+
+	// A failed rebuild keeps the last template.
+	err = s.machine.RequestContainerTemplate(instance.LXC, "trusty")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.SetContainerTemplateFailed(instance.LXC, "trusty", "no space left on device")
+	c.Assert(err, jc.ErrorIsNil)
+	templates, err = s.machine.ContainerTemplates()
+	c.Assert(err, jc.ErrorIsNil)
+	t = templates[0]
+	c.Assert(t.PrimeRequested().IsZero(), jc.IsTrue)
+	c.Assert(t.Built(), gc.Equals, built)
+	c.Assert(t.Packages(), jc.DeepEquals, packages)
+	c.Assert(t.Error(), gc.Equals, "no space left on device")
+}
+
+func (s *ContainerTemplateSuite) TestRemoveMachineRemovesTemplates(c *gc.C) {
+	err := s.machine.SetContainerTemplateBuilt(instance.LXC, "trusty", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.Remove()
+	c.Assert(err, jc.ErrorIsNil)
+
+	templates, err := s.State.AllContainerTemplates()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(templates, gc.HasLen, 0)
+}
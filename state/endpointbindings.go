@@ -0,0 +1,124 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/utils/set"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/network"
+)
+
+// EndpointBindings returns the spaces the service's endpoints are bound
+// to, by endpoint name. The space used by the endpoints that are not
+// bound explicitly, if any, is held under the empty name.
+func (s *Service) EndpointBindings() map[string]string {
+	bindings := make(map[string]string)
+	for endpoint, space := range s.doc.EndpointBindings {
+		bindings[endpoint] = space
+	}
+	if s.doc.DefaultSpace != "" {
+		bindings[""] = s.doc.DefaultSpace
+	}
+	return bindings
+}
+
+// SetEndpointBindings binds the endpoints of the service to spaces,
+// replacing any existing bindings. The bindings map endpoint names to
+// space names; a binding with an empty endpoint name sets the space
+// used by all the endpoints not bound explicitly.
+func (s *Service) SetEndpointBindings(bindings map[string]string) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot bind endpoints of service %q", s.doc.Name)
+	var defaultSpace string
+	explicit := make(map[string]string)
+	spaces := make(set.Strings)
+	for endpoint, space := range bindings {
+		if !network.IsValidSpace(space) {
+			return errors.Errorf("invalid space name %q", space)
+		}
+		if _, err := s.st.Space(space); err != nil {
+			return errors.Trace(err)
+		}
+		spaces.Add(space)
+		if endpoint == "" {
+			defaultSpace = space
+			continue
+		}
+		if _, err := s.Endpoint(endpoint); err != nil {
+			return errors.Trace(err)
+		}
+		explicit[endpoint] = space
+	}
+	ops := []txn.Op{{
+		C:  servicesC,
+		Id: s.doc.DocID,
+		// The endpoints checked above come from the current charm.
+		Assert: bson.D{
+			{"life", Alive},
+			{"charmurl", s.doc.CharmURL},
+		},
+		Update: bson.D{{"$set", bson.D{
+			{"endpointbindings", explicit},
+			{"defaultspace", defaultSpace},
+		}}},
+	}}
+	for _, space := range spaces.SortedValues() {
+		ops = append(ops, assertSpaceExistsOp(s.st, space))
+	}
+	if err := s.st.runTransaction(ops); err != nil {
+		return onAbort(err, errors.New("service or spaces changed"))
+	}
+	s.doc.EndpointBindings = explicit
+	s.doc.DefaultSpace = defaultSpace
+	return nil
+}
+
+// boundSpace returns the space the named endpoint of the service is
+// bound to, or the empty string if it is not bound. The empty endpoint
+// name selects the space used by the endpoints not bound explicitly.
+func (s *Service) boundSpace(endpoint string) string {
+	if space, ok := s.doc.EndpointBindings[endpoint]; ok && endpoint != "" {
+		return space
+	}
+	return s.doc.DefaultSpace
+}
+
+// PrivateAddressForEndpoint returns the private address the unit uses
+// over the named endpoint of its service, and whether it is valid. If
+// the endpoint is bound to a space, this is the first of the addresses
+// of the unit's machine in that space; the empty endpoint name selects
+// the space used by the endpoints not bound explicitly. If the endpoint
+// is not bound, or the machine has no address in its space, the unit's
+// private address is returned.
+func (u *Unit) PrivateAddressForEndpoint(endpoint string) (string, bool) {
+	if address, ok := u.boundAddress(endpoint); ok {
+		return address, true
+	}
+	return u.PrivateAddress()
+}
+
+func (u *Unit) boundAddress(endpoint string) (string, bool) {
+	service, err := u.Service()
+	if err != nil {
+		unitLogger.Errorf("%v", err)
+		return "", false
+	}
+	spaceName := service.boundSpace(endpoint)
+	if spaceName == "" {
+		return "", false
+	}
+	subnets, err := u.st.subnetsInSpace(spaceName)
+	if err != nil {
+		unitLogger.Errorf("%v", err)
+		return "", false
+	}
+	address, ok := addressInSpace(u.addressesOfMachine(), subnets)
+	if !ok {
+		unitLogger.Warningf("unit %q has no address in space %q", u, spaceName)
+		return "", false
+	}
+	return address.Value, true
+}
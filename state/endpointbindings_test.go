@@ -0,0 +1,136 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+)
+
+type EndpointBindingsSuite struct {
+	ConnSuite
+	service *state.Service
+	unit    *state.Unit
+	machine *state.Machine
+}
+
+var _ = gc.Suite(&EndpointBindingsSuite{})
+
+func (s *EndpointBindingsSuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	for space, cidr := range map[string]string{
+		"internal": "10.0.0.0/24",
+		"storage":  "192.168.1.0/24",
+		"empty":    "172.16.0.0/16",
+	} {
+		_, err := s.State.AddSpace(space, nil)
+		c.Assert(err, jc.ErrorIsNil)
+		_, err = s.State.AddSubnet(state.SubnetInfo{CIDR: cidr, SpaceName: space})
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	s.service = s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	var err error
+	s.unit, err = s.service.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	s.machine, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.unit.AssignToMachine(s.machine)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.SetAddresses(
+		network.NewAddress("8.8.8.8", network.ScopePublic),
+		network.NewAddress("10.0.0.5", network.ScopeCloudLocal),
+		network.NewAddress("192.168.1.5", network.ScopeCloudLocal),
+	)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *EndpointBindingsSuite) TestSetEndpointBindings(c *gc.C) {
+	c.Check(s.service.EndpointBindings(), gc.HasLen, 0)
+
+	bindings := map[string]string{
+		"":   "internal",
+		"db": "storage",
+	}
+	err := s.service.SetEndpointBindings(bindings)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(s.service.EndpointBindings(), jc.DeepEquals, bindings)
+
+	err = s.service.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(s.service.EndpointBindings(), jc.DeepEquals, bindings)
+
+	// New bindings replace the old ones.
+	err = s.service.SetEndpointBindings(map[string]string{"url": "internal"})
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.service.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(s.service.EndpointBindings(), jc.DeepEquals, map[string]string{"url": "internal"})
+}
+
+func (s *EndpointBindingsSuite) TestSetEndpointBindingsErrors(c *gc.C) {
+	for i, test := range []struct {
+		bindings map[string]string
+		err      string
+	}{{
+		bindings: map[string]string{"db": "Storage"},
+		err:      `invalid space name "Storage"`,
+	}, {
+		bindings: map[string]string{"db": "missing"},
+		err:      `space "missing" not found`,
+	}, {
+		bindings: map[string]string{"server": "storage"},
+		err:      `service "wordpress" has no "server" relation`,
+	}} {
+		c.Logf("test %d: %v", i, test.bindings)
+		err := s.service.SetEndpointBindings(test.bindings)
+		c.Check(err, gc.ErrorMatches, `cannot bind endpoints of service "wordpress": `+test.err)
+	}
+	c.Check(s.service.EndpointBindings(), gc.HasLen, 0)
+}
+
+func (s *EndpointBindingsSuite) TestPrivateAddressForEndpoint(c *gc.C) {
+	// Without bindings, the unit's private address is used.
+	address, ok := s.unit.PrivateAddressForEndpoint("db")
+	c.Check(ok, jc.IsTrue)
+	c.Check(address, gc.Equals, "10.0.0.5")
+
+	err := s.service.SetEndpointBindings(map[string]string{
+		"":      "storage",
+		"url":   "internal",
+		"cache": "empty",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	for endpoint, expect := range map[string]string{
+		"db":  "192.168.1.5",
+		"":    "192.168.1.5",
+		"url": "10.0.0.5",
+		// The machine has no address in the space.
+		"cache": "10.0.0.5",
+	} {
+		c.Logf("endpoint %q", endpoint)
+		address, ok := s.unit.PrivateAddressForEndpoint(endpoint)
+		c.Check(ok, jc.IsTrue)
+		c.Check(address, gc.Equals, expect)
+	}
+}
+
+func (s *EndpointBindingsSuite) TestRelationUnitPrivateAddress(c *gc.C) {
+	err := s.service.SetEndpointBindings(map[string]string{"db": "storage"})
+	c.Assert(err, jc.ErrorIsNil)
+	s.AddTestingService(c, "mysql", s.AddTestingCharm(c, "mysql"))
+	eps, err := s.State.InferEndpoints("wordpress", "mysql")
+	c.Assert(err, jc.ErrorIsNil)
+	rel, err := s.State.AddRelation(eps...)
+	c.Assert(err, jc.ErrorIsNil)
+	ru, err := rel.Unit(s.unit)
+	c.Assert(err, jc.ErrorIsNil)
+
+	address, ok := ru.PrivateAddress()
+	c.Check(ok, jc.IsTrue)
+	c.Check(address, gc.Equals, "192.168.1.5")
+}
@@ -32,6 +32,15 @@ type environmentDoc struct {
 	Life       Life
 	Owner      string `bson:"owner"`
 	ServerUUID string `bson:"server-uuid"`
+
+	// InstancePollGeneration is incremented whenever an immediate
+	// poll of the environment's instances is requested, and
+	// InstancePollCompleted holds the generation of the last such
+	// poll to complete. InstancePollStale holds the ids of the
+	// machines whose instances could not be queried in that poll.
+	InstancePollGeneration int64    `bson:"instancepollgeneration"`
+	InstancePollCompleted  int64    `bson:"instancepollcompleted"`
+	InstancePollStale      []string `bson:"instancepollstale,omitempty"`
 }
 
 // StateServerEnvironment returns the environment that was bootstrapped.
@@ -186,6 +195,78 @@ func (e *Environment) Owner() names.UserTag {
 	return names.NewUserTag(e.doc.Owner)
 }
 
+// InstancePollGeneration returns the generation of the last requested
+// immediate poll of the environment's instances, or 0 if none has been
+// requested.
+func (e *Environment) InstancePollGeneration() int64 {
+	return e.doc.InstancePollGeneration
+}
+
+// InstancePollCompleted returns the generation of the last requested
+// instance poll to complete.
+func (e *Environment) InstancePollCompleted() int64 {
+	return e.doc.InstancePollCompleted
+}
+
+// InstancePollStale returns the ids of the machines whose instances
+// could not be queried in the last completed instance poll.
+func (e *Environment) InstancePollStale() []string {
+	return e.doc.InstancePollStale
+}
+
+// RequestInstancePoll asks the instance poller to query the provider
+// for the addresses and status of all the environment's instances now,
+// rather than at their next scheduled poll. It returns the generation
+// of the request; the machines' instance information is up to date once
+// InstancePollCompleted reaches it.
+func (e *Environment) RequestInstancePoll() (int64, error) {
+	ops := []txn.Op{{
+		C:      environmentsC,
+		Id:     e.doc.UUID,
+		Update: bson.D{{"$inc", bson.D{{"instancepollgeneration", 1}}}},
+		Assert: isEnvAliveDoc,
+	}}
+	err := e.st.runTransaction(ops)
+	if err == txn.ErrAborted {
+		return 0, errors.New("cannot request instance poll: environment is no longer alive")
+	} else if err != nil {
+		return 0, errors.Annotate(err, "cannot request instance poll")
+	}
+	// Other requests may have been made concurrently; any of them
+	// covers this one.
+	if err := e.Refresh(); err != nil {
+		return 0, errors.Annotate(err, "cannot request instance poll")
+	}
+	return e.doc.InstancePollGeneration, nil
+}
+
+// CompleteInstancePoll records that the instance poll with the given
+// generation has completed, and that the instances of the given
+// machines could not be queried. Completing a poll older than the last
+// recorded one has no effect.
+func (e *Environment) CompleteInstancePoll(generation int64, stale []string) error {
+	ops := []txn.Op{{
+		C:  environmentsC,
+		Id: e.doc.UUID,
+		Assert: bson.D{{"instancepollcompleted", bson.D{
+			{"$not", bson.D{{"$gte", generation}}},
+		}}},
+		Update: bson.D{{"$set", bson.D{
+			{"instancepollcompleted", generation},
+			{"instancepollstale", stale},
+		}}},
+	}}
+	err := e.st.runTransaction(ops)
+	if err == txn.ErrAborted {
+		return nil
+	} else if err != nil {
+		return errors.Annotate(err, "cannot complete instance poll")
+	}
+	e.doc.InstancePollCompleted = generation
+	e.doc.InstancePollStale = stale
+	return nil
+}
+
 // globalKey returns the global database key for the environment.
 func (e *Environment) globalKey() string {
 	return environGlobalKey
@@ -12,6 +12,7 @@ import (
 
 	"github.com/juju/juju/environs/config"
 	"github.com/juju/juju/state"
+	statetesting "github.com/juju/juju/state/testing"
 	"github.com/juju/juju/testing"
 )
 
@@ -37,6 +38,67 @@ func (s *EnvironSuite) TestEnvironment(c *gc.C) {
 	})
 }
 
+func (s *EnvironSuite) TestRequestInstancePoll(c *gc.C) {
+	env, err := s.State.Environment()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(env.InstancePollGeneration(), gc.Equals, int64(0))
+
+	generation, err := env.RequestInstancePoll()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(generation, gc.Equals, int64(1))
+	c.Assert(env.InstancePollGeneration(), gc.Equals, generation)
+
+	generation, err = env.RequestInstancePoll()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(generation, gc.Equals, int64(2))
+
+	env, err = s.State.Environment()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(env.InstancePollGeneration(), gc.Equals, int64(2))
+	c.Assert(env.InstancePollCompleted(), gc.Equals, int64(0))
+}
+
+func (s *EnvironSuite) TestCompleteInstancePoll(c *gc.C) {
+	env, err := s.State.Environment()
+	c.Assert(err, jc.ErrorIsNil)
+	err = env.CompleteInstancePoll(2, []string{"1"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Completing an older poll changes nothing.
+	err = env.CompleteInstancePoll(1, nil)
+	c.Assert(err, jc.ErrorIsNil)
+
+	env, err = s.State.Environment()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(env.InstancePollCompleted(), gc.Equals, int64(2))
+	c.Assert(env.InstancePollStale(), jc.DeepEquals, []string{"1"})
+}
+
+func (s *EnvironSuite) TestRequestInstancePollDyingEnvironment(c *gc.C) {
+	env, err := s.State.Environment()
+	c.Assert(err, jc.ErrorIsNil)
+	err = env.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = env.RequestInstancePoll()
+	c.Assert(err, gc.ErrorMatches, "cannot request instance poll: environment is no longer alive")
+}
+
+func (s *EnvironSuite) TestWatchEnvironments(c *gc.C) {
+	w := s.State.WatchEnvironments()
+	defer statetesting.AssertStop(c, w)
+	wc := statetesting.NewStringsWatcherC(c, s.State, w)
+	wc.AssertChange(s.State.EnvironUUID())
+	wc.AssertNoChange()
+
+	cfg, uuid := s.createTestEnvConfig(c)
+	_, st, err := s.State.NewEnvironment(cfg, names.NewUserTag("test@remote"))
+	c.Assert(err, jc.ErrorIsNil)
+	defer st.Close()
+	wc.AssertChange(uuid)
+	wc.AssertNoChange()
+}
+
 func (s *EnvironSuite) TestNewEnvironmentNonExistentLocalUser(c *gc.C) {
 	cfg, _ := s.createTestEnvConfig(c)
 	owner := names.NewUserTag("non-existent@local")
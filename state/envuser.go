@@ -32,6 +32,54 @@ type envUserDoc struct {
 	CreatedBy      string     `bson:"createdby"`
 	DateCreated    time.Time  `bson:"datecreated"`
 	LastConnection *time.Time `bson:"lastconnection"`
+	Access         Access     `bson:"access,omitempty"`
+}
+
+// Access represents the level of access a user has to an environment.
+type Access string
+
+const (
+	// ReadAccess allows a user to look at the environment, but not to
+	// change it.
+	ReadAccess Access = "read"
+
+	// WriteAccess allows a user to deploy and change the environment.
+	WriteAccess Access = "write"
+
+	// AdminAccess allows a user to do anything with the environment,
+	// including managing its users and blocks, and destroying it.
+	AdminAccess Access = "admin"
+)
+
+// accessRanks orders the access levels; higher levels include the
+// permissions of all lower ones.
+var accessRanks = map[Access]int{
+	ReadAccess:  1,
+	WriteAccess: 2,
+	AdminAccess: 3,
+}
+
+// Validate returns an error if the access level is not known.
+func (a Access) Validate() error {
+	if _, ok := accessRanks[a]; !ok {
+		return errors.NotValidf("access level %q", a)
+	}
+	return nil
+}
+
+// Includes returns whether a user with access level a is allowed to do
+// everything a user with the other access level is.
+func (a Access) Includes(other Access) bool {
+	return accessRanks[a] >= accessRanks[other] && accessRanks[other] > 0
+}
+
+// ParseAccess returns the access level with the given name.
+func ParseAccess(s string) (Access, error) {
+	access := Access(s)
+	if err := access.Validate(); err != nil {
+		return "", errors.Trace(err)
+	}
+	return access, nil
 }
 
 // ID returns the ID of the environment user.
@@ -74,6 +122,35 @@ func (e *EnvironmentUser) LastConnection() *time.Time {
 	return e.doc.LastConnection
 }
 
+// Access returns the level of access the user has to the environment.
+func (e *EnvironmentUser) Access() Access {
+	if e.doc.Access == "" {
+		// Users added before access levels existed could do anything.
+		return AdminAccess
+	}
+	return e.doc.Access
+}
+
+// SetAccess changes the level of access the user has to the environment.
+func (e *EnvironmentUser) SetAccess(access Access) error {
+	if err := access.Validate(); err != nil {
+		return errors.Trace(err)
+	}
+	ops := []txn.Op{{
+		C:      envUsersC,
+		Id:     e.ID(),
+		Assert: txn.DocExists,
+		Update: bson.D{{"$set", bson.D{{"access", access}}}},
+	}}
+	if err := e.st.runTransaction(ops); err == txn.ErrAborted {
+		return errors.NotFoundf("environment user %q", e.UserName())
+	} else if err != nil {
+		return errors.Annotatef(err, "cannot set access for envuser %q", e.ID())
+	}
+	e.doc.Access = access
+	return nil
+}
+
 // UpdateLastConnection updates the last connection time of the environment user.
 func (e *EnvironmentUser) UpdateLastConnection() error {
 	timestamp := nowToTheSecond()
@@ -108,11 +185,20 @@ func (st *State) EnvironmentUser(user names.UserTag) (*EnvironmentUser, error) {
 	if err == mgo.ErrNotFound {
 		return nil, errors.NotFoundf("environment user %q", user.Username())
 	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 	return envUser, nil
 }
 
-// AddEnvironmentUser adds a new user to the database.
-func (st *State) AddEnvironmentUser(user, createdBy names.UserTag) (*EnvironmentUser, error) {
+// AddEnvironmentUser adds a new user to the database, with the given
+// level of access to the environment. If the user already has access
+// to the environment, an error satisfying errors.IsAlreadyExists is
+// returned.
+func (st *State) AddEnvironmentUser(user, createdBy names.UserTag, access Access) (*EnvironmentUser, error) {
+	if err := access.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
 	var displayName string
 	// Ensure local user exists in state before adding them as an environment user.
 	if user.IsLocal() {
@@ -131,10 +217,10 @@ func (st *State) AddEnvironmentUser(user, createdBy names.UserTag) (*Environment
 	}
 
 	envuuid := st.EnvironUUID()
-	op, doc := createEnvUserOpAndDoc(envuuid, user, createdBy, displayName)
+	op, doc := createEnvUserOpAndDoc(envuuid, user, createdBy, displayName, access)
 	err := st.runTransaction([]txn.Op{op})
 	if err == txn.ErrAborted {
-		err = errors.New("env user already exists")
+		err = errors.AlreadyExistsf("env user")
 	}
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -142,7 +228,7 @@ func (st *State) AddEnvironmentUser(user, createdBy names.UserTag) (*Environment
 	return &EnvironmentUser{st: st, doc: *doc}, nil
 }
 
-func createEnvUserOpAndDoc(envuuid string, user, createdBy names.UserTag, displayName string) (txn.Op, *envUserDoc) {
+func createEnvUserOpAndDoc(envuuid string, user, createdBy names.UserTag, displayName string, access Access) (txn.Op, *envUserDoc) {
 	username := user.Username()
 	creatorname := createdBy.Username()
 	id := envUserID(envuuid, username)
@@ -153,6 +239,7 @@ func createEnvUserOpAndDoc(envuuid string, user, createdBy names.UserTag, displa
 		DisplayName: displayName,
 		CreatedBy:   creatorname,
 		DateCreated: nowToTheSecond(),
+		Access:      access,
 	}
 	op := txn.Op{
 		C:      envUsersC,
@@ -28,7 +28,7 @@ func (s *EnvUserSuite) TestAddEnvironmentUser(c *gc.C) {
 	now := state.NowToTheSecond()
 	user := s.factory.MakeUser(c, &factory.UserParams{Name: "validusername", NoEnvUser: true})
 	createdBy := s.factory.MakeUser(c, &factory.UserParams{Name: "createdby"})
-	envUser, err := s.State.AddEnvironmentUser(user.UserTag(), createdBy.UserTag())
+	envUser, err := s.State.AddEnvironmentUser(user.UserTag(), createdBy.UserTag(), state.WriteAccess)
 	c.Assert(err, jc.ErrorIsNil)
 
 	c.Assert(envUser.ID(), gc.Equals, fmt.Sprintf("%s:validusername@local", s.envTag.Id()))
@@ -38,6 +38,7 @@ func (s *EnvUserSuite) TestAddEnvironmentUser(c *gc.C) {
 	c.Assert(envUser.CreatedBy(), gc.Equals, "createdby@local")
 	c.Assert(envUser.DateCreated().Equal(now) || envUser.DateCreated().After(now), jc.IsTrue)
 	c.Assert(envUser.LastConnection(), gc.IsNil)
+	c.Assert(envUser.Access(), gc.Equals, state.WriteAccess)
 
 	envUser, err = s.State.EnvironmentUser(user.UserTag())
 	c.Assert(err, jc.ErrorIsNil)
@@ -48,17 +49,74 @@ func (s *EnvUserSuite) TestAddEnvironmentUser(c *gc.C) {
 	c.Assert(envUser.CreatedBy(), gc.Equals, "createdby@local")
 	c.Assert(envUser.DateCreated().Equal(now) || envUser.DateCreated().After(now), jc.IsTrue)
 	c.Assert(envUser.LastConnection(), gc.IsNil)
+	c.Assert(envUser.Access(), gc.Equals, state.WriteAccess)
+}
+
+func (s *EnvUserSuite) TestAddEnvironmentUserExisting(c *gc.C) {
+	user := s.factory.MakeUser(c, &factory.UserParams{Name: "validusername"})
+	_, err := s.State.AddEnvironmentUser(user.UserTag(), user.UserTag(), state.WriteAccess)
+	c.Assert(err, gc.ErrorMatches, "env user already exists")
+	c.Assert(err, jc.Satisfies, errors.IsAlreadyExists)
+}
+
+func (s *EnvUserSuite) TestAddEnvironmentUserInvalidAccess(c *gc.C) {
+	user := s.factory.MakeUser(c, &factory.UserParams{Name: "validusername", NoEnvUser: true})
+	_, err := s.State.AddEnvironmentUser(user.UserTag(), user.UserTag(), state.Access("superuser"))
+	c.Assert(err, gc.ErrorMatches, `access level "superuser" not valid`)
+}
+
+func (s *EnvUserSuite) TestSetAccess(c *gc.C) {
+	user := s.factory.MakeUser(c, &factory.UserParams{Name: "validusername", NoEnvUser: true})
+	envUser, err := s.State.AddEnvironmentUser(user.UserTag(), user.UserTag(), state.ReadAccess)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = envUser.SetAccess(state.AdminAccess)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(envUser.Access(), gc.Equals, state.AdminAccess)
+
+	envUser, err = s.State.EnvironmentUser(user.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(envUser.Access(), gc.Equals, state.AdminAccess)
+
+	err = envUser.SetAccess(state.Access(""))
+	c.Assert(err, gc.ErrorMatches, `access level "" not valid`)
+}
+
+func (s *EnvUserSuite) TestOwnerHasAdminAccess(c *gc.C) {
+	env, err := s.State.Environment()
+	c.Assert(err, jc.ErrorIsNil)
+	envUser, err := s.State.EnvironmentUser(env.Owner())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(envUser.Access(), gc.Equals, state.AdminAccess)
+}
+
+func (s *EnvUserSuite) TestAccessIncludes(c *gc.C) {
+	for i, test := range []struct {
+		access, other state.Access
+		includes      bool
+	}{
+		{state.AdminAccess, state.AdminAccess, true},
+		{state.AdminAccess, state.ReadAccess, true},
+		{state.WriteAccess, state.ReadAccess, true},
+		{state.WriteAccess, state.AdminAccess, false},
+		{state.ReadAccess, state.WriteAccess, false},
+		{state.AdminAccess, state.Access("bogus"), false},
+		{state.Access("bogus"), state.ReadAccess, false},
+	} {
+		c.Logf("test %d: %q includes %q", i, test.access, test.other)
+		c.Check(test.access.Includes(test.other), gc.Equals, test.includes)
+	}
 }
 
 func (s *EnvUserSuite) TestAddEnvironmentNoUserFails(c *gc.C) {
 	createdBy := s.factory.MakeUser(c, &factory.UserParams{Name: "createdby"})
-	_, err := s.State.AddEnvironmentUser(names.NewLocalUserTag("validusername"), createdBy.UserTag())
+	_, err := s.State.AddEnvironmentUser(names.NewLocalUserTag("validusername"), createdBy.UserTag(), state.WriteAccess)
 	c.Assert(err, gc.ErrorMatches, `user "validusername" does not exist locally: user "validusername" not found`)
 }
 
 func (s *EnvUserSuite) TestAddEnvironmentNoCreatedByUserFails(c *gc.C) {
 	user := s.factory.MakeUser(c, &factory.UserParams{Name: "validusername"})
-	_, err := s.State.AddEnvironmentUser(user.UserTag(), names.NewLocalUserTag("createdby"))
+	_, err := s.State.AddEnvironmentUser(user.UserTag(), names.NewLocalUserTag("createdby"), state.WriteAccess)
 	c.Assert(err, gc.ErrorMatches, `createdBy user "createdby" does not exist locally: user "createdby" not found`)
 }
 
@@ -154,7 +212,7 @@ func (s *EnvUserSuite) newEnvWithUser(c *gc.C, name string, user names.UserTag)
 	newEnv, err := envState.Environment()
 	c.Assert(err, jc.ErrorIsNil)
 
-	_, err = envState.AddEnvironmentUser(user, newEnv.Owner())
+	_, err = envState.AddEnvironmentUser(user, newEnv.Owner(), state.WriteAccess)
 	c.Assert(err, jc.ErrorIsNil)
 	return newEnv
 }
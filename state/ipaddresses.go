@@ -136,3 +136,25 @@ func (i *IPAddress) AllocateTo(machineId string, interfaceId string) (err error)
 
 	return i.st.runTransaction(ops)
 }
+
+// AllocatedIPAddresses returns the IP addresses allocated to the machine
+// with the given id, ordered by value.
+func (st *State) AllocatedIPAddresses(machineId string) ([]*IPAddress, error) {
+	addresses, closer := st.getCollection(ipaddressesC)
+	defer closer()
+
+	var docs []ipaddressDoc
+	query := bson.D{
+		{"env-uuid", st.EnvironUUID()},
+		{"machineid", machineId},
+		{"state", AddressStateAllocated},
+	}
+	if err := addresses.Find(query).Sort("value").All(&docs); err != nil {
+		return nil, errors.Annotatef(err, "cannot get IP addresses of machine %q", machineId)
+	}
+	result := make([]*IPAddress, len(docs))
+	for i, doc := range docs {
+		result[i] = &IPAddress{st, doc}
+	}
+	return result, nil
+}
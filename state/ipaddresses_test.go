@@ -120,3 +120,37 @@ func (s *IPAddressSuite) TestIPAddressAddress(c *gc.C) {
 	c.Assert(ipAddr.Address(), jc.DeepEquals, addr)
 
 }
+
+func (s *IPAddressSuite) TestAllocatedIPAddresses(c *gc.C) {
+	for _, value := range []string{"192.168.1.2", "192.168.1.1", "192.168.1.3", "192.168.1.4"} {
+		addr := network.NewAddress(value, network.ScopeCloudLocal)
+		ipAddr, err := s.State.AddIPAddress(addr, "foobar")
+		c.Assert(err, jc.ErrorIsNil)
+		switch value {
+		case "192.168.1.3":
+			// Picked for the machine but not allocated yet.
+			err = ipAddr.AllocateTo("0/lxc/0", "")
+		case "192.168.1.4":
+			err = ipAddr.AllocateTo("1", "")
+			c.Assert(err, jc.ErrorIsNil)
+			err = ipAddr.SetState(state.AddressStateAllocated)
+		default:
+			err = ipAddr.AllocateTo("0/lxc/0", "")
+			c.Assert(err, jc.ErrorIsNil)
+			err = ipAddr.SetState(state.AddressStateAllocated)
+		}
+		c.Assert(err, jc.ErrorIsNil)
+	}
+
+	addresses, err := s.State.AllocatedIPAddresses("0/lxc/0")
+	c.Assert(err, jc.ErrorIsNil)
+	values := make([]string, len(addresses))
+	for i, addr := range addresses {
+		values[i] = addr.Value()
+	}
+	c.Assert(values, jc.DeepEquals, []string{"192.168.1.1", "192.168.1.2"})
+
+	addresses, err = s.State.AllocatedIPAddresses("2")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(addresses, gc.HasLen, 0)
+}
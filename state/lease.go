@@ -4,104 +4,298 @@
 package state
 
 import (
+	"strings"
+	"time"
+
 	"github.com/juju/errors"
+	jujutxn "github.com/juju/txn"
+	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 	"gopkg.in/mgo.v2/txn"
 
-	"time"
-
 	"github.com/juju/juju/lease"
 )
 
-type leaseEntity struct {
-	LastUpdate time.Time
-	lease.Token
+// leaseDoc records the holder of the lease for a namespace. Leases
+// are only ever changed by transactions which assert the txn-revno
+// read when the change was decided upon, so that concurrent managers
+// on different state servers cannot both believe they won a claim.
+type leaseDoc struct {
+	DocID      string    `bson:"_id"`
+	Namespace  string    `bson:"namespace"`
+	EnvUUID    string    `bson:"env-uuid"`
+	Holder     string    `bson:"holder"`
+	Expiration time.Time `bson:"expiration"`
+	TxnRevno   int64     `bson:"txn-revno"`
+}
+
+// token returns the lease token recorded by the document. When
+// allEnvironments is true the namespace of the token includes the
+// environment UUID prefix.
+func (doc leaseDoc) token(allEnvironments bool) lease.Token {
+	namespace := doc.Namespace
+	if allEnvironments {
+		namespace = doc.DocID
+	}
+	return lease.Token{
+		Namespace:  namespace,
+		Id:         doc.Holder,
+		Expiration: doc.Expiration,
+	}
 }
 
 // NewLeasePersistor returns a new LeasePersistor. It should be passed
-// functions it can use to run transactions and get collections.
+// functions it can use to run transactions, get collections and
+// watch the lease collection.
 func NewLeasePersistor(
 	collectionName string,
-	runTransaction func([]txn.Op) error,
+	run func(jujutxn.TransactionSource) error,
 	getCollection func(string) (_ stateCollection, closer func()),
+	watchCollection func() NotifyWatcher,
 ) *LeasePersistor {
 	return &LeasePersistor{
-		collectionName: collectionName,
-		runTransaction: runTransaction,
-		getCollection:  getCollection,
+		collectionName:  collectionName,
+		run:             run,
+		getCollection:   getCollection,
+		watchCollection: watchCollection,
+	}
+}
+
+// newAllEnvironmentsLeasePersistor returns a LeasePersistor which
+// stores the leases of every environment. The namespaces of the
+// leases it handles are prefixed with the UUID of the environment they
+// belong to, as returned by EnvironLeaseNamespace; it must be passed
+// functions which do not add environment UUIDs themselves.
+func newAllEnvironmentsLeasePersistor(
+	collectionName string,
+	run func(jujutxn.TransactionSource) error,
+	getCollection func(string) (_ stateCollection, closer func()),
+	watchCollection func() NotifyWatcher,
+) *LeasePersistor {
+	p := NewLeasePersistor(collectionName, run, getCollection, watchCollection)
+	p.allEnvironments = true
+	return p
+}
+
+// AllEnvironmentsLeaseStore returns a lease store which holds the
+// leases of every environment, for use by the lease manager running
+// on a state server. Lease namespaces are given to it as returned by
+// EnvironLeaseNamespace.
+func (st *State) AllEnvironmentsLeaseStore() lease.LeaseStore {
+	getCollection := func(name string) (stateCollection, func()) {
+		coll, closer := st.getRawCollection(name)
+		return &genericStateCollection{Collection: coll}, closer
 	}
+	return newAllEnvironmentsLeasePersistor(leaseC, st.runRaw, getCollection, func() NotifyWatcher {
+		return newLeaseWatcher(st)
+	})
+}
+
+// EnvironLeaseNamespace returns the namespace under which a lease for
+// the given namespace in the given environment is known to a store
+// which holds the leases of all environments.
+func EnvironLeaseNamespace(envUUID, namespace string) string {
+	return addEnvUUID(envUUID, namespace)
 }
 
-// LeasePersistor represents logic which can persist lease tokens to a
-// data store.
+// LeasePersistor is the authoritative store for lease tokens. It
+// implements lease.LeaseStore.
 type LeasePersistor struct {
-	collectionName string
-	runTransaction func([]txn.Op) error
-	getCollection  func(string) (_ stateCollection, closer func())
+	collectionName  string
+	run             func(jujutxn.TransactionSource) error
+	getCollection   func(string) (_ stateCollection, closer func())
+	watchCollection func() NotifyWatcher
+	allEnvironments bool
 }
 
-// WriteToken writes the given token to the data store with the given
-// ID.
-func (p *LeasePersistor) WriteToken(id string, tok lease.Token) error {
+var _ lease.LeaseStore = (*LeasePersistor)(nil)
 
-	entity := leaseEntity{time.Now(), tok}
+// ClaimLease implements lease.LeaseStore. A claim by the current
+// holder extends the lease; a claim by anyone else only succeeds if
+// there is no lease for the namespace, or if it expired at or
+// before expiredBy.
+func (p *LeasePersistor) ClaimLease(tok lease.Token, expiredBy time.Time) (lease.Token, error) {
+	return p.claimLease(tok, "", expiredBy)
+}
 
-	// Write's should always overwrite anything that's there. The
-	// business-logic of managing leases is handled elsewhere.
-	ops := []txn.Op{
-		// First remove anything that's there.
-		{
-			C:      p.collectionName,
-			Id:     id,
-			Remove: true,
-		},
-		// Then insert the token.
-		{
-			Assert: txn.DocMissing,
+// ClaimLeaseUnlessHeld implements lease.LeaseStore. The claim asserts
+// that there is no lease document for the blocker namespace, so a
+// blocker lease recorded concurrently cannot be missed.
+func (p *LeasePersistor) ClaimLeaseUnlessHeld(tok lease.Token, blocker string, expiredBy time.Time) (lease.Token, error) {
+	return p.claimLease(tok, blocker, expiredBy)
+}
+
+func (p *LeasePersistor) claimLease(tok lease.Token, blocker string, expiredBy time.Time) (lease.Token, error) {
+
+	holder := tok
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		current, err := p.leaseDoc(tok.Namespace)
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, errors.Trace(err)
+		}
+		var ops []txn.Op
+		if blocker != "" {
+			if _, err := p.leaseDoc(blocker); err == nil {
+				holder = lease.Token{Namespace: tok.Namespace}
+				if current != nil {
+					holder = current.token(p.allEnvironments)
+				}
+				return nil, jujutxn.ErrNoOperations
+			} else if !errors.IsNotFound(err) {
+				return nil, errors.Trace(err)
+			}
+			// Like the namespace of an inserted lease, the blocker
+			// is given as the id of its document.
+			ops = append(ops, txn.Op{
+				C:      p.collectionName,
+				Id:     blocker,
+				Assert: txn.DocMissing,
+			})
+		}
+		if current == nil {
+			doc, err := p.newLeaseDoc(tok)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			holder = tok
+			return append(ops, txn.Op{
+				C:      p.collectionName,
+				Id:     tok.Namespace,
+				Assert: txn.DocMissing,
+				Insert: doc,
+			}), nil
+		}
+		if current.Holder != tok.Id && current.Expiration.After(expiredBy) {
+			holder = current.token(p.allEnvironments)
+			return nil, jujutxn.ErrNoOperations
+		}
+		holder = tok
+		return append(ops, txn.Op{
 			C:      p.collectionName,
-			Id:     id,
-			Insert: entity,
-		},
+			Id:     current.DocID,
+			Assert: bson.D{{"txn-revno", current.TxnRevno}},
+			Update: bson.D{{"$set", bson.D{
+				{"holder", tok.Id},
+				{"expiration", tok.Expiration},
+			}}},
+		}), nil
 	}
+	if err := p.run(buildTxn); err != nil {
+		return lease.Token{}, errors.Annotatef(err, `could not claim lease for "%s"`, tok.Namespace)
+	}
+	return holder, nil
+}
+
+// ReleaseLease implements lease.LeaseStore.
+func (p *LeasePersistor) ReleaseLease(namespace, id string) error {
 
-	if err := p.runTransaction(ops); err != nil {
-		return errors.Annotatef(err, `could not add token "%s" to data-store`, tok.Id)
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		current, err := p.leaseDoc(namespace)
+		if errors.IsNotFound(err) {
+			return nil, lease.NotLeaseOwnerErr
+		} else if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if current.Holder != id {
+			return nil, lease.NotLeaseOwnerErr
+		}
+		return []txn.Op{{
+			C:      p.collectionName,
+			Id:     current.DocID,
+			Assert: bson.D{{"txn-revno", current.TxnRevno}},
+			Remove: true,
+		}}, nil
 	}
+	return p.run(buildTxn)
+}
 
+// ExpireLeases implements lease.LeaseStore.
+func (p *LeasePersistor) ExpireLeases(expiredBy time.Time) error {
+
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		docs, err := p.leaseDocs(bson.D{{"expiration", bson.D{{"$lte", expiredBy}}}})
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if len(docs) == 0 {
+			return nil, jujutxn.ErrNoOperations
+		}
+		ops := make([]txn.Op, len(docs))
+		for i, doc := range docs {
+			ops[i] = txn.Op{
+				C:      p.collectionName,
+				Id:     doc.DocID,
+				Assert: bson.D{{"txn-revno", doc.TxnRevno}},
+				Remove: true,
+			}
+		}
+		return ops, nil
+	}
+	if err := p.run(buildTxn); err != nil {
+		return errors.Annotate(err, "could not expire leases")
+	}
 	return nil
 }
 
-// RemoveToken removes the lease token with the given ID from the data
-// store.
-func (p *LeasePersistor) RemoveToken(id string) error {
-
-	ops := []txn.Op{{C: p.collectionName, Id: id, Remove: true}}
-	if err := p.runTransaction(ops); err != nil {
-		return errors.Annotatef(err, `could not remove token "%s"`, id)
+// Leases implements lease.LeaseStore.
+func (p *LeasePersistor) Leases() ([]lease.Token, error) {
+	docs, err := p.leaseDocs(nil)
+	if err != nil {
+		return nil, errors.Annotate(err, "could not retrieve leases")
+	}
+	tokens := make([]lease.Token, len(docs))
+	for i, doc := range docs {
+		tokens[i] = doc.token(p.allEnvironments)
 	}
+	return tokens, nil
+}
 
-	return nil
+// WatchLeases implements lease.LeaseStore.
+func (p *LeasePersistor) WatchLeases() lease.Watcher {
+	return p.watchCollection()
 }
 
-// PersistedTokens retrieves all tokens currently persisted.
-func (p *LeasePersistor) PersistedTokens() (tokens []lease.Token, _ error) {
+// newLeaseDoc returns the document to insert to record the given
+// token. Documents inserted into an environment's own store have their
+// id and environment UUID filled in by its transaction runner.
+func (p *LeasePersistor) newLeaseDoc(tok lease.Token) (*leaseDoc, error) {
+	doc := &leaseDoc{
+		Namespace:  tok.Namespace,
+		Holder:     tok.Id,
+		Expiration: tok.Expiration,
+	}
+	if p.allEnvironments {
+		parts := strings.SplitN(tok.Namespace, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.NotValidf("lease namespace %q without environment UUID", tok.Namespace)
+		}
+		doc.DocID = tok.Namespace
+		doc.EnvUUID = parts[0]
+		doc.Namespace = parts[1]
+	}
+	return doc, nil
+}
 
+func (p *LeasePersistor) leaseDoc(namespace string) (*leaseDoc, error) {
 	collection, closer := p.getCollection(p.collectionName)
 	defer closer()
 
-	// Pipeline entities into tokens.
-	var query bson.D
-	iter := collection.Find(query).Iter()
-	defer iter.Close()
-
-	var doc leaseEntity
-	for iter.Next(&doc) {
-		tokens = append(tokens, doc.Token)
+	var doc leaseDoc
+	if err := collection.FindId(namespace).One(&doc); err == mgo.ErrNotFound {
+		return nil, errors.NotFoundf("lease for %q", namespace)
+	} else if err != nil {
+		return nil, errors.Annotatef(err, "cannot get lease for %q", namespace)
 	}
+	return &doc, nil
+}
 
-	if err := iter.Err(); err != nil {
-		return nil, errors.Annotate(err, "could not retrieve all persisted tokens")
-	}
+func (p *LeasePersistor) leaseDocs(query bson.D) ([]leaseDoc, error) {
+	collection, closer := p.getCollection(p.collectionName)
+	defer closer()
 
-	return tokens, nil
+	var docs []leaseDoc
+	if err := collection.Find(query).All(&docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
 }
@@ -6,106 +6,262 @@ package state
 import (
 	"time"
 
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	gitjujutesting "github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
-	"gopkg.in/mgo.v2/txn"
 
 	"github.com/juju/juju/lease"
+	"github.com/juju/juju/testing"
 )
 
 const (
-	testCollectionName = "test collection"
-	testNamespace      = "leadership-stub-service"
-	testId             = "stub-unit/0"
-	testDuration       = 30 * time.Hour
+	testNamespace = "leadership-stub-service"
+	testId        = "stub-unit/0"
+	otherId       = "stub-unit/1"
+	testDuration  = 30 * time.Second
 )
 
 var (
 	_ = gc.Suite(&leaseSuite{})
 )
 
-//
-// Stub functions for when we don't care.
-//
+type leaseSuite struct {
+	testing.BaseSuite
+	gitjujutesting.MgoSuite
+	State *State
+	now   time.Time
+}
 
-func stubRunTransaction(ops []txn.Op) error {
-	return nil
+func (s *leaseSuite) SetUpSuite(c *gc.C) {
+	s.BaseSuite.SetUpSuite(c)
+	s.MgoSuite.SetUpSuite(c)
 }
 
-func stubGetCollection(collectionName string) (stateCollection, func()) {
-	return &genericStateCollection{}, func() {}
+func (s *leaseSuite) TearDownSuite(c *gc.C) {
+	s.MgoSuite.TearDownSuite(c)
+	s.BaseSuite.TearDownSuite(c)
 }
 
-type leaseSuite struct{}
+func (s *leaseSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	s.MgoSuite.SetUpTest(c)
+	owner := names.NewLocalUserTag("test-admin")
+	st, err := Initialize(owner, TestingMongoInfo(), testing.EnvironConfig(c), TestingDialOpts(), nil)
+	c.Assert(err, jc.ErrorIsNil)
+	s.State = st
+	// Mongo only stores times to the millisecond.
+	s.now = time.Now().Round(time.Second)
+}
 
-func (s *leaseSuite) TestWriteToken(c *gc.C) {
+func (s *leaseSuite) TearDownTest(c *gc.C) {
+	if s.State != nil {
+		s.State.Close()
+	}
+	s.MgoSuite.TearDownTest(c)
+	s.BaseSuite.TearDownTest(c)
+}
 
-	tok := lease.Token{testNamespace, testId, time.Now().Add(testDuration)}
+func (s *leaseSuite) token(id string, expiration time.Time) lease.Token {
+	return lease.Token{testNamespace, id, expiration}
+}
 
-	stubRunTransaction := func(ops []txn.Op) error {
-		c.Assert(ops, gc.HasLen, 2)
+func (s *leaseSuite) assertLeases(c *gc.C, expected ...lease.Token) {
+	tokens, err := s.State.Leases()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(tokens, gc.HasLen, len(expected))
+	for i, tok := range tokens {
+		c.Check(tok.Namespace, gc.Equals, expected[i].Namespace)
+		c.Check(tok.Id, gc.Equals, expected[i].Id)
+		c.Check(tok.Expiration.Equal(expected[i].Expiration), jc.IsTrue)
+	}
+}
 
-		// First delete.
-		c.Check(ops[0].C, gc.Equals, testCollectionName)
-		c.Check(ops[0].Remove, gc.Equals, true)
-		c.Check(ops[0].Id, gc.Equals, testId)
+func (s *leaseSuite) TestClaimLease(c *gc.C) {
+	tok := s.token(testId, s.now.Add(testDuration))
+	holder, err := s.State.ClaimLease(tok, s.now)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(holder, gc.DeepEquals, tok)
+	s.assertLeases(c, tok)
+}
 
-		// Then insert.
-		c.Check(ops[1].Assert, gc.Equals, txn.DocMissing)
-		c.Check(ops[1].C, gc.Equals, testCollectionName)
-		c.Check(ops[1].Insert.(leaseEntity).Token, gc.DeepEquals, tok)
-		c.Check(ops[1].Id, gc.Equals, testId)
+func (s *leaseSuite) TestClaimLeaseHeldByOther(c *gc.C) {
+	tok := s.token(testId, s.now.Add(testDuration))
+	_, err := s.State.ClaimLease(tok, s.now)
+	c.Assert(err, jc.ErrorIsNil)
 
-		return nil
-	}
+	holder, err := s.State.ClaimLease(s.token(otherId, s.now.Add(testDuration)), s.now)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(holder.Id, gc.Equals, testId)
+	s.assertLeases(c, tok)
+}
+
+func (s *leaseSuite) TestClaimLeaseExtends(c *gc.C) {
+	_, err := s.State.ClaimLease(s.token(testId, s.now.Add(testDuration)), s.now)
+	c.Assert(err, jc.ErrorIsNil)
+
+	extended := s.token(testId, s.now.Add(2*testDuration))
+	holder, err := s.State.ClaimLease(extended, s.now)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(holder, gc.DeepEquals, extended)
+	s.assertLeases(c, extended)
+}
 
-	persistor := NewLeasePersistor(testCollectionName, stubRunTransaction, stubGetCollection)
+func (s *leaseSuite) TestClaimLeaseExpired(c *gc.C) {
+	_, err := s.State.ClaimLease(s.token(testId, s.now.Add(testDuration)), s.now)
+	c.Assert(err, jc.ErrorIsNil)
 
-	err := persistor.WriteToken(testId, tok)
+	// The lease has expired, but not by more than the tolerance the
+	// caller allows for clock skew.
+	later := s.now.Add(testDuration + time.Second)
+	other := s.token(otherId, later.Add(testDuration))
+	holder, err := s.State.ClaimLease(other, later.Add(-lease.ClockSkewTolerance))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(holder.Id, gc.Equals, testId)
 
-	c.Assert(err, gc.IsNil)
+	later = later.Add(lease.ClockSkewTolerance)
+	holder, err = s.State.ClaimLease(other, later.Add(-lease.ClockSkewTolerance))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(holder, gc.DeepEquals, other)
+	s.assertLeases(c, other)
 }
 
-func (s *leaseSuite) TestRemoveToken(c *gc.C) {
+func (s *leaseSuite) TestClaimLeaseUnlessHeld(c *gc.C) {
+	blocker := lease.Token{"blocker", otherId, s.now.Add(testDuration)}
+	_, err := s.State.ClaimLease(blocker, s.now)
+	c.Assert(err, jc.ErrorIsNil)
 
-	stubRunTransaction := func(ops []txn.Op) error {
-		c.Assert(ops, gc.HasLen, 1)
+	tok := s.token(testId, s.now.Add(testDuration))
+	holder, err := s.State.ClaimLeaseUnlessHeld(tok, "blocker", s.now)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(holder.Id, gc.Equals, "")
+	s.assertLeases(c, blocker)
 
-		c.Check(ops[0].C, gc.Equals, testCollectionName)
-		c.Check(ops[0].Remove, gc.Equals, true)
-		c.Check(ops[0].Id, gc.Equals, testId)
+	err = s.State.ReleaseLease("blocker", otherId)
+	c.Assert(err, jc.ErrorIsNil)
+	holder, err = s.State.ClaimLeaseUnlessHeld(tok, "blocker", s.now)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(holder, gc.DeepEquals, tok)
+	s.assertLeases(c, tok)
+}
 
-		return nil
-	}
+func (s *leaseSuite) TestClaimLeaseUnlessHeldBlockedConcurrently(c *gc.C) {
+	blocker := lease.Token{"blocker", otherId, s.now.Add(testDuration)}
+	defer SetBeforeHooks(c, s.State, func() {
+		_, err := s.State.ClaimLease(blocker, s.now)
+		c.Assert(err, jc.ErrorIsNil)
+	}).Check()
+
+	tok := s.token(testId, s.now.Add(testDuration))
+	holder, err := s.State.ClaimLeaseUnlessHeld(tok, "blocker", s.now)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(holder.Id, gc.Equals, "")
+	s.assertLeases(c, blocker)
+}
 
-	persistor := NewLeasePersistor(testCollectionName, stubRunTransaction, stubGetCollection)
-	err := persistor.RemoveToken(testId)
+func (s *leaseSuite) TestReleaseLease(c *gc.C) {
+	_, err := s.State.ClaimLease(s.token(testId, s.now.Add(testDuration)), s.now)
+	c.Assert(err, jc.ErrorIsNil)
 
-	c.Assert(err, gc.IsNil)
+	err = s.State.ReleaseLease(testNamespace, testId)
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertLeases(c)
 }
 
-func (s *leaseSuite) TestPersistedTokens(c *gc.C) {
+func (s *leaseSuite) TestReleaseLeaseNotOwner(c *gc.C) {
+	tok := s.token(testId, s.now.Add(testDuration))
+	_, err := s.State.ClaimLease(tok, s.now)
+	c.Assert(err, jc.ErrorIsNil)
 
-	closerCallCount := 0
-	stubGetCollection := func(collectionName string) (stateCollection, func()) {
-		c.Check(collectionName, gc.Equals, testCollectionName)
-		return &genericStateCollection{}, func() { closerCallCount++ }
+	err = s.State.ReleaseLease(testNamespace, otherId)
+	c.Assert(errors.Cause(err), gc.Equals, lease.NotLeaseOwnerErr)
+	s.assertLeases(c, tok)
+
+	err = s.State.ReleaseLease("no-such-namespace", testId)
+	c.Assert(errors.Cause(err), gc.Equals, lease.NotLeaseOwnerErr)
+}
+
+func (s *leaseSuite) TestExpireLeases(c *gc.C) {
+	short := lease.Token{"short", testId, s.now.Add(time.Second)}
+	long := lease.Token{"long", testId, s.now.Add(testDuration)}
+	for _, tok := range []lease.Token{short, long} {
+		_, err := s.State.ClaimLease(tok, s.now)
+		c.Assert(err, jc.ErrorIsNil)
 	}
 
-	persistor := NewLeasePersistor(testCollectionName, stubRunTransaction, stubGetCollection)
+	err := s.State.ExpireLeases(s.now.Add(2 * time.Second))
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertLeases(c, long)
+}
 
-	// PersistedTokens will panic when it tries to use the empty collection.
-	defer func() {
-		r := recover()
-		c.Assert(r, gc.NotNil)
+func (s *leaseSuite) TestWatchLeases(c *gc.C) {
+	w := s.State.WatchLeases()
+	defer func() { c.Check(w.Stop(), jc.ErrorIsNil) }()
 
-		switch closerCallCount {
-		case 1:
-		case 0:
-			c.Errorf("The closer for the collection was never called.")
-		default:
-			c.Errorf("The closer for the collection was called too many times (%d).", closerCallCount)
+	assertChange := func() {
+		s.State.StartSync()
+		select {
+		case _, ok := <-w.Changes():
+			c.Assert(ok, jc.IsTrue)
+		case <-time.After(testing.LongWait):
+			c.Fatalf("watcher did not send change")
 		}
-	}()
+	}
+	assertNoChange := func() {
+		s.State.StartSync()
+		select {
+		case <-w.Changes():
+			c.Fatalf("watcher sent unexpected change")
+		case <-time.After(testing.ShortWait):
+		}
+	}
+
+	// Initial event.
+	assertChange()
+	assertNoChange()
+
+	_, err := s.State.ClaimLease(s.token(testId, s.now.Add(testDuration)), s.now)
+	c.Assert(err, jc.ErrorIsNil)
+	assertChange()
+	assertNoChange()
+
+	err = s.State.ReleaseLease(testNamespace, testId)
+	c.Assert(err, jc.ErrorIsNil)
+	assertChange()
+	assertNoChange()
+}
+
+func (s *leaseSuite) TestAllEnvironmentsLeaseStore(c *gc.C) {
+	store := s.State.AllEnvironmentsLeaseStore()
+	namespace := EnvironLeaseNamespace(s.State.EnvironUUID(), testNamespace)
+	tok := lease.Token{namespace, testId, s.now.Add(testDuration)}
+	holder, err := store.ClaimLease(tok, s.now)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(holder, gc.Equals, tok)
+
+	// The environment's own store sees the lease without the prefix.
+	s.assertLeases(c, s.token(testId, s.now.Add(testDuration)))
+
+	// A lease with the same namespace in another environment is
+	// distinct.
+	other := lease.Token{EnvironLeaseNamespace("other-uuid", testNamespace), otherId, s.now.Add(testDuration)}
+	holder, err = store.ClaimLease(other, s.now)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(holder, gc.Equals, other)
+	s.assertLeases(c, s.token(testId, s.now.Add(testDuration)))
+
+	tokens, err := store.Leases()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(tokens, gc.HasLen, 2)
+
+	err = store.ReleaseLease(namespace, testId)
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertLeases(c)
+}
 
-	persistor.PersistedTokens()
+func (s *leaseSuite) TestAllEnvironmentsLeaseStoreRequiresEnvironUUID(c *gc.C) {
+	store := s.State.AllEnvironmentsLeaseStore()
+	_, err := store.ClaimLease(s.token(testId, s.now.Add(testDuration)), s.now)
+	c.Assert(err, gc.ErrorMatches, `could not claim lease for "leadership-stub-service": lease namespace "leadership-stub-service" without environment UUID not valid`)
 }
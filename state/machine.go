@@ -177,6 +177,10 @@ type instanceData struct {
 	CpuPower   *uint64     `bson:"cpupower,omitempty"`
 	Tags       *[]string   `bson:"tags,omitempty"`
 	AvailZone  *string     `bson:"availzone,omitempty"`
+
+	// LastPolled records when the instance poller last queried the
+	// provider for the instance's addresses and status.
+	LastPolled time.Time `bson:"lastpolled,omitempty"`
 }
 
 func hardwareCharacteristics(instData instanceData) *instance.HardwareCharacteristics {
@@ -651,6 +655,7 @@ func (m *Machine) Remove() (err error) {
 		removeRequestedNetworksOp(m.st, m.globalKey()),
 		annotationRemoveOp(m.st, m.globalKey()),
 		removeRebootDocOp(m.st, m.globalKey()),
+		removeObservedInterfacesOp(m.st, m.Id()),
 	}
 	ifacesOps, err := m.removeNetworkInterfacesOps()
 	if err != nil {
@@ -664,9 +669,14 @@ func (m *Machine) Remove() (err error) {
 	if err != nil {
 		return err
 	}
+	templateOps, err := removeContainerTemplatesOps(m.st, m.Id())
+	if err != nil {
+		return err
+	}
 	ops = append(ops, ifacesOps...)
 	ops = append(ops, portsOps...)
 	ops = append(ops, blockDeviceOps...)
+	ops = append(ops, templateOps...)
 	ops = append(ops, removeContainerRefOps(m.st, m.Id())...)
 	// The only abort conditions in play indicate that the machine has already
 	// been removed.
@@ -788,6 +798,39 @@ func (m *Machine) SetInstanceStatus(status string) (err error) {
 	return errors.NotProvisionedf("machine %v", m.Id())
 }
 
+// InstancePolled returns when the provider was last queried for the
+// addresses and status of the machine's instance, or the zero time if
+// it has not been queried since the instance was provisioned.
+func (m *Machine) InstancePolled() (time.Time, error) {
+	instData, err := getInstanceData(m.st, m.Id())
+	if errors.IsNotFound(err) {
+		err = errors.NotProvisionedf("machine %v", m.Id())
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return instData.LastPolled, nil
+}
+
+// SetInstancePolled records when the provider was queried for the
+// addresses and status of the machine's instance.
+func (m *Machine) SetInstancePolled(polled time.Time) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot set instance poll time for machine %q", m)
+
+	ops := []txn.Op{{
+		C:      instanceDataC,
+		Id:     m.doc.DocID,
+		Assert: txn.DocExists,
+		Update: bson.D{{"$set", bson.D{{"lastpolled", polled.UTC().Truncate(time.Millisecond)}}}},
+	}}
+	if err = m.st.runTransaction(ops); err == nil {
+		return nil
+	} else if err != txn.ErrAborted {
+		return err
+	}
+	return errors.NotProvisionedf("machine %v", m.Id())
+}
+
 // AvailabilityZone returns the provier-specific instance availability
 // zone in which the machine was provisioned.
 func (m *Machine) AvailabilityZone() (string, error) {
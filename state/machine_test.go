@@ -945,6 +945,28 @@ func (s *MachineSuite) TestNotProvisionedMachineInstanceStatus(c *gc.C) {
 	c.Assert(err, jc.Satisfies, errors.IsNotProvisioned)
 }
 
+func (s *MachineSuite) TestMachineSetInstancePolled(c *gc.C) {
+	err := s.machine.SetProvisioned("umbrella/0", "fake_nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	polled, err := s.machine.InstancePolled()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(polled.IsZero(), jc.IsTrue)
+
+	now := time.Now().Truncate(time.Millisecond)
+	err = s.machine.SetInstancePolled(now)
+	c.Assert(err, jc.ErrorIsNil)
+	polled, err = s.machine.InstancePolled()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(polled.Equal(now), jc.IsTrue)
+}
+
+func (s *MachineSuite) TestNotProvisionedMachineInstancePolled(c *gc.C) {
+	err := s.machine.SetInstancePolled(time.Now())
+	c.Assert(err, gc.ErrorMatches, ".* not provisioned")
+	_, err = s.machine.InstancePolled()
+	c.Assert(err, jc.Satisfies, errors.IsNotProvisioned)
+}
+
 func (s *MachineSuite) TestMachineRefresh(c *gc.C) {
 	m0, err := s.State.AddMachine("quantal", state.JobHostUnits)
 	c.Assert(err, jc.ErrorIsNil)
@@ -0,0 +1,845 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	jujutxn "github.com/juju/txn"
+	"github.com/juju/utils/set"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/environs/config"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/version"
+)
+
+// EnvironmentExport holds the model of an environment exported from
+// one state server, to be imported into another.
+type EnvironmentExport struct {
+	// UUID, Name and Owner identify the environment, which keeps its
+	// UUID in the state server it is imported into.
+	UUID  string
+	Name  string
+	Owner string
+
+	// Model holds the environment's documents, encoded as BSON.
+	Model []byte
+
+	// Charms holds the storage paths of the environment's charm
+	// archives, and Tools the versions of the tools stored for the
+	// environment. Their contents are not part of the model, and are
+	// copied separately.
+	Charms []string
+	Tools  []version.Binary
+}
+
+// exportedModel is the decoded form of EnvironmentExport.Model.
+type exportedModel struct {
+	EnvUsers    []bson.D            `bson:"envusers"`
+	Collections map[string][]bson.D `bson:"collections"`
+}
+
+// envDocCollections holds the collections that are not in
+// multiEnvCollections but whose documents each belong to a single
+// environment, identified by their env-uuid field.
+var envDocCollections = set.NewStrings(
+	actionresultsC,
+	ipaddressesC,
+	metricsC,
+)
+
+// environmentCollections returns the names of all the collections
+// holding documents that belong to a single environment, and so make
+// up its model.
+func environmentCollections() []string {
+	return multiEnvCollections.Union(envDocCollections).SortedValues()
+}
+
+// importBatchSize is the number of documents inserted or removed in
+// each transaction when importing or discarding an environment.
+const importBatchSize = 100
+
+// serverConfigAttrs holds the environment settings that belong to the
+// state server running the environment rather than to the environment
+// itself; they are taken from the importing state server's environment.
+var serverConfigAttrs = []string{
+	"ca-cert",
+	"ca-private-key",
+	"state-port",
+	"api-port",
+	"syslog-port",
+	"rsyslog-ca-cert",
+}
+
+// ExportEnvironment exports the model of the environment: its services,
+// units, machines with their instance ids, relations, settings, charms
+// and tools, so that it can be imported into another state server.
+// The state server environment, and environments related to others
+// hosted by the same state server, cannot be exported.
+func (st *State) ExportEnvironment() (_ *EnvironmentExport, err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot export environment")
+	env, err := st.Environment()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if env.Life() != Alive {
+		return nil, errors.Errorf("environment is no longer alive")
+	}
+	if env.UUID() == env.ServerTag().Id() {
+		return nil, errors.Errorf("the state server environment cannot be migrated")
+	}
+	// The environment is exported once its migration has been
+	// started, and again if the copy to the target is restarted.
+	if phase, err := st.MigrationPhase(); err == nil && phase != MigrationCopying {
+		return nil, errors.Errorf("environment is already migrating")
+	} else if err != nil && !errors.IsNotFound(err) {
+		return nil, errors.Trace(err)
+	}
+	if remotes, err := st.AllRemoteServices(); err != nil {
+		return nil, errors.Trace(err)
+	} else if len(remotes) > 0 {
+		return nil, errors.Errorf("environment has relations with other environments")
+	}
+	if offers, err := st.Offers(); err != nil {
+		return nil, errors.Trace(err)
+	} else if len(offers) > 0 {
+		return nil, errors.Errorf("environment offers endpoints to other environments")
+	}
+
+	model := exportedModel{Collections: make(map[string][]bson.D)}
+	for _, name := range environmentCollections() {
+		docs, err := st.exportDocs(name, bson.D{{"env-uuid", env.UUID()}})
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if len(docs) > 0 {
+			model.Collections[name] = docs
+		}
+	}
+	model.EnvUsers, err = st.exportDocs(envUsersC, bson.D{{"envuuid", env.UUID()}})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	data, err := bson.Marshal(model)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	export := &EnvironmentExport{
+		UUID:  env.UUID(),
+		Name:  env.Name(),
+		Owner: env.Owner().Username(),
+		Model: data,
+	}
+	charms, closer := st.getCollection(charmsC)
+	defer closer()
+	var charmDocs []charmDoc
+	if err := charms.Find(nil).Select(bson.D{{"storagepath", 1}}).All(&charmDocs); err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, doc := range charmDocs {
+		if doc.StoragePath != "" {
+			export.Charms = append(export.Charms, doc.StoragePath)
+		}
+	}
+	storage, err := st.ToolsStorage()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer storage.Close()
+	metadata, err := storage.AllMetadata()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, m := range metadata {
+		export.Tools = append(export.Tools, m.Version)
+	}
+	return export, nil
+}
+
+// exportDocs returns the documents of the named collection that match
+// sel, without the fields maintained by the transaction runner.
+func (st *State) exportDocs(collName string, sel bson.D) ([]bson.D, error) {
+	coll, closer := st.getRawCollection(collName)
+	defer closer()
+
+	var docs []bson.D
+	if err := coll.Find(sel).All(&docs); err != nil {
+		return nil, errors.Annotatef(err, "cannot read %s", collName)
+	}
+	for i, doc := range docs {
+		var stripped bson.D
+		for _, elem := range doc {
+			switch elem.Name {
+			case "txn-revno", "txn-queue":
+				continue
+			}
+			stripped = append(stripped, elem)
+		}
+		docs[i] = stripped
+	}
+	return docs, nil
+}
+
+// ImportEnvironment adds an environment exported from another state
+// server to the state server, keeping its UUID, and returns it along
+// with a State for it. The environment's owner must be a user of the
+// state server. Settings that belong to the state server, such as its
+// CA certificate, are replaced with those of this state server, and any
+// blocks are removed. The environment's charm archives and tools must
+// be copied separately.
+func (st *State) ImportEnvironment(export *EnvironmentExport) (_ *Environment, _ *State, err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot import environment %q", export.Name)
+	if !names.IsValidEnvironment(export.UUID) {
+		return nil, nil, errors.NotValidf("environment UUID %q", export.UUID)
+	}
+	owner := names.NewUserTag(export.Owner)
+	if owner.IsLocal() {
+		if _, err := st.User(owner); err != nil {
+			return nil, nil, errors.Annotate(err, "owner must be a user of the state server")
+		}
+	}
+	if _, err := st.GetEnvironment(names.NewEnvironTag(export.UUID)); err == nil {
+		return nil, nil, errors.AlreadyExistsf("environment")
+	} else if !errors.IsNotFound(err) {
+		return nil, nil, errors.Trace(err)
+	}
+	var model exportedModel
+	if err := bson.Unmarshal(export.Model, &model); err != nil {
+		return nil, nil, errors.Annotate(err, "invalid model")
+	}
+	if err := checkImportedModel(export.UUID, &model); err != nil {
+		return nil, nil, errors.Annotate(err, "invalid model")
+	}
+	ssEnv, err := st.StateServerEnvironment()
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	serverConfig, err := st.serverEnvironConfig(ssEnv)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+
+	newState, err := st.ForEnviron(names.NewEnvironTag(export.UUID))
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	defer func() {
+		if err != nil {
+			if err := newState.DiscardEnvironment(); err != nil {
+				logger.Errorf("cannot discard partly imported environment %q: %v", export.Name, err)
+			}
+			newState.Close()
+		}
+	}()
+	var ops []txn.Op
+	for _, name := range environmentCollections() {
+		for _, doc := range model.Collections[name] {
+			id, err := docIdValue(doc)
+			if err != nil {
+				return nil, nil, errors.Trace(err)
+			}
+			if name == settingsC && id == newState.docID(environGlobalKey) {
+				doc = importedEnvironConfig(doc, serverConfig)
+			}
+			ops = append(ops, txn.Op{
+				C:      name,
+				Id:     id,
+				Assert: txn.DocMissing,
+				Insert: doc,
+			})
+		}
+	}
+	for _, doc := range model.EnvUsers {
+		id, err := docIdValue(doc)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		ops = append(ops, txn.Op{
+			C:      envUsersC,
+			Id:     id,
+			Assert: txn.DocMissing,
+			Insert: doc,
+		})
+	}
+	// The environment document is inserted last, so that the state
+	// server only starts running the environment's workers once the
+	// rest of its model is in place.
+	for len(ops) > importBatchSize {
+		if err := newState.runTransaction(ops[:importBatchSize]); err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		ops = ops[importBatchSize:]
+	}
+	ops = append(ops,
+		createEnvironmentOp(newState, owner, export.Name, export.UUID, ssEnv.UUID()),
+		txn.Op{
+			C:      importsC,
+			Id:     export.UUID,
+			Assert: txn.DocMissing,
+			Insert: &importDoc{DocID: export.UUID},
+		},
+	)
+	if err := newState.runTransaction(ops); err == txn.ErrAborted {
+		return nil, nil, errors.Errorf("environment already exists")
+	} else if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	env, err := newState.Environment()
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	return env, newState, nil
+}
+
+// serverEnvironConfig returns the settings of the state server
+// environment.
+func (st *State) serverEnvironConfig(ssEnv *Environment) (*config.Config, error) {
+	if st.EnvironUUID() == ssEnv.UUID() {
+		return st.EnvironConfig()
+	}
+	ssState, err := st.ForEnviron(ssEnv.EnvironTag())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer ssState.Close()
+	return ssState.EnvironConfig()
+}
+
+// importedEnvironConfig returns the settings document of an imported
+// environment, with the state server settings of the importing state
+// server and without blocks.
+func importedEnvironConfig(doc bson.D, serverConfig *config.Config) bson.D {
+	serverAttrs := serverConfig.AllAttrs()
+	var result bson.D
+	seen := make(map[string]bool)
+	for _, elem := range doc {
+		if strings.HasPrefix(elem.Name, config.BlockKeyPrefix) {
+			continue
+		}
+		for _, key := range serverConfigAttrs {
+			if elem.Name == key {
+				elem.Value = serverAttrs[key]
+				seen[key] = true
+			}
+		}
+		if elem.Value != nil {
+			result = append(result, elem)
+		}
+	}
+	for _, key := range serverConfigAttrs {
+		if value, ok := serverAttrs[key]; ok && !seen[key] {
+			result = append(result, bson.DocElem{key, value})
+		}
+	}
+	return result
+}
+
+// checkImportedModel returns an error unless every document in the
+// model belongs to the environment with the given UUID, so that an
+// import cannot add to or replace the documents of other environments.
+func checkImportedModel(uuid string, model *exportedModel) error {
+	known := set.NewStrings(environmentCollections()...)
+	for name, docs := range model.Collections {
+		if !known.Contains(name) {
+			return errors.Errorf("unexpected collection %q", name)
+		}
+		for _, doc := range docs {
+			if err := checkImportedDoc(uuid, doc, "env-uuid", multiEnvCollections.Contains(name)); err != nil {
+				return errors.Annotatef(err, "invalid %s document", name)
+			}
+		}
+	}
+	for _, doc := range model.EnvUsers {
+		if err := checkImportedDoc(uuid, doc, "envuuid", true); err != nil {
+			return errors.Annotate(err, "invalid environment user document")
+		}
+	}
+	return nil
+}
+
+// checkImportedDoc returns an error unless the document's envField
+// holds the given environment UUID and, if prefixedId is true, its _id
+// is prefixed with the UUID.
+func checkImportedDoc(uuid string, doc bson.D, envField string, prefixedId bool) error {
+	id, err := docIdValue(doc)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	var docUUID interface{}
+	for _, elem := range doc {
+		if elem.Name == envField {
+			docUUID = elem.Value
+		}
+	}
+	if docUUID != uuid {
+		return errors.Errorf("document %v belongs to environment %v", id, docUUID)
+	}
+	if prefixedId {
+		if s, ok := id.(string); !ok || !strings.HasPrefix(s, uuid+":") {
+			return errors.Errorf("document id %v is not in environment %q", id, uuid)
+		}
+	}
+	return nil
+}
+
+// docIdValue returns the _id of the document.
+func docIdValue(doc bson.D) (interface{}, error) {
+	for _, elem := range doc {
+		if elem.Name == "_id" {
+			return elem.Value, nil
+		}
+	}
+	return nil, errors.New("document has no _id")
+}
+
+// DiscardEnvironment removes all the documents of the environment,
+// which must not be the state server environment. It is used to remove
+// an environment once it has been migrated to another state server, or
+// when its import into this state server is rolled back; the machines
+// and units of the environment are left running, to be managed by the
+// other state server.
+func (st *State) DiscardEnvironment() (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot discard environment")
+	ssEnv, err := st.StateServerEnvironment()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	uuid := st.EnvironUUID()
+	if uuid == ssEnv.UUID() {
+		return errors.Errorf("the state server environment cannot be discarded")
+	}
+	// The environment document is removed first, so the state server
+	// stops the environment's workers before the rest of it goes.
+	ops := []txn.Op{{
+		C:      environmentsC,
+		Id:     uuid,
+		Remove: true,
+	}}
+	if err := st.runTransaction(ops); err != nil {
+		return errors.Trace(err)
+	}
+	ops = nil
+	for _, name := range environmentCollections() {
+		docs, err := st.exportDocs(name, bson.D{{"env-uuid", uuid}})
+		if err != nil {
+			return errors.Trace(err)
+		}
+		for _, doc := range docs {
+			id, err := docIdValue(doc)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			ops = append(ops, txn.Op{C: name, Id: id, Remove: true})
+		}
+	}
+	envUsers, err := st.exportDocs(envUsersC, bson.D{{"envuuid", uuid}})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, doc := range envUsers {
+		id, err := docIdValue(doc)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		ops = append(ops, txn.Op{C: envUsersC, Id: id, Remove: true})
+	}
+	ops = append(ops,
+		txn.Op{C: migrationsC, Id: uuid, Remove: true},
+		txn.Op{C: importsC, Id: uuid, Remove: true},
+	)
+	for len(ops) > 0 {
+		n := importBatchSize
+		if n > len(ops) {
+			n = len(ops)
+		}
+		if err := st.runTransaction(ops[:n]); err != nil {
+			return errors.Trace(err)
+		}
+		ops = ops[n:]
+	}
+	return nil
+}
+
+// MigrationTarget holds the addresses and CA certificate of the API
+// servers of the state server that an environment's agents should
+// connect to, in place of their current state server's.
+type MigrationTarget struct {
+	APIHostPorts [][]network.HostPort
+	CACert       string
+}
+
+// MigrationPhase describes how far the migration of an environment to
+// another state server has got. It is recorded in state, so that an
+// interrupted migration can be resumed or rolled back.
+type MigrationPhase string
+
+const (
+	// MigrationCopying means the environment is being copied to the
+	// target state server; its agents have not been redirected.
+	MigrationCopying MigrationPhase = "copying"
+
+	// MigrationRedirecting means the agents of the environment are
+	// being redirected to the target state server.
+	MigrationRedirecting MigrationPhase = "redirecting"
+
+	// MigrationRollingBack means the migration has failed, and the
+	// agents that moved to the target state server are being brought
+	// back.
+	MigrationRollingBack MigrationPhase = "rolling-back"
+)
+
+// migrationDoc records the migration of an environment to another
+// state server, and the target its agents are redirected to, if any.
+type migrationDoc struct {
+	DocID        string         `bson:"_id"`
+	Phase        MigrationPhase `bson:"phase"`
+	APIHostPorts [][]hostPort   `bson:"apihostports,omitempty"`
+	CACert       string         `bson:"cacert,omitempty"`
+	Started      time.Time      `bson:"started"`
+}
+
+// StartMigration records that the environment is being copied to
+// another state server, before its agents are redirected there. It
+// returns an error satisfying errors.IsAlreadyExists if the
+// environment is already migrating.
+func (st *State) StartMigration() (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot start migration")
+	env, err := st.Environment()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if env.Life() != Alive {
+		return errors.Errorf("environment is no longer alive")
+	}
+	if env.UUID() == env.ServerTag().Id() {
+		return errors.Errorf("the state server environment cannot be migrated")
+	}
+	ops := []txn.Op{{
+		C:      migrationsC,
+		Id:     env.UUID(),
+		Assert: txn.DocMissing,
+		Insert: &migrationDoc{
+			DocID:   env.UUID(),
+			Phase:   MigrationCopying,
+			Started: nowToTheSecond(),
+		},
+	}}
+	if err := st.runTransaction(ops); err == txn.ErrAborted {
+		return errors.AlreadyExistsf("migration of environment %q", env.Name())
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// MigrationPhase returns the phase of the environment's migration. It
+// returns an error satisfying errors.IsNotFound if the environment is
+// not migrating.
+func (st *State) MigrationPhase() (MigrationPhase, error) {
+	doc, err := st.migrationDoc()
+	if err != nil {
+		return "", err
+	}
+	return doc.Phase, nil
+}
+
+// SetMigrationTarget redirects the agents of the environment to the
+// API servers of another state server, replacing any previous target.
+// The environment must not be the state server environment, and its
+// migration must not be being rolled back.
+func (st *State) SetMigrationTarget(target MigrationTarget) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot set migration target")
+	if len(target.APIHostPorts) == 0 {
+		return errors.Errorf("no API addresses")
+	}
+	if target.CACert == "" {
+		return errors.Errorf("no CA certificate")
+	}
+	env, err := st.Environment()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if env.UUID() == env.ServerTag().Id() {
+		return errors.Errorf("the state server environment cannot be migrated")
+	}
+	doc := migrationDoc{
+		DocID:        env.UUID(),
+		Phase:        MigrationRedirecting,
+		APIHostPorts: instanceHostPortsToHostPorts(target.APIHostPorts),
+		CACert:       target.CACert,
+		Started:      nowToTheSecond(),
+	}
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		current, err := st.migrationDoc()
+		if errors.IsNotFound(err) {
+			return []txn.Op{{
+				C:      migrationsC,
+				Id:     doc.DocID,
+				Assert: txn.DocMissing,
+				Insert: doc,
+			}}, nil
+		} else if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if current.Phase == MigrationRollingBack {
+			return nil, errors.Errorf("migration is being rolled back")
+		}
+		return []txn.Op{{
+			C:      migrationsC,
+			Id:     doc.DocID,
+			Assert: bson.D{{"phase", current.Phase}},
+			Update: bson.D{{"$set", bson.D{
+				{"phase", doc.Phase},
+				{"apihostports", doc.APIHostPorts},
+				{"cacert", doc.CACert},
+			}}},
+		}}, nil
+	}
+	return st.run(buildTxn)
+}
+
+// RollBackMigration stops redirecting the agents of the environment to
+// the target state server, and records that its migration is being
+// rolled back, so that the rollback is resumed if it is interrupted.
+func (st *State) RollBackMigration() error {
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		doc, err := st.migrationDoc()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if doc.Phase == MigrationRollingBack {
+			return nil, jujutxn.ErrNoOperations
+		}
+		return []txn.Op{{
+			C:      migrationsC,
+			Id:     doc.DocID,
+			Assert: bson.D{{"phase", doc.Phase}},
+			Update: bson.D{
+				{"$set", bson.D{{"phase", MigrationRollingBack}}},
+				{"$unset", bson.D{{"apihostports", nil}, {"cacert", nil}}},
+			},
+		}}, nil
+	}
+	if err := st.run(buildTxn); err != nil {
+		return errors.Annotate(err, "cannot roll back migration")
+	}
+	return nil
+}
+
+// MigrationTarget returns the state server the agents of the
+// environment are being redirected to. It returns an error satisfying
+// errors.IsNotFound if they are not being redirected.
+func (st *State) MigrationTarget() (*MigrationTarget, error) {
+	doc, err := st.migrationDoc()
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.APIHostPorts) == 0 {
+		return nil, errors.NotFoundf("migration target")
+	}
+	return &MigrationTarget{
+		APIHostPorts: hostPortsToInstanceHostPorts(doc.APIHostPorts),
+		CACert:       doc.CACert,
+	}, nil
+}
+
+func (st *State) migrationDoc() (*migrationDoc, error) {
+	migrations, closer := st.getCollection(migrationsC)
+	defer closer()
+
+	var doc migrationDoc
+	err := migrations.FindId(st.EnvironUUID()).One(&doc)
+	if err == mgo.ErrNotFound {
+		return nil, errors.NotFoundf("migration of environment %q", st.EnvironUUID())
+	} else if err != nil {
+		return nil, errors.Annotate(err, "cannot get migration")
+	}
+	return &doc, nil
+}
+
+// ClearMigrationTarget removes the record of the environment's
+// migration, once it has finished or been rolled back, so its agents
+// are no longer redirected to another state server.
+func (st *State) ClearMigrationTarget() error {
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if _, err := st.migrationDoc(); errors.IsNotFound(err) {
+			return nil, jujutxn.ErrNoOperations
+		} else if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return []txn.Op{{
+			C:      migrationsC,
+			Id:     st.EnvironUUID(),
+			Assert: txn.DocExists,
+			Remove: true,
+		}}, nil
+	}
+	if err := st.run(buildTxn); err != nil {
+		return errors.Annotate(err, "cannot clear migration target")
+	}
+	return nil
+}
+
+// AgentAPIHostPorts returns the API addresses the agents of the
+// environment should connect to: those of the state server it is
+// migrating to if there is one, or else those of this state server.
+func (st *State) AgentAPIHostPorts() ([][]network.HostPort, error) {
+	target, err := st.MigrationTarget()
+	if errors.IsNotFound(err) {
+		return st.APIHostPorts()
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return target.APIHostPorts, nil
+}
+
+// AgentCACert returns the CA certificate of the API servers returned by
+// AgentAPIHostPorts.
+func (st *State) AgentCACert() (string, error) {
+	target, err := st.MigrationTarget()
+	if errors.IsNotFound(err) {
+		return st.CACert(), nil
+	} else if err != nil {
+		return "", errors.Trace(err)
+	}
+	return target.CACert, nil
+}
+
+// WatchAgentAPIHostPorts returns a watcher that notifies of changes to
+// the values returned by AgentAPIHostPorts and AgentCACert.
+func (st *State) WatchAgentAPIHostPorts() NotifyWatcher {
+	return newDocWatcher(st, []docKey{
+		{stateServersC, apiHostPortsKey},
+		{migrationsC, st.EnvironUUID()},
+	})
+}
+
+// importDoc records the agents that have logged in to an environment
+// since it was imported, until the import is finished.
+type importDoc struct {
+	DocID     string   `bson:"_id"`
+	CheckedIn []string `bson:"checkedin"`
+}
+
+// RecordAgentCheckIn records that the agent with the given tag has
+// logged in to the environment, if the environment is being imported.
+func (st *State) RecordAgentCheckIn(tag names.Tag) error {
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		checkedIn, err := st.AgentsCheckedIn()
+		if errors.IsNotFound(err) {
+			return nil, jujutxn.ErrNoOperations
+		} else if err != nil {
+			return nil, errors.Trace(err)
+		}
+		for _, checkedInTag := range checkedIn {
+			if checkedInTag == tag.String() {
+				return nil, jujutxn.ErrNoOperations
+			}
+		}
+		return []txn.Op{{
+			C:      importsC,
+			Id:     st.EnvironUUID(),
+			Assert: txn.DocExists,
+			Update: bson.D{{"$addToSet", bson.D{{"checkedin", tag.String()}}}},
+		}}, nil
+	}
+	if err := st.run(buildTxn); err != nil {
+		return errors.Annotatef(err, "cannot record check-in of %s", names.ReadableString(tag))
+	}
+	return nil
+}
+
+// AgentsCheckedIn returns the tags of the agents that have logged in to
+// the environment since it was imported. It returns an error satisfying
+// errors.IsNotFound if the environment is not being imported.
+func (st *State) AgentsCheckedIn() ([]string, error) {
+	imports, closer := st.getCollection(importsC)
+	defer closer()
+
+	var doc importDoc
+	err := imports.FindId(st.EnvironUUID()).One(&doc)
+	if err == mgo.ErrNotFound {
+		return nil, errors.NotFoundf("import of environment %q", st.EnvironUUID())
+	} else if err != nil {
+		return nil, errors.Annotate(err, "cannot get checked in agents")
+	}
+	return doc.CheckedIn, nil
+}
+
+// ExpectedAgents returns the tags of the agents of the environment that
+// should check in once it has been migrated: those of its provisioned
+// machines and of their units.
+func (st *State) ExpectedAgents() ([]string, error) {
+	machines, err := st.AllMachines()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var tags []string
+	for _, m := range machines {
+		if _, err := m.InstanceId(); errors.IsNotProvisioned(err) {
+			continue
+		} else if err != nil {
+			return nil, errors.Trace(err)
+		}
+		tags = append(tags, m.Tag().String())
+		units, err := m.Units()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		for _, u := range units {
+			tags = append(tags, u.Tag().String())
+		}
+	}
+	return tags, nil
+}
+
+// TrackAgentCheckIns starts recording the agents that log in to the
+// environment afresh, as when agents that had moved to another state
+// server are redirected back to this one.
+func (st *State) TrackAgentCheckIns() error {
+	uuid := st.EnvironUUID()
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if _, err := st.AgentsCheckedIn(); errors.IsNotFound(err) {
+			return []txn.Op{{
+				C:      importsC,
+				Id:     uuid,
+				Assert: txn.DocMissing,
+				Insert: &importDoc{DocID: uuid},
+			}}, nil
+		} else if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return []txn.Op{{
+			C:      importsC,
+			Id:     uuid,
+			Assert: txn.DocExists,
+			Update: bson.D{{"$set", bson.D{{"checkedin", []string{}}}}},
+		}}, nil
+	}
+	if err := st.run(buildTxn); err != nil {
+		return errors.Annotate(err, "cannot track agent check-ins")
+	}
+	return nil
+}
+
+// FinishImport stops recording the agents that log in to the
+// environment, once its migration is complete.
+func (st *State) FinishImport() error {
+	ops := []txn.Op{{
+		C:      importsC,
+		Id:     st.EnvironUUID(),
+		Remove: true,
+	}}
+	if err := st.runTransaction(ops); err != nil {
+		return errors.Annotate(err, "cannot finish import")
+	}
+	return nil
+}
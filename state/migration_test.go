@@ -0,0 +1,289 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/environs/config"
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/network"
+	"github.com/juju/juju/state"
+	statetesting "github.com/juju/juju/state/testing"
+	"github.com/juju/juju/testing"
+	"github.com/juju/juju/testing/factory"
+)
+
+type MigrationSuite struct {
+	ConnSuite
+	otherState *state.State
+	machine    *state.Machine
+	unit       *state.Unit
+}
+
+var _ = gc.Suite(&MigrationSuite{})
+
+func (s *MigrationSuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	s.otherState = s.factory.MakeEnvironment(c, &factory.EnvParams{
+		Name:        "hosted",
+		ConfigAttrs: testing.Attrs{config.PreventAllChangesKey: true},
+	})
+	s.AddCleanup(func(*gc.C) { s.otherState.Close() })
+	otherFactory := factory.NewFactory(s.otherState)
+	s.machine = otherFactory.MakeMachine(c, &factory.MachineParams{InstanceId: "i-hosted"})
+	s.unit = otherFactory.MakeUnit(c, &factory.UnitParams{Machine: s.machine})
+}
+
+func (s *MigrationSuite) TestExportImport(c *gc.C) {
+	_, err := s.otherState.AddIPAddress(network.NewAddress("10.0.0.5"), "")
+	c.Assert(err, jc.ErrorIsNil)
+	export, err := s.otherState.ExportEnvironment()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(export.UUID, gc.Equals, s.otherState.EnvironUUID())
+	c.Assert(export.Name, gc.Equals, "hosted")
+	c.Assert(export.Charms, gc.DeepEquals, []string{"fake-storage-path"})
+
+	// The environment can only exist once in a state server, so it is
+	// removed before being imported again.
+	err = s.otherState.SetMigrationTarget(state.MigrationTarget{
+		APIHostPorts: [][]network.HostPort{network.NewHostPorts(17070, "10.0.1.1")},
+		CACert:       "target-cert",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.otherState.DiscardEnvironment()
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.State.GetEnvironment(s.otherState.EnvironTag())
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	_, err = s.otherState.Machine(s.machine.Id())
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	_, err = s.otherState.MigrationTarget()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	_, err = s.otherState.IPAddress("10.0.0.5")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	env, st, err := s.State.ImportEnvironment(export)
+	c.Assert(err, jc.ErrorIsNil)
+	defer st.Close()
+	c.Assert(env.UUID(), gc.Equals, export.UUID)
+	c.Assert(env.Name(), gc.Equals, "hosted")
+	c.Assert(env.ServerTag(), gc.Equals, s.State.EnvironTag())
+
+	m, err := st.Machine(s.machine.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	instId, err := m.InstanceId()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(instId, gc.Equals, instance.Id("i-hosted"))
+	u, err := st.Unit(s.unit.Name())
+	c.Assert(err, jc.ErrorIsNil)
+	machineId, err := u.AssignedMachineId()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(machineId, gc.Equals, s.machine.Id())
+	_, err = st.IPAddress("10.0.0.5")
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Blocks are not carried over.
+	cfg, err := st.EnvironConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	_, blocked := cfg.AllAttrs()[config.PreventAllChangesKey]
+	c.Assert(blocked, jc.IsFalse)
+
+	_, _, err = s.State.ImportEnvironment(export)
+	c.Assert(err, jc.Satisfies, errors.IsAlreadyExists)
+}
+
+func (s *MigrationSuite) TestExportStateServerEnvironment(c *gc.C) {
+	_, err := s.State.ExportEnvironment()
+	c.Assert(err, gc.ErrorMatches, "cannot export environment: the state server environment cannot be migrated")
+}
+
+func (s *MigrationSuite) TestExportMigrating(c *gc.C) {
+	err := s.otherState.SetMigrationTarget(state.MigrationTarget{
+		APIHostPorts: [][]network.HostPort{network.NewHostPorts(17070, "10.0.1.1")},
+		CACert:       "target-cert",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.otherState.ExportEnvironment()
+	c.Assert(err, gc.ErrorMatches, "cannot export environment: environment is already migrating")
+}
+
+func (s *MigrationSuite) TestImportOtherEnvironmentDocuments(c *gc.C) {
+	export, err := s.otherState.ExportEnvironment()
+	c.Assert(err, jc.ErrorIsNil)
+	// The exported documents belong to the original environment, not
+	// the one being imported.
+	export.UUID = "deadbeef-0bad-400d-8000-4b1d0d06f00d"
+	_, _, err = s.State.ImportEnvironment(export)
+	c.Assert(err, gc.ErrorMatches, `cannot import environment "hosted": invalid model: invalid .* document: document .* belongs to environment .*`)
+	_, err = s.State.GetEnvironment(names.NewEnvironTag(export.UUID))
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *MigrationSuite) TestMigrationPhases(c *gc.C) {
+	_, err := s.otherState.MigrationPhase()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	err = s.otherState.StartMigration()
+	c.Assert(err, jc.ErrorIsNil)
+	phase, err := s.otherState.MigrationPhase()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(phase, gc.Equals, state.MigrationCopying)
+	err = s.otherState.StartMigration()
+	c.Assert(err, jc.Satisfies, errors.IsAlreadyExists)
+
+	// The environment can be exported while it is being copied, and
+	// its agents are not redirected yet.
+	_, err = s.otherState.ExportEnvironment()
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.otherState.MigrationTarget()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	target := state.MigrationTarget{
+		APIHostPorts: [][]network.HostPort{network.NewHostPorts(17070, "10.0.1.1")},
+		CACert:       "target-cert",
+	}
+	err = s.otherState.SetMigrationTarget(target)
+	c.Assert(err, jc.ErrorIsNil)
+	phase, err = s.otherState.MigrationPhase()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(phase, gc.Equals, state.MigrationRedirecting)
+
+	err = s.otherState.RollBackMigration()
+	c.Assert(err, jc.ErrorIsNil)
+	phase, err = s.otherState.MigrationPhase()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(phase, gc.Equals, state.MigrationRollingBack)
+	_, err = s.otherState.MigrationTarget()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	addrs, err := s.otherState.AgentAPIHostPorts()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(addrs, gc.Not(jc.DeepEquals), target.APIHostPorts)
+	err = s.otherState.SetMigrationTarget(target)
+	c.Assert(err, gc.ErrorMatches, "cannot set migration target: migration is being rolled back")
+	_, err = s.otherState.ExportEnvironment()
+	c.Assert(err, gc.ErrorMatches, "cannot export environment: environment is already migrating")
+
+	err = s.otherState.ClearMigrationTarget()
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.otherState.MigrationPhase()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *MigrationSuite) TestAgentCheckIns(c *gc.C) {
+	export, err := s.otherState.ExportEnvironment()
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Check-ins are only recorded for imported environments.
+	err = s.otherState.RecordAgentCheckIn(s.machine.Tag())
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.otherState.AgentsCheckedIn()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	err = s.otherState.SetMigrationTarget(state.MigrationTarget{
+		APIHostPorts: [][]network.HostPort{network.NewHostPorts(17070, "10.0.1.1")},
+		CACert:       "target-cert",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.otherState.DiscardEnvironment()
+	c.Assert(err, jc.ErrorIsNil)
+	_, st, err := s.State.ImportEnvironment(export)
+	c.Assert(err, jc.ErrorIsNil)
+	defer st.Close()
+
+	expected, err := st.ExpectedAgents()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(expected, jc.SameContents, []string{s.machine.Tag().String(), s.unit.Tag().String()})
+	checkedIn, err := st.AgentsCheckedIn()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(checkedIn, gc.HasLen, 0)
+
+	for i := 0; i < 2; i++ {
+		err = st.RecordAgentCheckIn(s.unit.Tag())
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	checkedIn, err = st.AgentsCheckedIn()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(checkedIn, gc.DeepEquals, []string{s.unit.Tag().String()})
+
+	err = st.TrackAgentCheckIns()
+	c.Assert(err, jc.ErrorIsNil)
+	checkedIn, err = st.AgentsCheckedIn()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(checkedIn, gc.HasLen, 0)
+
+	err = st.FinishImport()
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = st.AgentsCheckedIn()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *MigrationSuite) TestMigrationTarget(c *gc.C) {
+	hostPorts := [][]network.HostPort{network.NewHostPorts(17070, "10.0.0.1")}
+	err := s.State.SetAPIHostPorts(hostPorts)
+	c.Assert(err, jc.ErrorIsNil)
+
+	addrs, err := s.otherState.AgentAPIHostPorts()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(addrs, jc.DeepEquals, hostPorts)
+	caCert, err := s.otherState.AgentCACert()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(caCert, gc.Equals, s.otherState.CACert())
+
+	w := s.otherState.WatchAgentAPIHostPorts()
+	defer statetesting.AssertStop(c, w)
+	wc := statetesting.NewNotifyWatcherC(c, s.otherState, w)
+	wc.AssertOneChange()
+
+	target := state.MigrationTarget{
+		APIHostPorts: [][]network.HostPort{network.NewHostPorts(17070, "10.0.1.1")},
+		CACert:       "target-cert",
+	}
+	err = s.otherState.SetMigrationTarget(target)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+	got, err := s.otherState.MigrationTarget()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(*got, jc.DeepEquals, target)
+	addrs, err = s.otherState.AgentAPIHostPorts()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(addrs, jc.DeepEquals, target.APIHostPorts)
+	caCert, err = s.otherState.AgentCACert()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(caCert, gc.Equals, "target-cert")
+
+	// Other environments are unaffected.
+	addrs, err = s.State.AgentAPIHostPorts()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(addrs, jc.DeepEquals, hostPorts)
+
+	err = s.otherState.ClearMigrationTarget()
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+	_, err = s.otherState.MigrationTarget()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *MigrationSuite) TestMigrationTargetStateServerEnvironment(c *gc.C) {
+	err := s.State.SetMigrationTarget(state.MigrationTarget{
+		APIHostPorts: [][]network.HostPort{network.NewHostPorts(17070, "10.0.1.1")},
+		CACert:       "target-cert",
+	})
+	c.Assert(err, gc.ErrorMatches, "cannot set migration target: the state server environment cannot be migrated")
+	err = s.State.DiscardEnvironment()
+	c.Assert(err, gc.ErrorMatches, "cannot discard environment: the state server environment cannot be discarded")
+}
+
+func (s *MigrationSuite) TestImportUnknownOwner(c *gc.C) {
+	export, err := s.otherState.ExportEnvironment()
+	c.Assert(err, jc.ErrorIsNil)
+	export.UUID = "deadbeef-0bad-400d-8000-4b1d0d06f00d"
+	export.Owner = "nobody"
+	_, _, err = s.State.ImportEnvironment(export)
+	c.Assert(err, gc.ErrorMatches, `cannot import environment "hosted": owner must be a user of the state server: user "nobody" not found`)
+	_, err = s.State.GetEnvironment(names.NewEnvironTag(export.UUID))
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
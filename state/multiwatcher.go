@@ -20,6 +20,9 @@ import (
 type Multiwatcher struct {
 	all *storeManager
 
+	// filter restricts the deltas returned by Next.
+	filter multiwatcher.Filter
+
 	// The following fields are maintained by the storeManager
 	// goroutine.
 	revno   int64
@@ -34,6 +37,16 @@ func NewMultiwatcher(all *storeManager) *Multiwatcher {
 	}
 }
 
+// NewFilteredMultiwatcher creates a new watcher that can observe
+// changes to an underlying store manager, but which only reports
+// changes to entities matched by filter.
+func NewFilteredMultiwatcher(all *storeManager, filter multiwatcher.Filter) *Multiwatcher {
+	return &Multiwatcher{
+		all:    all,
+		filter: filter,
+	}
+}
+
 // Stop stops the watcher.
 func (w *Multiwatcher) Stop() error {
 	select {
@@ -235,8 +248,16 @@ func (sm *storeManager) respond() {
 		if len(changes) == 0 {
 			continue
 		}
-		req.changes = changes
 		w.revno = sm.all.latestRevno
+		changes = w.filter.Apply(changes)
+		if len(changes) == 0 {
+			// Nothing the watcher is interested in has changed,
+			// but it has still now seen everything up to the
+			// latest revno.
+			sm.seen(revno)
+			continue
+		}
+		req.changes = changes
 		req.reply <- true
 		if req := req.next; req == nil {
 			// Last request for this watcher.
@@ -7,7 +7,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"strings"
 
+	"github.com/juju/names"
 	"gopkg.in/juju/charm.v4"
 
 	"github.com/juju/juju/constraints"
@@ -238,3 +240,100 @@ func AnyJobNeedsState(jobs ...MachineJob) bool {
 	}
 	return false
 }
+
+// Filter restricts the deltas delivered to a watcher to those for
+// entities of interest. A zero Filter matches every entity.
+type Filter struct {
+	// Kinds, if not empty, holds the entity kinds (as returned in
+	// EntityId.Kind) which are of interest.
+	Kinds []string `json:",omitempty"`
+
+	// Services and Machines, if either is not empty, restrict the
+	// deltas to entities which concern the named services or the
+	// machines with the given ids: the services and machines
+	// themselves, their units, relations involving the services,
+	// and annotations on any of them. Containers are considered to
+	// concern the machine which hosts them.
+	Services []string `json:",omitempty"`
+	Machines []string `json:",omitempty"`
+}
+
+// IsEmpty returns whether the filter matches every entity.
+func (f Filter) IsEmpty() bool {
+	return len(f.Kinds) == 0 && len(f.Services) == 0 && len(f.Machines) == 0
+}
+
+// Match returns whether deltas for the given entity should be
+// delivered.
+func (f Filter) Match(info EntityInfo) bool {
+	if len(f.Kinds) > 0 && !contains(f.Kinds, info.EntityId().Kind) {
+		return false
+	}
+	if len(f.Services) == 0 && len(f.Machines) == 0 {
+		return true
+	}
+	switch info := info.(type) {
+	case *MachineInfo:
+		return f.matchMachine(info.Id)
+	case *ServiceInfo:
+		return f.matchService(info.Name)
+	case *UnitInfo:
+		return f.matchService(info.Service) || f.matchMachine(info.MachineId)
+	case *RelationInfo:
+		for _, ep := range info.Endpoints {
+			if f.matchService(ep.ServiceName) {
+				return true
+			}
+		}
+	case *AnnotationInfo:
+		tag, err := names.ParseTag(info.Tag)
+		if err != nil {
+			return false
+		}
+		switch tag := tag.(type) {
+		case names.MachineTag:
+			return f.matchMachine(tag.Id())
+		case names.ServiceTag:
+			return f.matchService(tag.Id())
+		case names.UnitTag:
+			return f.matchService(names.UnitService(tag.Id()))
+		}
+	}
+	return false
+}
+
+// Apply returns the deltas which match the filter.
+func (f Filter) Apply(deltas []Delta) []Delta {
+	if f.IsEmpty() {
+		return deltas
+	}
+	var matched []Delta
+	for _, d := range deltas {
+		if f.Match(d.Entity) {
+			matched = append(matched, d)
+		}
+	}
+	return matched
+}
+
+func (f Filter) matchService(name string) bool {
+	return name != "" && contains(f.Services, name)
+}
+
+func (f Filter) matchMachine(id string) bool {
+	for _, m := range f.Machines {
+		if id == m || strings.HasPrefix(id, m+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
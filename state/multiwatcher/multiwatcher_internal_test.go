@@ -34,3 +34,106 @@ func (s *ConstantsSuite) TestAnyJobNeedsState(c *gc.C) {
 	c.Assert(AnyJobNeedsState(JobManageEnviron), jc.IsTrue)
 	c.Assert(AnyJobNeedsState(JobHostUnits, JobManageEnviron), jc.IsTrue)
 }
+
+type FilterSuite struct{}
+
+var _ = gc.Suite(&FilterSuite{})
+
+var filterTests = []struct {
+	about   string
+	filter  Filter
+	matches []EntityInfo
+	misses  []EntityInfo
+}{{
+	about: "empty filter matches everything",
+	matches: []EntityInfo{
+		&MachineInfo{Id: "0"},
+		&ServiceInfo{Name: "wordpress"},
+		&UnitInfo{Name: "wordpress/0", Service: "wordpress"},
+		&AnnotationInfo{Tag: "service-wordpress"},
+	},
+}, {
+	about:  "kinds",
+	filter: Filter{Kinds: []string{"service", "unit"}},
+	matches: []EntityInfo{
+		&ServiceInfo{Name: "wordpress"},
+		&UnitInfo{Name: "wordpress/0", Service: "wordpress"},
+	},
+	misses: []EntityInfo{
+		&MachineInfo{Id: "0"},
+		&AnnotationInfo{Tag: "service-wordpress"},
+	},
+}, {
+	about:  "services",
+	filter: Filter{Services: []string{"wordpress"}},
+	matches: []EntityInfo{
+		&ServiceInfo{Name: "wordpress"},
+		&UnitInfo{Name: "wordpress/0", Service: "wordpress"},
+		&RelationInfo{Key: "wordpress:db mysql:server", Endpoints: []Endpoint{
+			{ServiceName: "wordpress"}, {ServiceName: "mysql"},
+		}},
+		&AnnotationInfo{Tag: "service-wordpress"},
+		&AnnotationInfo{Tag: "unit-wordpress-0"},
+	},
+	misses: []EntityInfo{
+		&MachineInfo{Id: "0"},
+		&ServiceInfo{Name: "mysql"},
+		&UnitInfo{Name: "mysql/0", Service: "mysql"},
+		&RelationInfo{Key: "mysql:cluster", Endpoints: []Endpoint{{ServiceName: "mysql"}}},
+		&AnnotationInfo{Tag: "service-mysql"},
+		&AnnotationInfo{Tag: "environment-deadbeef-0bad-400d-8000-4b1d0d06f00d"},
+	},
+}, {
+	about:  "machines",
+	filter: Filter{Machines: []string{"1"}},
+	matches: []EntityInfo{
+		&MachineInfo{Id: "1"},
+		&MachineInfo{Id: "1/lxc/0"},
+		&UnitInfo{Name: "wordpress/0", Service: "wordpress", MachineId: "1"},
+		&UnitInfo{Name: "mysql/0", Service: "mysql", MachineId: "1/lxc/0"},
+		&AnnotationInfo{Tag: "machine-1"},
+	},
+	misses: []EntityInfo{
+		&MachineInfo{Id: "0"},
+		&MachineInfo{Id: "10"},
+		&ServiceInfo{Name: "wordpress"},
+		&UnitInfo{Name: "wordpress/1", Service: "wordpress", MachineId: "2"},
+		&UnitInfo{Name: "wordpress/2", Service: "wordpress"},
+		&AnnotationInfo{Tag: "machine-0"},
+	},
+}, {
+	about:  "services, machines and kinds combined",
+	filter: Filter{Kinds: []string{"unit"}, Services: []string{"mysql"}, Machines: []string{"2"}},
+	matches: []EntityInfo{
+		&UnitInfo{Name: "mysql/0", Service: "mysql", MachineId: "1"},
+		&UnitInfo{Name: "wordpress/1", Service: "wordpress", MachineId: "2"},
+	},
+	misses: []EntityInfo{
+		&ServiceInfo{Name: "mysql"},
+		&MachineInfo{Id: "2"},
+		&UnitInfo{Name: "wordpress/0", Service: "wordpress", MachineId: "1"},
+	},
+}}
+
+func (s *FilterSuite) TestMatch(c *gc.C) {
+	for i, test := range filterTests {
+		c.Logf("test %d: %s", i, test.about)
+		for _, info := range test.matches {
+			c.Check(test.filter.Match(info), jc.IsTrue, gc.Commentf("%#v", info))
+		}
+		for _, info := range test.misses {
+			c.Check(test.filter.Match(info), jc.IsFalse, gc.Commentf("%#v", info))
+		}
+	}
+}
+
+func (s *FilterSuite) TestApply(c *gc.C) {
+	deltas := []Delta{
+		{Entity: &MachineInfo{Id: "0"}},
+		{Entity: &ServiceInfo{Name: "wordpress"}},
+		{Removed: true, Entity: &UnitInfo{Name: "wordpress/0", Service: "wordpress"}},
+	}
+	c.Assert(Filter{}.Apply(deltas), gc.DeepEquals, deltas)
+	c.Assert(Filter{Kinds: []string{"unit"}}.Apply(deltas), gc.DeepEquals, deltas[2:])
+	c.Assert(Filter{Machines: []string{"1"}}.Apply(deltas), gc.HasLen, 0)
+}
@@ -638,6 +638,35 @@ func (*storeManagerSuite) TestRun(c *gc.C) {
 	}, "")
 }
 
+func (*storeManagerSuite) TestRunFiltered(c *gc.C) {
+	b := newTestBacking([]multiwatcher.EntityInfo{
+		&multiwatcher.MachineInfo{Id: "0"},
+		&multiwatcher.ServiceInfo{Name: "logging"},
+		&multiwatcher.ServiceInfo{Name: "wordpress"},
+	})
+	sm := newStoreManager(b)
+	defer func() {
+		c.Check(sm.Stop(), gc.IsNil)
+	}()
+	w := NewFilteredMultiwatcher(sm, multiwatcher.Filter{Services: []string{"wordpress"}})
+	checkNext(c, w, []multiwatcher.Delta{
+		{Entity: &multiwatcher.ServiceInfo{Name: "wordpress"}},
+	}, "")
+
+	// Changes to entities outside the filter are not reported.
+	b.updateEntity(&multiwatcher.MachineInfo{Id: "0", InstanceId: "i-0"})
+	b.updateEntity(&multiwatcher.ServiceInfo{Name: "logging", Exposed: true})
+	b.updateEntity(&multiwatcher.UnitInfo{Name: "wordpress/0", Service: "wordpress"})
+	checkNext(c, w, []multiwatcher.Delta{
+		{Entity: &multiwatcher.UnitInfo{Name: "wordpress/0", Service: "wordpress"}},
+	}, "")
+	b.deleteEntity(multiwatcher.EntityId{"service", "logging"})
+	b.deleteEntity(multiwatcher.EntityId{"service", "wordpress"})
+	checkNext(c, w, []multiwatcher.Delta{
+		{Removed: true, Entity: &multiwatcher.ServiceInfo{Name: "wordpress"}},
+	}, "")
+}
+
 func (*storeManagerSuite) TestMultiwatcherStop(c *gc.C) {
 	sm := newStoreManager(newTestBacking(nil))
 	defer func() {
@@ -0,0 +1,268 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+// ObservedInterface describes a network interface as last found on a
+// machine by its agent.
+type ObservedInterface struct {
+	// InterfaceName is the name of the interface (e.g. "eth1.42").
+	InterfaceName string `bson:"interfacename"`
+
+	// MACAddress is the hardware address of the interface, if any.
+	MACAddress string `bson:"macaddress,omitempty"`
+
+	// MTU is the maximum transmission unit of the interface.
+	MTU int `bson:"mtu,omitempty"`
+
+	// IsUp is true when the interface is up.
+	IsUp bool `bson:"isup"`
+
+	// Addresses holds the addresses of the interface, in CIDR
+	// notation (e.g. "10.0.0.5/24").
+	Addresses []string `bson:"addresses,omitempty"`
+
+	// VLANTag is the tag of a VLAN interface, or 0 for other
+	// interfaces.
+	VLANTag int `bson:"vlantag,omitempty"`
+
+	// ParentInterfaceName is the name of the raw interface of a VLAN
+	// interface.
+	ParentInterfaceName string `bson:"parentinterfacename,omitempty"`
+}
+
+// observedInterfacesDoc records the network interfaces last found on a
+// machine.
+type observedInterfacesDoc struct {
+	DocID      string              `bson:"_id"`
+	EnvUUID    string              `bson:"env-uuid"`
+	MachineId  string              `bson:"machineid"`
+	Interfaces []ObservedInterface `bson:"interfaces"`
+	Updated    time.Time           `bson:"updated"`
+}
+
+// SetObservedInterfaces records the network interfaces found on the
+// machine by its agent, replacing any recorded before.
+func (m *Machine) SetObservedInterfaces(interfaces []ObservedInterface) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot set observed network interfaces of machine %s", m.doc.Id)
+	sorted := make([]ObservedInterface, len(interfaces))
+	copy(sorted, interfaces)
+	sort.Sort(observedInterfacesByName(sorted))
+
+	coll, closer := m.st.getCollection(observedInterfacesC)
+	defer closer()
+
+	docID := m.st.docID(m.doc.Id)
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := m.Refresh(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		if m.doc.Life == Dead {
+			return nil, errors.New("machine is dead")
+		}
+		ops := []txn.Op{{
+			C:      machinesC,
+			Id:     m.doc.DocID,
+			Assert: notDeadDoc,
+		}}
+		updated := nowToTheSecond()
+		count, err := coll.FindId(docID).Count()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if count == 0 {
+			return append(ops, txn.Op{
+				C:      observedInterfacesC,
+				Id:     docID,
+				Assert: txn.DocMissing,
+				Insert: &observedInterfacesDoc{
+					DocID:      docID,
+					EnvUUID:    m.st.EnvironUUID(),
+					MachineId:  m.doc.Id,
+					Interfaces: sorted,
+					Updated:    updated,
+				},
+			}), nil
+		}
+		return append(ops, txn.Op{
+			C:      observedInterfacesC,
+			Id:     docID,
+			Assert: txn.DocExists,
+			Update: bson.D{{"$set", bson.D{
+				{"interfaces", sorted},
+				{"updated", updated},
+			}}},
+		}), nil
+	}
+	return m.st.run(buildTxn)
+}
+
+// ObservedInterfaces returns the network interfaces last found on the
+// machine by its agent, ordered by name, and when they were found. If
+// the agent has not reported them yet, an error satisfying
+// errors.IsNotFound is returned.
+func (m *Machine) ObservedInterfaces() ([]ObservedInterface, time.Time, error) {
+	coll, closer := m.st.getCollection(observedInterfacesC)
+	defer closer()
+
+	var doc observedInterfacesDoc
+	err := coll.FindId(m.st.docID(m.doc.Id)).One(&doc)
+	if err == mgo.ErrNotFound {
+		return nil, time.Time{}, errors.NotFoundf("observed network interfaces of machine %s", m.doc.Id)
+	}
+	if err != nil {
+		return nil, time.Time{}, errors.Annotatef(err, "cannot get observed network interfaces of machine %s", m.doc.Id)
+	}
+	return doc.Interfaces, doc.Updated, nil
+}
+
+// removeObservedInterfacesOp returns the operation that removes the
+// network interfaces recorded for the machine.
+func removeObservedInterfacesOp(st *State, machineId string) txn.Op {
+	return txn.Op{
+		C:      observedInterfacesC,
+		Id:     st.docID(machineId),
+		Remove: true,
+	}
+}
+
+type observedInterfacesByName []ObservedInterface
+
+func (s observedInterfacesByName) Len() int           { return len(s) }
+func (s observedInterfacesByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s observedInterfacesByName) Less(i, j int) bool { return s[i].InterfaceName < s[j].InterfaceName }
+
+// InterfaceDrift describes how a network interface found on a machine
+// differs from its configuration in state.
+type InterfaceDrift struct {
+	InterfaceName string
+	Problem       string
+}
+
+// InterfaceDrift compares the network interfaces last found on the
+// machine by its agent with the ones configured in state, and returns
+// the differences, ordered by interface name. Interfaces found on the
+// machine but not configured in state are only reported when they are
+// VLANs, as other interfaces are not managed by Juju. If the agent has
+// not reported the interfaces yet, an error satisfying
+// errors.IsNotFound is returned.
+func (m *Machine) InterfaceDrift() ([]InterfaceDrift, error) {
+	observed, _, err := m.ObservedInterfaces()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	intended, err := m.NetworkInterfaces()
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot get network interfaces of machine %s", m.doc.Id)
+	}
+	networks := make(map[string]*Network)
+	for _, iface := range intended {
+		name := iface.NetworkName()
+		if _, ok := networks[name]; ok {
+			continue
+		}
+		nw, err := m.st.Network(name)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		networks[name] = nw
+	}
+	return interfaceDrift(intended, networks, observed), nil
+}
+
+// interfaceDrift returns the differences between the intended network
+// interfaces, on the given networks, and the observed ones.
+func interfaceDrift(intended []*NetworkInterface, networks map[string]*Network, observed []ObservedInterface) []InterfaceDrift {
+	var drift []InterfaceDrift
+	report := func(name, format string, args ...interface{}) {
+		drift = append(drift, InterfaceDrift{
+			InterfaceName: name,
+			Problem:       fmt.Sprintf(format, args...),
+		})
+	}
+	found := make(map[string]ObservedInterface)
+	for _, iface := range observed {
+		found[iface.InterfaceName] = iface
+	}
+	known := make(map[string]bool)
+	for _, iface := range intended {
+		name := iface.InterfaceName()
+		known[name] = true
+		nw := networks[iface.NetworkName()]
+		actual, ok := found[name]
+		switch {
+		case !ok && !iface.IsDisabled():
+			report(name, "missing on machine")
+			continue
+		case !ok:
+			continue
+		case iface.IsDisabled() && actual.IsUp:
+			report(name, "up but disabled")
+			continue
+		case iface.IsDisabled():
+			continue
+		case !actual.IsUp:
+			report(name, "down but enabled")
+		}
+		if actual.MACAddress != "" && !strings.EqualFold(actual.MACAddress, iface.MACAddress()) {
+			report(name, "MAC address is %q, expected %q", actual.MACAddress, iface.MACAddress())
+		}
+		if nw == nil {
+			continue
+		}
+		if actual.VLANTag != nw.VLANTag() {
+			report(name, "VLAN tag is %d, expected %d", actual.VLANTag, nw.VLANTag())
+		}
+		if actual.IsUp && !hasAddressInCIDR(actual.Addresses, nw.CIDR()) {
+			report(name, "no address in network %q (%s)", nw.Name(), nw.CIDR())
+		}
+	}
+	for _, iface := range observed {
+		if iface.VLANTag != 0 && !known[iface.InterfaceName] {
+			report(iface.InterfaceName, "VLAN not configured in juju")
+		}
+	}
+	sort.Stable(interfaceDriftByName(drift))
+	return drift
+}
+
+// hasAddressInCIDR returns whether any of the addresses, in CIDR
+// notation, is in the given network. An invalid or empty CIDR matches
+// any address.
+func hasAddressInCIDR(addresses []string, cidr string) bool {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return len(addresses) > 0
+	}
+	for _, addr := range addresses {
+		ip, _, err := net.ParseCIDR(addr)
+		if err != nil {
+			ip = net.ParseIP(addr)
+		}
+		if ip != nil && ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+type interfaceDriftByName []InterfaceDrift
+
+func (s interfaceDriftByName) Len() int           { return len(s) }
+func (s interfaceDriftByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s interfaceDriftByName) Less(i, j int) bool { return s[i].InterfaceName < s[j].InterfaceName }
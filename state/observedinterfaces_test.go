@@ -0,0 +1,180 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+)
+
+type ObservedInterfacesSuite struct {
+	ConnSuite
+	machine *state.Machine
+}
+
+var _ = gc.Suite(&ObservedInterfacesSuite{})
+
+func (s *ObservedInterfacesSuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	var err error
+	s.machine, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	networks := []state.NetworkInfo{{
+		Name:       "net1",
+		ProviderId: "net1",
+		CIDR:       "0.1.2.0/24",
+	}, {
+		Name:       "vlan42",
+		ProviderId: "vlan42",
+		CIDR:       "0.2.2.0/24",
+		VLANTag:    42,
+	}, {
+		Name:       "net2",
+		ProviderId: "net2",
+		CIDR:       "0.5.2.0/24",
+	}}
+	interfaces := []state.NetworkInterfaceInfo{{
+		MACAddress:    "aa:bb:cc:dd:ee:f1",
+		InterfaceName: "eth1",
+		NetworkName:   "net1",
+	}, {
+		MACAddress:    "aa:bb:cc:dd:ee:f1",
+		InterfaceName: "eth1.42",
+		NetworkName:   "vlan42",
+		IsVirtual:     true,
+	}, {
+		MACAddress:    "aa:bb:cc:dd:ee:f2",
+		InterfaceName: "eth2",
+		NetworkName:   "net2",
+		Disabled:      true,
+	}}
+	err = s.machine.SetInstanceInfo("i-am", "fake_nonce", nil, networks, interfaces)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *ObservedInterfacesSuite) TestSetObservedInterfaces(c *gc.C) {
+	_, _, err := s.machine.ObservedInterfaces()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	before := state.NowToTheSecond()
+	observed := []state.ObservedInterface{{
+		InterfaceName: "eth1",
+		MACAddress:    "aa:bb:cc:dd:ee:f1",
+		MTU:           1500,
+		IsUp:          true,
+		Addresses:     []string{"0.1.2.3/24"},
+	}, {
+		InterfaceName: "eth0",
+		MTU:           1500,
+	}}
+	err = s.machine.SetObservedInterfaces(observed)
+	c.Assert(err, jc.ErrorIsNil)
+	interfaces, updated, err := s.machine.ObservedInterfaces()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(interfaces, jc.DeepEquals, []state.ObservedInterface{observed[1], observed[0]})
+	c.Assert(updated.Before(before), jc.IsFalse)
+
+	err = s.machine.SetObservedInterfaces(observed[:1])
+	c.Assert(err, jc.ErrorIsNil)
+	interfaces, _, err = s.machine.ObservedInterfaces()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(interfaces, jc.DeepEquals, observed[:1])
+}
+
+func (s *ObservedInterfacesSuite) TestSetObservedInterfacesDeadMachine(c *gc.C) {
+	err := s.machine.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.SetObservedInterfaces(nil)
+	c.Assert(err, gc.ErrorMatches, "cannot set observed network interfaces of machine 0: machine is dead")
+}
+
+func (s *ObservedInterfacesSuite) TestObservedInterfacesRemovedWithMachine(c *gc.C) {
+	err := s.machine.SetObservedInterfaces([]state.ObservedInterface{{InterfaceName: "eth0"}})
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.Remove()
+	c.Assert(err, jc.ErrorIsNil)
+	_, _, err = s.machine.ObservedInterfaces()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *ObservedInterfacesSuite) TestInterfaceDriftNotReported(c *gc.C) {
+	_, err := s.machine.InterfaceDrift()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *ObservedInterfacesSuite) TestInterfaceDriftNone(c *gc.C) {
+	err := s.machine.SetObservedInterfaces([]state.ObservedInterface{{
+		InterfaceName: "eth0",
+		IsUp:          true,
+	}, {
+		InterfaceName: "eth1",
+		MACAddress:    "AA:BB:CC:DD:EE:F1",
+		IsUp:          true,
+		Addresses:     []string{"0.1.2.3/24"},
+	}, {
+		InterfaceName:       "eth1.42",
+		MACAddress:          "aa:bb:cc:dd:ee:f1",
+		IsUp:                true,
+		Addresses:           []string{"0.2.2.3/24"},
+		VLANTag:             42,
+		ParentInterfaceName: "eth1",
+	}, {
+		InterfaceName: "eth2",
+		MACAddress:    "aa:bb:cc:dd:ee:f2",
+	}})
+	c.Assert(err, jc.ErrorIsNil)
+	drift, err := s.machine.InterfaceDrift()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(drift, gc.HasLen, 0)
+}
+
+func (s *ObservedInterfacesSuite) TestInterfaceDrift(c *gc.C) {
+	err := s.machine.SetObservedInterfaces([]state.ObservedInterface{{
+		InterfaceName: "eth1",
+		MACAddress:    "aa:bb:cc:dd:ee:ff",
+		IsUp:          true,
+		Addresses:     []string{"10.0.0.3/24"},
+	}, {
+		InterfaceName: "eth2",
+		MACAddress:    "aa:bb:cc:dd:ee:f2",
+		IsUp:          true,
+	}, {
+		InterfaceName:       "eth2.69",
+		IsUp:                true,
+		VLANTag:             69,
+		ParentInterfaceName: "eth2",
+	}})
+	c.Assert(err, jc.ErrorIsNil)
+	drift, err := s.machine.InterfaceDrift()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(drift, jc.DeepEquals, []state.InterfaceDrift{
+		{"eth1", `MAC address is "aa:bb:cc:dd:ee:ff", expected "aa:bb:cc:dd:ee:f1"`},
+		{"eth1", `no address in network "net1" (0.1.2.0/24)`},
+		{"eth1.42", "missing on machine"},
+		{"eth2", "up but disabled"},
+		{"eth2.69", "VLAN not configured in juju"},
+	})
+
+	err = s.machine.SetObservedInterfaces([]state.ObservedInterface{{
+		InterfaceName: "eth1",
+		MACAddress:    "aa:bb:cc:dd:ee:f1",
+	}, {
+		InterfaceName: "eth1.42",
+		MACAddress:    "aa:bb:cc:dd:ee:f1",
+		IsUp:          true,
+		Addresses:     []string{"0.2.2.3/24"},
+	}})
+	c.Assert(err, jc.ErrorIsNil)
+	drift, err = s.machine.InterfaceDrift()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(drift, jc.DeepEquals, []state.InterfaceDrift{
+		{"eth1", "down but enabled"},
+		{"eth1.42", "VLAN tag is 0, expected 42"},
+	})
+}
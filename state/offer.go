@@ -0,0 +1,248 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/juju/errors"
+	"gopkg.in/juju/charm.v4"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+// Offer represents a service endpoint that is offered to the other
+// environments hosted by the same state server, so that their
+// services can relate to it.
+type Offer struct {
+	st  *State
+	doc offerDoc
+}
+
+// offerDoc is the internal representation of an Offer in MongoDB.
+// Offers are stored in a collection shared by all environments, so
+// they can be found from any of them.
+type offerDoc struct {
+	DocID    string   `bson:"_id"`
+	EnvUUID  string   `bson:"envuuid"`
+	EnvName  string   `bson:"envname"`
+	Endpoint Endpoint `bson:"endpoint"`
+}
+
+func offerID(envUUID, serviceName, relationName string) string {
+	return envUUID + ":" + serviceName + ":" + relationName
+}
+
+// String returns the name consumers use to refer to the offered
+// endpoint, of the form <environment>.<service>:<relation>.
+func (o *Offer) String() string {
+	return o.doc.EnvName + "." + o.doc.Endpoint.String()
+}
+
+// EnvUUID returns the UUID of the environment offering the endpoint.
+func (o *Offer) EnvUUID() string {
+	return o.doc.EnvUUID
+}
+
+// EnvName returns the name of the environment offering the endpoint.
+func (o *Offer) EnvName() string {
+	return o.doc.EnvName
+}
+
+// Endpoint returns the offered endpoint.
+func (o *Offer) Endpoint() Endpoint {
+	return o.doc.Endpoint
+}
+
+// AddOffer offers the named relation of the named service to the other
+// environments hosted by the state server.
+func (st *State) AddOffer(serviceName, relationName string) (offer *Offer, err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot offer %s:%s", serviceName, relationName)
+	svc, err := st.Service(serviceName)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if svc.Life() != Alive {
+		return nil, errors.Errorf("service is not alive")
+	}
+	ep, err := svc.Endpoint(relationName)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if ep.Role == charm.RolePeer {
+		return nil, errors.Errorf("peer relations cannot be offered")
+	}
+	if ep.Scope == charm.ScopeContainer {
+		return nil, errors.Errorf("container scoped relations cannot be offered")
+	}
+	env, err := st.Environment()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	doc := offerDoc{
+		DocID:    offerID(st.EnvironUUID(), serviceName, relationName),
+		EnvUUID:  st.EnvironUUID(),
+		EnvName:  env.Name(),
+		Endpoint: ep,
+	}
+	ops := []txn.Op{{
+		C:      servicesC,
+		Id:     st.docID(serviceName),
+		Assert: isAliveDoc,
+	}, {
+		C:      offersC,
+		Id:     doc.DocID,
+		Assert: txn.DocMissing,
+		Insert: doc,
+	}}
+	if err := st.runTransaction(ops); err == txn.ErrAborted {
+		if alive, err := isAlive(st, servicesC, serviceName); err != nil {
+			return nil, errors.Trace(err)
+		} else if !alive {
+			return nil, errors.Errorf("service is not alive")
+		}
+		return nil, errors.AlreadyExistsf("offer")
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &Offer{st: st, doc: doc}, nil
+}
+
+// RemoveOffer stops offering the named relation of the named service.
+// Existing relations to the endpoint are not affected.
+func (st *State) RemoveOffer(serviceName, relationName string) error {
+	ops := []txn.Op{{
+		C:      offersC,
+		Id:     offerID(st.EnvironUUID(), serviceName, relationName),
+		Assert: txn.DocExists,
+		Remove: true,
+	}}
+	if err := st.runTransaction(ops); err == txn.ErrAborted {
+		return errors.NotFoundf("offer %s:%s", serviceName, relationName)
+	} else if err != nil {
+		return errors.Annotatef(err, "cannot remove offer %s:%s", serviceName, relationName)
+	}
+	return nil
+}
+
+// Offers returns the endpoints offered by the environment.
+func (st *State) Offers() ([]*Offer, error) {
+	return st.findOffers(bson.D{{"envuuid", st.EnvironUUID()}})
+}
+
+// FindOffer returns the offer of an endpoint in the named environment.
+// The endpoint is of the form <service>[:<relation>]; the relation may
+// be omitted if the service offers a single endpoint.
+func (st *State) FindOffer(envName, endpoint string) (*Offer, error) {
+	desc := envName + "." + endpoint
+	serviceName, relationName := endpoint, ""
+	if i := strings.Index(endpoint, ":"); i != -1 {
+		serviceName, relationName = endpoint[:i], endpoint[i+1:]
+	}
+	environments, closer := st.getCollection(environmentsC)
+	defer closer()
+
+	var envDocs []environmentDoc
+	if err := environments.Find(bson.D{{"name", envName}}).All(&envDocs); err != nil {
+		return nil, errors.Annotatef(err, "cannot get environment %q", envName)
+	}
+	switch len(envDocs) {
+	case 0:
+		return nil, errors.NotFoundf("environment %q", envName)
+	case 1:
+	default:
+		return nil, errors.Errorf("environment name %q is ambiguous", envName)
+	}
+	all, err := st.findOffers(bson.D{
+		{"envuuid", envDocs[0].UUID},
+		{"endpoint.servicename", serviceName},
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var offers []*Offer
+	for _, offer := range all {
+		if relationName == "" || offer.doc.Endpoint.Name == relationName {
+			offers = append(offers, offer)
+		}
+	}
+	switch len(offers) {
+	case 0:
+		return nil, errors.NotFoundf("offer %q", desc)
+	case 1:
+		return offers[0], nil
+	}
+	var names []string
+	for _, offer := range offers {
+		names = append(names, fmt.Sprintf("%q", offer.String()))
+	}
+	return nil, errors.Errorf("%q offers more than one endpoint: %s", desc, strings.Join(names, ", "))
+}
+
+func (st *State) findOffers(sel bson.D) ([]*Offer, error) {
+	offersCollection, closer := st.getCollection(offersC)
+	defer closer()
+
+	var docs []offerDoc
+	if err := offersCollection.Find(sel).Sort("_id").All(&docs); err != nil {
+		return nil, errors.Annotate(err, "cannot get offers")
+	}
+	offers := make([]*Offer, len(docs))
+	for i, doc := range docs {
+		offers[i] = &Offer{st: st, doc: doc}
+	}
+	return offers, nil
+}
+
+// removeOffersOps returns the operations that stop offering all the
+// endpoints of the named service.
+func removeOffersOps(st *State, serviceName string) ([]txn.Op, error) {
+	offers, err := st.findOffers(bson.D{
+		{"envuuid", st.EnvironUUID()},
+		{"endpoint.servicename", serviceName},
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var ops []txn.Op
+	for _, offer := range offers {
+		ops = append(ops, txn.Op{
+			C:      offersC,
+			Id:     offer.doc.DocID,
+			Remove: true,
+		})
+	}
+	return ops, nil
+}
+
+// ConsumeOffer relates the given local endpoint, of the form
+// <service>[:<relation>], to the offered endpoint. The offering service
+// is recorded as a remote service of the environment, and the relation
+// is also added to the offering environment.
+func (st *State) ConsumeOffer(offer *Offer, localEndpoint string) (_ *Relation, err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot relate %q to %q", localEndpoint, offer)
+	if offer.doc.EnvUUID == st.EnvironUUID() {
+		return nil, errors.Errorf("offer is from this environment")
+	}
+	offered := offer.doc.Endpoint
+	remote, err := st.ensureRemoteService(offered.ServiceName, offer.doc.EnvUUID, offer.doc.EnvName, offered.Relation)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer func() {
+		// Don't leave a newly added remote service behind if it could
+		// not be related to.
+		if err != nil && remote.Refresh() == nil && remote.doc.RelationCount == 0 {
+			if err := remote.Destroy(); err != nil {
+				logger.Errorf("cannot destroy remote service %q: %v", remote, err)
+			}
+		}
+	}()
+	eps, err := st.InferEndpoints(localEndpoint, offered.String())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return st.AddRelation(eps...)
+}
@@ -0,0 +1,240 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing/factory"
+)
+
+type OfferSuite struct {
+	ConnSuite
+	otherState *state.State
+	mysql      *state.Service
+}
+
+var _ = gc.Suite(&OfferSuite{})
+
+func (s *OfferSuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	s.otherState = s.factory.MakeEnvironment(c, &factory.EnvParams{Name: "db"})
+	s.AddCleanup(func(*gc.C) { s.otherState.Close() })
+	ch := state.AddTestingCharm(c, s.otherState, "mysql")
+	s.mysql = state.AddTestingService(c, s.otherState, "mysql", ch, s.owner)
+}
+
+func (s *OfferSuite) TestAddOffer(c *gc.C) {
+	offer, err := s.otherState.AddOffer("mysql", "server")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(offer.String(), gc.Equals, "db.mysql:server")
+	c.Assert(offer.EnvName(), gc.Equals, "db")
+	c.Assert(offer.EnvUUID(), gc.Equals, s.otherState.EnvironUUID())
+	c.Assert(offer.Endpoint().ServiceName, gc.Equals, "mysql")
+
+	offers, err := s.otherState.Offers()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(offers, gc.HasLen, 1)
+	c.Assert(offers[0].String(), gc.Equals, "db.mysql:server")
+
+	offers, err = s.State.Offers()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(offers, gc.HasLen, 0)
+}
+
+func (s *OfferSuite) TestAddOfferErrors(c *gc.C) {
+	_, err := s.otherState.AddOffer("mysql", "server")
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.otherState.AddOffer("mysql", "server")
+	c.Assert(err, gc.ErrorMatches, `cannot offer mysql:server: offer already exists`)
+	c.Assert(errors.Cause(err), jc.Satisfies, errors.IsAlreadyExists)
+
+	_, err = s.otherState.AddOffer("mysql", "foo")
+	c.Assert(err, gc.ErrorMatches, `cannot offer mysql:foo: .*`)
+	_, err = s.otherState.AddOffer("wordpress", "db")
+	c.Assert(err, gc.ErrorMatches, `cannot offer wordpress:db: service "wordpress" not found`)
+
+	state.AddTestingService(c, s.otherState, "riak", state.AddTestingCharm(c, s.otherState, "riak"), s.owner)
+	_, err = s.otherState.AddOffer("riak", "ring")
+	c.Assert(err, gc.ErrorMatches, `cannot offer riak:ring: peer relations cannot be offered`)
+}
+
+func (s *OfferSuite) TestRemoveOffer(c *gc.C) {
+	_, err := s.otherState.AddOffer("mysql", "server")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.otherState.RemoveOffer("mysql", "server")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.otherState.RemoveOffer("mysql", "server")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	offers, err := s.otherState.Offers()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(offers, gc.HasLen, 0)
+}
+
+func (s *OfferSuite) TestServiceDestroyRemovesOffers(c *gc.C) {
+	_, err := s.otherState.AddOffer("mysql", "server")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.mysql.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+
+	offers, err := s.otherState.Offers()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(offers, gc.HasLen, 0)
+}
+
+func (s *OfferSuite) TestFindOffer(c *gc.C) {
+	_, err := s.otherState.AddOffer("mysql", "server")
+	c.Assert(err, jc.ErrorIsNil)
+
+	offer, err := s.State.FindOffer("db", "mysql")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(offer.String(), gc.Equals, "db.mysql:server")
+	offer, err = s.State.FindOffer("db", "mysql:server")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(offer.String(), gc.Equals, "db.mysql:server")
+
+	_, err = s.State.FindOffer("db", "mysql:foo")
+	c.Assert(err, gc.ErrorMatches, `offer "db.mysql:foo" not found`)
+	_, err = s.State.FindOffer("db", "wordpress")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	_, err = s.State.FindOffer("nope", "mysql")
+	c.Assert(err, gc.ErrorMatches, `environment "nope" not found`)
+}
+
+func (s *OfferSuite) TestConsumeOffer(c *gc.C) {
+	wordpress := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	offer, err := s.otherState.AddOffer("mysql", "server")
+	c.Assert(err, jc.ErrorIsNil)
+
+	rel, err := s.State.ConsumeOffer(offer, "wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(rel.String(), gc.Equals, "wordpress:db mysql:server")
+	c.Assert(rel.IsRemote(), jc.IsTrue)
+	c.Assert(rel.RemoteServiceName(), gc.Equals, "mysql")
+	c.Assert(rel.RemoteEnvUUID(), gc.Equals, s.otherState.EnvironUUID())
+
+	// The offering service is a remote service of the consuming
+	// environment...
+	remote, err := s.State.RemoteService("mysql")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(remote.String(), gc.Equals, "db.mysql")
+	c.Assert(remote.SourceEnvUUID(), gc.Equals, s.otherState.EnvironUUID())
+	rels, err := remote.Relations()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(rels, gc.HasLen, 1)
+
+	// ...and the consuming service one of the offering environment,
+	// where the relation has a counterpart.
+	env, err := s.State.Environment()
+	c.Assert(err, jc.ErrorIsNil)
+	otherRemote, err := s.otherState.RemoteService("wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(otherRemote.SourceEnvName(), gc.Equals, env.Name())
+	otherRel, err := s.otherState.KeyRelation(rel.String())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(otherRel.RemoteServiceName(), gc.Equals, "wordpress")
+	c.Assert(otherRel.RemoteEnvUUID(), gc.Equals, s.State.EnvironUUID())
+
+	rels, err = wordpress.Relations()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(rels, gc.HasLen, 1)
+}
+
+func (s *OfferSuite) TestConsumeOfferSameEnvironment(c *gc.C) {
+	state.AddTestingService(c, s.otherState, "wordpress", state.AddTestingCharm(c, s.otherState, "wordpress"), s.owner)
+	offer, err := s.otherState.AddOffer("mysql", "server")
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.otherState.ConsumeOffer(offer, "wordpress")
+	c.Assert(err, gc.ErrorMatches, `cannot relate "wordpress" to "db.mysql:server": offer is from this environment`)
+}
+
+func (s *OfferSuite) TestServiceNameClash(c *gc.C) {
+	s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	offer, err := s.otherState.AddOffer("mysql", "server")
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.State.ConsumeOffer(offer, "wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = s.State.AddService("mysql", s.owner.String(), s.AddTestingCharm(c, "mysql"), nil)
+	c.Assert(err, gc.ErrorMatches, `cannot add service "mysql": remote service with the same name already exists`)
+}
+
+func (s *OfferSuite) TestRemoteUnitSettings(c *gc.C) {
+	wordpress := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	offer, err := s.otherState.AddOffer("mysql", "server")
+	c.Assert(err, jc.ErrorIsNil)
+	rel, err := s.State.ConsumeOffer(offer, "wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = rel.SetRemoteUnitSettings("wordpress/0", nil)
+	c.Assert(err, gc.ErrorMatches, `.*"wordpress/0" is not a unit of remote service "mysql"`)
+
+	settings := map[string]interface{}{"host": "10.0.0.1"}
+	err = rel.SetRemoteUnitSettings("mysql/0", settings)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Local units see the remote unit as they would a local one.
+	unit, err := wordpress.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	ru, err := rel.Unit(unit)
+	c.Assert(err, jc.ErrorIsNil)
+	read, err := ru.ReadSettings("mysql/0")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(read, gc.DeepEquals, settings)
+
+	settings = map[string]interface{}{"host": "10.0.0.2"}
+	err = rel.SetRemoteUnitSettings("mysql/0", settings)
+	c.Assert(err, jc.ErrorIsNil)
+	read, err = rel.UnitSettings("mysql/0")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(read, gc.DeepEquals, settings)
+
+	// Destroying the relation leaves it in place until the remote unit
+	// leaves.
+	err = rel.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+	err = rel.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(rel.Life(), gc.Equals, state.Dying)
+	err = rel.SetRemoteUnitSettings("mysql/1", settings)
+	c.Assert(errors.Cause(err), gc.Equals, state.ErrCannotEnterScope)
+
+	err = rel.RemoveRemoteUnit("mysql/0")
+	c.Assert(err, jc.ErrorIsNil)
+	err = rel.Refresh()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	// The remote service remains until it is destroyed.
+	remote, err := s.State.RemoteService("mysql")
+	c.Assert(err, jc.ErrorIsNil)
+	rels, err := remote.Relations()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(rels, gc.HasLen, 0)
+	err = remote.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.State.RemoteService("mysql")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *OfferSuite) TestRemoteServiceDestroy(c *gc.C) {
+	s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	offer, err := s.otherState.AddOffer("mysql", "server")
+	c.Assert(err, jc.ErrorIsNil)
+	rel, err := s.State.ConsumeOffer(offer, "wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+
+	remote, err := s.State.RemoteService("mysql")
+	c.Assert(err, jc.ErrorIsNil)
+	err = remote.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = rel.Refresh()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	err = remote.Refresh()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
@@ -134,7 +134,7 @@ func (st *State) envSetupOps(cfg *config.Config, serverUUID string, owner names.
 	if serverUUID == "" {
 		serverUUID = uuid
 	}
-	envUserOp, _ := createEnvUserOpAndDoc(uuid, owner, owner, owner.Name())
+	envUserOp, _ := createEnvUserOpAndDoc(uuid, owner, owner, owner.Name(), AdminAccess)
 	ops := []txn.Op{
 		createConstraintsOp(st, environGlobalKey, constraints.Value{}),
 		createSettingsOp(st, environGlobalKey, cfg.AllAttrs()),
@@ -169,6 +169,9 @@ var indexes = []struct {
 	{subnetsC, []string{"providerid"}, true, true},
 	{ipaddressesC, []string{"state"}, false, false},
 	{ipaddressesC, []string{"subnetid"}, false, false},
+	{userSessionsC, []string{"user"}, false, false},
+	{userSessionsC, []string{"server"}, false, false},
+	{sshAccessC, []string{"env-uuid", "time"}, false, false},
 }
 
 // The capped collection used for transaction logs defaults to 10MB.
@@ -225,8 +228,11 @@ func newState(session *mgo.Session, mongoInfo *mongo.MongoInfo, policy Policy) (
 		mongoInfo: mongoInfo,
 		policy:    policy,
 		db:        db,
+		traces:    newRequestTraces(),
 	}
-	st.LeasePersistor = NewLeasePersistor(leaseC, st.runTransaction, st.getCollection)
+	st.LeasePersistor = NewLeasePersistor(leaseC, st.run, st.getCollection, func() NotifyWatcher {
+		return newLeaseWatcher(st)
+	})
 	log := db.C(txnLogC)
 	logInfo := mgo.CollectionInfo{Capped: true, MaxBytes: logSize}
 	// The lack of error code for this error was reported upstream:
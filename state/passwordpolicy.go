@@ -0,0 +1,75 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"unicode"
+
+	"github.com/juju/errors"
+)
+
+// PasswordPolicy holds the rules that user passwords must follow.
+type PasswordPolicy struct {
+	// MinLength holds the minimum number of characters in a password.
+	MinLength int
+
+	// MinCharClasses holds the minimum number of classes of
+	// character a password must contain: lower case letters, upper
+	// case letters, digits and everything else.
+	MinCharClasses int
+}
+
+// Validate returns an error if the password does not follow the policy.
+func (p PasswordPolicy) Validate(password string) error {
+	if len([]rune(password)) < p.MinLength {
+		return errors.Errorf("password must be at least %d characters long", p.MinLength)
+	}
+	if classes := passwordCharClasses(password); classes < p.MinCharClasses {
+		return errors.Errorf("password must contain at least %d of: lower case letters, upper case letters, digits and other characters", p.MinCharClasses)
+	}
+	return nil
+}
+
+// passwordCharClasses returns the number of classes of character in
+// the password.
+func passwordCharClasses(password string) int {
+	var lower, upper, digit, other int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+	return lower + upper + digit + other
+}
+
+// PasswordPolicy returns the rules for user passwords. Users belong to
+// the state server, so the rules are taken from the configuration of
+// the state server environment.
+func (st *State) PasswordPolicy() (PasswordPolicy, error) {
+	cfg, err := st.StateServerEnvironConfig()
+	if err != nil {
+		return PasswordPolicy{}, errors.Annotate(err, "cannot read password policy")
+	}
+	return PasswordPolicy{
+		MinLength:      cfg.PasswordMinLength(),
+		MinCharClasses: cfg.PasswordMinCharClasses(),
+	}, nil
+}
+
+// checkPassword returns an error if the password does not follow the
+// password policy.
+func (st *State) checkPassword(password string) error {
+	policy, err := st.PasswordPolicy()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return policy.Validate(password)
+}
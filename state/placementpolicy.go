@@ -0,0 +1,624 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	"github.com/juju/utils/set"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+// PlacementPolicyKind identifies a rule constraining where the units
+// of a service are placed.
+type PlacementPolicyKind string
+
+const (
+	// AntiAffinity keeps the units of a service off the machines
+	// hosting units of another service, or other units of the same
+	// service. Machines count as hosting the units in their
+	// containers. The provisioner also prefers availability zones
+	// without such units.
+	AntiAffinity PlacementPolicyKind = "anti-affinity"
+
+	// Affinity places each unit of a service in the availability zone
+	// of a unit of another service that has no unit of the service in
+	// its zone yet. It is zone affinity only: the units are not placed
+	// on the same machines as the other service's units.
+	Affinity PlacementPolicyKind = "affinity"
+
+	// MaxUnitsPerZone limits the number of units of a service in each
+	// availability zone.
+	MaxUnitsPerZone PlacementPolicyKind = "max-units-per-zone"
+)
+
+// PlacementPolicy is a rule constraining where the units of a service
+// are placed.
+type PlacementPolicy struct {
+	Kind PlacementPolicyKind
+
+	// Service names the other service, for Affinity and AntiAffinity.
+	Service string
+
+	// MaxUnits holds the limit, for MaxUnitsPerZone.
+	MaxUnits int
+}
+
+// String returns the policy in the form kind=value.
+func (p PlacementPolicy) String() string {
+	if p.Kind == MaxUnitsPerZone {
+		return fmt.Sprintf("%s=%d", p.Kind, p.MaxUnits)
+	}
+	return fmt.Sprintf("%s=%s", p.Kind, p.Service)
+}
+
+// Validate returns an error if the policy is not valid for a service
+// with the given name.
+func (p PlacementPolicy) Validate(service string) error {
+	switch p.Kind {
+	case AntiAffinity, Affinity:
+		if !names.IsValidService(p.Service) {
+			return errors.NotValidf("%s policy with service name %q", p.Kind, p.Service)
+		}
+		if p.Kind == Affinity && p.Service == service {
+			return errors.NotValidf("affinity policy of service %q with itself", service)
+		}
+		if p.MaxUnits != 0 {
+			return errors.NotValidf("%s policy with a unit limit", p.Kind)
+		}
+	case MaxUnitsPerZone:
+		if p.MaxUnits < 1 {
+			return errors.NotValidf("%s policy with limit %d", p.Kind, p.MaxUnits)
+		}
+		if p.Service != "" {
+			return errors.NotValidf("%s policy with a service name", p.Kind)
+		}
+	default:
+		return errors.NotValidf("placement policy kind %q", p.Kind)
+	}
+	return nil
+}
+
+// placementPolicyDoc records a PlacementPolicy in a service document.
+type placementPolicyDoc struct {
+	Kind     PlacementPolicyKind `bson:"kind"`
+	Service  string              `bson:"service,omitempty"`
+	MaxUnits int                 `bson:"maxunits,omitempty"`
+}
+
+// PlacementPolicies returns the placement policies of the service.
+func (s *Service) PlacementPolicies() []PlacementPolicy {
+	policies := make([]PlacementPolicy, len(s.doc.PlacementPolicies))
+	for i, doc := range s.doc.PlacementPolicies {
+		policies[i] = PlacementPolicy{
+			Kind:     doc.Kind,
+			Service:  doc.Service,
+			MaxUnits: doc.MaxUnits,
+		}
+	}
+	return policies
+}
+
+// SetPlacementPolicies replaces the placement policies of the service.
+// The policies are honoured when units are assigned to machines and
+// when machines are provisioned; units already placed are not moved.
+// The services named by the policies need not exist.
+func (s *Service) SetPlacementPolicies(policies []PlacementPolicy) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot set placement policies of service %q", s.doc.Name)
+	var docs []placementPolicyDoc
+	seen := make(set.Strings)
+	for _, p := range policies {
+		if err := p.Validate(s.doc.Name); err != nil {
+			return errors.Trace(err)
+		}
+		key := string(p.Kind) + "=" + p.Service
+		if seen.Contains(key) {
+			return errors.Errorf("duplicate %s policy", p.Kind)
+		}
+		seen.Add(key)
+		docs = append(docs, placementPolicyDoc{
+			Kind:     p.Kind,
+			Service:  p.Service,
+			MaxUnits: p.MaxUnits,
+		})
+	}
+	ops := []txn.Op{{
+		C:      servicesC,
+		Id:     s.doc.DocID,
+		Assert: isAliveDoc,
+		Update: bson.D{{"$set", bson.D{{"placementpolicies", docs}}}},
+	}}
+	if err := s.st.runTransaction(ops); err != nil {
+		return onAbort(err, errNotAlive)
+	}
+	s.doc.PlacementPolicies = docs
+	return nil
+}
+
+// placementPoliciesFor returns the placement policies that apply to the
+// units of the named service: its own, and the anti-affinity policies
+// of other services naming it.
+func (st *State) placementPoliciesFor(service string) ([]PlacementPolicy, error) {
+	services, closer := st.getCollection(servicesC)
+	defer closer()
+
+	var docs []serviceDoc
+	err := services.Find(bson.D{{"$or", []bson.D{
+		{{"name", service}},
+		{{"placementpolicies", bson.D{{"$elemMatch", bson.D{
+			{"kind", AntiAffinity},
+			{"service", service},
+		}}}}},
+	}}}).Select(bson.D{{"name", 1}, {"placementpolicies", 1}}).All(&docs)
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot read placement policies of service %q", service)
+	}
+	var policies []PlacementPolicy
+	for _, doc := range docs {
+		if doc.Name == service {
+			policies = append(policies, newService(st, &doc).PlacementPolicies()...)
+		} else {
+			policies = append(policies, PlacementPolicy{Kind: AntiAffinity, Service: doc.Name})
+		}
+	}
+	return policies, nil
+}
+
+// unitPlacement records where a principal unit is placed.
+type unitPlacement struct {
+	unit    string
+	service string
+	host    string
+	zone    string
+}
+
+// placementSnapshot describes where the principal units of some
+// services are placed.
+type placementSnapshot struct {
+	units []unitPlacement
+
+	// zones maps the ids of provisioned top level machines to their
+	// availability zones, where known.
+	zones map[string]string
+
+	// revnos maps the names of the services whose units are included
+	// to their placement revision numbers, or to -1 for services that
+	// do not exist.
+	revnos map[string]int64
+}
+
+// placementAttempts is the number of times an assignment is checked
+// against the placement policies and retried, when units it depends on
+// are assigned concurrently.
+const placementAttempts = 3
+
+// placementServices returns the names of the services whose placement
+// the given policies of the named service depend on.
+func placementServices(service string, policies []PlacementPolicy) []string {
+	services := set.NewStrings(service)
+	for _, p := range policies {
+		if p.Service != "" {
+			services.Add(p.Service)
+		}
+	}
+	return services.SortedValues()
+}
+
+// readPlacement returns a snapshot of the placement of the principal
+// units of the named services, with the availability zones of the
+// machines hosting them and of the given top level machines. Only
+// those units and instances are read, by the indexed unit service and
+// instance data id, so the cost does not grow with the environment.
+func (st *State) readPlacement(services []string, hosts []string) (*placementSnapshot, error) {
+	serviceColl, closer := st.getCollection(servicesC)
+	defer closer()
+	var sdocs []serviceDoc
+	err := serviceColl.Find(bson.D{{"name", bson.D{{"$in", services}}}}).Select(
+		bson.D{{"name", 1}, {"placementrevno", 1}},
+	).All(&sdocs)
+	if err != nil {
+		return nil, errors.Annotate(err, "cannot read placement revisions")
+	}
+
+	units, closer := st.getCollection(unitsC)
+	defer closer()
+	var udocs []unitDoc
+	err = units.Find(bson.D{
+		{"service", bson.D{{"$in", services}}},
+		{"principal", ""},
+		{"machineid", bson.D{{"$ne", ""}}},
+	}).Select(bson.D{{"name", 1}, {"service", 1}, {"machineid", 1}}).Sort("name").All(&udocs)
+	if err != nil {
+		return nil, errors.Annotate(err, "cannot read unit placement")
+	}
+
+	hostIds := set.NewStrings(hosts...)
+	for _, doc := range udocs {
+		hostIds.Add(TopParentId(doc.MachineId))
+	}
+	instanceIds := make([]string, 0, len(hostIds))
+	for _, id := range hostIds.SortedValues() {
+		instanceIds = append(instanceIds, st.docID(id))
+	}
+	instances, closer := st.getCollection(instanceDataC)
+	defer closer()
+	var idocs []instanceData
+	err = instances.Find(bson.D{
+		{"_id", bson.D{{"$in", instanceIds}}},
+		{"availzone", bson.D{{"$exists", true}}},
+	}).All(&idocs)
+	if err != nil {
+		return nil, errors.Annotate(err, "cannot read availability zones")
+	}
+
+	snapshot := &placementSnapshot{
+		zones:  make(map[string]string),
+		revnos: make(map[string]int64),
+	}
+	for _, service := range services {
+		snapshot.revnos[service] = -1
+	}
+	for _, doc := range sdocs {
+		snapshot.revnos[doc.Name] = doc.PlacementRevno
+	}
+	for _, doc := range idocs {
+		if doc.AvailZone != nil && *doc.AvailZone != "" {
+			snapshot.zones[doc.MachineId] = *doc.AvailZone
+		}
+	}
+	for _, doc := range udocs {
+		host := TopParentId(doc.MachineId)
+		snapshot.units = append(snapshot.units, unitPlacement{
+			unit:    doc.Name,
+			service: doc.Service,
+			host:    host,
+			zone:    snapshot.zones[host],
+		})
+	}
+	return snapshot, nil
+}
+
+// readUnitPlacement returns the placement policies of the unit and a
+// snapshot of the placement of the units they depend on, including
+// the zones of the given top level machines.
+func (u *Unit) readUnitPlacement(hosts []string) ([]PlacementPolicy, *placementSnapshot, error) {
+	policies, err := u.st.placementPoliciesFor(u.doc.Service)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	snapshot, err := u.st.readPlacement(placementServices(u.doc.Service, policies), hosts)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	return policies, snapshot, nil
+}
+
+// placementOps returns the operations that record the assignment of a
+// principal unit of the named service, checked against the snapshot,
+// and assert that no unit of the services in the snapshot has been
+// assigned since it was read. Every assignment increments the
+// placement revision number of the unit's service, so assignments that
+// depend on each other's placement cannot both succeed.
+func (snapshot *placementSnapshot) placementOps(st *State, service string) []txn.Op {
+	if snapshot == nil {
+		return nil
+	}
+	names := make([]string, 0, len(snapshot.revnos))
+	for name := range snapshot.revnos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var ops []txn.Op
+	for _, name := range names {
+		op := txn.Op{
+			C:      servicesC,
+			Id:     st.docID(name),
+			Assert: txn.DocMissing,
+		}
+		if revno := snapshot.revnos[name]; revno != -1 {
+			op.Assert = placementRevnoAssert(revno)
+		}
+		if name == service {
+			op.Update = bson.D{{"$inc", bson.D{{"placementrevno", 1}}}}
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// placementRevnoAssert asserts that a service's placement revision
+// number is unchanged.
+func placementRevnoAssert(revno int64) bson.D {
+	if revno == 0 {
+		// Services added before placement revisions were recorded
+		// have no placementrevno field.
+		return bson.D{{"placementrevno", bson.D{{"$in", []interface{}{0, nil}}}}}
+	}
+	return bson.D{{"placementrevno", revno}}
+}
+
+// zoneCounts returns the number of units of the named service in each
+// availability zone.
+func (snapshot *placementSnapshot) zoneCounts(service string) map[string]int {
+	counts := make(map[string]int)
+	for _, u := range snapshot.units {
+		if u.service == service && u.zone != "" {
+			counts[u.zone]++
+		}
+	}
+	return counts
+}
+
+// hostConflict returns the name of a unit, on the top level machine of
+// the machine with the given id, that a unit must not share it with
+// under the given policies; or "" if there is none.
+func (snapshot *placementSnapshot) hostConflict(policies []PlacementPolicy, machineId string) string {
+	avoid := make(set.Strings)
+	for _, p := range policies {
+		if p.Kind == AntiAffinity {
+			avoid.Add(p.Service)
+		}
+	}
+	host := TopParentId(machineId)
+	for _, u := range snapshot.units {
+		if u.host == host && avoid.Contains(u.service) {
+			return u.unit
+		}
+	}
+	return ""
+}
+
+// zonePlacement returns the availability zones in which units of the
+// named service may be placed under the given policies.
+func (snapshot *placementSnapshot) zonePlacement(service string, policies []PlacementPolicy) ZonePlacement {
+	var placement ZonePlacement
+	own := snapshot.zoneCounts(service)
+	excluded := make(set.Strings)
+	avoided := make(set.Strings)
+	for _, p := range policies {
+		switch p.Kind {
+		case MaxUnitsPerZone:
+			for zone, n := range own {
+				if n >= p.MaxUnits {
+					excluded.Add(zone)
+				}
+			}
+		case AntiAffinity:
+			for zone := range snapshot.zoneCounts(p.Service) {
+				avoided.Add(zone)
+			}
+		case Affinity:
+			// Zones where units of the other service lack a partner
+			// are the only ones allowed, until there are none.
+			var lacking []string
+			for zone, n := range snapshot.zoneCounts(p.Service) {
+				if n > own[zone] {
+					lacking = append(lacking, zone)
+				}
+			}
+			if len(lacking) == 0 {
+				continue
+			}
+			placement.Allowed = intersectZones(placement.Allowed, lacking)
+		}
+	}
+	placement.Excluded = sortedZoneSet(excluded)
+	placement.Avoided = sortedZoneSet(avoided)
+	return placement
+}
+
+// allows reports whether a unit of the named service may be placed on
+// the machine with the given id under the given policies. Machines
+// whose availability zone is not known are only checked against the
+// units they host.
+func (snapshot *placementSnapshot) allows(service string, policies []PlacementPolicy, machineId string) bool {
+	if snapshot.hostConflict(policies, machineId) != "" {
+		return false
+	}
+	zone, ok := snapshot.zones[TopParentId(machineId)]
+	if !ok {
+		return true
+	}
+	return snapshot.zonePlacement(service, policies).Allows(zone)
+}
+
+// ZonePlacement describes the availability zones in which a machine
+// may be started, to honour the placement policies of the units
+// assigned to it.
+type ZonePlacement struct {
+	// Allowed holds the only zones the machine may be started in, if
+	// it is not nil.
+	Allowed []string
+
+	// Excluded holds zones the machine must not be started in.
+	Excluded []string
+
+	// Avoided holds zones the machine should not be started in if
+	// there is a choice.
+	Avoided []string
+}
+
+// Allows reports whether the placement allows the given zone.
+func (p ZonePlacement) Allows(zone string) bool {
+	if p.Allowed != nil && !set.NewStrings(p.Allowed...).Contains(zone) {
+		return false
+	}
+	return !set.NewStrings(p.Excluded...).Contains(zone)
+}
+
+// checkHostPlacement returns an error if the unit must not be placed
+// on the machine with the given id under its placement policies.
+// Otherwise it returns the snapshot the placement was checked against,
+// for the assignment to assert that it still holds.
+func (u *Unit) checkHostPlacement(machineId string) (*placementSnapshot, error) {
+	policies, snapshot, err := u.readUnitPlacement([]string{TopParentId(machineId)})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if other := snapshot.hostConflict(policies, machineId); other != "" {
+		return nil, errors.Errorf("anti-affinity placement policy: machine %s hosts unit %q", TopParentId(machineId), other)
+	}
+	return snapshot, nil
+}
+
+// ZonePlacement returns the availability zones in which the machine
+// may be started, to honour the placement policies of the units
+// assigned to it and to its containers.
+func (m *Machine) ZonePlacement() (ZonePlacement, error) {
+	host := TopParentId(m.doc.Id)
+	units, closer := m.st.getCollection(unitsC)
+	defer closer()
+	var udocs []unitDoc
+	err := units.Find(bson.D{
+		{"principal", ""},
+		{"machineid", bson.RegEx{Pattern: "^" + regexp.QuoteMeta(host) + "(/|$)"}},
+	}).Select(bson.D{{"service", 1}}).All(&udocs)
+	if err != nil {
+		return ZonePlacement{}, errors.Annotatef(err, "cannot read units of machine %s", host)
+	}
+	servicePolicies := make(map[string][]PlacementPolicy)
+	relevant := make(set.Strings)
+	for _, doc := range udocs {
+		if _, ok := servicePolicies[doc.Service]; ok {
+			continue
+		}
+		policies, err := m.st.placementPoliciesFor(doc.Service)
+		if err != nil {
+			return ZonePlacement{}, errors.Trace(err)
+		}
+		servicePolicies[doc.Service] = policies
+		relevant = relevant.Union(set.NewStrings(placementServices(doc.Service, policies)...))
+	}
+	var placement ZonePlacement
+	if len(servicePolicies) == 0 {
+		return placement, nil
+	}
+	snapshot, err := m.st.readPlacement(relevant.SortedValues(), []string{host})
+	if err != nil {
+		return ZonePlacement{}, errors.Trace(err)
+	}
+	excluded := make(set.Strings)
+	avoided := make(set.Strings)
+	for _, service := range relevant.SortedValues() {
+		policies, ok := servicePolicies[service]
+		if !ok {
+			continue
+		}
+		p := snapshot.zonePlacement(service, policies)
+		if p.Allowed != nil {
+			placement.Allowed = intersectZones(placement.Allowed, p.Allowed)
+		}
+		excluded = excluded.Union(set.NewStrings(p.Excluded...))
+		avoided = avoided.Union(set.NewStrings(p.Avoided...))
+	}
+	placement.Excluded = sortedZoneSet(excluded)
+	placement.Avoided = sortedZoneSet(avoided)
+	return placement, nil
+}
+
+// PlacementViolations describes how the current placement of the
+// service's units violates its placement policies, as may happen when
+// policies are set after units are placed, or when several machines are
+// provisioned at once.
+func (s *Service) PlacementViolations() ([]string, error) {
+	policies := s.PlacementPolicies()
+	if len(policies) == 0 {
+		return nil, nil
+	}
+	snapshot, err := s.st.readPlacement(placementServices(s.doc.Name, policies), nil)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var violations []string
+	own := snapshot.zoneCounts(s.doc.Name)
+	for _, p := range policies {
+		switch p.Kind {
+		case AntiAffinity:
+			for _, u := range snapshot.units {
+				if u.service != s.doc.Name {
+					continue
+				}
+				for _, other := range snapshot.units {
+					// Report each pair of units of the same service once.
+					if other.host != u.host || other.service != p.Service || other.unit == u.unit ||
+						(p.Service == s.doc.Name && other.unit < u.unit) {
+						continue
+					}
+					violations = append(violations, fmt.Sprintf(
+						"%s: units %q and %q share machine %s", p, u.unit, other.unit, u.host,
+					))
+				}
+			}
+		case Affinity:
+			other := snapshot.zoneCounts(p.Service)
+			var lacking []string
+			for _, zone := range sortedZones(other) {
+				if other[zone] > own[zone] {
+					lacking = append(lacking, zone)
+				}
+			}
+			if len(lacking) == 0 {
+				continue
+			}
+			for _, zone := range sortedZones(own) {
+				if own[zone] > other[zone] {
+					violations = append(violations, fmt.Sprintf(
+						"%s: zone %q has %d units for %d of %q, while zones %q lack units",
+						p, zone, own[zone], other[zone], p.Service, lacking,
+					))
+				}
+			}
+		case MaxUnitsPerZone:
+			for _, zone := range sortedZones(own) {
+				if own[zone] > p.MaxUnits {
+					violations = append(violations, fmt.Sprintf("%s: zone %q has %d units", p, zone, own[zone]))
+				}
+			}
+		}
+	}
+	return violations, nil
+}
+
+func sortedZones(counts map[string]int) []string {
+	zones := make([]string, 0, len(counts))
+	for zone := range counts {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	return zones
+}
+
+// sortedZoneSet returns the zones in the set in order, or nil if there
+// are none.
+func sortedZoneSet(zones set.Strings) []string {
+	if zones.IsEmpty() {
+		return nil
+	}
+	return zones.SortedValues()
+}
+
+// intersectZones returns the zones in both of the given lists, treating
+// a nil list as holding every zone.
+func intersectZones(a, b []string) []string {
+	var zones []string
+	switch {
+	case a == nil && b == nil:
+		return nil
+	case a == nil:
+		zones = set.NewStrings(b...).SortedValues()
+	case b == nil:
+		zones = set.NewStrings(a...).SortedValues()
+	default:
+		zones = set.NewStrings(a...).Intersection(set.NewStrings(b...)).SortedValues()
+	}
+	if zones == nil {
+		zones = []string{}
+	}
+	return zones
+}
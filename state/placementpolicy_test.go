@@ -0,0 +1,224 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/state"
+)
+
+type PlacementPolicySuite struct {
+	ConnSuite
+	wordpress *state.Service
+	mysql     *state.Service
+}
+
+var _ = gc.Suite(&PlacementPolicySuite{})
+
+func (s *PlacementPolicySuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	s.wordpress = s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	s.mysql = s.AddTestingService(c, "mysql", s.AddTestingCharm(c, "mysql"))
+}
+
+func (s *PlacementPolicySuite) addMachine(c *gc.C, zone string) *state.Machine {
+	m, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	if zone != "" {
+		hc := &instance.HardwareCharacteristics{AvailabilityZone: &zone}
+		err = m.SetProvisioned(instance.Id("i-"+m.Id()), "fake_nonce", hc)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	return m
+}
+
+func (s *PlacementPolicySuite) addUnit(c *gc.C, svc *state.Service, m *state.Machine) *state.Unit {
+	u, err := svc.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	err = u.AssignToMachine(m)
+	c.Assert(err, jc.ErrorIsNil)
+	return u
+}
+
+func (s *PlacementPolicySuite) setPolicies(c *gc.C, svc *state.Service, policies ...state.PlacementPolicy) {
+	err := svc.SetPlacementPolicies(policies)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *PlacementPolicySuite) TestSetPlacementPolicies(c *gc.C) {
+	c.Assert(s.wordpress.PlacementPolicies(), gc.HasLen, 0)
+	policies := []state.PlacementPolicy{
+		{Kind: state.AntiAffinity, Service: "wordpress"},
+		{Kind: state.Affinity, Service: "mysql"},
+		{Kind: state.MaxUnitsPerZone, MaxUnits: 2},
+	}
+	s.setPolicies(c, s.wordpress, policies...)
+	c.Assert(s.wordpress.PlacementPolicies(), gc.DeepEquals, policies)
+
+	wordpress, err := s.State.Service("wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(wordpress.PlacementPolicies(), gc.DeepEquals, policies)
+	c.Assert(policies[2].String(), gc.Equals, "max-units-per-zone=2")
+
+	s.setPolicies(c, s.wordpress)
+	c.Assert(s.wordpress.PlacementPolicies(), gc.HasLen, 0)
+}
+
+func (s *PlacementPolicySuite) TestSetPlacementPoliciesInvalid(c *gc.C) {
+	for i, test := range []struct {
+		policies []state.PlacementPolicy
+		err      string
+	}{{
+		policies: []state.PlacementPolicy{{Kind: "nearby", Service: "mysql"}},
+		err:      `placement policy kind "nearby" not valid`,
+	}, {
+		policies: []state.PlacementPolicy{{Kind: state.Affinity, Service: "wordpress"}},
+		err:      `affinity policy of service "wordpress" with itself not valid`,
+	}, {
+		policies: []state.PlacementPolicy{{Kind: state.AntiAffinity, Service: "my_sql"}},
+		err:      `anti-affinity policy with service name "my_sql" not valid`,
+	}, {
+		policies: []state.PlacementPolicy{{Kind: state.MaxUnitsPerZone}},
+		err:      `max-units-per-zone policy with limit 0 not valid`,
+	}, {
+		policies: []state.PlacementPolicy{
+			{Kind: state.MaxUnitsPerZone, MaxUnits: 1},
+			{Kind: state.MaxUnitsPerZone, MaxUnits: 2},
+		},
+		err: `duplicate max-units-per-zone policy`,
+	}} {
+		c.Logf("test %d", i)
+		err := s.wordpress.SetPlacementPolicies(test.policies)
+		c.Check(err, gc.ErrorMatches, `cannot set placement policies of service "wordpress": `+test.err)
+	}
+	c.Assert(s.wordpress.PlacementPolicies(), gc.HasLen, 0)
+}
+
+func (s *PlacementPolicySuite) TestAssignToMachineAntiAffinity(c *gc.C) {
+	s.setPolicies(c, s.wordpress, state.PlacementPolicy{Kind: state.AntiAffinity, Service: "mysql"})
+	m0 := s.addMachine(c, "")
+	s.addUnit(c, s.mysql, m0)
+
+	u, err := s.wordpress.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	err = u.AssignToMachine(m0)
+	c.Assert(err, gc.ErrorMatches, `cannot assign unit "wordpress/0" to machine 0: anti-affinity placement policy: machine 0 hosts unit "mysql/0"`)
+
+	// Containers share their host.
+	container, err := s.State.AddMachineInsideMachine(state.MachineTemplate{
+		Series: "quantal",
+		Jobs:   []state.MachineJob{state.JobHostUnits},
+	}, m0.Id(), instance.LXC)
+	c.Assert(err, jc.ErrorIsNil)
+	err = u.AssignToMachine(container)
+	c.Assert(err, gc.ErrorMatches, `cannot assign unit "wordpress/0" to machine 0/lxc/0: anti-affinity placement policy: machine 0 hosts unit "mysql/0"`)
+
+	// The policy applies to the units of the other service too.
+	m1 := s.addMachine(c, "")
+	s.addUnit(c, s.wordpress, m1)
+	mysql1, err := s.mysql.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	err = mysql1.AssignToMachine(m1)
+	c.Assert(err, gc.ErrorMatches, `cannot assign unit "mysql/1" to machine 1: anti-affinity placement policy: machine 1 hosts unit "wordpress/1"`)
+	err = mysql1.AssignToMachine(m0)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *PlacementPolicySuite) TestAssignToMachineAntiAffinityConcurrent(c *gc.C) {
+	s.setPolicies(c, s.wordpress, state.PlacementPolicy{Kind: state.AntiAffinity, Service: "mysql"})
+	m0 := s.addMachine(c, "")
+	u, err := s.wordpress.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+
+	// A mysql unit is placed on the machine after the wordpress unit's
+	// placement has been checked, but before it is assigned.
+	defer state.SetBeforeHooks(c, s.State, func() {
+		s.addUnit(c, s.mysql, m0)
+	}).Check()
+	err = u.AssignToMachine(m0)
+	c.Assert(err, gc.ErrorMatches, `cannot assign unit "wordpress/0" to machine 0: anti-affinity placement policy: machine 0 hosts unit "mysql/0"`)
+}
+
+func (s *PlacementPolicySuite) TestAssignToCleanMachine(c *gc.C) {
+	s.setPolicies(c, s.wordpress, state.PlacementPolicy{Kind: state.MaxUnitsPerZone, MaxUnits: 1})
+	m0 := s.addMachine(c, "zone-a")
+	s.addUnit(c, s.wordpress, m0)
+	s.addMachine(c, "zone-a")
+	m2 := s.addMachine(c, "zone-b")
+
+	u, err := s.wordpress.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	m, err := u.AssignToCleanMachine()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.Id(), gc.Equals, m2.Id())
+
+	// Both zones are now full.
+	u, err = s.wordpress.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = u.AssignToCleanMachine()
+	c.Assert(err, gc.ErrorMatches, "all eligible machines in use")
+}
+
+func (s *PlacementPolicySuite) TestZonePlacement(c *gc.C) {
+	s.setPolicies(c, s.wordpress,
+		state.PlacementPolicy{Kind: state.Affinity, Service: "mysql"},
+		state.PlacementPolicy{Kind: state.MaxUnitsPerZone, MaxUnits: 1},
+	)
+	varnish := s.AddTestingService(c, "varnish", s.AddTestingCharm(c, "varnish"))
+	s.setPolicies(c, varnish, state.PlacementPolicy{Kind: state.AntiAffinity, Service: "wordpress"})
+
+	s.addUnit(c, s.mysql, s.addMachine(c, "zone-a"))
+	s.addUnit(c, s.mysql, s.addMachine(c, "zone-b"))
+	s.addUnit(c, s.wordpress, s.addMachine(c, "zone-a"))
+	s.addUnit(c, varnish, s.addMachine(c, "zone-c"))
+
+	m := s.addMachine(c, "")
+	placement, err := m.ZonePlacement()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(placement.Allowed, gc.IsNil)
+	c.Assert(placement.Excluded, gc.HasLen, 0)
+
+	s.addUnit(c, s.wordpress, m)
+	placement, err = m.ZonePlacement()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(placement, jc.DeepEquals, state.ZonePlacement{
+		Allowed:  []string{"zone-b"},
+		Excluded: []string{"zone-a"},
+		Avoided:  []string{"zone-c"},
+	})
+	c.Assert(placement.Allows("zone-b"), jc.IsTrue)
+	c.Assert(placement.Allows("zone-c"), jc.IsFalse)
+}
+
+func (s *PlacementPolicySuite) TestPlacementViolations(c *gc.C) {
+	m0 := s.addMachine(c, "zone-a")
+	m1 := s.addMachine(c, "zone-a")
+	s.addUnit(c, s.wordpress, m0)
+	s.addUnit(c, s.wordpress, m0)
+	s.addUnit(c, s.wordpress, m1)
+	s.addUnit(c, s.mysql, m1)
+	s.addUnit(c, s.mysql, s.addMachine(c, "zone-b"))
+
+	violations, err := s.wordpress.PlacementViolations()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(violations, gc.HasLen, 0)
+
+	s.setPolicies(c, s.wordpress,
+		state.PlacementPolicy{Kind: state.AntiAffinity, Service: "wordpress"},
+		state.PlacementPolicy{Kind: state.AntiAffinity, Service: "mysql"},
+		state.PlacementPolicy{Kind: state.Affinity, Service: "mysql"},
+		state.PlacementPolicy{Kind: state.MaxUnitsPerZone, MaxUnits: 2},
+	)
+	violations, err = s.wordpress.PlacementViolations()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(violations, jc.DeepEquals, []string{
+		`anti-affinity=wordpress: units "wordpress/0" and "wordpress/1" share machine 0`,
+		`anti-affinity=mysql: units "wordpress/2" and "mysql/0" share machine 1`,
+		`affinity=mysql: zone "zone-a" has 3 units for 1 of "mysql", while zones ["zone-b"] lack units`,
+		`max-units-per-zone=2: zone "zone-a" has 3 units`,
+	})
+}
@@ -44,6 +44,12 @@ type relationDoc struct {
 	Endpoints []Endpoint
 	Life      Life
 	UnitCount int
+
+	// RemoteEnvUUID and RemoteService are set when one of the
+	// endpoints belongs to a remote service; they hold the service's
+	// name and the UUID of the environment it runs in.
+	RemoteEnvUUID string `bson:"remoteenvuuid,omitempty"`
+	RemoteService string `bson:"remoteservice,omitempty"`
 }
 
 // Relation represents a relation between one or two service endpoints.
@@ -147,7 +153,7 @@ func (r *Relation) destroyOps(ignoreService string) (ops []txn.Op, isRemove bool
 		return nil, false, errAlreadyDying
 	}
 	if r.doc.UnitCount == 0 {
-		removeOps, err := r.removeOps(ignoreService, nil)
+		removeOps, err := r.removeOps(ignoreService, "")
 		if err != nil {
 			return nil, false, err
 		}
@@ -163,16 +169,16 @@ func (r *Relation) destroyOps(ignoreService string) (ops []txn.Op, isRemove bool
 
 // removeOps returns the operations necessary to remove the relation. If
 // ignoreService is not empty, no operations affecting that service will be
-// included; if departingUnit is not nil, this implies that the relation's
+// included; if departingUnit is not empty, this implies that the relation's
 // services may be Dying and otherwise unreferenced, and may thus require
 // removal themselves.
-func (r *Relation) removeOps(ignoreService string, departingUnit *Unit) ([]txn.Op, error) {
+func (r *Relation) removeOps(ignoreService string, departingUnit string) ([]txn.Op, error) {
 	relOp := txn.Op{
 		C:      relationsC,
 		Id:     r.doc.DocID,
 		Remove: true,
 	}
-	if departingUnit != nil {
+	if departingUnit != "" {
 		relOp.Assert = bson.D{{"life", Dying}, {"unitcount", 1}}
 	} else {
 		relOp.Assert = bson.D{{"life", Alive}, {"unitcount", 0}}
@@ -182,14 +188,22 @@ func (r *Relation) removeOps(ignoreService string, departingUnit *Unit) ([]txn.O
 		if ep.ServiceName == ignoreService {
 			continue
 		}
+		if ep.ServiceName == r.doc.RemoteService {
+			remoteOps, err := r.remoteServiceRemoveOps(departingUnit != "")
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, remoteOps...)
+			continue
+		}
 		var asserts bson.D
 		hasRelation := bson.D{{"relationcount", bson.D{{"$gt", 0}}}}
-		if departingUnit == nil {
+		if departingUnit == "" {
 			// We're constructing a destroy operation, either of the relation
 			// or one of its services, and can therefore be assured that both
 			// services are Alive.
 			asserts = append(hasRelation, isAliveDoc...)
-		} else if ep.ServiceName == departingUnit.ServiceName() {
+		} else if ep.ServiceName == names.UnitService(departingUnit) {
 			// This service must have at least one unit -- the one that's
 			// departing the relation -- so it cannot be ready for removal.
 			cannotDieYet := bson.D{{"unitcount", bson.D{{"$gt", 0}}}}
@@ -203,7 +217,11 @@ func (r *Relation) removeOps(ignoreService string, departingUnit *Unit) ([]txn.O
 			hasLastRef := bson.D{{"life", Dying}, {"unitcount", 0}, {"relationcount", 1}}
 			removable := append(bson.D{{"_id", ep.ServiceName}}, hasLastRef...)
 			if err := services.Find(removable).One(&svc.doc); err == nil {
-				ops = append(ops, svc.removeOps(hasLastRef)...)
+				removeOps, err := svc.removeOps(hasLastRef)
+				if err != nil {
+					return nil, errors.Trace(err)
+				}
+				ops = append(ops, removeOps...)
 				continue
 			} else if err != mgo.ErrNotFound {
 				return nil, err
@@ -227,6 +245,38 @@ func (r *Relation) removeOps(ignoreService string, departingUnit *Unit) ([]txn.O
 	return append(ops, cleanupOp), nil
 }
 
+// remoteServiceRemoveOps returns the operations necessary to release
+// the relation's reference on its remote service. If a unit is departing
+// the relation, the remote service may be Dying and unreferenced, and is
+// then removed too.
+func (r *Relation) remoteServiceRemoveOps(departing bool) ([]txn.Op, error) {
+	hasRelation := bson.D{{"relationcount", bson.D{{"$gt", 0}}}}
+	asserts := append(hasRelation, isAliveDoc...)
+	if departing {
+		remoteServices, closer := r.st.getCollection(remoteServicesC)
+		defer closer()
+
+		svc := &RemoteService{st: r.st}
+		hasLastRef := bson.D{{"life", Dying}, {"relationcount", 1}}
+		removable := append(bson.D{{"_id", r.doc.RemoteService}}, hasLastRef...)
+		if err := remoteServices.Find(removable).One(&svc.doc); err == nil {
+			return []txn.Op{svc.removeOps(hasLastRef)}, nil
+		} else if err != mgo.ErrNotFound {
+			return nil, err
+		}
+		asserts = bson.D{{"$or", []bson.D{
+			{{"life", Alive}},
+			{{"relationcount", bson.D{{"$gt", 1}}}},
+		}}}
+	}
+	return []txn.Op{{
+		C:      remoteServicesC,
+		Id:     r.st.docID(r.doc.RemoteService),
+		Assert: asserts,
+		Update: bson.D{{"$inc", bson.D{{"relationcount", -1}}}},
+	}}, nil
+}
+
 // Id returns the integer internal relation key. This is exposed
 // because the unit agent needs to expose a value derived from this
 // (as JUJU_RELATION_ID) to allow relation hooks to differentiate
@@ -294,3 +344,227 @@ func (r *Relation) Unit(u *Unit) (*RelationUnit, error) {
 		scope:    strings.Join(scope, "#"),
 	}, nil
 }
+
+// IsRemote returns whether one of the relation's endpoints belongs to a
+// remote service.
+func (r *Relation) IsRemote() bool {
+	return r.doc.RemoteService != ""
+}
+
+// RemoteServiceName returns the name of the remote service of the
+// relation, or "" if the relation is not remote.
+func (r *Relation) RemoteServiceName() string {
+	return r.doc.RemoteService
+}
+
+// RemoteEnvUUID returns the UUID of the environment running the remote
+// service of the relation, or "" if the relation is not remote.
+func (r *Relation) RemoteEnvUUID() string {
+	return r.doc.RemoteEnvUUID
+}
+
+// WatchUnits returns a watcher that notifies of the units of the named
+// service entering and leaving the relation's scope, and of changes to
+// their settings in the relation.
+func (r *Relation) WatchUnits(serviceName string) (RelationUnitsWatcher, error) {
+	ep, err := r.Endpoint(serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if ep.Scope == charm.ScopeContainer {
+		return nil, errors.Errorf("cannot watch units of container scoped relation %q", r)
+	}
+	scope := fmt.Sprintf("r#%d#%s", r.doc.Id, ep.Role)
+	return newRelationUnitsWatcher(r.st, newRelationScopeWatcher(r.st, scope, "")), nil
+}
+
+// UnitSettings returns the settings of the named unit in the relation.
+func (r *Relation) UnitSettings(unitName string) (map[string]interface{}, error) {
+	key, err := r.unitKey(unitName)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := readSettings(r.st, key)
+	if err != nil {
+		return nil, err
+	}
+	return settings.Map(), nil
+}
+
+// unitKey returns the scope and settings key of the named unit in the
+// relation, which must not have container scope.
+func (r *Relation) unitKey(unitName string) (string, error) {
+	if !names.IsValidUnit(unitName) {
+		return "", errors.NotValidf("unit name %q", unitName)
+	}
+	ep, err := r.Endpoint(names.UnitService(unitName))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("r#%d#%s#%s", r.doc.Id, ep.Role, unitName), nil
+}
+
+// remoteUnitKey returns the scope and settings key of the named unit of
+// the relation's remote service.
+func (r *Relation) remoteUnitKey(unitName string) (string, error) {
+	if !r.IsRemote() {
+		return "", errors.Errorf("relation %q is not remote", r)
+	}
+	if !names.IsValidUnit(unitName) {
+		return "", errors.NotValidf("unit name %q", unitName)
+	}
+	if names.UnitService(unitName) != r.doc.RemoteService {
+		return "", errors.Errorf("%q is not a unit of remote service %q", unitName, r.doc.RemoteService)
+	}
+	return r.unitKey(unitName)
+}
+
+// SetRemoteUnitSettings records the settings of a unit of the relation's
+// remote service, as published in the remote environment. The unit enters
+// the relation's scope if it has not already.
+func (r *Relation) SetRemoteUnitSettings(unitName string, settings map[string]interface{}) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot set settings of remote unit %q in relation %q", unitName, r)
+	key, err := r.remoteUnitKey(unitName)
+	if err != nil {
+		return err
+	}
+	rel := &Relation{r.st, r.doc}
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := rel.Refresh(); err != nil {
+				return nil, err
+			}
+		}
+		relationScopes, closer := r.st.getCollection(relationScopesC)
+		defer closer()
+		settingsColl, closer := r.st.getCollection(settingsC)
+		defer closer()
+
+		// As in EnterScope, the settings must be written before the
+		// scope doc is created.
+		var ops []txn.Op
+		if count, err := settingsColl.FindId(key).Count(); err != nil {
+			return nil, err
+		} else if count == 0 {
+			ops = append(ops, createSettingsOp(r.st, key, settings))
+		} else {
+			rop, _, err := replaceSettingsOp(r.st, key, settings)
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, rop)
+		}
+		if count, err := relationScopes.FindId(key).Count(); err != nil {
+			return nil, err
+		} else if count != 0 {
+			return ops, nil
+		}
+		if rel.doc.Life != Alive {
+			return nil, ErrCannotEnterScope
+		}
+		rsDocID := r.st.docID(key)
+		return append(ops, txn.Op{
+			C:      relationsC,
+			Id:     rel.doc.DocID,
+			Assert: isAliveDoc,
+			Update: bson.D{{"$inc", bson.D{{"unitcount", 1}}}},
+		}, txn.Op{
+			C:      relationScopesC,
+			Id:     rsDocID,
+			Assert: txn.DocMissing,
+			Insert: relationScopeDoc{
+				DocID:   rsDocID,
+				Key:     key,
+				EnvUUID: r.st.EnvironUUID(),
+			},
+		}), nil
+	}
+	return r.st.run(buildTxn)
+}
+
+// RemoveRemoteUnit records that a unit of the relation's remote service
+// has left the relation in the remote environment. As with units leaving
+// scope, the relation is removed if it is Dying and this was its last
+// unit.
+func (r *Relation) RemoveRemoteUnit(unitName string) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot remove remote unit %q from relation %q", unitName, r)
+	key, err := r.remoteUnitKey(unitName)
+	if err != nil {
+		return err
+	}
+	relationScopes, closer := r.st.getCollection(relationScopesC)
+	defer closer()
+
+	rel := &Relation{r.st, r.doc}
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := rel.Refresh(); errors.IsNotFound(err) {
+				return nil, jujutxn.ErrNoOperations
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		if count, err := relationScopes.FindId(key).Count(); err != nil {
+			return nil, err
+		} else if count == 0 {
+			return nil, jujutxn.ErrNoOperations
+		}
+		ops := []txn.Op{{
+			C:      relationScopesC,
+			Id:     r.st.docID(key),
+			Assert: txn.DocExists,
+			Remove: true,
+		}}
+		if rel.doc.Life == Alive {
+			ops = append(ops, txn.Op{
+				C:      relationsC,
+				Id:     rel.doc.DocID,
+				Assert: bson.D{{"life", Alive}},
+				Update: bson.D{{"$inc", bson.D{{"unitcount", -1}}}},
+			})
+		} else if rel.doc.UnitCount > 1 {
+			ops = append(ops, txn.Op{
+				C:      relationsC,
+				Id:     rel.doc.DocID,
+				Assert: bson.D{{"unitcount", bson.D{{"$gt", 1}}}},
+				Update: bson.D{{"$inc", bson.D{{"unitcount", -1}}}},
+			})
+		} else {
+			relOps, err := rel.removeOps("", unitName)
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, relOps...)
+		}
+		return ops, nil
+	}
+	return r.st.run(buildTxn)
+}
+
+// RemoveRemoteUnits removes all the units of the relation's remote
+// service from the relation, as when the relation has been removed from
+// the remote environment.
+func (r *Relation) RemoveRemoteUnits() error {
+	if !r.IsRemote() {
+		return errors.Errorf("relation %q is not remote", r)
+	}
+	ep, err := r.Endpoint(r.doc.RemoteService)
+	if err != nil {
+		return err
+	}
+	relationScopes, closer := r.st.getCollection(relationScopesC)
+	defer closer()
+
+	prefix := fmt.Sprintf("r#%d#%s#", r.doc.Id, ep.Role)
+	var docs []relationScopeDoc
+	sel := bson.D{{"key", bson.D{{"$regex", "^" + prefix}}}}
+	if err := relationScopes.Find(sel).All(&docs); err != nil {
+		return errors.Annotatef(err, "cannot get remote units of relation %q", r)
+	}
+	for _, doc := range docs {
+		if err := r.RemoveRemoteUnit(doc.unitName()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -38,9 +38,11 @@ func (ru *RelationUnit) Endpoint() Endpoint {
 	return ru.endpoint
 }
 
-// PrivateAddress returns the private address of the unit and whether it is valid.
+// PrivateAddress returns the private address of the unit in the space
+// the relation's endpoint is bound to, and whether it is valid. See
+// Unit.PrivateAddressForEndpoint.
 func (ru *RelationUnit) PrivateAddress() (string, bool) {
-	return ru.unit.PrivateAddress()
+	return ru.unit.PrivateAddressForEndpoint(ru.endpoint.Name)
 }
 
 // ErrCannotEnterScope indicates that a relation unit failed to enter its scope
@@ -335,7 +337,7 @@ func (ru *RelationUnit) LeaveScope() error {
 				Update: bson.D{{"$inc", bson.D{{"unitcount", -1}}}},
 			})
 		} else {
-			relOps, err := ru.relation.removeOps("", ru.unit)
+			relOps, err := ru.relation.removeOps("", ru.unit.Name())
 			if err != nil {
 				return nil, err
 			}
@@ -0,0 +1,345 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	jujutxn "github.com/juju/txn"
+	"gopkg.in/juju/charm.v4"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+// RemoteService represents a service of another environment hosted by
+// the same state server, which the services of this environment relate
+// to through the endpoints it offers. Remote services have no units in
+// this environment; the settings of their units in each relation are
+// copied from the source environment.
+type RemoteService struct {
+	st  *State
+	doc remoteServiceDoc
+}
+
+// remoteServiceDoc is the internal representation of a RemoteService
+// in MongoDB.
+type remoteServiceDoc struct {
+	DocID         string           `bson:"_id"`
+	Name          string           `bson:"name"`
+	EnvUUID       string           `bson:"env-uuid"`
+	SourceEnvUUID string           `bson:"sourceenvuuid"`
+	SourceEnvName string           `bson:"sourceenvname"`
+	Endpoints     []charm.Relation `bson:"endpoints"`
+	Life          Life             `bson:"life"`
+	RelationCount int              `bson:"relationcount"`
+}
+
+func newRemoteService(st *State, doc *remoteServiceDoc) *RemoteService {
+	return &RemoteService{
+		st:  st,
+		doc: *doc,
+	}
+}
+
+// Name returns the name of the remote service, which is also its name
+// in the source environment.
+func (s *RemoteService) Name() string {
+	return s.doc.Name
+}
+
+// String returns the name of the remote service qualified with the
+// name of its source environment.
+func (s *RemoteService) String() string {
+	return s.doc.SourceEnvName + "." + s.doc.Name
+}
+
+// SourceEnvUUID returns the UUID of the environment the service runs in.
+func (s *RemoteService) SourceEnvUUID() string {
+	return s.doc.SourceEnvUUID
+}
+
+// SourceEnvName returns the name of the environment the service runs in.
+func (s *RemoteService) SourceEnvName() string {
+	return s.doc.SourceEnvName
+}
+
+// Life returns whether the remote service is Alive, Dying or Dead.
+func (s *RemoteService) Life() Life {
+	return s.doc.Life
+}
+
+// Endpoints returns the endpoints of the remote service that can be
+// related to, sorted by name.
+func (s *RemoteService) Endpoints() []Endpoint {
+	eps := make([]Endpoint, len(s.doc.Endpoints))
+	for i, rel := range s.doc.Endpoints {
+		eps[i] = Endpoint{ServiceName: s.doc.Name, Relation: rel}
+	}
+	sort.Sort(epSlice(eps))
+	return eps
+}
+
+// Endpoint returns the endpoint of the remote service with the given
+// relation name.
+func (s *RemoteService) Endpoint(relationName string) (Endpoint, error) {
+	for _, ep := range s.Endpoints() {
+		if ep.Name == relationName {
+			return ep, nil
+		}
+	}
+	return Endpoint{}, fmt.Errorf("remote service %q has no %q relation", s, relationName)
+}
+
+// Relations returns the relations of the remote service.
+func (s *RemoteService) Relations() ([]*Relation, error) {
+	return serviceRelations(s.st, s.doc.Name)
+}
+
+// Refresh refreshes the contents of the remote service from the
+// underlying state. It returns an error that satisfies
+// errors.IsNotFound if the remote service has been removed.
+func (s *RemoteService) Refresh() error {
+	remoteServices, closer := s.st.getCollection(remoteServicesC)
+	defer closer()
+
+	err := remoteServices.FindId(s.doc.DocID).One(&s.doc)
+	if err == mgo.ErrNotFound {
+		return errors.NotFoundf("remote service %q", s.doc.Name)
+	}
+	if err != nil {
+		return errors.Annotatef(err, "cannot refresh remote service %q", s.doc.Name)
+	}
+	return nil
+}
+
+// Destroy ensures that the remote service and all its relations will be
+// removed at some point; if no relation involving the remote service
+// has any units in scope, they are all removed immediately.
+func (s *RemoteService) Destroy() (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot destroy remote service %q", s.doc.Name)
+	defer func() {
+		if err == nil {
+			// This is a white lie; the document might actually be removed.
+			s.doc.Life = Dying
+		}
+	}()
+	svc := &RemoteService{st: s.st, doc: s.doc}
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := svc.Refresh(); errors.IsNotFound(err) {
+				return nil, jujutxn.ErrNoOperations
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		switch ops, err := svc.destroyOps(); err {
+		case errRefresh:
+		case errAlreadyDying:
+			return nil, jujutxn.ErrNoOperations
+		case nil:
+			return ops, nil
+		default:
+			return nil, err
+		}
+		return nil, jujutxn.ErrTransientFailure
+	}
+	return s.st.run(buildTxn)
+}
+
+// destroyOps returns the operations required to destroy the remote
+// service. If it returns errRefresh, the remote service should be
+// refreshed and the destruction operations recalculated.
+func (s *RemoteService) destroyOps() ([]txn.Op, error) {
+	if s.doc.Life == Dying {
+		return nil, errAlreadyDying
+	}
+	rels, err := s.Relations()
+	if err != nil {
+		return nil, err
+	}
+	if len(rels) != s.doc.RelationCount {
+		return nil, errRefresh
+	}
+	var ops []txn.Op
+	removeCount := 0
+	for _, rel := range rels {
+		relOps, isRemove, err := rel.destroyOps(s.doc.Name)
+		if err == errAlreadyDying {
+			relOps = []txn.Op{{
+				C:      relationsC,
+				Id:     rel.doc.DocID,
+				Assert: bson.D{{"life", Dying}},
+			}}
+		} else if err != nil {
+			return nil, err
+		}
+		if isRemove {
+			removeCount++
+		}
+		ops = append(ops, relOps...)
+	}
+	// If all the relations of the remote service will be removed, so
+	// can the remote service; otherwise it is removed along with the
+	// last of its relations.
+	if s.doc.RelationCount == removeCount {
+		hasLastRefs := bson.D{{"life", Alive}, {"relationcount", removeCount}}
+		return append(ops, s.removeOps(hasLastRefs)), nil
+	}
+	update := bson.D{{"$set", bson.D{{"life", Dying}}}}
+	if removeCount != 0 {
+		decref := bson.D{{"$inc", bson.D{{"relationcount", -removeCount}}}}
+		update = append(update, decref...)
+	}
+	return append(ops, txn.Op{
+		C:      remoteServicesC,
+		Id:     s.doc.DocID,
+		Assert: bson.D{{"life", Alive}, {"relationcount", s.doc.RelationCount}},
+		Update: update,
+	}), nil
+}
+
+// removeOps returns the operation required to remove the remote
+// service. Supplied asserts will be included in the operation.
+func (s *RemoteService) removeOps(asserts bson.D) txn.Op {
+	return txn.Op{
+		C:      remoteServicesC,
+		Id:     s.doc.DocID,
+		Assert: asserts,
+		Remove: true,
+	}
+}
+
+// AddRemoteService records a service of another environment, with the
+// given endpoints, so that services of this environment can relate to
+// it. The name must not be used by any other service of the environment.
+func (st *State) AddRemoteService(name, sourceEnvUUID, sourceEnvName string, endpoints []charm.Relation) (_ *RemoteService, err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot add remote service %q", name)
+	if !names.IsValidService(name) {
+		return nil, errors.Errorf("invalid name")
+	}
+	if sourceEnvUUID == st.EnvironUUID() {
+		return nil, errors.Errorf("service is in this environment")
+	}
+	if exists, err := isNotDead(st, servicesC, name); err != nil {
+		return nil, errors.Trace(err)
+	} else if exists {
+		return nil, errors.Errorf("service already exists")
+	}
+	if exists, err := isNotDead(st, remoteServicesC, name); err != nil {
+		return nil, errors.Trace(err)
+	} else if exists {
+		return nil, errors.Errorf("remote service already exists")
+	}
+	env, err := st.Environment()
+	if err != nil {
+		return nil, errors.Trace(err)
+	} else if env.Life() != Alive {
+		return nil, errors.Errorf("environment is no longer alive")
+	}
+	docID := st.docID(name)
+	doc := &remoteServiceDoc{
+		DocID:         docID,
+		Name:          name,
+		EnvUUID:       st.EnvironUUID(),
+		SourceEnvUUID: sourceEnvUUID,
+		SourceEnvName: sourceEnvName,
+		Endpoints:     endpoints,
+		Life:          Alive,
+	}
+	ops := []txn.Op{
+		env.assertAliveOp(),
+		{
+			C:      servicesC,
+			Id:     docID,
+			Assert: txn.DocMissing,
+		}, {
+			C:      remoteServicesC,
+			Id:     docID,
+			Assert: txn.DocMissing,
+			Insert: doc,
+		},
+	}
+	if err := st.runTransaction(ops); err == txn.ErrAborted {
+		err := env.Refresh()
+		if (err == nil && env.Life() != Alive) || errors.IsNotFound(err) {
+			return nil, errors.Errorf("environment is no longer alive")
+		} else if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return nil, errors.Errorf("service already exists")
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return newRemoteService(st, doc), nil
+}
+
+// RemoteService returns a remote service by name.
+func (st *State) RemoteService(name string) (*RemoteService, error) {
+	remoteServices, closer := st.getCollection(remoteServicesC)
+	defer closer()
+
+	if !names.IsValidService(name) {
+		return nil, errors.Errorf("%q is not a valid service name", name)
+	}
+	doc := &remoteServiceDoc{}
+	err := remoteServices.FindId(name).One(doc)
+	if err == mgo.ErrNotFound {
+		return nil, errors.NotFoundf("remote service %q", name)
+	}
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot get remote service %q", name)
+	}
+	return newRemoteService(st, doc), nil
+}
+
+// AllRemoteServices returns all the remote services related to by
+// services of the environment.
+func (st *State) AllRemoteServices() ([]*RemoteService, error) {
+	remoteServices, closer := st.getCollection(remoteServicesC)
+	defer closer()
+
+	docs := []remoteServiceDoc{}
+	if err := remoteServices.Find(nil).All(&docs); err != nil {
+		return nil, errors.Annotate(err, "cannot get all remote services")
+	}
+	services := make([]*RemoteService, len(docs))
+	for i := range docs {
+		services[i] = newRemoteService(st, &docs[i])
+	}
+	return services, nil
+}
+
+// ensureRemoteService returns the remote service with the given name
+// from the given source environment, adding it or the given endpoint
+// to it as needed.
+func (st *State) ensureRemoteService(name, sourceEnvUUID, sourceEnvName string, ep charm.Relation) (*RemoteService, error) {
+	svc, err := st.RemoteService(name)
+	if errors.IsNotFound(err) {
+		return st.AddRemoteService(name, sourceEnvUUID, sourceEnvName, []charm.Relation{ep})
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if svc.doc.SourceEnvUUID != sourceEnvUUID {
+		return nil, errors.Errorf("remote service %q is from environment %q", name, svc.doc.SourceEnvName)
+	}
+	if _, err := svc.Endpoint(ep.Name); err == nil {
+		return svc, nil
+	}
+	ops := []txn.Op{{
+		C:      remoteServicesC,
+		Id:     svc.doc.DocID,
+		Assert: isAliveDoc,
+		Update: bson.D{{"$addToSet", bson.D{{"endpoints", ep}}}},
+	}}
+	if err := st.runTransaction(ops); err == txn.ErrAborted {
+		return nil, errors.Errorf("remote service %q is not alive", name)
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return svc, svc.Refresh()
+}
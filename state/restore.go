@@ -46,9 +46,14 @@ func (info *RestoreInfo) Status() RestoreStatus {
 
 // SetStatus sets the status of the current restore. Checks are made
 // to ensure that status changes are performed in the correct order.
+// Setting RestorePending locks the environment for a restore, so it
+// fails if a restore is already pending or in progress.
 func (info *RestoreInfo) SetStatus(status RestoreStatus) error {
 	var assertSane bson.D
 
+	if status == RestorePending {
+		assertSane = bson.D{{"status", bson.D{{"$nin", []RestoreStatus{RestorePending, RestoreInProgress}}}}}
+	}
 	if status == RestoreInProgress {
 		assertSane = bson.D{{"status", RestorePending}}
 	}
@@ -63,11 +68,19 @@ func (info *RestoreInfo) SetStatus(status RestoreStatus) error {
 		Update: bson.D{{"$set", bson.D{{"status", status}}}},
 	}}
 	err := info.st.runTransaction(ops)
+	if err == txn.ErrAborted && status == RestorePending {
+		return errors.Errorf("cannot set restore status to %q: "+
+			"restore already pending or in progress", status)
+	}
 	if err == txn.ErrAborted {
 		return errors.Errorf("cannot set restore status to %q: Another "+
 			"status change occurred concurrently", status)
 	}
-	return errors.Annotatef(err, "cannot set restore status to %q", status)
+	if err != nil {
+		return errors.Annotatef(err, "cannot set restore status to %q", status)
+	}
+	info.doc.Status = status
+	return nil
 }
 
 // EnsureRestoreInfo returns the current info doc, if it does not exists
@@ -0,0 +1,47 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+)
+
+type RestoreInfoSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&RestoreInfoSuite{})
+
+func (s *RestoreInfoSuite) TestEnsureRestoreInfo(c *gc.C) {
+	info, err := s.State.EnsureRestoreInfo()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.Status(), gc.Equals, state.UnknownRestoreStatus)
+}
+
+func (s *RestoreInfoSuite) TestSetStatusPendingLocks(c *gc.C) {
+	info, err := s.State.EnsureRestoreInfo()
+	c.Assert(err, jc.ErrorIsNil)
+	err = info.SetStatus(state.RestorePending)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.Status(), gc.Equals, state.RestorePending)
+
+	other, err := s.State.EnsureRestoreInfo()
+	c.Assert(err, jc.ErrorIsNil)
+	err = other.SetStatus(state.RestorePending)
+	c.Assert(err, gc.ErrorMatches, `cannot set restore status to "PENDING": restore already pending or in progress`)
+
+	err = info.SetStatus(state.RestoreInProgress)
+	c.Assert(err, jc.ErrorIsNil)
+	err = other.SetStatus(state.RestorePending)
+	c.Assert(err, gc.ErrorMatches, `cannot set restore status to "PENDING": restore already pending or in progress`)
+
+	// Once the restore is over, another one can start.
+	err = info.SetStatus(state.RestoreFinished)
+	c.Assert(err, jc.ErrorIsNil)
+	err = other.SetStatus(state.RestorePending)
+	c.Assert(err, jc.ErrorIsNil)
+}
@@ -47,6 +47,18 @@ type serviceDoc struct {
 	OwnerTag          string     `bson:"ownertag"`
 	TxnRevno          int64      `bson:"txn-revno"`
 	MetricCredentials []byte     `bson:"metric-credentials"`
+
+	PlacementPolicies []placementPolicyDoc `bson:"placementpolicies,omitempty"`
+
+	// PlacementRevno is incremented whenever a unit of the service is
+	// assigned to a machine, so that assignments checked against
+	// placement policies can assert the placement they depend on.
+	PlacementRevno int64 `bson:"placementrevno"`
+
+	// EndpointBindings maps endpoint names to the spaces they are
+	// bound to; DefaultSpace is the space of the other endpoints.
+	EndpointBindings map[string]string `bson:"endpointbindings,omitempty"`
+	DefaultSpace     string            `bson:"defaultspace,omitempty"`
 }
 
 func newService(st *State, doc *serviceDoc) *Service {
@@ -154,6 +166,13 @@ func (s *Service) destroyOps() ([]txn.Op, error) {
 		return nil, errRefresh
 	}
 	ops := []txn.Op{minUnitsRemoveOp(s.st, s.doc.Name)}
+	// A service that is going away can no longer be related to
+	// from other environments.
+	offerOps, err := removeOffersOps(s.st, s.doc.Name)
+	if err != nil {
+		return nil, err
+	}
+	ops = append(ops, offerOps...)
 	removeCount := 0
 	for _, rel := range rels {
 		relOps, isRemove, err := rel.destroyOps(s.doc.Name)
@@ -175,7 +194,11 @@ func (s *Service) destroyOps() ([]txn.Op, error) {
 	// removed, the service can also be removed.
 	if s.doc.UnitCount == 0 && s.doc.RelationCount == removeCount {
 		hasLastRefs := bson.D{{"life", Alive}, {"unitcount", 0}, {"relationcount", removeCount}}
-		return append(ops, s.removeOps(hasLastRefs)...), nil
+		removeOps, err := s.removeOps(hasLastRefs)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return append(ops, removeOps...), nil
 	}
 	// In all other cases, service removal will be handled as a consequence
 	// of the removal of the last unit or relation referencing it. If any
@@ -213,7 +236,7 @@ func (s *Service) destroyOps() ([]txn.Op, error) {
 
 // removeOps returns the operations required to remove the service. Supplied
 // asserts will be included in the operation on the service document.
-func (s *Service) removeOps(asserts bson.D) []txn.Op {
+func (s *Service) removeOps(asserts bson.D) ([]txn.Op, error) {
 	settingsDocID := s.st.docID(s.settingsKey())
 	ops := []txn.Op{{
 		C:      servicesC,
@@ -231,7 +254,12 @@ func (s *Service) removeOps(asserts bson.D) []txn.Op {
 	}}
 	ops = append(ops, removeRequestedNetworksOp(s.st, s.globalKey()))
 	ops = append(ops, removeConstraintsOp(s.st, s.globalKey()))
-	return append(ops, annotationRemoveOp(s.st, s.globalKey()))
+	historyOps, err := s.removeConfigHistoryOps()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	ops = append(ops, historyOps...)
+	return append(ops, annotationRemoveOp(s.st, s.globalKey())), nil
 }
 
 // IsExposed returns whether this service is exposed. The explicitly open
@@ -709,7 +737,11 @@ func (s *Service) removeUnitOps(u *Unit, asserts bson.D) ([]txn.Op, error) {
 	}
 	if s.doc.Life == Dying && s.doc.RelationCount == 0 && s.doc.UnitCount == 1 {
 		hasLastRef := bson.D{{"life", Dying}, {"relationcount", 0}, {"unitcount", 1}}
-		return append(ops, s.removeOps(hasLastRef)...), nil
+		removeOps, err := s.removeOps(hasLastRef)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return append(ops, removeOps...), nil
 	}
 	svcOp := txn.Op{
 		C:      servicesC,
@@ -785,32 +817,10 @@ func (s *Service) ConfigSettings() (charm.Settings, error) {
 
 // UpdateConfigSettings changes a service's charm config settings. Values set
 // to nil will be deleted; unknown and invalid values will return an error.
+// The change is recorded in the service's config history without a user;
+// see UpdateConfigSettingsBy.
 func (s *Service) UpdateConfigSettings(changes charm.Settings) error {
-	charm, _, err := s.Charm()
-	if err != nil {
-		return err
-	}
-	changes, err = charm.Config().ValidateSettings(changes)
-	if err != nil {
-		return err
-	}
-	// TODO(fwereade) state.Settings is itself really problematic in just
-	// about every use case. This needs to be resolved some time; but at
-	// least the settings docs are keyed by charm url as well as service
-	// name, so the actual impact of a race is non-threatening.
-	node, err := readSettings(s.st, s.settingsKey())
-	if err != nil {
-		return err
-	}
-	for name, value := range changes {
-		if value == nil {
-			node.Delete(name)
-		} else {
-			node.Set(name, value)
-		}
-	}
-	_, err = node.Write()
-	return err
+	return s.UpdateConfigSettingsBy("", changes)
 }
 
 var ErrSubordinateConstraints = stderrors.New("constraints do not apply to subordinate services")
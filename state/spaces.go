@@ -0,0 +1,229 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"net"
+	"sort"
+
+	"github.com/juju/errors"
+	jujutxn "github.com/juju/txn"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/network"
+)
+
+// Space is a named group of subnets. The endpoints of a service can
+// be bound to spaces, so that its units use addresses in the bound
+// space when talking over them.
+type Space struct {
+	st  *State
+	doc spaceDoc
+}
+
+type spaceDoc struct {
+	DocID   string `bson:"_id"`
+	EnvUUID string `bson:"env-uuid"`
+	Name    string `bson:"name"`
+}
+
+// Name returns the name of the space.
+func (s *Space) Name() string {
+	return s.doc.Name
+}
+
+// String returns the name of the space.
+func (s *Space) String() string {
+	return s.doc.Name
+}
+
+// Subnets returns the subnets in the space, ordered by CIDR.
+func (s *Space) Subnets() ([]*Subnet, error) {
+	return s.st.subnetsInSpace(s.doc.Name)
+}
+
+// AddSpace creates a new space with the given name, holding the subnets
+// with the given CIDRs. The subnets must already exist, and must not be
+// in another space. If a space with the same name already exists, an
+// error satisfying errors.IsAlreadyExists is returned.
+func (st *State) AddSpace(name string, subnets []string) (space *Space, err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot add space %q", name)
+	if !network.IsValidSpace(name) {
+		return nil, errors.Errorf("invalid name")
+	}
+	doc := spaceDoc{
+		DocID:   st.docID(name),
+		EnvUUID: st.EnvironUUID(),
+		Name:    name,
+	}
+	ops := []txn.Op{{
+		C:      spacesC,
+		Id:     doc.DocID,
+		Assert: txn.DocMissing,
+		Insert: doc,
+	}}
+	for _, cidr := range subnets {
+		subnet, err := st.Subnet(cidr)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if subnet.SpaceName() != "" {
+			return nil, errors.Errorf("subnet %q is already in space %q", cidr, subnet.SpaceName())
+		}
+		ops = append(ops, subnet.setSpaceOp(name))
+	}
+	if err := st.runTransaction(ops); err == txn.ErrAborted {
+		if _, err := st.Space(name); err == nil {
+			return nil, errors.AlreadyExistsf("space %q", name)
+		}
+		return nil, errors.Errorf("subnets changed while adding space")
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &Space{st: st, doc: doc}, nil
+}
+
+// Space returns the space with the given name.
+func (st *State) Space(name string) (*Space, error) {
+	spaces, closer := st.getCollection(spacesC)
+	defer closer()
+
+	var doc spaceDoc
+	err := spaces.FindId(name).One(&doc)
+	if err == mgo.ErrNotFound {
+		return nil, errors.NotFoundf("space %q", name)
+	}
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot get space %q", name)
+	}
+	return &Space{st: st, doc: doc}, nil
+}
+
+// AllSpaces returns all the spaces in the environment, ordered by name.
+func (st *State) AllSpaces() ([]*Space, error) {
+	spaces, closer := st.getCollection(spacesC)
+	defer closer()
+
+	var docs []spaceDoc
+	if err := spaces.Find(nil).Sort("name").All(&docs); err != nil {
+		return nil, errors.Annotate(err, "cannot get all spaces")
+	}
+	result := make([]*Space, len(docs))
+	for i, doc := range docs {
+		result[i] = &Space{st: st, doc: doc}
+	}
+	return result, nil
+}
+
+func (st *State) subnetsInSpace(name string) ([]*Subnet, error) {
+	subnets, closer := st.getCollection(subnetsC)
+	defer closer()
+
+	var docs []subnetDoc
+	if err := subnets.Find(bson.D{{"spacename", name}}).All(&docs); err != nil {
+		return nil, errors.Annotatef(err, "cannot get subnets of space %q", name)
+	}
+	result := make([]*Subnet, len(docs))
+	for i, doc := range docs {
+		result[i] = &Subnet{st, doc}
+	}
+	sort.Sort(subnetsByCIDR(result))
+	return result, nil
+}
+
+type subnetsByCIDR []*Subnet
+
+func (s subnetsByCIDR) Len() int           { return len(s) }
+func (s subnetsByCIDR) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s subnetsByCIDR) Less(i, j int) bool { return s[i].CIDR() < s[j].CIDR() }
+
+// SpaceName returns the name of the space the subnet is in, or the
+// empty string if it is not in one.
+func (s *Subnet) SpaceName() string {
+	return s.doc.SpaceName
+}
+
+// SetSpace puts the subnet in the named space. A subnet can only be in
+// one space, and cannot be moved once it is in one.
+func (s *Subnet) SetSpace(name string) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot set space of subnet %q", s.doc.CIDR)
+	if s.doc.SpaceName == name {
+		return nil
+	}
+	if s.doc.SpaceName != "" {
+		return errors.Errorf("subnet is already in space %q", s.doc.SpaceName)
+	}
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := s.Refresh(); err != nil {
+				return nil, errors.Trace(err)
+			}
+			if s.doc.SpaceName == name {
+				return nil, jujutxn.ErrNoOperations
+			}
+			if s.doc.SpaceName != "" {
+				return nil, errors.Errorf("subnet is already in space %q", s.doc.SpaceName)
+			}
+		}
+		if _, err := s.st.Space(name); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return []txn.Op{
+			assertSpaceExistsOp(s.st, name),
+			s.setSpaceOp(name),
+		}, nil
+	}
+	if err := s.st.run(buildTxn); err != nil {
+		return err
+	}
+	s.doc.SpaceName = name
+	return nil
+}
+
+// setSpaceOp returns the operation putting the subnet, which must be
+// alive and not yet in a space, in the named space.
+func (s *Subnet) setSpaceOp(name string) txn.Op {
+	return txn.Op{
+		C:  subnetsC,
+		Id: s.doc.DocID,
+		Assert: bson.D{
+			{"life", Alive},
+			{"spacename", bson.D{{"$exists", false}}},
+		},
+		Update: bson.D{{"$set", bson.D{{"spacename", name}}}},
+	}
+}
+
+func assertSpaceExistsOp(st *State, name string) txn.Op {
+	return txn.Op{
+		C:      spacesC,
+		Id:     st.docID(name),
+		Assert: txn.DocExists,
+	}
+}
+
+// addressInSpace returns the first of the addresses that is in one of
+// the given subnets.
+func addressInSpace(addresses []network.Address, subnets []*Subnet) (network.Address, bool) {
+	var nets []*net.IPNet
+	for _, subnet := range subnets {
+		if _, ipNet, err := net.ParseCIDR(subnet.CIDR()); err == nil {
+			nets = append(nets, ipNet)
+		}
+	}
+	for _, addr := range addresses {
+		ip := net.ParseIP(addr.Value)
+		if ip == nil {
+			continue
+		}
+		for _, ipNet := range nets {
+			if ipNet.Contains(ip) {
+				return addr, true
+			}
+		}
+	}
+	return network.Address{}, false
+}
@@ -0,0 +1,147 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+)
+
+type SpaceSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&SpaceSuite{})
+
+func (s *SpaceSuite) addSubnet(c *gc.C, cidr, space string) *state.Subnet {
+	subnet, err := s.State.AddSubnet(state.SubnetInfo{CIDR: cidr, SpaceName: space})
+	c.Assert(err, jc.ErrorIsNil)
+	return subnet
+}
+
+func (s *SpaceSuite) assertSubnets(c *gc.C, space *state.Space, cidrs ...string) {
+	subnets, err := space.Subnets()
+	c.Assert(err, jc.ErrorIsNil)
+	var found []string
+	for _, subnet := range subnets {
+		c.Check(subnet.SpaceName(), gc.Equals, space.Name())
+		found = append(found, subnet.CIDR())
+	}
+	c.Check(found, jc.DeepEquals, cidrs)
+}
+
+func (s *SpaceSuite) TestAddSpace(c *gc.C) {
+	s.addSubnet(c, "10.0.1.0/24", "")
+	s.addSubnet(c, "10.0.0.0/24", "")
+	s.addSubnet(c, "192.168.0.0/24", "")
+
+	space, err := s.State.AddSpace("internal", []string{"10.0.1.0/24", "10.0.0.0/24"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(space.Name(), gc.Equals, "internal")
+	s.assertSubnets(c, space, "10.0.0.0/24", "10.0.1.0/24")
+
+	space, err = s.State.Space("internal")
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertSubnets(c, space, "10.0.0.0/24", "10.0.1.0/24")
+
+	subnet, err := s.State.Subnet("192.168.0.0/24")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(subnet.SpaceName(), gc.Equals, "")
+}
+
+func (s *SpaceSuite) TestAddSpaceWithoutSubnets(c *gc.C) {
+	space, err := s.State.AddSpace("public", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertSubnets(c, space)
+}
+
+func (s *SpaceSuite) TestAddSpaceErrors(c *gc.C) {
+	_, err := s.State.AddSpace("Not_Valid", nil)
+	c.Assert(err, gc.ErrorMatches, `cannot add space "Not_Valid": invalid name`)
+
+	_, err = s.State.AddSpace("internal", []string{"10.0.0.0/24"})
+	c.Assert(err, gc.ErrorMatches, `cannot add space "internal": subnet "10.0.0.0/24" not found`)
+
+	s.addSubnet(c, "10.0.0.0/24", "")
+	_, err = s.State.AddSpace("internal", []string{"10.0.0.0/24"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = s.State.AddSpace("internal", nil)
+	c.Assert(err, jc.Satisfies, errors.IsAlreadyExists)
+	c.Assert(err, gc.ErrorMatches, `cannot add space "internal": space "internal" already exists`)
+
+	_, err = s.State.AddSpace("storage", []string{"10.0.0.0/24"})
+	c.Assert(err, gc.ErrorMatches, `cannot add space "storage": subnet "10.0.0.0/24" is already in space "internal"`)
+}
+
+func (s *SpaceSuite) TestSpaceNotFound(c *gc.C) {
+	_, err := s.State.Space("internal")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	c.Assert(err, gc.ErrorMatches, `space "internal" not found`)
+}
+
+func (s *SpaceSuite) TestAllSpaces(c *gc.C) {
+	spaces, err := s.State.AllSpaces()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(spaces, gc.HasLen, 0)
+
+	for _, name := range []string{"storage", "internal", "public"} {
+		_, err := s.State.AddSpace(name, nil)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	spaces, err = s.State.AllSpaces()
+	c.Assert(err, jc.ErrorIsNil)
+	var names []string
+	for _, space := range spaces {
+		names = append(names, space.Name())
+	}
+	c.Check(names, jc.DeepEquals, []string{"internal", "public", "storage"})
+}
+
+func (s *SpaceSuite) TestAddSubnetInSpace(c *gc.C) {
+	space, err := s.State.AddSpace("internal", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	subnet := s.addSubnet(c, "10.0.0.0/24", "internal")
+	c.Check(subnet.SpaceName(), gc.Equals, "internal")
+	s.assertSubnets(c, space, "10.0.0.0/24")
+}
+
+func (s *SpaceSuite) TestAddSubnetInMissingSpace(c *gc.C) {
+	_, err := s.State.AddSubnet(state.SubnetInfo{CIDR: "10.0.0.0/24", SpaceName: "internal"})
+	c.Assert(err, gc.ErrorMatches, `cannot add subnet 10.0.0.0/24: space "internal" not found`)
+	_, err = s.State.Subnet("10.0.0.0/24")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *SpaceSuite) TestSubnetSetSpace(c *gc.C) {
+	space, err := s.State.AddSpace("internal", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	subnet := s.addSubnet(c, "10.0.0.0/24", "")
+
+	err = subnet.SetSpace("internal")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(subnet.SpaceName(), gc.Equals, "internal")
+	s.assertSubnets(c, space, "10.0.0.0/24")
+
+	// Setting the same space again is a no-op.
+	err = subnet.SetSpace("internal")
+	c.Assert(err, jc.ErrorIsNil)
+
+	_, err = s.State.AddSpace("storage", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	err = subnet.SetSpace("storage")
+	c.Assert(err, gc.ErrorMatches, `cannot set space of subnet "10.0.0.0/24": subnet is already in space "internal"`)
+}
+
+func (s *SpaceSuite) TestSubnetSetMissingSpace(c *gc.C) {
+	subnet := s.addSubnet(c, "10.0.0.0/24", "")
+	err := subnet.SetSpace("internal")
+	c.Assert(err, gc.ErrorMatches, `cannot set space of subnet "10.0.0.0/24": space "internal" not found`)
+	err = subnet.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Check(subnet.SpaceName(), gc.Equals, "")
+}
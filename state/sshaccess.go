@@ -0,0 +1,91 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+// sshAccessDoc records that a user was given the address of a machine
+// or unit to connect to with ssh.
+type sshAccessDoc struct {
+	DocID   string    `bson:"_id"`
+	EnvUUID string    `bson:"env-uuid"`
+	User    string    `bson:"user"`
+	Target  string    `bson:"target"`
+	Address string    `bson:"address"`
+	Time    time.Time `bson:"time"`
+}
+
+// SSHAccess records that a user was given the address of a machine or
+// unit to connect to with ssh.
+type SSHAccess struct {
+	doc sshAccessDoc
+}
+
+// UserTag returns the tag of the user given the address.
+func (a *SSHAccess) UserTag() names.UserTag {
+	return names.NewUserTag(a.doc.User)
+}
+
+// Target returns the name of the machine or unit.
+func (a *SSHAccess) Target() string {
+	return a.doc.Target
+}
+
+// Address returns the address the user was given.
+func (a *SSHAccess) Address() string {
+	return a.doc.Address
+}
+
+// Time returns when the user was given the address.
+func (a *SSHAccess) Time() time.Time {
+	return a.doc.Time
+}
+
+// AddSSHAccess records that the given user was given the address of
+// the named machine or unit to connect to with ssh. The address must
+// not be handed out if the access cannot be recorded.
+func (st *State) AddSSHAccess(user names.UserTag, target, address string) error {
+	doc := sshAccessDoc{
+		DocID:   bson.NewObjectId().Hex(),
+		EnvUUID: st.EnvironUUID(),
+		User:    user.Username(),
+		Target:  target,
+		Address: address,
+		Time:    nowToTheSecond(),
+	}
+	ops := []txn.Op{{
+		C:      sshAccessC,
+		Id:     doc.DocID,
+		Assert: txn.DocMissing,
+		Insert: &doc,
+	}}
+	if err := st.runTransaction(ops); err != nil {
+		return errors.Annotatef(err, "cannot record ssh access of %q to %q", user.Username(), target)
+	}
+	return nil
+}
+
+// SSHAccesses returns the recorded ssh accesses to the environment's
+// machines and units, oldest first.
+func (st *State) SSHAccesses() ([]*SSHAccess, error) {
+	accesses, closer := st.getCollection(sshAccessC)
+	defer closer()
+
+	var docs []sshAccessDoc
+	if err := accesses.Find(nil).Sort("time", "_id").All(&docs); err != nil {
+		return nil, errors.Annotate(err, "cannot get ssh accesses")
+	}
+	result := make([]*SSHAccess, len(docs))
+	for i, doc := range docs {
+		result[i] = &SSHAccess{doc: doc}
+	}
+	return result, nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"github.com/juju/names"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+type SSHAccessSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&SSHAccessSuite{})
+
+func (s *SSHAccessSuite) TestAddSSHAccess(c *gc.C) {
+	bob := names.NewLocalUserTag("bob")
+	err := s.State.AddSSHAccess(bob, "0", "10.0.0.1")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.State.AddSSHAccess(bob, "wordpress/0", "10.0.0.2")
+	c.Assert(err, jc.ErrorIsNil)
+
+	accesses, err := s.State.SSHAccesses()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(accesses, gc.HasLen, 2)
+	c.Assert(accesses[0].UserTag(), gc.Equals, bob)
+	c.Assert(accesses[0].Target(), gc.Equals, "0")
+	c.Assert(accesses[0].Address(), gc.Equals, "10.0.0.1")
+	c.Assert(accesses[0].Time().IsZero(), jc.IsFalse)
+	c.Assert(accesses[1].Target(), gc.Equals, "wordpress/0")
+}
+
+func (s *SSHAccessSuite) TestSSHAccessesPerEnvironment(c *gc.C) {
+	err := s.State.AddSSHAccess(names.NewLocalUserTag("bob"), "0", "10.0.0.1")
+	c.Assert(err, jc.ErrorIsNil)
+
+	st := s.factory.MakeEnvironment(c, nil)
+	defer st.Close()
+	accesses, err := st.SSHAccesses()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(accesses, gc.HasLen, 0)
+}
@@ -0,0 +1,487 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juju/errors"
+	jujutxn "github.com/juju/txn"
+	"github.com/juju/utils/set"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/version"
+)
+
+// StagedUpgradeStatus describes the states a staged upgrade may be in.
+type StagedUpgradeStatus string
+
+const (
+	// StagedUpgradeRunning indicates that agents are being released
+	// to the target version in batches.
+	StagedUpgradeRunning StagedUpgradeStatus = "running"
+
+	// StagedUpgradeHalted indicates that released agents did not come
+	// back healthy in time, so no more agents are released.
+	StagedUpgradeHalted StagedUpgradeStatus = "halted"
+
+	// StagedUpgradeComplete indicates that all agents were released.
+	StagedUpgradeComplete StagedUpgradeStatus = "complete"
+
+	// StagedUpgradeCancelled indicates that the agent version was set
+	// directly, releasing all the agents at once.
+	StagedUpgradeCancelled StagedUpgradeStatus = "cancelled"
+
+	// currentStagedUpgradeId is the id of the staged upgrade document
+	// of an environment.
+	currentStagedUpgradeId = "current"
+)
+
+// StagedUpgradeArgs holds the parameters of a staged upgrade.
+type StagedUpgradeArgs struct {
+	// CanaryPercent is the percentage of the machines, besides the
+	// state servers, released first.
+	CanaryPercent int
+
+	// BatchSize is the number of machines released at a time after
+	// the canaries. Zero releases all the remaining machines at once.
+	BatchSize int
+
+	// HealthTimeout is how long released agents have to come back
+	// healthy on the target version before the upgrade is halted.
+	HealthTimeout time.Duration
+}
+
+// Validate returns an error if the arguments are not valid.
+func (args StagedUpgradeArgs) Validate() error {
+	if args.CanaryPercent < 0 || args.CanaryPercent > 100 {
+		return errors.NotValidf("canary percentage %d", args.CanaryPercent)
+	}
+	if args.BatchSize < 0 {
+		return errors.NotValidf("batch size %d", args.BatchSize)
+	}
+	if args.HealthTimeout <= 0 {
+		return errors.NotValidf("health timeout %v", args.HealthTimeout)
+	}
+	return nil
+}
+
+type stagedUpgradeDoc struct {
+	DocID           string              `bson:"_id"`
+	EnvUUID         string              `bson:"env-uuid"`
+	PreviousVersion version.Number      `bson:"previousversion"`
+	TargetVersion   version.Number      `bson:"targetversion"`
+	CanaryPercent   int                 `bson:"canarypercent"`
+	BatchSize       int                 `bson:"batchsize"`
+	HealthTimeout   time.Duration       `bson:"healthtimeout"`
+	Status          StagedUpgradeStatus `bson:"status"`
+	HaltReason      string              `bson:"haltreason,omitempty"`
+	Started         time.Time           `bson:"started"`
+	StageStarted    time.Time           `bson:"stagestarted"`
+	Released        []string            `bson:"released"`
+}
+
+// StagedUpgrade describes an upgrade of the agents of an environment
+// that releases the state servers first, then a percentage of the
+// other machines as canaries, then the rest in batches, each once the
+// agents released before are healthy on the target version.
+type StagedUpgrade struct {
+	st  *State
+	doc stagedUpgradeDoc
+}
+
+// PreviousVersion returns the version being upgraded from.
+func (su *StagedUpgrade) PreviousVersion() version.Number {
+	return su.doc.PreviousVersion
+}
+
+// TargetVersion returns the version being upgraded to.
+func (su *StagedUpgrade) TargetVersion() version.Number {
+	return su.doc.TargetVersion
+}
+
+// Args returns the parameters the upgrade was started with.
+func (su *StagedUpgrade) Args() StagedUpgradeArgs {
+	return StagedUpgradeArgs{
+		CanaryPercent: su.doc.CanaryPercent,
+		BatchSize:     su.doc.BatchSize,
+		HealthTimeout: su.doc.HealthTimeout,
+	}
+}
+
+// Status returns the status of the upgrade.
+func (su *StagedUpgrade) Status() StagedUpgradeStatus {
+	return su.doc.Status
+}
+
+// HaltReason returns why the upgrade was halted, if it was.
+func (su *StagedUpgrade) HaltReason() string {
+	return su.doc.HaltReason
+}
+
+// Started returns when the upgrade was started.
+func (su *StagedUpgrade) Started() time.Time {
+	return su.doc.Started
+}
+
+// StageStarted returns when the agents being waited for were released,
+// or when the upgrade was started or resumed if that was later.
+func (su *StagedUpgrade) StageStarted() time.Time {
+	return su.doc.StageStarted
+}
+
+// Released returns the ids of the machines, besides the state servers,
+// whose agents were released to the target version, in the order they
+// were released.
+func (su *StagedUpgrade) Released() []string {
+	result := make([]string, len(su.doc.Released))
+	copy(result, su.doc.Released)
+	return result
+}
+
+// InProgress returns whether the upgrade still holds back agents: it is
+// either running or halted.
+func (su *StagedUpgrade) InProgress() bool {
+	return su.doc.Status == StagedUpgradeRunning || su.doc.Status == StagedUpgradeHalted
+}
+
+// HoldsMachine returns whether the agent of the given machine, which
+// must not be a state server, is held back from the target version.
+func (su *StagedUpgrade) HoldsMachine(machineId string) bool {
+	if !su.InProgress() {
+		return false
+	}
+	return !set.NewStrings(su.doc.Released...).Contains(machineId)
+}
+
+// Refresh updates the contents of the StagedUpgrade from underlying
+// state.
+func (su *StagedUpgrade) Refresh() error {
+	doc, err := su.st.stagedUpgradeDoc()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	su.doc = *doc
+	return nil
+}
+
+// ReleaseMachines releases the agents of the given machines to the
+// target version, and starts waiting for them to be healthy. It fails
+// if the upgrade is not running.
+func (su *StagedUpgrade) ReleaseMachines(machineIds []string) error {
+	now := nowToTheSecond()
+	ops := []txn.Op{{
+		C:      stagedUpgradesC,
+		Id:     su.doc.DocID,
+		Assert: su.assertRunning(),
+		Update: bson.D{
+			{"$addToSet", bson.D{{"released", bson.D{{"$each", machineIds}}}}},
+			{"$set", bson.D{{"stagestarted", now}}},
+		},
+	}}
+	if err := su.st.runTransaction(ops); err == txn.ErrAborted {
+		return errors.New("cannot release machines: staged upgrade is not running")
+	} else if err != nil {
+		return errors.Annotate(err, "cannot release machines")
+	}
+	released := set.NewStrings(su.doc.Released...)
+	for _, id := range machineIds {
+		if !released.Contains(id) {
+			su.doc.Released = append(su.doc.Released, id)
+			released.Add(id)
+		}
+	}
+	su.doc.StageStarted = now
+	return nil
+}
+
+// Halt stops releasing agents, recording why. It fails if the upgrade
+// is not running.
+func (su *StagedUpgrade) Halt(reason string) error {
+	return su.setStatus(StagedUpgradeHalted, reason)
+}
+
+// Complete marks the upgrade as complete, once all agents are released
+// and healthy. It fails if the upgrade is not running.
+func (su *StagedUpgrade) Complete() error {
+	return su.setStatus(StagedUpgradeComplete, "")
+}
+
+func (su *StagedUpgrade) setStatus(status StagedUpgradeStatus, reason string) error {
+	ops := []txn.Op{{
+		C:      stagedUpgradesC,
+		Id:     su.doc.DocID,
+		Assert: su.assertRunning(),
+		Update: bson.D{{"$set", bson.D{
+			{"status", status},
+			{"haltreason", reason},
+		}}},
+	}}
+	if err := su.st.runTransaction(ops); err == txn.ErrAborted {
+		return errors.Errorf("cannot set staged upgrade status to %q: upgrade is not running", status)
+	} else if err != nil {
+		return errors.Annotatef(err, "cannot set staged upgrade status to %q", status)
+	}
+	su.doc.Status = status
+	su.doc.HaltReason = reason
+	return nil
+}
+
+func (su *StagedUpgrade) assertRunning() bson.D {
+	return bson.D{
+		{"targetversion", su.doc.TargetVersion},
+		{"status", StagedUpgradeRunning},
+	}
+}
+
+// StagedUpgrade returns the last staged upgrade of the environment. If
+// there was none, an error satisfying errors.IsNotFound is returned.
+func (st *State) StagedUpgrade() (*StagedUpgrade, error) {
+	doc, err := st.stagedUpgradeDoc()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &StagedUpgrade{st: st, doc: *doc}, nil
+}
+
+func (st *State) stagedUpgradeDoc() (*stagedUpgradeDoc, error) {
+	coll, closer := st.getCollection(stagedUpgradesC)
+	defer closer()
+
+	var doc stagedUpgradeDoc
+	err := coll.FindId(st.docID(currentStagedUpgradeId)).One(&doc)
+	if err == mgo.ErrNotFound {
+		return nil, errors.NotFoundf("staged upgrade")
+	} else if err != nil {
+		return nil, errors.Annotate(err, "cannot read staged upgrade")
+	}
+	return &doc, nil
+}
+
+// StartStagedUpgrade sets the agent version of the environment to the
+// given version, holding back the agents of the machines other than
+// the state servers until they are released by the staged upgrade
+// worker. A halted staged upgrade is replaced, keeping the agents it
+// held back on their version.
+func (st *State) StartStagedUpgrade(newVersion version.Number, args StagedUpgradeArgs) (err error) {
+	defer errors.DeferredAnnotatef(&err, "cannot start staged upgrade to %s", newVersion)
+	if err := args.Validate(); err != nil {
+		return errors.Trace(err)
+	}
+	docID := st.docID(currentStagedUpgradeId)
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		settings, err := readSettings(st, environGlobalKey)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		agentVersion, ok := settings.Get("agent-version")
+		if !ok {
+			return nil, errors.Errorf("no agent version set in the environment")
+		}
+		currentVersion, ok := agentVersion.(string)
+		if !ok {
+			return nil, errors.Errorf("invalid agent version format: expected string, got %v", agentVersion)
+		}
+		if newVersion.String() == currentVersion {
+			return nil, errors.Errorf("agent version is already %s", currentVersion)
+		}
+		previousVersion, err := version.Parse(currentVersion)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+
+		existing, err := st.stagedUpgradeDoc()
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, errors.Trace(err)
+		}
+		var otherVersions []string
+		if existing != nil {
+			switch existing.Status {
+			case StagedUpgradeRunning:
+				return nil, errors.Errorf("a staged upgrade to %s is already running", existing.TargetVersion)
+			case StagedUpgradeHalted:
+				// The agents held back are still on the
+				// previous version.
+				previousVersion = existing.PreviousVersion
+				otherVersions = append(otherVersions, previousVersion.String())
+			}
+		}
+		if err := st.checkCanUpgrade(currentVersion, newVersion.String(), otherVersions...); err != nil {
+			return nil, errors.Trace(err)
+		}
+
+		now := nowToTheSecond()
+		doc := stagedUpgradeDoc{
+			DocID:           docID,
+			EnvUUID:         st.EnvironUUID(),
+			PreviousVersion: previousVersion,
+			TargetVersion:   newVersion,
+			CanaryPercent:   args.CanaryPercent,
+			BatchSize:       args.BatchSize,
+			HealthTimeout:   args.HealthTimeout,
+			Status:          StagedUpgradeRunning,
+			Started:         now,
+			StageStarted:    now,
+		}
+		ops := []txn.Op{{
+			C:      upgradeInfoC,
+			Id:     currentUpgradeId,
+			Assert: txn.DocMissing,
+		}, {
+			C:      settingsC,
+			Id:     st.docID(environGlobalKey),
+			Assert: bson.D{{"txn-revno", settings.txnRevno}},
+			Update: bson.D{{"$set", bson.D{{"agent-version", newVersion.String()}}}},
+		}}
+		if existing == nil {
+			return append(ops, txn.Op{
+				C:      stagedUpgradesC,
+				Id:     docID,
+				Assert: txn.DocMissing,
+				Insert: &doc,
+			}), nil
+		}
+		return append(ops, txn.Op{
+			C:      stagedUpgradesC,
+			Id:     docID,
+			Assert: bson.D{{"status", existing.Status}, {"targetversion", existing.TargetVersion}},
+			Update: bson.D{
+				{"$set", bson.D{
+					{"previousversion", doc.PreviousVersion},
+					{"targetversion", doc.TargetVersion},
+					{"canarypercent", doc.CanaryPercent},
+					{"batchsize", doc.BatchSize},
+					{"healthtimeout", doc.HealthTimeout},
+					{"status", doc.Status},
+					{"started", doc.Started},
+					{"stagestarted", doc.StageStarted},
+					{"released", []string{}},
+				}},
+				{"$unset", bson.D{{"haltreason", nil}}},
+			},
+		}), nil
+	}
+	return st.run(buildTxn)
+}
+
+// ResumeStagedUpgrade resumes a halted staged upgrade, waiting again
+// for the agents released last to be healthy before releasing more.
+func (st *State) ResumeStagedUpgrade() error {
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		doc, err := st.stagedUpgradeDoc()
+		if errors.IsNotFound(err) {
+			return nil, errors.New("no staged upgrade to resume")
+		} else if err != nil {
+			return nil, errors.Trace(err)
+		}
+		switch doc.Status {
+		case StagedUpgradeRunning:
+			return nil, jujutxn.ErrNoOperations
+		case StagedUpgradeHalted:
+		default:
+			return nil, errors.Errorf("staged upgrade to %s is %s", doc.TargetVersion, doc.Status)
+		}
+		return []txn.Op{{
+			C:      stagedUpgradesC,
+			Id:     doc.DocID,
+			Assert: bson.D{{"status", StagedUpgradeHalted}, {"targetversion", doc.TargetVersion}},
+			Update: bson.D{
+				{"$set", bson.D{
+					{"status", StagedUpgradeRunning},
+					{"stagestarted", nowToTheSecond()},
+				}},
+				{"$unset", bson.D{{"haltreason", nil}}},
+			},
+		}}, nil
+	}
+	err := st.run(buildTxn)
+	return errors.Annotate(err, "cannot resume staged upgrade")
+}
+
+// cancelStagedUpgradeOps returns the operations that cancel the staged
+// upgrade in progress, if any.
+func (st *State) cancelStagedUpgradeOps() ([]txn.Op, error) {
+	doc, err := st.stagedUpgradeDoc()
+	if errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	}
+	su := &StagedUpgrade{st: st, doc: *doc}
+	if !su.InProgress() {
+		return nil, nil
+	}
+	return []txn.Op{{
+		C:      stagedUpgradesC,
+		Id:     doc.DocID,
+		Assert: bson.D{{"status", doc.Status}, {"targetversion", doc.TargetVersion}},
+		Update: bson.D{{"$set", bson.D{{"status", StagedUpgradeCancelled}}}},
+	}}, nil
+}
+
+// WatchAgentVersion returns a watcher that notifies of changes to the
+// agent version of the environment, and to the agents released by a
+// staged upgrade.
+func (st *State) WatchAgentVersion() NotifyWatcher {
+	return newDocWatcher(st, []docKey{
+		{settingsC, st.docID(environGlobalKey)},
+		{stagedUpgradesC, st.docID(currentStagedUpgradeId)},
+	})
+}
+
+// WatchStagedUpgrade returns a watcher that notifies of changes to the
+// staged upgrade of the environment.
+func (st *State) WatchStagedUpgrade() NotifyWatcher {
+	return newEntityWatcher(st, stagedUpgradesC, st.docID(currentStagedUpgradeId))
+}
+
+// MachineProblems returns why the agent of the given machine, or the
+// agents of its units, are not healthy on the target version: not
+// running it, not alive, or reporting an error.
+func (su *StagedUpgrade) MachineProblems(m *Machine) ([]string, error) {
+	var problems []string
+	target := su.doc.TargetVersion
+	if tools, err := m.AgentTools(); errors.IsNotFound(err) {
+		problems = append(problems, "agent has not reported its version")
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	} else if tools.Version.Number != target {
+		problems = append(problems, fmt.Sprintf("agent is running %s", tools.Version.Number))
+	}
+	if alive, err := m.AgentPresence(); err != nil {
+		return nil, errors.Trace(err)
+	} else if !alive {
+		problems = append(problems, "agent is not alive")
+	}
+	if status, info, _, err := m.Status(); err != nil {
+		return nil, errors.Trace(err)
+	} else if status == StatusError {
+		problems = append(problems, fmt.Sprintf("agent status is error: %s", info))
+	}
+	units, err := m.Units()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, u := range units {
+		// Units not deployed yet have no agent to wait for.
+		tools, err := u.AgentTools()
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if tools.Version.Number != target {
+			problems = append(problems, fmt.Sprintf("agent of unit %s is running %s", u.Name(), tools.Version.Number))
+		}
+		if alive, err := u.AgentPresence(); err != nil {
+			return nil, errors.Trace(err)
+		} else if !alive {
+			problems = append(problems, fmt.Sprintf("agent of unit %s is not alive", u.Name()))
+		}
+	}
+	return problems, nil
+}
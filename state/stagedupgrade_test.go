@@ -0,0 +1,238 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+	statetesting "github.com/juju/juju/state/testing"
+	"github.com/juju/juju/version"
+)
+
+type StagedUpgradeSuite struct {
+	ConnSuite
+	machine        *state.Machine
+	currentVersion version.Number
+	args           state.StagedUpgradeArgs
+}
+
+var _ = gc.Suite(&StagedUpgradeSuite{})
+
+func (s *StagedUpgradeSuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	cfg, err := s.State.EnvironConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	var ok bool
+	s.currentVersion, ok = cfg.AgentVersion()
+	c.Assert(ok, jc.IsTrue)
+
+	s.machine, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	s.setAgentVersion(c, s.machine, s.currentVersion)
+	s.args = state.StagedUpgradeArgs{
+		CanaryPercent: 10,
+		BatchSize:     5,
+		HealthTimeout: 10 * time.Minute,
+	}
+}
+
+func (s *StagedUpgradeSuite) setAgentVersion(c *gc.C, m *state.Machine, vers version.Number) {
+	err := m.SetAgentVersion(version.Binary{Number: vers, Series: "quantal", Arch: "amd64"})
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *StagedUpgradeSuite) assertAgentVersion(c *gc.C, expected version.Number) {
+	cfg, err := s.State.EnvironConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	agentVersion, ok := cfg.AgentVersion()
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(agentVersion, gc.Equals, expected)
+}
+
+func (s *StagedUpgradeSuite) TestStagedUpgradeNotFound(c *gc.C) {
+	_, err := s.State.StagedUpgrade()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *StagedUpgradeSuite) TestStartStagedUpgrade(c *gc.C) {
+	err := s.State.StartStagedUpgrade(vers("4.5.6"), s.args)
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertAgentVersion(c, vers("4.5.6"))
+
+	staged, err := s.State.StagedUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(staged.PreviousVersion(), gc.Equals, s.currentVersion)
+	c.Assert(staged.TargetVersion(), gc.Equals, vers("4.5.6"))
+	c.Assert(staged.Args(), jc.DeepEquals, s.args)
+	c.Assert(staged.Status(), gc.Equals, state.StagedUpgradeRunning)
+	c.Assert(staged.HaltReason(), gc.Equals, "")
+	c.Assert(staged.Released(), gc.HasLen, 0)
+	c.Assert(staged.Started().IsZero(), jc.IsFalse)
+	c.Assert(staged.StageStarted().Equal(staged.Started()), jc.IsTrue)
+	c.Assert(staged.InProgress(), jc.IsTrue)
+	c.Assert(staged.HoldsMachine(s.machine.Id()), jc.IsTrue)
+}
+
+func (s *StagedUpgradeSuite) TestStartStagedUpgradeInvalidArgs(c *gc.C) {
+	for i, test := range []struct {
+		args state.StagedUpgradeArgs
+		err  string
+	}{{
+		args: state.StagedUpgradeArgs{CanaryPercent: 101, HealthTimeout: time.Minute},
+		err:  "canary percentage 101 not valid",
+	}, {
+		args: state.StagedUpgradeArgs{BatchSize: -1, HealthTimeout: time.Minute},
+		err:  "batch size -1 not valid",
+	}, {
+		args: state.StagedUpgradeArgs{},
+		err:  "health timeout 0 not valid",
+	}} {
+		c.Logf("test %d", i)
+		err := s.State.StartStagedUpgrade(vers("4.5.6"), test.args)
+		c.Check(err, gc.ErrorMatches, "cannot start staged upgrade to 4.5.6: "+test.err)
+	}
+	s.assertAgentVersion(c, s.currentVersion)
+}
+
+func (s *StagedUpgradeSuite) TestStartStagedUpgradeSameVersion(c *gc.C) {
+	err := s.State.StartStagedUpgrade(s.currentVersion, s.args)
+	c.Assert(err, gc.ErrorMatches, "cannot start staged upgrade to .*: agent version is already .*")
+}
+
+func (s *StagedUpgradeSuite) TestStartStagedUpgradeWhileRunning(c *gc.C) {
+	err := s.State.StartStagedUpgrade(vers("4.5.6"), s.args)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.State.StartStagedUpgrade(vers("4.5.7"), s.args)
+	c.Assert(err, gc.ErrorMatches, "cannot start staged upgrade to 4.5.7: a staged upgrade to 4.5.6 is already running")
+	s.assertAgentVersion(c, vers("4.5.6"))
+}
+
+func (s *StagedUpgradeSuite) TestStartStagedUpgradeReplacesHalted(c *gc.C) {
+	other, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	s.setAgentVersion(c, other, s.currentVersion)
+	err = s.State.StartStagedUpgrade(vers("4.5.6"), s.args)
+	c.Assert(err, jc.ErrorIsNil)
+	staged, err := s.State.StagedUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+	err = staged.ReleaseMachines([]string{other.Id()})
+	c.Assert(err, jc.ErrorIsNil)
+	s.setAgentVersion(c, other, vers("4.5.6"))
+	err = staged.Halt("machine 1 is broken")
+	c.Assert(err, jc.ErrorIsNil)
+
+	// The machine held back is still on the version the halted
+	// upgrade started from.
+	err = s.State.StartStagedUpgrade(vers("4.5.7"), s.args)
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertAgentVersion(c, vers("4.5.7"))
+
+	err = staged.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(staged.PreviousVersion(), gc.Equals, s.currentVersion)
+	c.Assert(staged.TargetVersion(), gc.Equals, vers("4.5.7"))
+	c.Assert(staged.Status(), gc.Equals, state.StagedUpgradeRunning)
+	c.Assert(staged.HaltReason(), gc.Equals, "")
+	c.Assert(staged.Released(), gc.HasLen, 0)
+}
+
+func (s *StagedUpgradeSuite) TestReleaseMachines(c *gc.C) {
+	err := s.State.StartStagedUpgrade(vers("4.5.6"), s.args)
+	c.Assert(err, jc.ErrorIsNil)
+	staged, err := s.State.StagedUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+	err = staged.ReleaseMachines([]string{"3", "1"})
+	c.Assert(err, jc.ErrorIsNil)
+	err = staged.ReleaseMachines([]string{"1", s.machine.Id()})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(staged.Released(), jc.DeepEquals, []string{"3", "1", s.machine.Id()})
+	c.Assert(staged.HoldsMachine(s.machine.Id()), jc.IsFalse)
+
+	err = staged.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(staged.Released(), jc.DeepEquals, []string{"3", "1", s.machine.Id()})
+}
+
+func (s *StagedUpgradeSuite) TestHaltAndResume(c *gc.C) {
+	err := s.State.StartStagedUpgrade(vers("4.5.6"), s.args)
+	c.Assert(err, jc.ErrorIsNil)
+	staged, err := s.State.StagedUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+	err = staged.Halt("machine 0 is not healthy")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(staged.Status(), gc.Equals, state.StagedUpgradeHalted)
+	c.Assert(staged.HaltReason(), gc.Equals, "machine 0 is not healthy")
+	c.Assert(staged.HoldsMachine(s.machine.Id()), jc.IsTrue)
+
+	err = staged.ReleaseMachines([]string{s.machine.Id()})
+	c.Assert(err, gc.ErrorMatches, "cannot release machines: staged upgrade is not running")
+	err = staged.Halt("again")
+	c.Assert(err, gc.ErrorMatches, `cannot set staged upgrade status to "halted": upgrade is not running`)
+
+	err = s.State.ResumeStagedUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+	err = staged.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(staged.Status(), gc.Equals, state.StagedUpgradeRunning)
+	c.Assert(staged.HaltReason(), gc.Equals, "")
+
+	// Resuming a running upgrade does nothing.
+	err = s.State.ResumeStagedUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *StagedUpgradeSuite) TestResumeStagedUpgradeErrors(c *gc.C) {
+	err := s.State.ResumeStagedUpgrade()
+	c.Assert(err, gc.ErrorMatches, "cannot resume staged upgrade: no staged upgrade to resume")
+
+	err = s.State.StartStagedUpgrade(vers("4.5.6"), s.args)
+	c.Assert(err, jc.ErrorIsNil)
+	staged, err := s.State.StagedUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+	err = staged.Complete()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(staged.InProgress(), jc.IsFalse)
+	c.Assert(staged.HoldsMachine(s.machine.Id()), jc.IsFalse)
+	err = s.State.ResumeStagedUpgrade()
+	c.Assert(err, gc.ErrorMatches, "cannot resume staged upgrade: staged upgrade to 4.5.6 is complete")
+}
+
+func (s *StagedUpgradeSuite) TestSetEnvironAgentVersionCancelsStagedUpgrade(c *gc.C) {
+	err := s.State.StartStagedUpgrade(vers("4.5.6"), s.args)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.State.SetEnvironAgentVersion(s.currentVersion)
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertAgentVersion(c, s.currentVersion)
+
+	staged, err := s.State.StagedUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(staged.Status(), gc.Equals, state.StagedUpgradeCancelled)
+	c.Assert(staged.HoldsMachine(s.machine.Id()), jc.IsFalse)
+
+	// A new staged upgrade can be started.
+	err = s.State.StartStagedUpgrade(vers("4.5.6"), s.args)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *StagedUpgradeSuite) TestWatchStagedUpgrade(c *gc.C) {
+	w := s.State.WatchStagedUpgrade()
+	defer statetesting.AssertStop(c, w)
+	wc := statetesting.NewNotifyWatcherC(c, s.State, w)
+	wc.AssertOneChange()
+
+	err := s.State.StartStagedUpgrade(vers("4.5.6"), s.args)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	staged, err := s.State.StagedUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+	err = staged.ReleaseMachines([]string{s.machine.Id()})
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/juju/juju/environs/config"
 	"github.com/juju/juju/mongo"
 	"github.com/juju/juju/network"
+	"github.com/juju/juju/state/multiwatcher"
 	"github.com/juju/juju/state/presence"
 	"github.com/juju/juju/state/watcher"
 	"github.com/juju/juju/version"
@@ -52,10 +53,41 @@ const (
 	minUnitsC          = "minunits"
 	settingsC          = "settings"
 	settingsrefsC      = "settingsrefs"
-	constraintsC       = "constraints"
-	unitsC             = "units"
-	subnetsC           = "subnets"
-	ipaddressesC       = "ipaddresses"
+	// configHistoryC records the changes made to the configuration
+	// of services.
+	configHistoryC = "confighistory"
+	constraintsC   = "constraints"
+	unitsC         = "units"
+	subnetsC       = "subnets"
+	ipaddressesC   = "ipaddresses"
+
+	// spacesC holds the named groups of subnets that service
+	// endpoints can be bound to.
+	spacesC = "spaces"
+
+	// containerTemplatesC records the container templates that have
+	// been built, or requested, on host machines.
+	containerTemplatesC = "containertemplates"
+
+	// observedInterfacesC records the network interfaces last found
+	// on each machine by its agent.
+	observedInterfacesC = "observedinterfaces"
+
+	// offersC holds the service endpoints offered to other
+	// environments, and remoteServicesC the services of other
+	// environments related to through those offers.
+	offersC         = "offers"
+	remoteServicesC = "remoteservices"
+
+	// stagedUpgradesC records the progress of staged agent upgrades.
+	stagedUpgradesC = "stagedupgrades"
+
+	// migrationsC records the environments whose agents are being
+	// redirected to another state server.
+	migrationsC = "migrations"
+	// importsC records the agents that have checked in to environments
+	// imported from another state server.
+	importsC = "imports"
 
 	// actionsC and related collections store state of Actions that
 	// have been enqueued.
@@ -102,6 +134,18 @@ const (
 
 	// restoreInfoC is used to track restore progress
 	restoreInfoC = "restoreInfo"
+
+	// upgradeBackupC records the state backup taken before the state
+	// server upgrade steps are run, for rolling back the upgrade.
+	upgradeBackupC = "upgradeBackup"
+
+	// userSessionsC records the API connections of logged in users
+	// to all the API servers.
+	userSessionsC = "userSessions"
+
+	// sshAccessC records the users who were given the address of a
+	// machine or unit to connect to with ssh.
+	sshAccessC = "sshAccess"
 )
 
 // State represents the state of an environment
@@ -123,6 +167,10 @@ type State struct {
 	mu         sync.Mutex
 	allManager *storeManager
 	environTag names.EnvironTag
+
+	// traces records the trace ids of the API requests in flight
+	// on the state, for logging the transactions run for them.
+	traces *requestTraces
 }
 
 // StateServingInfo holds information needed by a state server.
@@ -193,12 +241,18 @@ func (st *State) MongoSession() *mgo.Session {
 type closeFunc func()
 
 func (st *State) Watch() *Multiwatcher {
+	return st.WatchFiltered(multiwatcher.Filter{})
+}
+
+// WatchFiltered returns a watcher for observing changes to the
+// entities matched by filter. An empty filter matches every entity.
+func (st *State) WatchFiltered(filter multiwatcher.Filter) *Multiwatcher {
 	st.mu.Lock()
 	if st.allManager == nil {
 		st.allManager = newStoreManager(newAllWatcherStateBacking(st))
 	}
 	st.mu.Unlock()
-	return NewMultiwatcher(st.allManager)
+	return NewFilteredMultiwatcher(st.allManager, filter)
 }
 
 func (st *State) EnvironConfig() (*config.Config, error) {
@@ -210,6 +264,25 @@ func (st *State) EnvironConfig() (*config.Config, error) {
 	return config.New(config.NoDefaults, attrs)
 }
 
+// StateServerEnvironConfig returns the configuration of the state
+// server environment, which holds the settings that apply to all the
+// environments it hosts.
+func (st *State) StateServerEnvironConfig() (*config.Config, error) {
+	info, err := st.StateServerInfo()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if info.EnvironmentTag == st.EnvironTag() {
+		return st.EnvironConfig()
+	}
+	ssState, err := st.ForEnviron(info.EnvironmentTag)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer ssState.Close()
+	return ssState.EnvironConfig()
+}
+
 // checkEnvironConfig returns an error if the config is definitely invalid.
 func checkEnvironConfig(cfg *config.Config) error {
 	if cfg.AdminSecret() != "" {
@@ -253,19 +326,21 @@ func IsVersionInconsistentError(e interface{}) bool {
 	return ok
 }
 
-func (st *State) checkCanUpgrade(currentVersion, newVersion string) error {
+// checkCanUpgrade returns an error if any agent runs a version other
+// than currentVersion, newVersion or one of otherVersions.
+func (st *State) checkCanUpgrade(currentVersion, newVersion string, otherVersions ...string) error {
 	db, closer := st.newDB()
 	defer closer()
 
-	matchCurrent := "^" + regexp.QuoteMeta(currentVersion) + "-"
-	matchNew := "^" + regexp.QuoteMeta(newVersion) + "-"
+	var noMatch []bson.D
+	for _, vers := range append([]string{currentVersion, newVersion}, otherVersions...) {
+		match := "^" + regexp.QuoteMeta(vers) + "-"
+		noMatch = append(noMatch, bson.D{{"tools.version", bson.D{{"$not", bson.RegEx{match, ""}}}}})
+	}
 	// Get all machines and units with a different or empty version.
 	sel := bson.D{{"$or", []bson.D{
 		{{"tools", bson.D{{"$exists", false}}}},
-		{{"$and", []bson.D{
-			{{"tools.version", bson.D{{"$not", bson.RegEx{matchCurrent, ""}}}}},
-			{{"tools.version", bson.D{{"$not", bson.RegEx{matchNew, ""}}}}},
-		}}},
+		{{"$and", noMatch}},
 	}}}
 	var agentTags []string
 	for _, name := range []string{machinesC, unitsC} {
@@ -343,7 +418,13 @@ func (st *State) SetEnvironAgentVersion(newVersion version.Number) (err error) {
 				Update: bson.D{{"$set", bson.D{{"agent-version", newVersion.String()}}}},
 			},
 		}
-		return ops, nil
+		// Setting the version directly releases all the agents
+		// held back by a staged upgrade.
+		cancelOps, err := st.cancelStagedUpgradeOps()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return append(ops, cancelOps...), nil
 	}
 	if err = st.run(buildTxn); err == jujutxn.ErrExcessiveContention {
 		// Although there is a small chance of a race here, try to
@@ -1115,6 +1196,11 @@ func (st *State) AddService(name, owner string, ch *Charm, networks []string) (s
 	} else if exists {
 		return nil, errors.Errorf("service already exists")
 	}
+	if exists, err := isNotDead(st, remoteServicesC, name); err != nil {
+		return nil, errors.Trace(err)
+	} else if exists {
+		return nil, errors.Errorf("remote service with the same name already exists")
+	}
 	env, err := st.Environment()
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -1157,6 +1243,10 @@ func (st *State) AddService(name, owner string, ch *Charm, networks []string) (s
 				EnvUUID:  st.EnvironUUID()},
 		},
 		{
+			C:      remoteServicesC,
+			Id:     serviceID,
+			Assert: txn.DocMissing,
+		}, {
 			C:      servicesC,
 			Id:     serviceID,
 			Assert: txn.DocMissing,
@@ -1261,6 +1351,7 @@ func (st *State) AddSubnet(args SubnetInfo) (subnet *Subnet, err error) {
 		AllocatableIPHigh: args.AllocatableIPHigh,
 		AllocatableIPLow:  args.AllocatableIPLow,
 		AvailabilityZone:  args.AvailabilityZone,
+		SpaceName:         args.SpaceName,
 	}
 	subnet = &Subnet{doc: subDoc, st: st}
 	err = subnet.Validate()
@@ -1273,15 +1364,22 @@ func (st *State) AddSubnet(args SubnetInfo) (subnet *Subnet, err error) {
 		Assert: txn.DocMissing,
 		Insert: subDoc,
 	}}
+	if args.SpaceName != "" {
+		ops = append(ops, assertSpaceExistsOp(st, args.SpaceName))
+	}
 
 	err = st.runTransaction(ops)
 	switch err {
 	case txn.ErrAborted:
 		if _, err = st.Subnet(args.CIDR); err == nil {
 			return nil, errors.AlreadyExistsf("subnet %q", args.CIDR)
-		} else if err != nil {
+		} else if !errors.IsNotFound(err) {
 			return nil, errors.Trace(err)
 		}
+		if _, err = st.Space(args.SpaceName); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return nil, errors.Errorf("space %q changed while adding subnet", args.SpaceName)
 	case nil:
 		// if the ProviderId was not unique adding the subnet can fail
 		// without an error. Refreshing catches this
@@ -1562,23 +1660,10 @@ func (st *State) endpoints(name string, filter func(ep Endpoint) bool) ([]Endpoi
 	} else {
 		return nil, errors.Errorf("invalid endpoint %q", name)
 	}
-	svc, err := st.Service(svcName)
+	eps, err := st.serviceEndpoints(svcName, relName)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	eps := []Endpoint{}
-	if relName != "" {
-		ep, err := svc.Endpoint(relName)
-		if err != nil {
-			return nil, errors.Trace(err)
-		}
-		eps = append(eps, ep)
-	} else {
-		eps, err = svc.Endpoints()
-		if err != nil {
-			return nil, errors.Trace(err)
-		}
-	}
 	final := []Endpoint{}
 	for _, ep := range eps {
 		if filter(ep) {
@@ -1588,8 +1673,50 @@ func (st *State) endpoints(name string, filter func(ep Endpoint) bool) ([]Endpoi
 	return final, nil
 }
 
-// AddRelation creates a new relation with the given endpoints.
+// serviceEndpoints returns the named endpoint of the named service, or
+// all its endpoints if relName is empty. The service may be a remote
+// service.
+func (st *State) serviceEndpoints(svcName, relName string) ([]Endpoint, error) {
+	svc, err := st.Service(svcName)
+	if errors.IsNotFound(err) {
+		remote, err := st.RemoteService(svcName)
+		if errors.IsNotFound(err) {
+			return nil, errors.NotFoundf("service %q", svcName)
+		} else if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if relName == "" {
+			return remote.Endpoints(), nil
+		}
+		ep, err := remote.Endpoint(relName)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return []Endpoint{ep}, nil
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if relName == "" {
+		return svc.Endpoints()
+	}
+	ep, err := svc.Endpoint(relName)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return []Endpoint{ep}, nil
+}
+
+// AddRelation creates a new relation with the given endpoints. If one of
+// the endpoints belongs to a remote service, the relation is also added
+// to the environment running the remote service.
 func (st *State) AddRelation(eps ...Endpoint) (r *Relation, err error) {
+	return st.addRelation(eps, true)
+}
+
+// addRelation creates a new relation with the given endpoints. The
+// relation is added to the environment of a remote service only if
+// addCounterpart is true.
+func (st *State) addRelation(eps []Endpoint, addCounterpart bool) (r *Relation, err error) {
 	key := relationKey(eps)
 	defer errors.DeferredAnnotatef(&err, "cannot add relation %q", key)
 	// Enforce basic endpoint sanity. The epCount restrictions may be relaxed
@@ -1618,6 +1745,7 @@ func (st *State) AddRelation(eps ...Endpoint) (r *Relation, err error) {
 	// If a service's charm is upgraded while we're trying to add a relation,
 	// we'll need to re-validate service sanity.
 	var doc *relationDoc
+	var remote *RemoteService
 	buildTxn := func(attempt int) ([]txn.Op, error) {
 		// Perform initial relation sanity check.
 		if exists, err := isNotDead(st, relationsC, key); err != nil {
@@ -1629,10 +1757,17 @@ func (st *State) AddRelation(eps ...Endpoint) (r *Relation, err error) {
 		var ops []txn.Op
 		var subordinateCount int
 		series := map[string]bool{}
+		remote = nil
 		for _, ep := range eps {
 			svc, err := st.Service(ep.ServiceName)
 			if errors.IsNotFound(err) {
-				return nil, errors.Errorf("service %q does not exist", ep.ServiceName)
+				var remoteOps []txn.Op
+				remoteOps, remote, err = st.addRemoteRelationOps(ep, remote)
+				if err != nil {
+					return nil, errors.Trace(err)
+				}
+				ops = append(ops, remoteOps...)
+				continue
 			} else if err != nil {
 				return nil, errors.Trace(err)
 			} else if svc.doc.Life != Alive {
@@ -1680,6 +1815,10 @@ func (st *State) AddRelation(eps ...Endpoint) (r *Relation, err error) {
 			Endpoints: eps,
 			Life:      Alive,
 		}
+		if remote != nil {
+			doc.RemoteEnvUUID = remote.doc.SourceEnvUUID
+			doc.RemoteService = remote.doc.Name
+		}
 		ops = append(ops, txn.Op{
 			C:      relationsC,
 			Id:     docID,
@@ -1688,10 +1827,77 @@ func (st *State) AddRelation(eps ...Endpoint) (r *Relation, err error) {
 		})
 		return ops, nil
 	}
-	if err = st.run(buildTxn); err == nil {
-		return &Relation{st, *doc}, nil
+	if err = st.run(buildTxn); err != nil {
+		return nil, errors.Trace(err)
 	}
-	return nil, errors.Trace(err)
+	rel := &Relation{st, *doc}
+	if remote != nil && addCounterpart {
+		if err := st.addCounterpartRelation(rel); err != nil {
+			if err := rel.Destroy(); err != nil {
+				logger.Errorf("cannot destroy relation %q: %v", rel, err)
+			}
+			return nil, errors.Trace(err)
+		}
+	}
+	return rel, nil
+}
+
+// addRemoteRelationOps returns the operations necessary to add a
+// relation to the given endpoint of a remote service, along with the
+// remote service. If the relation already has a remote endpoint, as
+// given by other, an error is returned: relations between remote
+// services are not supported.
+func (st *State) addRemoteRelationOps(ep Endpoint, other *RemoteService) ([]txn.Op, *RemoteService, error) {
+	remote, err := st.RemoteService(ep.ServiceName)
+	if errors.IsNotFound(err) {
+		return nil, nil, errors.Errorf("service %q does not exist", ep.ServiceName)
+	} else if err != nil {
+		return nil, nil, errors.Trace(err)
+	} else if remote.doc.Life != Alive {
+		return nil, nil, errors.Errorf("service %q is not alive", ep.ServiceName)
+	}
+	if other != nil {
+		return nil, nil, errors.Errorf("cannot relate remote services %q and %q", other, remote)
+	}
+	if ep.Scope == charm.ScopeContainer {
+		return nil, nil, errors.Errorf("remote service %q cannot have container scoped relations", remote)
+	}
+	if _, err := remote.Endpoint(ep.Name); err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	return []txn.Op{{
+		C:      remoteServicesC,
+		Id:     st.docID(ep.ServiceName),
+		Assert: isAliveDoc,
+		Update: bson.D{{"$inc", bson.D{{"relationcount", 1}}}},
+	}}, remote, nil
+}
+
+// addCounterpartRelation adds the given remote relation to the
+// environment running its remote service, where the local service of
+// the relation is recorded as a remote service in turn.
+func (st *State) addCounterpartRelation(rel *Relation) error {
+	env, err := st.Environment()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	other, err := st.ForEnviron(names.NewEnvironTag(rel.doc.RemoteEnvUUID))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer other.Close()
+	for _, ep := range rel.doc.Endpoints {
+		if ep.ServiceName == rel.doc.RemoteService {
+			continue
+		}
+		if _, err := other.ensureRemoteService(ep.ServiceName, st.EnvironUUID(), env.Name(), ep.Relation); err != nil {
+			return errors.Annotatef(err, "cannot add relation to environment %q", rel.doc.RemoteEnvUUID)
+		}
+	}
+	if _, err := other.addRelation(rel.doc.Endpoints, false); err != nil {
+		return errors.Annotatef(err, "cannot add relation to environment %q", rel.doc.RemoteEnvUUID)
+	}
+	return nil
 }
 
 // EndpointsRelation returns the existing relation with the given endpoints.
@@ -3710,3 +3710,28 @@ func (s *SetAdminMongoPasswordSuite) TestSetAdminMongoPassword(c *gc.C) {
 	err = tryOpenState(mongoInfo)
 	c.Assert(err, jc.ErrorIsNil)
 }
+
+func (s *StateSuite) TestTraceRequestLogsTransactions(c *gc.C) {
+	txnLogger := loggo.GetLogger("juju.state.txn")
+	defer txnLogger.SetLogLevel(txnLogger.LogLevel())
+	txnLogger.SetLogLevel(loggo.TRACE)
+	var tw loggo.TestWriter
+	c.Assert(loggo.RegisterWriter("txn-tester", &tw, loggo.TRACE), gc.IsNil)
+	defer loggo.RemoveWriter("txn-tester")
+
+	doneA := s.State.TraceRequest("0123456789abcdef")
+	doneB := s.State.TraceRequest("fedcba9876543210")
+	_, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	doneB()
+	_, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	doneA()
+	_, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(tw.Log(), jc.LogMatches, []string{
+		`\[0123456789abcdef,fedcba9876543210\] running transaction in environment .*`,
+		`\[0123456789abcdef\] running transaction in environment .*`,
+		`\[-\] running transaction in environment .*`,
+	})
+}
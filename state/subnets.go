@@ -38,6 +38,10 @@ type SubnetInfo struct {
 	// AvailabilityZone describes which availability zone this subnet is in. It can
 	// be empty if the provider does not support availability zones.
 	AvailabilityZone string
+
+	// SpaceName is the name of the space the subnet is in. It can be
+	// empty if the subnet is not in a space.
+	SpaceName string
 }
 
 type Subnet struct {
@@ -56,6 +60,7 @@ type subnetDoc struct {
 
 	VLANTag          int    `bson:",omitempty"`
 	AvailabilityZone string `bson:",omitempty"`
+	SpaceName        string `bson:"spacename,omitempty"`
 }
 
 // Life returns whether the subnet is Alive, Dying or Dead.
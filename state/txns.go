@@ -6,13 +6,79 @@ package state
 import (
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 
+	"github.com/juju/loggo"
 	jujutxn "github.com/juju/txn"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 	"gopkg.in/mgo.v2/txn"
 )
 
+// txnLogger logs the transactions run by State, together with the
+// trace ids of the API requests in flight when they were run.
+var txnLogger = loggo.GetLogger("juju.state.txn")
+
+// requestTraces records the trace ids of the API requests being served
+// with a State. It is shared by the transaction runners of the State,
+// so that they can be logged with the requests that may have caused
+// them without a copy of the State for each request.
+type requestTraces struct {
+	mu  sync.Mutex
+	ids map[string]int
+}
+
+func newRequestTraces() *requestTraces {
+	return &requestTraces{ids: make(map[string]int)}
+}
+
+func (t *requestTraces) add(traceId string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ids[traceId]++
+}
+
+func (t *requestTraces) remove(traceId string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ids[traceId]--; t.ids[traceId] <= 0 {
+		delete(t.ids, traceId)
+	}
+}
+
+// String returns the trace ids of the requests in flight, sorted and
+// separated by commas, or "-" if there are none.
+func (t *requestTraces) String() string {
+	t.mu.Lock()
+	ids := make([]string, 0, len(t.ids))
+	for id := range t.ids {
+		ids = append(ids, id)
+	}
+	t.mu.Unlock()
+	if len(ids) == 0 {
+		return "-"
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
+
+// TraceRequest records that the API request with the given trace id
+// is being served with st until the returned function is called. The
+// transactions run by st in the meantime are logged with its trace id.
+// Requests served concurrently are all logged with each transaction,
+// as a transaction cannot be told apart from those of the others.
+func (st *State) TraceRequest(traceId string) (done func()) {
+	if traceId == "" || st.traces == nil {
+		return func() {}
+	}
+	st.traces.add(traceId)
+	return func() {
+		st.traces.remove(traceId)
+	}
+}
+
 // txnRunner returns a jujutxn.Runner instance.
 //
 // If st.transactionRunner is non-nil, then that will be
@@ -27,7 +93,9 @@ func (st *State) txnRunner(session *mgo.Session) jujutxn.Runner {
 	if st.transactionRunner != nil {
 		return st.transactionRunner
 	}
-	return newMultiEnvRunner(st.EnvironUUID(), st.db.With(session))
+	runner := newMultiEnvRunner(st.EnvironUUID(), st.db.With(session))
+	runner.traces = st.traces
+	return runner
 }
 
 // runTransaction is a convenience method delegating to transactionRunner.
@@ -51,7 +119,7 @@ func (st *State) ResumeTransactions() error {
 	return st.txnRunner(session).ResumeTransactions()
 }
 
-func newMultiEnvRunner(envUUID string, db *mgo.Database) jujutxn.Runner {
+func newMultiEnvRunner(envUUID string, db *mgo.Database) *multiEnvRunner {
 	return &multiEnvRunner{
 		rawRunner: jujutxn.NewRunner(jujutxn.RunnerParams{Database: db}),
 		envUUID:   envUUID,
@@ -61,6 +129,10 @@ func newMultiEnvRunner(envUUID string, db *mgo.Database) jujutxn.Runner {
 type multiEnvRunner struct {
 	rawRunner jujutxn.Runner
 	envUUID   string
+
+	// traces holds the trace ids of the API requests in flight
+	// when transactions are run, if they are recorded.
+	traces *requestTraces
 }
 
 // RunTransaction is part of the jujutxn.Runner interface. Operations
@@ -68,6 +140,7 @@ type multiEnvRunner struct {
 // to ensure correct interaction with these collections.
 func (r *multiEnvRunner) RunTransaction(ops []txn.Op) error {
 	r.updateOps(ops)
+	r.logOps(ops)
 	return r.rawRunner.RunTransaction(ops)
 }
 
@@ -84,10 +157,24 @@ func (r *multiEnvRunner) Run(transactions jujutxn.TransactionSource) error {
 			return nil, err
 		}
 		r.updateOps(ops)
+		r.logOps(ops)
 		return ops, nil
 	})
 }
 
+// logOps logs the operations of a transaction about to be run, with
+// the trace ids of the requests in flight.
+func (r *multiEnvRunner) logOps(ops []txn.Op) {
+	if txnLogger.EffectiveLogLevel() > loggo.TRACE {
+		return
+	}
+	traceIds := "-"
+	if r.traces != nil {
+		traceIds = r.traces.String()
+	}
+	txnLogger.Tracef("[%s] running transaction in environment %s: %+v", traceIds, r.envUUID, ops)
+}
+
 // Run is part of the jujutxn.Run interface.
 func (r *multiEnvRunner) ResumeTransactions() error {
 	return r.rawRunner.ResumeTransactions()
@@ -237,6 +324,14 @@ func (st *State) runRawTransaction(ops []txn.Op) error {
 	return runner.RunTransaction(ops)
 }
 
+// runRaw is a convenience method that will run transactions using a
+// "raw" transaction runner, as returned by rawTxnRunner.
+func (st *State) runRaw(transactions jujutxn.TransactionSource) error {
+	session := st.db.Session.Copy()
+	defer session.Close()
+	return st.rawTxnRunner(session).Run(transactions)
+}
+
 // getRawRunner returns the underlying "raw" transaction runner from
 // the passed transaction runner.
 func getRawRunner(runner jujutxn.Runner) jujutxn.Runner {
@@ -1012,6 +1012,7 @@ var (
 	unitNotAliveErr    = stderrors.New("unit is not alive")
 	alreadyAssignedErr = stderrors.New("unit is already assigned to a machine")
 	inUseErr           = stderrors.New("machine is not unused")
+	placementErr       = stderrors.New("placement of related units has changed")
 )
 
 // assignToMachine is the internal version of AssignToMachine,
@@ -1021,7 +1022,9 @@ var (
 // - unitNotAliveErr when the unit is not alive.
 // - alreadyAssignedErr when the unit has already been assigned
 // - inUseErr when the machine already has a unit assigned (if unused is true)
-func (u *Unit) assignToMachine(m *Machine, unused bool) (err error) {
+// - placementErr when units that the placement check depended on have
+// been assigned since the snapshot was read.
+func (u *Unit) assignToMachine(m *Machine, unused bool, snapshot *placementSnapshot) (err error) {
 	if u.doc.Series != m.doc.Series {
 		return fmt.Errorf("series does not match")
 	}
@@ -1070,6 +1073,7 @@ func (u *Unit) assignToMachine(m *Machine, unused bool) (err error) {
 		Assert: massert,
 		Update: bson.D{{"$addToSet", bson.D{{"principals", u.doc.Name}}}, {"$set", bson.D{{"clean", false}}}},
 	}}
+	ops = append(ops, snapshot.placementOps(u.st, u.doc.Service)...)
 	err = u.st.runTransaction(ops)
 	if err == nil {
 		u.doc.MachineId = m.doc.Id
@@ -1092,10 +1096,12 @@ func (u *Unit) assignToMachine(m *Machine, unused bool) (err error) {
 		return unitNotAliveErr
 	case m0.Life() != Alive:
 		return machineNotAliveErr
-	case u0.doc.MachineId != "" || !unused:
+	case u0.doc.MachineId != "":
 		return alreadyAssignedErr
+	case unused && !m0.doc.Clean:
+		return inUseErr
 	}
-	return inUseErr
+	return placementErr
 }
 
 func assignContextf(err *error, unit *Unit, target string) {
@@ -1104,15 +1110,31 @@ func assignContextf(err *error, unit *Unit, target string) {
 	}
 }
 
-// AssignToMachine assigns this unit to a given machine.
+// AssignToMachine assigns this unit to a given machine. It fails if the
+// unit's anti-affinity placement policies keep it off the machine.
 func (u *Unit) AssignToMachine(m *Machine) (err error) {
 	defer assignContextf(&err, u, fmt.Sprintf("machine %s", m))
-	return u.assignToMachine(m, false)
+	if u.doc.MachineId != "" {
+		return u.assignToMachine(m, false, nil)
+	}
+	for attempt := 0; attempt < placementAttempts; attempt++ {
+		snapshot, err := u.checkHostPlacement(m.Id())
+		if err != nil {
+			return err
+		}
+		if err := u.assignToMachine(m, false, snapshot); err != placementErr {
+			return err
+		}
+	}
+	return jujutxn.ErrExcessiveContention
 }
 
 // assignToNewMachine assigns the unit to a machine created according to
-// the supplied params, with the supplied constraints.
-func (u *Unit) assignToNewMachine(template MachineTemplate, parentId string, containerType instance.ContainerType) error {
+// the supplied params, with the supplied constraints. If a parent
+// machine is given, the snapshot its placement was checked against
+// must be given too; it returns placementErr if the placement has
+// changed since.
+func (u *Unit) assignToNewMachine(template MachineTemplate, parentId string, containerType instance.ContainerType, snapshot *placementSnapshot) error {
 	template.principals = []string{u.doc.Name}
 	template.Dirty = true
 
@@ -1152,6 +1174,7 @@ func (u *Unit) assignToNewMachine(template MachineTemplate, parentId string, con
 			Id:     parentDocId,
 			Assert: bson.D{hasNoContainersTerm},
 		})
+		ops = append(ops, snapshot.placementOps(u.st, u.doc.Service)...)
 	}
 	isUnassigned := bson.D{{"machineid", ""}}
 	asserts := append(isAliveDoc, isUnassigned...)
@@ -1205,6 +1228,9 @@ func (u *Unit) assignToNewMachine(template MachineTemplate, parentId string, con
 	if len(containers) > 0 {
 		return machineNotCleanErr
 	}
+	if snapshot != nil {
+		return placementErr
+	}
 	return fmt.Errorf("cannot add container within machine: transaction aborted for unknown reason")
 }
 
@@ -1239,8 +1265,8 @@ func (u *Unit) AssignToNewMachineOrContainer() (err error) {
 		return u.AssignToNewMachine()
 	}
 
-	// Find a clean, empty machine on which to create a container.
-	var host machineDoc
+	// Find a clean, empty machine on which to create a container,
+	// that the unit's placement policies allow it on.
 	hostCons := *cons
 	noContainer := instance.NONE
 	hostCons.Container = &noContainer
@@ -1249,14 +1275,20 @@ func (u *Unit) AssignToNewMachineOrContainer() (err error) {
 		return err
 	}
 	defer closer()
-	err = query.One(&host)
-	if err == mgo.ErrNotFound {
+	var hosts []machineDoc
+	if err := query.All(&hosts); err != nil {
+		return err
+	}
+	hosts, snapshot, err := u.allowedMachineDocs(hosts)
+	if err != nil {
+		return err
+	}
+	if len(hosts) == 0 {
 		// No existing clean, empty machine so create a new one.
 		// The container constraint will be used by AssignToNewMachine to create the required container.
 		return u.AssignToNewMachine()
-	} else if err != nil {
-		return err
 	}
+	host := hosts[0]
 	svc, err := u.Service()
 	if err != nil {
 		return err
@@ -1271,9 +1303,10 @@ func (u *Unit) AssignToNewMachineOrContainer() (err error) {
 		Jobs:              []MachineJob{JobHostUnits},
 		RequestedNetworks: requestedNetworks,
 	}
-	err = u.assignToNewMachine(template, host.Id, *cons.Container)
-	if err == machineNotCleanErr {
-		// The clean machine was used before we got a chance to use it so just
+	err = u.assignToNewMachine(template, host.Id, *cons.Container, snapshot)
+	if err == machineNotCleanErr || err == placementErr {
+		// The clean machine was used, or the placement of related
+		// units changed, before we got a chance to use it so just
 		// stick the unit on a new machine.
 		return u.AssignToNewMachine()
 	}
@@ -1313,7 +1346,7 @@ func (u *Unit) AssignToNewMachine() (err error) {
 		Jobs:              []MachineJob{JobHostUnits},
 		RequestedNetworks: requestedNetworks,
 	}
-	return u.assignToNewMachine(template, "", containerType)
+	return u.assignToNewMachine(template, "", containerType, nil)
 }
 
 var noCleanMachines = stderrors.New("all eligible machines in use")
@@ -1436,9 +1469,48 @@ func (u *Unit) findCleanMachineQuery(requireEmpty bool, cons *constraints.Value)
 	return machines.Find(terms), closer, nil
 }
 
+// allowedMachineDocs returns those of the given machines that the
+// unit's placement policies allow it to be placed on, and the
+// snapshot they were checked against.
+func (u *Unit) allowedMachineDocs(mdocs []machineDoc) ([]machineDoc, *placementSnapshot, error) {
+	hosts := make([]string, len(mdocs))
+	for i, mdoc := range mdocs {
+		hosts[i] = TopParentId(mdoc.Id)
+	}
+	policies, snapshot, err := u.readUnitPlacement(hosts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(policies) == 0 {
+		return mdocs, snapshot, nil
+	}
+	var allowed []machineDoc
+	for _, mdoc := range mdocs {
+		if snapshot.allows(u.doc.Service, policies, mdoc.Id) {
+			allowed = append(allowed, mdoc)
+		}
+	}
+	return allowed, snapshot, nil
+}
+
 // assignToCleanMaybeEmptyMachine implements AssignToCleanMachine and AssignToCleanEmptyMachine.
 // A 'machine' may be a machine instance or container depending on the service constraints.
-func (u *Unit) assignToCleanMaybeEmptyMachine(requireEmpty bool) (m *Machine, err error) {
+func (u *Unit) assignToCleanMaybeEmptyMachine(requireEmpty bool) (*Machine, error) {
+	for attempt := 0; attempt < placementAttempts; attempt++ {
+		m, err := u.tryAssignToCleanMaybeEmptyMachine(requireEmpty)
+		if err != placementErr {
+			return m, err
+		}
+	}
+	err := jujutxn.ErrExcessiveContention
+	assignContextf(&err, u, "clean machine")
+	return nil, err
+}
+
+// tryAssignToCleanMaybeEmptyMachine makes one attempt at
+// assignToCleanMaybeEmptyMachine. It returns placementErr if the
+// placement of related units changed while it was choosing a machine.
+func (u *Unit) tryAssignToCleanMaybeEmptyMachine(requireEmpty bool) (m *Machine, err error) {
 	context := "clean"
 	if requireEmpty {
 		context += ", empty"
@@ -1468,16 +1540,21 @@ func (u *Unit) assignToCleanMaybeEmptyMachine(requireEmpty bool) (m *Machine, er
 	// instances for those that are provisioned. Instances
 	// will be distributed across in preference to
 	// unprovisioned machines.
-	var mdocs []*machineDoc
+	var mdocs []machineDoc
 	if err := query.All(&mdocs); err != nil {
 		assignContextf(&err, u, context)
 		return nil, err
 	}
+	mdocs, snapshot, err := u.allowedMachineDocs(mdocs)
+	if err != nil {
+		assignContextf(&err, u, context)
+		return nil, err
+	}
 	var unprovisioned []*Machine
 	var instances []instance.Id
 	instanceMachines := make(map[instance.Id]*Machine)
-	for _, mdoc := range mdocs {
-		m := newMachine(u.st, mdoc)
+	for i := range mdocs {
+		m := newMachine(u.st, &mdocs[i])
 		instance, err := m.InstanceId()
 		if errors.IsNotProvisioned(err) {
 			unprovisioned = append(unprovisioned, m)
@@ -1521,10 +1598,13 @@ func (u *Unit) assignToCleanMaybeEmptyMachine(requireEmpty bool) (m *Machine, er
 	// provisioned without the fact having yet been recorded
 	// in state.
 	for _, m := range machines {
-		err := u.assignToMachine(m, true)
+		err := u.assignToMachine(m, true, snapshot)
 		if err == nil {
 			return m, nil
 		}
+		if err == placementErr {
+			return nil, err
+		}
 		if err != inUseErr && err != machineNotAliveErr {
 			assignContextf(&err, u, context)
 			return nil, err
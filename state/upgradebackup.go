@@ -0,0 +1,258 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/utils/set"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/version"
+)
+
+// currentUpgradeBackupId is the id of the upgrade backup document.
+const currentUpgradeBackupId = "current"
+
+// upgradeBackupDoc records the state backup taken before the state
+// server upgrade steps were run for an upgrade.
+type upgradeBackupDoc struct {
+	Id              string         `bson:"_id"`
+	PreviousVersion version.Number `bson:"previousversion"`
+	TargetVersion   version.Number `bson:"targetversion"`
+	BackupId        string         `bson:"backupid"`
+	Taken           time.Time      `bson:"taken"`
+	// TxnMark orders before the ids of the transactions run after
+	// the backup was taken.
+	TxnMark bson.ObjectId `bson:"txnmark"`
+	// Machines holds the ids of the machine documents, of all the
+	// environments, when the backup was taken.
+	Machines   []string `bson:"machines"`
+	RolledBack bool     `bson:"rolledback"`
+}
+
+// UpgradeBackup describes the state backup taken before the state
+// server upgrade steps were run for the last upgrade, which the upgrade
+// can be rolled back to.
+type UpgradeBackup struct {
+	st  *State
+	doc upgradeBackupDoc
+}
+
+// PreviousVersion returns the version upgraded from, which the upgrade
+// is rolled back to.
+func (b *UpgradeBackup) PreviousVersion() version.Number {
+	return b.doc.PreviousVersion
+}
+
+// TargetVersion returns the version upgraded to.
+func (b *UpgradeBackup) TargetVersion() version.Number {
+	return b.doc.TargetVersion
+}
+
+// BackupId returns the id of the state backup.
+func (b *UpgradeBackup) BackupId() string {
+	return b.doc.BackupId
+}
+
+// Taken returns when the backup was taken.
+func (b *UpgradeBackup) Taken() time.Time {
+	return b.doc.Taken
+}
+
+// RolledBack returns whether the upgrade was rolled back.
+func (b *UpgradeBackup) RolledBack() bool {
+	return b.doc.RolledBack
+}
+
+// MachinesAddedSince returns the machines added since the backup was
+// taken, in any environment. They are identified by their id for the
+// environment of the state, and by their global id for the others.
+func (b *UpgradeBackup) MachinesAddedSince() ([]string, error) {
+	machineIds, err := b.st.allMachineDocIds()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	known := set.NewStrings(b.doc.Machines...)
+	var added []string
+	for _, id := range machineIds {
+		if !known.Contains(id) {
+			added = append(added, b.st.localID(id))
+		}
+	}
+	return added, nil
+}
+
+// txnApplying and txnApplied are the states of the transactions, in
+// the transaction collection, whose changes are, or are being, applied.
+const (
+	txnApplying = 4
+	txnApplied  = 6
+)
+
+// ChangesSince returns the documents changed by the transactions run
+// since the backup was taken, which restoring it would lose, sorted.
+// They are identified by collection and id, the ids being local to the
+// environment of the state where possible. Changes to the upgrade
+// records themselves are not included, and neither are changes to the
+// restore info, which locks the environment while the backup is
+// restored.
+func (b *UpgradeBackup) ChangesSince() ([]string, error) {
+	txns, closer := b.st.getRawCollection(txnsC)
+	defer closer()
+
+	query := bson.D{
+		{"_id", bson.D{{"$gt", b.doc.TxnMark}}},
+		{"s", bson.D{{"$in", []int{txnApplying, txnApplied}}}},
+	}
+	changed := make(set.Strings)
+	iter := txns.Find(query).Select(bson.D{{"o.c", 1}, {"o.d", 1}}).Iter()
+	for {
+		var doc struct {
+			Ops []struct {
+				C  string      `bson:"c"`
+				Id interface{} `bson:"d"`
+			} `bson:"o"`
+		}
+		if !iter.Next(&doc) {
+			break
+		}
+		for _, op := range doc.Ops {
+			if op.C == upgradeInfoC || op.C == upgradeBackupC || op.C == restoreInfoC {
+				continue
+			}
+			id := fmt.Sprint(op.Id)
+			if s, ok := op.Id.(string); ok {
+				id = b.st.localID(s)
+			}
+			changed.Add(op.C + " " + id)
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return nil, errors.Annotate(err, "cannot read transactions")
+	}
+	return changed.SortedValues(), nil
+}
+
+// SetRolledBack records that the upgrade was rolled back. As restoring
+// the backup replaces the upgrade backup document with the one it
+// holds, if any, the document is written again as a whole.
+func (b *UpgradeBackup) SetRolledBack() error {
+	doc := b.doc
+	doc.RolledBack = true
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		return b.st.setUpgradeBackupOps(doc)
+	}
+	if err := b.st.run(buildTxn); err != nil {
+		return errors.Annotate(err, "cannot record upgrade rollback")
+	}
+	b.doc = doc
+	return nil
+}
+
+// SetUpgradeBackup records the id of the state backup taken before the
+// state server upgrade steps were run for the upgrade from the previous
+// version to the target version, replacing the record of any backup
+// taken for an earlier upgrade.
+func (st *State) SetUpgradeBackup(previousVersion, targetVersion version.Number, backupId string) error {
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		machineIds, err := st.allMachineDocIds()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		doc := upgradeBackupDoc{
+			Id:              currentUpgradeBackupId,
+			PreviousVersion: previousVersion,
+			TargetVersion:   targetVersion,
+			BackupId:        backupId,
+			Taken:           nowToTheSecond(),
+			TxnMark:         bson.NewObjectId(),
+			Machines:        machineIds,
+		}
+		return st.setUpgradeBackupOps(doc)
+	}
+	if err := st.run(buildTxn); err != nil {
+		return errors.Annotate(err, "cannot record upgrade backup")
+	}
+	return nil
+}
+
+// UpgradeBackup returns the record of the state backup taken before the
+// state server upgrade steps were run for the last upgrade. If there is
+// none, an error satisfying errors.IsNotFound is returned.
+func (st *State) UpgradeBackup() (*UpgradeBackup, error) {
+	doc, err := st.upgradeBackupDoc()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &UpgradeBackup{st: st, doc: *doc}, nil
+}
+
+func (st *State) upgradeBackupDoc() (*upgradeBackupDoc, error) {
+	coll, closer := st.getCollection(upgradeBackupC)
+	defer closer()
+
+	var doc upgradeBackupDoc
+	err := coll.FindId(currentUpgradeBackupId).One(&doc)
+	if err == mgo.ErrNotFound {
+		return nil, errors.NotFoundf("upgrade backup")
+	} else if err != nil {
+		return nil, errors.Annotate(err, "cannot read upgrade backup")
+	}
+	return &doc, nil
+}
+
+// setUpgradeBackupOps returns the operations that replace the upgrade
+// backup document, or insert it if there is none.
+func (st *State) setUpgradeBackupOps(doc upgradeBackupDoc) ([]txn.Op, error) {
+	_, err := st.upgradeBackupDoc()
+	if errors.IsNotFound(err) {
+		return []txn.Op{{
+			C:      upgradeBackupC,
+			Id:     currentUpgradeBackupId,
+			Assert: txn.DocMissing,
+			Insert: doc,
+		}}, nil
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return []txn.Op{{
+		C:      upgradeBackupC,
+		Id:     currentUpgradeBackupId,
+		Assert: txn.DocExists,
+		Update: bson.D{{"$set", bson.D{
+			{"previousversion", doc.PreviousVersion},
+			{"targetversion", doc.TargetVersion},
+			{"backupid", doc.BackupId},
+			{"taken", doc.Taken},
+			{"txnmark", doc.TxnMark},
+			{"machines", doc.Machines},
+			{"rolledback", doc.RolledBack},
+		}}},
+	}}, nil
+}
+
+// allMachineDocIds returns the ids of the machine documents of all the
+// environments.
+func (st *State) allMachineDocIds() ([]string, error) {
+	machines, closer := st.getRawCollection(machinesC)
+	defer closer()
+
+	var ids []string
+	var doc struct {
+		DocID string `bson:"_id"`
+	}
+	iter := machines.Find(nil).Select(bson.D{{"_id", 1}}).Iter()
+	for iter.Next(&doc) {
+		ids = append(ids, doc.DocID)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, errors.Annotate(err, "cannot read machines")
+	}
+	return ids, nil
+}
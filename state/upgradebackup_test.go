@@ -0,0 +1,124 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/testing/factory"
+)
+
+type UpgradeBackupSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&UpgradeBackupSuite{})
+
+func (s *UpgradeBackupSuite) TestUpgradeBackupNotFound(c *gc.C) {
+	_, err := s.State.UpgradeBackup()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *UpgradeBackupSuite) TestSetUpgradeBackup(c *gc.C) {
+	err := s.State.SetUpgradeBackup(vers("1.21.1"), vers("1.22.0"), "backup-1")
+	c.Assert(err, jc.ErrorIsNil)
+
+	backup, err := s.State.UpgradeBackup()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(backup.PreviousVersion(), gc.Equals, vers("1.21.1"))
+	c.Assert(backup.TargetVersion(), gc.Equals, vers("1.22.0"))
+	c.Assert(backup.BackupId(), gc.Equals, "backup-1")
+	c.Assert(backup.Taken().IsZero(), jc.IsFalse)
+	c.Assert(backup.RolledBack(), jc.IsFalse)
+
+	// A backup for a later upgrade replaces it.
+	err = backup.SetRolledBack()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.State.SetUpgradeBackup(vers("1.21.1"), vers("1.22.1"), "backup-2")
+	c.Assert(err, jc.ErrorIsNil)
+	backup, err = s.State.UpgradeBackup()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(backup.TargetVersion(), gc.Equals, vers("1.22.1"))
+	c.Assert(backup.BackupId(), gc.Equals, "backup-2")
+	c.Assert(backup.RolledBack(), jc.IsFalse)
+}
+
+func (s *UpgradeBackupSuite) TestSetRolledBack(c *gc.C) {
+	err := s.State.SetUpgradeBackup(vers("1.21.1"), vers("1.22.0"), "backup-1")
+	c.Assert(err, jc.ErrorIsNil)
+	backup, err := s.State.UpgradeBackup()
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Restoring a backup taken before any upgrade backup was
+	// recorded removes the record, outside of any transaction.
+	coll, closer := state.GetRawCollection(s.State, "upgradeBackup")
+	defer closer()
+	err = coll.RemoveId("current")
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = backup.SetRolledBack()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(backup.RolledBack(), jc.IsTrue)
+
+	backup, err = s.State.UpgradeBackup()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(backup.TargetVersion(), gc.Equals, vers("1.22.0"))
+	c.Assert(backup.BackupId(), gc.Equals, "backup-1")
+	c.Assert(backup.RolledBack(), jc.IsTrue)
+}
+
+func (s *UpgradeBackupSuite) TestMachinesAddedSince(c *gc.C) {
+	s.factory.MakeMachine(c, nil)
+	err := s.State.SetUpgradeBackup(vers("1.21.1"), vers("1.22.0"), "backup-1")
+	c.Assert(err, jc.ErrorIsNil)
+	backup, err := s.State.UpgradeBackup()
+	c.Assert(err, jc.ErrorIsNil)
+
+	added, err := backup.MachinesAddedSince()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(added, gc.HasLen, 0)
+
+	m1 := s.factory.MakeMachine(c, nil)
+	otherState := s.factory.MakeEnvironment(c, nil)
+	defer otherState.Close()
+	m2 := factory.NewFactory(otherState).MakeMachine(c, nil)
+
+	added, err = backup.MachinesAddedSince()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(added, jc.SameContents, []string{
+		m1.Id(),
+		otherState.EnvironUUID() + ":" + m2.Id(),
+	})
+}
+
+func (s *UpgradeBackupSuite) TestChangesSince(c *gc.C) {
+	m0 := s.factory.MakeMachine(c, nil)
+	err := s.State.SetUpgradeBackup(vers("1.21.1"), vers("1.22.0"), "backup-1")
+	c.Assert(err, jc.ErrorIsNil)
+	backup, err := s.State.UpgradeBackup()
+	c.Assert(err, jc.ErrorIsNil)
+
+	changed, err := backup.ChangesSince()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(changed, gc.HasLen, 0)
+
+	err = m0.SetProvisioned("i-0", "fake-nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	err = backup.SetRolledBack()
+	c.Assert(err, jc.ErrorIsNil)
+	info, err := s.State.EnsureRestoreInfo()
+	c.Assert(err, jc.ErrorIsNil)
+	err = info.SetStatus(state.RestorePending)
+	c.Assert(err, jc.ErrorIsNil)
+
+	changed, err = backup.ChangesSince()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(changed, jc.DeepEquals, []string{
+		"instanceData " + m0.Id(),
+		"machines " + m0.Id(),
+	})
+}
@@ -94,7 +94,7 @@ func AddStateUsersAsEnvironUsers(st *State) error {
 
 		_, err := st.EnvironmentUser(uTag)
 		if err != nil && errors.IsNotFound(err) {
-			_, err = st.AddEnvironmentUser(uTag, uTag)
+			_, err = st.AddEnvironmentUser(uTag, uTag, AdminAccess)
 			if err != nil {
 				return errors.Trace(err)
 			}
@@ -749,6 +749,15 @@ func AddEnvUUIDToMeterStatus(st *State) error {
 	return addEnvUUIDToEntityCollection(st, meterStatusC)
 }
 
+// AddEnvUUIDToLeases prepends the environment UUID to the ID of all
+// lease docs and adds new "env-uuid" and "namespace" fields, so that
+// services with the same name in different environments do not share
+// a lease. The lease documents must have been migrated by
+// MigrateLeaseDocs first.
+func AddEnvUUIDToLeases(st *State) error {
+	return addEnvUUIDToEntityCollection(st, leaseC, setOldID("namespace"))
+}
+
 func addEnvUUIDToEntityCollection(st *State, collName string, updates ...updateFunc) error {
 	env, err := st.Environment()
 	if err != nil {
@@ -939,3 +948,45 @@ func FixSequenceFields(st *State) error {
 	}
 	return st.runRawTransaction(ops)
 }
+
+// MigrateLeaseDocs converts the lease documents written before state
+// became the authority for leases, which held the lease token in a
+// "token" subdocument, to record the lease holder and expiration
+// directly.
+func MigrateLeaseDocs(st *State) error {
+	leases, closer := st.getRawCollection(leaseC)
+	defer closer()
+
+	iter := leases.Find(bson.D{{"token", bson.D{{"$exists", true}}}}).Iter()
+	defer iter.Close()
+
+	ops := []txn.Op{}
+	var doc struct {
+		Id    string `bson:"_id"`
+		Token struct {
+			Id         string    `bson:"id"`
+			Expiration time.Time `bson:"expiration"`
+		} `bson:"token"`
+	}
+	for iter.Next(&doc) {
+		ops = append(ops, txn.Op{
+			C:      leaseC,
+			Id:     doc.Id,
+			Assert: txn.DocExists,
+			Update: bson.D{
+				{"$set", bson.D{
+					{"holder", doc.Token.Id},
+					{"expiration", doc.Token.Expiration},
+				}},
+				{"$unset", bson.D{
+					{"token", 1},
+					{"lastupdate", 1},
+				}},
+			},
+		})
+	}
+	if err := iter.Err(); err != nil {
+		return errors.Annotate(err, "cannot read leases")
+	}
+	return st.runRawTransaction(ops)
+}
@@ -1097,7 +1097,7 @@ func (s *upgradesSuite) setUpPortsMigration(c *gc.C) ([]*Machine, map[int][]*Uni
 	stateOwner, err := s.state.AddUser("bob", "notused", "notused", "bob")
 	c.Assert(err, jc.ErrorIsNil)
 	ownerTag := stateOwner.UserTag()
-	_, err = s.state.AddEnvironmentUser(ownerTag, ownerTag)
+	_, err = s.state.AddEnvironmentUser(ownerTag, ownerTag, AdminAccess)
 	c.Assert(err, jc.ErrorIsNil)
 
 	for i := range services {
@@ -1520,7 +1520,7 @@ func (s *upgradesSuite) setUpMeterStatusCreation(c *gc.C) []*Unit {
 	stateOwner, err := s.state.AddUser("bob", "notused", "notused", "bob")
 	c.Assert(err, jc.ErrorIsNil)
 	ownerTag := stateOwner.UserTag()
-	_, err = s.state.AddEnvironmentUser(ownerTag, ownerTag)
+	_, err = s.state.AddEnvironmentUser(ownerTag, ownerTag, AdminAccess)
 	c.Assert(err, jc.ErrorIsNil)
 
 	for i := 0; i < 3; i++ {
@@ -1985,3 +1985,85 @@ func (s *upgradesSuite) TestFixSequenceFields(c *gc.C) {
 		Counter: 4,
 	}})
 }
+
+func (s *upgradesSuite) TestMigrateLeaseDocs(c *gc.C) {
+	leases, closer := s.state.getRawCollection(leaseC)
+	defer closer()
+
+	expiration := time.Date(2015, 3, 4, 5, 6, 7, 0, time.UTC)
+	err := leases.Insert(
+		bson.D{
+			{"_id", "svc-leadership"},
+			{"lastupdate", expiration.Add(-time.Minute)},
+			{"token", bson.D{
+				{"namespace", "svc-leadership"},
+				{"id", "svc/0"},
+				{"expiration", expiration},
+			}},
+		},
+		// This record is already migrated and should be left
+		// untouched.
+		bson.D{
+			{"_id", "other-leadership"},
+			{"holder", "other/1"},
+			{"expiration", expiration},
+		},
+	)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = MigrateLeaseDocs(s.state)
+	c.Assert(err, jc.ErrorIsNil)
+
+	var docs []bson.M
+	err = leases.Find(nil).Sort("_id").All(&docs)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(docs, gc.HasLen, 2)
+	for _, doc := range docs {
+		c.Check(doc["token"], gc.IsNil)
+		c.Check(doc["lastupdate"], gc.IsNil)
+	}
+
+	for namespace, holder := range map[string]string{
+		"svc-leadership":   "svc/0",
+		"other-leadership": "other/1",
+	} {
+		var doc leaseDoc
+		err := leases.FindId(namespace).One(&doc)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(doc.Holder, gc.Equals, holder)
+		c.Check(doc.Expiration.Equal(expiration), jc.IsTrue)
+	}
+}
+
+func (s *upgradesSuite) TestAddEnvUUIDToLeases(c *gc.C) {
+	expiration := time.Date(2015, 3, 4, 5, 6, 7, 0, time.UTC)
+	coll, newIDs := s.checkAddEnvUUIDToCollection(c, AddEnvUUIDToLeases, leaseC,
+		bson.M{
+			"_id":        "wordpress-leadership",
+			"holder":     "wordpress/0",
+			"expiration": expiration,
+		},
+		bson.M{
+			"_id":        "mysql-leadership",
+			"holder":     "mysql/1",
+			"expiration": expiration,
+		},
+	)
+
+	var newDoc leaseDoc
+	s.FindId(c, coll, newIDs[0], &newDoc)
+	c.Assert(newDoc.Namespace, gc.Equals, "wordpress-leadership")
+	c.Assert(newDoc.Holder, gc.Equals, "wordpress/0")
+
+	s.FindId(c, coll, newIDs[1], &newDoc)
+	c.Assert(newDoc.Namespace, gc.Equals, "mysql-leadership")
+	c.Assert(newDoc.Holder, gc.Equals, "mysql/1")
+
+	doc, err := s.state.LeasePersistor.leaseDoc("wordpress-leadership")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(doc.Holder, gc.Equals, "wordpress/0")
+}
+
+func (s *upgradesSuite) TestAddEnvUUIDToLeasesIdempotent(c *gc.C) {
+	s.checkAddEnvUUIDToCollectionIdempotent(c, AddEnvUUIDToLeases, leaseC)
+}
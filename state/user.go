@@ -11,6 +11,7 @@ package state
 import (
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/juju/errors"
@@ -19,6 +20,8 @@ import (
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/utils/ssh"
 )
 
 const (
@@ -37,11 +40,15 @@ func (st *State) checkUserExists(name string) (bool, error) {
 	return count > 0, nil
 }
 
-// AddUser adds a user to the database.
+// AddUser adds a user to the database. The password must follow the
+// password policy.
 func (st *State) AddUser(name, displayName, password, creator string) (*User, error) {
 	if !names.IsValidUserName(name) {
 		return nil, errors.Errorf("invalid user name %q", name)
 	}
+	if err := st.checkPassword(password); err != nil {
+		return nil, errors.Trace(err)
+	}
 	salt, err := utils.RandomSalt()
 	if err != nil {
 		return nil, err
@@ -157,6 +164,17 @@ type userDoc struct {
 	CreatedBy    string     `bson:"createdby"`
 	DateCreated  time.Time  `bson:"datecreated"`
 	LastLogin    *time.Time `bson:"lastlogin"`
+
+	// FailedLogins holds the number of consecutive failed logins
+	// since the last successful one, and LoginBlockedUntil the time
+	// until which further logins are refused because of them.
+	FailedLogins      int        `bson:"failedlogins,omitempty"`
+	LoginBlockedUntil *time.Time `bson:"loginblockeduntil,omitempty"`
+
+	// SSHKeys holds the SSH public keys of the user, which are
+	// authorized on the machines of the environments the user can
+	// change.
+	SSHKeys []string `bson:"sshkeys,omitempty"`
 }
 
 // String returns "<name>@local" where <name> is the Name of the user.
@@ -234,8 +252,16 @@ func (u *User) UpdateLastLogin() error {
 	return nil
 }
 
-// SetPassword sets the password associated with the User.
+// SetPassword sets the password associated with the User. The
+// password must follow the password policy.
 func (u *User) SetPassword(password string) error {
+	if err := u.st.checkPassword(password); err != nil {
+		return errors.Trace(err)
+	}
+	return u.setPassword(password)
+}
+
+func (u *User) setPassword(password string) error {
 	salt, err := utils.RandomSalt()
 	if err != nil {
 		return err
@@ -243,19 +269,148 @@ func (u *User) SetPassword(password string) error {
 	return u.SetPasswordHash(utils.UserPasswordHash(password, salt), salt)
 }
 
-// SetPasswordHash stores the hash and the salt of the password.
+// SetPasswordHash stores the hash and the salt of the password. Any
+// failed logins are forgotten, so that logins are no longer refused
+// because of them.
 func (u *User) SetPasswordHash(pwHash string, pwSalt string) error {
 	ops := []txn.Op{{
 		C:      usersC,
 		Id:     u.Name(),
 		Assert: txn.DocExists,
-		Update: bson.D{{"$set", bson.D{{"passwordhash", pwHash}, {"passwordsalt", pwSalt}}}},
+		Update: bson.D{
+			{"$set", bson.D{{"passwordhash", pwHash}, {"passwordsalt", pwSalt}}},
+			{"$unset", bson.D{{"failedlogins", nil}, {"loginblockeduntil", nil}}},
+		},
 	}}
 	if err := u.st.runTransaction(ops); err != nil {
 		return errors.Annotatef(err, "cannot set password of user %q", u.Name())
 	}
 	u.doc.PasswordHash = pwHash
 	u.doc.PasswordSalt = pwSalt
+	u.doc.FailedLogins = 0
+	u.doc.LoginBlockedUntil = nil
+	return nil
+}
+
+// FailedLogins returns the number of consecutive failed logins by the
+// User since it last logged in successfully.
+func (u *User) FailedLogins() int {
+	return u.doc.FailedLogins
+}
+
+// LoginBlockedUntil returns the time in UTC until which logins by the
+// User are refused because of failed logins. The result is nil if
+// logins are not being refused.
+func (u *User) LoginBlockedUntil() *time.Time {
+	when := u.doc.LoginBlockedUntil
+	if when == nil {
+		return nil
+	}
+	result := when.UTC()
+	return &result
+}
+
+// LoginBlockedError is returned when a login by a user is refused
+// because of the user's failed logins.
+type LoginBlockedError struct {
+	User  string
+	Until time.Time
+}
+
+func (e *LoginBlockedError) Error() string {
+	return fmt.Sprintf("logins by user %q are blocked until %v", e.User, e.Until)
+}
+
+// IsLoginBlocked returns whether the cause of err is a
+// *LoginBlockedError.
+func IsLoginBlocked(err error) bool {
+	_, ok := errors.Cause(err).(*LoginBlockedError)
+	return ok
+}
+
+// checkLoginNotBlocked returns a *LoginBlockedError if logins by the
+// User are refused at the given time.
+func (u *User) checkLoginNotBlocked(now time.Time) error {
+	if until := u.LoginBlockedUntil(); until != nil && until.After(now) {
+		return &LoginBlockedError{User: u.Name(), Until: *until}
+	}
+	return nil
+}
+
+// loginNotBlockedAssert returns an assertion that logins by a user are
+// not refused at the given time.
+func loginNotBlockedAssert(now time.Time) bson.DocElem {
+	return bson.DocElem{"$or", []bson.D{
+		{{"loginblockeduntil", bson.D{{"$exists", false}}}},
+		{{"loginblockeduntil", bson.D{{"$lte", now}}}},
+	}}
+}
+
+// RecordFailedLogin records a failed login by the User. Further logins
+// are refused for the time that blockFor returns for the new number of
+// consecutive failed logins. If logins by the User are already refused,
+// possibly because of concurrent failed logins, nothing is recorded and
+// a *LoginBlockedError is returned.
+func (u *User) RecordFailedLogin(blockFor func(failedLogins int) time.Duration) error {
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := u.Refresh(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		now := time.Now().UTC()
+		if err := u.checkLoginNotBlocked(now); err != nil {
+			return nil, err
+		}
+		var assert interface{} = u.doc.FailedLogins
+		if u.doc.FailedLogins == 0 {
+			assert = bson.D{{"$in", []interface{}{0, nil}}}
+		}
+		failedLogins := u.doc.FailedLogins + 1
+		update := bson.D{{"failedlogins", failedLogins}}
+		if duration := blockFor(failedLogins); duration > 0 {
+			update = append(update, bson.DocElem{"loginblockeduntil", nowToTheSecond().Add(duration)})
+		}
+		return []txn.Op{{
+			C:      usersC,
+			Id:     u.Name(),
+			Assert: bson.D{{"failedlogins", assert}, loginNotBlockedAssert(now)},
+			Update: bson.D{{"$set", update}},
+		}}, nil
+	}
+	if err := u.st.run(buildTxn); err != nil {
+		return errors.Annotatef(err, "cannot record failed login of user %q", u.Name())
+	}
+	return u.Refresh()
+}
+
+// ResetFailedLogins forgets the failed logins of the User, after it
+// has logged in successfully. If logins by the User have been refused
+// since it was read, because of concurrent failed logins, the failed
+// logins are kept and a *LoginBlockedError is returned.
+func (u *User) ResetFailedLogins() error {
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := u.Refresh(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		now := time.Now().UTC()
+		if err := u.checkLoginNotBlocked(now); err != nil {
+			return nil, err
+		}
+		return []txn.Op{{
+			C:      usersC,
+			Id:     u.Name(),
+			Assert: bson.D{loginNotBlockedAssert(now)},
+			Update: bson.D{{"$unset", bson.D{{"failedlogins", nil}, {"loginblockeduntil", nil}}}},
+		}}, nil
+	}
+	if err := u.st.run(buildTxn); err != nil {
+		return errors.Annotatef(err, "cannot reset failed logins of user %q", u.Name())
+	}
+	u.doc.FailedLogins = 0
+	u.doc.LoginBlockedUntil = nil
 	return nil
 }
 
@@ -280,7 +435,8 @@ func (u *User) PasswordValid(password string) bool {
 		// fails because we will try again at the next request
 		logger.Debugf("User %s logged in with CompatSalt resetting password for new salt",
 			u.Name())
-		err := u.SetPassword(password)
+		// The password may predate the password policy.
+		err := u.setPassword(password)
 		if err != nil {
 			logger.Errorf("Cannot set resalted password for user %q", u.Name())
 		}
@@ -340,6 +496,171 @@ func (u *User) IsDisabled() bool {
 	return u.doc.Deactivated
 }
 
+// Remove removes the user. Like a disabled user, a removed user cannot
+// log in; in addition, its SSH keys are deleted, so they are revoked on
+// all machines for good. The user record itself is kept, so the name
+// cannot be reused.
+func (u *User) Remove() error {
+	environment, err := u.st.StateServerEnvironment()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if u.doc.Name == environment.Owner().Name() {
+		return errors.Unauthorizedf("cannot remove state server environment owner")
+	}
+	ops := []txn.Op{{
+		C:      usersC,
+		Id:     u.Name(),
+		Assert: txn.DocExists,
+		Update: bson.D{
+			{"$set", bson.D{{"deactivated", true}}},
+			{"$unset", bson.D{{"sshkeys", nil}}},
+		},
+	}}
+	if err := u.st.runTransaction(ops); err != nil {
+		if err == txn.ErrAborted {
+			err = fmt.Errorf("user no longer exists")
+		}
+		return errors.Annotatef(err, "cannot remove user %q", u.Name())
+	}
+	u.doc.Deactivated = true
+	u.doc.SSHKeys = nil
+	return nil
+}
+
+// SSHKeys returns the SSH public keys of the user.
+func (u *User) SSHKeys() []string {
+	return append([]string(nil), u.doc.SSHKeys...)
+}
+
+// AddSSHKeys adds the given SSH public keys to the user. It is an error
+// to add a key that is not valid or that the user already has.
+func (u *User) AddSSHKeys(keys ...string) error {
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := u.Refresh(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		fingerprints := make(map[string]bool)
+		for _, key := range u.doc.SSHKeys {
+			if fingerprint, _, err := ssh.KeyFingerprint(key); err == nil {
+				fingerprints[fingerprint] = true
+			}
+		}
+		newKeys := u.doc.SSHKeys
+		for _, key := range keys {
+			key = strings.TrimSpace(key)
+			fingerprint, _, err := ssh.KeyFingerprint(key)
+			if err != nil {
+				return nil, errors.NotValidf("ssh key %q", key)
+			}
+			if fingerprints[fingerprint] {
+				return nil, errors.AlreadyExistsf("ssh key with fingerprint %q", fingerprint)
+			}
+			fingerprints[fingerprint] = true
+			newKeys = append(newKeys, key)
+		}
+		return u.setSSHKeysOps(newKeys), nil
+	}
+	if err := u.st.run(buildTxn); err != nil {
+		return errors.Annotatef(err, "cannot add ssh keys to user %q", u.Name())
+	}
+	return u.Refresh()
+}
+
+// RemoveSSHKeys removes SSH public keys from the user. Each key is
+// identified by its fingerprint or comment.
+func (u *User) RemoveSSHKeys(ids ...string) error {
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := u.Refresh(); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
+		remove := make(map[string]bool)
+		for _, id := range ids {
+			remove[id] = true
+		}
+		var newKeys []string
+		for _, key := range u.doc.SSHKeys {
+			fingerprint, comment, err := ssh.KeyFingerprint(key)
+			if err == nil && (remove[fingerprint] || comment != "" && remove[comment]) {
+				delete(remove, fingerprint)
+				delete(remove, comment)
+				continue
+			}
+			newKeys = append(newKeys, key)
+		}
+		for _, id := range ids {
+			if remove[id] {
+				return nil, errors.NotFoundf("ssh key %q", id)
+			}
+		}
+		return u.setSSHKeysOps(newKeys), nil
+	}
+	if err := u.st.run(buildTxn); err != nil {
+		return errors.Annotatef(err, "cannot remove ssh keys from user %q", u.Name())
+	}
+	return u.Refresh()
+}
+
+// setSSHKeysOps returns the operations that replace the SSH keys of the
+// user, asserting that they have not changed since it was read.
+func (u *User) setSSHKeysOps(keys []string) []txn.Op {
+	var assert interface{} = u.doc.SSHKeys
+	if len(u.doc.SSHKeys) == 0 {
+		assert = bson.D{{"$exists", false}}
+	}
+	update := bson.D{{"$set", bson.D{{"sshkeys", keys}}}}
+	if len(keys) == 0 {
+		update = bson.D{{"$unset", bson.D{{"sshkeys", nil}}}}
+	}
+	return []txn.Op{{
+		C:      usersC,
+		Id:     u.Name(),
+		Assert: bson.D{{"sshkeys", assert}},
+		Update: update,
+	}}
+}
+
+// AuthorizedUserKeys returns the SSH keys of the enabled local users
+// that are allowed to change the environment. They are the keys, in
+// addition to those in the environment config, that are authorized on
+// the machines of the environment.
+func (st *State) AuthorizedUserKeys() ([]string, error) {
+	envUsers, closer := st.getCollection(envUsersC)
+	defer closer()
+
+	var docs []envUserDoc
+	if err := envUsers.Find(bson.D{{"envuuid", st.EnvironUUID()}}).All(&docs); err != nil {
+		return nil, errors.Annotate(err, "cannot read environment users")
+	}
+	var keys []string
+	for _, doc := range docs {
+		envUser := &EnvironmentUser{st: st, doc: doc}
+		if !envUser.Access().Includes(WriteAccess) {
+			continue
+		}
+		userTag := envUser.UserTag()
+		if !userTag.IsLocal() {
+			// Users from identity providers have no SSH keys.
+			continue
+		}
+		user, err := st.User(userTag)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if user.IsDisabled() {
+			continue
+		}
+		keys = append(keys, user.SSHKeys()...)
+	}
+	return keys, nil
+}
+
 // userList type is used to provide the methods for sorting.
 type userList []*User
 
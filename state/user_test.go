@@ -12,7 +12,9 @@ import (
 	gc "gopkg.in/check.v1"
 
 	"github.com/juju/juju/state"
+	statetesting "github.com/juju/juju/state/testing"
 	"github.com/juju/juju/testing/factory"
+	sshtesting "github.com/juju/juju/utils/ssh/testing"
 )
 
 type UserSuite struct {
@@ -232,3 +234,292 @@ func (s *UserSuite) TestAllUsers(c *gc.C) {
 	c.Check(users[5].Name(), gc.Equals, "fred")
 	c.Check(users[6].Name(), gc.Equals, "test-admin")
 }
+
+func (s *UserSuite) setPasswordPolicy(c *gc.C, minLength, minCharClasses int) {
+	err := s.State.UpdateEnvironConfig(map[string]interface{}{
+		"password-min-length":       minLength,
+		"password-min-char-classes": minCharClasses,
+	}, nil, nil)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *UserSuite) TestPasswordPolicy(c *gc.C) {
+	policy, err := s.State.PasswordPolicy()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(policy, gc.Equals, state.PasswordPolicy{})
+
+	s.setPasswordPolicy(c, 10, 3)
+	policy, err = s.State.PasswordPolicy()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(policy, gc.Equals, state.PasswordPolicy{MinLength: 10, MinCharClasses: 3})
+}
+
+func (s *UserSuite) TestPasswordPolicyValidate(c *gc.C) {
+	policy := state.PasswordPolicy{MinLength: 10, MinCharClasses: 3}
+	for i, test := range []struct {
+		password string
+		err      string
+	}{{
+		password: "Sh0rt!",
+		err:      "password must be at least 10 characters long",
+	}, {
+		password: "alllowercaseletters",
+		err:      "password must contain at least 3 of: lower case letters, upper case letters, digits and other characters",
+	}, {
+		password: "lower and spaces",
+		err:      "password must contain at least 3 of: lower case letters, upper case letters, digits and other characters",
+	}, {
+		password: "Mixed and spaces",
+	}, {
+		password: "lower-1234567",
+	}} {
+		c.Logf("test %d: %q", i, test.password)
+		err := policy.Validate(test.password)
+		if test.err == "" {
+			c.Check(err, jc.ErrorIsNil)
+		} else {
+			c.Check(err, gc.ErrorMatches, test.err)
+		}
+	}
+}
+
+func (s *UserSuite) TestAddUserChecksPasswordPolicy(c *gc.C) {
+	s.setPasswordPolicy(c, 10, 0)
+	_, err := s.State.AddUser("bob", "Bob", "short", "admin")
+	c.Assert(err, gc.ErrorMatches, "password must be at least 10 characters long")
+	_, err = s.State.AddUser("bob", "Bob", "long enough password", "admin")
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *UserSuite) TestSetPasswordChecksPasswordPolicy(c *gc.C) {
+	user := s.factory.MakeUser(c, &factory.UserParams{Password: "a-password"})
+	s.setPasswordPolicy(c, 0, 3)
+	err := user.SetPassword("weak-password")
+	c.Assert(err, gc.ErrorMatches, "password must contain at least 3 of: .*")
+	c.Assert(user.PasswordValid("a-password"), jc.IsTrue)
+
+	err = user.SetPassword("Strong-password")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.PasswordValid("Strong-password"), jc.IsTrue)
+}
+
+func (s *UserSuite) TestPasswordPolicyFromHostedEnvironment(c *gc.C) {
+	s.setPasswordPolicy(c, 10, 0)
+	st := s.factory.MakeEnvironment(c, nil)
+	defer st.Close()
+	policy, err := st.PasswordPolicy()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(policy.MinLength, gc.Equals, 10)
+}
+
+func (s *UserSuite) TestRecordFailedLogin(c *gc.C) {
+	user := s.factory.MakeUser(c, nil)
+	c.Assert(user.FailedLogins(), gc.Equals, 0)
+	c.Assert(user.LoginBlockedUntil(), gc.IsNil)
+
+	var failures []int
+	blockFor := func(failedLogins int) time.Duration {
+		failures = append(failures, failedLogins)
+		if failedLogins < 3 {
+			return 0
+		}
+		return time.Hour
+	}
+	err := user.RecordFailedLogin(blockFor)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.FailedLogins(), gc.Equals, 1)
+	c.Assert(user.LoginBlockedUntil(), gc.IsNil)
+
+	// A stale User still counts from the latest number of failures.
+	other, err := s.State.User(user.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+	err = user.RecordFailedLogin(blockFor)
+	c.Assert(err, jc.ErrorIsNil)
+	err = other.RecordFailedLogin(blockFor)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(other.FailedLogins(), gc.Equals, 3)
+	c.Assert(failures, jc.DeepEquals, []int{1, 2, 2, 3})
+
+	blockedUntil := other.LoginBlockedUntil()
+	c.Assert(blockedUntil, gc.NotNil)
+	c.Assert(blockedUntil.After(time.Now().Add(59*time.Minute)), jc.IsTrue)
+
+	// While logins are blocked, failed logins by a stale User are
+	// refused rather than recorded.
+	err = user.RecordFailedLogin(blockFor)
+	c.Assert(err, jc.Satisfies, state.IsLoginBlocked)
+	c.Assert(err, gc.ErrorMatches, `cannot record failed login of user ".*": logins by user ".*" are blocked until .*`)
+	c.Assert(user.FailedLogins(), gc.Equals, 3)
+	c.Assert(failures, jc.DeepEquals, []int{1, 2, 2, 3})
+}
+
+func (s *UserSuite) TestResetFailedLogins(c *gc.C) {
+	user := s.factory.MakeUser(c, nil)
+	err := user.RecordFailedLogin(func(int) time.Duration { return 0 })
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = user.ResetFailedLogins()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.FailedLogins(), gc.Equals, 0)
+	c.Assert(user.LoginBlockedUntil(), gc.IsNil)
+	err = user.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.FailedLogins(), gc.Equals, 0)
+	c.Assert(user.LoginBlockedUntil(), gc.IsNil)
+}
+
+func (s *UserSuite) TestResetFailedLoginsWhileBlocked(c *gc.C) {
+	user := s.factory.MakeUser(c, nil)
+	other, err := s.State.User(user.UserTag())
+	c.Assert(err, jc.ErrorIsNil)
+	err = other.RecordFailedLogin(func(int) time.Duration { return time.Hour })
+	c.Assert(err, jc.ErrorIsNil)
+
+	// The failed logins recorded since the stale User was read
+	// are not forgotten while logins are blocked.
+	err = user.ResetFailedLogins()
+	c.Assert(err, jc.Satisfies, state.IsLoginBlocked)
+	err = user.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.FailedLogins(), gc.Equals, 1)
+	c.Assert(user.LoginBlockedUntil(), gc.NotNil)
+}
+
+func (s *UserSuite) TestSetPasswordResetsFailedLogins(c *gc.C) {
+	user := s.factory.MakeUser(c, nil)
+	err := user.RecordFailedLogin(func(int) time.Duration { return time.Hour })
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = user.SetPassword("a-new-password")
+	c.Assert(err, jc.ErrorIsNil)
+	err = user.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.FailedLogins(), gc.Equals, 0)
+	c.Assert(user.LoginBlockedUntil(), gc.IsNil)
+}
+
+func (s *UserSuite) TestAddSSHKeys(c *gc.C) {
+	user := s.factory.MakeUser(c, nil)
+	c.Assert(user.SSHKeys(), gc.HasLen, 0)
+
+	key1 := sshtesting.ValidKeyOne.Key + " user@host"
+	key2 := sshtesting.ValidKeyTwo.Key
+	err := user.AddSSHKeys(key1, key2)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.SSHKeys(), jc.DeepEquals, []string{key1, key2})
+
+	err = user.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.SSHKeys(), jc.DeepEquals, []string{key1, key2})
+}
+
+func (s *UserSuite) TestAddSSHKeysErrors(c *gc.C) {
+	user := s.factory.MakeUser(c, nil)
+	err := user.AddSSHKeys(sshtesting.ValidKeyOne.Key)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = user.AddSSHKeys("invalid-key")
+	c.Assert(err, gc.ErrorMatches, `cannot add ssh keys to user ".*": ssh key "invalid-key" not valid`)
+	err = user.AddSSHKeys(sshtesting.ValidKeyTwo.Key, sshtesting.ValidKeyOne.Key+" again")
+	c.Assert(err, gc.ErrorMatches, `cannot add ssh keys to user ".*": ssh key with fingerprint ".*" already exists`)
+	c.Assert(user.SSHKeys(), jc.DeepEquals, []string{sshtesting.ValidKeyOne.Key})
+}
+
+func (s *UserSuite) TestRemoveSSHKeys(c *gc.C) {
+	user := s.factory.MakeUser(c, nil)
+	key1 := sshtesting.ValidKeyOne.Key + " user@host"
+	key2 := sshtesting.ValidKeyTwo.Key
+	key3 := sshtesting.ValidKeyThree.Key
+	err := user.AddSSHKeys(key1, key2, key3)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = user.RemoveSSHKeys("user@host", sshtesting.ValidKeyThree.Fingerprint)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.SSHKeys(), jc.DeepEquals, []string{key2})
+
+	err = user.RemoveSSHKeys(sshtesting.ValidKeyTwo.Fingerprint, "missing")
+	c.Assert(err, gc.ErrorMatches, `cannot remove ssh keys from user ".*": ssh key "missing" not found`)
+	c.Assert(user.SSHKeys(), jc.DeepEquals, []string{key2})
+
+	err = user.RemoveSSHKeys(sshtesting.ValidKeyTwo.Fingerprint)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.SSHKeys(), gc.HasLen, 0)
+}
+
+func (s *UserSuite) TestRemove(c *gc.C) {
+	user := s.factory.MakeUser(c, &factory.UserParams{Password: "a-password"})
+	err := user.AddSSHKeys(sshtesting.ValidKeyOne.Key)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = user.Remove()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.IsDisabled(), jc.IsTrue)
+	c.Assert(user.SSHKeys(), gc.HasLen, 0)
+	c.Assert(user.PasswordValid("a-password"), jc.IsFalse)
+
+	err = user.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(user.IsDisabled(), jc.IsTrue)
+	c.Assert(user.SSHKeys(), gc.HasLen, 0)
+}
+
+func (s *UserSuite) TestCantRemoveAdmin(c *gc.C) {
+	user, err := s.State.User(s.owner)
+	c.Assert(err, jc.ErrorIsNil)
+	err = user.Remove()
+	c.Assert(err, gc.ErrorMatches, "cannot remove state server environment owner")
+}
+
+func (s *UserSuite) TestAuthorizedUserKeys(c *gc.C) {
+	writer := s.factory.MakeUser(c, &factory.UserParams{Access: state.WriteAccess})
+	err := writer.AddSSHKeys(sshtesting.ValidKeyOne.Key)
+	c.Assert(err, jc.ErrorIsNil)
+	reader := s.factory.MakeUser(c, &factory.UserParams{Access: state.ReadAccess})
+	err = reader.AddSSHKeys(sshtesting.ValidKeyTwo.Key)
+	c.Assert(err, jc.ErrorIsNil)
+	outsider := s.factory.MakeUser(c, &factory.UserParams{NoEnvUser: true})
+	err = outsider.AddSSHKeys(sshtesting.ValidKeyThree.Key)
+	c.Assert(err, jc.ErrorIsNil)
+
+	keys, err := s.State.AuthorizedUserKeys()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(keys, jc.DeepEquals, []string{sshtesting.ValidKeyOne.Key})
+
+	err = writer.Disable()
+	c.Assert(err, jc.ErrorIsNil)
+	keys, err = s.State.AuthorizedUserKeys()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(keys, gc.HasLen, 0)
+}
+
+func (s *UserSuite) TestWatchAuthorizedKeys(c *gc.C) {
+	w := s.State.WatchAuthorizedKeys()
+	defer statetesting.AssertStop(c, w)
+	wc := statetesting.NewNotifyWatcherC(c, s.State, w)
+	wc.AssertOneChange()
+
+	user := s.factory.MakeUser(c, nil)
+	wc.AssertOneChange()
+
+	err := user.AddSSHKeys(sshtesting.ValidKeyOne.Key)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	// Writes to users that leave their keys alone are not reported.
+	err = user.UpdateLastLogin()
+	c.Assert(err, jc.ErrorIsNil)
+	err = user.RecordFailedLogin(func(int) time.Duration { return 0 })
+	c.Assert(err, jc.ErrorIsNil)
+	other := s.factory.MakeUser(c, &factory.UserParams{NoEnvUser: true})
+	err = other.UpdateLastLogin()
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+
+	err = s.State.UpdateEnvironConfig(map[string]interface{}{"authorized-keys": sshtesting.ValidKeyTwo.Key}, nil, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	err = user.Disable()
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+}
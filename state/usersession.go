@@ -0,0 +1,224 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+// userSessionDoc records an API connection of a logged in user. The
+// sessions of all the API servers are recorded, so that they can be
+// listed and closed through any one of them.
+type userSessionDoc struct {
+	DocID         string    `bson:"_id"`
+	Server        string    `bson:"server"`
+	ConnectionId  int64     `bson:"connection-id"`
+	User          string    `bson:"user"`
+	EnvUUID       string    `bson:"env-uuid"`
+	RemoteAddress string    `bson:"remote-address"`
+	LoginTime     time.Time `bson:"login-time"`
+	Closing       bool      `bson:"closing"`
+}
+
+// UserSession represents an API connection of a logged in user.
+type UserSession struct {
+	st  *State
+	doc userSessionDoc
+}
+
+// userSessionID returns the id of the document recording the
+// connection with the given id to the given API server.
+func userSessionID(server string, connectionId int64) string {
+	return fmt.Sprintf("%s:%d", server, connectionId)
+}
+
+// Server returns the tag of the API server serving the connection.
+func (s *UserSession) Server() string {
+	return s.doc.Server
+}
+
+// ConnectionId returns the id of the connection, which is unique to
+// the API server serving it.
+func (s *UserSession) ConnectionId() int64 {
+	return s.doc.ConnectionId
+}
+
+// UserTag returns the tag of the user who logged in.
+func (s *UserSession) UserTag() names.UserTag {
+	return names.NewUserTag(s.doc.User)
+}
+
+// EnvironTag returns the tag of the environment the user logged in to.
+func (s *UserSession) EnvironTag() names.EnvironTag {
+	return names.NewEnvironTag(s.doc.EnvUUID)
+}
+
+// RemoteAddress returns the address the connection was made from.
+func (s *UserSession) RemoteAddress() string {
+	return s.doc.RemoteAddress
+}
+
+// LoginTime returns the time the user logged in.
+func (s *UserSession) LoginTime() time.Time {
+	return s.doc.LoginTime
+}
+
+// Closing returns whether the connection has been asked to close.
+func (s *UserSession) Closing() bool {
+	return s.doc.Closing
+}
+
+// Remove forgets the session, once the connection has been closed.
+func (s *UserSession) Remove() error {
+	ops := []txn.Op{{
+		C:      userSessionsC,
+		Id:     s.doc.DocID,
+		Remove: true,
+	}}
+	if err := s.st.runTransaction(ops); err != nil {
+		return errors.Annotatef(err, "cannot remove session %q", s.doc.DocID)
+	}
+	return nil
+}
+
+// AddUserSession records an API connection to the given API server of
+// the given user, logged in to the state's environment.
+func (st *State) AddUserSession(server string, connectionId int64, user names.UserTag, remoteAddr string) (*UserSession, error) {
+	doc := userSessionDoc{
+		DocID:         userSessionID(server, connectionId),
+		Server:        server,
+		ConnectionId:  connectionId,
+		User:          user.Username(),
+		EnvUUID:       st.EnvironUUID(),
+		RemoteAddress: remoteAddr,
+		LoginTime:     nowToTheSecond(),
+	}
+	ops := []txn.Op{{
+		C:      userSessionsC,
+		Id:     doc.DocID,
+		Assert: txn.DocMissing,
+		Insert: &doc,
+	}}
+	err := st.runTransaction(ops)
+	if err == txn.ErrAborted {
+		err = errors.AlreadyExistsf("session %q", doc.DocID)
+	}
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot add session of %q", user.Username())
+	}
+	return &UserSession{st: st, doc: doc}, nil
+}
+
+// UserSessions returns the API connections of the given user to all
+// the API servers, oldest first.
+func (st *State) UserSessions(user names.UserTag) ([]*UserSession, error) {
+	return st.userSessions(bson.D{{"user", user.Username()}})
+}
+
+// ServerUserSessions returns the API connections of users to the given
+// API server, oldest first.
+func (st *State) ServerUserSessions(server string) ([]*UserSession, error) {
+	return st.userSessions(bson.D{{"server", server}})
+}
+
+func (st *State) userSessions(query bson.D) ([]*UserSession, error) {
+	sessions, closer := st.getCollection(userSessionsC)
+	defer closer()
+
+	var docs []userSessionDoc
+	if err := sessions.Find(query).Sort("login-time", "server", "connection-id").All(&docs); err != nil {
+		return nil, errors.Annotate(err, "cannot get user sessions")
+	}
+	result := make([]*UserSession, len(docs))
+	for i, doc := range docs {
+		result[i] = &UserSession{st: st, doc: doc}
+	}
+	return result, nil
+}
+
+// CloseUserSessions asks the API servers to close all the connections
+// of the given user, and returns how many there were. The connections
+// are closed by the API servers serving them once they notice the
+// request; see WatchUserSessions.
+func (st *State) CloseUserSessions(user names.UserTag) (int, error) {
+	sessions, err := st.UserSessions(user)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	var ops []txn.Op
+	for _, s := range sessions {
+		ops = append(ops, txn.Op{
+			C:      userSessionsC,
+			Id:     s.doc.DocID,
+			Assert: txn.DocExists,
+			Update: bson.D{{"$set", bson.D{{"closing", true}}}},
+		})
+	}
+	// Sessions which end in the meantime need not be closed, so the
+	// transaction is retried without them.
+	for len(ops) > 0 {
+		err := st.runTransaction(ops)
+		if err != txn.ErrAborted {
+			if err != nil {
+				return 0, errors.Annotatef(err, "cannot close sessions of %q", user.Username())
+			}
+			break
+		}
+		if ops, err = st.existingUserSessionOps(ops); err != nil {
+			return 0, errors.Trace(err)
+		}
+	}
+	return len(ops), nil
+}
+
+// existingUserSessionOps returns the operations on sessions which still
+// exist.
+func (st *State) existingUserSessionOps(ops []txn.Op) ([]txn.Op, error) {
+	sessions, closer := st.getCollection(userSessionsC)
+	defer closer()
+
+	var existing []txn.Op
+	for _, op := range ops {
+		count, err := sessions.FindId(op.Id).Count()
+		if err != nil {
+			return nil, errors.Annotate(err, "cannot get user sessions")
+		}
+		if count > 0 {
+			existing = append(existing, op)
+		}
+	}
+	return existing, nil
+}
+
+// RemoveServerUserSessions forgets all the sessions recorded for the
+// given API server, whose connections must all have ended; it is
+// called when an API server starts, to clean up after one which did
+// not stop cleanly.
+func (st *State) RemoveServerUserSessions(server string) error {
+	sessions, err := st.ServerUserSessions(server)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	var ops []txn.Op
+	for _, s := range sessions {
+		ops = append(ops, txn.Op{
+			C:      userSessionsC,
+			Id:     s.doc.DocID,
+			Remove: true,
+		})
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+	if err := st.runTransaction(ops); err != nil {
+		return errors.Annotatef(err, "cannot remove sessions of %q", server)
+	}
+	return nil
+}
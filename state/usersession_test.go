@@ -0,0 +1,115 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"github.com/juju/names"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	statetesting "github.com/juju/juju/state/testing"
+)
+
+type UserSessionSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&UserSessionSuite{})
+
+func (s *UserSessionSuite) TestAddUserSession(c *gc.C) {
+	bob := names.NewLocalUserTag("bob")
+	session, err := s.State.AddUserSession("machine-0", 26, bob, "10.0.0.1:1234")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(session.Server(), gc.Equals, "machine-0")
+	c.Assert(session.ConnectionId(), gc.Equals, int64(26))
+	c.Assert(session.UserTag(), gc.Equals, bob)
+	c.Assert(session.EnvironTag(), gc.Equals, s.State.EnvironTag())
+	c.Assert(session.RemoteAddress(), gc.Equals, "10.0.0.1:1234")
+	c.Assert(session.LoginTime().IsZero(), jc.IsFalse)
+	c.Assert(session.Closing(), jc.IsFalse)
+
+	sessions, err := s.State.UserSessions(bob)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(sessions, gc.HasLen, 1)
+	c.Assert(sessions[0].ConnectionId(), gc.Equals, int64(26))
+
+	_, err = s.State.AddUserSession("machine-0", 26, bob, "10.0.0.1:1234")
+	c.Assert(err, gc.ErrorMatches, `cannot add session of "bob@local": session "machine-0:26" already exists`)
+}
+
+func (s *UserSessionSuite) TestUserSessionsAcrossServers(c *gc.C) {
+	bob := names.NewLocalUserTag("bob")
+	alice := names.NewLocalUserTag("alice")
+	for i, server := range []string{"machine-0", "machine-1"} {
+		_, err := s.State.AddUserSession(server, int64(i), bob, "10.0.0.1:1234")
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	_, err := s.State.AddUserSession("machine-1", 5, alice, "10.0.0.2:1234")
+	c.Assert(err, jc.ErrorIsNil)
+
+	sessions, err := s.State.UserSessions(bob)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(sessions, gc.HasLen, 2)
+
+	sessions, err = s.State.ServerUserSessions("machine-1")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(sessions, gc.HasLen, 2)
+
+	closed, err := s.State.CloseUserSessions(bob)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(closed, gc.Equals, 2)
+	for user, closing := range map[names.UserTag]bool{bob: true, alice: false} {
+		sessions, err := s.State.UserSessions(user)
+		c.Assert(err, jc.ErrorIsNil)
+		for _, session := range sessions {
+			c.Check(session.Closing(), gc.Equals, closing)
+		}
+	}
+
+	err = s.State.RemoveServerUserSessions("machine-1")
+	c.Assert(err, jc.ErrorIsNil)
+	sessions, err = s.State.UserSessions(bob)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(sessions, gc.HasLen, 1)
+	c.Assert(sessions[0].Server(), gc.Equals, "machine-0")
+}
+
+func (s *UserSessionSuite) TestRemove(c *gc.C) {
+	bob := names.NewLocalUserTag("bob")
+	session, err := s.State.AddUserSession("machine-0", 1, bob, "10.0.0.1:1234")
+	c.Assert(err, jc.ErrorIsNil)
+	err = session.Remove()
+	c.Assert(err, jc.ErrorIsNil)
+	err = session.Remove()
+	c.Assert(err, jc.ErrorIsNil)
+
+	closed, err := s.State.CloseUserSessions(bob)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(closed, gc.Equals, 0)
+}
+
+func (s *UserSessionSuite) TestWatchUserSessions(c *gc.C) {
+	bob := names.NewLocalUserTag("bob")
+	w := s.State.WatchUserSessions("machine-0")
+	defer statetesting.AssertStop(c, w)
+	wc := statetesting.NewNotifyWatcherC(c, s.State, w)
+	wc.AssertOneChange()
+
+	session, err := s.State.AddUserSession("machine-0", 1, bob, "10.0.0.1:1234")
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	// Sessions of other API servers are ignored.
+	_, err = s.State.AddUserSession("machine-1", 1, bob, "10.0.0.1:1234")
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+
+	_, err = s.State.CloseUserSessions(bob)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	err = session.Remove()
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+}
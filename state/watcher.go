@@ -5,6 +5,7 @@ package state
 
 import (
 	"fmt"
+	"reflect"
 	"regexp"
 	"strings"
 	"time"
@@ -165,6 +166,21 @@ func (st *State) WatchServices() StringsWatcher {
 	return newLifecycleWatcher(st, servicesC, nil, nil)
 }
 
+// WatchRemoteRelations returns a StringsWatcher that notifies of changes
+// to the lifecycles of the relations of the environment with remote
+// services, identified by their keys.
+func (st *State) WatchRemoteRelations() StringsWatcher {
+	members := bson.D{{"remoteservice", bson.D{{"$exists", true}}}}
+	return newLifecycleWatcher(st, relationsC, members, nil)
+}
+
+// WatchEnvironments returns a StringsWatcher that notifies of changes
+// to the lifecycles of all the environments hosted by the state server,
+// identified by their UUIDs.
+func (st *State) WatchEnvironments() StringsWatcher {
+	return newLifecycleWatcher(st, environmentsC, nil, nil)
+}
+
 // WatchUnits returns a StringsWatcher that notifies of changes to the
 // lifecycles of units of s.
 func (s *Service) WatchUnits() StringsWatcher {
@@ -696,13 +712,13 @@ var _ Watcher = (*relationUnitsWatcher)(nil)
 // Watch returns a watcher that notifies of changes to conterpart units in
 // the relation.
 func (ru *RelationUnit) Watch() RelationUnitsWatcher {
-	return newRelationUnitsWatcher(ru)
+	return newRelationUnitsWatcher(ru.st, ru.WatchScope())
 }
 
-func newRelationUnitsWatcher(ru *RelationUnit) RelationUnitsWatcher {
+func newRelationUnitsWatcher(st *State, sw *RelationScopeWatcher) RelationUnitsWatcher {
 	w := &relationUnitsWatcher{
-		commonWatcher: commonWatcher{st: ru.st},
-		sw:            ru.WatchScope(),
+		commonWatcher: commonWatcher{st: st},
+		sw:            sw,
 		watching:      make(set.Strings),
 		updates:       make(chan watcher.Change),
 		out:           make(chan multiwatcher.RelationUnitsChange),
@@ -1328,6 +1344,68 @@ func (w *entityWatcher) loop(collName string, key interface{}) error {
 	}
 }
 
+// docWatcher notifies about changes to any of a set of documents.
+type docWatcher struct {
+	commonWatcher
+	out chan struct{}
+}
+
+var _ Watcher = (*docWatcher)(nil)
+
+// docKey identifies a document watched by a docWatcher.
+type docKey struct {
+	coll string
+	id   interface{}
+}
+
+func newDocWatcher(st *State, docKeys []docKey) NotifyWatcher {
+	w := &docWatcher{
+		commonWatcher: commonWatcher{st: st},
+		out:           make(chan struct{}),
+	}
+	go func() {
+		defer w.tomb.Done()
+		defer close(w.out)
+		w.tomb.Kill(w.loop(docKeys))
+	}()
+	return w
+}
+
+// Changes returns the event channel for the docWatcher.
+func (w *docWatcher) Changes() <-chan struct{} {
+	return w.out
+}
+
+func (w *docWatcher) loop(docKeys []docKey) error {
+	in := make(chan watcher.Change)
+	for _, key := range docKeys {
+		coll, closer := w.st.getCollection(key.coll)
+		txnRevno, err := getTxnRevno(coll, key.id)
+		closer()
+		if err != nil {
+			return err
+		}
+		w.st.watcher.Watch(coll.Name(), key.id, txnRevno, in)
+		defer w.st.watcher.Unwatch(coll.Name(), key.id, in)
+	}
+	out := w.out
+	for {
+		select {
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case <-w.st.watcher.Dead():
+			return stateWatcherDeadError(w.st.watcher.Err())
+		case ch := <-in:
+			if _, ok := collect(ch, in, w.tomb.Dying()); !ok {
+				return tomb.ErrDying
+			}
+			out = w.out
+		case out <- struct{}{}:
+			out = nil
+		}
+	}
+}
+
 // machineUnitsWatcher notifies about assignments and lifecycle changes
 // for all units of a machine.
 //
@@ -1612,6 +1690,273 @@ func (w *cleanupWatcher) loop() (err error) {
 	}
 }
 
+// authorizedKeysWatcher notifies of changes that may affect the SSH
+// keys authorized on the machines of an environment.
+type authorizedKeysWatcher struct {
+	commonWatcher
+	out chan struct{}
+}
+
+var _ Watcher = (*authorizedKeysWatcher)(nil)
+
+// WatchAuthorizedKeys returns a NotifyWatcher that notifies when the
+// SSH keys authorized on the machines of the environment may have
+// changed: that is, when the environment config, the users of the
+// environment, or the users themselves change.
+func (st *State) WatchAuthorizedKeys() NotifyWatcher {
+	w := &authorizedKeysWatcher{
+		commonWatcher: commonWatcher{st: st},
+		out:           make(chan struct{}),
+	}
+	go func() {
+		defer w.tomb.Done()
+		defer close(w.out)
+		w.tomb.Kill(w.loop())
+	}()
+	return w
+}
+
+// Changes returns the event channel for w.
+func (w *authorizedKeysWatcher) Changes() <-chan struct{} {
+	return w.out
+}
+
+func (w *authorizedKeysWatcher) loop() error {
+	settings, closer := w.st.getCollection(settingsC)
+	configKey := w.st.docID(environGlobalKey)
+	txnRevno, err := getTxnRevno(settings, configKey)
+	closer()
+	if err != nil {
+		return err
+	}
+	in := make(chan watcher.Change)
+	w.st.watcher.Watch(settingsC, configKey, txnRevno, in)
+	defer w.st.watcher.Unwatch(settingsC, configKey, in)
+
+	// Users are written to for reasons other than their keys, such
+	// as logins, so only changes to their keys are reported.
+	userCh := make(chan watcher.Change)
+	w.st.watcher.WatchCollection(usersC, userCh)
+	defer w.st.watcher.UnwatchCollection(usersC, userCh)
+	keys := make(map[interface{}]userKeys)
+	if _, err := w.updateUserKeys(keys, nil); err != nil {
+		return err
+	}
+
+	envUserPrefix := envUserID(w.st.EnvironUUID(), "")
+	isEnvUser := func(id interface{}) bool {
+		key, ok := id.(string)
+		return ok && strings.HasPrefix(key, envUserPrefix)
+	}
+	w.st.watcher.WatchCollectionWithFilter(envUsersC, in, isEnvUser)
+	defer w.st.watcher.UnwatchCollection(envUsersC, in)
+
+	out := w.out
+	for {
+		select {
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case <-w.st.watcher.Dead():
+			return stateWatcherDeadError(w.st.watcher.Err())
+		case ch := <-in:
+			if _, ok := collect(ch, in, w.tomb.Dying()); !ok {
+				return tomb.ErrDying
+			}
+			out = w.out
+		case ch := <-userCh:
+			ids, ok := collect(ch, userCh, w.tomb.Dying())
+			if !ok {
+				return tomb.ErrDying
+			}
+			changed, err := w.updateUserKeys(keys, ids)
+			if err != nil {
+				return err
+			}
+			if changed {
+				out = w.out
+			}
+		case out <- struct{}{}:
+			out = nil
+		}
+	}
+}
+
+// userKeys holds the fields of a user document that affect the SSH
+// keys authorized on machines.
+type userKeys struct {
+	SSHKeys     []string `bson:"sshkeys,omitempty"`
+	Deactivated bool     `bson:"deactivated"`
+}
+
+// updateUserKeys reads the keys of the users with the given ids, or of
+// all users if ids is nil, into keys. It returns whether the keys of
+// any of the users changed. Users that are not in keys are taken to
+// have no keys, so that adding or removing users without keys is not
+// reported.
+func (w *authorizedKeysWatcher) updateUserKeys(keys map[interface{}]userKeys, ids map[interface{}]bool) (bool, error) {
+	users, closer := w.st.getCollection(usersC)
+	defer closer()
+
+	var query interface{}
+	if ids != nil {
+		var exist []interface{}
+		for id, ok := range ids {
+			if ok {
+				exist = append(exist, id)
+			}
+		}
+		query = bson.D{{"_id", bson.D{{"$in", exist}}}}
+	}
+	iter := users.Find(query).Select(bson.D{{"sshkeys", 1}, {"deactivated", 1}}).Iter()
+	changed := false
+	found := make(map[interface{}]bool)
+	var doc struct {
+		Id   interface{} `bson:"_id"`
+		Keys userKeys    `bson:",inline"`
+	}
+	for iter.Next(&doc) {
+		found[doc.Id] = true
+		if len(doc.Keys.SSHKeys) == 0 {
+			doc.Keys.SSHKeys = nil
+		}
+		if !reflect.DeepEqual(keys[doc.Id], doc.Keys) {
+			changed = true
+		}
+		keys[doc.Id] = doc.Keys
+		doc.Keys = userKeys{}
+	}
+	if err := iter.Close(); err != nil {
+		return false, errors.Trace(err)
+	}
+	for id := range ids {
+		if found[id] {
+			continue
+		}
+		if !reflect.DeepEqual(keys[id], userKeys{}) {
+			changed = true
+		}
+		delete(keys, id)
+	}
+	return changed, nil
+}
+
+// leaseWatcher notifies of changes in the lease collection.
+type leaseWatcher struct {
+	commonWatcher
+	out chan struct{}
+}
+
+var _ Watcher = (*leaseWatcher)(nil)
+
+func newLeaseWatcher(st *State) NotifyWatcher {
+	w := &leaseWatcher{
+		commonWatcher: commonWatcher{st: st},
+		out:           make(chan struct{}),
+	}
+	go func() {
+		defer w.tomb.Done()
+		defer close(w.out)
+		w.tomb.Kill(w.loop())
+	}()
+	return w
+}
+
+// Changes returns the event channel for w.
+func (w *leaseWatcher) Changes() <-chan struct{} {
+	return w.out
+}
+
+func (w *leaseWatcher) loop() (err error) {
+	in := make(chan watcher.Change)
+
+	w.st.watcher.WatchCollection(leaseC, in)
+	defer w.st.watcher.UnwatchCollection(leaseC, in)
+
+	out := w.out
+	for {
+		select {
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case <-w.st.watcher.Dead():
+			return stateWatcherDeadError(w.st.watcher.Err())
+		case ch := <-in:
+			if _, ok := collect(ch, in, w.tomb.Dying()); !ok {
+				return tomb.ErrDying
+			}
+			out = w.out
+		case out <- struct{}{}:
+			out = nil
+		}
+	}
+}
+
+// WatchUserSessions returns a NotifyWatcher which notifies when the
+// sessions recorded for the given API server change, including when
+// they are asked to close.
+func (st *State) WatchUserSessions(server string) NotifyWatcher {
+	prefix := server + ":"
+	filter := func(id interface{}) bool {
+		if id, ok := id.(string); ok {
+			return strings.HasPrefix(id, prefix)
+		}
+		return false
+	}
+	return newUserSessionsWatcher(st, filter)
+}
+
+// userSessionsWatcher notifies of changes to the documents of the
+// user sessions collection which match its filter.
+type userSessionsWatcher struct {
+	commonWatcher
+	filter func(interface{}) bool
+	out    chan struct{}
+}
+
+var _ Watcher = (*userSessionsWatcher)(nil)
+
+func newUserSessionsWatcher(st *State, filter func(interface{}) bool) NotifyWatcher {
+	w := &userSessionsWatcher{
+		commonWatcher: commonWatcher{st: st},
+		filter:        filter,
+		out:           make(chan struct{}),
+	}
+	go func() {
+		defer w.tomb.Done()
+		defer close(w.out)
+		w.tomb.Kill(w.loop())
+	}()
+	return w
+}
+
+// Changes returns the event channel for w.
+func (w *userSessionsWatcher) Changes() <-chan struct{} {
+	return w.out
+}
+
+func (w *userSessionsWatcher) loop() (err error) {
+	in := make(chan watcher.Change)
+
+	w.st.watcher.WatchCollectionWithFilter(userSessionsC, in, w.filter)
+	defer w.st.watcher.UnwatchCollection(userSessionsC, in)
+
+	out := w.out
+	for {
+		select {
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case <-w.st.watcher.Dead():
+			return stateWatcherDeadError(w.st.watcher.Err())
+		case ch := <-in:
+			if _, ok := collect(ch, in, w.tomb.Dying()); !ok {
+				return tomb.ErrDying
+			}
+			out = w.out
+		case out <- struct{}{}:
+			out = nil
+		}
+	}
+}
+
 // actionStatusWatcher is a StringsWatcher that filters notifications
 // to Action Id's that match the ActionReceiver and ActionStatus set
 // provided.
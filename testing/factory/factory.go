@@ -41,6 +41,9 @@ type UserParams struct {
 	Creator     names.Tag
 	NoEnvUser   bool
 	Disabled    bool
+	// Access holds the user's access to the environment, if
+	// NoEnvUser is false. It defaults to state.AdminAccess.
+	Access state.Access
 }
 
 // EnvUserParams defines the parameters for creating an environment user.
@@ -48,6 +51,8 @@ type EnvUserParams struct {
 	User        string
 	DisplayName string
 	CreatedBy   names.Tag
+	// Access defaults to state.AdminAccess.
+	Access state.Access
 }
 
 // CharmParams defines the parameters for creating a charm.
@@ -147,8 +152,11 @@ func (factory *Factory) MakeUser(c *gc.C, params *UserParams) *state.User {
 	user, err := factory.st.AddUser(
 		params.Name, params.DisplayName, params.Password, creatorUserTag.Name())
 	c.Assert(err, jc.ErrorIsNil)
+	if params.Access == "" {
+		params.Access = state.AdminAccess
+	}
 	if !params.NoEnvUser {
-		_, err := factory.st.AddEnvironmentUser(user.UserTag(), names.NewUserTag(user.CreatedBy()))
+		_, err := factory.st.AddEnvironmentUser(user.UserTag(), names.NewUserTag(user.CreatedBy()), params.Access)
 		c.Assert(err, jc.ErrorIsNil)
 	}
 	if params.Disabled {
@@ -174,8 +182,11 @@ func (factory *Factory) MakeEnvUser(c *gc.C, params *EnvUserParams) *state.Envir
 		user := factory.MakeUser(c, nil)
 		params.CreatedBy = user.UserTag()
 	}
+	if params.Access == "" {
+		params.Access = state.AdminAccess
+	}
 	createdByUserTag := params.CreatedBy.(names.UserTag)
-	envUser, err := factory.st.AddEnvironmentUser(names.NewUserTag(params.User), createdByUserTag)
+	envUser, err := factory.st.AddEnvironmentUser(names.NewUserTag(params.User), createdByUserTag, params.Access)
 	c.Assert(err, jc.ErrorIsNil)
 	return envUser
 }
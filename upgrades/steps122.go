@@ -8,11 +8,14 @@ import (
 )
 
 // stateStepsFor122 returns upgrade steps form Juju 1.22 that manipulate state directly.
+// They are reversible, as restoring the state backup undoes them, except
+// the ones that also write files on disk.
 func stateStepsFor122() []Step {
 	return []Step{
 		&upgradeStep{
 			description: "prepend the environment UUID to the ID of all settings docs",
 			targets:     []Target{DatabaseMaster},
+			reversible:  true,
 			run: func(context Context) error {
 				return state.AddEnvUUIDToSettings(context.State())
 			},
@@ -20,6 +23,7 @@ func stateStepsFor122() []Step {
 		&upgradeStep{
 			description: "prepend the environment UUID to the ID of all settingsRefs docs",
 			targets:     []Target{DatabaseMaster},
+			reversible:  true,
 			run: func(context Context) error {
 				return state.AddEnvUUIDToSettingsRefs(context.State())
 			},
@@ -27,6 +31,7 @@ func stateStepsFor122() []Step {
 		&upgradeStep{
 			description: "prepend the environment UUID to the ID of all networks docs",
 			targets:     []Target{DatabaseMaster},
+			reversible:  true,
 			run: func(context Context) error {
 				return state.AddEnvUUIDToNetworks(context.State())
 			},
@@ -34,6 +39,7 @@ func stateStepsFor122() []Step {
 		&upgradeStep{
 			description: "prepend the environment UUID to the ID of all requestedNetworks docs",
 			targets:     []Target{DatabaseMaster},
+			reversible:  true,
 			run: func(context Context) error {
 				return state.AddEnvUUIDToRequestedNetworks(context.State())
 			},
@@ -41,70 +47,100 @@ func stateStepsFor122() []Step {
 		&upgradeStep{
 			description: "prepend the environment UUID to the ID of all networkInterfaces docs",
 			targets:     []Target{DatabaseMaster},
+			reversible:  true,
 			run: func(context Context) error {
 				return state.AddEnvUUIDToNetworkInterfaces(context.State())
 			},
 		}, &upgradeStep{
 			description: "prepend the environment UUID to the ID of all statuses docs",
 			targets:     []Target{DatabaseMaster},
+			reversible:  true,
 			run: func(context Context) error {
 				return state.AddEnvUUIDToStatuses(context.State())
 			},
 		}, &upgradeStep{
 			description: "prepend the environment UUID to the ID of all annotations docs",
 			targets:     []Target{DatabaseMaster},
+			reversible:  true,
 			run: func(context Context) error {
 				return state.AddEnvUUIDToAnnotations(context.State())
 			},
 		}, &upgradeStep{
 			description: "prepend the environment UUID to the ID of all constraints docs",
 			targets:     []Target{DatabaseMaster},
+			reversible:  true,
 			run: func(context Context) error {
 				return state.AddEnvUUIDToConstraints(context.State())
 			},
 		}, &upgradeStep{
 			description: "prepend the environment UUID to the ID of all meterStatus docs",
 			targets:     []Target{DatabaseMaster},
+			reversible:  true,
 			run: func(context Context) error {
 				return state.AddEnvUUIDToMeterStatus(context.State())
 			},
 		}, &upgradeStep{
 			description: "prepend the environment UUID to the ID of all openPorts docs",
 			targets:     []Target{DatabaseMaster},
+			reversible:  true,
 			run: func(context Context) error {
 				return state.AddEnvUUIDToOpenPorts(context.State())
 			},
 		}, &upgradeStep{
 			description: "fix environment UUID for minUnits docs",
 			targets:     []Target{DatabaseMaster},
+			reversible:  true,
 			run: func(context Context) error {
 				return state.FixMinUnitsEnvUUID(context.State())
 			},
 		}, &upgradeStep{
 			description: "fix sequence documents",
 			targets:     []Target{DatabaseMaster},
+			reversible:  true,
 			run: func(context Context) error {
 				return state.FixSequenceFields(context.State())
 			},
 		}, &upgradeStep{
 			description: "update system identity in state",
 			targets:     []Target{DatabaseMaster},
-			run:         ensureSystemSSHKeyRedux,
+			// It writes the system identity file and the agent
+			// config, which restoring the state backup leaves alone.
+			run: ensureSystemSSHKeyRedux,
+		},
+		&upgradeStep{
+			description: "migrate lease documents to record their holder",
+			targets:     []Target{DatabaseMaster},
+			reversible:  true,
+			run: func(context Context) error {
+				return state.MigrateLeaseDocs(context.State())
+			},
+		},
+		&upgradeStep{
+			description: "prepend the environment UUID to the ID of all lease docs",
+			targets:     []Target{DatabaseMaster},
+			reversible:  true,
+			run: func(context Context) error {
+				return state.AddEnvUUIDToLeases(context.State())
+			},
 		},
 		&upgradeStep{
 			description: "set AvailZone in instanceData",
 			targets:     []Target{DatabaseMaster},
+			reversible:  true,
 			run:         addAvaililityZoneToInstanceData,
 		},
 	}
 }
 
 // stepsFor122 returns upgrade steps form Juju 1.22 that only need the API.
+// Updating the authorized keys is reversible, as it only changes the
+// environment config.
 func stepsFor122() []Step {
 	return []Step{
 		&upgradeStep{
 			description: "update the authorized keys for the system identity",
 			targets:     []Target{DatabaseMaster},
+			reversible:  true,
 			run:         updateAuthorizedKeysForSystemIdentity,
 		},
 	}
@@ -4,9 +4,11 @@
 package upgrades_test
 
 import (
+	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
 
 	"github.com/juju/juju/testing"
+	"github.com/juju/juju/upgrades"
 	"github.com/juju/juju/version"
 )
 
@@ -33,6 +35,8 @@ func (s *steps122Suite) TestStateStepsFor122(c *gc.C) {
 		"fix environment UUID for minUnits docs",
 		"fix sequence documents",
 		"update system identity in state",
+		"migrate lease documents to record their holder",
+		"prepend the environment UUID to the ID of all lease docs",
 		"set AvailZone in instanceData",
 	}
 	assertStateSteps(c, version.MustParse("1.22.0"), expected)
@@ -44,3 +48,32 @@ func (s *steps122Suite) TestStepsFor122(c *gc.C) {
 	}
 	assertSteps(c, version.MustParse("1.22.0"), expected)
 }
+
+func (s *steps122Suite) TestStepsFor122Irreversible(c *gc.C) {
+	// The system identity step writes files on disk, so upgrades
+	// across 1.22 cannot be rolled back.
+	steps := upgrades.IrreversibleSteps(version.MustParse("1.21.0"), version.MustParse("1.22.0"))
+	c.Assert(steps, jc.DeepEquals, []string{"update system identity in state"})
+}
+
+func (s *steps122Suite) TestStepsFor122ReversibleOnlyOnDatabaseMaster(c *gc.C) {
+	// Restoring the state backup only undoes changes to the database,
+	// so a step run on other machines, which changes their files,
+	// cannot be reversible.
+	for _, ops := range [][]upgrades.Operation{
+		(*upgrades.StateUpgradeOperations)(),
+		(*upgrades.UpgradeOperations)(),
+	} {
+		for _, op := range ops {
+			if op.TargetVersion() != version.MustParse("1.22.0") {
+				continue
+			}
+			for _, step := range op.Steps() {
+				if step.Reversible() {
+					c.Check(step.Targets(), jc.DeepEquals, []upgrades.Target{upgrades.DatabaseMaster},
+						gc.Commentf("step %q", step.Description()))
+				}
+			}
+		}
+	}
+}
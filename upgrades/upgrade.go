@@ -6,6 +6,7 @@ package upgrades
 import (
 	"fmt"
 
+	"github.com/juju/errors"
 	"github.com/juju/juju/version"
 	"github.com/juju/loggo"
 )
@@ -23,6 +24,15 @@ type Step interface {
 
 	// Run executes the upgrade business logic.
 	Run(Context) error
+
+	// Reversible returns whether the changes made by Run can be undone
+	// when the upgrade is rolled back.
+	Reversible() bool
+
+	// Reverse undoes the changes made by Run that are not undone by
+	// restoring the state backup taken before the upgrade. It is only
+	// called for reversible steps and, like Run, must be idempotent.
+	Reverse(Context) error
 }
 
 // Operation defines what steps to perform to upgrade to a target version.
@@ -109,6 +119,67 @@ func PerformUpgrade(from version.Number, targets []Target, context Context) erro
 	return nil
 }
 
+// IrreversibleSteps returns the descriptions of the upgrade steps, for
+// any target, that are run when upgrading from the "from" version to
+// the "to" version and cannot be reversed. An upgrade can only be
+// rolled back if there are none.
+func IrreversibleSteps(from, to version.Number) []string {
+	var descriptions []string
+	for _, ops := range []*opsIterator{
+		newOpsIterator(from, to, stateUpgradeOperations()),
+		newOpsIterator(from, to, upgradeOperations()),
+	} {
+		for ops.Next() {
+			for _, step := range ops.Get().Steps() {
+				if !step.Reversible() {
+					descriptions = append(descriptions, step.Description())
+				}
+			}
+		}
+	}
+	return descriptions
+}
+
+// PerformRollback runs the reverse operations of the API-based upgrade
+// steps run on the "target" type of machine when upgrading to this
+// version of Juju from the "to" version, the one being rolled back to,
+// most recent first. The changes made by state-based steps are undone
+// by restoring the state backup taken before the upgrade, so they are
+// not reversed here.
+func PerformRollback(to version.Number, targets []Target, context Context) error {
+	var steps []Step
+	ops := newUpgradeOpsIterator(to)
+	for ops.Next() {
+		for _, step := range ops.Get().Steps() {
+			if !targetsMatch(targets, step.Targets()) {
+				continue
+			}
+			// Nothing is reversed unless everything can be.
+			if !step.Reversible() {
+				return &upgradeError{
+					description: step.Description(),
+					err:         errors.New("upgrade step cannot be reversed"),
+				}
+			}
+			steps = append(steps, step)
+		}
+	}
+	apiContext := context.APIContext()
+	for i := len(steps) - 1; i >= 0; i-- {
+		step := steps[i]
+		logger.Infof("reversing upgrade step: %v", step.Description())
+		if err := step.Reverse(apiContext); err != nil {
+			logger.Errorf("reversing upgrade step %q failed: %v", step.Description(), err)
+			return &upgradeError{
+				description: step.Description(),
+				err:         err,
+			}
+		}
+	}
+	logger.Infof("All upgrade steps reversed successfully")
+	return nil
+}
+
 func hasStateTarget(targets []Target) bool {
 	for _, target := range targets {
 		if target == StateServer || target == DatabaseMaster {
@@ -161,6 +232,16 @@ type upgradeStep struct {
 	description string
 	targets     []Target
 	run         func(Context) error
+
+	// reversible records that the changes made by run can be undone
+	// when the upgrade is rolled back. Steps that only change the
+	// database are reversible without a reverse function, as their
+	// changes are undone by restoring the state backup.
+	reversible bool
+
+	// reverse, if not nil, undoes the changes made by run that are
+	// not undone by restoring the state backup.
+	reverse func(Context) error
 }
 
 var _ Step = (*upgradeStep)(nil)
@@ -179,3 +260,16 @@ func (step *upgradeStep) Targets() []Target {
 func (step *upgradeStep) Run(context Context) error {
 	return step.run(context)
 }
+
+// Reversible is defined on the Step interface.
+func (step *upgradeStep) Reversible() bool {
+	return step.reversible
+}
+
+// Reverse is defined on the Step interface.
+func (step *upgradeStep) Reverse(context Context) error {
+	if step.reverse == nil {
+		return nil
+	}
+	return step.reverse(context)
+}
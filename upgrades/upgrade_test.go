@@ -109,6 +109,19 @@ func (u *mockUpgradeStep) Run(ctx upgrades.Context) error {
 	return nil
 }
 
+func (u *mockUpgradeStep) Reversible() bool {
+	return !strings.HasPrefix(u.msg, "irreversible")
+}
+
+func (u *mockUpgradeStep) Reverse(ctx upgrades.Context) error {
+	if strings.HasSuffix(u.msg, "error") {
+		return errors.New("reverse error occurred")
+	}
+	context := ctx.(*mockContext)
+	context.messages = append(context.messages, "reverse "+u.msg)
+	return nil
+}
+
 func newUpgradeStep(msg string, targets ...upgrades.Target) *mockUpgradeStep {
 	if len(targets) < 1 {
 		panic(fmt.Sprintf("step %q must have at least one target", msg))
@@ -553,6 +566,14 @@ func (s *contextStep) Run(context upgrades.Context) error {
 	return nil
 }
 
+func (s *contextStep) Reversible() bool {
+	return true
+}
+
+func (s *contextStep) Reverse(context upgrades.Context) error {
+	return s.Run(context)
+}
+
 func (s *upgradeSuite) TestStateStepsGetRestrictedContext(c *gc.C) {
 	s.PatchValue(upgrades.StateUpgradeOperations, func() []upgrades.Operation {
 		return []upgrades.Operation{
@@ -627,6 +648,91 @@ func (s *upgradeSuite) TestStateStepsNotAttemptedWhenNoStateTarget(c *gc.C) {
 	check(upgrades.HostMachine, 0)
 }
 
+func rollbackStateOperations() []upgrades.Operation {
+	return []upgrades.Operation{
+		&mockUpgradeOperation{
+			targetVersion: version.MustParse("1.21.0"),
+			steps: []upgrades.Step{
+				newUpgradeStep("irreversible state step - 1.21.0", upgrades.DatabaseMaster),
+			},
+		},
+		&mockUpgradeOperation{
+			targetVersion: version.MustParse("1.22.0"),
+			steps: []upgrades.Step{
+				newUpgradeStep("state step - 1.22.0", upgrades.DatabaseMaster),
+			},
+		},
+	}
+}
+
+func rollbackOperations() []upgrades.Operation {
+	return []upgrades.Operation{
+		&mockUpgradeOperation{
+			targetVersion: version.MustParse("1.20.0"),
+			steps: []upgrades.Step{
+				newUpgradeStep("irreversible step - 1.20.0", upgrades.AllMachines),
+			},
+		},
+		&mockUpgradeOperation{
+			targetVersion: version.MustParse("1.21.0"),
+			steps: []upgrades.Step{
+				newUpgradeStep("step 1 - 1.21.0", upgrades.AllMachines),
+				newUpgradeStep("step 2 - 1.21.0", upgrades.StateServer),
+			},
+		},
+		&mockUpgradeOperation{
+			targetVersion: version.MustParse("1.22.0"),
+			steps: []upgrades.Step{
+				newUpgradeStep("step 1 - 1.22.0", upgrades.HostMachine),
+				newUpgradeStep("step 2 - 1.22.0 error", upgrades.StateServer),
+			},
+		},
+	}
+}
+
+func (s *upgradeSuite) TestIrreversibleSteps(c *gc.C) {
+	s.PatchValue(upgrades.StateUpgradeOperations, rollbackStateOperations)
+	s.PatchValue(upgrades.UpgradeOperations, rollbackOperations)
+
+	steps := upgrades.IrreversibleSteps(version.MustParse("1.21.0"), version.MustParse("1.22-alpha1"))
+	c.Check(steps, gc.HasLen, 0)
+	steps = upgrades.IrreversibleSteps(version.MustParse("1.20.0"), version.MustParse("1.22.0"))
+	c.Check(steps, jc.DeepEquals, []string{"irreversible state step - 1.21.0"})
+	steps = upgrades.IrreversibleSteps(version.MustParse("1.19.0"), version.MustParse("1.21.0"))
+	c.Check(steps, jc.DeepEquals, []string{
+		"irreversible state step - 1.21.0",
+		"irreversible step - 1.20.0",
+	})
+}
+
+func (s *upgradeSuite) TestPerformRollback(c *gc.C) {
+	s.PatchValue(upgrades.StateUpgradeOperations, rollbackStateOperations)
+	s.PatchValue(upgrades.UpgradeOperations, rollbackOperations)
+	vers := version.Current
+	vers.Number = version.MustParse("1.22.0")
+	s.PatchValue(&version.Current, vers)
+
+	// State steps are not reversed, and steps are reversed from the
+	// most recent one.
+	ctx := &mockContext{}
+	err := upgrades.PerformRollback(version.MustParse("1.20.0"), targets(upgrades.HostMachine), ctx)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ctx.messages, jc.DeepEquals, []string{
+		"reverse step 1 - 1.22.0",
+		"reverse step 1 - 1.21.0",
+	})
+
+	ctx = &mockContext{}
+	err = upgrades.PerformRollback(version.MustParse("1.20.0"), targets(upgrades.StateServer), ctx)
+	c.Assert(err, gc.ErrorMatches, "step 2 - 1.22.0 error: reverse error occurred")
+	c.Assert(ctx.messages, gc.HasLen, 0)
+
+	ctx = &mockContext{}
+	err = upgrades.PerformRollback(version.MustParse("1.19.0"), targets(upgrades.HostMachine), ctx)
+	c.Assert(err, gc.ErrorMatches, "irreversible step - 1.20.0: upgrade step cannot be reversed")
+	c.Assert(ctx.messages, gc.HasLen, 0)
+}
+
 func (s *upgradeSuite) TestUpgradeOperationsOrdered(c *gc.C) {
 	var previous version.Number
 	for i, utv := range (*upgrades.UpgradeOperations)() {
@@ -6,6 +6,7 @@ package apiaddressupdater
 import (
 	"fmt"
 
+	"github.com/juju/errors"
 	"github.com/juju/loggo"
 
 	"github.com/juju/juju/api/watcher"
@@ -15,6 +16,11 @@ import (
 
 var logger = loggo.GetLogger("juju.worker.apiaddressupdater")
 
+// ErrRedirected is returned by the worker once it has redirected the
+// agent to the API servers of another state server, which the agent's
+// environment has moved to. The agent must restart to connect to them.
+var ErrRedirected = errors.New("environment has moved to another state server")
+
 // APIAddressUpdater is responsible for propagating API addresses.
 //
 // In practice, APIAddressUpdater is used by a machine agent to watch
@@ -28,6 +34,7 @@ type APIAddressUpdater struct {
 // which can be used to watch for API address changes.
 type APIAddresser interface {
 	APIHostPorts() ([][]network.HostPort, error)
+	CACert() (string, error)
 	WatchAPIHostPorts() (watcher.NotifyWatcher, error)
 }
 
@@ -37,6 +44,15 @@ type APIAddressSetter interface {
 	SetAPIHostPorts(servers [][]network.HostPort) error
 }
 
+// APIRedirectSetter is implemented by APIAddressSetters that can also
+// redirect the agent to the API servers of another state server, whose
+// CA certificate differs from the current one.
+type APIRedirectSetter interface {
+	APIAddressSetter
+	CurrentCACert() string
+	SetAPIRedirect(servers [][]network.HostPort, caCert string) error
+}
+
 // NewAPIAddressUpdater returns a worker.Worker that watches for changes to
 // API addresses and then sets them on the APIAddressSetter.
 func NewAPIAddressUpdater(addresser APIAddresser, setter APIAddressSetter) worker.Worker {
@@ -55,6 +71,19 @@ func (c *APIAddressUpdater) Handle() error {
 	if err != nil {
 		return fmt.Errorf("error getting addresses: %v", err)
 	}
+	if redirectSetter, ok := c.setter.(APIRedirectSetter); ok {
+		caCert, err := c.addresser.CACert()
+		if err != nil {
+			return fmt.Errorf("error getting CA certificate: %v", err)
+		}
+		if caCert != redirectSetter.CurrentCACert() {
+			if err := redirectSetter.SetAPIRedirect(addresses, caCert); err != nil {
+				return fmt.Errorf("error redirecting agent: %v", err)
+			}
+			logger.Infof("agent redirected to API addresses %q", addresses)
+			return ErrRedirected
+		}
+	}
 	if err := c.setter.SetAPIHostPorts(addresses); err != nil {
 		return fmt.Errorf("error setting addresses: %v", err)
 	}
@@ -10,6 +10,7 @@ import (
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
 
+	"github.com/juju/juju/api/watcher"
 	jujutesting "github.com/juju/juju/juju/testing"
 	"github.com/juju/juju/network"
 	"github.com/juju/juju/state"
@@ -100,3 +101,101 @@ func (s *APIAddressUpdaterSuite) TestAddressChange(c *gc.C) {
 		c.Assert(servers, gc.DeepEquals, updatedServers)
 	}
 }
+
+type apiRedirectSetter struct {
+	apiAddressSetter
+	caCert     string
+	redirected chan string
+}
+
+func (s *apiRedirectSetter) CurrentCACert() string {
+	return s.caCert
+}
+
+func (s *apiRedirectSetter) SetAPIRedirect(servers [][]network.HostPort, caCert string) error {
+	s.redirected <- caCert
+	return nil
+}
+
+func (s *APIAddressUpdaterSuite) TestNoRedirectWithSameCACert(c *gc.C) {
+	setter := &apiRedirectSetter{
+		apiAddressSetter: apiAddressSetter{servers: make(chan [][]network.HostPort, 1)},
+		caCert:           s.State.CACert(),
+		redirected:       make(chan string, 1),
+	}
+	st, _ := s.OpenAPIAsNewMachine(c, state.JobHostUnits)
+	worker := apiaddressupdater.NewAPIAddressUpdater(st.Machiner(), setter)
+	defer func() { c.Assert(worker.Wait(), gc.IsNil) }()
+	defer worker.Kill()
+
+	select {
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for SetAPIHostPorts to be called")
+	case <-setter.servers:
+	}
+	select {
+	case <-setter.redirected:
+		c.Fatalf("unexpected redirect")
+	case <-time.After(coretesting.ShortWait):
+	}
+}
+
+func (s *APIAddressUpdaterSuite) TestRedirect(c *gc.C) {
+	targetServers := [][]network.HostPort{
+		network.NewHostPorts(17070, "10.0.0.1"),
+	}
+	addresser := &fakeAddresser{
+		servers: targetServers,
+		caCert:  "target cert",
+		changes: make(chan struct{}, 1),
+	}
+	addresser.changes <- struct{}{}
+	setter := &apiRedirectSetter{
+		caCert:     "source cert",
+		redirected: make(chan string, 1),
+	}
+	worker := apiaddressupdater.NewAPIAddressUpdater(addresser, setter)
+	defer worker.Kill()
+
+	select {
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for SetAPIRedirect to be called")
+	case caCert := <-setter.redirected:
+		c.Assert(caCert, gc.Equals, "target cert")
+	}
+	c.Assert(worker.Wait(), gc.Equals, apiaddressupdater.ErrRedirected)
+}
+
+type fakeAddresser struct {
+	servers [][]network.HostPort
+	caCert  string
+	changes chan struct{}
+}
+
+func (a *fakeAddresser) APIHostPorts() ([][]network.HostPort, error) {
+	return a.servers, nil
+}
+
+func (a *fakeAddresser) CACert() (string, error) {
+	return a.caCert, nil
+}
+
+func (a *fakeAddresser) WatchAPIHostPorts() (watcher.NotifyWatcher, error) {
+	return &fakeWatcher{changes: a.changes}, nil
+}
+
+type fakeWatcher struct {
+	changes chan struct{}
+}
+
+func (w *fakeWatcher) Changes() <-chan struct{} {
+	return w.changes
+}
+
+func (w *fakeWatcher) Stop() error {
+	return nil
+}
+
+func (w *fakeWatcher) Err() error {
+	return nil
+}
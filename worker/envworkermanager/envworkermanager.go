@@ -0,0 +1,130 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package envworkermanager
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+	"github.com/juju/names"
+	"launchpad.net/tomb"
+
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/watcher"
+	"github.com/juju/juju/worker"
+)
+
+var logger = loggo.GetLogger("juju.worker.envworkermanager")
+
+// InitialState defines the State functionality used by the
+// envWorkerManager.
+type InitialState interface {
+	WatchEnvironments() state.StringsWatcher
+	GetEnvironment(names.EnvironTag) (*state.Environment, error)
+	EnvironUUID() string
+}
+
+// EnvWorkersStarter starts the workers of the environment with the
+// given UUID, returning a single worker that runs them all.
+type EnvWorkersStarter func(uuid string) (worker.Worker, error)
+
+// NewEnvWorkerManager returns a Worker which manages the workers of the
+// environments hosted by the state server, other than the state server
+// environment itself, whose workers are run by the machine agent. The
+// workers of each environment are started when it is created, restarted
+// if they fail, and stopped once the environment is dead.
+func NewEnvWorkerManager(st InitialState, startEnvWorkers EnvWorkersStarter) worker.Worker {
+	m := &envWorkerManager{
+		st:              st,
+		startEnvWorkers: startEnvWorkers,
+		runner:          worker.NewRunner(neverFatal, neverImportant),
+	}
+	go func() {
+		defer m.tomb.Done()
+		m.tomb.Kill(m.loop())
+	}()
+	return m
+}
+
+type envWorkerManager struct {
+	tomb            tomb.Tomb
+	st              InitialState
+	startEnvWorkers EnvWorkersStarter
+	runner          worker.Runner
+}
+
+// Kill satisfies the Worker interface.
+func (m *envWorkerManager) Kill() {
+	m.tomb.Kill(nil)
+}
+
+// Wait satisfies the Worker interface.
+func (m *envWorkerManager) Wait() error {
+	return m.tomb.Wait()
+}
+
+func (m *envWorkerManager) loop() error {
+	go func() {
+		// When the runner stops, make sure we stop as well.
+		m.tomb.Kill(m.runner.Wait())
+	}()
+	defer func() {
+		// When we return, make sure that we kill the runner and wait
+		// for it, so that no environment workers outlive us.
+		m.runner.Kill()
+		m.tomb.Kill(m.runner.Wait())
+	}()
+	w := m.st.WatchEnvironments()
+	defer watcher.Stop(w, &m.tomb)
+	for {
+		select {
+		case uuids, ok := <-w.Changes():
+			if !ok {
+				return watcher.EnsureErr(w)
+			}
+			for _, uuid := range uuids {
+				if err := m.envChanged(uuid); err != nil {
+					return errors.Trace(err)
+				}
+			}
+		case <-m.tomb.Dying():
+			return tomb.ErrDying
+		}
+	}
+}
+
+// envChanged starts or stops the workers of the environment with the
+// given UUID, according to its life.
+func (m *envWorkerManager) envChanged(uuid string) error {
+	if uuid == m.st.EnvironUUID() {
+		return nil
+	}
+	env, err := m.st.GetEnvironment(names.NewEnvironTag(uuid))
+	if errors.IsNotFound(err) {
+		logger.Debugf("stopping workers of removed environment %s", uuid)
+		return m.runner.StopWorker(uuid)
+	} else if err != nil {
+		return errors.Annotatef(err, "cannot load environment %s", uuid)
+	}
+	if env.Life() == state.Dead {
+		logger.Debugf("stopping workers of dead environment %s", uuid)
+		return m.runner.StopWorker(uuid)
+	}
+	// Workers keep running while the environment is dying, so that
+	// they can clean up after it.
+	logger.Debugf("starting workers of environment %s", uuid)
+	return m.runner.StartWorker(uuid, func() (worker.Worker, error) {
+		return m.startEnvWorkers(uuid)
+	})
+}
+
+// neverFatal means that the runner keeps restarting the workers of an
+// environment whatever error they fail with; one environment's failure
+// must not affect the others.
+func neverFatal(error) bool {
+	return false
+}
+
+func neverImportant(error, error) bool {
+	return false
+}
@@ -0,0 +1,129 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package envworkermanager_test
+
+import (
+	stdtesting "testing"
+	"time"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"launchpad.net/tomb"
+
+	"github.com/juju/juju/juju/testing"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/worker"
+	"github.com/juju/juju/worker/envworkermanager"
+)
+
+func TestPackage(t *stdtesting.T) {
+	coretesting.MgoTestPackage(t)
+}
+
+type suite struct {
+	testing.JujuConnSuite
+	started chan string
+	workers chan *fakeWorker
+}
+
+var _ = gc.Suite(&suite{})
+
+func (s *suite) SetUpTest(c *gc.C) {
+	s.JujuConnSuite.SetUpTest(c)
+	s.started = make(chan string, 10)
+	s.workers = make(chan *fakeWorker, 10)
+}
+
+func (s *suite) startEnvWorkers(uuid string) (worker.Worker, error) {
+	w := newFakeWorker()
+	s.started <- uuid
+	s.workers <- w
+	return w, nil
+}
+
+func (s *suite) assertStarted(c *gc.C, uuid string) *fakeWorker {
+	select {
+	case started := <-s.started:
+		c.Assert(started, gc.Equals, uuid)
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for workers of %s to start", uuid)
+	}
+	return <-s.workers
+}
+
+func (s *suite) assertNotStarted(c *gc.C) {
+	select {
+	case uuid := <-s.started:
+		c.Fatalf("unexpected start of workers for %s", uuid)
+	case <-time.After(coretesting.ShortWait):
+	}
+}
+
+func (s *suite) TestStartsWorkersForHostedEnvironments(c *gc.C) {
+	hostedState := s.Factory.MakeEnvironment(c, nil)
+	defer hostedState.Close()
+
+	m := envworkermanager.NewEnvWorkerManager(s.State, s.startEnvWorkers)
+	defer func() {
+		c.Assert(worker.Stop(m), jc.ErrorIsNil)
+	}()
+	// Workers are not started for the state server environment.
+	s.assertStarted(c, hostedState.EnvironUUID())
+	s.assertNotStarted(c)
+
+	newState := s.Factory.MakeEnvironment(c, nil)
+	defer newState.Close()
+	s.assertStarted(c, newState.EnvironUUID())
+	s.assertNotStarted(c)
+}
+
+func (s *suite) TestRestartsFailedWorkers(c *gc.C) {
+	s.PatchValue(&worker.RestartDelay, time.Millisecond)
+	hostedState := s.Factory.MakeEnvironment(c, nil)
+	defer hostedState.Close()
+
+	m := envworkermanager.NewEnvWorkerManager(s.State, s.startEnvWorkers)
+	defer func() {
+		c.Assert(worker.Stop(m), jc.ErrorIsNil)
+	}()
+	w := s.assertStarted(c, hostedState.EnvironUUID())
+	w.tomb.Kill(errors.New("boom"))
+	s.assertStarted(c, hostedState.EnvironUUID())
+}
+
+func (s *suite) TestStopsWorkersWhenKilled(c *gc.C) {
+	hostedState := s.Factory.MakeEnvironment(c, nil)
+	defer hostedState.Close()
+
+	m := envworkermanager.NewEnvWorkerManager(s.State, s.startEnvWorkers)
+	w := s.assertStarted(c, hostedState.EnvironUUID())
+	c.Assert(worker.Stop(m), jc.ErrorIsNil)
+	select {
+	case <-w.tomb.Dead():
+	default:
+		c.Fatalf("environment workers still running")
+	}
+}
+
+type fakeWorker struct {
+	tomb tomb.Tomb
+}
+
+func newFakeWorker() *fakeWorker {
+	w := &fakeWorker{}
+	go func() {
+		defer w.tomb.Done()
+		<-w.tomb.Dying()
+	}()
+	return w
+}
+
+func (w *fakeWorker) Kill() {
+	w.tomb.Kill(nil)
+}
+
+func (w *fakeWorker) Wait() error {
+	return w.tomb.Wait()
+}
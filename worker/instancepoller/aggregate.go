@@ -21,6 +21,7 @@ type instanceGetter interface {
 type aggregator struct {
 	environ instanceGetter
 	reqc    chan instanceInfoReq
+	gatherc chan int
 	tomb    tomb.Tomb
 }
 
@@ -28,6 +29,7 @@ func newAggregator(env instanceGetter) *aggregator {
 	a := &aggregator{
 		environ: env,
 		reqc:    make(chan instanceInfoReq),
+		gatherc: make(chan int),
 	}
 	go func() {
 		defer a.tomb.Done()
@@ -56,12 +58,26 @@ func (a *aggregator) instanceInfo(id instance.Id) (instanceInfo, error) {
 	return r.info, r.err
 }
 
+// gatherInstanceInfo asks for the next n instance info requests to be
+// served by a single query of the provider, which is made as soon as
+// they have all arrived, or gatherTime after the first of them,
+// without waiting on the usual rate limit.
+func (a *aggregator) gatherInstanceInfo(n int) {
+	select {
+	case a.gatherc <- n:
+	case <-a.tomb.Dying():
+	}
+}
+
 var gatherTime = 3 * time.Second
 
 func (a *aggregator) loop() error {
 	timer := time.NewTimer(0)
 	timer.Stop()
 	var reqs []instanceInfoReq
+	// gathering holds the number of requests still expected
+	// for a requested bulk query.
+	var gathering int
 	// We use a capacity of 1 so that sporadic requests will
 	// be serviced immediately without having to wait.
 	bucket := ratelimit.NewBucket(gatherTime, 1)
@@ -69,13 +85,27 @@ func (a *aggregator) loop() error {
 		select {
 		case <-a.tomb.Dying():
 			return tomb.ErrDying
-		case req := <-a.reqc:
+		case n := <-a.gatherc:
+			gathering = n
 			if len(reqs) == 0 {
+				timer.Reset(gatherTime)
+			}
+		case req := <-a.reqc:
+			if len(reqs) == 0 && gathering == 0 {
 				waitTime := bucket.Take(1)
 				timer.Reset(waitTime)
 			}
 			reqs = append(reqs, req)
+			if gathering > 0 {
+				if gathering--; gathering == 0 {
+					timer.Reset(0)
+				}
+			}
 		case <-timer.C:
+			gathering = 0
+			if len(reqs) == 0 {
+				continue
+			}
 			ids := make([]instance.Id, len(reqs))
 			for i, req := range reqs {
 				ids[i] = req.instId
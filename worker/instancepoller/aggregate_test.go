@@ -187,6 +187,56 @@ func (s *aggregateSuite) TestBatching(c *gc.C) {
 	c.Assert(testGetter.counter, gc.Equals, int32(testGetter.totalCount/testGetter.batchSize)+1)
 }
 
+func (s *aggregateSuite) TestGatherInstanceInfo(c *gc.C) {
+	// The requests must not wait for gatherTime once they have all
+	// arrived.
+	s.PatchValue(&gatherTime, testing.LongWait)
+	testGetter := new(testInstanceGetter)
+	testGetter.newTestInstance("foo", "foobar", []string{"192.168.1.1"})
+	testGetter.newTestInstance("foo2", "not foobar", []string{"192.168.1.2"})
+	testGetter.newTestInstance("foo3", "ok-ish", []string{"192.168.1.3"})
+	aggregator := newAggregator(testGetter)
+	aggregator.gatherInstanceInfo(3)
+
+	var wg sync.WaitGroup
+	checkInfo := func(id instance.Id, expectStatus string) {
+		info, err := aggregator.instanceInfo(id)
+		c.Check(err, jc.ErrorIsNil)
+		c.Check(info.status, gc.Equals, expectStatus)
+		wg.Done()
+	}
+	wg.Add(3)
+	go checkInfo("foo", "foobar")
+	go checkInfo("foo2", "not foobar")
+	go checkInfo("foo3", "ok-ish")
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(testing.LongWait / 2):
+		c.Fatalf("requests not served")
+	}
+	c.Assert(testGetter.counter, gc.Equals, int32(1))
+	c.Assert(testGetter.ids, gc.HasLen, 3)
+}
+
+func (s *aggregateSuite) TestGatherInstanceInfoWaitsAtMostGatherTime(c *gc.C) {
+	s.PatchValue(&gatherTime, 10*time.Millisecond)
+	testGetter := new(testInstanceGetter)
+	testGetter.newTestInstance("foo", "foobar", []string{"192.168.1.1"})
+	aggregator := newAggregator(testGetter)
+	aggregator.gatherInstanceInfo(3)
+
+	info, err := aggregator.instanceInfo("foo")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info.status, gc.Equals, "foobar")
+	c.Assert(testGetter.counter, gc.Equals, int32(1))
+}
+
 func (s *aggregateSuite) TestError(c *gc.C) {
 	testGetter := new(testInstanceGetter)
 	ourError := fmt.Errorf("Some error")
@@ -48,7 +48,7 @@ func (s *machineSuite) TestSetsInstanceInfoInitially(c *gc.C) {
 	s.PatchValue(&ShortPoll, coretesting.ShortWait/10)
 	s.PatchValue(&LongPoll, coretesting.ShortWait/10)
 
-	go runMachine(context, m, nil, died)
+	go runMachine(context, m, nil, nil, nil, died)
 	time.Sleep(coretesting.ShortWait)
 
 	killMachineLoop(c, m, context.dyingc, died)
@@ -56,6 +56,55 @@ func (s *machineSuite) TestSetsInstanceInfoInitially(c *gc.C) {
 	c.Assert(m.addresses, gc.DeepEquals, testAddrs)
 	c.Assert(m.setAddressCount, gc.Equals, 1)
 	c.Assert(m.instStatus, gc.Equals, "running")
+	c.Assert(m.polled.IsZero(), jc.IsFalse)
+}
+
+func (s *machineSuite) TestPollRequested(c *gc.C) {
+	s.PatchValue(&ShortPoll, time.Hour)
+	s.PatchValue(&LongPoll, time.Hour)
+	polls := make(chan instance.Id, 10)
+	context := &testMachineContext{
+		getInstanceInfo: func(id instance.Id) (instanceInfo, error) {
+			polls <- id
+			return instanceInfo{testAddrs, "running"}, nil
+		},
+		dyingc: make(chan struct{}),
+	}
+	m := &testMachine{
+		id:         "99",
+		instanceId: "i1234",
+		refresh:    func() error { return nil },
+		life:       state.Alive,
+		status:     state.StatusStarted,
+	}
+	poll := make(chan int64, 1)
+	tracker := newPollTracker()
+	died := make(chan machine)
+	go runMachine(context, m, nil, poll, tracker, died)
+
+	waitPoll := func() {
+		select {
+		case id := <-polls:
+			c.Assert(id, gc.Equals, instance.Id("i1234"))
+		case <-time.After(coretesting.LongWait):
+			c.Fatalf("instance not polled")
+		}
+	}
+	// The machine is polled when first seen, and again on request.
+	waitPoll()
+	tracker.start(1, []string{"99"})
+	poll <- 1
+	waitPoll()
+	select {
+	case result := <-tracker.done:
+		c.Assert(result, jc.DeepEquals, pollResult{generation: 1})
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("requested poll not completed")
+	}
+
+	killMachineLoop(c, m, context.dyingc, died)
+	c.Assert(context.killAllErr, gc.Equals, nil)
+	c.Assert(m.polled.IsZero(), jc.IsFalse)
 }
 
 func (s *machineSuite) TestShortPollIntervalWhenNoAddress(c *gc.C) {
@@ -137,7 +186,7 @@ func countPolls(c *gc.C, addrs []network.Address, instId, instStatus string, mac
 	}
 	died := make(chan machine)
 
-	go runMachine(context, m, nil, died)
+	go runMachine(context, m, nil, nil, nil, died)
 
 	time.Sleep(coretesting.ShortWait)
 	killMachineLoop(c, m, context.dyingc, died)
@@ -166,7 +215,7 @@ func (s *machineSuite) TestSinglePollWhenInstancInfoUnimplemented(c *gc.C) {
 	}
 	died := make(chan machine)
 
-	go runMachine(context, m, nil, died)
+	go runMachine(context, m, nil, nil, nil, died)
 
 	time.Sleep(coretesting.ShortWait)
 	killMachineLoop(c, m, context.dyingc, died)
@@ -192,7 +241,7 @@ func (*machineSuite) TestChangedRefreshes(c *gc.C) {
 	}
 	died := make(chan machine)
 	changed := make(chan struct{})
-	go runMachine(context, m, changed, died)
+	go runMachine(context, m, changed, nil, nil, died)
 	select {
 	case <-died:
 		c.Fatalf("machine died prematurely")
@@ -269,7 +318,7 @@ func testTerminatingErrors(c *gc.C, mutate func(m *testMachine, err error)) {
 	mutate(m, expectErr)
 	died := make(chan machine)
 	changed := make(chan struct{}, 1)
-	go runMachine(context, m, changed, died)
+	go runMachine(context, m, changed, nil, nil, died)
 	changed <- struct{}{}
 	select {
 	case <-died:
@@ -333,6 +382,7 @@ type testMachine struct {
 	life            state.Life
 	addresses       []network.Address
 	setAddressCount int
+	polled          time.Time
 }
 
 func (m *testMachine) Id() string {
@@ -381,6 +431,13 @@ func (m *testMachine) SetInstanceStatus(status string) error {
 	return nil
 }
 
+func (m *testMachine) SetInstancePolled(polled time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.polled = polled
+	return nil
+}
+
 func (m *testMachine) SetAddresses(addrs ...network.Address) error {
 	if m.setAddressesErr != nil {
 		return m.setAddressesErr
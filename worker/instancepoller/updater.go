@@ -5,10 +5,13 @@ package instancepoller
 
 import (
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/juju/errors"
 	"github.com/juju/loggo"
+	"github.com/juju/utils/set"
 
 	"github.com/juju/juju/instance"
 	"github.com/juju/juju/network"
@@ -38,6 +41,7 @@ type machine interface {
 	SetAddresses(...network.Address) error
 	InstanceStatus() (string, error)
 	SetInstanceStatus(status string) error
+	SetInstancePolled(polled time.Time) error
 	String() string
 	Refresh() error
 	Life() state.Life
@@ -73,14 +77,112 @@ type updaterContext interface {
 	newMachineContext() machineContext
 	getMachine(id string) (machine, error)
 	dying() <-chan struct{}
+
+	// pollRequests returns a channel that receives a value when an
+	// immediate poll of all machines may have been requested.
+	pollRequests() <-chan struct{}
+
+	// pollRequested reports whether an immediate poll of all
+	// machines has been requested since it was last called, and
+	// if so returns the generation of the request.
+	pollRequested() (int64, bool, error)
+
+	// completePoll records that the requested poll with the given
+	// generation has completed, and that the instances of the
+	// given machines could not be queried.
+	completePoll(generation int64, stale []string) error
+
+	// gatherInstanceInfo asks for the next n instance info requests
+	// to be served by a single query of the provider.
+	gatherInstanceInfo(n int)
 }
 
 type updater struct {
 	context     updaterContext
 	machines    map[string]chan struct{}
+	polls       map[string]chan int64
+	tracker     *pollTracker
 	machineDead chan machine
 }
 
+// pollResult holds the outcome of a requested poll of all machines.
+type pollResult struct {
+	generation int64
+	stale      []string
+}
+
+// pollTracker follows the machines' progress through the latest
+// requested poll, and delivers its result on done once every
+// machine has polled its instance or gone away.
+type pollTracker struct {
+	done chan pollResult
+
+	// mu protects the following fields.
+	mu         sync.Mutex
+	generation int64
+	pending    set.Strings
+	stale      []string
+}
+
+func newPollTracker() *pollTracker {
+	return &pollTracker{
+		done: make(chan pollResult, 1),
+	}
+}
+
+// start begins tracking the requested poll with the given generation
+// of the given machines' instances, abandoning any earlier poll that
+// is still in progress.
+func (t *pollTracker) start(generation int64, ids []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.generation = generation
+	t.pending = set.NewStrings(ids...)
+	t.stale = nil
+	t.checkDone()
+}
+
+// polled records whether the given machine's instance was queried
+// successfully in the requested poll with the given generation.
+func (t *pollTracker) polled(id string, generation int64, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if generation != t.generation || !t.pending.Contains(id) {
+		return
+	}
+	t.pending.Remove(id)
+	if !ok {
+		t.stale = append(t.stale, id)
+	}
+	t.checkDone()
+}
+
+// removed records that the given machine no longer needs polling.
+func (t *pollTracker) removed(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.pending.Contains(id) {
+		return
+	}
+	t.pending.Remove(id)
+	t.checkDone()
+}
+
+// checkDone delivers the result of the current poll if no machines
+// remain to be polled, replacing the result of any earlier poll that
+// has not yet been received. It must be called with t.mu held.
+func (t *pollTracker) checkDone() {
+	if !t.pending.IsEmpty() {
+		return
+	}
+	sort.Strings(t.stale)
+	select {
+	case <-t.done:
+	default:
+	}
+	t.done <- pollResult{t.generation, t.stale}
+}
+
 // watchMachinesLoop watches for changes provided by the given
 // machinesWatcher and starts machine goroutines to deal
 // with them, using the provided newMachineContext
@@ -89,6 +191,8 @@ func watchMachinesLoop(context updaterContext, w machinesWatcher) (err error) {
 	p := &updater{
 		context:     context,
 		machines:    make(map[string]chan struct{}),
+		polls:       make(map[string]chan int64),
+		tracker:     newPollTracker(),
 		machineDead: make(chan machine),
 	}
 	defer func() {
@@ -103,6 +207,9 @@ func watchMachinesLoop(context updaterContext, w machinesWatcher) (err error) {
 			delete(p.machines, (<-p.machineDead).Id())
 		}
 	}()
+	// Poll requests are only acted on once the initial set of
+	// machines is known, so that none of them are missed.
+	var pollRequests <-chan struct{}
 	for {
 		select {
 		case ids, ok := <-w.Changes():
@@ -112,14 +219,55 @@ func watchMachinesLoop(context updaterContext, w machinesWatcher) (err error) {
 			if err := p.startMachines(ids); err != nil {
 				return err
 			}
+			pollRequests = p.context.pollRequests()
+		case _, ok := <-pollRequests:
+			if !ok {
+				return errors.New("instance poll request watcher stopped")
+			}
+			generation, requested, err := p.context.pollRequested()
+			if err != nil {
+				return err
+			}
+			if requested {
+				p.pollMachines(generation)
+			}
+		case result := <-p.tracker.done:
+			if err := p.context.completePoll(result.generation, result.stale); err != nil {
+				return err
+			}
 		case m := <-p.machineDead:
 			delete(p.machines, m.Id())
+			delete(p.polls, m.Id())
+			p.tracker.removed(m.Id())
 		case <-p.context.dying():
 			return nil
 		}
 	}
 }
 
+// pollMachines asks every machine goroutine to poll its instance now
+// for the request with the given generation, with their requests
+// served by a single query of the provider.
+func (p *updater) pollMachines(generation int64) {
+	logger.Infof("polling the instances of %d machines on request", len(p.polls))
+	ids := make([]string, 0, len(p.polls))
+	for id := range p.polls {
+		ids = append(ids, id)
+	}
+	p.tracker.start(generation, ids)
+	p.context.gatherInstanceInfo(len(p.polls))
+	for _, poll := range p.polls {
+		// The channel is buffered, so a machine that has not yet
+		// seen an earlier request will poll just once, for the
+		// latest one.
+		select {
+		case <-poll:
+		default:
+		}
+		poll <- generation
+	}
+}
+
 func (p *updater) startMachines(ids []string) error {
 	for _, id := range ids {
 		if c := p.machines[id]; c == nil {
@@ -142,8 +290,10 @@ func (p *updater) startMachines(ids []string) error {
 				continue
 			}
 			c = make(chan struct{})
+			poll := make(chan int64, 1)
 			p.machines[id] = c
-			go runMachine(p.context.newMachineContext(), m, c, p.machineDead)
+			p.polls[id] = poll
+			go runMachine(p.context.newMachineContext(), m, c, poll, p.tracker, p.machineDead)
 		} else {
 			c <- struct{}{}
 		}
@@ -153,7 +303,7 @@ func (p *updater) startMachines(ids []string) error {
 
 // runMachine processes the address and status publishing for a given machine.
 // We assume that the machine is alive when this is first called.
-func runMachine(context machineContext, m machine, changed <-chan struct{}, died chan<- machine) {
+func runMachine(context machineContext, m machine, changed <-chan struct{}, poll <-chan int64, tracker *pollTracker, died chan<- machine) {
 	defer func() {
 		// We can't just send on the died channel because the
 		// central loop might be trying to write to us on the
@@ -166,20 +316,41 @@ func runMachine(context machineContext, m machine, changed <-chan struct{}, died
 			}
 		}
 	}()
-	if err := machineLoop(context, m, changed); err != nil {
+	if err := machineLoop(context, m, changed, poll, tracker); err != nil {
 		context.killAll(err)
 	}
 }
 
-func machineLoop(context machineContext, m machine, changed <-chan struct{}) error {
+func machineLoop(context machineContext, m machine, changed <-chan struct{}, poll <-chan int64, tracker *pollTracker) error {
 	// Use a short poll interval when initially waiting for
 	// a machine's address and machine agent to start, and a long one when it already
 	// has an address and the machine agent is started.
 	pollInterval := ShortPoll
 	pollInstance := true
+	// requested holds the generation of the requested poll being
+	// served, if any, and lastRecorded when a poll was last recorded
+	// on the machine.
+	var requested int64
+	var lastRecorded time.Time
 	for {
 		if pollInstance {
-			instInfo, err := pollInstanceInfo(context, m)
+			polled := time.Now()
+			instInfo, queried, err := pollInstanceInfo(context, m)
+			if queried && (requested != 0 || polled.Sub(lastRecorded) >= LongPoll) {
+				// Record the poll once its results are visible. Scheduled
+				// polls are recorded at most once every LongPoll, to save
+				// writing to the machine on every short poll.
+				if err := m.SetInstancePolled(polled); err != nil {
+					logger.Errorf("cannot set instance poll time on %q: %v", m, err)
+				} else {
+					lastRecorded = polled
+				}
+			}
+			if requested != 0 {
+				// An unprovisioned machine has no instance to be stale.
+				tracker.polled(m.Id(), requested, queried || errors.IsNotProvisioned(err))
+				requested = 0
+			}
 			if err != nil && !errors.IsNotProvisioned(err) {
 				// If the provider doesn't implement Addresses/Status now,
 				// it never will until we're upgraded, so don't bother
@@ -213,6 +384,8 @@ func machineLoop(context machineContext, m machine, changed <-chan struct{}) err
 		select {
 		case <-time.After(pollInterval):
 			pollInstance = true
+		case requested = <-poll:
+			pollInstance = true
 		case <-context.dying():
 			return nil
 		case <-changed:
@@ -228,23 +401,24 @@ func machineLoop(context machineContext, m machine, changed <-chan struct{}) err
 
 // pollInstanceInfo checks the current provider addresses and status
 // for the given machine's instance, and sets them on the machine if they've changed.
-func pollInstanceInfo(context machineContext, m machine) (instInfo instanceInfo, err error) {
+// It reports whether the provider was queried successfully.
+func pollInstanceInfo(context machineContext, m machine) (instInfo instanceInfo, queried bool, err error) {
 	instInfo = instanceInfo{}
 	instId, err := m.InstanceId()
 	// We can't ask the machine for its addresses if it isn't provisioned yet.
 	if errors.IsNotProvisioned(err) {
-		return instInfo, err
+		return instInfo, false, err
 	}
 	if err != nil {
-		return instInfo, fmt.Errorf("cannot get machine's instance id: %v", err)
+		return instInfo, false, fmt.Errorf("cannot get machine's instance id: %v", err)
 	}
 	instInfo, err = context.instanceInfo(instId)
 	if err != nil {
 		if errors.IsNotImplemented(err) {
-			return instInfo, err
+			return instInfo, false, err
 		}
 		logger.Warningf("cannot get instance info for instance %q: %v", instId, err)
-		return instInfo, nil
+		return instInfo, false, nil
 	}
 	currentInstStatus, err := m.InstanceStatus()
 	if err != nil {
@@ -266,7 +440,7 @@ func pollInstanceInfo(context machineContext, m machine) (instInfo instanceInfo,
 			logger.Errorf("cannot set addresses on %q: %v", m, err)
 		}
 	}
-	return instInfo, err
+	return instInfo, true, err
 }
 
 func addressesEqual(a0, a1 []network.Address) bool {
@@ -12,6 +12,7 @@ import (
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
 
+	"github.com/juju/juju/instance"
 	"github.com/juju/juju/state"
 	coretesting "github.com/juju/juju/testing"
 )
@@ -169,10 +170,93 @@ func (s *updaterSuite) TestManualMachinesIgnored(c *gc.C) {
 	c.Assert(watcher.stopped, jc.IsTrue)
 }
 
+func (s *updaterSuite) TestPollRequested(c *gc.C) {
+	s.PatchValue(&ShortPoll, time.Hour)
+	s.PatchValue(&LongPoll, time.Hour)
+	polls := make(chan instance.Id, 10)
+	dyingc := make(chan struct{})
+	context := &testUpdaterContext{
+		dyingc: dyingc,
+		newMachineContextFunc: func() machineContext {
+			return &testMachineContext{
+				getInstanceInfo: func(id instance.Id) (instanceInfo, error) {
+					polls <- id
+					if id == "i2" {
+						return instanceInfo{}, errors.New("no instance info")
+					}
+					return instanceInfo{testAddrs, "running"}, nil
+				},
+				dyingc: dyingc,
+			}
+		},
+		getMachineFunc: func(id string) (machine, error) {
+			return &testMachine{
+				id:         id,
+				instanceId: instance.Id("i" + id),
+				life:       state.Alive,
+				refresh:    func() error { return nil },
+			}, nil
+		},
+		pollc:      make(chan struct{}),
+		generation: 1,
+		gathered:   make(chan int, 1),
+		completed:  make(chan pollResult, 1),
+	}
+	watcher := &testMachinesWatcher{
+		changes: make(chan []string),
+	}
+	done := make(chan error)
+	go func() {
+		done <- watchMachinesLoop(context, watcher)
+	}()
+	waitPolls := func() {
+		polled := make(map[instance.Id]bool)
+		for len(polled) < 2 {
+			select {
+			case id := <-polls:
+				polled[id] = true
+			case <-time.After(coretesting.LongWait):
+				c.Fatalf("timed out waiting for instances to be polled")
+			}
+		}
+		c.Assert(polled, jc.DeepEquals, map[instance.Id]bool{"i1": true, "i2": true})
+	}
+	// Both machines are polled when first seen, and again on request.
+	watcher.changes <- []string{"1", "2"}
+	waitPolls()
+	context.pollc <- struct{}{}
+	select {
+	case n := <-context.gathered:
+		c.Assert(n, gc.Equals, 2)
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for instance info to be gathered")
+	}
+	waitPolls()
+	// The instance of machine 2 could not be queried.
+	select {
+	case result := <-context.completed:
+		c.Assert(result, jc.DeepEquals, pollResult{1, []string{"2"}})
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for poll to complete")
+	}
+
+	close(context.dyingc)
+	select {
+	case err := <-done:
+		c.Assert(err, jc.ErrorIsNil)
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for watchMachinesLoop to terminate")
+	}
+}
+
 type testUpdaterContext struct {
 	newMachineContextFunc func() machineContext
 	getMachineFunc        func(id string) (machine, error)
 	dyingc                chan struct{}
+	pollc                 chan struct{}
+	generation            int64
+	gathered              chan int
+	completed             chan pollResult
 }
 
 func (context *testUpdaterContext) newMachineContext() machineContext {
@@ -187,6 +271,27 @@ func (context *testUpdaterContext) dying() <-chan struct{} {
 	return context.dyingc
 }
 
+func (context *testUpdaterContext) pollRequests() <-chan struct{} {
+	return context.pollc
+}
+
+func (context *testUpdaterContext) pollRequested() (int64, bool, error) {
+	return context.generation, context.generation != 0, nil
+}
+
+func (context *testUpdaterContext) completePoll(generation int64, stale []string) error {
+	if context.completed != nil {
+		context.completed <- pollResult{generation, stale}
+	}
+	return nil
+}
+
+func (context *testUpdaterContext) gatherInstanceInfo(n int) {
+	if context.gathered != nil {
+		context.gathered <- n
+	}
+}
+
 type testMachinesWatcher struct {
 	stopped bool
 	changes chan []string
@@ -7,6 +7,7 @@ import (
 	"launchpad.net/tomb"
 
 	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/watcher"
 	"github.com/juju/juju/worker"
 )
 
@@ -16,6 +17,13 @@ type updaterWorker struct {
 	*aggregator
 
 	observer *worker.EnvironObserver
+
+	// env and envWatcher are used to notice requests for an
+	// immediate poll of all instances; pollHandled holds the
+	// generation of the last request acted on.
+	env         *state.Environment
+	envWatcher  state.NotifyWatcher
+	pollHandled int64
 }
 
 // NewWorker returns a worker that keeps track of
@@ -54,6 +62,14 @@ func (u *updaterWorker) loop() (err error) {
 			err = obsErr
 		}
 	}()
+	if u.env, err = u.st.Environment(); err != nil {
+		return err
+	}
+	// Requests that were not completed before we started are
+	// acted on, so that whoever made them learns of the result.
+	u.pollHandled = u.env.InstancePollCompleted()
+	u.envWatcher = u.env.Watch()
+	defer watcher.Stop(u.envWatcher, &u.tomb)
 	return watchMachinesLoop(u, u.st.WatchEnvironMachines())
 }
 
@@ -69,6 +85,26 @@ func (u *updaterWorker) dying() <-chan struct{} {
 	return u.tomb.Dying()
 }
 
+func (u *updaterWorker) pollRequests() <-chan struct{} {
+	return u.envWatcher.Changes()
+}
+
+func (u *updaterWorker) pollRequested() (int64, bool, error) {
+	if err := u.env.Refresh(); err != nil {
+		return 0, false, err
+	}
+	generation := u.env.InstancePollGeneration()
+	if generation <= u.pollHandled {
+		return 0, false, nil
+	}
+	u.pollHandled = generation
+	return generation, true, nil
+}
+
+func (u *updaterWorker) completePoll(generation int64, stale []string) error {
+	return u.env.CompleteInstancePoll(generation, stale)
+}
+
 func (u *updaterWorker) killAll(err error) {
 	u.tomb.Kill(err)
 }
@@ -137,6 +137,55 @@ func (s *workerSuite) TestWorker(c *gc.C) {
 	}
 }
 
+func (s *workerSuite) TestWorkerPollsOnRequest(c *gc.C) {
+	s.PatchValue(&ShortPoll, time.Hour)
+	s.PatchValue(&LongPoll, time.Hour)
+	s.PatchValue(&gatherTime, 10*time.Millisecond)
+	m, err := s.State.AddMachine("series", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	inst, _ := testing.AssertStartInstance(c, s.Environ, m.Id())
+	err = m.SetProvisioned(inst.Id(), "nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	s.State.StartSync()
+	w := NewWorker(s.State)
+	defer func() {
+		c.Assert(worker.Stop(w), gc.IsNil)
+	}()
+
+	waitFor := func(what string, done func() bool) {
+		for a := coretesting.LongAttempt.Start(); a.Next(); {
+			if done() {
+				return
+			}
+			s.State.StartSync()
+		}
+		c.Fatalf("timed out waiting for %s", what)
+	}
+	// The instance is polled when the machine is first seen.
+	waitFor("instance to be polled", func() bool {
+		polled, err := m.InstancePolled()
+		c.Assert(err, jc.ErrorIsNil)
+		return !polled.IsZero()
+	})
+
+	// The next poll would be an hour away, so only the
+	// request can cause it.
+	dummy.SetInstanceAddresses(inst, s.addressesForIndex(1))
+	env, err := s.State.Environment()
+	c.Assert(err, jc.ErrorIsNil)
+	generation, err := env.RequestInstancePoll()
+	c.Assert(err, jc.ErrorIsNil)
+	waitFor("requested poll to complete", func() bool {
+		err := env.Refresh()
+		c.Assert(err, jc.ErrorIsNil)
+		return env.InstancePollCompleted() >= generation
+	})
+	c.Assert(env.InstancePollStale(), gc.HasLen, 0)
+	err = m.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(m.Addresses(), jc.DeepEquals, s.addressesForIndex(1))
+}
+
 // TODO(rog)
 // - check that the environment observer is actually hooked up.
 // - check that the environment observer is stopped.
@@ -30,3 +30,10 @@ func IsRunningInLXC(machineId string) bool {
 func (nw *Networker) IsVLANModuleLoaded() bool {
 	return nw.isVLANSupportInstalled
 }
+
+var (
+	SafeApplyTimeout     = &safeApplyTimeout
+	SafeApplyRetryDelay  = &safeApplyRetryDelay
+	CheckAPIConnectivity = &checkAPIConnectivity
+	UndoCommands         = undoCommands
+)
@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"launchpad.net/tomb"
 
@@ -46,6 +47,10 @@ type Networker struct {
 	// to the network config (intrusive mode) or not (non-intrusive mode).
 	intrusiveMode bool
 
+	// safeApply determines whether changes to the network config are
+	// undone when the API server cannot be reached after making them.
+	safeApply bool
+
 	// configBasePath is the root directory where the networking
 	// config is kept (usually /etc/network).
 	configBaseDir string
@@ -73,17 +78,28 @@ type Networker struct {
 	// commands holds generated scripts (e.g. for bringing interfaces
 	// up or down, etc.) which were not executed yet.
 	commands []string
+
+	// rolledBack holds the network info whose changes were last
+	// rolled back in safe-apply mode, so that they are not applied
+	// again until the network info changes.
+	rolledBack map[string]network.Info
+
+	// apiCheck receives the result of the API connectivity check in
+	// progress in safe-apply mode, if any.
+	apiCheck chan error
 }
 
 var _ worker.Worker = (*Networker)(nil)
 
 // NewNetworker returns a Worker that handles machine networking
 // configuration. If there is no <configBasePath>/interfaces file, an
-// error is returned.
+// error is returned. In safe-apply mode, changes are undone when the
+// API server cannot be reached after making them.
 func NewNetworker(
 	st *apinetworker.State,
 	agentConfig agent.Config,
 	intrusiveMode bool,
+	safeApply bool,
 	configBaseDir string,
 ) (*Networker, error) {
 	tag, ok := agentConfig.Tag().(names.MachineTag)
@@ -96,6 +112,7 @@ func NewNetworker(
 		st:            st,
 		tag:           tag,
 		intrusiveMode: intrusiveMode,
+		safeApply:     safeApply,
 		configBaseDir: configBaseDir,
 		configFiles:   make(map[string]*configFile),
 		networkInfo:   make(map[string]network.Info),
@@ -149,6 +166,13 @@ func (nw *Networker) IntrusiveMode() bool {
 	return nw.intrusiveMode
 }
 
+// SafeApply returns whether the networker undoes changes to the
+// network config when the API server cannot be reached after making
+// them.
+func (nw *Networker) SafeApply() bool {
+	return nw.safeApply
+}
+
 // IsPrimaryInterfaceOrLoopback returns whether the given
 // interfaceName matches the primary or loopback network interface.
 func (nw *Networker) IsPrimaryInterfaceOrLoopback(interfaceName string) bool {
@@ -186,6 +210,8 @@ func (nw *Networker) loop() error {
 			if err := nw.handle(); err != nil {
 				return err
 			}
+		case <-time.After(observeInterval):
+			nw.reportInterfaces()
 		}
 	}
 }
@@ -205,6 +231,7 @@ func (nw *Networker) init() (apiwatcher.NotifyWatcher, error) {
 	if err := nw.applyAndExecute(); err != nil {
 		return nil, err
 	}
+	nw.reportInterfaces()
 	return nw.st.WatchInterfaces(nw.tag)
 }
 
@@ -225,6 +252,7 @@ func (nw *Networker) handle() error {
 	if err := nw.applyAndExecute(); err != nil {
 		return err
 	}
+	nw.reportInterfaces()
 	return nil
 }
 
@@ -359,12 +387,28 @@ func (nw *Networker) fetchNetworkInfo() error {
 // applyAndExecute updates or removes config files as needed, and runs
 // all accumulated pending commands, and if all commands succeed,
 // resets the commands slice. If the networker is running in "safe
-// mode" nothing is changed.
+// mode" nothing is changed. In safe-apply mode, when commands were
+// run and the API server cannot be reached afterwards, the config
+// files are restored and the commands undone.
 func (nw *Networker) applyAndExecute() error {
 	if !nw.IntrusiveMode() {
 		logger.Warningf("running in non-intrusive mode - no changes made")
 		return nil
 	}
+	if nw.SafeApply() && nw.isRolledBack() {
+		logger.Warningf("not applying network changes that were rolled back before")
+		nw.commands = []string{}
+		nw.configFiles = make(map[string]*configFile)
+		return nil
+	}
+	var snapshot *configSnapshot
+	if nw.SafeApply() && len(nw.commands) > 0 {
+		var err error
+		if snapshot, err = nw.snapshotConfig(); err != nil {
+			logger.Errorf("failed to record network config: %v", err)
+			return err
+		}
+	}
 
 	// Create the config subdir, if needed.
 	configSubDir := nw.ConfigSubDir()
@@ -407,7 +451,18 @@ func (nw *Networker) applyAndExecute() error {
 		if err := ExecuteCommands(nw.commands); err != nil {
 			return err
 		}
+		executed := nw.commands
 		nw.commands = []string{}
+		if snapshot != nil {
+			if err := nw.verifyAPIConnectivity(); err == tomb.ErrDying {
+				// The changes could not be verified.
+				nw.rollBack(snapshot, executed)
+				return err
+			} else if err != nil {
+				logger.Errorf("undoing network changes: %v", err)
+				nw.rollBack(snapshot, executed)
+			}
+		}
 	}
 	return nil
 }
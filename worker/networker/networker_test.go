@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/juju/errors"
 	"github.com/juju/names"
 	jc "github.com/juju/testing/checkers"
 	"github.com/juju/utils"
@@ -71,6 +72,12 @@ func (s *networkerSuite) SetUpTest(c *gc.C) {
 	s.PatchValue(&networker.Interfaces, func() ([]net.Interface, error) {
 		return s.machineInterfaces, nil
 	})
+	s.PatchValue(&networker.InterfaceAddresses, func(name string) ([]string, error) {
+		if s.interfacesWithAddress.Contains(name) && name == "eth0" {
+			return []string{"0.1.2.3/24"}, nil
+		}
+		return nil, nil
+	})
 
 	// Create the networker API facade.
 	s.apiFacade = s.apiState.Networker()
@@ -199,6 +206,129 @@ func (s *networkerSuite) TestDisabledInterfacesAreBroughtDown(c *gc.C) {
 	}
 }
 
+func (s *networkerSuite) TestReportsObservedInterfaces(c *gc.C) {
+	nw := s.newNetworker(c, false)
+	defer worker.Stop(nw)
+
+	timeout := time.After(coretesting.LongWait)
+	for {
+		interfaces, _, err := s.stateMachine.ObservedInterfaces()
+		if errors.IsNotFound(err) {
+			select {
+			case <-time.After(coretesting.ShortWait):
+				continue
+			case <-timeout:
+				c.Fatalf("network interfaces not reported")
+			}
+		}
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(interfaces, jc.DeepEquals, []state.ObservedInterface{{
+			InterfaceName: "eth0",
+			MTU:           1500,
+			IsUp:          true,
+			Addresses:     []string{"0.1.2.3/24"},
+		}, {
+			InterfaceName: "eth1",
+			MTU:           1500,
+		}, {
+			InterfaceName: "eth2",
+			MTU:           1500,
+		}})
+		return
+	}
+}
+
+func (s *networkerSuite) TestSafeApplyKeepsChangesWhenAPIReachable(c *gc.C) {
+	executed := s.recordCommands()
+	nw := s.newSafeApplyNetworker(c)
+	defer worker.Stop(nw)
+	c.Assert(nw.SafeApply(), jc.IsTrue)
+
+	s.waitForCommand(c, executed, "ifup eth2")
+	s.assertHaveConfig(c, nw, "", "eth1", "eth1.42", "eth2")
+}
+
+func (s *networkerSuite) TestSafeApplyRollsBackWhenAPIUnreachable(c *gc.C) {
+	s.PatchValue(networker.CheckAPIConnectivity, func(*apinetworker.State, names.MachineTag) error {
+		return errors.New("connection is shut down")
+	})
+	executed := s.recordCommands()
+	nw := s.newSafeApplyNetworker(c)
+	defer worker.Stop(nw)
+
+	s.waitForCommand(c, executed, "ifup eth2")
+	// The interfaces brought up are brought down again, in reverse
+	// order, and the config files written are removed.
+	s.waitForCommand(c, executed, "ifdown eth2")
+	s.waitForCommand(c, executed, "ifdown eth1.42")
+	s.waitForCommand(c, executed, "ifdown eth1")
+	s.waitForCommand(c, executed, "ifdown eth0.69")
+	s.assertNoConfig(c, nw, "", "eth1", "eth1.42", "eth2", "eth0.69")
+}
+
+func (s *networkerSuite) TestSafeApplyDoesNotReapplyRolledBackChanges(c *gc.C) {
+	s.PatchValue(networker.CheckAPIConnectivity, func(*apinetworker.State, names.MachineTag) error {
+		return errors.New("connection is shut down")
+	})
+	executed := s.recordCommands()
+	nw := s.newSafeApplyNetworker(c)
+	defer worker.Stop(nw)
+
+	s.waitForCommand(c, executed, "ifup eth2")
+	s.waitForCommand(c, executed, "ifdown eth0.69")
+
+	// The initial event of the watcher, handled after the rollback,
+	// does not bring the interfaces up again.
+	select {
+	case command := <-executed:
+		c.Fatalf("unexpected command %q", command)
+	case <-time.After(coretesting.ShortWait):
+	}
+	s.assertNoConfig(c, nw, "eth1", "eth1.42", "eth2", "eth0.69")
+}
+
+func (s *networkerSuite) TestSafeApplyStopsWhileCheckingAPI(c *gc.C) {
+	checking := make(chan struct{}, 1)
+	unblock := make(chan struct{})
+	defer close(unblock)
+	s.PatchValue(networker.CheckAPIConnectivity, func(*apinetworker.State, names.MachineTag) error {
+		checking <- struct{}{}
+		<-unblock
+		return nil
+	})
+	s.PatchValue(networker.SafeApplyTimeout, coretesting.LongWait)
+	s.upInterfaces = set.NewStrings("lo", "eth0")
+	s.interfacesWithAddress = set.NewStrings("lo", "eth0")
+	nw, err := networker.NewNetworker(s.apiFacade, agentConfig(s.stateMachine.Id()), true, true, c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+	select {
+	case <-checking:
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("API connectivity not checked")
+	}
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- worker.Stop(nw)
+	}()
+	select {
+	case err := <-stopped:
+		c.Assert(err, jc.ErrorIsNil)
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("networker not stopped while checking API connectivity")
+	}
+}
+
+func (s *networkerSuite) TestUndoCommands(c *gc.C) {
+	undo := networker.UndoCommands([]string{
+		"lsmod | grep -q 8021q || modprobe 8021q",
+		"ifup eth1",
+		"ifup eth1.42",
+		"ifdown eth2",
+	})
+	c.Assert(undo, jc.DeepEquals, []string{"ifup eth2", "ifdown eth1.42", "ifdown eth1"})
+}
+
 func (s *networkerSuite) TestIsRunningInLXC(c *gc.C) {
 	tests := []struct {
 		machineId string
@@ -414,13 +544,54 @@ func (s *networkerSuite) newCustomNetworker(
 	s.vlanModuleLoaded = false
 	configDir := c.MkDir()
 
-	nw, err := networker.NewNetworker(facade, agentConfig(machineId), intrusiveMode, configDir)
+	nw, err := networker.NewNetworker(facade, agentConfig(machineId), intrusiveMode, false, configDir)
 	c.Assert(err, jc.ErrorIsNil)
 	c.Assert(nw, gc.NotNil)
 
 	return nw, configDir
 }
 
+// newSafeApplyNetworker returns a networker in intrusive and safe-apply
+// modes for the test machine.
+func (s *networkerSuite) newSafeApplyNetworker(c *gc.C) *networker.Networker {
+	s.PatchValue(networker.SafeApplyTimeout, coretesting.ShortWait)
+	s.PatchValue(networker.SafeApplyRetryDelay, time.Millisecond)
+	s.upInterfaces = set.NewStrings("lo", "eth0")
+	s.interfacesWithAddress = set.NewStrings("lo", "eth0")
+	s.lastCommands = make(chan []string)
+	nw, err := networker.NewNetworker(s.apiFacade, agentConfig(s.stateMachine.Id()), true, true, c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+	return nw
+}
+
+// recordCommands returns a channel receiving each command executed by
+// the networker.
+func (s *networkerSuite) recordCommands() <-chan string {
+	executed := make(chan string, 100)
+	execute := networker.ExecuteCommands
+	s.PatchValue(&networker.ExecuteCommands, func(commands []string) error {
+		for _, command := range commands {
+			executed <- command
+		}
+		return execute(commands)
+	})
+	return executed
+}
+
+func (s *networkerSuite) waitForCommand(c *gc.C, executed <-chan string, expected string) {
+	timeout := time.After(coretesting.LongWait)
+	for {
+		select {
+		case command := <-executed:
+			if command == expected {
+				return
+			}
+		case <-timeout:
+			c.Fatalf("command %q not executed", expected)
+		}
+	}
+}
+
 func (s *networkerSuite) newNetworker(c *gc.C, canWriteConfig bool) *networker.Networker {
 	nw, _ := s.newCustomNetworker(c, s.apiFacade, s.stateMachine.Id(), canWriteConfig, true)
 	return nw
@@ -0,0 +1,68 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package networker
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/juju/apiserver/params"
+)
+
+// observeInterval is how often the networker reports the network
+// interfaces found on the machine, besides after changing them.
+var observeInterval = 5 * time.Minute
+
+// reportInterfaces reports the network interfaces found on the
+// machine through the API, so they can be compared with the ones
+// configured in state. Failures are only logged.
+func (nw *Networker) reportInterfaces() {
+	interfaces, err := observedInterfaces()
+	if err != nil {
+		logger.Warningf("cannot find network interfaces to report: %v", err)
+		return
+	}
+	if err := nw.st.SetObservedNetworkInterfaces(nw.tag, interfaces); err != nil {
+		logger.Warningf("cannot report network interfaces: %v", err)
+		return
+	}
+	logger.Debugf("reported %d network interfaces", len(interfaces))
+}
+
+// observedInterfaces returns the network interfaces found on the
+// machine, except loopback interfaces. VLAN interfaces are recognised
+// by their name, as set up by the networker (e.g. "eth1.42").
+func observedInterfaces() ([]params.ObservedNetworkInterface, error) {
+	interfaces, err := Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	var result []params.ObservedNetworkInterface
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := InterfaceAddresses(iface.Name)
+		if err != nil {
+			logger.Warningf("cannot get addresses of network interface %q: %v", iface.Name, err)
+		}
+		observed := params.ObservedNetworkInterface{
+			InterfaceName: iface.Name,
+			MACAddress:    iface.HardwareAddr.String(),
+			MTU:           iface.MTU,
+			IsUp:          iface.Flags&net.FlagUp != 0,
+			Addresses:     addrs,
+		}
+		if i := strings.LastIndex(iface.Name, "."); i > 0 {
+			if tag, err := strconv.Atoi(iface.Name[i+1:]); err == nil && tag > 0 {
+				observed.VLANTag = tag
+				observed.ParentInterfaceName = iface.Name[:i]
+			}
+		}
+		result = append(result, observed)
+	}
+	return result, nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package networker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/juju/names"
+	"github.com/juju/utils"
+	"launchpad.net/tomb"
+
+	apinetworker "github.com/juju/juju/api/networker"
+	"github.com/juju/juju/network"
+)
+
+// In safe-apply mode, the networker waits up to safeApplyTimeout for
+// the API server to be reachable after reconfiguring the network,
+// checking every safeApplyRetryDelay, before undoing the changes.
+var (
+	safeApplyTimeout    = 1 * time.Minute
+	safeApplyRetryDelay = 5 * time.Second
+)
+
+// checkAPIConnectivity returns an error if the API server cannot be
+// reached. It is a variable so it can be patched in tests.
+var checkAPIConnectivity = func(st *apinetworker.State, tag names.MachineTag) error {
+	_, err := st.MachineNetworkInfo(tag)
+	return err
+}
+
+// configSnapshot holds the contents of network config files before
+// they are changed, so the changes can be undone.
+type configSnapshot struct {
+	// data holds the contents of the files that existed, using the
+	// full file path as key.
+	data map[string][]byte
+
+	// missing holds the full paths of the files that did not exist.
+	missing []string
+}
+
+// snapshotConfig records the contents of all network config files the
+// networker knows about or may remove.
+func (nw *Networker) snapshotConfig() (*configSnapshot, error) {
+	var paths []string
+	for fileName := range nw.configFiles {
+		paths = append(paths, fileName)
+	}
+	files, err := ioutil.ReadDir(nw.ConfigSubDir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, info := range files {
+		fileName := filepath.Join(nw.ConfigSubDir(), info.Name())
+		if _, ok := nw.configFiles[fileName]; !ok && info.Mode().IsRegular() {
+			paths = append(paths, fileName)
+		}
+	}
+	snapshot := &configSnapshot{data: make(map[string][]byte)}
+	for _, fileName := range paths {
+		data, err := ioutil.ReadFile(fileName)
+		if os.IsNotExist(err) {
+			snapshot.missing = append(snapshot.missing, fileName)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		snapshot.data[fileName] = data
+	}
+	return snapshot, nil
+}
+
+// restore writes back the recorded network config files, and removes
+// the ones that did not exist.
+func (s *configSnapshot) restore() error {
+	for fileName, data := range s.data {
+		if err := utils.AtomicWriteFile(fileName, data, 0644); err != nil {
+			return fmt.Errorf("cannot restore %q: %v", fileName, err)
+		}
+	}
+	for _, fileName := range s.missing {
+		if err := os.Remove(fileName); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("cannot remove %q: %v", fileName, err)
+		}
+	}
+	return nil
+}
+
+// verifyAPIConnectivity waits for the API server to be reachable, and
+// returns an error if it is not before safeApplyTimeout, or if the
+// networker is stopped first.
+func (nw *Networker) verifyAPIConnectivity() error {
+	timeout := time.After(safeApplyTimeout)
+	for {
+		// The check may hang if the connection is broken, so it is
+		// run in the background. A check that hangs is waited for,
+		// rather than started again, and it finishes at the latest
+		// when the connection is closed as the worker stops.
+		if nw.apiCheck == nil {
+			result := make(chan error, 1)
+			go func() {
+				result <- checkAPIConnectivity(nw.st, nw.tag)
+			}()
+			nw.apiCheck = result
+		}
+		select {
+		case <-nw.tomb.Dying():
+			return tomb.ErrDying
+		case err := <-nw.apiCheck:
+			nw.apiCheck = nil
+			if err == nil {
+				return nil
+			}
+			logger.Warningf("cannot reach API server after reconfiguring network: %v", err)
+		case <-timeout:
+			return fmt.Errorf("API server not reachable after %v", safeApplyTimeout)
+		}
+		select {
+		case <-nw.tomb.Dying():
+			return tomb.ErrDying
+		case <-time.After(safeApplyRetryDelay):
+		case <-timeout:
+			return fmt.Errorf("API server not reachable after %v", safeApplyTimeout)
+		}
+	}
+}
+
+// rollBack restores the network config files from the snapshot and
+// undoes the given commands, which were executed after it was taken.
+// Any failure is logged, so that as much as possible is undone.
+func (nw *Networker) rollBack(snapshot *configSnapshot, executed []string) {
+	if err := snapshot.restore(); err != nil {
+		logger.Errorf("cannot restore network config: %v", err)
+	}
+	for _, command := range undoCommands(executed) {
+		if err := ExecuteCommands([]string{command}); err != nil {
+			logger.Errorf("cannot undo network change: %v", err)
+		}
+	}
+	// Read the restored files again when next handling a change.
+	nw.configFiles = make(map[string]*configFile)
+	// Do not apply the same network info again.
+	nw.rolledBack = make(map[string]network.Info)
+	for name, info := range nw.networkInfo {
+		nw.rolledBack[name] = info
+	}
+}
+
+// isRolledBack returns whether the network info fetched last is the
+// same as the one whose changes were last rolled back.
+func (nw *Networker) isRolledBack() bool {
+	return nw.rolledBack != nil && reflect.DeepEqual(nw.networkInfo, nw.rolledBack)
+}
+
+// undoCommands returns the commands that undo the given ifup and ifdown
+// commands, in reverse order. Other commands, such as loading the VLAN
+// kernel module, are not undone.
+func undoCommands(commands []string) []string {
+	var undo []string
+	for i := len(commands) - 1; i >= 0; i-- {
+		fields := strings.Fields(commands[i])
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "ifup":
+			undo = append(undo, "ifdown "+fields[1])
+		case "ifdown":
+			undo = append(undo, "ifup "+fields[1])
+		}
+	}
+	return undo
+}
@@ -16,6 +16,7 @@ var (
 	Interfaces          = interfaces
 	InterfaceIsUp       = interfaceIsUp
 	InterfaceHasAddress = interfaceHasAddress
+	InterfaceAddresses  = interfaceAddresses
 )
 
 // executeCommands execute a batch of commands one by one.
@@ -71,6 +72,24 @@ func interfaceHasAddress(interfaceName string) bool {
 	return len(addrs) != 0
 }
 
+// interfaceAddresses returns the addresses assigned to the given
+// network interface, in CIDR notation.
+func interfaceAddresses(interfaceName string) ([]string, error) {
+	iface, err := net.InterfaceByName(interfaceName)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, len(addrs))
+	for i, addr := range addrs {
+		result[i] = addr.String()
+	}
+	return result, nil
+}
+
 // interfaces returns all known network interfaces on the machine.
 func interfaces() ([]net.Interface, error) {
 	return net.Interfaces()
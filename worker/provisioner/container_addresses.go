@@ -0,0 +1,287 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package provisioner
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	"github.com/juju/utils/set"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/network"
+)
+
+// The files the host's default route and name servers are read from.
+var (
+	hostRouteFile      = "/proc/net/route"
+	hostResolvConfFile = "/etc/resolv.conf"
+)
+
+// Overridden for testing.
+var (
+	// interfaceAddrs returns the addresses of the host's network
+	// interface with the given name.
+	interfaceAddrs = func(name string) ([]net.Addr, error) {
+		iface, err := net.InterfaceByName(name)
+		if err != nil {
+			return nil, err
+		}
+		return iface.Addrs()
+	}
+
+	// runHostCommand runs the given shell command on the host.
+	runHostCommand = func(command string) error {
+		output, err := exec.Command("/bin/sh", "-c", command).CombinedOutput()
+		if err != nil {
+			return errors.Annotatef(err, "command %q failed: %s", command, output)
+		}
+		return nil
+	}
+)
+
+// prepareContainerInterfaces allocates a static address for the
+// container through the API, routes it to the container through the
+// given bridge, and returns the configuration of the container's
+// network interfaces.
+//
+// The container's address is in the subnet of its host, but the
+// container stays on the bridge: the host routes the address to the
+// bridge, and answers ARP requests for it on its primary interface,
+// and for the rest of the subnet on the bridge. The container uses
+// the host's address on the bridge as its gateway, and the name
+// servers of its host.
+func prepareContainerInterfaces(api APICalls, machineId, bridgeDevice string) ([]network.Info, error) {
+	interfaces, err := api.PrepareContainerInterfaceInfo(names.NewMachineTag(machineId))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	gateway, err := bridgeAddress(bridgeDevice)
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot find gateway of container %q", machineId)
+	}
+	dnsServers, err := hostDNSServers()
+	if err != nil {
+		logger.Warningf("cannot find name servers of container %q: %v", machineId, err)
+	}
+	for i := range interfaces {
+		interfaces[i].GatewayAddress = gateway
+		if len(interfaces[i].DNSServers) == 0 {
+			interfaces[i].DNSServers = dnsServers
+		}
+	}
+	if err := setupContainerRoutes(bridgeDevice, interfaces); err != nil {
+		removeContainerRoutes(bridgeDevice, interfaces)
+		return nil, errors.Annotatef(err, "cannot route addresses of container %q", machineId)
+	}
+	return interfaces, nil
+}
+
+// setupContainerRoutes routes the addresses of the given interfaces
+// to the bridge, with proxy ARP enabled on the bridge and the host's
+// primary interface so that the container and the rest of its subnet
+// can reach each other through the host.
+func setupContainerRoutes(bridgeDevice string, interfaces []network.Info) error {
+	primary, err := hostPrimaryInterface()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	commands := []string{
+		"sysctl -w net.ipv4.ip_forward=1",
+		fmt.Sprintf("sysctl -w net.ipv4.conf.%s.proxy_arp=1", primary),
+		fmt.Sprintf("sysctl -w net.ipv4.conf.%s.proxy_arp=1", bridgeDevice),
+	}
+	for _, iface := range interfaces {
+		commands = append(commands, fmt.Sprintf("ip route replace %s dev %s", iface.Address.Value, bridgeDevice))
+	}
+	for _, command := range commands {
+		if err := runHostCommand(command); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// removeContainerRoutes removes the routes to the addresses of the
+// given interfaces of a destroyed container. Failures are only logged,
+// as a route left behind does not stop anything else working.
+func removeContainerRoutes(bridgeDevice string, interfaces []network.Info) {
+	for _, iface := range interfaces {
+		command := fmt.Sprintf("ip route del %s dev %s", iface.Address.Value, bridgeDevice)
+		if err := runHostCommand(command); err != nil {
+			logger.Warningf("cannot remove route to container address %q: %v", iface.Address.Value, err)
+		}
+	}
+}
+
+// abandonContainerAddresses removes the routes to the addresses of the
+// given interfaces of a container that failed to start, and releases
+// the addresses allocated to it.
+func abandonContainerAddresses(releaser *addressReleaser, bridgeDevice, machineId string, interfaces []network.Info) {
+	removeContainerRoutes(bridgeDevice, interfaces)
+	releaser.releaseMachine(names.NewMachineTag(machineId))
+}
+
+// containerInterfaces returns the interfaces with static addresses of
+// the container with the given instance id, if any.
+func containerInterfaces(api APICalls, id instance.Id) []network.Info {
+	tag, ok := containerTag(id)
+	if !ok {
+		return nil
+	}
+	interfaces, err := api.GetContainerInterfaceInfo(tag)
+	if err != nil && !params.IsCodeNotImplemented(err) {
+		logger.Warningf("cannot get addresses of container %q: %v", id, err)
+	}
+	return interfaces
+}
+
+// addressReleaser releases the addresses allocated to destroyed
+// containers, remembering the containers whose addresses could not be
+// released so that they can be retried. The API server keeps the
+// addresses recorded until they are released.
+type addressReleaser struct {
+	api APICalls
+
+	// mu protects pending.
+	mu      sync.Mutex
+	pending set.Strings
+}
+
+func newAddressReleaser(api APICalls) *addressReleaser {
+	return &addressReleaser{
+		api:     api,
+		pending: make(set.Strings),
+	}
+}
+
+// release releases the addresses allocated to the containers with the
+// given instance ids, and retries any earlier releases that failed.
+func (r *addressReleaser) release(ids []instance.Id) {
+	r.mu.Lock()
+	for _, id := range ids {
+		if tag, ok := containerTag(id); ok {
+			r.pending.Add(tag.String())
+		}
+	}
+	r.mu.Unlock()
+	r.retry()
+}
+
+// releaseMachine releases the addresses allocated to the container of
+// the machine with the given tag, and retries any earlier releases that
+// failed.
+func (r *addressReleaser) releaseMachine(tag names.MachineTag) {
+	r.mu.Lock()
+	r.pending.Add(tag.String())
+	r.mu.Unlock()
+	r.retry()
+}
+
+// retry retries the releases that failed earlier.
+func (r *addressReleaser) retry() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, tagString := range r.pending.SortedValues() {
+		tag, err := names.ParseMachineTag(tagString)
+		if err != nil {
+			r.pending.Remove(tagString)
+			continue
+		}
+		err = r.api.ReleaseContainerAddresses(tag)
+		if err != nil && !params.IsCodeNotFound(err) && !params.IsCodeNotImplemented(err) {
+			logger.Warningf("will retry: %v", err)
+			continue
+		}
+		r.pending.Remove(tagString)
+	}
+}
+
+// containerTag returns the tag of the machine of the container with the
+// given instance id, which is the machine tag with an optional prefix.
+func containerTag(id instance.Id) (names.MachineTag, bool) {
+	i := strings.Index(string(id), "machine-")
+	if i < 0 {
+		return names.MachineTag{}, false
+	}
+	tag, err := names.ParseMachineTag(string(id)[i:])
+	if err != nil {
+		return names.MachineTag{}, false
+	}
+	return tag, true
+}
+
+// hostPrimaryInterface returns the name of the host's network
+// interface with the default route.
+func hostPrimaryInterface() (string, error) {
+	f, err := os.Open(hostRouteFile)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// The fields are the interface, destination and gateway,
+		// the addresses in little-endian hex, followed by others.
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		return fields[0], nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", errors.Trace(err)
+	}
+	return "", errors.New("no default route")
+}
+
+// bridgeAddress returns the IPv4 address of the host on the bridge
+// with the given name.
+func bridgeAddress(bridgeDevice string) (network.Address, error) {
+	addrs, err := interfaceAddrs(bridgeDevice)
+	if err != nil {
+		return network.Address{}, errors.Trace(err)
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if ok && ipNet.IP.To4() != nil {
+			return network.NewAddress(ipNet.IP.String(), network.ScopeCloudLocal), nil
+		}
+	}
+	return network.Address{}, errors.Errorf("bridge %q has no IPv4 address", bridgeDevice)
+}
+
+// hostDNSServers returns the addresses of the name servers of the host.
+func hostDNSServers() ([]network.Address, error) {
+	f, err := os.Open(hostResolvConfFile)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer f.Close()
+	var servers []network.Address
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+		if ip := net.ParseIP(fields[1]); ip != nil && ip.IsLoopback() {
+			// Not reachable from the container.
+			continue
+		}
+		servers = append(servers, network.NewAddress(fields[1], network.ScopeUnknown))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return servers, nil
+}
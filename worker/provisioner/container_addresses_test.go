@@ -0,0 +1,106 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package provisioner_test
+
+import (
+	"io/ioutil"
+	"net"
+	"path/filepath"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/network"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/worker/provisioner"
+)
+
+type containerAddressesSuite struct {
+	coretesting.BaseSuite
+}
+
+var _ = gc.Suite(&containerAddressesSuite{})
+
+const (
+	routeHeader       = "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n"
+	localRoute        = "eth0\t0000A8C0\t00000000\t0001\t0\t0\t0\t00FFFFFF\t0\t0\t0\n"
+	defaultRoute      = "eth0\t00000000\t0100A8C0\t0003\t0\t0\t0\t00000000\t0\t0\t0\n"
+	routeContents     = routeHeader + localRoute + defaultRoute
+	noDefaultContents = routeHeader + localRoute
+)
+
+func (s *containerAddressesSuite) writeFile(c *gc.C, contents string) string {
+	path := filepath.Join(c.MkDir(), "file")
+	err := ioutil.WriteFile(path, []byte(contents), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	return path
+}
+
+func (s *containerAddressesSuite) TestHostPrimaryInterface(c *gc.C) {
+	s.PatchValue(provisioner.HostRouteFile, s.writeFile(c, routeContents))
+	primary, err := provisioner.HostPrimaryInterface()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(primary, gc.Equals, "eth0")
+}
+
+func (s *containerAddressesSuite) TestHostPrimaryInterfaceNoDefaultRoute(c *gc.C) {
+	s.PatchValue(provisioner.HostRouteFile, s.writeFile(c, noDefaultContents))
+	_, err := provisioner.HostPrimaryInterface()
+	c.Assert(err, gc.ErrorMatches, "no default route")
+}
+
+func (s *containerAddressesSuite) TestBridgeAddress(c *gc.C) {
+	s.PatchValue(provisioner.InterfaceAddrs, func(name string) ([]net.Addr, error) {
+		c.Check(name, gc.Equals, "lxcbr0")
+		return []net.Addr{
+			&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)},
+			&net.IPNet{IP: net.ParseIP("10.0.3.1"), Mask: net.CIDRMask(24, 32)},
+		}, nil
+	})
+	addr, err := provisioner.BridgeAddress("lxcbr0")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(addr.Value, gc.Equals, "10.0.3.1")
+}
+
+func (s *containerAddressesSuite) TestBridgeAddressNoIPv4(c *gc.C) {
+	s.PatchValue(provisioner.InterfaceAddrs, func(name string) ([]net.Addr, error) {
+		return nil, nil
+	})
+	_, err := provisioner.BridgeAddress("lxcbr0")
+	c.Assert(err, gc.ErrorMatches, `bridge "lxcbr0" has no IPv4 address`)
+}
+
+func (s *containerAddressesSuite) TestHostDNSServers(c *gc.C) {
+	s.PatchValue(provisioner.HostResolvConfFile, s.writeFile(c, `# generated
+nameserver 10.0.0.2
+nameserver 127.0.1.1
+search example.com
+nameserver 8.8.8.8
+`))
+	servers, err := provisioner.HostDNSServers()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(servers, jc.DeepEquals, network.NewAddresses("10.0.0.2", "8.8.8.8"))
+}
+
+func (s *containerAddressesSuite) TestContainerTag(c *gc.C) {
+	for i, test := range []struct {
+		id  instance.Id
+		tag string
+	}{
+		{"juju-machine-1-lxc-0", "machine-1-lxc-0"},
+		{"machine-2-kvm-3", "machine-2-kvm-3"},
+		{"user-local-machine-0-lxc-4", "machine-0-lxc-4"},
+		{"i-deadbeef", ""},
+	} {
+		c.Logf("test %d: %s", i, test.id)
+		tag, ok := provisioner.ContainerTag(test.id)
+		if test.tag == "" {
+			c.Check(ok, jc.IsFalse)
+			continue
+		}
+		c.Check(ok, jc.IsTrue)
+		c.Check(tag.String(), gc.Equals, test.tag)
+	}
+}
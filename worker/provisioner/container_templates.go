@@ -0,0 +1,183 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package provisioner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/utils/fslock"
+
+	"github.com/juju/juju/agent"
+	apiprovisioner "github.com/juju/juju/api/provisioner"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/container"
+	"github.com/juju/juju/container/lxc"
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/worker"
+)
+
+// templatePollPeriod is how often the container templates recorded for
+// the machine are checked for requests and expiry.
+var templatePollPeriod = 5 * time.Minute
+
+// ContainerTemplatesParams are used to create a container templates
+// worker.
+type ContainerTemplatesParams struct {
+	ImageURLGetter container.ImageURLGetter
+	Machine        *apiprovisioner.Machine
+	Provisioner    *apiprovisioner.State
+	Config         agent.Config
+	InitLock       *fslock.Lock
+}
+
+// NewContainerTemplatesWorker returns a worker that builds the LXC
+// templates requested for the machine ahead of its first containers,
+// rebuilds them once they are older than the environment's
+// lxc-template-max-age, and records them in state.
+func NewContainerTemplatesWorker(params ContainerTemplatesParams) worker.Worker {
+	ct := &containerTemplates{params: params}
+	return worker.NewPeriodicWorker(ct.update, templatePollPeriod)
+}
+
+type containerTemplates struct {
+	params ContainerTemplatesParams
+
+	// initialised records whether the host has been set up to run
+	// lxc containers.
+	initialised bool
+}
+
+// newTemplateBuilder returns the builder used for the machine's LXC
+// templates. It is a variable so that tests can replace it.
+var newTemplateBuilder = func(
+	provisioner *apiprovisioner.State,
+	config agent.Config,
+	imageURLGetter container.ImageURLGetter,
+) (lxc.TemplateBuilder, error) {
+	managerConfig, err := containerManagerConfig(instance.LXC, provisioner, config)
+	if err != nil {
+		return nil, err
+	}
+	manager, err := lxc.NewContainerManager(managerConfig, imageURLGetter)
+	if err != nil {
+		return nil, err
+	}
+	return manager.(lxc.TemplateBuilder), nil
+}
+
+// templatePackages is a variable so that tests can replace it.
+var templatePackages = lxc.TemplatePackages
+
+func (ct *containerTemplates) update(stop <-chan struct{}) error {
+	machine := ct.params.Machine
+	templates, err := machine.ContainerTemplates()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := ct.recordExistingTemplate(templates); err != nil {
+		return errors.Trace(err)
+	}
+	config, err := ct.params.Provisioner.ContainerConfig()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	now := time.Now()
+	for _, template := range templates {
+		if !templateNeedsBuild(template, config.LXCTemplateMaxAge, now) {
+			continue
+		}
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		logger.Infof("building %s template for series %q", template.Kind, template.Series)
+		packages, err := ct.build(template.Series, config)
+		if err != nil {
+			logger.Errorf("cannot build %s template for series %q: %v", template.Kind, template.Series, err)
+			err = machine.SetContainerTemplateFailed(instance.LXC, template.Series, err)
+		} else {
+			err = machine.SetContainerTemplateBuilt(instance.LXC, template.Series, packages)
+		}
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// recordExistingTemplate records the LXC template for the machine's
+// own series if it was built for a container before anything asked for
+// it, so that the cache shows up in status.
+func (ct *containerTemplates) recordExistingTemplate(templates []params.ContainerTemplate) error {
+	series, err := ct.params.Machine.Series()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, template := range templates {
+		if template.Kind == string(instance.LXC) && template.Series == series {
+			return nil
+		}
+	}
+	packages, err := templatePackages(series)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	return ct.params.Machine.SetContainerTemplateBuilt(instance.LXC, series, packages)
+}
+
+// templateNeedsBuild reports whether the template has been requested,
+// or was built more than maxAge before now. A zero maxAge means that
+// templates never expire.
+func templateNeedsBuild(template params.ContainerTemplate, maxAge time.Duration, now time.Time) bool {
+	if template.Kind != string(instance.LXC) {
+		return false
+	}
+	if template.PrimeRequested != nil {
+		return true
+	}
+	return template.Built != nil && maxAge > 0 && now.Sub(*template.Built) > maxAge
+}
+
+// build builds the LXC template for the series on the host, setting up
+// the host to run lxc containers first if necessary, and returns the
+// packages installed in it.
+func (ct *containerTemplates) build(series string, config params.ContainerConfig) ([]string, error) {
+	if !ct.initialised {
+		hostSeries, err := ct.params.Machine.Series()
+		if err != nil {
+			return nil, err
+		}
+		if err := ct.params.InitLock.Lock(fmt.Sprintf("initialise-%s", instance.LXC)); err != nil {
+			return nil, errors.Annotate(err, "failed to acquire initialization lock")
+		}
+		err = lxc.NewContainerInitialiser(hostSeries).Initialise()
+		ct.params.InitLock.Unlock()
+		if err != nil {
+			return nil, errors.Annotate(err, "setting up container dependencies on host machine")
+		}
+		ct.initialised = true
+	}
+	builder, err := newTemplateBuilder(ct.params.Provisioner, ct.params.Config, ct.params.ImageURLGetter)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	bridgeDevice := ct.params.Config.Value(agent.LxcBridge)
+	if bridgeDevice == "" {
+		bridgeDevice = lxc.DefaultLxcBridge
+	}
+	return builder.BuildTemplate(lxc.TemplateParams{
+		Series:               series,
+		Network:              container.BridgeNetworkConfig(bridgeDevice),
+		AuthorizedKeys:       config.AuthorizedKeys,
+		AptProxy:             config.AptProxy,
+		AptMirror:            config.AptMirror,
+		EnablePackageUpdates: config.EnableOSRefreshUpdate,
+		EnableOSUpgrades:     config.EnableOSUpgrade,
+	})
+}
@@ -0,0 +1,175 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package provisioner_test
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	jc "github.com/juju/testing/checkers"
+	"github.com/juju/utils/apt"
+	"github.com/juju/utils/fslock"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/agent"
+	apiprovisioner "github.com/juju/juju/api/provisioner"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/container"
+	"github.com/juju/juju/container/lxc"
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/state"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/worker"
+	"github.com/juju/juju/worker/provisioner"
+)
+
+type ContainerTemplatesSuite struct {
+	CommonProvisionerSuite
+	host     *state.Machine
+	builder  *fakeTemplateBuilder
+	initLock *fslock.Lock
+}
+
+var _ = gc.Suite(&ContainerTemplatesSuite{})
+
+type fakeTemplateBuilder struct {
+	series   chan string
+	packages []string
+	err      error
+}
+
+func (b *fakeTemplateBuilder) BuildTemplate(params lxc.TemplateParams) ([]string, error) {
+	b.series <- params.Series
+	return b.packages, b.err
+}
+
+func (s *ContainerTemplatesSuite) SetUpTest(c *gc.C) {
+	s.CommonProvisionerSuite.SetUpTest(c)
+	s.HookCommandOutput(&apt.CommandOutput, []byte{}, nil)
+	s.PatchValue(provisioner.TemplatePollPeriod, 10*time.Millisecond)
+	s.PatchValue(provisioner.TemplatePackages, func(series string) ([]string, error) {
+		return nil, errors.NotFoundf("template for series %q", series)
+	})
+	s.builder = &fakeTemplateBuilder{
+		series:   make(chan string, 10),
+		packages: []string{"cloud-init=0.7.5-0ubuntu1"},
+	}
+	s.PatchValue(provisioner.NewTemplateBuilder, func(*apiprovisioner.State, agent.Config, container.ImageURLGetter) (lxc.TemplateBuilder, error) {
+		return s.builder, nil
+	})
+	var err error
+	s.initLock, err = fslock.NewLock(c.MkDir(), "container-init")
+	c.Assert(err, jc.ErrorIsNil)
+	s.host, err = s.State.Machine("0")
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *ContainerTemplatesSuite) startWorker(c *gc.C) worker.Worker {
+	tag := s.host.Tag().(names.MachineTag)
+	machine, err := s.provisioner.Machine(tag)
+	c.Assert(err, jc.ErrorIsNil)
+	return provisioner.NewContainerTemplatesWorker(provisioner.ContainerTemplatesParams{
+		Machine:     machine,
+		Provisioner: s.provisioner,
+		Config:      s.AgentConfigForTag(c, tag),
+		InitLock:    s.initLock,
+	})
+}
+
+func (s *ContainerTemplatesSuite) waitForTemplate(c *gc.C, check func(*state.ContainerTemplate) bool) *state.ContainerTemplate {
+	for a := coretesting.LongAttempt.Start(); a.Next(); {
+		templates, err := s.host.ContainerTemplates()
+		c.Assert(err, jc.ErrorIsNil)
+		if len(templates) == 1 && check(templates[0]) {
+			return templates[0]
+		}
+	}
+	c.Fatalf("template not recorded")
+	return nil
+}
+
+func (s *ContainerTemplatesSuite) TestBuildsRequestedTemplate(c *gc.C) {
+	err := s.host.RequestContainerTemplate(instance.LXC, "trusty")
+	c.Assert(err, jc.ErrorIsNil)
+	w := s.startWorker(c)
+	defer func() { c.Assert(worker.Stop(w), jc.ErrorIsNil) }()
+
+	select {
+	case series := <-s.builder.series:
+		c.Assert(series, gc.Equals, "trusty")
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("template not built")
+	}
+	t := s.waitForTemplate(c, func(t *state.ContainerTemplate) bool {
+		return !t.Built().IsZero()
+	})
+	c.Assert(t.Series(), gc.Equals, "trusty")
+	c.Assert(t.PrimeRequested().IsZero(), jc.IsTrue)
+	c.Assert(t.Packages(), jc.DeepEquals, []string{"cloud-init=0.7.5-0ubuntu1"})
+	c.Assert(t.Error(), gc.Equals, "")
+}
+
+func (s *ContainerTemplatesSuite) TestRecordsFailedBuild(c *gc.C) {
+	s.builder.err = errors.New("no space left on device")
+	err := s.host.RequestContainerTemplate(instance.LXC, "trusty")
+	c.Assert(err, jc.ErrorIsNil)
+	w := s.startWorker(c)
+	defer func() { c.Assert(worker.Stop(w), jc.ErrorIsNil) }()
+
+	t := s.waitForTemplate(c, func(t *state.ContainerTemplate) bool {
+		return t.Error() != ""
+	})
+	c.Assert(t.Error(), gc.Equals, "no space left on device")
+	c.Assert(t.PrimeRequested().IsZero(), jc.IsTrue)
+	c.Assert(t.Built().IsZero(), jc.IsTrue)
+}
+
+func (s *ContainerTemplatesSuite) TestRecordsExistingTemplate(c *gc.C) {
+	s.PatchValue(provisioner.TemplatePackages, func(series string) ([]string, error) {
+		c.Check(series, gc.Equals, "quantal")
+		return []string{"python2.7=2.7.6-8"}, nil
+	})
+	w := s.startWorker(c)
+	defer func() { c.Assert(worker.Stop(w), jc.ErrorIsNil) }()
+
+	t := s.waitForTemplate(c, func(t *state.ContainerTemplate) bool {
+		return !t.Built().IsZero()
+	})
+	c.Assert(t.Kind(), gc.Equals, instance.LXC)
+	c.Assert(t.Series(), gc.Equals, "quantal")
+	c.Assert(t.Packages(), jc.DeepEquals, []string{"python2.7=2.7.6-8"})
+	select {
+	case series := <-s.builder.series:
+		c.Fatalf("unexpected build of %q template", series)
+	default:
+	}
+}
+
+func (s *ContainerTemplatesSuite) TestTemplateNeedsBuild(c *gc.C) {
+	now := time.Now()
+	built := now.Add(-2 * time.Hour)
+	for i, test := range []struct {
+		template params.ContainerTemplate
+		maxAge   time.Duration
+		expect   bool
+	}{{
+		template: params.ContainerTemplate{Kind: "lxc", PrimeRequested: &now},
+		expect:   true,
+	}, {
+		template: params.ContainerTemplate{Kind: "lxc", Built: &built},
+	}, {
+		template: params.ContainerTemplate{Kind: "lxc", Built: &built},
+		maxAge:   3 * time.Hour,
+	}, {
+		template: params.ContainerTemplate{Kind: "lxc", Built: &built},
+		maxAge:   time.Hour,
+		expect:   true,
+	}, {
+		template: params.ContainerTemplate{Kind: "kvm", PrimeRequested: &now},
+	}} {
+		c.Logf("test %d", i)
+		c.Check(provisioner.TemplateNeedsBuild(test.template, test.maxAge, now), gc.Equals, test.expect)
+	}
+}
@@ -4,6 +4,8 @@
 package provisioner
 
 import (
+	"time"
+
 	"github.com/juju/juju/api/watcher"
 	"github.com/juju/juju/environs/config"
 )
@@ -22,4 +24,21 @@ func GetRetryWatcher(p Provisioner) (watcher.NotifyWatcher, error) {
 var (
 	ContainerManagerConfig = containerManagerConfig
 	GetToolsFinder         = &getToolsFinder
+	RetryPolicyFromConfig  = &retryPolicyFromConfig
+	NewTemplateBuilder     = &newTemplateBuilder
+	TemplatePackages       = &templatePackages
+	TemplatePollPeriod     = &templatePollPeriod
+	TemplateNeedsBuild     = templateNeedsBuild
+	HostRouteFile          = &hostRouteFile
+	HostResolvConfFile     = &hostResolvConfFile
+	HostPrimaryInterface   = hostPrimaryInterface
+	BridgeAddress          = bridgeAddress
+	InterfaceAddrs         = &interfaceAddrs
+	RunHostCommand         = &runHostCommand
+	HostDNSServers         = hostDNSServers
+	ContainerTag           = containerTag
 )
+
+func RetryPolicyDelay(p RetryPolicy, attempts int) time.Duration {
+	return p.delay(attempts)
+}
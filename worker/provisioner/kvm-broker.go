@@ -6,6 +6,7 @@ package provisioner
 import (
 	"github.com/juju/errors"
 	"github.com/juju/loggo"
+	"github.com/juju/names"
 
 	"github.com/juju/juju/agent"
 	"github.com/juju/juju/container"
@@ -31,6 +32,7 @@ func NewKvmBroker(
 		manager:     manager,
 		api:         api,
 		agentConfig: agentConfig,
+		releaser:    newAddressReleaser(api),
 	}, nil
 }
 
@@ -38,6 +40,7 @@ type kvmBroker struct {
 	manager     container.Manager
 	api         APICalls
 	agentConfig agent.Config
+	releaser    *addressReleaser
 }
 
 // StartInstance is specified in the Broker interface.
@@ -48,19 +51,19 @@ func (broker *kvmBroker) StartInstance(args environs.StartInstanceParams) (*envi
 	// TODO: refactor common code out of the container brokers.
 	machineId := args.MachineConfig.MachineId
 	kvmLogger.Infof("starting kvm container for machineId: %s", machineId)
+	// Releasing the addresses of containers destroyed earlier may
+	// have failed.
+	broker.releaser.retry()
 
-	// TODO: Default to using the host network until we can configure.  Yes,
-	// this is using the LxcBridge value, we should put it in the api call for
-	// container config.
-	bridgeDevice := broker.agentConfig.Value(agent.LxcBridge)
-	if bridgeDevice == "" {
-		bridgeDevice = kvm.DefaultKvmBridge
-	}
+	// TODO: Default to using the host network until we can configure.
+	bridgeDevice := broker.bridgeDevice()
 	network := container.BridgeNetworkConfig(bridgeDevice)
 
 	series := args.Tools.OneSeries()
 	args.MachineConfig.MachineContainerType = instance.KVM
 	args.MachineConfig.Tools = args.Tools[0]
+	// The container manager sizes the container from the constraints.
+	args.MachineConfig.Constraints = args.Constraints
 
 	config, err := broker.api.ContainerConfig()
 	if err != nil {
@@ -83,10 +86,22 @@ func (broker *kvmBroker) StartInstance(args environs.StartInstanceParams) (*envi
 		kvmLogger.Errorf("failed to populate machine config: %v", err)
 		return nil, err
 	}
+	if config.AllocateContainerAddresses {
+		interfaces, err := prepareContainerInterfaces(broker.api, machineId, bridgeDevice)
+		if err != nil {
+			kvmLogger.Errorf("failed to prepare container network: %v", err)
+			broker.releaser.releaseMachine(names.NewMachineTag(machineId))
+			return nil, err
+		}
+		network.Interfaces = interfaces
+	}
 
 	inst, hardware, err := broker.manager.CreateContainer(args.MachineConfig, series, network)
 	if err != nil {
 		kvmLogger.Errorf("failed to start container: %v", err)
+		if config.AllocateContainerAddresses {
+			abandonContainerAddresses(broker.releaser, bridgeDevice, machineId, network.Interfaces)
+		}
 		return nil, err
 	}
 	kvmLogger.Infof("started kvm container for machineId: %s, %s, %s", machineId, inst.Id(), hardware.String())
@@ -96,16 +111,29 @@ func (broker *kvmBroker) StartInstance(args environs.StartInstanceParams) (*envi
 	}, nil
 }
 
+// bridgeDevice returns the name of the bridge the containers use.
+func (broker *kvmBroker) bridgeDevice() string {
+	// TODO: Yes, this is using the LxcBridge value, we should put it
+	// in the api call for container config.
+	if bridgeDevice := broker.agentConfig.Value(agent.LxcBridge); bridgeDevice != "" {
+		return bridgeDevice
+	}
+	return kvm.DefaultKvmBridge
+}
+
 // StopInstances shuts down the given instances.
 func (broker *kvmBroker) StopInstances(ids ...instance.Id) error {
 	// TODO: potentially parallelise.
 	for _, id := range ids {
 		kvmLogger.Infof("stopping kvm container for instance: %s", id)
+		interfaces := containerInterfaces(broker.api, id)
 		if err := broker.manager.DestroyContainer(id); err != nil {
 			kvmLogger.Errorf("container did not stop: %v", err)
 			return err
 		}
+		removeContainerRoutes(broker.bridgeDevice(), interfaces)
 	}
+	broker.releaser.release(ids)
 	return nil
 }
 
@@ -7,6 +7,7 @@ import (
 	"errors"
 
 	"github.com/juju/loggo"
+	"github.com/juju/names"
 
 	"github.com/juju/juju/agent"
 	"github.com/juju/juju/apiserver/params"
@@ -14,6 +15,7 @@ import (
 	"github.com/juju/juju/container/lxc"
 	"github.com/juju/juju/environs"
 	"github.com/juju/juju/instance"
+	"github.com/juju/juju/network"
 )
 
 var lxcLogger = loggo.GetLogger("juju.provisioner.lxc")
@@ -22,6 +24,9 @@ var _ environs.InstanceBroker = (*lxcBroker)(nil)
 
 type APICalls interface {
 	ContainerConfig() (params.ContainerConfig, error)
+	PrepareContainerInterfaceInfo(names.MachineTag) ([]network.Info, error)
+	GetContainerInterfaceInfo(names.MachineTag) ([]network.Info, error)
+	ReleaseContainerAddresses(names.MachineTag) error
 }
 
 // Override for testing.
@@ -39,6 +44,7 @@ func newLxcBroker(
 		manager:     manager,
 		api:         api,
 		agentConfig: agentConfig,
+		releaser:    newAddressReleaser(api),
 	}, nil
 }
 
@@ -46,6 +52,7 @@ type lxcBroker struct {
 	manager     container.Manager
 	api         APICalls
 	agentConfig agent.Config
+	releaser    *addressReleaser
 }
 
 // StartInstance is specified in the Broker interface.
@@ -56,17 +63,19 @@ func (broker *lxcBroker) StartInstance(args environs.StartInstanceParams) (*envi
 	// TODO: refactor common code out of the container brokers.
 	machineId := args.MachineConfig.MachineId
 	lxcLogger.Infof("starting lxc container for machineId: %s", machineId)
+	// Releasing the addresses of containers destroyed earlier may
+	// have failed.
+	broker.releaser.retry()
 
 	// Default to using the host network until we can configure.
-	bridgeDevice := broker.agentConfig.Value(agent.LxcBridge)
-	if bridgeDevice == "" {
-		bridgeDevice = lxc.DefaultLxcBridge
-	}
+	bridgeDevice := broker.bridgeDevice()
 	network := container.BridgeNetworkConfig(bridgeDevice)
 
 	series := args.Tools.OneSeries()
 	args.MachineConfig.MachineContainerType = instance.LXC
 	args.MachineConfig.Tools = args.Tools[0]
+	// The container manager sizes the container from the constraints.
+	args.MachineConfig.Constraints = args.Constraints
 
 	config, err := broker.api.ContainerConfig()
 	if err != nil {
@@ -88,10 +97,22 @@ func (broker *lxcBroker) StartInstance(args environs.StartInstanceParams) (*envi
 		lxcLogger.Errorf("failed to populate machine config: %v", err)
 		return nil, err
 	}
+	if config.AllocateContainerAddresses {
+		interfaces, err := prepareContainerInterfaces(broker.api, machineId, bridgeDevice)
+		if err != nil {
+			lxcLogger.Errorf("failed to prepare container network: %v", err)
+			broker.releaser.releaseMachine(names.NewMachineTag(machineId))
+			return nil, err
+		}
+		network.Interfaces = interfaces
+	}
 
 	inst, hardware, err := broker.manager.CreateContainer(args.MachineConfig, series, network)
 	if err != nil {
 		lxcLogger.Errorf("failed to start container: %v", err)
+		if config.AllocateContainerAddresses {
+			abandonContainerAddresses(broker.releaser, bridgeDevice, machineId, network.Interfaces)
+		}
 		return nil, err
 	}
 	lxcLogger.Infof("started lxc container for machineId: %s, %s, %s", machineId, inst.Id(), hardware.String())
@@ -101,16 +122,27 @@ func (broker *lxcBroker) StartInstance(args environs.StartInstanceParams) (*envi
 	}, nil
 }
 
+// bridgeDevice returns the name of the bridge the containers use.
+func (broker *lxcBroker) bridgeDevice() string {
+	if bridgeDevice := broker.agentConfig.Value(agent.LxcBridge); bridgeDevice != "" {
+		return bridgeDevice
+	}
+	return lxc.DefaultLxcBridge
+}
+
 // StopInstances shuts down the given instances.
 func (broker *lxcBroker) StopInstances(ids ...instance.Id) error {
 	// TODO: potentially parallelise.
 	for _, id := range ids {
 		lxcLogger.Infof("stopping lxc container for instance: %s", id)
+		interfaces := containerInterfaces(broker.api, id)
 		if err := broker.manager.DestroyContainer(id); err != nil {
 			lxcLogger.Errorf("container did not stop: %v", err)
 			return err
 		}
+		removeContainerRoutes(broker.bridgeDevice(), interfaces)
 	}
+	broker.releaser.release(ids)
 	return nil
 }
 
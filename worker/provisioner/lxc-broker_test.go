@@ -6,7 +6,9 @@ package provisioner_test
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/juju/errors"
@@ -26,6 +28,7 @@ import (
 	"github.com/juju/juju/instance"
 	instancetest "github.com/juju/juju/instance/testing"
 	jujutesting "github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/network"
 	"github.com/juju/juju/state"
 	coretesting "github.com/juju/juju/testing"
 	coretools "github.com/juju/juju/tools"
@@ -41,8 +44,10 @@ type lxcSuite struct {
 
 type lxcBrokerSuite struct {
 	lxcSuite
-	broker      environs.InstanceBroker
-	agentConfig agent.ConfigSetterWriter
+	broker       environs.InstanceBroker
+	agentConfig  agent.ConfigSetterWriter
+	api          *fakeAPI
+	hostCommands []string
 }
 
 var _ = gc.Suite(&lxcBrokerSuite{})
@@ -81,28 +86,51 @@ func (s *lxcBrokerSuite) SetUpTest(c *gc.C) {
 		})
 	c.Assert(err, jc.ErrorIsNil)
 	managerConfig := container.ManagerConfig{container.ConfigName: "juju", "use-clone": "false"}
-	s.broker, err = provisioner.NewLxcBroker(&fakeAPI{}, s.agentConfig, managerConfig, nil)
+	s.api = &fakeAPI{}
+	s.broker, err = provisioner.NewLxcBroker(s.api, s.agentConfig, managerConfig, nil)
 	c.Assert(err, jc.ErrorIsNil)
+
+	// Stand in for the host's network configuration.
+	s.hostCommands = nil
+	s.PatchValue(provisioner.RunHostCommand, func(command string) error {
+		s.hostCommands = append(s.hostCommands, command)
+		return nil
+	})
+	s.PatchValue(provisioner.InterfaceAddrs, func(name string) ([]net.Addr, error) {
+		return []net.Addr{&net.IPNet{IP: net.ParseIP("10.0.3.1"), Mask: net.CIDRMask(24, 32)}}, nil
+	})
+	routes := filepath.Join(c.MkDir(), "route")
+	err = ioutil.WriteFile(routes, []byte(routeContents), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	s.PatchValue(provisioner.HostRouteFile, routes)
 }
 
 func (s *lxcBrokerSuite) startInstance(c *gc.C, machineId string) instance.Instance {
+	result := s.startInstanceWithConstraints(c, machineId, constraints.Value{})
+	return result.Instance
+}
+
+func (s *lxcBrokerSuite) startInstanceWithConstraints(c *gc.C, machineId string, cons constraints.Value) *environs.StartInstanceResult {
+	result, err := s.tryStartInstance(c, machineId, cons)
+	c.Assert(err, jc.ErrorIsNil)
+	return result
+}
+
+func (s *lxcBrokerSuite) tryStartInstance(c *gc.C, machineId string, cons constraints.Value) (*environs.StartInstanceResult, error) {
 	machineNonce := "fake-nonce"
 	stateInfo := jujutesting.FakeStateInfo(machineId)
 	apiInfo := jujutesting.FakeAPIInfo(machineId)
 	machineConfig, err := environs.NewMachineConfig(machineId, machineNonce, "released", "quantal", true, nil, stateInfo, apiInfo)
 	c.Assert(err, jc.ErrorIsNil)
-	cons := constraints.Value{}
 	possibleTools := coretools.List{&coretools.Tools{
 		Version: version.MustParseBinary("2.3.4-quantal-amd64"),
 		URL:     "http://tools.testing.invalid/2.3.4-quantal-amd64.tgz",
 	}}
-	result, err := s.broker.StartInstance(environs.StartInstanceParams{
+	return s.broker.StartInstance(environs.StartInstanceParams{
 		Constraints:   cons,
 		Tools:         possibleTools,
 		MachineConfig: machineConfig,
 	})
-	c.Assert(err, jc.ErrorIsNil)
-	return result.Instance
 }
 
 func (s *lxcBrokerSuite) TestStartInstance(c *gc.C) {
@@ -118,6 +146,15 @@ func (s *lxcBrokerSuite) TestStartInstance(c *gc.C) {
 	c.Assert(string(lxcConfContents), jc.Contains, "lxc.network.link = lxcbr0")
 }
 
+func (s *lxcBrokerSuite) TestStartInstanceWithConstraints(c *gc.C) {
+	result := s.startInstanceWithConstraints(c, "1/lxc/0", constraints.MustParse("mem=512M cpu-cores=1"))
+	c.Assert(*result.Hardware.Mem, gc.Equals, uint64(512))
+	c.Assert(*result.Hardware.CpuCores, gc.Equals, uint64(1))
+	config, err := ioutil.ReadFile(filepath.Join(s.LxcDir, string(result.Instance.Id()), "config"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(config), jc.Contains, "lxc.cgroup.memory.limit_in_bytes = 512M\nlxc.cgroup.cpuset.cpus = 0\n")
+}
+
 func (s *lxcBrokerSuite) TestStartInstanceWithBridgeEnviron(c *gc.C) {
 	s.agentConfig.SetValue(agent.LxcBridge, "br0")
 	machineId := "1/lxc/0"
@@ -132,6 +169,60 @@ func (s *lxcBrokerSuite) TestStartInstanceWithBridgeEnviron(c *gc.C) {
 	c.Assert(string(lxcConfContents), jc.Contains, "lxc.network.link = br0")
 }
 
+func (s *lxcBrokerSuite) TestStartInstanceWithStaticAddress(c *gc.C) {
+	s.api.allocateAddresses = true
+	lxc := s.startInstance(c, "1/lxc/0")
+	c.Assert(s.api.prepared, jc.DeepEquals, []names.MachineTag{names.NewMachineTag("1/lxc/0")})
+	lxcConfContents, err := ioutil.ReadFile(filepath.Join(s.ContainerDir, string(lxc.Id()), "lxc.conf"))
+	c.Assert(err, jc.ErrorIsNil)
+	// The container's gateway is the host's address on the bridge.
+	c.Assert(string(lxcConfContents), jc.Contains, "lxc.network.ipv4 = 0.10.0.5/24\nlxc.network.ipv4.gateway = 10.0.3.1\n")
+	userData, err := ioutil.ReadFile(filepath.Join(s.ContainerDir, string(lxc.Id()), "cloud-init"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(userData), jc.Contains, "address 0.10.0.5")
+	c.Assert(string(userData), jc.Contains, "ifdown --force eth0; ifup eth0")
+	// The host routes the address to the container.
+	c.Assert(s.hostCommands, jc.DeepEquals, []string{
+		"sysctl -w net.ipv4.ip_forward=1",
+		"sysctl -w net.ipv4.conf.eth0.proxy_arp=1",
+		"sysctl -w net.ipv4.conf.lxcbr0.proxy_arp=1",
+		"ip route replace 0.10.0.5 dev lxcbr0",
+	})
+}
+
+func (s *lxcBrokerSuite) TestStartInstanceFailureReleasesStaticAddress(c *gc.C) {
+	s.api.allocateAddresses = true
+	// The container manager refuses the constraints.
+	_, err := s.tryStartInstance(c, "1/lxc/0", constraints.MustParse("root-disk=10G"))
+	c.Assert(err, gc.ErrorMatches, ".*root-disk is not supported by lxc containers")
+	c.Assert(s.hostCommands[len(s.hostCommands)-1], gc.Equals, "ip route del 0.10.0.5 dev lxcbr0")
+	c.Assert(s.api.released, jc.DeepEquals, []names.MachineTag{names.NewMachineTag("1/lxc/0")})
+}
+
+func (s *lxcBrokerSuite) TestStartInstanceRouteFailureReleasesStaticAddress(c *gc.C) {
+	s.api.allocateAddresses = true
+	s.PatchValue(provisioner.RunHostCommand, func(command string) error {
+		s.hostCommands = append(s.hostCommands, command)
+		if strings.HasPrefix(command, "ip route replace") {
+			return errors.New("no route for you")
+		}
+		return nil
+	})
+	_, err := s.tryStartInstance(c, "1/lxc/0", constraints.Value{})
+	c.Assert(err, gc.ErrorMatches, `cannot route addresses of container "1/lxc/0": no route for you`)
+	c.Assert(s.hostCommands[len(s.hostCommands)-1], gc.Equals, "ip route del 0.10.0.5 dev lxcbr0")
+	c.Assert(s.api.released, jc.DeepEquals, []names.MachineTag{names.NewMachineTag("1/lxc/0")})
+}
+
+func (s *lxcBrokerSuite) TestStartInstanceWithoutStaticAddress(c *gc.C) {
+	lxc := s.startInstance(c, "1/lxc/0")
+	c.Assert(s.api.prepared, gc.HasLen, 0)
+	lxcConfContents, err := ioutil.ReadFile(filepath.Join(s.ContainerDir, string(lxc.Id()), "lxc.conf"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(lxcConfContents), gc.Not(jc.Contains), "lxc.network.ipv4")
+	c.Assert(s.hostCommands, gc.HasLen, 0)
+}
+
 func (s *lxcBrokerSuite) TestStopInstance(c *gc.C) {
 	lxc0 := s.startInstance(c, "1/lxc/0")
 	lxc1 := s.startInstance(c, "1/lxc/1")
@@ -146,6 +237,43 @@ func (s *lxcBrokerSuite) TestStopInstance(c *gc.C) {
 	err = s.broker.StopInstances(lxc1.Id(), lxc2.Id())
 	c.Assert(err, jc.ErrorIsNil)
 	s.assertInstances(c)
+
+	// The addresses of the stopped containers are released.
+	c.Assert(s.api.released, jc.DeepEquals, []names.MachineTag{
+		names.NewMachineTag("1/lxc/0"),
+		names.NewMachineTag("1/lxc/1"),
+		names.NewMachineTag("1/lxc/2"),
+	})
+}
+
+func (s *lxcBrokerSuite) TestStopInstanceWithStaticAddress(c *gc.C) {
+	s.api.allocateAddresses = true
+	lxc0 := s.startInstance(c, "1/lxc/0")
+	s.hostCommands = nil
+
+	err := s.broker.StopInstances(lxc0.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.hostCommands, jc.DeepEquals, []string{"ip route del 0.10.0.5 dev lxcbr0"})
+	c.Assert(s.api.released, jc.DeepEquals, []names.MachineTag{names.NewMachineTag("1/lxc/0")})
+}
+
+func (s *lxcBrokerSuite) TestStopInstanceRetriesRelease(c *gc.C) {
+	lxc0 := s.startInstance(c, "1/lxc/0")
+	lxc1 := s.startInstance(c, "1/lxc/1")
+
+	s.api.releaseErr = errors.New("provider unavailable")
+	err := s.broker.StopInstances(lxc0.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.api.released, gc.HasLen, 0)
+
+	// The failed release is retried along with the next one.
+	s.api.releaseErr = nil
+	err = s.broker.StopInstances(lxc1.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.api.released, jc.DeepEquals, []names.MachineTag{
+		names.NewMachineTag("1/lxc/0"),
+		names.NewMachineTag("1/lxc/1"),
+	})
 }
 
 func (s *lxcBrokerSuite) TestAllInstances(c *gc.C) {
@@ -294,12 +422,48 @@ func (s *lxcProvisionerSuite) TestContainerStartedAndStopped(c *gc.C) {
 	s.waitRemoved(c, container)
 }
 
-type fakeAPI struct{}
+type fakeAPI struct {
+	allocateAddresses bool
+	releaseErr        error
+	prepared          []names.MachineTag
+	released          []names.MachineTag
+}
 
-func (*fakeAPI) ContainerConfig() (params.ContainerConfig, error) {
+func (f *fakeAPI) ContainerConfig() (params.ContainerConfig, error) {
 	return params.ContainerConfig{
-		UpdateBehavior:          &params.UpdateBehavior{true, true},
-		ProviderType:            "fake",
-		AuthorizedKeys:          coretesting.FakeAuthKeys,
-		SSLHostnameVerification: true}, nil
+		UpdateBehavior:             &params.UpdateBehavior{true, true},
+		ProviderType:               "fake",
+		AuthorizedKeys:             coretesting.FakeAuthKeys,
+		SSLHostnameVerification:    true,
+		AllocateContainerAddresses: f.allocateAddresses}, nil
+}
+
+func (f *fakeAPI) PrepareContainerInterfaceInfo(tag names.MachineTag) ([]network.Info, error) {
+	f.prepared = append(f.prepared, tag)
+	return f.interfaceInfo(), nil
+}
+
+func (f *fakeAPI) GetContainerInterfaceInfo(tag names.MachineTag) ([]network.Info, error) {
+	for _, prepared := range f.prepared {
+		if prepared == tag {
+			return f.interfaceInfo(), nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeAPI) interfaceInfo() []network.Info {
+	return []network.Info{{
+		CIDR:          "0.10.0.0/24",
+		InterfaceName: "eth0",
+		Address:       network.NewAddress("0.10.0.5", network.ScopeCloudLocal),
+	}}
+}
+
+func (f *fakeAPI) ReleaseContainerAddresses(tag names.MachineTag) error {
+	if f.releaseErr != nil {
+		return f.releaseErr
+	}
+	f.released = append(f.released, tag)
+	return nil
 }
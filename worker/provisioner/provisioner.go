@@ -140,6 +140,7 @@ func (p *provisioner) getStartTask(harvestMode config.HarvestMode) (ProvisionerT
 	task := NewProvisionerTask(
 		machineTag,
 		harvestMode,
+		retryPolicyFromConfig(envCfg),
 		p.st,
 		getToolsFinder(p.st),
 		machineWatcher,
@@ -214,6 +215,7 @@ func (p *environProvisioner) loop() error {
 				logger.Errorf("loaded invalid environment configuration: %v", err)
 			}
 			task.SetHarvestMode(environConfig.ProvisionerHarvestMode())
+			task.SetRetryPolicy(retryPolicyFromConfig(environConfig))
 		}
 	}
 }
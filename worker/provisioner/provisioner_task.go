@@ -5,6 +5,7 @@ package provisioner
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/juju/errors"
@@ -23,6 +24,7 @@ import (
 	"github.com/juju/juju/environs/config"
 	"github.com/juju/juju/instance"
 	"github.com/juju/juju/network"
+	"github.com/juju/juju/provider/common"
 	"github.com/juju/juju/state/watcher"
 	coretools "github.com/juju/juju/tools"
 	"github.com/juju/juju/version"
@@ -39,6 +41,49 @@ type ProvisionerTask interface {
 	// should harvest machines. See config.HarvestMode for
 	// documentation of behavior.
 	SetHarvestMode(mode config.HarvestMode)
+
+	// SetRetryPolicy sets how the provisioner task retries starting
+	// instances that fail to start for transient reasons.
+	SetRetryPolicy(policy RetryPolicy)
+}
+
+// RetryPolicy describes how the provisioner task retries starting an
+// instance that failed to start for a transient reason.
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts to start an instance,
+	// the first included.
+	Attempts int
+
+	// Delay is the amount of time to wait before the first retry. It
+	// doubles after each further attempt.
+	Delay time.Duration
+
+	// MaxDelay is the longest amount of time to wait between attempts.
+	MaxDelay time.Duration
+}
+
+// delay returns the amount of time to wait after the given number of
+// failed attempts.
+func (p RetryPolicy) delay(attempts int) time.Duration {
+	delay := p.Delay
+	for i := 1; i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// retryPolicyFromConfig returns the retry policy configured for the
+// environment.
+var retryPolicyFromConfig = func(cfg *config.Config) RetryPolicy {
+	opts := cfg.ProvisionerRetryOpts()
+	return RetryPolicy{
+		Attempts: opts.Attempts,
+		Delay:    opts.Delay,
+		MaxDelay: opts.MaxDelay,
+	}
 }
 
 type MachineGetter interface {
@@ -60,6 +105,7 @@ var _ ToolsFinder = (*apiprovisioner.State)(nil)
 func NewProvisionerTask(
 	machineTag names.MachineTag,
 	harvestMode config.HarvestMode,
+	retryPolicy RetryPolicy,
 	machineGetter MachineGetter,
 	toolsFinder ToolsFinder,
 	machineWatcher apiwatcher.StringsWatcher,
@@ -79,7 +125,10 @@ func NewProvisionerTask(
 		auth:                   auth,
 		harvestMode:            harvestMode,
 		harvestModeChan:        make(chan config.HarvestMode, 1),
+		retryPolicy:            retryPolicy,
+		retryPolicyChan:        make(chan RetryPolicy, 1),
 		machines:               make(map[string]*apiprovisioner.Machine),
+		attempts:               make(map[string]*startAttempts),
 		imageStream:            imageStream,
 		secureServerConnection: secureServerConnection,
 	}
@@ -103,10 +152,24 @@ type provisionerTask struct {
 	secureServerConnection bool
 	harvestMode            config.HarvestMode
 	harvestModeChan        chan config.HarvestMode
+	retryPolicy            RetryPolicy
+	retryPolicyChan        chan RetryPolicy
 	// instance id -> instance
 	instances map[instance.Id]instance.Instance
 	// machine id -> machine
 	machines map[string]*apiprovisioner.Machine
+	// machine id -> failed attempts to start an instance, for machines
+	// waiting to be retried
+	attempts map[string]*startAttempts
+}
+
+// startAttempts records the failed attempts to start an instance for a
+// machine that is waiting to be retried.
+type startAttempts struct {
+	count int
+	due   time.Time
+	// failedZones holds the availability zones that lacked capacity.
+	failedZones set.Strings
 }
 
 // Kill implements worker.Worker.Kill.
@@ -185,6 +248,12 @@ func (task *provisionerTask) loop() error {
 			if err := task.processMachinesWithTransientErrors(); err != nil {
 				return errors.Annotate(err, "failed to process machines with transient errors")
 			}
+		case policy := <-task.retryPolicyChan:
+			task.retryPolicy = policy
+		case <-task.nextRetry():
+			if err := task.retryDueMachines(); err != nil {
+				return errors.Annotate(err, "failed to retry starting machines")
+			}
 		}
 	}
 }
@@ -197,6 +266,70 @@ func (task *provisionerTask) SetHarvestMode(mode config.HarvestMode) {
 	}
 }
 
+// SetRetryPolicy implements ProvisionerTask.SetRetryPolicy().
+func (task *provisionerTask) SetRetryPolicy(policy RetryPolicy) {
+	select {
+	case task.retryPolicyChan <- policy:
+	case <-task.Dying():
+	}
+}
+
+// nextRetry returns a channel that receives when the next attempt to
+// start an instance is due, or nil if no attempt is waiting.
+func (task *provisionerTask) nextRetry() <-chan time.Time {
+	var next time.Time
+	for _, attempts := range task.attempts {
+		if next.IsZero() || attempts.due.Before(next) {
+			next = attempts.due
+		}
+	}
+	if next.IsZero() {
+		return nil
+	}
+	return time.After(next.Sub(time.Now()))
+}
+
+// retryDueMachines makes another attempt to start instances for the
+// machines whose retries are due.
+func (task *provisionerTask) retryDueMachines() error {
+	now := time.Now()
+	var ids []string
+	for id, attempts := range task.attempts {
+		if !attempts.due.After(now) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	var due []*apiprovisioner.Machine
+	for _, id := range ids {
+		machine, ok := task.machines[id]
+		if ok {
+			if err := machine.Refresh(); params.IsCodeNotFoundOrCodeUnauthorized(err) {
+				ok = false
+			} else if err != nil {
+				return errors.Annotatef(err, "failed to refresh machine %q", id)
+			}
+		}
+		// Machines that have gone away, been provisioned or put in error
+		// meanwhile are not retried; nor are those that are no longer
+		// alive, which processMachines deals with.
+		if !ok || machine.Life() != params.Alive {
+			delete(task.attempts, id)
+			continue
+		}
+		if _, err := machine.InstanceId(); !params.IsCodeNotProvisioned(err) {
+			delete(task.attempts, id)
+			continue
+		}
+		if status, _, err := machine.Status(); err != nil || status != params.StatusPending {
+			delete(task.attempts, id)
+			continue
+		}
+		due = append(due, machine)
+	}
+	return task.startMachines(due)
+}
+
 func (task *provisionerTask) processMachinesWithTransientErrors() error {
 	machines, statusResults, err := task.machineGetter.MachinesWithTransientErrors()
 	if err != nil {
@@ -210,6 +343,9 @@ func (task *provisionerTask) processMachinesWithTransientErrors() error {
 			continue
 		}
 		machine := machines[i]
+		// The retry was requested, so the machine gets a fresh set of
+		// attempts.
+		delete(task.attempts, machine.Id())
 		if err := machine.SetStatus(params.StatusPending, "", nil); err != nil {
 			logger.Errorf("cannot reset status of machine %q: %v", status.Id, err)
 			continue
@@ -363,6 +499,10 @@ func (task *provisionerTask) pendingOrDead(ids []string) (pending, dead []*apipr
 				logger.Infof("cannot get machine %q status: %v", machine, err)
 				continue
 			}
+			if attempts, ok := task.attempts[id]; ok && status == params.StatusPending {
+				logger.Debugf("machine %q is waiting until %v to retry provisioning", machine, attempts.due)
+				continue
+			}
 			if status == params.StatusPending {
 				pending = append(pending, machine)
 				logger.Infof("found machine %q pending provisioning", machine)
@@ -570,22 +710,61 @@ func (task *provisionerTask) startMachine(
 	startInstanceParams environs.StartInstanceParams,
 ) error {
 
+	attempts, ok := task.attempts[machine.Id()]
+	if !ok {
+		attempts = &startAttempts{failedZones: make(set.Strings)}
+	}
+	// Choose the availability zone to honour the placement policies of
+	// the machine's units, and to avoid zones that lacked capacity,
+	// unless the zone was chosen by the user.
+	if startInstanceParams.Placement == "" {
+		zone, err := task.nextZone(machine, startInstanceParams, attempts.failedZones)
+		switch {
+		case errors.Cause(err) == errNoAllowedZone:
+			delete(task.attempts, machine.Id())
+			return task.setErrorStatus("cannot start instance for machine %q: %v", machine, err)
+		case err != nil:
+			logger.Warningf("cannot choose availability zone for machine %q: %v", machine, err)
+		case zone != "":
+			logger.Infof("starting machine %q in availability zone %q", machine, zone)
+			startInstanceParams.Placement = "zone=" + zone
+		}
+	}
+
 	result, err := task.broker.StartInstance(startInstanceParams)
 	if err != nil {
-		// If this is a retryable error, we retry once
-		if instance.IsRetryableCreationError(errors.Cause(err)) {
-			logger.Infof("retryable error received on start instance - retrying instance creation")
-			result, err = task.broker.StartInstance(startInstanceParams)
-			if err != nil {
-				return task.setErrorStatus("cannot start instance for machine after a retry %q: %v", machine, err)
-			}
-		} else {
+		attempts.count++
+		transient, zones := classifyStartError(err)
+		for _, zone := range zones {
+			attempts.failedZones.Add(zone)
+		}
+		if !transient || attempts.count >= task.retryPolicy.Attempts {
+			delete(task.attempts, machine.Id())
 			// Set the state to error, so the machine will be skipped next
 			// time until the error is resolved, but don't return an
 			// error; just keep going with the other machines.
+			if attempts.count > 1 {
+				return task.setErrorStatus(
+					fmt.Sprintf("cannot start instance for machine %%q after %d attempts: %%v", attempts.count),
+					machine, err,
+				)
+			}
 			return task.setErrorStatus("cannot start instance for machine %q: %v", machine, err)
 		}
+		// Record the attempt in the machine's status, and try again
+		// once the delay has passed.
+		delay := task.retryPolicy.delay(attempts.count)
+		attempts.due = time.Now().Add(delay)
+		task.attempts[machine.Id()] = attempts
+		info := fmt.Sprintf("attempt %d of %d to start instance failed, retrying in %v: %v",
+			attempts.count, task.retryPolicy.Attempts, delay, err)
+		logger.Infof("machine %q: %s", machine, info)
+		if err := machine.SetStatus(params.StatusPending, info, nil); err != nil {
+			return errors.Annotatef(err, "cannot set status for machine %q", machine)
+		}
+		return nil
 	}
+	delete(task.attempts, machine.Id())
 
 	inst := result.Instance
 	hardware := result.Hardware
@@ -608,6 +787,94 @@ func (task *provisionerTask) startMachine(
 	return nil
 }
 
+// classifyStartError reports whether a failure to start an instance is
+// transient, so that starting the instance is worth retrying, and the
+// availability zones that lacked capacity, if that was the reason.
+// Failures that are not known to be transient are permanent.
+func classifyStartError(err error) (transient bool, zones []string) {
+	switch err := errors.Cause(err).(type) {
+	case *instance.CapacityError:
+		return true, err.Zones
+	case *instance.RetryableCreationError:
+		return true, nil
+	}
+	return false, nil
+}
+
+// errNoAllowedZone is returned by nextZone when the placement policies
+// of a machine's units allow none of the availability zones.
+var errNoAllowedZone = errors.New("no availability zone satisfies the placement policies of its units")
+
+// nextZone returns the availability zone in which to try starting an
+// instance for the machine next: of the zones allowed by the placement
+// policies of the machine's units, the least populated for its
+// distribution group, preferring those that did not lack capacity and
+// then those the policies do not avoid. It returns "" if the broker
+// does not support availability zones, or there is no reason to choose,
+// leaving the broker to do so.
+func (task *provisionerTask) nextZone(
+	machine *apiprovisioner.Machine,
+	args environs.StartInstanceParams,
+	failedZones set.Strings,
+) (string, error) {
+	zonedEnv, ok := task.broker.(common.ZonedEnviron)
+	if !ok {
+		return "", nil
+	}
+	placement, err := machine.ZonePlacement()
+	if params.IsCodeNotImplemented(err) {
+		// Older state servers have no placement policies.
+		placement = params.ZonePlacement{}
+	} else if err != nil {
+		return "", errors.Trace(err)
+	}
+	restricted := placement.Allowed != nil || len(placement.Excluded) > 0
+	if !restricted && len(placement.Avoided) == 0 && failedZones.IsEmpty() {
+		return "", nil
+	}
+
+	var group []instance.Id
+	if args.DistributionGroup != nil {
+		if group, err = args.DistributionGroup(); err != nil {
+			return "", errors.Trace(err)
+		}
+	}
+	zoneInstances, err := common.AvailabilityZoneAllocations(zonedEnv, group)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	allowed := set.NewStrings(placement.Allowed...)
+	excluded := set.NewStrings(placement.Excluded...)
+	var candidates []string
+	for _, zone := range zoneInstances {
+		if excluded.Contains(zone.ZoneName) || (placement.Allowed != nil && !allowed.Contains(zone.ZoneName)) {
+			continue
+		}
+		candidates = append(candidates, zone.ZoneName)
+	}
+	if len(candidates) == 0 {
+		if restricted {
+			return "", errNoAllowedZone
+		}
+		return "", nil
+	}
+	avoided := set.NewStrings(placement.Avoided...)
+	for _, skipAvoided := range []bool{true, false} {
+		for _, zone := range candidates {
+			if failedZones.Contains(zone) || (skipAvoided && avoided.Contains(zone)) {
+				continue
+			}
+			return zone, nil
+		}
+	}
+	// Every candidate zone has lacked capacity; if the policies
+	// restrict the zones, try one of them again.
+	if restricted {
+		return candidates[0], nil
+	}
+	return "", nil
+}
+
 type provisioningInfo struct {
 	Constraints   constraints.Value
 	Series        string
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/juju/errors"
@@ -33,6 +34,7 @@ import (
 	"github.com/juju/juju/juju/testing"
 	"github.com/juju/juju/mongo"
 	"github.com/juju/juju/network"
+	"github.com/juju/juju/provider/common"
 	"github.com/juju/juju/provider/dummy"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/state/multiwatcher"
@@ -64,6 +66,14 @@ var veryShortAttempt = utils.AttemptStrategy{
 	Delay: 80 * time.Millisecond,
 }
 
+// fastRetryPolicy is used by the provisioners under test, so that
+// retries do not slow the tests down.
+var fastRetryPolicy = provisioner.RetryPolicy{
+	Attempts: 3,
+	Delay:    10 * time.Millisecond,
+	MaxDelay: 50 * time.Millisecond,
+}
+
 func (s *CommonProvisionerSuite) SetUpSuite(c *gc.C) {
 	s.JujuConnSuite.SetUpSuite(c)
 	s.defaultConstraints = constraints.MustParse("arch=amd64 mem=4G cpu-cores=1 root-disk=8G")
@@ -77,6 +87,9 @@ func (s *CommonProvisionerSuite) SetUpTest(c *gc.C) {
 	dummy.SetStatePolicy(nil)
 
 	s.JujuConnSuite.SetUpTest(c)
+	s.PatchValue(provisioner.RetryPolicyFromConfig, func(*config.Config) provisioner.RetryPolicy {
+		return fastRetryPolicy
+	})
 
 	// Create the operations channel with more than enough space
 	// for those tests that don't listen on it.
@@ -1062,6 +1075,7 @@ func (s *ProvisionerSuite) newProvisionerTask(
 	return provisioner.NewProvisionerTask(
 		names.NewMachineTag("0"),
 		harvestingMethod,
+		fastRetryPolicy,
 		machineGetter,
 		toolsFinder,
 		machineWatcher,
@@ -1253,6 +1267,240 @@ func (b *mockBroker) StartInstance(args environs.StartInstanceParams) (*environs
 	return nil, fmt.Errorf("error: some error")
 }
 
+func (s *ProvisionerSuite) TestProvisionerRetriesWithBackoff(c *gc.C) {
+	broker := &failingBroker{
+		Environ:  s.Environ,
+		failures: []error{instance.NewRetryableCreationError("try again")},
+	}
+	task := s.newProvisionerTask(c, config.HarvestAll, broker, s.provisioner, mockToolsFinder{})
+	defer stop(c, task)
+
+	// Every attempt fails, so the machine ends up in error once the
+	// attempts allowed by the policy are used up.
+	m, err := s.addMachine()
+	c.Assert(err, jc.ErrorIsNil)
+	for a := coretesting.LongAttempt.Start(); a.Next(); {
+		status, info, _, err := m.Status()
+		c.Assert(err, jc.ErrorIsNil)
+		if status != state.StatusError {
+			continue
+		}
+		c.Assert(info, gc.Equals, "try again")
+		break
+	}
+	c.Assert(broker.placements(), gc.HasLen, fastRetryPolicy.Attempts)
+	_, err = m.InstanceId()
+	c.Assert(err, jc.Satisfies, errors.IsNotProvisioned)
+}
+
+func (s *ProvisionerSuite) TestProvisionerRetriesInAnotherZone(c *gc.C) {
+	broker := &failingBroker{
+		Environ:  s.Environ,
+		zones:    []string{"zone1", "zone2"},
+		failures: []error{instance.NewCapacityError([]string{"zone1"}, "no capacity in zone1")},
+	}
+	task := s.newProvisionerTask(c, config.HarvestAll, broker, s.provisioner, mockToolsFinder{})
+	defer stop(c, task)
+
+	m, err := s.addMachine()
+	c.Assert(err, jc.ErrorIsNil)
+	s.checkStartInstance(c, m)
+	c.Assert(broker.placements(), gc.DeepEquals, []string{"", "zone=zone2"})
+}
+
+func (s *ProvisionerSuite) TestProvisionerSkipsEveryZoneLackingCapacity(c *gc.C) {
+	broker := &failingBroker{
+		Environ: s.Environ,
+		zones:   []string{"zone1", "zone2", "zone3"},
+		failures: []error{
+			instance.NewCapacityError([]string{"zone1", "zone2"}, "no capacity in zone1 or zone2"),
+		},
+	}
+	task := s.newProvisionerTask(c, config.HarvestAll, broker, s.provisioner, mockToolsFinder{})
+	defer stop(c, task)
+
+	m, err := s.addMachine()
+	c.Assert(err, jc.ErrorIsNil)
+	s.checkStartInstance(c, m)
+	c.Assert(broker.placements(), gc.DeepEquals, []string{"", "zone=zone3"})
+}
+
+func (s *ProvisionerSuite) TestProvisionerDoesNotRetryPermanentErrors(c *gc.C) {
+	broker := &failingBroker{
+		Environ:  s.Environ,
+		failures: []error{errors.New("no such image")},
+	}
+	task := s.newProvisionerTask(c, config.HarvestAll, broker, s.provisioner, mockToolsFinder{})
+	defer stop(c, task)
+
+	m, err := s.addMachine()
+	c.Assert(err, jc.ErrorIsNil)
+	for a := coretesting.LongAttempt.Start(); a.Next(); {
+		status, info, _, err := m.Status()
+		c.Assert(err, jc.ErrorIsNil)
+		if status != state.StatusError {
+			continue
+		}
+		c.Assert(info, gc.Equals, "no such image")
+		break
+	}
+	c.Assert(broker.placements(), gc.HasLen, 1)
+}
+
+// addZonedUnits adds a wordpress service limited to one unit per
+// availability zone, with a unit on a machine provisioned in zone1, and
+// returns the machine of a new unit.
+func (s *ProvisionerSuite) addZonedUnits(c *gc.C) *state.Machine {
+	wordpress := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	err := wordpress.SetPlacementPolicies([]state.PlacementPolicy{{Kind: state.MaxUnitsPerZone, MaxUnits: 1}})
+	c.Assert(err, jc.ErrorIsNil)
+	zone := "zone1"
+	m0, err := s.BackingState.AddOneMachine(state.MachineTemplate{
+		Series:                  coretesting.FakeDefaultSeries,
+		Jobs:                    []state.MachineJob{state.JobHostUnits},
+		InstanceId:              "i-zone1",
+		Nonce:                   "fake_nonce",
+		HardwareCharacteristics: instance.HardwareCharacteristics{AvailabilityZone: &zone},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	unit, err := wordpress.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	err = unit.AssignToMachine(m0)
+	c.Assert(err, jc.ErrorIsNil)
+
+	unit, err = wordpress.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	err = unit.AssignToNewMachine()
+	c.Assert(err, jc.ErrorIsNil)
+	machineId, err := unit.AssignedMachineId()
+	c.Assert(err, jc.ErrorIsNil)
+	m, err := s.BackingState.Machine(machineId)
+	c.Assert(err, jc.ErrorIsNil)
+	return m
+}
+
+func (s *ProvisionerSuite) TestProvisionerHonoursZonePlacement(c *gc.C) {
+	broker := &failingBroker{
+		Environ:  s.Environ,
+		zones:    []string{"zone1", "zone2"},
+		failures: []error{nil},
+	}
+	task := s.newProvisionerTask(c, config.HarvestAll, broker, s.provisioner, mockToolsFinder{})
+	defer stop(c, task)
+
+	m := s.addZonedUnits(c)
+	s.checkStartInstance(c, m)
+	c.Assert(broker.placements(), gc.DeepEquals, []string{"zone=zone2"})
+}
+
+func (s *ProvisionerSuite) TestProvisionerNoZoneAllowed(c *gc.C) {
+	broker := &failingBroker{
+		Environ:  s.Environ,
+		zones:    []string{"zone1"},
+		failures: []error{nil},
+	}
+	task := s.newProvisionerTask(c, config.HarvestAll, broker, s.provisioner, mockToolsFinder{})
+	defer stop(c, task)
+
+	m := s.addZonedUnits(c)
+	for a := coretesting.LongAttempt.Start(); a.Next(); {
+		status, info, _, err := m.Status()
+		c.Assert(err, jc.ErrorIsNil)
+		if status != state.StatusError {
+			continue
+		}
+		c.Assert(info, gc.Equals, "no availability zone satisfies the placement policies of its units")
+		break
+	}
+	c.Assert(broker.placements(), gc.HasLen, 0)
+}
+
+func (s *ProvisionerSuite) TestRetryPolicyDelay(c *gc.C) {
+	policy := provisioner.RetryPolicy{
+		Attempts: 10,
+		Delay:    10 * time.Second,
+		MaxDelay: time.Minute,
+	}
+	for attempts, expect := range []time.Duration{
+		1: 10 * time.Second,
+		2: 20 * time.Second,
+		3: 40 * time.Second,
+		4: time.Minute,
+		9: time.Minute,
+	} {
+		if expect == 0 {
+			continue
+		}
+		c.Check(provisioner.RetryPolicyDelay(policy, attempts), gc.Equals, expect)
+	}
+}
+
+// failingBroker fails to start instances with each of its failures in
+// turn, repeating the last; but it starts instances for nil failures,
+// and in the availability zones other than the ones its failures name.
+type failingBroker struct {
+	environs.Environ
+	zones    []string
+	failures []error
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func (b *failingBroker) placements() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.calls...)
+}
+
+func (b *failingBroker) StartInstance(args environs.StartInstanceParams) (*environs.StartInstanceResult, error) {
+	b.mu.Lock()
+	n := len(b.calls)
+	b.calls = append(b.calls, args.Placement)
+	b.mu.Unlock()
+
+	err := b.failures[len(b.failures)-1]
+	if n < len(b.failures) {
+		err = b.failures[n]
+	}
+	if capacityErr, ok := err.(*instance.CapacityError); ok && args.Placement != "" && !placedInZone(args.Placement, capacityErr.Zones) {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	args.Placement = ""
+	return b.Environ.StartInstance(args)
+}
+
+// placedInZone returns whether placement names one of the given
+// availability zones.
+func placedInZone(placement string, zones []string) bool {
+	for _, zone := range zones {
+		if placement == "zone="+zone {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *failingBroker) AvailabilityZones() ([]common.AvailabilityZone, error) {
+	var zones []common.AvailabilityZone
+	for _, name := range b.zones {
+		zones = append(zones, mockZone(name))
+	}
+	return zones, nil
+}
+
+func (b *failingBroker) InstanceAvailabilityZoneNames(ids []instance.Id) ([]string, error) {
+	return make([]string, len(ids)), nil
+}
+
+type mockZone string
+
+func (z mockZone) Name() string    { return string(z) }
+func (z mockZone) Available() bool { return true }
+
 type mockToolsFinder struct {
 }
 
@@ -0,0 +1,296 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// Package remoterelations implements the worker that connects the
+// relations between the services of environments hosted by the same
+// state server.
+package remoterelations
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+	"launchpad.net/tomb"
+
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/multiwatcher"
+	"github.com/juju/juju/state/watcher"
+	"github.com/juju/juju/worker"
+)
+
+var logger = loggo.GetLogger("juju.worker.remoterelations")
+
+// RemoteEnviron is a connection to the API of the environment running
+// the remote service of a relation, through which the changes of the
+// relation are applied to its counterpart there.
+type RemoteEnviron interface {
+	// PublishRelationChange applies the change to the remote
+	// environment's copy of the relation. It returns an error
+	// satisfying params.IsCodeNotFound if there is no such copy.
+	PublishRelationChange(change params.RemoteRelationChange) error
+
+	// Close closes the connection.
+	Close() error
+}
+
+// OpenRemoteEnvironFunc connects to the API of the environment with the
+// given UUID.
+type OpenRemoteEnvironFunc func(uuid string) (RemoteEnviron, error)
+
+type remoteRelationsWorker struct {
+	st         *state.State
+	openRemote OpenRemoteEnvironFunc
+	tomb       tomb.Tomb
+
+	// mu guards remotes, which holds the connections to the
+	// environments of remote services, by UUID.
+	mu      sync.Mutex
+	remotes map[string]RemoteEnviron
+}
+
+// NewWorker returns a worker that publishes the settings of the units of
+// the environment's services, in relations with remote services, to the
+// environments running the remote services; and that destroys those
+// relations in the remote environments when they are destroyed in this
+// one. Their counterparts in the remote environments do the same, so the
+// units on each side see the other side's units as they would local ones.
+//
+// The remote environments are only changed through their APIs, which
+// are connected to with openRemote.
+func NewWorker(st *state.State, openRemote OpenRemoteEnvironFunc) worker.Worker {
+	w := &remoteRelationsWorker{
+		st:         st,
+		openRemote: openRemote,
+		remotes:    make(map[string]RemoteEnviron),
+	}
+	go func() {
+		defer w.tomb.Done()
+		w.tomb.Kill(w.loop())
+	}()
+	return w
+}
+
+// Kill is part of the worker.Worker interface.
+func (w *remoteRelationsWorker) Kill() {
+	w.tomb.Kill(nil)
+}
+
+// Wait is part of the worker.Worker interface.
+func (w *remoteRelationsWorker) Wait() error {
+	return w.tomb.Wait()
+}
+
+func (w *remoteRelationsWorker) loop() error {
+	defer w.closeRemotes()
+	rw := w.st.WatchRemoteRelations()
+	defer watcher.Stop(rw, &w.tomb)
+
+	// relations holds the relations being published. Their stop
+	// channels are closed (and set to nil) to stop publishing them.
+	relations := make(map[string]*relationPublisher)
+	done := make(chan string)
+	defer func() {
+		for _, r := range relations {
+			if r.stop != nil {
+				close(r.stop)
+			}
+		}
+		for len(relations) > 0 {
+			delete(relations, <-done)
+		}
+	}()
+	for {
+		select {
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case keys, ok := <-rw.Changes():
+			if !ok {
+				return watcher.EnsureErr(rw)
+			}
+			for _, key := range keys {
+				rel, err := w.st.KeyRelation(key)
+				if errors.IsNotFound(err) {
+					if r := relations[key]; r != nil && r.stop != nil {
+						close(r.stop)
+						r.stop = nil
+						// The relation is only removed once all the local
+						// units have left it, but the removal may be seen
+						// before their departure.
+						if err := w.removeCounterpartUnits(r.rel); err != nil {
+							return errors.Trace(err)
+						}
+					}
+					continue
+				} else if err != nil {
+					return errors.Trace(err)
+				}
+				if rel.Life() != state.Alive {
+					if err := w.destroyCounterpart(rel); err != nil {
+						return errors.Trace(err)
+					}
+				}
+				if _, ok := relations[key]; !ok {
+					r := &relationPublisher{
+						rel:  rel,
+						stop: make(chan struct{}),
+					}
+					relations[key] = r
+					go w.runRelation(rel, r.stop, done)
+				}
+			}
+		case key := <-done:
+			delete(relations, key)
+		}
+	}
+}
+
+// relationPublisher holds a relation being published, and the channel
+// that stops publishing it.
+type relationPublisher struct {
+	rel  *state.Relation
+	stop chan struct{}
+}
+
+// runRelation publishes the changes of the units of the relation's
+// local service until it is stopped, and then reports on done.
+func (w *remoteRelationsWorker) runRelation(rel *state.Relation, stop <-chan struct{}, done chan<- string) {
+	defer func() {
+		done <- rel.String()
+	}()
+	if err := w.relationLoop(rel, stop); err != nil {
+		w.tomb.Kill(errors.Annotatef(err, "cannot publish relation %q", rel))
+	}
+}
+
+func (w *remoteRelationsWorker) relationLoop(rel *state.Relation, stop <-chan struct{}) error {
+	remote, err := w.remoteEnviron(rel.RemoteEnvUUID())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	var localService string
+	for _, ep := range rel.Endpoints() {
+		if ep.ServiceName != rel.RemoteServiceName() {
+			localService = ep.ServiceName
+		}
+	}
+	uw, err := rel.WatchUnits(localService)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer watcher.Stop(uw, &w.tomb)
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-w.tomb.Dying():
+			return nil
+		case change, ok := <-uw.Changes():
+			if !ok {
+				return watcher.EnsureErr(uw)
+			}
+			if err := w.publish(rel, remote, change); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+}
+
+// publish copies the changes of the local units of rel to its
+// counterpart in the remote environment.
+func (w *remoteRelationsWorker) publish(rel *state.Relation, remote RemoteEnviron, change multiwatcher.RelationUnitsChange) error {
+	var changed []string
+	for unitName := range change.Changed {
+		changed = append(changed, unitName)
+	}
+	sort.Strings(changed)
+	relChange := params.RemoteRelationChange{
+		RelationKey:   rel.String(),
+		SourceEnvUUID: w.st.EnvironUUID(),
+		DepartedUnits: change.Departed,
+	}
+	for _, unitName := range changed {
+		settings, err := rel.UnitSettings(unitName)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return errors.Trace(err)
+		}
+		relChange.ChangedUnits = append(relChange.ChangedUnits, params.RemoteRelationUnit{
+			UnitName: unitName,
+			Settings: settings,
+		})
+	}
+	if len(relChange.ChangedUnits) == 0 && len(relChange.DepartedUnits) == 0 {
+		return nil
+	}
+	logger.Debugf("publishing changes of units %v and departures of units %v in relation %q", changed, change.Departed, rel)
+	return w.publishChange(remote, relChange)
+}
+
+// publishChange applies the change to the remote environment's copy of
+// the relation, if it still exists.
+func (w *remoteRelationsWorker) publishChange(remote RemoteEnviron, change params.RemoteRelationChange) error {
+	err := remote.PublishRelationChange(change)
+	if params.IsCodeNotFound(err) {
+		logger.Debugf("relation %q has been removed from the remote environment", change.RelationKey)
+		return nil
+	}
+	return errors.Annotatef(err, "cannot publish changes of relation %q", change.RelationKey)
+}
+
+// destroyCounterpart destroys the remote environment's copy of rel.
+func (w *remoteRelationsWorker) destroyCounterpart(rel *state.Relation) error {
+	remote, err := w.remoteEnviron(rel.RemoteEnvUUID())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	logger.Infof("destroying relation %q in environment %q", rel, rel.RemoteEnvUUID())
+	return w.publishChange(remote, params.RemoteRelationChange{
+		RelationKey:   rel.String(),
+		SourceEnvUUID: w.st.EnvironUUID(),
+		Destroy:       true,
+	})
+}
+
+// removeCounterpartUnits removes the units of the local service of rel
+// from the remote environment's copy of rel.
+func (w *remoteRelationsWorker) removeCounterpartUnits(rel *state.Relation) error {
+	remote, err := w.remoteEnviron(rel.RemoteEnvUUID())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return w.publishChange(remote, params.RemoteRelationChange{
+		RelationKey:    rel.String(),
+		SourceEnvUUID:  w.st.EnvironUUID(),
+		RemoveAllUnits: true,
+	})
+}
+
+// remoteEnviron returns a connection to the API of the environment
+// with the given UUID, opening it if needed.
+func (w *remoteRelationsWorker) remoteEnviron(uuid string) (RemoteEnviron, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if remote, ok := w.remotes[uuid]; ok {
+		return remote, nil
+	}
+	remote, err := w.openRemote(uuid)
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot connect to environment %q", uuid)
+	}
+	w.remotes[uuid] = remote
+	return remote, nil
+}
+
+func (w *remoteRelationsWorker) closeRemotes() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for uuid, remote := range w.remotes {
+		if err := remote.Close(); err != nil {
+			logger.Warningf("error closing connection to environment %q: %v", uuid, err)
+		}
+	}
+}
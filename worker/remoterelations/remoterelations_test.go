@@ -0,0 +1,180 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package remoterelations_test
+
+import (
+	stdtesting "testing"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	apiremoterelations "github.com/juju/juju/apiserver/remoterelations"
+	apiservertesting "github.com/juju/juju/apiserver/testing"
+	"github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/state"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/testing/factory"
+	"github.com/juju/juju/worker"
+	"github.com/juju/juju/worker/remoterelations"
+)
+
+func TestPackage(t *stdtesting.T) {
+	coretesting.MgoTestPackage(t)
+}
+
+type remoteRelationsSuite struct {
+	testing.JujuConnSuite
+	otherState *state.State
+	rel        *state.Relation
+	otherRel   *state.Relation
+	mysql      *state.Service
+}
+
+var _ = gc.Suite(&remoteRelationsSuite{})
+
+func (s *remoteRelationsSuite) SetUpTest(c *gc.C) {
+	s.JujuConnSuite.SetUpTest(c)
+	s.otherState = s.Factory.MakeEnvironment(c, &factory.EnvParams{Name: "db"})
+	s.AddCleanup(func(*gc.C) { s.otherState.Close() })
+	otherFactory := factory.NewFactory(s.otherState)
+	s.mysql = otherFactory.MakeService(c, &factory.ServiceParams{
+		Name:  "mysql",
+		Charm: otherFactory.MakeCharm(c, &factory.CharmParams{Name: "mysql"}),
+	})
+	s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+
+	offer, err := s.otherState.AddOffer("mysql", "server")
+	c.Assert(err, jc.ErrorIsNil)
+	s.rel, err = s.State.ConsumeOffer(offer, "wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+	s.otherRel, err = s.otherState.KeyRelation(s.rel.String())
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+// openRemote connects to the remote relations API of the test
+// environment with the given UUID, as a state server.
+func (s *remoteRelationsSuite) openRemote(uuid string) (remoterelations.RemoteEnviron, error) {
+	var st *state.State
+	switch uuid {
+	case s.State.EnvironUUID():
+		st = s.State
+	case s.otherState.EnvironUUID():
+		st = s.otherState
+	default:
+		return nil, errors.NotFoundf("environment %q", uuid)
+	}
+	auth := apiservertesting.FakeAuthorizer{
+		Tag:            names.NewMachineTag("0"),
+		EnvironManager: true,
+	}
+	api, err := apiremoterelations.NewRemoteRelationsAPI(st, common.NewResources(), auth)
+	if err != nil {
+		return nil, err
+	}
+	return remoteEnviron{api}, nil
+}
+
+// remoteEnviron implements remoterelations.RemoteEnviron by calling
+// the API end point directly.
+type remoteEnviron struct {
+	api *apiremoterelations.RemoteRelationsAPI
+}
+
+func (r remoteEnviron) PublishRelationChange(change params.RemoteRelationChange) error {
+	results, err := r.api.PublishRelationChanges(params.RemoteRelationChanges{
+		Changes: []params.RemoteRelationChange{change},
+	})
+	if err != nil {
+		return err
+	}
+	return results.OneError()
+}
+
+func (r remoteEnviron) Close() error {
+	return nil
+}
+
+// waitFor polls until check returns true, failing the test if it takes
+// too long.
+func (s *remoteRelationsSuite) waitFor(c *gc.C, desc string, check func() bool) {
+	timeout := time.After(coretesting.LongWait)
+	for {
+		s.State.StartSync()
+		s.otherState.StartSync()
+		select {
+		case <-time.After(coretesting.ShortWait):
+			if check() {
+				return
+			}
+		case <-timeout:
+			c.Fatalf("timed out waiting for %s", desc)
+		}
+	}
+}
+
+func (s *remoteRelationsSuite) TestPublishesUnitSettings(c *gc.C) {
+	w := remoterelations.NewWorker(s.otherState, s.openRemote)
+	defer func() { c.Assert(worker.Stop(w), jc.ErrorIsNil) }()
+
+	unit, err := s.mysql.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	ru, err := s.otherRel.Unit(unit)
+	c.Assert(err, jc.ErrorIsNil)
+	settings := map[string]interface{}{"host": "10.0.0.1"}
+	err = ru.EnterScope(settings)
+	c.Assert(err, jc.ErrorIsNil)
+
+	s.waitFor(c, "remote unit settings", func() bool {
+		read, err := s.rel.UnitSettings(unit.Name())
+		if errors.IsNotFound(err) {
+			return false
+		}
+		c.Assert(err, jc.ErrorIsNil)
+		return read["host"] == "10.0.0.1"
+	})
+
+	err = ru.LeaveScope()
+	c.Assert(err, jc.ErrorIsNil)
+	s.waitFor(c, "remote unit departure", func() bool {
+		return !s.inScope(c, unit.Name())
+	})
+}
+
+// inScope returns whether the named unit of the remote service is in
+// the scope of the consuming environment's relation.
+func (s *remoteRelationsSuite) inScope(c *gc.C, unitName string) bool {
+	w, err := s.rel.WatchUnits("mysql")
+	c.Assert(err, jc.ErrorIsNil)
+	defer func() { c.Assert(w.Stop(), jc.ErrorIsNil) }()
+	s.State.StartSync()
+	select {
+	case change := <-w.Changes():
+		_, ok := change.Changed[unitName]
+		return ok
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for relation units")
+	}
+	panic("unreachable")
+}
+
+func (s *remoteRelationsSuite) TestDestroysCounterpart(c *gc.C) {
+	w := remoterelations.NewWorker(s.State, s.openRemote)
+	defer func() { c.Assert(worker.Stop(w), jc.ErrorIsNil) }()
+
+	err := s.rel.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+	s.waitFor(c, "counterpart relation removal", func() bool {
+		err := s.otherRel.Refresh()
+		if errors.IsNotFound(err) {
+			return true
+		}
+		c.Assert(err, jc.ErrorIsNil)
+		return false
+	})
+}
@@ -0,0 +1,198 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// Package stagedupgrader implements the worker that drives staged
+// upgrades of the agents of an environment.
+package stagedupgrader
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+	"launchpad.net/tomb"
+
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/watcher"
+	"github.com/juju/juju/worker"
+)
+
+var logger = loggo.GetLogger("juju.worker.stagedupgrader")
+
+// checkInterval is how often the health of the released agents is
+// checked while a staged upgrade is running.
+var checkInterval = 30 * time.Second
+
+type stagedUpgrader struct {
+	st   *state.State
+	tomb tomb.Tomb
+}
+
+// NewWorker returns a worker that drives the staged upgrade of the
+// environment's agents, if one is running. Once the state server agents
+// are healthy on the target version, it releases the canary machines,
+// then the other machines in batches, each once all the agents released
+// before are healthy. If released agents are not healthy before the
+// upgrade's health timeout, it halts the upgrade.
+func NewWorker(st *state.State) worker.Worker {
+	w := &stagedUpgrader{st: st}
+	go func() {
+		defer w.tomb.Done()
+		w.tomb.Kill(w.loop())
+	}()
+	return w
+}
+
+// Kill is part of the worker.Worker interface.
+func (w *stagedUpgrader) Kill() {
+	w.tomb.Kill(nil)
+}
+
+// Wait is part of the worker.Worker interface.
+func (w *stagedUpgrader) Wait() error {
+	return w.tomb.Wait()
+}
+
+func (w *stagedUpgrader) loop() error {
+	sw := w.st.WatchStagedUpgrade()
+	defer watcher.Stop(sw, &w.tomb)
+
+	var check <-chan time.Time
+	for {
+		select {
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case _, ok := <-sw.Changes():
+			if !ok {
+				return watcher.EnsureErr(sw)
+			}
+		case <-check:
+		}
+		running, err := w.advance()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		check = nil
+		if running {
+			check = time.After(checkInterval)
+		}
+	}
+}
+
+// advance checks the health of the agents released by the staged
+// upgrade, and releases more or halts the upgrade accordingly. It
+// returns whether the upgrade is still running.
+func (w *stagedUpgrader) advance() (bool, error) {
+	staged, err := w.st.StagedUpgrade()
+	if errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, errors.Trace(err)
+	}
+	if staged.Status() != state.StagedUpgradeRunning {
+		return false, nil
+	}
+	machines, err := w.st.AllMachines()
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+
+	// The state servers are released first, then the other machines
+	// whose agents need upgrading.
+	var stateServers, others, candidates []*state.Machine
+	for _, m := range machines {
+		if m.Life() != state.Alive {
+			continue
+		}
+		if m.IsManager() {
+			stateServers = append(stateServers, m)
+			continue
+		}
+		if _, err := m.InstanceId(); errors.IsNotProvisioned(err) {
+			// The machine will start with the target version.
+			continue
+		} else if err != nil {
+			return false, errors.Trace(err)
+		}
+		others = append(others, m)
+		if !staged.HoldsMachine(m.Id()) {
+			continue
+		}
+		if tools, err := m.AgentTools(); err == nil && tools.Version.Number == staged.TargetVersion() {
+			continue
+		}
+		candidates = append(candidates, m)
+	}
+
+	waitingFor := stateServers
+	for _, m := range others {
+		if !staged.HoldsMachine(m.Id()) {
+			waitingFor = append(waitingFor, m)
+		}
+	}
+	var problems []string
+	for _, m := range waitingFor {
+		machineProblems, err := staged.MachineProblems(m)
+		if err != nil {
+			return false, errors.Trace(err)
+		}
+		if len(machineProblems) > 0 {
+			problems = append(problems, fmt.Sprintf("machine %s: %s", m.Id(), strings.Join(machineProblems, ", ")))
+		}
+	}
+	if len(problems) > 0 {
+		timeout := staged.Args().HealthTimeout
+		if time.Since(staged.StageStarted()) < timeout {
+			logger.Debugf("waiting for upgraded agents: %s", strings.Join(problems, "; "))
+			return true, nil
+		}
+		reason := fmt.Sprintf("agents not healthy on %s after %v: %s",
+			staged.TargetVersion(), timeout, strings.Join(problems, "; "))
+		logger.Errorf("halting staged upgrade: %s", reason)
+		if err := staged.Halt(reason); err != nil {
+			return false, errors.Trace(err)
+		}
+		return false, nil
+	}
+
+	if len(candidates) == 0 {
+		logger.Infof("staged upgrade to %s complete", staged.TargetVersion())
+		if err := staged.Complete(); err != nil {
+			return false, errors.Trace(err)
+		}
+		return false, nil
+	}
+	count := batchSize(staged, len(others))
+	if count > len(candidates) {
+		count = len(candidates)
+	}
+	ids := make([]string, count)
+	for i, m := range candidates[:count] {
+		ids[i] = m.Id()
+	}
+	logger.Infof("releasing machines %s to %s", strings.Join(ids, ", "), staged.TargetVersion())
+	if err := staged.ReleaseMachines(ids); err != nil {
+		return false, errors.Trace(err)
+	}
+	return true, nil
+}
+
+// batchSize returns how many machines to release next: the canary
+// percentage of all the machines other than the state servers if none
+// were released yet, and the upgrade's batch size otherwise.
+func batchSize(staged *state.StagedUpgrade, machineCount int) int {
+	args := staged.Args()
+	if len(staged.Released()) == 0 {
+		count := (machineCount*args.CanaryPercent + 99) / 100
+		if count < 1 {
+			count = 1
+		}
+		return count
+	}
+	if args.BatchSize == 0 {
+		return machineCount
+	}
+	return args.BatchSize
+}
@@ -0,0 +1,174 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package stagedupgrader_test
+
+import (
+	"fmt"
+	stdtesting "testing"
+	"time"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/instance"
+	"github.com/juju/juju/juju/testing"
+	"github.com/juju/juju/state"
+	coretesting "github.com/juju/juju/testing"
+	"github.com/juju/juju/version"
+	"github.com/juju/juju/worker"
+	"github.com/juju/juju/worker/stagedupgrader"
+)
+
+func TestPackage(t *stdtesting.T) {
+	coretesting.MgoTestPackage(t)
+}
+
+type stagedUpgraderSuite struct {
+	testing.JujuConnSuite
+	stateServer *state.Machine
+	machines    []*state.Machine
+	oldVersion  version.Number
+	newVersion  version.Number
+}
+
+var _ = gc.Suite(&stagedUpgraderSuite{})
+
+func (s *stagedUpgraderSuite) SetUpTest(c *gc.C) {
+	s.JujuConnSuite.SetUpTest(c)
+	s.PatchValue(stagedupgrader.CheckInterval, 10*time.Millisecond)
+
+	cfg, err := s.State.EnvironConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	var ok bool
+	s.oldVersion, ok = cfg.AgentVersion()
+	c.Assert(ok, jc.IsTrue)
+	s.newVersion = s.oldVersion
+	s.newVersion.Patch++
+
+	s.stateServer = s.addMachine(c, state.JobManageEnviron)
+	s.machines = nil
+	for i := 0; i < 4; i++ {
+		s.machines = append(s.machines, s.addMachine(c, state.JobHostUnits))
+	}
+}
+
+// addMachine adds a provisioned machine running the old version, with
+// its agent alive.
+func (s *stagedUpgraderSuite) addMachine(c *gc.C, job state.MachineJob) *state.Machine {
+	m, err := s.State.AddMachine("quantal", job)
+	c.Assert(err, jc.ErrorIsNil)
+	err = m.SetProvisioned(instance.Id("i-"+m.Id()), "fake_nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	s.setAgentVersion(c, m, s.oldVersion)
+	pinger, err := m.SetAgentPresence()
+	c.Assert(err, jc.ErrorIsNil)
+	s.AddCleanup(func(c *gc.C) { c.Check(pinger.Kill(), jc.ErrorIsNil) })
+	return m
+}
+
+func (s *stagedUpgraderSuite) setAgentVersion(c *gc.C, m *state.Machine, vers version.Number) {
+	err := m.SetAgentVersion(version.Binary{Number: vers, Series: "quantal", Arch: "amd64"})
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *stagedUpgraderSuite) startUpgrade(c *gc.C, args state.StagedUpgradeArgs) *state.StagedUpgrade {
+	err := s.State.StartStagedUpgrade(s.newVersion, args)
+	c.Assert(err, jc.ErrorIsNil)
+	staged, err := s.State.StagedUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+	return staged
+}
+
+// waitFor polls the staged upgrade until check returns true, failing
+// the test if it takes too long.
+func (s *stagedUpgraderSuite) waitFor(c *gc.C, staged *state.StagedUpgrade, desc string, check func() bool) {
+	timeout := time.After(coretesting.LongWait)
+	for {
+		s.State.StartSync()
+		select {
+		case <-time.After(coretesting.ShortWait):
+			c.Assert(staged.Refresh(), jc.ErrorIsNil)
+			if check() {
+				return
+			}
+		case <-timeout:
+			c.Fatalf("timed out waiting for %s", desc)
+		}
+	}
+}
+
+func (s *stagedUpgraderSuite) waitForReleased(c *gc.C, staged *state.StagedUpgrade, expected ...string) {
+	s.waitFor(c, staged, fmt.Sprintf("machines %v to be released", expected), func() bool {
+		return len(staged.Released()) == len(expected)
+	})
+	c.Assert(staged.Released(), jc.DeepEquals, expected)
+}
+
+func (s *stagedUpgraderSuite) TestReleasesCanariesThenBatches(c *gc.C) {
+	staged := s.startUpgrade(c, state.StagedUpgradeArgs{
+		CanaryPercent: 25,
+		BatchSize:     2,
+		HealthTimeout: coretesting.LongWait,
+	})
+	w := stagedupgrader.NewWorker(s.State)
+	defer func() { c.Assert(worker.Stop(w), jc.ErrorIsNil) }()
+
+	// Nothing is released until the state server is upgraded.
+	time.Sleep(coretesting.ShortWait)
+	c.Assert(staged.Refresh(), jc.ErrorIsNil)
+	c.Assert(staged.Released(), gc.HasLen, 0)
+	s.setAgentVersion(c, s.stateServer, s.newVersion)
+
+	// A quarter of the 4 machines are canaries.
+	s.waitForReleased(c, staged, "1")
+	s.setAgentVersion(c, s.machines[0], s.newVersion)
+
+	s.waitForReleased(c, staged, "1", "2", "3")
+	s.setAgentVersion(c, s.machines[1], s.newVersion)
+	s.setAgentVersion(c, s.machines[2], s.newVersion)
+
+	s.waitForReleased(c, staged, "1", "2", "3", "4")
+	s.setAgentVersion(c, s.machines[3], s.newVersion)
+
+	s.waitFor(c, staged, "upgrade to complete", func() bool {
+		return staged.Status() == state.StagedUpgradeComplete
+	})
+}
+
+func (s *stagedUpgraderSuite) TestHaltsWhenAgentsNotHealthy(c *gc.C) {
+	staged := s.startUpgrade(c, state.StagedUpgradeArgs{
+		CanaryPercent: 50,
+		HealthTimeout: time.Millisecond,
+	})
+	s.setAgentVersion(c, s.stateServer, s.newVersion)
+	w := stagedupgrader.NewWorker(s.State)
+	defer func() { c.Assert(worker.Stop(w), jc.ErrorIsNil) }()
+
+	s.waitFor(c, staged, "upgrade to halt", func() bool {
+		return staged.Status() == state.StagedUpgradeHalted
+	})
+	c.Assert(staged.Released(), jc.DeepEquals, []string{"1", "2"})
+	c.Assert(staged.HaltReason(), gc.Matches, fmt.Sprintf(
+		"agents not healthy on %s after 1ms: machine 1: agent is running %s; machine 2: agent is running %s",
+		s.newVersion, s.oldVersion, s.oldVersion,
+	))
+}
+
+func (s *stagedUpgraderSuite) TestHaltsWhenStateServerNotHealthy(c *gc.C) {
+	staged := s.startUpgrade(c, state.StagedUpgradeArgs{
+		CanaryPercent: 50,
+		HealthTimeout: time.Millisecond,
+	})
+	w := stagedupgrader.NewWorker(s.State)
+	defer func() { c.Assert(worker.Stop(w), jc.ErrorIsNil) }()
+
+	s.waitFor(c, staged, "upgrade to halt", func() bool {
+		return staged.Status() == state.StagedUpgradeHalted
+	})
+	c.Assert(staged.Released(), gc.HasLen, 0)
+	c.Assert(staged.HaltReason(), gc.Matches, fmt.Sprintf(
+		"agents not healthy on %s after 1ms: machine 0: agent is running %s",
+		s.newVersion, s.oldVersion,
+	))
+}
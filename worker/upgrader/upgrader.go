@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/juju/errors"
 	"github.com/juju/loggo"
 	"github.com/juju/names"
 	"github.com/juju/utils"
@@ -38,6 +39,7 @@ type Upgrader struct {
 	tag              names.Tag
 	origAgentVersion version.Number
 	isUpgradeRunning func() bool
+	reverseUpgrade   func(version.Number) error
 }
 
 // NewUpgrader returns a new upgrader worker. It watches changes to the
@@ -46,11 +48,17 @@ type Upgrader struct {
 // an upgrade is needed, the worker will exit with an UpgradeReadyError
 // holding details of the requested upgrade. The tools will have been
 // downloaded and unpacked.
+//
+// When the agent is asked to downgrade because an upgrade was rolled
+// back, it downgrades even across minor versions, and reverseUpgrade,
+// if not nil, is called with the version downgraded to before the
+// worker exits.
 func NewUpgrader(
 	st *upgrader.State,
 	agentConfig agent.Config,
 	origAgentVersion version.Number,
 	isUpgradeRunning func() bool,
+	reverseUpgrade func(version.Number) error,
 ) *Upgrader {
 	u := &Upgrader{
 		st:               st,
@@ -58,6 +66,7 @@ func NewUpgrader(
 		tag:              agentConfig.Tag(),
 		origAgentVersion: origAgentVersion,
 		isUpgradeRunning: isUpgradeRunning,
+		reverseUpgrade:   reverseUpgrade,
 	}
 	go func() {
 		defer u.tomb.Done()
@@ -119,6 +128,7 @@ func (u *Upgrader) loop() error {
 		dying       <-chan struct{}
 		wantTools   *coretools.Tools
 		wantVersion version.Number
+		downgrade   bool
 	)
 	for {
 		select {
@@ -126,7 +136,7 @@ func (u *Upgrader) loop() error {
 			if !ok {
 				return watcher.EnsureErr(versionWatcher)
 			}
-			wantVersion, err = u.st.DesiredVersion(u.tag.String())
+			wantVersion, downgrade, err = u.st.DesiredVersionInfo(u.tag.String())
 			if err != nil {
 				return err
 			}
@@ -138,6 +148,8 @@ func (u *Upgrader) loop() error {
 		}
 		if wantVersion == version.Current.Number {
 			continue
+		} else if downgrade {
+			logger.Infof("upgrade to %v was rolled back", version.Current.Number)
 		} else if !allowedTargetVersion(u.origAgentVersion, version.Current.Number,
 			u.isUpgradeRunning(), wantVersion) {
 			// See also bug #1299802 where when upgrading from
@@ -154,7 +166,7 @@ func (u *Upgrader) loop() error {
 		// Check if tools have already been downloaded.
 		wantVersionBinary := toBinaryVersion(wantVersion)
 		if u.toolsAlreadyDownloaded(wantVersionBinary) {
-			return u.newUpgradeReadyError(wantVersionBinary)
+			return u.upgradeReady(wantVersionBinary, downgrade)
 		}
 
 		// Check if tools are available for download.
@@ -170,7 +182,7 @@ func (u *Upgrader) loop() error {
 		// upgrade the agent.
 		err := u.ensureTools(wantTools)
 		if err == nil {
-			return u.newUpgradeReadyError(wantTools.Version)
+			return u.upgradeReady(wantTools.Version, downgrade)
 		}
 		logger.Errorf("failed to fetch tools from %q: %v", wantTools.URL, err)
 		retry = retryAfter()
@@ -188,6 +200,19 @@ func (u *Upgrader) toolsAlreadyDownloaded(wantVersion version.Binary) bool {
 	return err == nil
 }
 
+// upgradeReady returns the error that makes the agent restart with the
+// given tools, after reversing the upgrade steps run for the current
+// version if the agent downgrades because its upgrade was rolled back.
+func (u *Upgrader) upgradeReady(newVersion version.Binary, downgrade bool) error {
+	if downgrade && u.reverseUpgrade != nil && newVersion.Number.Compare(version.Current.Number) < 0 {
+		logger.Infof("reversing upgrade steps before downgrading to %v", newVersion.Number)
+		if err := u.reverseUpgrade(newVersion.Number); err != nil {
+			return errors.Annotatef(err, "cannot reverse upgrade to %v", version.Current.Number)
+		}
+	}
+	return u.newUpgradeReadyError(newVersion)
+}
+
 func (u *Upgrader) newUpgradeReadyError(newVersion version.Binary) *UpgradeReadyError {
 	return &UpgradeReadyError{
 		OldTools:  version.Current,
@@ -43,6 +43,8 @@ type UpgraderSuite struct {
 	oldRetryAfter  func() <-chan time.Time
 	confVersion    version.Number
 	upgradeRunning bool
+	reversedTo     []version.Number
+	reverseErr     error
 }
 
 type AllowedTargetVersionSuite struct{}
@@ -55,6 +57,8 @@ func (s *UpgraderSuite) SetUpTest(c *gc.C) {
 	// s.machine needs to have IsManager() so that it can get the actual
 	// current revision to upgrade to.
 	s.state, s.machine = s.OpenAPIAsNewMachine(c, state.JobManageEnviron)
+	s.reversedTo = nil
+	s.reverseErr = nil
 	// Capture the value of RetryAfter, and use that captured
 	// value in the cleanup lambda.
 	oldRetryAfter := *upgrader.RetryAfter
@@ -93,6 +97,10 @@ func (s *UpgraderSuite) makeUpgrader(c *gc.C) *upgrader.Upgrader {
 		agentConfig(s.machine.Tag(), s.DataDir()),
 		s.confVersion,
 		func() bool { return s.upgradeRunning },
+		func(to version.Number) error {
+			s.reversedTo = append(s.reversedTo, to)
+			return s.reverseErr
+		},
 	)
 }
 
@@ -341,6 +349,58 @@ func (s *UpgraderSuite) TestUpgraderAllowsDowngradeToOrigVersionIfUpgradeInProgr
 	envtesting.CheckTools(c, foundTools, downgradeTools)
 }
 
+// rollBackUpgrade records that the upgrade from the given version to
+// the current version was rolled back.
+func (s *UpgraderSuite) rollBackUpgrade(c *gc.C, previous version.Number) {
+	err := s.State.SetUpgradeBackup(previous, version.Current.Number, "backup-id")
+	c.Assert(err, jc.ErrorIsNil)
+	backup, err := s.State.UpgradeBackup()
+	c.Assert(err, jc.ErrorIsNil)
+	err = backup.SetRolledBack()
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *UpgraderSuite) TestUpgraderDowngradesMinorVersionsAfterRollback(c *gc.C) {
+	stor := s.DefaultToolsStorage
+	origTools := envtesting.PrimeTools(c, stor, s.DataDir(), s.Environ.Config().AgentStream(), version.MustParseBinary("5.4.3-precise-amd64"))
+	s.PatchValue(&version.Current, origTools.Version)
+	downgradeTools := envtesting.AssertUploadFakeToolsVersions(
+		c, stor, s.Environ.Config().AgentStream(), s.Environ.Config().AgentStream(), version.MustParseBinary("5.3.3-precise-amd64"))[0]
+	err := statetesting.SetAgentVersion(s.State, downgradeTools.Version.Number)
+	c.Assert(err, jc.ErrorIsNil)
+	s.rollBackUpgrade(c, downgradeTools.Version.Number)
+
+	dummy.SetStorageDelay(coretesting.ShortWait)
+
+	u := s.makeUpgrader(c)
+	err = u.Stop()
+	envtesting.CheckUpgraderReadyError(c, err, &upgrader.UpgradeReadyError{
+		AgentName: s.machine.Tag().String(),
+		OldTools:  origTools.Version,
+		NewTools:  downgradeTools.Version,
+		DataDir:   s.DataDir(),
+	})
+	c.Assert(s.reversedTo, jc.DeepEquals, []version.Number{downgradeTools.Version.Number})
+}
+
+func (s *UpgraderSuite) TestUpgraderDoesNotDowngradeIfReverseFails(c *gc.C) {
+	stor := s.DefaultToolsStorage
+	origTools := envtesting.PrimeTools(c, stor, s.DataDir(), s.Environ.Config().AgentStream(), version.MustParseBinary("5.4.3-precise-amd64"))
+	s.PatchValue(&version.Current, origTools.Version)
+	downgradeTools := envtesting.AssertUploadFakeToolsVersions(
+		c, stor, s.Environ.Config().AgentStream(), s.Environ.Config().AgentStream(), version.MustParseBinary("5.3.3-precise-amd64"))[0]
+	err := statetesting.SetAgentVersion(s.State, downgradeTools.Version.Number)
+	c.Assert(err, jc.ErrorIsNil)
+	s.rollBackUpgrade(c, downgradeTools.Version.Number)
+	s.reverseErr = errors.New("boom")
+
+	dummy.SetStorageDelay(coretesting.ShortWait)
+
+	u := s.makeUpgrader(c)
+	err = u.Wait()
+	c.Assert(err, gc.ErrorMatches, "cannot reverse upgrade to 5.4.3: boom")
+}
+
 func (s *UpgraderSuite) TestUpgraderRefusesDowngradeToOrigVersionIfUpgradeNotInProgress(c *gc.C) {
 	downgradeVersion := version.MustParseBinary("5.3.0-precise-amd64")
 	s.confVersion = downgradeVersion.Number